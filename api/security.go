@@ -0,0 +1,100 @@
+package api
+
+import (
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	v1 "github.com/liuxd6825/k6server/api/v1"
+)
+
+// Security are the settings of the access to the REST API server, which is
+// open to anyone who can reach its address by default.
+type Security struct {
+	// Token is the bearer token the requests should have, if it isn't empty.
+	Token string
+	// ReadOnly makes the server reject the requests which would change the
+	// test run, i.e. the ones with other methods than GET and HEAD.
+	ReadOnly bool
+	// TLSConfig makes the server serve HTTPS, if it isn't nil.
+	TLSConfig *tls.Config
+}
+
+// NewTLSConfig returns the TLS configuration of the REST API server with the
+// PEM encoded certificate and its key. If the CAs of the clients are set,
+// their certificates are required and verified with them, i.e. mutual TLS.
+func NewTLSConfig(certPEM, keyPEM, clientCAsPEM []byte) (*tls.Config, error) {
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TLS certificate: %w", err)
+	}
+	conf := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if len(clientCAsPEM) > 0 {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(clientCAsPEM) {
+			return nil, errors.New("there are no certificates in the CAs of the clients")
+		}
+		conf.ClientCAs = pool
+		conf.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return conf, nil
+}
+
+// ListenAndServe serves the REST API with HTTPS if the server has a TLS
+// configuration, and with HTTP otherwise.
+func ListenAndServe(srv *http.Server) error {
+	if srv.TLSConfig != nil {
+		return srv.ListenAndServeTLS("", "")
+	}
+	return srv.ListenAndServe()
+}
+
+// withSecurityHandler returns the middleware which authenticates the requests
+// with the bearer token, and rejects the ones which would change the test run
+// in the read-only mode. The pings are always allowed, e.g. for health checks.
+func withSecurityHandler(sec Security, next http.Handler) http.Handler {
+	if sec.Token == "" && !sec.ReadOnly {
+		return next
+	}
+	token := []byte(sec.Token)
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/ping" {
+			next.ServeHTTP(rw, r)
+			return
+		}
+		if len(token) > 0 {
+			bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(bearer), token) != 1 {
+				rw.Header().Set("WWW-Authenticate", `Bearer realm="k6"`)
+				securityError(rw, "Unauthorized", "a valid bearer token is required", http.StatusUnauthorized)
+				return
+			}
+		}
+		if sec.ReadOnly && r.Method != http.MethodGet && r.Method != http.MethodHead {
+			securityError(rw, "Forbidden", "the REST API is read-only", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(rw, r)
+	})
+}
+
+func securityError(rw http.ResponseWriter, title, detail string, status int) {
+	data, err := json.Marshal(v1.ErrorResponse{
+		Errors: []v1.Error{{Status: strconv.Itoa(status), Title: title, Detail: detail}},
+	})
+	if err != nil {
+		panic(err)
+	}
+	rw.WriteHeader(status)
+	_, _ = rw.Write(data)
+}
@@ -0,0 +1,134 @@
+package api
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecurityHandler(t *testing.T) {
+	t.Parallel()
+
+	handler := withSecurityHandler(Security{Token: "secret", ReadOnly: true}, http.HandlerFunc(testHTTPHandler))
+	tests := []struct {
+		method, path, auth string
+		status             int
+	}{
+		{http.MethodGet, "/v1/status", "", http.StatusUnauthorized},
+		{http.MethodGet, "/v1/status", "Bearer wrong", http.StatusUnauthorized},
+		{http.MethodGet, "/v1/status", "secret", http.StatusUnauthorized},
+		{http.MethodGet, "/v1/status", "Bearer secret", http.StatusOK},
+		{http.MethodHead, "/v1/status", "Bearer secret", http.StatusOK},
+		{http.MethodPatch, "/v1/status", "Bearer secret", http.StatusForbidden},
+		{http.MethodPost, "/v1/setup", "Bearer secret", http.StatusForbidden},
+		{http.MethodPost, "/v1/setup", "", http.StatusUnauthorized},
+		{http.MethodGet, "/ping", "", http.StatusOK},
+	}
+	for _, tc := range tests {
+		rw := httptest.NewRecorder()
+		r := httptest.NewRequest(tc.method, tc.path, nil)
+		if tc.auth != "" {
+			r.Header.Set("Authorization", tc.auth)
+		}
+		handler.ServeHTTP(rw, r)
+		assert.Equal(t, tc.status, rw.Code, "%s %s with %q", tc.method, tc.path, tc.auth)
+		if tc.status == http.StatusUnauthorized {
+			assert.Equal(t, `Bearer realm="k6"`, rw.Header().Get("WWW-Authenticate"))
+		}
+	}
+}
+
+// newTestCertificate returns a PEM encoded certificate and its key, signed by
+// the parent, or self-signed if it's nil.
+func newTestCertificate(
+	t *testing.T, isCA bool, parent *x509.Certificate, parentKey *ecdsa.PrivateKey,
+) (*x509.Certificate, *ecdsa.PrivateKey, []byte, []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: "k6"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	return cert, key,
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	t.Parallel()
+
+	ca, caKey, caPEM, _ := newTestCertificate(t, true, nil, nil)
+	_, _, serverPEM, serverKeyPEM := newTestCertificate(t, false, ca, caKey)
+	_, _, clientPEM, clientKeyPEM := newTestCertificate(t, false, ca, caKey)
+
+	_, err := NewTLSConfig(serverPEM, clientKeyPEM, nil)
+	assert.Error(t, err)
+	_, err = NewTLSConfig(serverPEM, serverKeyPEM, []byte("invalid"))
+	assert.Error(t, err)
+
+	tlsConfig, err := NewTLSConfig(serverPEM, serverKeyPEM, caPEM)
+	require.NoError(t, err)
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(testHTTPHandler))
+	srv.TLS = tlsConfig
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	get := func(certs ...tls.Certificate) error {
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+			RootCAs:      roots,
+			Certificates: certs,
+			MinVersion:   tls.VersionTLS12,
+		}}}
+		res, err := client.Get(srv.URL)
+		if err == nil {
+			_ = res.Body.Close()
+		}
+		return err
+	}
+
+	// the certificates of the clients are required
+	assert.Error(t, get())
+	clientCert, err := tls.X509KeyPair(clientPEM, clientKeyPEM)
+	require.NoError(t, err)
+	assert.NoError(t, get(clientCert))
+
+	// and they should be signed by the CAs of the clients
+	_, _, otherPEM, otherKeyPEM := newTestCertificate(t, false, nil, nil)
+	otherCert, err := tls.X509KeyPair(otherPEM, otherKeyPEM)
+	require.NoError(t, err)
+	assert.Error(t, get(otherCert))
+}
@@ -15,6 +15,7 @@ import (
 	v1 "github.com/liuxd6825/k6server/api/v1"
 	"github.com/liuxd6825/k6server/execution"
 	"github.com/liuxd6825/k6server/lib"
+	"github.com/liuxd6825/k6server/log"
 	"github.com/liuxd6825/k6server/metrics"
 	"github.com/liuxd6825/k6server/metrics/engine"
 )
@@ -24,6 +25,9 @@ func newHandler(cs *v1.ControlSurface, profilingEnabled bool) http.Handler {
 	mux.Handle("/v1/", v1.NewHandler(cs))
 	mux.Handle("/ping", handlePing(cs.RunState.Logger))
 	mux.Handle("/", handlePing(cs.RunState.Logger))
+	for pattern, handler := range cs.Handlers {
+		mux.Handle(pattern, handler)
+	}
 
 	injectProfilerHandler(mux, profilingEnabled)
 
@@ -55,6 +59,9 @@ func GetServer(
 	samples chan metrics.SampleContainer,
 	me *engine.MetricsEngine,
 	es *execution.Scheduler,
+	sec Security,
+	handlers map[string]http.Handler,
+	logs *log.Buffer,
 ) *http.Server {
 	// TODO: reduce the control surface as much as possible? For example, if
 	// we refactor the Runner API, we won't need to send the Samples channel.
@@ -64,10 +71,27 @@ func GetServer(
 		MetricsEngine: me,
 		Scheduler:     es,
 		RunState:      runState,
+		Handlers:      handlers,
+		Logs:          logs,
 	}
 
-	mux := withLoggingHandler(runState.Logger, newHandler(cs, profilingEnabled))
-	return &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	return NewServer(addr, profilingEnabled, cs, sec)
+}
+
+// NewServer returns a http.Server instance that serves k6's REST API for the
+// given control surface, which allows serving it without a test run, e.g. in
+// the server mode. It should be served with ListenAndServe, which uses the TLS
+// configuration of the security settings, if they have one.
+func NewServer(addr string, profilingEnabled bool, cs *v1.ControlSurface, sec Security) *http.Server {
+	mux := withLoggingHandler(cs.RunState.Logger, withSecurityHandler(sec, newHandler(cs, profilingEnabled)))
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		TLSConfig:         sec.TLSConfig,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	srv.RegisterOnShutdown(cs.CloseStreams)
+	return srv
 }
 
 type wrappedResponseWriter struct {
@@ -80,6 +104,11 @@ func (w *wrappedResponseWriter) WriteHeader(status int) {
 	w.ResponseWriter.WriteHeader(w.status)
 }
 
+// Unwrap allows http.ResponseController to flush the streaming responses.
+func (w *wrappedResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
 // withLoggingHandler returns the middleware which logs response status for request.
 func withLoggingHandler(l logrus.FieldLogger, next http.Handler) http.HandlerFunc {
 	return func(rw http.ResponseWriter, r *http.Request) {
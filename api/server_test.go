@@ -10,6 +10,8 @@ import (
 	logtest "github.com/sirupsen/logrus/hooks/test"
 	"github.com/stretchr/testify/assert"
 
+	v1 "github.com/liuxd6825/k6server/api/v1"
+	"github.com/liuxd6825/k6server/lib"
 	"github.com/liuxd6825/k6server/lib/testutils"
 )
 
@@ -71,3 +73,21 @@ func TestPing(t *testing.T) {
 	assert.Equal(t, []byte{'o', 'k'}, rw.Body.Bytes())
 	assert.NoError(t, res.Body.Close())
 }
+
+func TestOutputHandlers(t *testing.T) {
+	t.Parallel()
+	logger := logrus.New()
+	logger.SetOutput(testutils.NewTestOutput(t))
+	cs := &v1.ControlSurface{
+		RunState: &lib.TestRunState{TestPreInitState: &lib.TestPreInitState{Logger: logger}},
+		Handlers: map[string]http.Handler{"/metrics": http.HandlerFunc(testHTTPHandler)},
+	}
+
+	rw := httptest.NewRecorder()
+	newHandler(cs, false).ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	res := rw.Result()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "ok", rw.Body.String())
+	assert.NoError(t, res.Body.Close())
+}
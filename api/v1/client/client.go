@@ -4,6 +4,7 @@ package client
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"io"
 	"net/http"
@@ -19,6 +20,7 @@ type Client struct {
 	BaseURL    *url.URL
 	httpClient *http.Client
 	logger     *logrus.Entry
+	token      string
 }
 
 // Option function are helpers that enable the flexible configuration of the
@@ -58,6 +60,29 @@ func WithLogger(logger *logrus.Entry) Option {
 	})
 }
 
+// WithToken sets the bearer token the requests are authenticated with.
+func WithToken(token string) Option {
+	return Option(func(c *Client) {
+		c.token = token
+	})
+}
+
+// WithTLSConfig makes the client use HTTPS with the TLS configuration, e.g.
+// with the CAs of the server and the certificate of the client.
+func WithTLSConfig(tlsConfig *tls.Config) Option {
+	return Option(func(c *Client) {
+		c.BaseURL.Scheme = "https"
+		c.httpClient = &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+	})
+}
+
+// authorize adds the bearer token to the request, if the client has one.
+func (c *Client) authorize(req *http.Request) {
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+}
+
 // CallAPI executes the desired REST API request.
 // it's expected that the body and out are the structs that follows the JSON:API
 func (c *Client) CallAPI(ctx context.Context, method string, rel *url.URL, body, out interface{}) (err error) {
@@ -90,9 +115,11 @@ func (c *Client) CallAPI(ctx context.Context, method string, rel *url.URL, body,
 	req := &http.Request{
 		Method: method,
 		URL:    c.BaseURL.ResolveReference(rel),
+		Header: make(http.Header),
 		Body:   bodyReader,
 	}
 	req = req.WithContext(ctx)
+	c.authorize(req)
 
 	res, err := c.httpClient.Do(req)
 	if err != nil {
@@ -0,0 +1,96 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	v1 "github.com/liuxd6825/k6server/api/v1"
+)
+
+// LogsOptions are the filters of the log entries.
+type LogsOptions struct {
+	// Level is the least severe level of the entries, all of them are
+	// returned if it's empty.
+	Level string
+	// Text is searched in the messages and the values of the fields.
+	Text string
+	// Limit is the maximum number of the most recent entries, zero is no limit.
+	Limit int
+}
+
+func (opts LogsOptions) query(follow bool) url.Values {
+	query := url.Values{}
+	if opts.Level != "" {
+		query.Set("level", opts.Level)
+	}
+	if opts.Text != "" {
+		query.Set("text", opts.Text)
+	}
+	if opts.Limit > 0 {
+		query.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if follow {
+		query.Set("follow", "true")
+	}
+	return query
+}
+
+// Logs returns the recent log entries kept by the server.
+func (c *Client) Logs(ctx context.Context, opts LogsOptions) (ret []v1.LogEntry, err error) {
+	var resp v1.LogsJSONAPI
+
+	rel := &url.URL{Path: "/v1/logs", RawQuery: opts.query(false).Encode()}
+	if err = c.CallAPI(ctx, http.MethodGet, rel, nil, &resp); err != nil {
+		return ret, err
+	}
+
+	return resp.Logs(), nil
+}
+
+// FollowLogs calls onLogs with the recent log entries, and then with the new
+// ones, until the context is done or the stream ends, e.g. because the server
+// has shut down.
+func (c *Client) FollowLogs(ctx context.Context, opts LogsOptions, onLogs func([]v1.LogEntry)) error {
+	rel := &url.URL{Path: "/v1/logs", RawQuery: opts.query(true).Encode()}
+	if c.logger != nil {
+		c.logger.Debugf("[REST API] Streaming '%s'", rel.String())
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.BaseURL.ResolveReference(rel).String(), nil)
+	if err != nil {
+		return err
+	}
+	c.authorize(req)
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if res.StatusCode >= 400 {
+		var errs v1.ErrorResponse
+		if err := json.NewDecoder(res.Body).Decode(&errs); err != nil {
+			return err
+		}
+		return errs.Errors[0]
+	}
+
+	err = readEvents(res.Body, func(event string, data []byte) error {
+		if event != "logs" {
+			return nil
+		}
+		var resp v1.LogsJSONAPI
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return err
+		}
+		onLogs(resp.Logs())
+		return nil
+	})
+	if ctx.Err() != nil {
+		return nil //nolint:nilerr // the stream was stopped by the caller
+	}
+	return err
+}
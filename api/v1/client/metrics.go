@@ -1,9 +1,16 @@
 package client
 
 import (
+	"bufio"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"net/url"
+	"strings"
+	"time"
 
 	v1 "github.com/liuxd6825/k6server/api/v1"
 )
@@ -19,3 +26,123 @@ func (c *Client) Metrics(ctx context.Context) (ret []v1.Metric, err error) {
 
 	return resp.Metrics(), nil
 }
+
+// MetricsStreamOptions are the options of the stream of the metrics.
+type MetricsStreamOptions struct {
+	// Interval is the interval of the snapshots of the metrics, the server's
+	// default is used if it's zero.
+	Interval time.Duration
+	// Names are the names of the streamed metrics, all of them are if it's empty.
+	Names []string
+	// Tags are the selectors of the samples, which are also the tags of the
+	// submetrics which are streamed instead of the metrics.
+	Tags map[string]string
+	// Samples enables streaming the samples of the metrics.
+	Samples bool
+}
+
+// StreamMetrics streams the metrics, calling onMetrics with every snapshot of
+// them and onSamples with the samples, until the context is done or the
+// stream ends, e.g. because the test run has ended.
+func (c *Client) StreamMetrics(
+	ctx context.Context, opts MetricsStreamOptions, onMetrics func([]v1.Metric), onSamples func([]v1.Sample),
+) error {
+	query := url.Values{}
+	if opts.Interval > 0 {
+		query.Set("interval", opts.Interval.String())
+	}
+	for _, name := range opts.Names {
+		query.Add("name", name)
+	}
+	for key, value := range opts.Tags {
+		query.Add("tag", key+":"+value)
+	}
+	if opts.Samples {
+		query.Set("samples", "true")
+	}
+	rel := &url.URL{Path: "/v1/metrics/stream", RawQuery: query.Encode()}
+	if c.logger != nil {
+		c.logger.Debugf("[REST API] Streaming '%s'", rel.String())
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.BaseURL.ResolveReference(rel).String(), nil)
+	if err != nil {
+		return err
+	}
+	c.authorize(req)
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if res.StatusCode >= 400 {
+		var errs v1.ErrorResponse
+		if err := json.NewDecoder(res.Body).Decode(&errs); err != nil {
+			return err
+		}
+		return errs.Errors[0]
+	}
+
+	err = readEvents(res.Body, func(event string, data []byte) error {
+		switch event {
+		case "metrics":
+			var resp v1.MetricsJSONAPI
+			if err := json.Unmarshal(data, &resp); err != nil {
+				return err
+			}
+			if onMetrics != nil {
+				onMetrics(resp.Metrics())
+			}
+		case "samples":
+			var resp v1.SamplesJSONAPI
+			if err := json.Unmarshal(data, &resp); err != nil {
+				return err
+			}
+			if onSamples != nil {
+				onSamples(resp.Data)
+			}
+		}
+		return nil
+	})
+	if ctx.Err() != nil {
+		return nil //nolint:nilerr // the stream was stopped by the caller
+	}
+	return err
+}
+
+// readEvents reads the Server-Sent Events of the body until its end.
+func readEvents(body io.Reader, handle func(event string, data []byte) error) error {
+	r := bufio.NewReader(body)
+	var (
+		event string
+		data  strings.Builder
+	)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		switch {
+		case line == "":
+			if data.Len() > 0 {
+				if err := handle(event, []byte(data.String())); err != nil {
+					return fmt.Errorf("invalid %s event: %w", event, err)
+				}
+			}
+			event = ""
+			data.Reset()
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+}
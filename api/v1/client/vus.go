@@ -0,0 +1,20 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	v1 "github.com/liuxd6825/k6server/api/v1"
+)
+
+// VUs returns the live state of all the initialized VUs.
+func (c *Client) VUs(ctx context.Context) (ret []v1.VU, err error) {
+	var resp v1.VUsJSONAPI
+
+	if err = c.CallAPI(ctx, http.MethodGet, &url.URL{Path: "/v1/vus"}, nil, &resp); err != nil {
+		return ret, err
+	}
+
+	return resp.VUs(), nil
+}
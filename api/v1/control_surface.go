@@ -2,9 +2,12 @@ package v1
 
 import (
 	"context"
+	"net/http"
+	"sync"
 
 	"github.com/liuxd6825/k6server/execution"
 	"github.com/liuxd6825/k6server/lib"
+	"github.com/liuxd6825/k6server/log"
 	"github.com/liuxd6825/k6server/metrics"
 	"github.com/liuxd6825/k6server/metrics/engine"
 )
@@ -17,4 +20,32 @@ type ControlSurface struct {
 	MetricsEngine *engine.MetricsEngine
 	Scheduler     *execution.Scheduler
 	RunState      *lib.TestRunState
+
+	// Faults is only set in the server mode, where there is no Scheduler.
+	Faults FaultInjector
+	// Runs is only set in the server mode, where test runs can be submitted.
+	Runs TestRuns
+	// Handlers are served besides the REST API by their patterns, e.g. the
+	// /metrics endpoint of the prometheus output.
+	Handlers map[string]http.Handler
+	// Logs are the recent log entries served at /v1/logs, which isn't
+	// available when they aren't kept.
+	Logs *log.Buffer
+
+	streamsOnce, closeStreamsOnce sync.Once
+	streams                       chan struct{}
+}
+
+// CloseStreams ends the streaming responses, e.g. of /v1/metrics/stream,
+// which http.Server.Shutdown would otherwise wait for.
+func (cs *ControlSurface) CloseStreams() {
+	streams := cs.streamsClosed()
+	cs.closeStreamsOnce.Do(func() { close(streams) })
+}
+
+func (cs *ControlSurface) streamsClosed() chan struct{} {
+	cs.streamsOnce.Do(func() {
+		cs.streams = make(chan struct{})
+	})
+	return cs.streams
 }
@@ -0,0 +1,12 @@
+package v1
+
+import "github.com/liuxd6825/k6server/lib/fault"
+
+// FaultInjector is implemented by the server mode, whose route faults can be
+// changed at runtime. The routes are identified by their method and path, as
+// returned by fault.RouteKey.
+type FaultInjector interface {
+	Faults() map[string]fault.Rule
+	SetFaults(route string, rule fault.Rule) error
+	ResetFaults(route string) error
+}
@@ -0,0 +1,55 @@
+package v1
+
+import (
+	"sort"
+
+	"github.com/liuxd6825/k6server/lib/fault"
+)
+
+// FaultsJSONAPI is JSON API envelop for the faults of all routes
+type FaultsJSONAPI struct {
+	Data []faultData `json:"data"`
+}
+
+// FaultJSONAPI is JSON API envelop for the faults of a single route
+type FaultJSONAPI struct {
+	Data faultData `json:"data"`
+}
+
+type faultData struct {
+	Type       string     `json:"type"`
+	ID         string     `json:"id"`
+	Attributes fault.Rule `json:"attributes"`
+}
+
+func newFaultData(route string, rule fault.Rule) faultData {
+	return faultData{
+		Type:       "faults",
+		ID:         route,
+		Attributes: rule,
+	}
+}
+
+// NewFaultJSONAPI creates the JSON API envelop for the faults of a route
+func NewFaultJSONAPI(route string, rule fault.Rule) FaultJSONAPI {
+	return FaultJSONAPI{Data: newFaultData(route, rule)}
+}
+
+func newFaultsJSONAPI(rules map[string]fault.Rule) FaultsJSONAPI {
+	routes := make([]string, 0, len(rules))
+	for route := range rules {
+		routes = append(routes, route)
+	}
+	sort.Strings(routes)
+
+	data := make([]faultData, 0, len(routes))
+	for _, route := range routes {
+		data = append(data, newFaultData(route, rules[route]))
+	}
+	return FaultsJSONAPI{Data: data}
+}
+
+// Rule extracts the fault.Rule from the JSON API envelop
+func (f FaultJSONAPI) Rule() fault.Rule {
+	return f.Data.Attributes
+}
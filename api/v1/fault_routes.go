@@ -0,0 +1,105 @@
+package v1
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/liuxd6825/k6server/lib/fault"
+)
+
+// faultRouteFromPath returns the route of the /v1/faults/{method}/{path...}
+// endpoints, e.g. "GET /users/:id" for /v1/faults/GET/users/:id.
+func faultRouteFromPath(path string) string {
+	method, routePath, _ := strings.Cut(path, "/")
+	return fault.RouteKey(method, "/"+routePath)
+}
+
+func checkFaultInjector(cs *ControlSurface, rw http.ResponseWriter) bool {
+	if cs.Faults == nil {
+		apiError(rw, "Not available", "faults can only be injected in the server mode", http.StatusNotImplemented)
+		return false
+	}
+	return true
+}
+
+func handleGetFaults(cs *ControlSurface, rw http.ResponseWriter, _ *http.Request) {
+	if !checkFaultInjector(cs, rw) {
+		return
+	}
+
+	data, err := json.Marshal(newFaultsJSONAPI(cs.Faults.Faults()))
+	if err != nil {
+		apiError(rw, "Encoding error", err.Error(), http.StatusInternalServerError)
+		return
+	}
+	_, _ = rw.Write(data)
+}
+
+func handleGetFault(cs *ControlSurface, rw http.ResponseWriter, _ *http.Request, route string) {
+	if !checkFaultInjector(cs, rw) {
+		return
+	}
+	writeFault(cs, rw, route)
+}
+
+func handleSetFault(cs *ControlSurface, rw http.ResponseWriter, r *http.Request, route string) {
+	if !checkFaultInjector(cs, rw) {
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		apiError(rw, "Couldn't read request", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var envelop FaultJSONAPI
+	if err = json.Unmarshal(body, &envelop); err != nil {
+		apiError(rw, "Invalid data", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err = cs.Faults.SetFaults(route, envelop.Rule()); err != nil {
+		faultError(rw, err)
+		return
+	}
+	writeFault(cs, rw, route)
+}
+
+func handleResetFault(cs *ControlSurface, rw http.ResponseWriter, _ *http.Request, route string) {
+	if !checkFaultInjector(cs, rw) {
+		return
+	}
+
+	if err := cs.Faults.ResetFaults(route); err != nil {
+		faultError(rw, err)
+		return
+	}
+	writeFault(cs, rw, route)
+}
+
+func writeFault(cs *ControlSurface, rw http.ResponseWriter, route string) {
+	rule, ok := cs.Faults.Faults()[route]
+	if !ok {
+		apiError(rw, "Not Found", "No route with that ID was found", http.StatusNotFound)
+		return
+	}
+
+	data, err := json.Marshal(NewFaultJSONAPI(route, rule))
+	if err != nil {
+		apiError(rw, "Encoding error", err.Error(), http.StatusInternalServerError)
+		return
+	}
+	_, _ = rw.Write(data)
+}
+
+func faultError(rw http.ResponseWriter, err error) {
+	if errors.Is(err, fault.ErrUnknownRoute) {
+		apiError(rw, "Not Found", err.Error(), http.StatusNotFound)
+		return
+	}
+	apiError(rw, "Invalid faults", err.Error(), http.StatusBadRequest)
+}
@@ -0,0 +1,128 @@
+package v1
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/liuxd6825/k6server/lib"
+	"github.com/liuxd6825/k6server/lib/fault"
+	"github.com/liuxd6825/k6server/lib/testutils/minirunner"
+)
+
+// testFaultInjector keeps the faults of the routes in a map, which is reset
+// to the declared faults.
+type testFaultInjector struct {
+	mu       sync.Mutex
+	declared map[string]fault.Rule
+	current  map[string]fault.Rule
+}
+
+func (fi *testFaultInjector) Faults() map[string]fault.Rule {
+	fi.mu.Lock()
+	defer fi.mu.Unlock()
+	rules := make(map[string]fault.Rule, len(fi.current))
+	for route, rule := range fi.current {
+		rules[route] = rule
+	}
+	return rules
+}
+
+func (fi *testFaultInjector) SetFaults(route string, rule fault.Rule) error {
+	fi.mu.Lock()
+	defer fi.mu.Unlock()
+	if _, ok := fi.current[route]; !ok {
+		return fmt.Errorf("%w '%s'", fault.ErrUnknownRoute, route)
+	}
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+	fi.current[route] = rule
+	return nil
+}
+
+func (fi *testFaultInjector) ResetFaults(route string) error {
+	fi.mu.Lock()
+	defer fi.mu.Unlock()
+	if _, ok := fi.current[route]; !ok {
+		return fmt.Errorf("%w '%s'", fault.ErrUnknownRoute, route)
+	}
+	fi.current[route] = fi.declared[route]
+	return nil
+}
+
+func TestFaults(t *testing.T) {
+	t.Parallel()
+
+	declared := map[string]fault.Rule{
+		"GET /users/:id": {Reset: &fault.Reset{Rate: 0.5}},
+		"POST /users":    {},
+	}
+	cs := getControlSurface(t, getTestRunState(t, lib.Options{}, &minirunner.MiniRunner{}))
+	cs.Faults = &testFaultInjector{declared: declared, current: map[string]fault.Rule{
+		"GET /users/:id": declared["GET /users/:id"],
+		"POST /users":    declared["POST /users"],
+	}}
+
+	serve := func(method, path string, body []byte) (int, []byte) {
+		rw := httptest.NewRecorder()
+		NewHandler(cs).ServeHTTP(rw, httptest.NewRequest(method, path, bytes.NewReader(body)))
+		return rw.Code, rw.Body.Bytes()
+	}
+
+	code, body := serve(http.MethodGet, "/v1/faults", nil)
+	require.Equal(t, http.StatusOK, code)
+	var list FaultsJSONAPI
+	require.NoError(t, json.Unmarshal(body, &list))
+	require.Len(t, list.Data, 2)
+	assert.Equal(t, "faults", list.Data[0].Type)
+	assert.Equal(t, "GET /users/:id", list.Data[0].ID)
+	assert.Equal(t, 0.5, list.Data[0].Attributes.Reset.Rate)
+	assert.Equal(t, "POST /users", list.Data[1].ID)
+
+	newRule, err := json.Marshal(NewFaultJSONAPI("", fault.Rule{Error: &fault.Error{Rate: 1, Status: 503}}))
+	require.NoError(t, err)
+	code, body = serve(http.MethodPut, "/v1/faults/POST/users", newRule)
+	require.Equal(t, http.StatusOK, code, string(body))
+	var single FaultJSONAPI
+	require.NoError(t, json.Unmarshal(body, &single))
+	assert.Equal(t, "POST /users", single.Data.ID)
+	assert.Equal(t, &fault.Error{Rate: 1, Status: 503}, single.Rule().Error)
+
+	code, body = serve(http.MethodGet, "/v1/faults/POST/users", nil)
+	require.Equal(t, http.StatusOK, code)
+	require.NoError(t, json.Unmarshal(body, &single))
+	assert.Equal(t, 503, single.Rule().Error.Status)
+
+	code, body = serve(http.MethodDelete, "/v1/faults/POST/users", nil)
+	require.Equal(t, http.StatusOK, code)
+	var reset FaultJSONAPI
+	require.NoError(t, json.Unmarshal(body, &reset))
+	assert.Equal(t, fault.Rule{}, reset.Rule())
+
+	code, _ = serve(http.MethodPut, "/v1/faults/GET/missing", newRule)
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, _ = serve(http.MethodPut, "/v1/faults/GET/users/:id", []byte(`{"data":{"attributes":{"reset":{"rate":2}}}}`))
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, _ = serve(http.MethodPost, "/v1/faults/GET/users/:id", nil)
+	assert.Equal(t, http.StatusMethodNotAllowed, code)
+}
+
+func TestFaultsOutsideServerMode(t *testing.T) {
+	t.Parallel()
+
+	cs := getControlSurface(t, getTestRunState(t, lib.Options{}, &minirunner.MiniRunner{}))
+
+	rw := httptest.NewRecorder()
+	NewHandler(cs).ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/v1/faults", nil))
+	assert.Equal(t, http.StatusNotImplemented, rw.Code)
+}
@@ -0,0 +1,102 @@
+package v1
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/liuxd6825/k6server/log"
+)
+
+// LogEntry is an entry of the logs of the test run, e.g. of the console of the
+// scripts, with its structured fields.
+type LogEntry struct {
+	Time    time.Time              `json:"time"`
+	Level   string                 `json:"level"`
+	Message string                 `json:"message"`
+	Fields  map[string]interface{} `json:"fields,omitempty"`
+}
+
+func newLogEntry(e log.Entry) LogEntry {
+	return LogEntry{
+		Time:    e.Time,
+		Level:   e.Level.String(),
+		Message: e.Message,
+		Fields:  e.Fields,
+	}
+}
+
+// logsQuery is the query of the logs, e.g.
+// /v1/logs?level=warning&text=timeout&limit=100&follow=true
+type logsQuery struct {
+	// level is the least severe level of the entries
+	level logrus.Level
+	// text is searched in the messages and the values of the fields
+	text string
+	// limit is the maximum number of the most recent entries, zero is no limit
+	limit int
+	// follow streams the new entries after the recent ones
+	follow bool
+}
+
+func parseLogsQuery(values url.Values) (*logsQuery, error) {
+	q := &logsQuery{level: logrus.TraceLevel, text: values.Get("text")}
+
+	if v := values.Get("level"); v != "" {
+		level, err := logrus.ParseLevel(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid level '%s': %w", v, err)
+		}
+		q.level = level
+	}
+	if v := values.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 0 {
+			return nil, fmt.Errorf("invalid limit '%s', it should be a positive number", v)
+		}
+		q.limit = limit
+	}
+	if v := values.Get("follow"); v != "" {
+		follow, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid follow '%s': %w", v, err)
+		}
+		q.follow = follow
+	}
+	return q, nil
+}
+
+func (q *logsQuery) matches(e log.Entry) bool {
+	// the more severe levels are the lower ones
+	if e.Level > q.level {
+		return false
+	}
+	if q.text == "" || strings.Contains(e.Message, q.text) {
+		return true
+	}
+	for _, v := range e.Fields {
+		if strings.Contains(fmt.Sprint(v), q.text) {
+			return true
+		}
+	}
+	return false
+}
+
+// filter returns the entries which match the query, up to its limit of the
+// most recent ones.
+func (q *logsQuery) filter(entries []log.Entry) []log.Entry {
+	matched := make([]log.Entry, 0, len(entries))
+	for _, e := range entries {
+		if q.matches(e) {
+			matched = append(matched, e)
+		}
+	}
+	if q.limit > 0 && len(matched) > q.limit {
+		matched = matched[len(matched)-q.limit:]
+	}
+	return matched
+}
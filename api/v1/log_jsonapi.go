@@ -0,0 +1,39 @@
+package v1
+
+import (
+	"strconv"
+
+	"github.com/liuxd6825/k6server/log"
+)
+
+// LogsJSONAPI is JSON API envelop for log entries
+type LogsJSONAPI struct {
+	Data []logEntryData `json:"data"`
+}
+
+type logEntryData struct {
+	Type       string   `json:"type"`
+	ID         string   `json:"id"`
+	Attributes LogEntry `json:"attributes"`
+}
+
+func newLogsJSONAPI(entries []log.Entry) LogsJSONAPI {
+	data := make([]logEntryData, 0, len(entries))
+	for _, e := range entries {
+		data = append(data, logEntryData{
+			Type:       "logs",
+			ID:         strconv.FormatUint(e.ID, 10),
+			Attributes: newLogEntry(e),
+		})
+	}
+	return LogsJSONAPI{Data: data}
+}
+
+// Logs extracts the []v1.LogEntry from the JSON API envelop
+func (l LogsJSONAPI) Logs() []LogEntry {
+	list := make([]LogEntry, 0, len(l.Data))
+	for _, d := range l.Data {
+		list = append(list, d.Attributes)
+	}
+	return list
+}
@@ -0,0 +1,78 @@
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/liuxd6825/k6server/log"
+)
+
+// logsStreamBufferSize is the number of the new log entries which are buffered
+// for a stream, the next ones are dropped until it catches up.
+const logsStreamBufferSize = 1000
+
+func handleGetLogs(cs *ControlSurface, rw http.ResponseWriter, r *http.Request) {
+	q, err := parseLogsQuery(r.URL.Query())
+	if err != nil {
+		apiError(rw, "Invalid query", err.Error(), http.StatusBadRequest)
+		return
+	}
+	if q.follow {
+		handleFollowLogs(cs, rw, r, q)
+		return
+	}
+
+	data, err := json.Marshal(newLogsJSONAPI(q.filter(cs.Logs.Entries())))
+	if err != nil {
+		apiError(rw, "Encoding error", err.Error(), http.StatusInternalServerError)
+		return
+	}
+	_, _ = rw.Write(data)
+}
+
+// handleFollowLogs sends the recent log entries, and then the new ones, as
+// Server-Sent Events, until the client disconnects or the server shuts down.
+func handleFollowLogs(cs *ControlSurface, rw http.ResponseWriter, r *http.Request, q *logsQuery) {
+	recent, newEntries, unsubscribe := cs.Logs.Subscribe(logsStreamBufferSize)
+	defer unsubscribe()
+
+	rc := http.NewResponseController(rw)
+	rw.Header().Set("Content-Type", "text/event-stream")
+	rw.Header().Set("Cache-Control", "no-cache")
+	rw.WriteHeader(http.StatusOK)
+
+	send := func(entries []log.Entry) bool {
+		data, err := json.Marshal(newLogsJSONAPI(entries))
+		if err != nil {
+			// it isn't logged, since the entry of the error would be streamed back
+			return false
+		}
+		if _, err = fmt.Fprintf(rw, "event: logs\ndata: %s\n\n", data); err != nil {
+			return false
+		}
+		return rc.Flush() == nil
+	}
+
+	if !send(q.filter(recent)) {
+		return
+	}
+	for {
+		select {
+		case e := <-newEntries:
+			// the entries which are already buffered are sent together
+			batch := []log.Entry{e}
+			for len(newEntries) > 0 {
+				batch = append(batch, <-newEntries)
+			}
+			batch = q.filter(batch)
+			if len(batch) > 0 && !send(batch) {
+				return
+			}
+		case <-cs.streamsClosed():
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package v1
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/liuxd6825/k6server/lib"
+	"github.com/liuxd6825/k6server/lib/testutils/minirunner"
+	"github.com/liuxd6825/k6server/log"
+)
+
+func TestGetLogs(t *testing.T) {
+	t.Parallel()
+
+	testState := getTestRunState(t, lib.Options{}, &minirunner.MiniRunner{})
+	cs := getControlSurface(t, testState)
+
+	rw := httptest.NewRecorder()
+	NewHandler(cs).ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/v1/logs", nil))
+	assert.Equal(t, http.StatusNotImplemented, rw.Code, "the logs aren't kept")
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	cs.Logs = log.NewBuffer(10)
+	logger.AddHook(cs.Logs)
+	logger.WithField("source", "console").Info("first request")
+	logger.WithField("url", "http://example.com").Warn("request timeout")
+	logger.Error("second request failed")
+
+	get := func(query string) []LogEntry {
+		rw := httptest.NewRecorder()
+		NewHandler(cs).ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/v1/logs?"+query, nil))
+		require.Equal(t, http.StatusOK, rw.Code, rw.Body.String())
+		var doc LogsJSONAPI
+		require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &doc))
+		return doc.Logs()
+	}
+	messages := func(entries []LogEntry) []string {
+		list := make([]string, 0, len(entries))
+		for _, e := range entries {
+			list = append(list, e.Message)
+		}
+		return list
+	}
+
+	entries := get("")
+	require.Len(t, entries, 3)
+	assert.Equal(t, "info", entries[0].Level)
+	assert.Equal(t, map[string]interface{}{"source": "console"}, entries[0].Fields)
+	assert.Equal(t, []string{"request timeout", "second request failed"}, messages(get("level=warning")))
+	assert.Equal(t, []string{"request timeout", "second request failed"}, messages(get("text=request&limit=2")),
+		"the limit keeps the most recent entries")
+	assert.Equal(t, []string{"request timeout"}, messages(get("text=example.com")), "the fields are searched")
+
+	for _, query := range []string{"level=loud", "limit=-1", "follow=maybe"} {
+		rw := httptest.NewRecorder()
+		NewHandler(cs).ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/v1/logs?"+query, nil))
+		assert.Equal(t, http.StatusBadRequest, rw.Code, query)
+	}
+}
+
+func TestFollowLogs(t *testing.T) {
+	t.Parallel()
+
+	testState := getTestRunState(t, lib.Options{}, &minirunner.MiniRunner{})
+	cs := getControlSurface(t, testState)
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	cs.Logs = log.NewBuffer(10)
+	logger.AddHook(cs.Logs)
+	logger.Warn("before")
+
+	srv := httptest.NewServer(NewHandler(cs))
+	t.Cleanup(srv.Close)
+
+	res, err := http.Get(srv.URL + "/v1/logs?follow=true&level=warning") //nolint:noctx
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = res.Body.Close() })
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+	events := readTestEvents(t, res.Body)
+
+	var doc LogsJSONAPI
+	require.NoError(t, json.Unmarshal([]byte(nextTestEvent(t, events, "logs").data), &doc))
+	require.Len(t, doc.Logs(), 1)
+	assert.Equal(t, "before", doc.Logs()[0].Message)
+
+	logger.Info("filtered out")
+	logger.Error("after")
+	require.NoError(t, json.Unmarshal([]byte(nextTestEvent(t, events, "logs").data), &doc))
+	require.Len(t, doc.Logs(), 1)
+	assert.Equal(t, "after", doc.Logs()[0].Message)
+	assert.Equal(t, "3", doc.Data[0].ID)
+
+	// the stream ends when the server shuts down
+	cs.CloseStreams()
+	select {
+	case _, ok := <-events:
+		assert.False(t, ok)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the stream didn't end")
+	}
+}
@@ -0,0 +1,144 @@
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/liuxd6825/k6server/metrics"
+	"github.com/liuxd6825/k6server/metrics/engine"
+)
+
+const defaultSeriesInterval = 10 * time.Second
+
+// Series are the values of the time series of a metric with the same values
+// of the grouped tags, over time.
+type Series struct {
+	Metric string            `json:"metric"`
+	Tags   map[string]string `json:"tags"`
+	Points []SeriesPoint     `json:"points"`
+}
+
+// SeriesPoint are the values of a series in the interval which starts at Time,
+// like the ones of the sink of a metric.
+type SeriesPoint struct {
+	Time   time.Time          `json:"time"`
+	Values map[string]float64 `json:"values"`
+}
+
+// SeriesJSONAPI is the JSON API envelope of the time series of a metric.
+type SeriesJSONAPI struct {
+	Data []seriesData `json:"data"`
+}
+
+type seriesData struct {
+	Type       string `json:"type"`
+	ID         string `json:"id"`
+	Attributes Series `json:"attributes"`
+}
+
+// Series extracts the []v1.Series from the JSON API envelope.
+func (s SeriesJSONAPI) Series() []Series {
+	list := make([]Series, 0, len(s.Data))
+	for _, d := range s.Data {
+		list = append(list, d.Attributes)
+	}
+	return list
+}
+
+func newSeriesJSONAPI(m *metrics.Metric, groups []engine.SeriesGroup, interval time.Duration) SeriesJSONAPI {
+	data := make([]seriesData, 0, len(groups))
+	for _, g := range groups {
+		series := Series{Metric: m.Name, Tags: g.Tags, Points: make([]SeriesPoint, 0, len(g.Buckets))}
+		for _, b := range g.Buckets {
+			series.Points = append(series.Points, SeriesPoint{Time: b.Time, Values: b.Sink.Format(interval)})
+		}
+
+		id := m.Name
+		if len(g.Tags) > 0 {
+			selectors := make([]string, 0, len(g.Tags))
+			for key, value := range g.Tags {
+				selectors = append(selectors, key+":"+value)
+			}
+			sort.Strings(selectors)
+			id += "{" + strings.Join(selectors, ",") + "}"
+		}
+		data = append(data, seriesData{Type: "series", ID: id, Attributes: series})
+	}
+	return SeriesJSONAPI{Data: data}
+}
+
+// parseSeriesQuery parses the query of the series of the metric, e.g.
+// /v1/metrics/http_req_duration/series?interval=30s&since=5m&tag=scenario:default&group=name&group=status
+func parseSeriesQuery(m *metrics.Metric, values url.Values) (engine.SeriesQuery, error) {
+	q := engine.SeriesQuery{
+		Metric:   m,
+		Tags:     make(map[string]string),
+		GroupBy:  values["group"],
+		Interval: defaultSeriesInterval,
+	}
+	// the submetrics are the time series of their parents with their tags
+	if m.Sub != nil {
+		q.Metric = m.Sub.Parent
+		for key, value := range m.Sub.Tags.Map() {
+			q.Tags[key] = value
+		}
+	}
+
+	if v := values.Get("interval"); v != "" {
+		interval, err := time.ParseDuration(v)
+		if err != nil {
+			return q, fmt.Errorf("invalid interval '%s': %w", v, err)
+		}
+		if interval < time.Second {
+			return q, fmt.Errorf("the interval should be at least %s", time.Second)
+		}
+		q.Interval = interval.Truncate(time.Second)
+	}
+	if v := values.Get("since"); v != "" {
+		since, err := time.ParseDuration(v)
+		if err != nil {
+			return q, fmt.Errorf("invalid since '%s': %w", v, err)
+		}
+		q.Since = time.Now().Add(-since)
+	}
+	for _, tag := range values["tag"] {
+		key, value, ok := strings.Cut(tag, ":")
+		if !ok || key == "" {
+			return q, fmt.Errorf("invalid tag selector '%s', it should be key:value", tag)
+		}
+		q.Tags[key] = value
+	}
+	return q, nil
+}
+
+func handleGetMetricSeries(cs *ControlSurface, rw http.ResponseWriter, r *http.Request, id string) {
+	m := cs.RunState.Registry.Get(id)
+	if m == nil {
+		// the submetrics aren't in the registry, but in their parents
+		cs.MetricsEngine.MetricsLock.Lock()
+		m = cs.MetricsEngine.ObservedMetrics[id]
+		cs.MetricsEngine.MetricsLock.Unlock()
+	}
+	if m == nil {
+		apiError(rw, "Not Found", "No metric with that ID was found", http.StatusNotFound)
+		return
+	}
+
+	q, err := parseSeriesQuery(m, r.URL.Query())
+	if err != nil {
+		apiError(rw, "Invalid query", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	data, err := json.Marshal(newSeriesJSONAPI(m, cs.MetricsEngine.QuerySeries(q), q.Interval))
+	if err != nil {
+		apiError(rw, "Encoding error", err.Error(), http.StatusInternalServerError)
+		return
+	}
+	_, _ = rw.Write(data)
+}
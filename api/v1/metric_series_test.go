@@ -0,0 +1,85 @@
+package v1
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/liuxd6825/k6server/lib"
+	"github.com/liuxd6825/k6server/lib/testutils/minirunner"
+	"github.com/liuxd6825/k6server/metrics"
+)
+
+func TestGetMetricSeries(t *testing.T) {
+	t.Parallel()
+
+	testState := getTestRunState(t, lib.Options{}, &minirunner.MiniRunner{})
+	testMetric, err := testState.Registry.NewMetric("my_metric", metrics.Rate)
+	require.NoError(t, err)
+	sm, err := testMetric.AddSubmetric("status:500")
+	require.NoError(t, err)
+	cs := getControlSurface(t, testState)
+
+	ingester := cs.MetricsEngine.CreateIngester()
+	require.NoError(t, ingester.Start())
+	now := time.Now()
+	tags := testState.Registry.RootTagSet()
+	ingester.AddMetricSamples([]metrics.SampleContainer{metrics.Samples{
+		{TimeSeries: metrics.TimeSeries{Metric: testMetric, Tags: tags.With("status", "200")}, Time: now, Value: 1},
+		{TimeSeries: metrics.TimeSeries{Metric: testMetric, Tags: tags.With("status", "500")}, Time: now, Value: 0},
+		{TimeSeries: metrics.TimeSeries{Metric: testMetric, Tags: tags.With("status", "500")}, Time: now, Value: 1},
+	}})
+	require.NoError(t, ingester.Stop())
+
+	get := func(path string) (int, SeriesJSONAPI) {
+		rw := httptest.NewRecorder()
+		NewHandler(cs).ServeHTTP(rw, httptest.NewRequest(http.MethodGet, path, nil))
+		var doc SeriesJSONAPI
+		if rw.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &doc))
+		}
+		return rw.Code, doc
+	}
+
+	t.Run("grouped", func(t *testing.T) {
+		t.Parallel()
+
+		code, doc := get("/v1/metrics/my_metric/series?interval=1m&group=status")
+		require.Equal(t, http.StatusOK, code)
+		require.Len(t, doc.Data, 2)
+		assert.Equal(t, "series", doc.Data[0].Type)
+		assert.Equal(t, "my_metric{status:200}", doc.Data[0].ID)
+		series := doc.Series()
+		assert.Equal(t, map[string]string{"status": "500"}, series[1].Tags)
+		require.Len(t, series[1].Points, 1)
+		assert.Equal(t, now.Truncate(time.Minute).UTC(), series[1].Points[0].Time.UTC())
+		assert.Equal(t, map[string]float64{"rate": 0.5}, series[1].Points[0].Values)
+	})
+
+	t.Run("submetric", func(t *testing.T) {
+		t.Parallel()
+
+		code, doc := get("/v1/metrics/" + sm.Metric.Name + "/series")
+		require.Equal(t, http.StatusOK, code)
+		require.Len(t, doc.Data, 1)
+		assert.Equal(t, sm.Metric.Name, doc.Data[0].ID)
+		require.Len(t, doc.Data[0].Attributes.Points, 1)
+		assert.Equal(t, map[string]float64{"rate": 0.5}, doc.Data[0].Attributes.Points[0].Values)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		t.Parallel()
+
+		code, _ := get("/v1/metrics/unknown/series")
+		assert.Equal(t, http.StatusNotFound, code)
+		for _, query := range []string{"interval=1ms", "since=abc", "tag=status"} {
+			code, _ = get("/v1/metrics/my_metric/series?" + query)
+			assert.Equal(t, http.StatusBadRequest, code, query)
+		}
+	})
+}
@@ -0,0 +1,232 @@
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/liuxd6825/k6server/metrics"
+)
+
+const (
+	defaultMetricsStreamInterval = time.Second
+	minMetricsStreamInterval     = 100 * time.Millisecond
+
+	// metricsStreamBufferSize is the number of the batches of samples which
+	// are buffered for a stream, the next ones are dropped until it catches up.
+	metricsStreamBufferSize = 64
+)
+
+// Sample is a metric sample, sent by the stream of the metrics.
+type Sample struct {
+	Metric   string            `json:"metric"`
+	Time     time.Time         `json:"time"`
+	Value    float64           `json:"value"`
+	Tags     map[string]string `json:"tags"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// SamplesJSONAPI is the envelope of the samples events of the stream of the
+// metrics.
+type SamplesJSONAPI struct {
+	Data []Sample `json:"data"`
+}
+
+// metricsStreamQuery is the query of the stream of the metrics, e.g.
+// /v1/metrics/stream?interval=5s&name=http_req_duration&tag=status:200&samples=true
+type metricsStreamQuery struct {
+	interval time.Duration
+	names    map[string]bool
+	tags     map[string]string
+	samples  bool
+
+	// submetric is the suffix of the submetrics which have the tags, e.g.
+	// "status:200", whose sinks are sent instead of the ones of the metrics.
+	submetric string
+}
+
+func parseMetricsStreamQuery(values url.Values) (*metricsStreamQuery, error) {
+	q := &metricsStreamQuery{
+		interval: defaultMetricsStreamInterval,
+		names:    make(map[string]bool),
+		tags:     make(map[string]string),
+	}
+
+	if v := values.Get("interval"); v != "" {
+		interval, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid interval '%s': %w", v, err)
+		}
+		if interval < minMetricsStreamInterval {
+			return nil, fmt.Errorf("the interval should be at least %s", minMetricsStreamInterval)
+		}
+		q.interval = interval
+	}
+	for _, name := range values["name"] {
+		q.names[name] = true
+	}
+	for _, tag := range values["tag"] {
+		key, value, ok := strings.Cut(tag, ":")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid tag selector '%s', it should be key:value", tag)
+		}
+		q.tags[key] = value
+	}
+	if v := values.Get("samples"); v != "" {
+		samples, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid samples '%s': %w", v, err)
+		}
+		q.samples = samples
+	}
+
+	if len(q.tags) > 0 {
+		selectors := make([]string, 0, len(q.tags))
+		for key, value := range q.tags {
+			selectors = append(selectors, key+":"+value)
+		}
+		sort.Strings(selectors)
+		q.submetric = strings.Join(selectors, ",")
+	}
+	return q, nil
+}
+
+// matchesSample returns whether the sample is of one of the metrics of the
+// query, and has all of its tags.
+func (q *metricsStreamQuery) matchesSample(sample metrics.Sample) bool {
+	if len(q.names) > 0 && !q.names[sample.Metric.Name] {
+		return false
+	}
+	for key, value := range q.tags {
+		if v, ok := sample.Tags.Get(key); !ok || v != value {
+			return false
+		}
+	}
+	return true
+}
+
+// snapshot returns the observed metrics of the query. When it has tags, the
+// observed submetrics with them are returned instead, which are added to the
+// registered metrics that don't have them yet, so they're aggregated from then
+// on, like the ones of thresholds.
+func (q *metricsStreamQuery) snapshot(cs *ControlSurface, t time.Duration) MetricsJSONAPI {
+	cs.MetricsEngine.MetricsLock.Lock()
+	defer cs.MetricsEngine.MetricsLock.Unlock()
+
+	observed := make(map[string]*metrics.Metric)
+	if q.submetric == "" {
+		for name, m := range cs.MetricsEngine.ObservedMetrics {
+			if len(q.names) == 0 || q.names[name] {
+				observed[name] = m
+			}
+		}
+		return newMetricsJSONAPI(observed, t)
+	}
+
+	for _, m := range cs.RunState.Registry.All() {
+		if len(q.names) > 0 && !q.names[m.Name] {
+			continue
+		}
+		sm, err := m.AddSubmetric(q.submetric)
+		if err != nil {
+			cs.RunState.Logger.WithError(err).Debugf("Couldn't add the submetric of '%s' for the stream", m.Name)
+			continue
+		}
+		if sm.Metric.Observed {
+			observed[sm.Metric.Name] = sm.Metric
+		}
+	}
+	return newMetricsJSONAPI(observed, t)
+}
+
+// handleStreamMetrics sends the snapshots of the metrics periodically, and
+// optionally their samples, as Server-Sent Events, until the client
+// disconnects or the test run ends.
+func handleStreamMetrics(cs *ControlSurface, rw http.ResponseWriter, r *http.Request) {
+	q, err := parseMetricsStreamQuery(r.URL.Query())
+	if err != nil {
+		apiError(rw, "Invalid query", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var samples <-chan []metrics.Sample
+	if q.samples {
+		var unsubscribe func()
+		samples, unsubscribe = cs.MetricsEngine.SubscribeSamples(metricsStreamBufferSize)
+		defer unsubscribe()
+	}
+
+	rc := http.NewResponseController(rw)
+	rw.Header().Set("Content-Type", "text/event-stream")
+	rw.Header().Set("Cache-Control", "no-cache")
+	rw.WriteHeader(http.StatusOK)
+
+	send := func(event string, v interface{}) bool {
+		data, err := json.Marshal(v)
+		if err != nil {
+			cs.RunState.Logger.WithError(err).Errorf("Couldn't encode the %s of the metrics stream", event)
+			return false
+		}
+		if _, err = fmt.Fprintf(rw, "event: %s\ndata: %s\n\n", event, data); err != nil {
+			return false
+		}
+		return rc.Flush() == nil
+	}
+	sendSnapshot := func() bool {
+		return send("metrics", q.snapshot(cs, currentTestRunDuration(cs)))
+	}
+
+	if !sendSnapshot() {
+		return
+	}
+	ticker := time.NewTicker(q.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if !sendSnapshot() {
+				return
+			}
+		case batch := <-samples:
+			matched := make([]Sample, 0, len(batch))
+			for _, sample := range batch {
+				if q.matchesSample(sample) {
+					matched = append(matched, newSample(sample))
+				}
+			}
+			if len(matched) > 0 && !send("samples", SamplesJSONAPI{Data: matched}) {
+				return
+			}
+		case <-cs.RunCtx.Done():
+			// the test run has ended, so the last snapshot is final
+			sendSnapshot()
+			return
+		case <-cs.streamsClosed():
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func newSample(sample metrics.Sample) Sample {
+	return Sample{
+		Metric:   sample.Metric.Name,
+		Time:     sample.Time,
+		Value:    sample.Value,
+		Tags:     sample.Tags.Map(),
+		Metadata: sample.Metadata,
+	}
+}
+
+func currentTestRunDuration(cs *ControlSurface) time.Duration {
+	if cs.Scheduler == nil {
+		return 0
+	}
+	return cs.Scheduler.GetState().GetCurrentTestRunDuration()
+}
@@ -0,0 +1,151 @@
+package v1
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/liuxd6825/k6server/lib"
+	"github.com/liuxd6825/k6server/lib/testutils/minirunner"
+	"github.com/liuxd6825/k6server/metrics"
+)
+
+type testEvent struct {
+	name string
+	data string
+}
+
+func readTestEvents(t *testing.T, body io.Reader) <-chan testEvent {
+	t.Helper()
+
+	events := make(chan testEvent)
+	go func() {
+		defer close(events)
+		scanner := bufio.NewScanner(body)
+		var ev testEvent
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case line == "":
+				events <- ev
+				ev = testEvent{}
+			case strings.HasPrefix(line, "event: "):
+				ev.name = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				ev.data = strings.TrimPrefix(line, "data: ")
+			}
+		}
+	}()
+	return events
+}
+
+func nextTestEvent(t *testing.T, events <-chan testEvent, name string) testEvent {
+	t.Helper()
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case ev, ok := <-events:
+			require.True(t, ok, "the stream ended")
+			if ev.name == name {
+				return ev
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for a %s event", name)
+		}
+	}
+}
+
+func TestStreamMetrics(t *testing.T) {
+	t.Parallel()
+
+	testState := getTestRunState(t, lib.Options{}, &minirunner.MiniRunner{})
+	testMetric, err := testState.Registry.NewMetric("my_metric", metrics.Gauge)
+	require.NoError(t, err)
+	otherMetric, err := testState.Registry.NewMetric("other_metric", metrics.Gauge)
+	require.NoError(t, err)
+	cs := getControlSurface(t, testState)
+	runCtx, runCancel := context.WithCancel(cs.RunCtx)
+	defer runCancel()
+	cs.RunCtx = runCtx
+
+	ingester := cs.MetricsEngine.CreateIngester()
+	require.NoError(t, ingester.Start())
+	t.Cleanup(func() { assert.NoError(t, ingester.Stop()) })
+
+	srv := httptest.NewServer(NewHandler(cs))
+	t.Cleanup(srv.Close)
+
+	res, err := http.Get(srv.URL + "/v1/metrics/stream?interval=100ms&name=my_metric&tag=a:1&samples=true")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = res.Body.Close() })
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+	events := readTestEvents(t, res.Body)
+
+	var snapshot MetricsJSONAPI
+	require.NoError(t, json.Unmarshal([]byte(nextTestEvent(t, events, "metrics").data), &snapshot))
+	assert.Empty(t, snapshot.Data)
+
+	tags := testState.Registry.RootTagSet()
+	ingester.AddMetricSamples([]metrics.SampleContainer{metrics.Samples{
+		{TimeSeries: metrics.TimeSeries{Metric: testMetric, Tags: tags.With("a", "1")}, Value: 3},
+		{TimeSeries: metrics.TimeSeries{Metric: testMetric, Tags: tags.With("a", "2")}, Value: 5},
+		{TimeSeries: metrics.TimeSeries{Metric: otherMetric, Tags: tags.With("a", "1")}, Value: 7},
+	}})
+
+	var samples SamplesJSONAPI
+	require.NoError(t, json.Unmarshal([]byte(nextTestEvent(t, events, "samples").data), &samples))
+	require.Len(t, samples.Data, 1)
+	assert.Equal(t, "my_metric", samples.Data[0].Metric)
+	assert.Equal(t, 3.0, samples.Data[0].Value)
+	assert.Equal(t, map[string]string{"a": "1"}, samples.Data[0].Tags)
+
+	// the snapshots have the submetric with the tags, which was added by the
+	// stream before the samples were ingested
+	for len(snapshot.Data) == 0 {
+		require.NoError(t, json.Unmarshal([]byte(nextTestEvent(t, events, "metrics").data), &snapshot))
+	}
+	streamed := snapshot.Metrics()
+	require.Len(t, streamed, 1)
+	assert.Equal(t, "my_metric{a:1}", streamed[0].Name)
+	assert.Equal(t, 3.0, streamed[0].Sample["value"])
+
+	// the stream ends with the test run, after a final snapshot
+	runCancel()
+	timeout := time.After(5 * time.Second)
+	var last testEvent
+	for ended := false; !ended; {
+		select {
+		case ev, ok := <-events:
+			if ended = !ok; ok {
+				last = ev
+			}
+		case <-timeout:
+			t.Fatal("the stream didn't end")
+		}
+	}
+	assert.Equal(t, "metrics", last.name)
+}
+
+func TestStreamMetricsInvalidQuery(t *testing.T) {
+	t.Parallel()
+
+	testState := getTestRunState(t, lib.Options{}, &minirunner.MiniRunner{})
+	cs := getControlSurface(t, testState)
+
+	for _, query := range []string{"interval=1ms", "interval=abc", "tag=a", "samples=maybe"} {
+		rw := httptest.NewRecorder()
+		NewHandler(cs).ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/v1/metrics/stream?"+query, nil))
+		assert.Equal(t, http.StatusBadRequest, rw.Code, query)
+	}
+}
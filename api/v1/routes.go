@@ -3,6 +3,7 @@ package v1
 
 import (
 	"net/http"
+	"strings"
 )
 
 // NewHandler returns the top handler for the v1 REST APIs
@@ -10,6 +11,10 @@ func NewHandler(cs *ControlSurface) http.Handler {
 	mux := http.NewServeMux()
 
 	mux.HandleFunc("/v1/status", func(rw http.ResponseWriter, r *http.Request) {
+		if cs.Scheduler == nil {
+			apiError(rw, "Not available", "there is no test run in the server mode", http.StatusNotImplemented)
+			return
+		}
 		switch r.Method {
 		case http.MethodGet:
 			handleGetStatus(cs, rw, r)
@@ -28,6 +33,14 @@ func NewHandler(cs *ControlSurface) http.Handler {
 		handleGetMetrics(cs, rw, r)
 	})
 
+	mux.HandleFunc("/v1/metrics/stream", func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			rw.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		handleStreamMetrics(cs, rw, r)
+	})
+
 	mux.HandleFunc("/v1/metrics/", func(rw http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
 			rw.WriteHeader(http.StatusMethodNotAllowed)
@@ -35,9 +48,102 @@ func NewHandler(cs *ControlSurface) http.Handler {
 		}
 
 		id := r.URL.Path[len("/v1/metrics/"):]
+		if id, ok := strings.CutSuffix(id, "/series"); ok {
+			handleGetMetricSeries(cs, rw, r, id)
+			return
+		}
 		handleGetMetric(cs, rw, r, id)
 	})
 
+	mux.HandleFunc("/v1/thresholds", func(rw http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			handleGetThresholds(cs, rw, r)
+		case http.MethodPost:
+			handleAddThreshold(cs, rw, r)
+		case http.MethodPatch:
+			handlePatchThreshold(cs, rw, r)
+		default:
+			rw.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/v1/scenarios", func(rw http.ResponseWriter, r *http.Request) {
+		if cs.Scheduler == nil {
+			apiError(rw, "Not available", "there is no test run in the server mode", http.StatusNotImplemented)
+			return
+		}
+		if r.Method != http.MethodGet {
+			rw.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		handleGetScenarios(cs, rw, r)
+	})
+
+	mux.HandleFunc("/v1/scenarios/", func(rw http.ResponseWriter, r *http.Request) {
+		if cs.Scheduler == nil {
+			apiError(rw, "Not available", "there is no test run in the server mode", http.StatusNotImplemented)
+			return
+		}
+
+		name := r.URL.Path[len("/v1/scenarios/"):]
+		if name, ok := strings.CutSuffix(name, "/stages"); ok {
+			switch r.Method {
+			case http.MethodPut:
+				handleUpdateScenarioStages(cs, rw, r, name, true)
+			case http.MethodPost:
+				handleUpdateScenarioStages(cs, rw, r, name, false)
+			default:
+				rw.WriteHeader(http.StatusMethodNotAllowed)
+			}
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			handleGetScenario(cs, rw, r, name)
+		case http.MethodPatch:
+			handlePatchScenario(cs, rw, r, name)
+		default:
+			rw.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/v1/vus", func(rw http.ResponseWriter, r *http.Request) {
+		if cs.Scheduler == nil {
+			apiError(rw, "Not available", "there is no test run in the server mode", http.StatusNotImplemented)
+			return
+		}
+		if r.Method != http.MethodGet {
+			rw.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		handleGetVUs(cs, rw, r)
+	})
+
+	mux.HandleFunc("/v1/vus/", func(rw http.ResponseWriter, r *http.Request) {
+		if cs.Scheduler == nil {
+			apiError(rw, "Not available", "there is no test run in the server mode", http.StatusNotImplemented)
+			return
+		}
+		if r.Method != http.MethodGet {
+			rw.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		handleGetVU(cs, rw, r, r.URL.Path[len("/v1/vus/"):])
+	})
+
+	mux.HandleFunc("/v1/logs", func(rw http.ResponseWriter, r *http.Request) {
+		if cs.Logs == nil {
+			apiError(rw, "Not available", "the logs aren't kept by the REST API", http.StatusNotImplemented)
+			return
+		}
+		if r.Method != http.MethodGet {
+			rw.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		handleGetLogs(cs, rw, r)
+	})
+
 	mux.HandleFunc("/v1/groups", func(rw http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
 			rw.WriteHeader(http.StatusMethodNotAllowed)
@@ -79,5 +185,43 @@ func NewHandler(cs *ControlSurface) http.Handler {
 		handleRunTeardown(cs, rw, r)
 	})
 
+	mux.HandleFunc("/v1/faults", func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			rw.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		handleGetFaults(cs, rw, r)
+	})
+
+	mux.HandleFunc("/v1/faults/", func(rw http.ResponseWriter, r *http.Request) {
+		route := faultRouteFromPath(r.URL.Path[len("/v1/faults/"):])
+		switch r.Method {
+		case http.MethodGet:
+			handleGetFault(cs, rw, r, route)
+		case http.MethodPut:
+			handleSetFault(cs, rw, r, route)
+		case http.MethodDelete:
+			handleResetFault(cs, rw, r, route)
+		default:
+			rw.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/v1/runs", func(rw http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			handleGetRuns(cs, rw, r)
+		case http.MethodPost:
+			handleSubmitRun(cs, rw, r)
+		default:
+			rw.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/v1/runs/", func(rw http.ResponseWriter, r *http.Request) {
+		handleRun(cs, rw, r, r.URL.Path[len("/v1/runs/"):])
+	})
+
 	return mux
 }
@@ -0,0 +1,105 @@
+package v1
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+
+	"gopkg.in/guregu/null.v3"
+)
+
+// RunStatus is the status of a test run submitted to the server mode.
+type RunStatus string
+
+// The statuses of a submitted test run, which is queued until the previous
+// runs are done.
+const (
+	RunQueued    RunStatus = "queued"
+	RunRunning   RunStatus = "running"
+	RunPassed    RunStatus = "passed"
+	RunFailed    RunStatus = "failed"
+	RunCancelled RunStatus = "cancelled"
+)
+
+// Ended returns whether the run is done, successfully or not.
+func (s RunStatus) Ended() bool {
+	return s == RunPassed || s == RunFailed || s == RunCancelled
+}
+
+// The errors of the TestRuns, which the REST API responds to with their
+// respective status codes.
+var (
+	ErrRunNotFound   = errors.New("no test run with that ID was found")
+	ErrRunEnded      = errors.New("the test run has already ended")
+	ErrRunQueueFull  = errors.New("too many test runs are queued")
+	ErrInvalidRun    = errors.New("invalid test run archive")
+	ErrRunNotStarted = errors.New("the test run hasn't started yet")
+	ErrRunNotEnded   = errors.New("the test run hasn't ended yet")
+)
+
+// Run is a test run submitted to the server mode.
+type Run struct {
+	ID     string    `json:"-" yaml:"id"`
+	Status RunStatus `json:"status" yaml:"status"`
+	// Script is the main file of the archive.
+	Script string `json:"script" yaml:"script"`
+
+	Submitted null.Time `json:"submitted" yaml:"submitted"`
+	Started   null.Time `json:"started" yaml:"started"`
+	Ended     null.Time `json:"ended" yaml:"ended"`
+
+	// ExitCode is the exit code `k6 run` would have exited with, set once
+	// the run has ended, and Error is its error, if it failed.
+	ExitCode null.Int `json:"exit-code" yaml:"exit-code"`
+	Error    string   `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// RunSummary is the end-of-test summary of a test run.
+type RunSummary struct {
+	// Text is what the run printed to stdout and stderr, i.e. the text
+	// summary or the output of the handleSummary() function of the script.
+	Text string `json:"text"`
+	// Data is the summary as exported by the --summary-export option.
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// RunSnapshot is the status and the metrics of a test run when it ended. They
+// are served instead of the ones of its control surface, which isn't kept.
+type RunSnapshot struct {
+	Status  StatusJSONAPI
+	Metrics MetricsJSONAPI
+}
+
+// NewRunSnapshot returns the snapshot of the status and the metrics of the
+// control surface of a test run.
+func NewRunSnapshot(cs *ControlSurface) RunSnapshot {
+	var t time.Duration
+	if cs.Scheduler != nil {
+		t = cs.Scheduler.GetState().GetCurrentTestRunDuration()
+	}
+
+	snapshot := RunSnapshot{Status: newStatusJSONAPIFromEngine(cs)}
+	cs.MetricsEngine.MetricsLock.Lock()
+	snapshot.Metrics = newMetricsJSONAPI(cs.MetricsEngine.ObservedMetrics, t)
+	cs.MetricsEngine.MetricsLock.Unlock()
+	return snapshot
+}
+
+// TestRuns is implemented by the server mode, which runs the archives of
+// `k6 archive` submitted through the REST API, one at a time and in the order
+// they were submitted. The runs are identified by the IDs returned by
+// SubmitRun.
+type TestRuns interface {
+	SubmitRun(archive io.Reader) (Run, error)
+	Runs() []Run
+	GetRun(id string) (Run, error)
+	CancelRun(id string) (Run, error)
+
+	// RunControlSurface returns the control surface of a run, which has its
+	// status and metrics, once it's started and until it ends. After that,
+	// they are in its snapshot.
+	RunControlSurface(id string) (*ControlSurface, error)
+	RunSnapshot(id string) (RunSnapshot, error)
+	RunSummary(id string) (RunSummary, error)
+}
@@ -0,0 +1,77 @@
+package v1
+
+// RunsJSONAPI is JSON API envelop for the submitted test runs
+type RunsJSONAPI struct {
+	Data []runData `json:"data"`
+}
+
+// RunJSONAPI is JSON API envelop for a submitted test run
+type RunJSONAPI struct {
+	Data runData `json:"data"`
+}
+
+type runData struct {
+	Type       string `json:"type"`
+	ID         string `json:"id"`
+	Attributes Run    `json:"attributes"`
+}
+
+func newRunData(run Run) runData {
+	return runData{
+		Type:       "runs",
+		ID:         run.ID,
+		Attributes: run,
+	}
+}
+
+// NewRunJSONAPI creates the JSON API envelop for a test run
+func NewRunJSONAPI(run Run) RunJSONAPI {
+	return RunJSONAPI{Data: newRunData(run)}
+}
+
+func newRunsJSONAPI(runs []Run) RunsJSONAPI {
+	data := make([]runData, 0, len(runs))
+	for _, run := range runs {
+		data = append(data, newRunData(run))
+	}
+	return RunsJSONAPI{Data: data}
+}
+
+// Run extracts the Run from the JSON API envelop
+func (r RunJSONAPI) Run() Run {
+	run := r.Data.Attributes
+	run.ID = r.Data.ID
+	return run
+}
+
+// Runs extracts the runs from the JSON API envelop
+func (r RunsJSONAPI) Runs() []Run {
+	runs := make([]Run, 0, len(r.Data))
+	for _, d := range r.Data {
+		run := d.Attributes
+		run.ID = d.ID
+		runs = append(runs, run)
+	}
+	return runs
+}
+
+// RunSummaryJSONAPI is JSON API envelop for the summary of a test run
+type RunSummaryJSONAPI struct {
+	Data runSummaryData `json:"data"`
+}
+
+type runSummaryData struct {
+	Type       string     `json:"type"`
+	ID         string     `json:"id"`
+	Attributes RunSummary `json:"attributes"`
+}
+
+// NewRunSummaryJSONAPI creates the JSON API envelop for the summary of a run
+func NewRunSummaryJSONAPI(id string, summary RunSummary) RunSummaryJSONAPI {
+	return RunSummaryJSONAPI{Data: runSummaryData{Type: "summaries", ID: id, Attributes: summary}}
+}
+
+// Summary extracts the RunSummary from the JSON API envelop
+func (s RunSummaryJSONAPI) Summary() RunSummary {
+	return s.Data.Attributes
+}
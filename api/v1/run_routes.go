@@ -0,0 +1,178 @@
+package v1
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+func checkTestRuns(cs *ControlSurface, rw http.ResponseWriter) bool {
+	if cs.Runs == nil {
+		apiError(rw, "Not available", "test runs can only be submitted in the server mode", http.StatusNotImplemented)
+		return false
+	}
+	return true
+}
+
+// handleRun handles the /v1/runs/{id}/... endpoints, where the status and
+// the metrics of a run are served by the same handlers as the ones of `k6 run`.
+func handleRun(cs *ControlSurface, rw http.ResponseWriter, r *http.Request, path string) {
+	if !checkTestRuns(cs, rw) {
+		return
+	}
+
+	id, endpoint, _ := strings.Cut(path, "/")
+	if endpoint == "cancel" {
+		if r.Method != http.MethodPost {
+			rw.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		run, err := cs.Runs.CancelRun(id)
+		writeRun(rw, run, err, http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		rw.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	switch {
+	case endpoint == "":
+		run, err := cs.Runs.GetRun(id)
+		writeRun(rw, run, err, http.StatusOK)
+	case endpoint == "summary":
+		handleGetRunSummary(cs, rw, id)
+	case endpoint == "status", endpoint == "metrics", strings.HasPrefix(endpoint, "metrics/"):
+		runCS, err := cs.Runs.RunControlSurface(id)
+		if errors.Is(err, ErrRunEnded) {
+			handleGetRunSnapshot(cs, rw, id, endpoint)
+			return
+		}
+		if err != nil {
+			runError(rw, err)
+			return
+		}
+		if endpoint == "status" {
+			handleGetStatus(runCS, rw, r)
+		} else if metric, ok := strings.CutPrefix(endpoint, "metrics/"); ok {
+			handleGetMetric(runCS, rw, r, metric)
+		} else {
+			handleGetMetrics(runCS, rw, r)
+		}
+	default:
+		apiError(rw, "Not Found", "Unknown test run endpoint", http.StatusNotFound)
+	}
+}
+
+// handleGetRunSnapshot serves the status and the metrics of a run that has
+// ended, from its snapshot.
+func handleGetRunSnapshot(cs *ControlSurface, rw http.ResponseWriter, id, endpoint string) {
+	snapshot, err := cs.Runs.RunSnapshot(id)
+	if err != nil {
+		runError(rw, err)
+		return
+	}
+
+	var data interface{} = snapshot.Metrics
+	switch metric, ok := strings.CutPrefix(endpoint, "metrics/"); {
+	case endpoint == "status":
+		data = snapshot.Status
+		rw.Header().Set("Content-Type", "application/json; charset=utf-8")
+	case ok:
+		i := slices.IndexFunc(snapshot.Metrics.Data, func(m metricData) bool { return m.ID == metric })
+		if i < 0 {
+			apiError(rw, "Not Found", "No metric with that ID was found", http.StatusNotFound)
+			return
+		}
+		data = metricJSONAPI{Data: snapshot.Metrics.Data[i]}
+	}
+
+	body, err := json.Marshal(data)
+	if err != nil {
+		apiError(rw, "Encoding error", err.Error(), http.StatusInternalServerError)
+		return
+	}
+	_, _ = rw.Write(body)
+}
+
+func handleGetRuns(cs *ControlSurface, rw http.ResponseWriter, _ *http.Request) {
+	if !checkTestRuns(cs, rw) {
+		return
+	}
+
+	data, err := json.Marshal(newRunsJSONAPI(cs.Runs.Runs()))
+	if err != nil {
+		apiError(rw, "Encoding error", err.Error(), http.StatusInternalServerError)
+		return
+	}
+	_, _ = rw.Write(data)
+}
+
+// maxRunArchiveSize is the size limit of the archives of the submitted runs.
+const maxRunArchiveSize = 256 << 20
+
+// handleSubmitRun queues the archive in the body of the request, e.g.
+// curl --data-binary @archive.tar localhost:6565/v1/runs
+func handleSubmitRun(cs *ControlSurface, rw http.ResponseWriter, r *http.Request) {
+	if !checkTestRuns(cs, rw) {
+		return
+	}
+
+	run, err := cs.Runs.SubmitRun(http.MaxBytesReader(rw, r.Body, maxRunArchiveSize))
+	if err == nil {
+		rw.Header().Set("Location", "/v1/runs/"+run.ID)
+	}
+	writeRun(rw, run, err, http.StatusCreated)
+}
+
+func handleGetRunSummary(cs *ControlSurface, rw http.ResponseWriter, id string) {
+	summary, err := cs.Runs.RunSummary(id)
+	if err != nil {
+		runError(rw, err)
+		return
+	}
+
+	data, err := json.Marshal(NewRunSummaryJSONAPI(id, summary))
+	if err != nil {
+		apiError(rw, "Encoding error", err.Error(), http.StatusInternalServerError)
+		return
+	}
+	_, _ = rw.Write(data)
+}
+
+func writeRun(rw http.ResponseWriter, run Run, err error, status int) {
+	if err != nil {
+		runError(rw, err)
+		return
+	}
+
+	data, err := json.Marshal(NewRunJSONAPI(run))
+	if err != nil {
+		apiError(rw, "Encoding error", err.Error(), http.StatusInternalServerError)
+		return
+	}
+	rw.WriteHeader(status)
+	_, _ = rw.Write(data)
+}
+
+func runError(rw http.ResponseWriter, err error) {
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		apiError(rw, "Archive too large",
+			fmt.Sprintf("the archive can't be larger than %d bytes", tooLarge.Limit), http.StatusRequestEntityTooLarge)
+	case errors.Is(err, ErrRunNotFound):
+		apiError(rw, "Not Found", err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrRunEnded), errors.Is(err, ErrRunNotStarted), errors.Is(err, ErrRunNotEnded):
+		apiError(rw, "Conflict", err.Error(), http.StatusConflict)
+	case errors.Is(err, ErrRunQueueFull):
+		apiError(rw, "Queue full", err.Error(), http.StatusServiceUnavailable)
+	case errors.Is(err, ErrInvalidRun):
+		apiError(rw, "Invalid archive", err.Error(), http.StatusBadRequest)
+	default:
+		apiError(rw, "Test run error", err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,264 @@
+package v1
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/liuxd6825/k6server/lib"
+	"github.com/liuxd6825/k6server/lib/testutils/minirunner"
+	"github.com/liuxd6825/k6server/metrics"
+)
+
+// testRuns keeps the submitted runs in a slice, where the first one is
+// running with the control surface of the test.
+type testRuns struct {
+	mu   sync.Mutex
+	runs []Run
+	cs   *ControlSurface
+}
+
+func (tr *testRuns) SubmitRun(archive io.Reader) (Run, error) {
+	data, err := io.ReadAll(io.LimitReader(archive, 64))
+	if err == nil {
+		_, err = io.Copy(io.Discard, archive) // without keeping the large ones
+	}
+	if err != nil {
+		return Run{}, fmt.Errorf("%w: %w", ErrInvalidRun, err)
+	}
+	if string(data) != "archive" {
+		return Run{}, fmt.Errorf("%w: not a tar file", ErrInvalidRun)
+	}
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	run := Run{ID: strconv.Itoa(len(tr.runs) + 1), Status: RunQueued, Script: "script.js"}
+	if len(tr.runs) == 0 {
+		run.Status = RunRunning
+	}
+	tr.runs = append(tr.runs, run)
+	return run, nil
+}
+
+func (tr *testRuns) Runs() []Run {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	return append([]Run(nil), tr.runs...)
+}
+
+func (tr *testRuns) find(id string) (*Run, error) {
+	for i := range tr.runs {
+		if tr.runs[i].ID == id {
+			return &tr.runs[i], nil
+		}
+	}
+	return nil, ErrRunNotFound
+}
+
+func (tr *testRuns) GetRun(id string) (Run, error) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	run, err := tr.find(id)
+	if err != nil {
+		return Run{}, err
+	}
+	return *run, nil
+}
+
+func (tr *testRuns) CancelRun(id string) (Run, error) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	run, err := tr.find(id)
+	if err != nil {
+		return Run{}, err
+	}
+	if run.Status.Ended() {
+		return *run, ErrRunEnded
+	}
+	run.Status = RunCancelled
+	return *run, nil
+}
+
+func (tr *testRuns) RunControlSurface(id string) (*ControlSurface, error) {
+	run, err := tr.GetRun(id)
+	if err != nil {
+		return nil, err
+	}
+	if run.Status.Ended() {
+		return nil, ErrRunEnded
+	}
+	if run.Status != RunRunning {
+		return nil, ErrRunNotStarted
+	}
+	return tr.cs, nil
+}
+
+func (tr *testRuns) RunSnapshot(id string) (RunSnapshot, error) {
+	run, err := tr.GetRun(id)
+	if err != nil {
+		return RunSnapshot{}, err
+	}
+	if !run.Status.Ended() {
+		return RunSnapshot{}, ErrRunNotEnded
+	}
+	return NewRunSnapshot(tr.cs), nil
+}
+
+func (tr *testRuns) RunSummary(id string) (RunSummary, error) {
+	run, err := tr.GetRun(id)
+	if err != nil {
+		return RunSummary{}, err
+	}
+	if !run.Status.Ended() {
+		return RunSummary{}, ErrRunNotEnded
+	}
+	return RunSummary{Text: "summary of " + id, Data: json.RawMessage(`{"metrics":{}}`)}, nil
+}
+
+func TestSubmitRuns(t *testing.T) {
+	t.Parallel()
+
+	cs := getControlSurface(t, getTestRunState(t, lib.Options{}, &minirunner.MiniRunner{}))
+	cs.Runs = &testRuns{cs: cs}
+
+	serve := func(method, path string, body []byte) *httptest.ResponseRecorder {
+		rw := httptest.NewRecorder()
+		NewHandler(cs).ServeHTTP(rw, httptest.NewRequest(method, path, bytes.NewReader(body)))
+		return rw
+	}
+	decodeRun := func(rw *httptest.ResponseRecorder) Run {
+		var envelop RunJSONAPI
+		require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &envelop))
+		assert.Equal(t, "runs", envelop.Data.Type)
+		return envelop.Run()
+	}
+
+	rw := serve(http.MethodPost, "/v1/runs", []byte("archive"))
+	require.Equal(t, http.StatusCreated, rw.Code, rw.Body.String())
+	assert.Equal(t, "/v1/runs/1", rw.Header().Get("Location"))
+	assert.Equal(t, Run{ID: "1", Status: RunRunning, Script: "script.js"}, decodeRun(rw))
+
+	rw = serve(http.MethodPost, "/v1/runs", []byte("archive"))
+	require.Equal(t, http.StatusCreated, rw.Code)
+	assert.Equal(t, RunQueued, decodeRun(rw).Status)
+
+	rw = serve(http.MethodGet, "/v1/runs", nil)
+	require.Equal(t, http.StatusOK, rw.Code)
+	var list RunsJSONAPI
+	require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &list))
+	runs := list.Runs()
+	require.Len(t, runs, 2)
+	assert.Equal(t, "1", runs[0].ID)
+	assert.Equal(t, "2", runs[1].ID)
+
+	rw = serve(http.MethodGet, "/v1/runs/1", nil)
+	require.Equal(t, http.StatusOK, rw.Code)
+	assert.Equal(t, "1", decodeRun(rw).ID)
+
+	t.Run("status and metrics", func(t *testing.T) {
+		t.Parallel()
+
+		rw := serve(http.MethodGet, "/v1/runs/1/status", nil)
+		require.Equal(t, http.StatusOK, rw.Code, rw.Body.String())
+		var status StatusJSONAPI
+		require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &status))
+		assert.Equal(t, "status", status.Data.Type)
+
+		rw = serve(http.MethodGet, "/v1/runs/1/metrics", nil)
+		require.Equal(t, http.StatusOK, rw.Code)
+		rw = serve(http.MethodGet, "/v1/runs/1/metrics/nope", nil)
+		assert.Equal(t, http.StatusNotFound, rw.Code)
+
+		rw = serve(http.MethodGet, "/v1/runs/2/status", nil)
+		assert.Equal(t, http.StatusConflict, rw.Code)
+		rw = serve(http.MethodGet, "/v1/runs/1/summary", nil)
+		assert.Equal(t, http.StatusConflict, rw.Code)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		t.Parallel()
+
+		rw := serve(http.MethodPost, "/v1/runs", []byte("not an archive"))
+		assert.Equal(t, http.StatusBadRequest, rw.Code)
+		rw = serve(http.MethodPost, "/v1/runs", make([]byte, maxRunArchiveSize+1))
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rw.Code)
+		rw = serve(http.MethodGet, "/v1/runs/42", nil)
+		assert.Equal(t, http.StatusNotFound, rw.Code)
+		rw = serve(http.MethodGet, "/v1/runs/1/nope", nil)
+		assert.Equal(t, http.StatusNotFound, rw.Code)
+		rw = serve(http.MethodDelete, "/v1/runs/1", nil)
+		assert.Equal(t, http.StatusMethodNotAllowed, rw.Code)
+		rw = serve(http.MethodGet, "/v1/runs/1/cancel", nil)
+		assert.Equal(t, http.StatusMethodNotAllowed, rw.Code)
+	})
+}
+
+func TestRunCancel(t *testing.T) {
+	t.Parallel()
+
+	testState := getTestRunState(t, lib.Options{}, &minirunner.MiniRunner{})
+	testMetric, err := testState.Registry.NewMetric("my_metric", metrics.Trend, metrics.Time)
+	require.NoError(t, err)
+	cs := getControlSurface(t, testState)
+	cs.MetricsEngine.ObservedMetrics = map[string]*metrics.Metric{"my_metric": testMetric}
+	runs := &testRuns{cs: cs, runs: []Run{{ID: "1", Status: RunRunning}}}
+	cs.Runs = runs
+
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		rw := httptest.NewRecorder()
+		NewHandler(cs).ServeHTTP(rw, httptest.NewRequest(method, path, nil))
+		return rw
+	}
+
+	rw := serve(http.MethodPost, "/v1/runs/1/cancel")
+	require.Equal(t, http.StatusOK, rw.Code)
+	var envelop RunJSONAPI
+	require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &envelop))
+	assert.Equal(t, RunCancelled, envelop.Run().Status)
+
+	rw = serve(http.MethodPost, "/v1/runs/1/cancel")
+	assert.Equal(t, http.StatusConflict, rw.Code)
+
+	// the status and the metrics of the ended run are the ones of its snapshot
+	rw = serve(http.MethodGet, "/v1/runs/1/status")
+	require.Equal(t, http.StatusOK, rw.Code, rw.Body.String())
+	var status StatusJSONAPI
+	require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &status))
+	assert.Equal(t, "status", status.Data.Type)
+	rw = serve(http.MethodGet, "/v1/runs/1/metrics")
+	require.Equal(t, http.StatusOK, rw.Code, rw.Body.String())
+	var list MetricsJSONAPI
+	require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &list))
+	require.Len(t, list.Data, 1)
+	assert.Equal(t, "my_metric", list.Data[0].ID)
+	rw = serve(http.MethodGet, "/v1/runs/1/metrics/my_metric")
+	assert.Equal(t, http.StatusOK, rw.Code, rw.Body.String())
+	rw = serve(http.MethodGet, "/v1/runs/1/metrics/nope")
+	assert.Equal(t, http.StatusNotFound, rw.Code)
+
+	rw = serve(http.MethodGet, "/v1/runs/1/summary")
+	require.Equal(t, http.StatusOK, rw.Code)
+	var summary RunSummaryJSONAPI
+	require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &summary))
+	assert.Equal(t, "summaries", summary.Data.Type)
+	assert.Equal(t, "summary of 1", summary.Summary().Text)
+	assert.JSONEq(t, `{"metrics":{}}`, string(summary.Summary().Data))
+}
+
+func TestRunsOutsideServerMode(t *testing.T) {
+	t.Parallel()
+
+	cs := getControlSurface(t, getTestRunState(t, lib.Options{}, &minirunner.MiniRunner{}))
+
+	rw := httptest.NewRecorder()
+	NewHandler(cs).ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/v1/runs", nil))
+	assert.Equal(t, http.StatusNotImplemented, rw.Code)
+}
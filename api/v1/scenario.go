@@ -0,0 +1,41 @@
+package v1
+
+import (
+	"gopkg.in/guregu/null.v3"
+
+	"github.com/liuxd6825/k6server/lib"
+	"github.com/liuxd6825/k6server/lib/executor"
+)
+
+// Scenario is the runtime state of a scenario, which can be changed while it's
+// running. The attributes are null when its executor doesn't support them.
+type Scenario struct {
+	Name     string `json:"name"`
+	Executor string `json:"executor"`
+	// Paused scenarios don't start new iterations until they are resumed
+	Paused null.Bool `json:"paused"`
+	// Stopped scenarios ended their regular duration early
+	Stopped null.Bool `json:"stopped"`
+	// RateMultiplier multiplies the rate of the arrival-rate scenarios
+	RateMultiplier null.Float `json:"rateMultiplier"`
+	// Stages are all the stages of the scenario, since its start
+	Stages []executor.Stage `json:"stages,omitempty"`
+}
+
+func newScenario(exec lib.Executor) Scenario {
+	s := Scenario{
+		Name:     exec.GetConfig().GetName(),
+		Executor: exec.GetConfig().GetType(),
+	}
+	if e, ok := exec.(lib.ScenarioControllableExecutor); ok {
+		s.Paused = null.BoolFrom(e.IsScenarioPaused())
+		s.Stopped = null.BoolFrom(e.IsScenarioStopped())
+	}
+	if e, ok := exec.(lib.RateMultipliableExecutor); ok {
+		s.RateMultiplier = null.FloatFrom(e.GetRateMultiplier())
+	}
+	if e, ok := exec.(executor.StagesUpdatableExecutor); ok {
+		s.Stages = e.GetStages()
+	}
+	return s
+}
@@ -0,0 +1,52 @@
+package v1
+
+// ScenariosJSONAPI is JSON API envelop for all scenarios
+type ScenariosJSONAPI struct {
+	Data []scenarioData `json:"data"`
+}
+
+// ScenarioJSONAPI is JSON API envelop for a single scenario
+type ScenarioJSONAPI struct {
+	Data scenarioData `json:"data"`
+}
+
+type scenarioData struct {
+	Type       string   `json:"type"`
+	ID         string   `json:"id"`
+	Attributes Scenario `json:"attributes"`
+}
+
+func newScenarioData(s Scenario) scenarioData {
+	return scenarioData{
+		Type:       "scenarios",
+		ID:         s.Name,
+		Attributes: s,
+	}
+}
+
+// NewScenarioJSONAPI creates the JSON API envelop for a scenario
+func NewScenarioJSONAPI(s Scenario) ScenarioJSONAPI {
+	return ScenarioJSONAPI{Data: newScenarioData(s)}
+}
+
+func newScenariosJSONAPI(scenarios []Scenario) ScenariosJSONAPI {
+	data := make([]scenarioData, 0, len(scenarios))
+	for _, s := range scenarios {
+		data = append(data, newScenarioData(s))
+	}
+	return ScenariosJSONAPI{Data: data}
+}
+
+// Scenario extracts the v1.Scenario from the JSON API envelop
+func (s ScenarioJSONAPI) Scenario() Scenario {
+	return s.Data.Attributes
+}
+
+// Scenarios extracts the []v1.Scenario from the JSON API envelop
+func (s ScenariosJSONAPI) Scenarios() []Scenario {
+	list := make([]Scenario, 0, len(s.Data))
+	for _, d := range s.Data {
+		list = append(list, d.Attributes)
+	}
+	return list
+}
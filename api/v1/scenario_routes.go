@@ -0,0 +1,138 @@
+package v1
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/liuxd6825/k6server/lib"
+	"github.com/liuxd6825/k6server/lib/executor"
+)
+
+func handleGetScenarios(cs *ControlSurface, rw http.ResponseWriter, _ *http.Request) {
+	executors := cs.Scheduler.GetExecutors()
+	scenarios := make([]Scenario, 0, len(executors))
+	for _, exec := range executors {
+		scenarios = append(scenarios, newScenario(exec))
+	}
+
+	data, err := json.Marshal(newScenariosJSONAPI(scenarios))
+	if err != nil {
+		apiError(rw, "Encoding error", err.Error(), http.StatusInternalServerError)
+		return
+	}
+	_, _ = rw.Write(data)
+}
+
+func getScenarioExecutor(cs *ControlSurface, rw http.ResponseWriter, name string) (lib.Executor, bool) {
+	for _, exec := range cs.Scheduler.GetExecutors() {
+		if exec.GetConfig().GetName() == name {
+			return exec, true
+		}
+	}
+	apiError(rw, "Not Found", "No scenario with that name was found", http.StatusNotFound)
+	return nil, false
+}
+
+func handleGetScenario(cs *ControlSurface, rw http.ResponseWriter, _ *http.Request, name string) {
+	exec, ok := getScenarioExecutor(cs, rw, name)
+	if !ok {
+		return
+	}
+	writeScenario(rw, exec)
+}
+
+func readScenario(rw http.ResponseWriter, r *http.Request) (Scenario, bool) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		apiError(rw, "Couldn't read request", err.Error(), http.StatusBadRequest)
+		return Scenario{}, false
+	}
+
+	var envelop ScenarioJSONAPI
+	if err = json.Unmarshal(body, &envelop); err != nil {
+		apiError(rw, "Invalid data", err.Error(), http.StatusBadRequest)
+		return Scenario{}, false
+	}
+	return envelop.Scenario(), true
+}
+
+// handlePatchScenario pauses, resumes or stops the scenario, or changes its
+// rate multiplier, depending on which attributes are set.
+func handlePatchScenario(cs *ControlSurface, rw http.ResponseWriter, r *http.Request, name string) {
+	exec, ok := getScenarioExecutor(cs, rw, name)
+	if !ok {
+		return
+	}
+	s, ok := readScenario(rw, r)
+	if !ok {
+		return
+	}
+
+	controllable, isControllable := exec.(lib.ScenarioControllableExecutor)
+	if (s.Paused.Valid || s.Stopped.Valid) && !isControllable {
+		apiError(rw, "Not supported", "the executor of the scenario can't be paused or stopped", http.StatusBadRequest)
+		return
+	}
+	if s.Stopped.Valid && !s.Stopped.Bool && controllable.IsScenarioStopped() {
+		apiError(rw, "Invalid data", "a stopped scenario can't be started again", http.StatusBadRequest)
+		return
+	}
+	multipliable, isMultipliable := exec.(lib.RateMultipliableExecutor)
+	if s.RateMultiplier.Valid && !isMultipliable {
+		apiError(rw, "Not supported", "the rate of the scenario can't be multiplied", http.StatusBadRequest)
+		return
+	}
+
+	if s.RateMultiplier.Valid {
+		if err := multipliable.SetRateMultiplier(s.RateMultiplier.Float64); err != nil {
+			apiError(rw, "Invalid data", err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if s.Paused.Valid {
+		if err := controllable.SetScenarioPaused(s.Paused.Bool); err != nil {
+			apiError(rw, "Pause error", err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if s.Stopped.Bool {
+		controllable.StopScenario()
+	}
+	writeScenario(rw, exec)
+}
+
+// handleUpdateScenarioStages replaces the remaining stages of the scenario, or
+// appends the stages to them.
+func handleUpdateScenarioStages(
+	cs *ControlSurface, rw http.ResponseWriter, r *http.Request, name string, replace bool,
+) {
+	exec, ok := getScenarioExecutor(cs, rw, name)
+	if !ok {
+		return
+	}
+	updatable, ok := exec.(executor.StagesUpdatableExecutor)
+	if !ok {
+		apiError(rw, "Not supported", "the stages of the scenario can't be updated", http.StatusBadRequest)
+		return
+	}
+	s, ok := readScenario(rw, r)
+	if !ok {
+		return
+	}
+
+	if err := updatable.UpdateStages(s.Stages, replace); err != nil {
+		apiError(rw, "Stages error", err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeScenario(rw, exec)
+}
+
+func writeScenario(rw http.ResponseWriter, exec lib.Executor) {
+	data, err := json.Marshal(NewScenarioJSONAPI(newScenario(exec)))
+	if err != nil {
+		apiError(rw, "Encoding error", err.Error(), http.StatusInternalServerError)
+		return
+	}
+	_, _ = rw.Write(data)
+}
@@ -0,0 +1,92 @@
+package v1
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v3"
+
+	"github.com/liuxd6825/k6server/lib"
+	"github.com/liuxd6825/k6server/lib/testutils/minirunner"
+)
+
+func TestScenarios(t *testing.T) {
+	t.Parallel()
+
+	scenarios := lib.ScenarioConfigs{}
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"car": {"executor": "constant-arrival-rate", "rate": 10, "duration": "10s", "preAllocatedVUs": 1, "maxVUs": 1},
+		"rar": {"executor": "ramping-arrival-rate", "stages": [{"duration": "10s", "target": 10}], "preAllocatedVUs": 1, "maxVUs": 1},
+		"si": {"executor": "shared-iterations", "iterations": 10, "vus": 1}
+	}`), &scenarios))
+	testState := getTestRunState(t, lib.Options{Scenarios: scenarios}, &minirunner.MiniRunner{})
+	cs := getControlSurface(t, testState)
+
+	serve := func(method, path string, s *Scenario) (int, []byte) {
+		var reqBody []byte
+		if s != nil {
+			var err error
+			reqBody, err = json.Marshal(NewScenarioJSONAPI(*s))
+			require.NoError(t, err)
+		}
+		rw := httptest.NewRecorder()
+		NewHandler(cs).ServeHTTP(rw, httptest.NewRequest(method, path, bytes.NewReader(reqBody)))
+		return rw.Code, rw.Body.Bytes()
+	}
+
+	code, body := serve(http.MethodGet, "/v1/scenarios", nil)
+	require.Equal(t, http.StatusOK, code, string(body))
+	var list ScenariosJSONAPI
+	require.NoError(t, json.Unmarshal(body, &list))
+	require.Len(t, list.Scenarios(), 3)
+	for _, s := range list.Scenarios() {
+		assert.Equal(t, null.BoolFrom(false), s.Paused, s.Name)
+		assert.Equal(t, s.Executor != "shared-iterations", s.RateMultiplier.Valid, s.Name)
+		assert.Equal(t, s.Executor == "ramping-arrival-rate", len(s.Stages) == 1, s.Name)
+	}
+
+	code, body = serve(http.MethodPatch, "/v1/scenarios/car", &Scenario{
+		Paused: null.BoolFrom(true), RateMultiplier: null.FloatFrom(2),
+	})
+	require.Equal(t, http.StatusOK, code, string(body))
+	var doc ScenarioJSONAPI
+	require.NoError(t, json.Unmarshal(body, &doc))
+	assert.Equal(t, "scenarios", doc.Data.Type)
+	assert.Equal(t, "car", doc.Data.ID)
+	assert.Equal(t, Scenario{
+		Name: "car", Executor: "constant-arrival-rate",
+		Paused: null.BoolFrom(true), Stopped: null.BoolFrom(false), RateMultiplier: null.FloatFrom(2),
+	}, doc.Scenario())
+
+	code, _ = serve(http.MethodPatch, "/v1/scenarios/car", &Scenario{RateMultiplier: null.FloatFrom(-1)})
+	assert.Equal(t, http.StatusBadRequest, code, "invalid rate multiplier")
+	code, _ = serve(http.MethodPatch, "/v1/scenarios/si", &Scenario{RateMultiplier: null.FloatFrom(2)})
+	assert.Equal(t, http.StatusBadRequest, code, "not an arrival-rate executor")
+	code, _ = serve(http.MethodPatch, "/v1/scenarios/unknown", &Scenario{Paused: null.BoolFrom(true)})
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _ = serve(http.MethodPut, "/v1/scenarios/car/stages", &Scenario{})
+	assert.Equal(t, http.StatusBadRequest, code, "no stages")
+	code, _ = serve(http.MethodPost, "/v1/scenarios/rar/stages", &Scenario{Stages: list.Scenarios()[1].Stages})
+	assert.Equal(t, http.StatusBadRequest, code, "not running")
+
+	code, body = serve(http.MethodPatch, "/v1/scenarios/si", &Scenario{Stopped: null.BoolFrom(true)})
+	require.Equal(t, http.StatusOK, code, string(body))
+	require.NoError(t, json.Unmarshal(body, &doc))
+	assert.True(t, doc.Scenario().Stopped.Bool)
+	code, _ = serve(http.MethodPatch, "/v1/scenarios/si", &Scenario{Stopped: null.BoolFrom(false)})
+	assert.Equal(t, http.StatusBadRequest, code, "can't be started again")
+
+	code, body = serve(http.MethodGet, "/v1/scenarios/si", nil)
+	require.Equal(t, http.StatusOK, code, string(body))
+	require.NoError(t, json.Unmarshal(body, &doc))
+	assert.True(t, doc.Scenario().Stopped.Bool)
+
+	cs.Scheduler = nil
+	code, _ = serve(http.MethodGet, "/v1/scenarios", nil)
+	assert.Equal(t, http.StatusNotImplemented, code)
+}
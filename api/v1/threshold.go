@@ -0,0 +1,29 @@
+package v1
+
+import (
+	"gopkg.in/guregu/null.v3"
+
+	"github.com/liuxd6825/k6server/metrics/engine"
+)
+
+// Threshold is a threshold of a metric, which can be added, muted and unmuted
+// during the test run.
+type Threshold struct {
+	Metric      string `json:"metric"`
+	Source      string `json:"threshold"`
+	AbortOnFail bool   `json:"abortOnFail"`
+	// OK is whether the threshold passed, when it was last evaluated
+	OK bool `json:"ok"`
+	// Muted thresholds don't fail or abort the test run
+	Muted null.Bool `json:"muted"`
+}
+
+func newThreshold(s engine.ThresholdState) Threshold {
+	return Threshold{
+		Metric:      s.Metric,
+		Source:      s.Source,
+		AbortOnFail: s.AbortOnFail,
+		OK:          !s.Failed,
+		Muted:       null.BoolFrom(s.Muted),
+	}
+}
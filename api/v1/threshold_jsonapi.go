@@ -0,0 +1,52 @@
+package v1
+
+// ThresholdsJSONAPI is JSON API envelop for the thresholds of all metrics
+type ThresholdsJSONAPI struct {
+	Data []thresholdData `json:"data"`
+}
+
+// ThresholdJSONAPI is JSON API envelop for a single threshold
+type ThresholdJSONAPI struct {
+	Data thresholdData `json:"data"`
+}
+
+type thresholdData struct {
+	Type       string    `json:"type"`
+	ID         string    `json:"id"`
+	Attributes Threshold `json:"attributes"`
+}
+
+func newThresholdData(t Threshold) thresholdData {
+	return thresholdData{
+		Type:       "thresholds",
+		ID:         t.Metric + " " + t.Source,
+		Attributes: t,
+	}
+}
+
+// NewThresholdJSONAPI creates the JSON API envelop for a threshold
+func NewThresholdJSONAPI(t Threshold) ThresholdJSONAPI {
+	return ThresholdJSONAPI{Data: newThresholdData(t)}
+}
+
+func newThresholdsJSONAPI(thresholds []Threshold) ThresholdsJSONAPI {
+	data := make([]thresholdData, 0, len(thresholds))
+	for _, t := range thresholds {
+		data = append(data, newThresholdData(t))
+	}
+	return ThresholdsJSONAPI{Data: data}
+}
+
+// Threshold extracts the v1.Threshold from the JSON API envelop
+func (t ThresholdJSONAPI) Threshold() Threshold {
+	return t.Data.Attributes
+}
+
+// Thresholds extracts the []v1.Threshold from the JSON API envelop
+func (t ThresholdsJSONAPI) Thresholds() []Threshold {
+	list := make([]Threshold, 0, len(t.Data))
+	for _, d := range t.Data {
+		list = append(list, d.Attributes)
+	}
+	return list
+}
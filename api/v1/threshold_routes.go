@@ -0,0 +1,110 @@
+package v1
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/liuxd6825/k6server/metrics/engine"
+)
+
+func handleGetThresholds(cs *ControlSurface, rw http.ResponseWriter, _ *http.Request) {
+	states := cs.MetricsEngine.Thresholds()
+	thresholds := make([]Threshold, 0, len(states))
+	for _, s := range states {
+		thresholds = append(thresholds, newThreshold(s))
+	}
+
+	data, err := json.Marshal(newThresholdsJSONAPI(thresholds))
+	if err != nil {
+		apiError(rw, "Encoding error", err.Error(), http.StatusInternalServerError)
+		return
+	}
+	_, _ = rw.Write(data)
+}
+
+func readThreshold(rw http.ResponseWriter, r *http.Request) (Threshold, bool) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		apiError(rw, "Couldn't read request", err.Error(), http.StatusBadRequest)
+		return Threshold{}, false
+	}
+
+	var envelop ThresholdJSONAPI
+	if err = json.Unmarshal(body, &envelop); err != nil {
+		apiError(rw, "Invalid data", err.Error(), http.StatusBadRequest)
+		return Threshold{}, false
+	}
+	t := envelop.Threshold()
+	if t.Metric == "" || t.Source == "" {
+		apiError(rw, "Invalid data", "both the metric and the threshold are required", http.StatusBadRequest)
+		return Threshold{}, false
+	}
+	return t, true
+}
+
+// handleAddThreshold adds the threshold to the metric, which is muted at once
+// if it's requested.
+func handleAddThreshold(cs *ControlSurface, rw http.ResponseWriter, r *http.Request) {
+	t, ok := readThreshold(rw, r)
+	if !ok {
+		return
+	}
+
+	err := cs.MetricsEngine.AddThreshold(t.Metric, t.Source, t.AbortOnFail)
+	if err == nil && t.Muted.Bool {
+		err = cs.MetricsEngine.SetThresholdMuted(t.Metric, t.Source, true)
+	}
+	if err != nil {
+		thresholdError(rw, err)
+		return
+	}
+	writeThreshold(cs, rw, t)
+}
+
+// handlePatchThreshold mutes or unmutes the threshold, the other attributes
+// can't be changed.
+func handlePatchThreshold(cs *ControlSurface, rw http.ResponseWriter, r *http.Request) {
+	t, ok := readThreshold(rw, r)
+	if !ok {
+		return
+	}
+	if !t.Muted.Valid {
+		apiError(rw, "Invalid data", "only the muted attribute of the thresholds can be changed", http.StatusBadRequest)
+		return
+	}
+
+	if err := cs.MetricsEngine.SetThresholdMuted(t.Metric, t.Source, t.Muted.Bool); err != nil {
+		thresholdError(rw, err)
+		return
+	}
+	writeThreshold(cs, rw, t)
+}
+
+func writeThreshold(cs *ControlSurface, rw http.ResponseWriter, t Threshold) {
+	for _, s := range cs.MetricsEngine.Thresholds() {
+		if s.Metric != t.Metric || s.Source != t.Source {
+			continue
+		}
+		data, err := json.Marshal(NewThresholdJSONAPI(newThreshold(s)))
+		if err != nil {
+			apiError(rw, "Encoding error", err.Error(), http.StatusInternalServerError)
+			return
+		}
+		_, _ = rw.Write(data)
+		return
+	}
+	apiError(rw, "Not Found", "No threshold with that metric and source was found", http.StatusNotFound)
+}
+
+func thresholdError(rw http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, engine.ErrThresholdsDisabled):
+		apiError(rw, "Not available", err.Error(), http.StatusNotImplemented)
+	case errors.Is(err, engine.ErrUnknownThreshold):
+		apiError(rw, "Not Found", err.Error(), http.StatusNotFound)
+	default:
+		apiError(rw, "Invalid threshold", err.Error(), http.StatusBadRequest)
+	}
+}
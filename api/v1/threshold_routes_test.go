@@ -0,0 +1,81 @@
+package v1
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v3"
+
+	"github.com/liuxd6825/k6server/lib"
+	"github.com/liuxd6825/k6server/lib/testutils/minirunner"
+	"github.com/liuxd6825/k6server/metrics"
+)
+
+func TestThresholds(t *testing.T) {
+	t.Parallel()
+
+	testState := getTestRunState(t, lib.Options{}, &minirunner.MiniRunner{})
+	_, err := testState.Registry.NewMetric("my_trend", metrics.Trend)
+	require.NoError(t, err)
+	cs := getControlSurface(t, testState)
+	require.NoError(t, cs.MetricsEngine.InitSubMetricsAndThresholds(lib.Options{}, false))
+
+	serve := func(method string, body interface{}) (int, []byte) {
+		var reqBody []byte
+		if body != nil {
+			reqBody, err = json.Marshal(body)
+			require.NoError(t, err)
+		}
+		rw := httptest.NewRecorder()
+		NewHandler(cs).ServeHTTP(rw, httptest.NewRequest(method, "/v1/thresholds", bytes.NewReader(reqBody)))
+		return rw.Code, rw.Body.Bytes()
+	}
+
+	code, body := serve(http.MethodPost, NewThresholdJSONAPI(Threshold{
+		Metric: "my_trend{status:500}", Source: "p(95)<100", AbortOnFail: true,
+	}))
+	require.Equal(t, http.StatusOK, code, string(body))
+	var doc ThresholdJSONAPI
+	require.NoError(t, json.Unmarshal(body, &doc))
+	assert.Equal(t, "thresholds", doc.Data.Type)
+	assert.Equal(t, Threshold{
+		Metric: "my_trend{status:500}", Source: "p(95)<100", AbortOnFail: true, OK: true, Muted: null.BoolFrom(false),
+	}, doc.Threshold())
+
+	code, _ = serve(http.MethodPost, NewThresholdJSONAPI(Threshold{Metric: "my_trend", Source: "rate<0.1"}))
+	assert.Equal(t, http.StatusBadRequest, code, "unsupported aggregation method")
+	code, _ = serve(http.MethodPost, NewThresholdJSONAPI(Threshold{Metric: "my_trend"}))
+	assert.Equal(t, http.StatusBadRequest, code, "no threshold")
+
+	code, body = serve(http.MethodPatch, NewThresholdJSONAPI(Threshold{
+		Metric: "my_trend{status:500}", Source: "p(95)<100", Muted: null.BoolFrom(true),
+	}))
+	require.Equal(t, http.StatusOK, code, string(body))
+	require.NoError(t, json.Unmarshal(body, &doc))
+	assert.True(t, doc.Threshold().Muted.Bool)
+
+	code, _ = serve(http.MethodPatch, NewThresholdJSONAPI(Threshold{Metric: "my_trend", Source: "p(95)<100"}))
+	assert.Equal(t, http.StatusBadRequest, code, "no muted attribute")
+	code, _ = serve(http.MethodPatch, NewThresholdJSONAPI(Threshold{
+		Metric: "my_trend", Source: "p(95)<100", Muted: null.BoolFrom(true),
+	}))
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, body = serve(http.MethodGet, nil)
+	require.Equal(t, http.StatusOK, code)
+	var list ThresholdsJSONAPI
+	require.NoError(t, json.Unmarshal(body, &list))
+	assert.Equal(t, []Threshold{{
+		Metric: "my_trend{status:500}", Source: "p(95)<100", AbortOnFail: true, OK: true, Muted: null.BoolFrom(true),
+	}}, list.Thresholds())
+
+	changes := cs.MetricsEngine.ThresholdChanges()
+	require.Len(t, changes, 2)
+	assert.Equal(t, "add", changes[0].Action)
+	assert.Equal(t, "mute", changes[1].Action)
+}
@@ -0,0 +1,47 @@
+package v1
+
+import (
+	"time"
+
+	"github.com/liuxd6825/k6server/lib"
+	"github.com/liuxd6825/k6server/lib/types"
+)
+
+// VU is the live state of an initialized VU.
+type VU struct {
+	ID       uint64 `json:"id"`
+	IDGlobal uint64 `json:"idGlobal"`
+	// Active VUs are currently used by the executor of a scenario
+	Active   bool   `json:"active"`
+	Scenario string `json:"scenario,omitempty"`
+	// Iteration is the __ITER of the current or last iteration of the VU, and
+	// ScenarioIteration the number of the iteration of the VU in the scenario
+	Iteration         int64 `json:"iteration"`
+	ScenarioIteration int64 `json:"scenarioIteration"`
+	// IterationDuration is the time spent in the current iteration, it's null
+	// when the VU isn't running an iteration
+	IterationDuration types.NullDuration `json:"iterationDuration"`
+	// InEventLoop is true when the VU waits on the event loop for asynchronous
+	// work, after the exported function of the iteration has returned
+	InEventLoop bool              `json:"inEventLoop"`
+	Tags        map[string]string `json:"tags"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+}
+
+func newVU(info lib.VUInfo, now time.Time) VU {
+	vu := VU{
+		ID:                info.IDLocal,
+		IDGlobal:          info.IDGlobal,
+		Active:            info.Active,
+		Scenario:          info.Scenario,
+		Iteration:         info.Iteration,
+		ScenarioIteration: info.ScenarioIteration,
+		InEventLoop:       info.InEventLoop,
+		Tags:              info.Tags,
+		Metadata:          info.Metadata,
+	}
+	if !info.IterationStart.IsZero() {
+		vu.IterationDuration = types.NullDurationFrom(now.Sub(info.IterationStart))
+	}
+	return vu
+}
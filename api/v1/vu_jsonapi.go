@@ -0,0 +1,54 @@
+package v1
+
+import "strconv"
+
+// VUsJSONAPI is JSON API envelop for all VUs
+type VUsJSONAPI struct {
+	Data []vuData `json:"data"`
+}
+
+// VUJSONAPI is JSON API envelop for a single VU
+type VUJSONAPI struct {
+	Data vuData `json:"data"`
+}
+
+type vuData struct {
+	Type       string `json:"type"`
+	ID         string `json:"id"`
+	Attributes VU     `json:"attributes"`
+}
+
+func newVUData(vu VU) vuData {
+	return vuData{
+		Type:       "vus",
+		ID:         strconv.FormatUint(vu.ID, 10),
+		Attributes: vu,
+	}
+}
+
+// NewVUJSONAPI creates the JSON API envelop for a VU
+func NewVUJSONAPI(vu VU) VUJSONAPI {
+	return VUJSONAPI{Data: newVUData(vu)}
+}
+
+func newVUsJSONAPI(vus []VU) VUsJSONAPI {
+	data := make([]vuData, 0, len(vus))
+	for _, vu := range vus {
+		data = append(data, newVUData(vu))
+	}
+	return VUsJSONAPI{Data: data}
+}
+
+// VU extracts the v1.VU from the JSON API envelop
+func (v VUJSONAPI) VU() VU {
+	return v.Data.Attributes
+}
+
+// VUs extracts the []v1.VU from the JSON API envelop
+func (v VUsJSONAPI) VUs() []VU {
+	list := make([]VU, 0, len(v.Data))
+	for _, d := range v.Data {
+		list = append(list, d.Attributes)
+	}
+	return list
+}
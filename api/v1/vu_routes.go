@@ -0,0 +1,55 @@
+package v1
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/liuxd6825/k6server/lib"
+)
+
+// getVUs returns the state of all the initialized VUs which can be inspected,
+// sorted by their IDs.
+func getVUs(cs *ControlSurface) []VU {
+	now := time.Now()
+	var vus []VU
+	for _, vu := range cs.Scheduler.GetState().GetInitializedVUs() {
+		if inspectable, ok := vu.(lib.InspectableVU); ok {
+			vus = append(vus, newVU(inspectable.GetVUInfo(), now))
+		}
+	}
+	sort.Slice(vus, func(i, j int) bool { return vus[i].ID < vus[j].ID })
+	return vus
+}
+
+func handleGetVUs(cs *ControlSurface, rw http.ResponseWriter, _ *http.Request) {
+	data, err := json.Marshal(newVUsJSONAPI(getVUs(cs)))
+	if err != nil {
+		apiError(rw, "Encoding error", err.Error(), http.StatusInternalServerError)
+		return
+	}
+	_, _ = rw.Write(data)
+}
+
+func handleGetVU(cs *ControlSurface, rw http.ResponseWriter, _ *http.Request, id string) {
+	vuID, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		apiError(rw, "Not Found", "No VU with that ID was found", http.StatusNotFound)
+		return
+	}
+	for _, vu := range getVUs(cs) {
+		if vu.ID != vuID {
+			continue
+		}
+		data, err := json.Marshal(NewVUJSONAPI(vu))
+		if err != nil {
+			apiError(rw, "Encoding error", err.Error(), http.StatusInternalServerError)
+			return
+		}
+		_, _ = rw.Write(data)
+		return
+	}
+	apiError(rw, "Not Found", "No VU with that ID was found", http.StatusNotFound)
+}
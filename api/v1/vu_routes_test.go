@@ -0,0 +1,85 @@
+package v1
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/liuxd6825/k6server/lib"
+	"github.com/liuxd6825/k6server/lib/testutils/minirunner"
+	"github.com/liuxd6825/k6server/lib/types"
+)
+
+type inspectableVU struct {
+	minirunner.VU
+	info lib.VUInfo
+}
+
+func (vu *inspectableVU) GetVUInfo() lib.VUInfo {
+	return vu.info
+}
+
+func TestGetVUs(t *testing.T) {
+	t.Parallel()
+
+	scenarios := lib.ScenarioConfigs{}
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"default": {"executor": "shared-iterations", "iterations": 3, "vus": 3}
+	}`), &scenarios))
+	testState := getTestRunState(t, lib.Options{Scenarios: scenarios}, &minirunner.MiniRunner{})
+	cs := getControlSurface(t, testState)
+	executionState := cs.Scheduler.GetState()
+	executionState.AddInitializedVU(&inspectableVU{
+		VU: minirunner.VU{ID: 2},
+		info: lib.VUInfo{
+			IDLocal: 2, IDGlobal: 12, Active: true, Scenario: "default", Iteration: 5, ScenarioIteration: 5,
+			IterationStart: time.Now().Add(-time.Minute), InEventLoop: true,
+			Tags: map[string]string{"scenario": "default"}, Metadata: map[string]string{"trace_id": "abc"},
+		},
+	})
+	executionState.AddInitializedVU(&inspectableVU{
+		VU:   minirunner.VU{ID: 1},
+		info: lib.VUInfo{IDLocal: 1, IDGlobal: 11, Tags: map[string]string{}},
+	})
+	executionState.AddInitializedVU(&minirunner.VU{ID: 3})
+
+	serve := func(path string) (int, []byte) {
+		rw := httptest.NewRecorder()
+		NewHandler(cs).ServeHTTP(rw, httptest.NewRequest(http.MethodGet, path, nil))
+		return rw.Code, rw.Body.Bytes()
+	}
+
+	code, body := serve("/v1/vus")
+	require.Equal(t, http.StatusOK, code, string(body))
+	var list VUsJSONAPI
+	require.NoError(t, json.Unmarshal(body, &list))
+	require.Len(t, list.Data, 2, "the VUs which can't be inspected aren't listed")
+	assert.Equal(t, "vus", list.Data[0].Type)
+	assert.Equal(t, "1", list.Data[0].ID)
+
+	vus := list.VUs()
+	assert.Equal(t, VU{ID: 1, IDGlobal: 11, Tags: map[string]string{}}, vus[0])
+	assert.True(t, vus[1].IterationDuration.Valid)
+	assert.GreaterOrEqual(t, vus[1].IterationDuration.TimeDuration(), time.Minute)
+	vus[1].IterationDuration = types.NullDuration{}
+	assert.Equal(t, VU{
+		ID: 2, IDGlobal: 12, Active: true, Scenario: "default", Iteration: 5, ScenarioIteration: 5, InEventLoop: true,
+		Tags: map[string]string{"scenario": "default"}, Metadata: map[string]string{"trace_id": "abc"},
+	}, vus[1])
+
+	code, body = serve("/v1/vus/1")
+	require.Equal(t, http.StatusOK, code, string(body))
+	var doc VUJSONAPI
+	require.NoError(t, json.Unmarshal(body, &doc))
+	assert.Equal(t, vus[0], doc.VU())
+
+	code, _ = serve("/v1/vus/3")
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _ = serve("/v1/vus/x")
+	assert.Equal(t, http.StatusNotFound, code)
+}
@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"gopkg.in/guregu/null.v3"
+
+	v1 "github.com/liuxd6825/k6server/api/v1"
+	"github.com/liuxd6825/k6server/cmd/state"
+	"github.com/liuxd6825/k6server/execution"
+	"github.com/liuxd6825/k6server/execution/distributed"
+	"github.com/liuxd6825/k6server/output"
+)
+
+// cmdAgent handles the `k6 agent` sub-command
+type cmdAgent struct {
+	gs *state.GlobalState
+}
+
+func (c *cmdAgent) run(cmd *cobra.Command, args []string) (err error) {
+	// The test options are the ones of the archive sent by the coordinator,
+	// so all of the instances run the same test.
+	var changed []string
+	optionFlagSet().VisitAll(func(f *pflag.Flag) {
+		if cmd.Flags().Changed(f.Name) {
+			changed = append(changed, "--"+f.Name)
+		}
+	})
+	if len(changed) > 0 {
+		return fmt.Errorf("the test options are set on the coordinator, %v can't be used with agents", changed)
+	}
+
+	logger := c.gs.Logger
+	conn, err := grpc.NewClient(args[0], grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+	client := distributed.NewDistributedTestClient(conn)
+
+	logger.Debugf("Registering with the coordinator at %s...", args[0])
+	resp, err := client.Register(c.gs.Ctx, &distributed.RegisterRequest{}, grpc.WaitForReady(true))
+	if err != nil {
+		return fmt.Errorf("couldn't register with the coordinator at %s: %w", args[0], err)
+	}
+	logger.Infof("Registered as the instance %d of %d", resp.InstanceID, resp.InstanceCount)
+
+	controller, err := distributed.NewAgentController(c.gs.Ctx, resp.InstanceID, client, logger)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := controller.Close(); cerr != nil {
+			logger.WithError(cerr).Debug("Couldn't close the connection to the coordinator")
+		}
+	}()
+
+	// The archive is run like `k6 run -` would run it from stdin.
+	gs := *c.gs
+	gs.Stdin = bytes.NewReader(resp.Archive)
+	runCmd := newCmdRun(&gs)
+	runCmd.loadConfiguredTest = func(
+		cmd *cobra.Command, args []string,
+	) (*loadedAndConfiguredTest, execution.Controller, error) {
+		test, err := loadAndConfigureLocalTest(&gs, cmd, args, getConfig)
+		if err != nil {
+			return nil, nil, err
+		}
+		// The thresholds are evaluated by the coordinator, on the metrics
+		// of all of the instances, which are sent to it.
+		test.preInitState.RuntimeOptions.NoThresholds = null.BoolFrom(true)
+		runCmd.extraOutputs = []output.Output{distributed.NewMetricsPusher(
+			controller, test.preInitState.Registry, test.derivedConfig.Thresholds,
+		)}
+		return test, controller, nil
+	}
+	runCmd.testRunInitialized = func(cs *v1.ControlSurface) {
+		go func() {
+			select {
+			case <-controller.Aborted():
+				execution.AbortTestRun(cs.RunCtx, controller.AbortError())
+			case <-cs.RunCtx.Done():
+			}
+		}()
+	}
+	return runCmd.run(cmd, []string{"-"})
+}
+
+func (c *cmdAgent) flagSet() *pflag.FlagSet {
+	flags := pflag.NewFlagSet("", pflag.ContinueOnError)
+	flags.SortFlags = false
+	options := optionFlagSet()
+	options.VisitAll(func(f *pflag.Flag) {
+		f.Hidden = true
+	})
+	flags.AddFlagSet(options)
+	flags.AddFlagSet(runtimeOptionFlagSet(true))
+	flags.AddFlagSet(configFlagSet())
+	return flags
+}
+
+func getCmdAgent(gs *state.GlobalState) *cobra.Command {
+	c := &cmdAgent{gs: gs}
+
+	exampleText := getExampleText(gs, `
+  # Run an instance of the test of a coordinator.
+  {{.}} agent coordinator.example.com:6566
+
+  # And send its metrics to an InfluxDB server.
+  {{.}} agent -o influxdb=http://1.2.3.4:8086/k6 coordinator.example.com:6566`[1:])
+
+	agentCmd := &cobra.Command{
+		Use:   "agent",
+		Short: "Run an instance of a distributed test",
+		Long: `Run an instance of a distributed test.
+
+The agent registers with the coordinator of the test run, which sends it the
+test and its execution segment, and then it runs the test like k6 run does,
+synchronized with the other instances. Its metrics are sent to the coordinator,
+which evaluates the thresholds of the test on the ones of all of the instances.`,
+		Example: exampleText,
+		Args:    exactArgsWithMsg(1, "arg should be the address of the coordinator"),
+		RunE:    c.run,
+	}
+
+	agentCmd.Flags().SortFlags = false
+	agentCmd.Flags().AddFlagSet(c.flagSet())
+
+	return agentCmd
+}
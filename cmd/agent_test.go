@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"bytes"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	"github.com/liuxd6825/k6server/cmd/tests"
+	"github.com/liuxd6825/k6server/execution/distributed"
+	"github.com/liuxd6825/k6server/lib"
+	"github.com/liuxd6825/k6server/lib/testutils"
+)
+
+func TestDistributedTestRun(t *testing.T) {
+	t.Parallel()
+
+	script := `
+		export function setup() { console.log('setup'); return { value: 42 }; }
+		export default function(data) {
+			if (data.value !== 42) { throw new Error('unexpected setup data'); }
+		}
+		export function teardown() { console.log('teardown'); }
+	`
+	arc, err := lib.ReadArchive(bytes.NewReader(getTestArchive(t, script,
+		`{"scenarios": {"test": {"executor": "shared-iterations", "vus": 2, "iterations": 10}}}`)))
+	require.NoError(t, err)
+	coordinator, err := distributed.NewCoordinator(arc, 2, testutils.NewLogger(t))
+	require.NoError(t, err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	grpcSrv := grpc.NewServer()
+	distributed.RegisterDistributedTestServer(grpcSrv, coordinator)
+	go func() { _ = grpcSrv.Serve(listener) }()
+	t.Cleanup(grpcSrv.Stop)
+
+	agents := []*tests.GlobalTestState{tests.NewGlobalTestState(t), tests.NewGlobalTestState(t)}
+	var wg sync.WaitGroup
+	for _, ts := range agents {
+		ts.CmdArgs = []string{"k6", "agent", "--address", "", listener.Addr().String()}
+		wg.Add(1)
+		go func(ts *tests.GlobalTestState) {
+			defer wg.Done()
+			newRootCommand(ts.GlobalState).execute()
+		}(ts)
+	}
+	wg.Wait()
+	<-coordinator.Done()
+	require.NoError(t, coordinator.Err())
+
+	var setups, teardowns int
+	for _, ts := range agents {
+		stdout := ts.Stdout.String()
+		assert.Contains(t, stdout, "execution: distributed")
+		assert.Contains(t, stdout, "(50.00%) 1 scenario")
+		assert.Contains(t, stdout, "iterations...........: 5")
+		for _, line := range ts.LoggerHook.Drain() {
+			setups += strings.Count(line.Message, "setup")
+			teardowns += strings.Count(line.Message, "teardown")
+		}
+	}
+	assert.Equal(t, 1, setups)
+	assert.Equal(t, 1, teardowns)
+}
+
+func TestAgentOptionFlags(t *testing.T) {
+	t.Parallel()
+
+	ts := tests.NewGlobalTestState(t)
+	ts.CmdArgs = []string{"k6", "agent", "--vus", "10", "localhost:6566"}
+	ts.ExpectedExitCode = -1
+	newRootCommand(ts.GlobalState).execute()
+	assert.True(t, testutils.LogContains(ts.LoggerHook.Drain(), logrus.ErrorLevel,
+		"the test options are set on the coordinator, [--vus] can't be used with agents"))
+}
+
+func TestCoordinatorInstanceCount(t *testing.T) {
+	t.Parallel()
+
+	ts := tests.NewGlobalTestState(t)
+	require.NoError(t, ts.FS.MkdirAll("/test", 0o755))
+	ts.Stdin = bytes.NewBufferString(`export default function() {}`)
+	ts.CmdArgs = []string{"k6", "coordinator", "--instance-count", "0", "-"}
+	ts.ExpectedExitCode = -1
+	newRootCommand(ts.GlobalState).execute()
+	assert.True(t, testutils.LogContains(ts.LoggerHook.Drain(), logrus.ErrorLevel,
+		"the instance count should be at least 1, not 0"))
+}
@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+
+	"github.com/spf13/pflag"
+
+	"github.com/liuxd6825/k6server/api"
+	"github.com/liuxd6825/k6server/api/v1/client"
+	"github.com/liuxd6825/k6server/cmd/state"
+	"github.com/liuxd6825/k6server/lib/fsext"
+	"github.com/liuxd6825/k6server/log"
+)
+
+// getAPISecurity returns the security settings of the REST API server from
+// the global flags.
+func getAPISecurity(gs *state.GlobalState) (api.Security, error) {
+	flags := gs.Flags
+	sec := api.Security{Token: flags.APIToken, ReadOnly: flags.APIReadOnly}
+	if flags.APITLSCert == "" && flags.APITLSKey == "" {
+		if flags.APITLSClientCA != "" {
+			return sec, errors.New("--api-tls-client-ca needs the certificate of the server, set with --api-tls-cert")
+		}
+		return sec, nil
+	}
+
+	var keyPEM, clientCAsPEM []byte
+	certPEM, err := fsext.ReadFile(gs.FS, flags.APITLSCert)
+	if err == nil {
+		keyPEM, err = fsext.ReadFile(gs.FS, flags.APITLSKey)
+	}
+	if err == nil && flags.APITLSClientCA != "" {
+		clientCAsPEM, err = fsext.ReadFile(gs.FS, flags.APITLSClientCA)
+	}
+	if err != nil {
+		return sec, fmt.Errorf("couldn't read the TLS settings of the REST API: %w", err)
+	}
+	tlsConfig, err := api.NewTLSConfig(certPEM, keyPEM, clientCAsPEM)
+	if err != nil {
+		return sec, fmt.Errorf("invalid TLS settings of the REST API: %w", err)
+	}
+	sec.TLSConfig = tlsConfig
+	return sec, nil
+}
+
+// addAPILogBuffer adds the hook which keeps the recent log entries for the
+// REST API to the logger, unless the REST API or the buffer is disabled.
+func addAPILogBuffer(gs *state.GlobalState) *log.Buffer {
+	if gs.Flags.Address == "" || gs.Flags.APILogBuffer <= 0 {
+		return nil
+	}
+	logs := log.NewBuffer(gs.Flags.APILogBuffer)
+	gs.Logger.AddHook(logs)
+	return logs
+}
+
+// apiClientFlags are the flags of the commands which are clients of the REST
+// API, besides the global --address and --api-token ones.
+type apiClientFlags struct {
+	tls      bool
+	caFile   string
+	certFile string
+	keyFile  string
+}
+
+func (f *apiClientFlags) flagSet() *pflag.FlagSet {
+	flags := pflag.NewFlagSet("", pflag.ContinueOnError)
+	flags.SortFlags = false
+	flags.BoolVar(&f.tls, "api-tls", false, "connect to the REST API server with HTTPS")
+	flags.StringVar(&f.caFile, "api-tls-ca", "",
+		"CA certificates the REST API server is verified with, instead of the system ones, implies --api-tls")
+	flags.StringVar(&f.certFile, "api-client-cert", "",
+		"TLS certificate of the client, for the REST API servers which require one, implies --api-tls")
+	flags.StringVar(&f.keyFile, "api-client-key", "", "key of the TLS certificate of the client")
+	return flags
+}
+
+// newAPIClient returns the client of the REST API at the global --address.
+func newAPIClient(gs *state.GlobalState, f *apiClientFlags) (*client.Client, error) {
+	options := []client.Option{client.WithToken(gs.Flags.APIToken)}
+	if f.tls || f.caFile != "" || f.certFile != "" || f.keyFile != "" {
+		tlsConfig, err := f.tlsConfig(gs)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, client.WithTLSConfig(tlsConfig))
+	}
+	return client.New(gs.Flags.Address, options...)
+}
+
+func (f *apiClientFlags) tlsConfig(gs *state.GlobalState) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if f.caFile != "" {
+		pem, err := fsext.ReadFile(gs.FS, f.caFile)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read the CAs of the REST API server: %w", err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("there are no certificates in '%s'", f.caFile)
+		}
+	}
+	if f.certFile != "" || f.keyFile != "" {
+		certPEM, err := fsext.ReadFile(gs.FS, f.certFile)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read the TLS certificate of the client: %w", err)
+		}
+		keyPEM, err := fsext.ReadFile(gs.FS, f.keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read the key of the TLS certificate of the client: %w", err)
+		}
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TLS certificate of the client: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
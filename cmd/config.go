@@ -31,6 +31,7 @@ func configFlagSet() *pflag.FlagSet {
 	flags.StringArrayP("out", "o", []string{}, "`uri` for an external metrics database")
 	flags.BoolP("linger", "l", false, "keep the API server alive past test end")
 	flags.Bool("no-usage-report", false, "don't send anonymous stats to the developers")
+	flags.Bool("history", false, "record the results of the test run in the history, see `k6 history`")
 	return flags
 }
 
@@ -42,6 +43,7 @@ type Config struct {
 	Linger        null.Bool `json:"linger" envconfig:"K6_LINGER"`
 	NoUsageReport null.Bool `json:"noUsageReport" envconfig:"K6_NO_USAGE_REPORT"`
 	WebDashboard  null.Bool `json:"webDashboard" envconfig:"K6_WEB_DASHBOARD"`
+	History       null.Bool `json:"history" envconfig:"K6_HISTORY"`
 
 	// TODO: deprecate
 	Collectors map[string]json.RawMessage `json:"collectors"`
@@ -71,6 +73,9 @@ func (c Config) Apply(cfg Config) Config {
 	if cfg.WebDashboard.Valid {
 		c.WebDashboard = cfg.WebDashboard
 	}
+	if cfg.History.Valid {
+		c.History = cfg.History
+	}
 	if len(cfg.Collectors) > 0 {
 		c.Collectors = cfg.Collectors
 	}
@@ -102,6 +107,7 @@ func getConfig(flags *pflag.FlagSet) (Config, error) {
 		Out:           out,
 		Linger:        getNullBool(flags, "linger"),
 		NoUsageReport: getNullBool(flags, "no-usage-report"),
+		History:       getNullBool(flags, "history"),
 	}, nil
 }
 
@@ -265,9 +271,14 @@ func consolidateErrorMessage(errList []error, title string) error {
 }
 
 func validateScenarioConfig(conf lib.ExecutorConfig, isExecutable func(string) bool) error {
-	execFn := conf.GetExec()
-	if !isExecutable(execFn) {
-		return fmt.Errorf("executor %s: function '%s' not found in exports", conf.GetName(), execFn)
+	execFns := []string{conf.GetExec()}
+	if mconf, ok := conf.(lib.MultiExecExecutorConfig); ok {
+		execFns = append(execFns, mconf.GetExecs()...)
+	}
+	for _, execFn := range execFns {
+		if !isExecutable(execFn) {
+			return fmt.Errorf("executor %s: function '%s' not found in exports", conf.GetName(), execFn)
+		}
 	}
 	return nil
 }
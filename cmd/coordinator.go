@@ -0,0 +1,225 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"google.golang.org/grpc"
+
+	"github.com/liuxd6825/k6server/cmd/state"
+	"github.com/liuxd6825/k6server/errext"
+	"github.com/liuxd6825/k6server/errext/exitcodes"
+	"github.com/liuxd6825/k6server/execution/distributed"
+	"github.com/liuxd6825/k6server/lib"
+	"github.com/liuxd6825/k6server/metrics/engine"
+)
+
+const defaultCoordinatorListenAddress = ":6566"
+
+// cmdCoordinator handles the `k6 coordinator` sub-command
+type cmdCoordinator struct {
+	gs            *state.GlobalState
+	grpcListen    string
+	instanceCount int
+}
+
+func (c *cmdCoordinator) run(cmd *cobra.Command, args []string) error {
+	logger := c.gs.Logger
+	printBanner(c.gs)
+
+	test, err := loadAndConfigureLocalTest(c.gs, cmd, args, getPartialConfig)
+	if err != nil {
+		return err
+	}
+
+	// Like for `k6 archive`, only the consolidated options are set back to
+	// the runner, since the agents derive the rest of them.
+	testRunState, err := test.buildTestRunState(test.consolidatedConfig.Options)
+	if err != nil {
+		return err
+	}
+	coordinator, err := distributed.NewCoordinator(testRunState.Runner.MakeArchive(), c.instanceCount, logger)
+	if err != nil {
+		return err
+	}
+
+	// The metrics of the instances are aggregated, and the thresholds are
+	// evaluated on them, like if the test was run by a single instance.
+	metricsEngine, err := engine.NewMetricsEngine(testRunState.Registry, logger)
+	if err != nil {
+		return err
+	}
+	noThresholds := testRunState.RuntimeOptions.NoThresholds.Bool
+	if err = metricsEngine.InitSubMetricsAndThresholds(test.derivedConfig.Options, noThresholds); err != nil {
+		return err
+	}
+	aggregator := distributed.NewMetricsAggregator(testRunState.Registry, metricsEngine, logger)
+	coordinator.SetMetricsAggregator(aggregator)
+	var finalizeThresholds func() []string
+	if !noThresholds {
+		finalizeThresholds = metricsEngine.StartThresholdCalculations(
+			nil, coordinator.Abort, aggregator.TestRunDuration,
+		)
+	}
+
+	listener, err := net.Listen("tcp", c.grpcListen)
+	if err != nil {
+		return fmt.Errorf("couldn't listen for gRPC on %s: %w", c.grpcListen, err)
+	}
+	grpcSrv := grpc.NewServer()
+	distributed.RegisterDistributedTestServer(grpcSrv, coordinator)
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- grpcSrv.Serve(listener)
+	}()
+	defer func() {
+		stopped := make(chan struct{})
+		go func() {
+			grpcSrv.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-time.After(5 * time.Second):
+			logger.Debug("The gRPC server did not shut down gracefully")
+			grpcSrv.Stop()
+		}
+	}()
+
+	stopReceived := make(chan struct{})
+	stopSignalHandling := handleTestAbortSignals(c.gs, func(sig os.Signal) {
+		logger.WithField("sig", sig).Debug("Stopping the coordinator in response to signal...")
+		close(stopReceived)
+	}, nil)
+	defer stopSignalHandling()
+
+	c.printDescription(args[0], listener.Addr().String())
+
+	select {
+	case <-coordinator.Done():
+	case err = <-serveErr:
+		return err
+	case <-stopReceived:
+		// The agents abort the test run when they lose the connection.
+		grpcSrv.Stop()
+		return errext.WithExitCodeIfNone(errors.New("the coordinator was stopped"), exitcodes.ExternalAbort)
+	}
+
+	var breachedThresholds []string
+	if finalizeThresholds != nil {
+		logger.Debug("Finalizing thresholds...")
+		breachedThresholds = finalizeThresholds()
+	}
+	if !testRunState.RuntimeOptions.NoSummary.Bool {
+		c.handleSummary(test, metricsEngine, aggregator)
+	}
+
+	if err = coordinator.Err(); err != nil {
+		return fmt.Errorf("the distributed test run failed: %w", err)
+	}
+	if len(breachedThresholds) > 0 {
+		return errext.WithAbortReasonIfNone(
+			errext.WithExitCodeIfNone(
+				fmt.Errorf("thresholds on metrics '%s' have been crossed", strings.Join(breachedThresholds, ", ")),
+				exitcodes.ThresholdsHaveFailed,
+			), errext.AbortedByThresholdsAfterTestEnd)
+	}
+	logger.Info("All of the instances finished the test run")
+	return nil
+}
+
+// handleSummary generates the end-of-test summary of the metrics and checks
+// of all of the instances.
+func (c *cmdCoordinator) handleSummary(
+	test *loadedAndConfiguredTest, metricsEngine *engine.MetricsEngine, aggregator *distributed.MetricsAggregator,
+) {
+	logger := c.gs.Logger
+	logger.Debug("Generating the end-of-test summary...")
+	rootGroup, err := aggregator.RootGroup()
+	if err != nil {
+		logger.WithError(err).Error("failed to aggregate the checks of the instances")
+		return
+	}
+	summaryResult, err := test.initRunner.HandleSummary(c.gs.Ctx, &lib.Summary{
+		Metrics:         metricsEngine.ObservedMetrics,
+		RootGroup:       rootGroup,
+		TestRunDuration: aggregator.TestRunDuration(),
+		NoColor:         c.gs.Flags.NoColor,
+		UIState: lib.UIState{
+			IsStdOutTTY: c.gs.Stdout.IsTTY,
+			IsStdErrTTY: c.gs.Stderr.IsTTY,
+		},
+	})
+	if err == nil {
+		err = handleSummaryResult(c.gs.FS, c.gs.Stdout, c.gs.Stderr, summaryResult)
+	}
+	if err != nil {
+		logger.WithError(err).Error("failed to handle the end-of-test summary")
+	}
+}
+
+// printDescription prints the settings of the distributed test run.
+func (c *cmdCoordinator) printDescription(filename, address string) {
+	gs := c.gs
+	valueColor := getColor(gs.Flags.NoColor || !gs.Stdout.IsTTY, color.FgCyan)
+
+	buf := &strings.Builder{}
+	fmt.Fprintf(buf, "     execution: %s\n", valueColor.Sprint("distributed"))
+	fmt.Fprintf(buf, "        script: %s\n", valueColor.Sprint(filename))
+	fmt.Fprintf(buf, "     instances: %s\n", valueColor.Sprint(c.instanceCount))
+	fmt.Fprintf(buf, "   grpc listen: %s\n\n", valueColor.Sprint(address))
+	fmt.Fprintf(buf, "Waiting for %d agent(s) to run the test with `%s agent %s`.\n",
+		c.instanceCount, gs.BinaryName, address)
+
+	printToStdout(gs, buf.String())
+}
+
+func (c *cmdCoordinator) flagSet() *pflag.FlagSet {
+	flags := pflag.NewFlagSet("", pflag.ContinueOnError)
+	flags.SortFlags = false
+	flags.AddFlagSet(optionFlagSet())
+	flags.AddFlagSet(runtimeOptionFlagSet(false))
+	flags.StringVar(&c.grpcListen, "grpc-listen", defaultCoordinatorListenAddress,
+		"`address` the agents connect to")
+	flags.IntVar(&c.instanceCount, "instance-count", 1, "number of agents that run the test")
+	return flags
+}
+
+func getCmdCoordinator(gs *state.GlobalState) *cobra.Command {
+	c := &cmdCoordinator{gs: gs}
+
+	exampleText := getExampleText(gs, `
+  # Split a test between 3 agents.
+  {{.}} coordinator --instance-count 3 -u 300 -d 5m script.js
+
+  # And run one of its instances on each load generator.
+  {{.}} agent coordinator.example.com:6566`[1:])
+
+	coordinatorCmd := &cobra.Command{
+		Use:   "coordinator",
+		Short: "Coordinate a distributed test run",
+		Long: `Coordinate a distributed test run.
+
+The test is split into execution segments, one for each of the agents that
+register with the coordinator, which synchronizes them during the test run.
+The metrics of the agents are aggregated by the coordinator, which evaluates
+the thresholds of the test on them, aborts the test run of all of the agents
+when one with abortOnFail fails, and shows the end-of-test summary of the whole
+test run once all of the agents have finished.`,
+		Example: exampleText,
+		Args:    exactArgsWithMsg(1, "arg should either be \"-\", if reading script from stdin, or a path to a script file"),
+		RunE:    c.run,
+	}
+
+	coordinatorCmd.Flags().SortFlags = false
+	coordinatorCmd.Flags().AddFlagSet(c.flagSet())
+
+	return coordinatorCmd
+}
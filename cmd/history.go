@@ -0,0 +1,225 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/liuxd6825/k6server/cmd/state"
+	"github.com/liuxd6825/k6server/errext"
+	"github.com/liuxd6825/k6server/history"
+	"github.com/liuxd6825/k6server/js"
+	"github.com/liuxd6825/k6server/lib"
+	"github.com/liuxd6825/k6server/lib/consts"
+)
+
+// recordTestRun records the results of a test run, which ended with runErr, in
+// the history.
+func recordTestRun(
+	gs *state.GlobalState, test *loadedAndConfiguredTest, started time.Time,
+	summary *lib.Summary, recorder *history.Recorder, runErr error,
+) error {
+	options, err := json.Marshal(test.derivedConfig.Options)
+	if err != nil {
+		return err
+	}
+	summaryData, err := json.Marshal(js.SummarizeMetrics(summary, test.derivedConfig.Options))
+	if err != nil {
+		return err
+	}
+
+	record := &history.Record{
+		Script:    test.source.URL.String(),
+		K6Version: consts.Version,
+		Started:   started,
+		Ended:     time.Now(),
+		Options:   options,
+		Tags:      test.derivedConfig.RunTags,
+		Summary:   summaryData,
+		Intervals: recorder.Intervals(),
+	}
+	for name, m := range summary.Metrics {
+		for _, t := range m.Thresholds.Thresholds {
+			record.Thresholds = append(record.Thresholds, history.Threshold{
+				Metric: name, Source: t.Source, OK: !t.LastFailed,
+			})
+		}
+	}
+	sort.SliceStable(record.Thresholds, func(i, j int) bool {
+		return record.Thresholds[i].Metric < record.Thresholds[j].Metric
+	})
+	if runErr != nil {
+		record.ExitCode = -1
+		var ecerr errext.HasExitCode
+		if errors.As(runErr, &ecerr) {
+			record.ExitCode = int(ecerr.ExitCode())
+		}
+		record.Error, _ = errext.Format(runErr)
+	}
+
+	store := history.NewStore(gs.FS, gs.Flags.HistoryDir)
+	if err := store.Save(record); err != nil {
+		return fmt.Errorf("couldn't record the test run in '%s': %w", store.Dir(), err)
+	}
+	gs.Logger.Debugf("Recorded the test run %s in '%s'", record.ID, store.Dir())
+	return nil
+}
+
+func getCmdHistory(gs *state.GlobalState) *cobra.Command {
+	historyCmd := &cobra.Command{
+		Use:   "history",
+		Short: "List and compare the recorded test runs",
+		Long: `List and compare the recorded test runs.
+
+The test runs are recorded with "k6 run --history", or the K6_HISTORY environment
+variable, in the directory of the global --history-dir flag. Their records have
+the options, the outcome of the thresholds, the end-of-test summary and the
+metrics aggregated in intervals of ` + history.DefaultInterval.String() + `.`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return cmd.Usage()
+		},
+	}
+	historyCmd.AddCommand(
+		getCmdHistoryList(gs),
+		getCmdHistoryDiff(gs),
+	)
+	return historyCmd
+}
+
+func getCmdHistoryList(gs *state.GlobalState) *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List the recorded test runs",
+		Args:  cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			records, err := history.NewStore(gs.FS, gs.Flags.HistoryDir).List()
+			if err != nil {
+				return err
+			}
+			if len(records) == 0 {
+				printToStdout(gs, fmt.Sprintf("No test runs are recorded in '%s'\n", gs.Flags.HistoryDir))
+				return nil
+			}
+
+			w := tabwriter.NewWriter(gs.Stdout, 0, 0, 2, ' ', 0)
+			_, _ = fmt.Fprintln(w, "ID\tSTARTED\tDURATION\tEXIT CODE\tTHRESHOLDS\tSCRIPT")
+			for _, r := range records {
+				thresholds := "-"
+				if len(r.Thresholds) > 0 {
+					thresholds = "passed"
+					if !r.ThresholdsPassed() {
+						thresholds = "failed"
+					}
+				}
+				_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\n",
+					r.ID, r.Started.Local().Format(time.DateTime), r.Ended.Sub(r.Started).Round(time.Second),
+					r.ExitCode, thresholds, r.Script)
+			}
+			return w.Flush()
+		},
+	}
+}
+
+func getCmdHistoryDiff(gs *state.GlobalState) *cobra.Command {
+	var alpha float64
+	diffCmd := &cobra.Command{
+		Use:   "diff <base-id> <id>",
+		Short: "Compare a recorded test run with a previous one",
+		Long: `Compare a recorded test run with a previous one.
+
+The changes of the trend metrics, like http_req_duration, are tested for
+significance with Welch's t-test, over their values in the intervals of the
+runs. The ones of the rate metrics, like checks, are tested with a
+two-proportion z-test. The significant changes are flagged as regressions when
+the trends increase, when the rates increase, except for checks, where fewer
+of them passing is a regression.`,
+		Example: `
+  # Compare the test run 4 with the test run 3
+  k6 history diff 3 4`[1:],
+		Args: exactArgsWithMsg(2, "arg should be the IDs of the two test runs"),
+		RunE: func(_ *cobra.Command, args []string) error {
+			if alpha <= 0 || alpha >= 1 {
+				return fmt.Errorf("the significance level should be between 0 and 1, not %g", alpha)
+			}
+			store := history.NewStore(gs.FS, gs.Flags.HistoryDir)
+			base, err := store.Load(args[0])
+			if err != nil {
+				return err
+			}
+			next, err := store.Load(args[1])
+			if err != nil {
+				return err
+			}
+			changes, err := history.Diff(base, next, alpha)
+			if err != nil {
+				return err
+			}
+			printHistoryDiff(gs, base, next, changes)
+			return nil
+		},
+	}
+	diffCmd.Flags().Float64Var(&alpha, "alpha", history.DefaultAlpha, "significance level of the regressions")
+	return diffCmd
+}
+
+func printHistoryDiff(gs *state.GlobalState, base, next *history.Record, changes []history.Change) {
+	noColor := gs.Flags.NoColor || !gs.Stdout.IsTTY
+	red, green := getColor(noColor, color.FgRed), getColor(noColor, color.FgGreen)
+
+	printToStdout(gs, fmt.Sprintf("Comparing the test run %s (%s) with the test run %s (%s)\n\n",
+		next.ID, next.Script, base.ID, base.Script))
+
+	regressions := 0
+	w := tabwriter.NewWriter(gs.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "METRIC\tSTAT\tBASE\tNEW\tCHANGE\tP-VALUE\t")
+	for _, c := range changes {
+		pValue, flag := "-", ""
+		if !math.IsNaN(c.PValue) {
+			pValue = strconv.FormatFloat(c.PValue, 'f', 4, 64)
+		}
+		switch {
+		case c.Regression:
+			regressions++
+			flag = red.Sprint("regression")
+		case c.Improvement:
+			flag = green.Sprint("improvement")
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", c.Metric, c.Stat,
+			formatHistoryValue(c.Base), formatHistoryValue(c.New), formatRelativeChange(c.Relative()), pValue, flag)
+	}
+	_ = w.Flush()
+
+	if regressions == 0 {
+		printToStdout(gs, "\nNo significant regressions were found\n")
+		return
+	}
+	printToStdout(gs, red.Sprintf("\n%d significant regression(s) were found\n", regressions))
+}
+
+func formatHistoryValue(v float64) string {
+	if v == math.Trunc(v) {
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	if math.Abs(v) < 1 {
+		return strconv.FormatFloat(v, 'g', 3, 64)
+	}
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
+
+func formatRelativeChange(r float64) string {
+	switch {
+	case math.IsInf(r, 0):
+		return "+inf"
+	case r == 0:
+		return "0%"
+	}
+	return fmt.Sprintf("%+.2f%%", r*100)
+}
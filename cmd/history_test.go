@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/liuxd6825/k6server/cmd/tests"
+	"github.com/liuxd6825/k6server/errext/exitcodes"
+	"github.com/liuxd6825/k6server/history"
+	"github.com/liuxd6825/k6server/lib/testutils"
+)
+
+func saveTestRecords(t *testing.T, ts *tests.GlobalTestState) {
+	t.Helper()
+
+	started := time.Date(2023, time.March, 1, 12, 0, 0, 0, time.Local)
+	store := history.NewStore(ts.FS, ts.Flags.HistoryDir)
+	for i, p95 := range []float64{100, 150} {
+		r := &history.Record{
+			Script:     "file:///test/script.js",
+			Started:    started.Add(time.Duration(i) * time.Hour),
+			Ended:      started.Add(time.Duration(i)*time.Hour + time.Minute),
+			Thresholds: []history.Threshold{{Metric: "http_req_duration", Source: "p(95)<120", OK: p95 < 120}},
+		}
+		if p95 >= 120 {
+			r.ExitCode = int(exitcodes.ThresholdsHaveFailed)
+		}
+		summary, err := json.Marshal(map[string]interface{}{"metrics": map[string]interface{}{
+			"http_req_duration": map[string]interface{}{"type": "trend", "values": map[string]float64{"p(95)": p95}},
+		}})
+		require.NoError(t, err)
+		r.Summary = summary
+		for _, offset := range []float64{-2, 1, -1, 2, 0} {
+			r.Intervals = append(r.Intervals, history.Interval{Metrics: map[string]map[string]float64{
+				"http_req_duration": {"p(95)": p95 + offset},
+			}})
+		}
+		require.NoError(t, store.Save(r))
+	}
+}
+
+func TestHistoryList(t *testing.T) {
+	t.Parallel()
+
+	ts := tests.NewGlobalTestState(t)
+	ts.CmdArgs = []string{"k6", "history", "list"}
+	newRootCommand(ts.GlobalState).execute()
+	assert.Contains(t, ts.Stdout.String(), "No test runs are recorded")
+
+	ts = tests.NewGlobalTestState(t)
+	saveTestRecords(t, ts)
+	ts.CmdArgs = []string{"k6", "history", "list"}
+	newRootCommand(ts.GlobalState).execute()
+
+	lines := ts.Stdout.String()
+	assert.Contains(t, lines, "ID  STARTED              DURATION  EXIT CODE  THRESHOLDS  SCRIPT\n")
+	assert.Contains(t, lines, "1   2023-03-01 12:00:00  1m0s      0          passed      file:///test/script.js\n")
+	assert.Contains(t, lines, "2   2023-03-01 13:00:00  1m0s      99         failed      file:///test/script.js\n")
+}
+
+func TestHistoryDiff(t *testing.T) {
+	t.Parallel()
+
+	ts := tests.NewGlobalTestState(t)
+	saveTestRecords(t, ts)
+	ts.CmdArgs = []string{"k6", "history", "diff", "1", "2"}
+	newRootCommand(ts.GlobalState).execute()
+
+	stdout := ts.Stdout.String()
+	assert.Contains(t, stdout, "Comparing the test run 2 (file:///test/script.js) with the test run 1")
+	assert.Regexp(t, `http_req_duration\s+p\(95\)\s+100\s+150\s+\+50\.00%\s+0\.0000\s+regression`, stdout)
+	assert.Contains(t, stdout, "1 significant regression(s) were found")
+
+	ts = tests.NewGlobalTestState(t)
+	saveTestRecords(t, ts)
+	ts.CmdArgs = []string{"k6", "history", "diff", "2", "1"}
+	newRootCommand(ts.GlobalState).execute()
+	assert.Regexp(t, `-33\.33%\s+0\.0000\s+improvement`, ts.Stdout.String())
+	assert.Contains(t, ts.Stdout.String(), "No significant regressions were found")
+}
+
+func TestHistoryDiffErrors(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name, err string
+		args      []string
+	}{
+		{name: "missing run", args: []string{"1", "3"}, err: "no recorded test run with that ID was found: 3"},
+		{name: "one run", args: []string{"1"}, err: "accepts 2 arg(s), received 1"},
+		{name: "invalid alpha", args: []string{"1", "2", "--alpha", "2"}, err: "the significance level should be between 0 and 1"},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ts := tests.NewGlobalTestState(t)
+			saveTestRecords(t, ts)
+			ts.CmdArgs = append([]string{"k6", "history", "diff"}, tc.args...)
+			ts.ExpectedExitCode = -1
+			newRootCommand(ts.GlobalState).execute()
+			assert.True(t, testutils.LogContains(ts.LoggerHook.Drain(), logrus.ErrorLevel, tc.err))
+		})
+	}
+}
@@ -78,16 +78,50 @@ func inspectOutputWithExecRequirements(
 		return nil, err
 	}
 
-	executionPlan := configuredTest.derivedConfig.Scenarios.GetFullExecutionRequirements(et)
+	scenarios := configuredTest.derivedConfig.Scenarios
+	executionPlan := scenarios.GetFullExecutionRequirements(et)
 	duration, _ := lib.GetEndOffset(executionPlan)
 
 	return struct {
 		lib.Options
 		TotalDuration types.NullDuration `json:"totalDuration"`
 		MaxVUs        uint64             `json:"maxVUs"`
+		Timeline      []scenarioTimeline `json:"timeline"`
 	}{
 		configuredTest.derivedConfig.Options,
 		types.NewNullDuration(duration, true),
 		lib.GetMaxPossibleVUs(executionPlan),
+		getScenariosTimeline(scenarios, et),
 	}, nil
 }
+
+// scenarioTimeline is when a scenario is planned to start and to end,
+// including its graceful stop, relative to the beginning of the test.
+type scenarioTimeline struct {
+	Scenario  string             `json:"scenario"`
+	StartTime types.NullDuration `json:"startTime"`
+	EndTime   types.NullDuration `json:"endTime"`
+}
+
+// getScenariosTimeline resolves the start times of the scenarios, after the
+// scenarios they start after, and returns them sorted by their start times.
+func getScenariosTimeline(scenarios lib.ScenarioConfigs, et *lib.ExecutionTuple) []scenarioTimeline {
+	startOffsets := scenarios.GetStartOffsets(et)
+	sortedConfigs := scenarios.GetSortedConfigs()
+
+	timeline := make([]scenarioTimeline, 0, len(sortedConfigs))
+	for _, config := range sortedConfigs {
+		start := startOffsets[config.GetName()]
+		end := start
+		if config.HasWork(et) {
+			duration, _ := lib.GetEndOffset(config.GetExecutionRequirements(et))
+			end += duration
+		}
+		timeline = append(timeline, scenarioTimeline{
+			Scenario:  config.GetName(),
+			StartTime: types.NewNullDuration(start, true),
+			EndTime:   types.NewNullDuration(end, true),
+		})
+	}
+	return timeline
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	v1 "github.com/liuxd6825/k6server/api/v1"
+	"github.com/liuxd6825/k6server/api/v1/client"
+	"github.com/liuxd6825/k6server/cmd/state"
+)
+
+func getCmdLogs(gs *state.GlobalState) *cobra.Command {
+	var (
+		clientFlags apiClientFlags
+		opts        client.LogsOptions
+		follow      bool
+	)
+
+	// logsCmd represents the logs command
+	logsCmd := &cobra.Command{
+		Use:   "logs",
+		Short: "Show the logs of the test run",
+		Long: `Show the logs of the test run.
+
+  The REST API server keeps the most recent log entries, as many as its global
+  --api-log-buffer flag, e.g. the ones of the console of the scripts. With the
+  --follow flag, the new entries are shown as well, until the server shuts down.
+
+  Use the global --address flag to specify the URL to the API server, and the
+  global --api-token flag for the servers which require a bearer token.`,
+		Example: `
+  # Follow the warnings and errors of a test run
+  k6 logs -f --level warning
+
+  # Show the last 20 entries with "timeout" in their messages or fields
+  k6 logs -n 20 --text timeout`[1:],
+		Args: cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			c, err := newAPIClient(gs, &clientFlags)
+			if err != nil {
+				return err
+			}
+
+			formatter := &logrus.TextFormatter{
+				ForceColors:   !gs.Flags.NoColor && gs.Stdout.IsTTY,
+				DisableColors: gs.Flags.NoColor,
+				FullTimestamp: true,
+			}
+			printEntries := func(entries []v1.LogEntry) {
+				for _, e := range entries {
+					printToStdout(gs, formatLogEntry(formatter, e))
+				}
+			}
+
+			if follow {
+				return c.FollowLogs(gs.Ctx, opts, printEntries)
+			}
+			entries, err := c.Logs(gs.Ctx, opts)
+			if err != nil {
+				return err
+			}
+			printEntries(entries)
+			return nil
+		},
+	}
+	logsCmd.Flags().SortFlags = false
+	logsCmd.Flags().BoolVarP(&follow, "follow", "f", false, "show the new log entries as well")
+	logsCmd.Flags().StringVar(&opts.Level, "level", "",
+		"least severe level of the log entries, e.g. warning for the warnings and the errors")
+	logsCmd.Flags().StringVar(&opts.Text, "text", "", "text in the messages or in the fields of the log entries")
+	logsCmd.Flags().IntVarP(&opts.Limit, "tail", "n", 0, "number of the most recent log entries to show, 0 shows all")
+	logsCmd.Flags().AddFlagSet(clientFlags.flagSet())
+
+	return logsCmd
+}
+
+// formatLogEntry formats the log entry the same way as the logs of k6.
+func formatLogEntry(formatter logrus.Formatter, e v1.LogEntry) string {
+	level, err := logrus.ParseLevel(e.Level)
+	if err != nil {
+		level = logrus.InfoLevel
+	}
+	line, err := formatter.Format(&logrus.Entry{Time: e.Time, Level: level, Message: e.Message, Data: e.Fields})
+	if err != nil {
+		return fmt.Sprintf("%s %s %s\n", e.Time, e.Level, e.Message)
+	}
+	return string(line)
+}
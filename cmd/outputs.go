@@ -3,6 +3,7 @@ package cmd
 import (
 	"errors"
 	"fmt"
+	"net/http"
 	"sort"
 	"strings"
 
@@ -14,6 +15,7 @@ import (
 	"github.com/liuxd6825/k6server/output/csv"
 	"github.com/liuxd6825/k6server/output/influxdb"
 	"github.com/liuxd6825/k6server/output/json"
+	"github.com/liuxd6825/k6server/output/prometheus"
 	"github.com/liuxd6825/k6server/output/statsd"
 
 	"github.com/grafana/xk6-dashboard/dashboard"
@@ -33,6 +35,7 @@ const (
 	builtinOutputInfluxdb
 	builtinOutputJSON
 	builtinOutputKafka
+	builtinOutputPrometheus
 	builtinOutputStatsd
 )
 
@@ -40,10 +43,11 @@ const (
 func getAllOutputConstructors() (map[string]output.Constructor, error) {
 	// Start with the built-in outputs
 	result := map[string]output.Constructor{
-		builtinOutputJSON.String():     json.New,
-		builtinOutputCloud.String():    cloud.New,
-		builtinOutputCSV.String():      csv.New,
-		builtinOutputInfluxdb.String(): influxdb.New,
+		builtinOutputJSON.String():       json.New,
+		builtinOutputCloud.String():      cloud.New,
+		builtinOutputCSV.String():        csv.New,
+		builtinOutputInfluxdb.String():   influxdb.New,
+		builtinOutputPrometheus.String(): prometheus.New,
 		builtinOutputKafka.String(): func(_ output.Params) (output.Output, error) {
 			return nil, errors.New("the kafka output was deprecated in k6 v0.32.0 and removed in k6 v0.34.0, " +
 				"please use the new xk6 kafka output extension instead - https://github.com/k6io/xk6-output-kafka")
@@ -152,6 +156,22 @@ func createOutputs(
 	return result, nil
 }
 
+// getOutputHTTPHandlers returns the handlers of the outputs which should be
+// served by the REST API server, by their patterns.
+func getOutputHTTPHandlers(outputs []output.Output) map[string]http.Handler {
+	handlers := make(map[string]http.Handler)
+	for _, out := range outputs {
+		handlerOut, ok := out.(output.WithHTTPHandler)
+		if !ok {
+			continue
+		}
+		if pattern, handler := handlerOut.HTTPHandler(); handler != nil {
+			handlers[pattern] = handler
+		}
+	}
+	return handlers
+}
+
 func parseOutputArgument(s string) (t, arg string) {
 	parts := strings.SplitN(s, "=", 2)
 	switch len(parts) {
@@ -5,20 +5,22 @@ import (
 	"gopkg.in/guregu/null.v3"
 
 	v1 "github.com/liuxd6825/k6server/api/v1"
-	"github.com/liuxd6825/k6server/api/v1/client"
 	"github.com/liuxd6825/k6server/cmd/state"
 )
 
 func getCmdPause(gs *state.GlobalState) *cobra.Command {
+	var clientFlags apiClientFlags
+
 	// pauseCmd represents the pause command
 	pauseCmd := &cobra.Command{
 		Use:   "pause",
 		Short: "Pause a running test",
 		Long: `Pause a running test.
 
-  Use the global --address flag to specify the URL to the API server.`,
+  Use the global --address flag to specify the URL to the API server, and the
+  global --api-token flag for the servers which require a bearer token.`,
 		RunE: func(_ *cobra.Command, _ []string) error {
-			c, err := client.New(gs.Flags.Address)
+			c, err := newAPIClient(gs, &clientFlags)
 			if err != nil {
 				return err
 			}
@@ -31,5 +33,7 @@ func getCmdPause(gs *state.GlobalState) *cobra.Command {
 			return yamlPrint(gs.Stdout, status)
 		},
 	}
+	pauseCmd.Flags().AddFlagSet(clientFlags.flagSet())
+
 	return pauseCmd
 }
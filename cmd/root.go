@@ -61,9 +61,9 @@ func newRootCommand(gs *state.GlobalState) *rootCommand {
 	rootCmd.SetIn(gs.Stdin)
 
 	subCommands := []func(*state.GlobalState) *cobra.Command{
-		getCmdArchive, getCmdCloud, getCmdNewScript, getCmdInspect,
-		getCmdLogin, getCmdPause, getCmdResume, getCmdScale, getCmdRun,
-		getCmdStats, getCmdStatus, getCmdVersion, getCmdServer,
+		getCmdAgent, getCmdArchive, getCmdCloud, getCmdCoordinator, getCmdNewScript, getCmdHistory, getCmdInspect,
+		getCmdLogin, getCmdLogs, getCmdPause, getCmdResume, getCmdScale, getCmdRun,
+		getCmdStats, getCmdStatus, getCmdVersion, getCmdServer, getCmdVUs,
 	}
 
 	for _, sc := range subCommands {
@@ -176,6 +176,11 @@ func rootCmdPersistentFlagSet(gs *state.GlobalState) *pflag.FlagSet {
 	flags.Lookup("config").DefValue = gs.DefaultFlags.ConfigFilePath
 	must(cobra.MarkFlagFilename(flags, "config"))
 
+	flags.StringVar(&gs.Flags.HistoryDir, "history-dir", gs.Flags.HistoryDir,
+		"directory of the test runs recorded with --history")
+	flags.Lookup("history-dir").DefValue = gs.DefaultFlags.HistoryDir
+	must(cobra.MarkFlagDirname(flags, "history-dir"))
+
 	flags.BoolVar(&gs.Flags.NoColor, "no-color", gs.Flags.NoColor, "disable colored output")
 	flags.Lookup("no-color").DefValue = strconv.FormatBool(gs.DefaultFlags.NoColor)
 
@@ -190,6 +195,19 @@ func rootCmdPersistentFlagSet(gs *state.GlobalState) *pflag.FlagSet {
 		gs.DefaultFlags.ProfilingEnabled,
 		"enable profiling (pprof) endpoints, k6's REST API should be enabled as well",
 	)
+	flags.StringVar(&gs.Flags.APIToken, "api-token", gs.Flags.APIToken,
+		"bearer token of the requests to the REST API, which the server requires and the clients send")
+	flags.Lookup("api-token").DefValue = ""
+	flags.StringVar(&gs.Flags.APITLSCert, "api-tls-cert", gs.DefaultFlags.APITLSCert,
+		"TLS certificate of the REST API server, which then serves HTTPS")
+	flags.StringVar(&gs.Flags.APITLSKey, "api-tls-key", gs.DefaultFlags.APITLSKey,
+		"key of the TLS certificate of the REST API server")
+	flags.StringVar(&gs.Flags.APITLSClientCA, "api-tls-client-ca", gs.DefaultFlags.APITLSClientCA,
+		"CA certificates the REST API server verifies the certificates of the clients with, which it then requires")
+	flags.BoolVar(&gs.Flags.APIReadOnly, "api-read-only", gs.DefaultFlags.APIReadOnly,
+		"reject the REST API requests which would change the test run, i.e. the ones other than GET and HEAD")
+	flags.IntVar(&gs.Flags.APILogBuffer, "api-log-buffer", gs.DefaultFlags.APILogBuffer,
+		"number of the recent log entries the REST API server keeps for /v1/logs, 0 disables it")
 
 	return flags
 }
@@ -17,12 +17,14 @@ import (
 	"github.com/spf13/pflag"
 
 	"github.com/liuxd6825/k6server/api"
+	v1 "github.com/liuxd6825/k6server/api/v1"
 	"github.com/liuxd6825/k6server/cmd/state"
 	"github.com/liuxd6825/k6server/errext"
 	"github.com/liuxd6825/k6server/errext/exitcodes"
 	"github.com/liuxd6825/k6server/event"
 	"github.com/liuxd6825/k6server/execution"
 	"github.com/liuxd6825/k6server/execution/local"
+	"github.com/liuxd6825/k6server/history"
 	"github.com/liuxd6825/k6server/js/common"
 	"github.com/liuxd6825/k6server/lib"
 	"github.com/liuxd6825/k6server/lib/fsext"
@@ -39,6 +41,16 @@ type cmdRun struct {
 
 	// TODO: figure out something more elegant?
 	loadConfiguredTest func(cmd *cobra.Command, args []string) (*loadedAndConfiguredTest, execution.Controller, error)
+
+	// testRunInitialized is called, if set, with the control surface of the
+	// test run before its VUs are initialized, e.g. by the test runs of the
+	// server mode, which serve it through the REST API of the server.
+	testRunInitialized func(cs *v1.ControlSurface)
+
+	// extraOutputs are added to the outputs of the test run, e.g. the one
+	// that sends the metrics of an agent of a distributed test run to its
+	// coordinator.
+	extraOutputs []output.Output
 }
 
 const (
@@ -67,6 +79,10 @@ func (c *cmdRun) run(cmd *cobra.Command, args []string) (err error) {
 	}()
 	printBanner(c.gs)
 
+	// The log entries are kept from the start, so the ones of the init are
+	// available through the REST API as well.
+	logs := addAPILogBuffer(c.gs)
+
 	globalCtx, globalCancel := context.WithCancel(c.gs.Ctx)
 	defer globalCancel()
 
@@ -164,6 +180,15 @@ func (c *cmdRun) run(cmd *cobra.Command, args []string) (err error) {
 	if err != nil {
 		return err
 	}
+	outputs = append(outputs, c.extraOutputs...)
+	var historyRecorder *history.Recorder
+	if conf.History.Bool {
+		historyRecorder, err = history.NewRecorder(history.DefaultInterval, conf.SummaryTrendStats)
+		if err != nil {
+			return err
+		}
+		outputs = append(outputs, historyRecorder)
+	}
 
 	metricsEngine, err := engine.NewMetricsEngine(testRunState.Registry, logger)
 	if err != nil {
@@ -171,9 +196,9 @@ func (c *cmdRun) run(cmd *cobra.Command, args []string) (err error) {
 	}
 
 	// We'll need to pipe metrics to the MetricsEngine and process them if any
-	// of these are enabled: thresholds, end-of-test summary
+	// of these are enabled: thresholds, end-of-test summary, history
 	shouldProcessMetrics := (!testRunState.RuntimeOptions.NoSummary.Bool ||
-		!testRunState.RuntimeOptions.NoThresholds.Bool)
+		!testRunState.RuntimeOptions.NoThresholds.Bool || conf.History.Bool)
 	var metricsIngester *engine.OutputIngester
 	if shouldProcessMetrics {
 		err = metricsEngine.InitSubMetricsAndThresholds(conf.Options, testRunState.RuntimeOptions.NoThresholds.Bool)
@@ -184,9 +209,31 @@ func (c *cmdRun) run(cmd *cobra.Command, args []string) (err error) {
 		// thresholds or the end-of-test summary are enabled.
 		metricsIngester = metricsEngine.CreateIngester()
 		outputs = append(outputs, metricsIngester)
+		setMetricWindows(execScheduler, metricsEngine)
+	}
+	if !testRunState.RuntimeOptions.NoThresholds.Bool {
+		setThresholdsWindows(execScheduler, metricsEngine)
 	}
 
 	executionState := execScheduler.GetState()
+	if historyRecorder != nil {
+		// This is deferred before the summary, so the run is recorded after
+		// it's printed, with the final outcome of the thresholds.
+		started := time.Now()
+		defer func() {
+			hErr := recordTestRun(c.gs, test, started, &lib.Summary{
+				Metrics:          metricsEngine.ObservedMetrics,
+				RootGroup:        testRunState.Runner.GetDefaultGroup(),
+				TestRunDuration:  executionState.GetCurrentTestRunDuration(),
+				NoColor:          true,
+				ThresholdChanges: metricsEngine.ThresholdChanges(),
+				Capacities:       getCapacities(execScheduler),
+			}, historyRecorder, err)
+			if hErr != nil {
+				logger.WithError(hErr).Error("failed to record the test run in the history")
+			}
+		}()
+	}
 	if !testRunState.RuntimeOptions.NoSummary.Bool {
 		defer func() {
 			logger.Debug("Generating the end-of-test summary...")
@@ -199,6 +246,8 @@ func (c *cmdRun) run(cmd *cobra.Command, args []string) (err error) {
 					IsStdOutTTY: c.gs.Stdout.IsTTY,
 					IsStdErrTTY: c.gs.Stderr.IsTTY,
 				},
+				ThresholdChanges: metricsEngine.ThresholdChanges(),
+				Capacities:       getCapacities(execScheduler),
 			})
 			if hsErr == nil {
 				hsErr = handleSummaryResult(c.gs.FS, c.gs.Stdout, c.gs.Stderr, summaryResult)
@@ -294,9 +343,29 @@ func (c *cmdRun) run(cmd *cobra.Command, args []string) (err error) {
 		logger.Debug("Metrics and traces processing finished!")
 	}()
 
+	if c.testRunInitialized != nil {
+		c.testRunInitialized(&v1.ControlSurface{
+			RunCtx:        runCtx,
+			Samples:       samples,
+			MetricsEngine: metricsEngine,
+			Scheduler:     execScheduler,
+			RunState:      testRunState,
+		})
+	}
+
 	// Spin up the REST API server, if not disabled.
+	outputHandlers := getOutputHTTPHandlers(outputs)
+	if c.gs.Flags.Address == "" {
+		for pattern := range outputHandlers {
+			logger.Warnf("The %s endpoint of the outputs isn't served, since the REST API is disabled", pattern)
+		}
+	}
 	if c.gs.Flags.Address != "" { //nolint:nestif
 		initBar.Modify(pb.WithConstProgress(0, "Init API server"))
+		apiSecurity, serr := getAPISecurity(c.gs)
+		if serr != nil {
+			return serr
+		}
 
 		// We cannot use backgroundProcesses here, since we need the REST API to
 		// be down before we can close the samples channel above and finish the
@@ -315,6 +384,9 @@ func (c *cmdRun) run(cmd *cobra.Command, args []string) (err error) {
 			samples,
 			metricsEngine,
 			execScheduler,
+			apiSecurity,
+			outputHandlers,
+			logs,
 		)
 		go func() {
 			defer apiWG.Done()
@@ -322,7 +394,7 @@ func (c *cmdRun) run(cmd *cobra.Command, args []string) (err error) {
 			if c.gs.Flags.ProfilingEnabled {
 				logger.Debugf("Profiling exposed on http://%s/debug/pprof/", c.gs.Flags.Address)
 			}
-			if aerr := srv.ListenAndServe(); aerr != nil && !errors.Is(aerr, http.ErrServerClosed) {
+			if aerr := api.ListenAndServe(srv); aerr != nil && !errors.Is(aerr, http.ErrServerClosed) {
 				// Only exit k6 if the user has explicitly set the REST API address
 				if cmd.Flags().Lookup("address").Changed {
 					logger.WithError(aerr).Error("Error from API server")
@@ -343,8 +415,12 @@ func (c *cmdRun) run(cmd *cobra.Command, args []string) (err error) {
 		}()
 	}
 
+	executionType := "local"
+	if _, isLocal := controller.(*local.Controller); !isLocal {
+		executionType = "distributed"
+	}
 	printExecutionDescription(
-		c.gs, "local", args[0], "", conf, executionState.ExecutionTuple, executionPlan, outputs,
+		c.gs, executionType, args[0], "", conf, executionState.ExecutionTuple, executionPlan, outputs,
 	)
 
 	// Trap Interrupts, SIGINTs and SIGTERMs.
@@ -462,14 +538,18 @@ func (c *cmdRun) setupTracerProvider(ctx context.Context, test *loadedAndConfigu
 	return nil
 }
 
-func getCmdRun(gs *state.GlobalState) *cobra.Command {
-	c := &cmdRun{
+func newCmdRun(gs *state.GlobalState) *cmdRun {
+	return &cmdRun{
 		gs: gs,
 		loadConfiguredTest: func(cmd *cobra.Command, args []string) (*loadedAndConfiguredTest, execution.Controller, error) {
 			test, err := loadAndConfigureLocalTest(gs, cmd, args, getConfig)
 			return test, local.NewController(), err
 		},
 	}
+}
+
+func getCmdRun(gs *state.GlobalState) *cobra.Command {
+	c := newCmdRun(gs)
 
 	exampleText := getExampleText(gs, `
   # Run a single VU, once.
@@ -532,3 +612,45 @@ func handleSummaryResult(fs fsext.Fs, stdOut, stdErr io.Writer, result map[strin
 
 	return consolidateErrorMessage(errs, "Could not save some summary information:")
 }
+
+// setThresholdsWindows lets the executors adapting their load to the outcome
+// of the thresholds, e.g. the breaking-point one, evaluate them.
+func setThresholdsWindows(execScheduler *execution.Scheduler, metricsEngine *engine.MetricsEngine) {
+	for _, exec := range execScheduler.GetExecutors() {
+		if tde, ok := exec.(lib.ThresholdsDrivenExecutor); ok {
+			tde.SetThresholdsWindows(func() lib.ThresholdsWindow {
+				return metricsEngine.NewThresholdsWindow()
+			})
+		}
+	}
+}
+
+// setMetricWindows lets the executors adapting their load to the values of a
+// metric, e.g. the closed-loop one, observe them.
+func setMetricWindows(execScheduler *execution.Scheduler, metricsEngine *engine.MetricsEngine) {
+	for _, exec := range execScheduler.GetExecutors() {
+		if mde, ok := exec.(lib.MetricsDrivenExecutor); ok {
+			mde.SetMetricWindows(func(metricName, aggregation string) (lib.MetricWindow, error) {
+				window, err := metricsEngine.NewMetricWindow(metricName, aggregation)
+				if err != nil {
+					return nil, err
+				}
+				return window, nil
+			})
+		}
+	}
+}
+
+// getCapacities returns the capacities found by the executors searching for
+// them, for the end-of-test summary.
+func getCapacities(execScheduler *execution.Scheduler) []lib.Capacity {
+	var capacities []lib.Capacity
+	for _, exec := range execScheduler.GetExecutors() {
+		if cre, ok := exec.(lib.CapacityReportingExecutor); ok {
+			if capacity, ok := cre.GetCapacity(); ok {
+				capacities = append(capacities, capacity)
+			}
+		}
+	}
+	return capacities
+}
@@ -6,18 +6,20 @@ import (
 	"github.com/spf13/cobra"
 
 	v1 "github.com/liuxd6825/k6server/api/v1"
-	"github.com/liuxd6825/k6server/api/v1/client"
 	"github.com/liuxd6825/k6server/cmd/state"
 )
 
 func getCmdScale(gs *state.GlobalState) *cobra.Command {
+	var clientFlags apiClientFlags
+
 	// scaleCmd represents the scale command
 	scaleCmd := &cobra.Command{
 		Use:   "scale",
 		Short: "Scale a running test",
 		Long: `Scale a running test.
 
-  Use the global --address flag to specify the URL to the API server.`,
+  Use the global --address flag to specify the URL to the API server, and the
+  global --api-token flag for the servers which require a bearer token.`,
 		RunE: func(cmd *cobra.Command, _ []string) error {
 			vus := getNullInt64(cmd.Flags(), "vus")
 			max := getNullInt64(cmd.Flags(), "max")
@@ -25,7 +27,7 @@ func getCmdScale(gs *state.GlobalState) *cobra.Command {
 				return errors.New("Specify either -u/--vus or -m/--max") //nolint:golint,stylecheck
 			}
 
-			c, err := client.New(gs.Flags.Address)
+			c, err := newAPIClient(gs, &clientFlags)
 			if err != nil {
 				return err
 			}
@@ -40,6 +42,7 @@ func getCmdScale(gs *state.GlobalState) *cobra.Command {
 
 	scaleCmd.Flags().Int64P("vus", "u", 1, "number of virtual users")
 	scaleCmd.Flags().Int64P("max", "m", 0, "max available virtual users")
+	scaleCmd.Flags().AddFlagSet(clientFlags.flagSet())
 
 	return scaleCmd
 }
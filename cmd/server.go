@@ -1,66 +1,544 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
 	"github.com/fatih/color"
-	"github.com/kataras/iris/v12"
-	"github.com/liuxd6825/k6server/cmd/state"
-	"github.com/liuxd6825/k6server/lib/fsext"
+	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
+
+	"github.com/liuxd6825/k6server/api"
+	v1 "github.com/liuxd6825/k6server/api/v1"
+	"github.com/liuxd6825/k6server/cmd/state"
+	"github.com/liuxd6825/k6server/errext"
+	"github.com/liuxd6825/k6server/errext/exitcodes"
+	"github.com/liuxd6825/k6server/js"
+	"github.com/liuxd6825/k6server/lib"
+	"github.com/liuxd6825/k6server/metrics"
+	"github.com/liuxd6825/k6server/metrics/engine"
+	"github.com/liuxd6825/k6server/output"
+	"github.com/liuxd6825/k6server/server"
+)
+
+const (
+	defaultServerListenAddress     = ":8080"
+	defaultServerGRPCListenAddress = ":9090"
 )
 
-const defaultServerScriptName = "script.js"
+// cmdServer handles the `k6 server` sub-command
+type cmdServer struct {
+	gs         *state.GlobalState
+	listen     string
+	grpcListen string
+	pool       server.PoolConfig
+	watch      bool
 
-// newScriptCmd represents the `k6 new` command
-type newServerCmd struct {
-	gs  *state.GlobalState
-	app *iris.Application
+	runLimits testRunLimits
 }
 
-func (c *newServerCmd) flagSet() *pflag.FlagSet {
-	flags := pflag.NewFlagSet("", pflag.ContinueOnError)
-	flags.SortFlags = false
+// watchInterval is how often the script files are checked for changes.
+const watchInterval = 500 * time.Millisecond
 
-	return flags
-}
+//nolint:funlen
+func (c *cmdServer) run(cmd *cobra.Command, args []string) (err error) {
+	var logger logrus.FieldLogger = c.gs.Logger
+	printBanner(c.gs)
+	logs := addAPILogBuffer(c.gs)
+
+	globalCtx, globalCancel := context.WithCancel(c.gs.Ctx)
+	defer globalCancel()
+
+	test, err := loadAndConfigureLocalTest(c.gs, cmd, args, getConfig)
+	if err != nil {
+		return err
+	}
+	if c.watch && (args[0] == "-" || detectTestType(test.source.Data) != testTypeJS) {
+		return errors.New("the --watch option can only be used with a script file")
+	}
+	if test.keyLogger != nil {
+		defer func() {
+			if klErr := test.keyLogger.Close(); klErr != nil {
+				logger.WithError(klErr).Warn("Error while closing the SSLKEYLOGFILE")
+			}
+		}()
+	}
+
+	conf := test.derivedConfig
+	testRunState, err := test.buildTestRunState(conf.Options)
+	if err != nil {
+		return err
+	}
+
+	// There is no execution plan in the server mode, the outputs get the
+	// samples for as long as the server is running.
+	outputs, err := createOutputs(c.gs, test, nil)
+	if err != nil {
+		return err
+	}
+
+	metricsEngine, err := engine.NewMetricsEngine(testRunState.Registry, logger)
+	if err != nil {
+		return err
+	}
+
+	shouldProcessMetrics := (!testRunState.RuntimeOptions.NoSummary.Bool ||
+		!testRunState.RuntimeOptions.NoThresholds.Bool)
+	var metricsIngester *engine.OutputIngester
+	if shouldProcessMetrics {
+		err = metricsEngine.InitSubMetricsAndThresholds(conf.Options, testRunState.RuntimeOptions.NoThresholds.Bool)
+		if err != nil {
+			return err
+		}
+		metricsIngester = metricsEngine.CreateIngester()
+		outputs = append(outputs, metricsIngester)
+	}
+
+	startTime := time.Now()
+	getServingDuration := func() time.Duration { return time.Since(startTime) }
+
+	if !testRunState.RuntimeOptions.NoSummary.Bool {
+		defer func() {
+			logger.Debug("Generating the end-of-test summary...")
+			summaryResult, hsErr := test.initRunner.HandleSummary(globalCtx, &lib.Summary{
+				Metrics:         metricsEngine.ObservedMetrics,
+				RootGroup:       testRunState.Runner.GetDefaultGroup(),
+				TestRunDuration: getServingDuration(),
+				NoColor:         c.gs.Flags.NoColor,
+				UIState: lib.UIState{
+					IsStdOutTTY: c.gs.Stdout.IsTTY,
+					IsStdErrTTY: c.gs.Stderr.IsTTY,
+				},
+				ThresholdChanges: metricsEngine.ThresholdChanges(),
+			})
+			if hsErr == nil {
+				hsErr = handleSummaryResult(c.gs.FS, c.gs.Stdout, c.gs.Stderr, summaryResult)
+			}
+			if hsErr != nil {
+				logger.WithError(hsErr).Error("failed to handle the end-of-test summary")
+			}
+		}()
+	}
+
+	// stopServer is called when the server should be stopped, either because
+	// of a signal, or because of an error from the outputs or the thresholds.
+	var (
+		stopOnce     sync.Once
+		stopErr      error
+		stopReceived = make(chan struct{})
+	)
+	stopServer := func(serr error) {
+		stopOnce.Do(func() {
+			stopErr = serr
+			close(stopReceived)
+		})
+	}
 
-func (c *newServerCmd) run(cmd *cobra.Command, args []string) error { //nolint:revive
-	target := defaultServerScriptName
-	if len(args) > 0 {
-		target = args[0]
+	outputManager := output.NewManager(outputs, logger, func(oerr error) {
+		if oerr != nil {
+			logger.WithError(oerr).Error("Received error to stop from output")
+		}
+		stopServer(oerr)
+	})
+	samples := make(chan metrics.SampleContainer, conf.MetricSamplesBufferSize.Int64)
+	waitOutputsFlushed, stopOutputs, err := outputManager.Start(samples)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		logger.Debug("Stopping outputs...")
+		stopOutputs(err)
+	}()
+
+	if !testRunState.RuntimeOptions.NoThresholds.Bool {
+		finalizeThresholds := metricsEngine.StartThresholdCalculations(
+			metricsIngester, stopServer, getServingDuration,
+		)
+		if finalizeThresholds != nil {
+			defer func() {
+				logger.Debug("Finalizing thresholds...")
+				breachedThresholds := finalizeThresholds()
+				if len(breachedThresholds) == 0 {
+					return
+				}
+				tErr := errext.WithAbortReasonIfNone(
+					errext.WithExitCodeIfNone(
+						fmt.Errorf("thresholds on metrics '%s' have been crossed", strings.Join(breachedThresholds, ", ")),
+						exitcodes.ThresholdsHaveFailed,
+					), errext.AbortedByThresholdsAfterTestEnd)
+				if err == nil {
+					err = tErr
+				} else {
+					logger.WithError(tErr).Debug("Crossed thresholds, but the server already stopped with another error")
+				}
+			}()
+		}
 	}
 
-	fileExists, err := fsext.Exists(c.gs.FS, target)
+	// The servers are shut down and the VUs are stopped before the samples
+	// channel is closed, so none of the handlers can send samples to a closed
+	// channel.
+	vusCtx, vusCancel := context.WithCancel(globalCtx)
+	var (
+		srv        *server.Server
+		shutdownWG sync.WaitGroup // the shutdowns of the HTTP and gRPC servers
+		watchWG    sync.WaitGroup
+	)
+	defer func() {
+		logger.Debug("Waiting for metrics processing to finish...")
+		stopServer(nil)
+		shutdownWG.Wait()
+		vusCancel()
+		watchWG.Wait()
+		if srv != nil {
+			srv.Wait()
+		}
+		close(samples)
+		waitOutputsFlushed()
+		logger.Debug("Metrics processing finished!")
+	}()
+
+	logger.Debug("Initializing the server...")
+	srv, err = server.New(vusCtx, testRunState, samples, c.pool)
 	if err != nil {
 		return err
 	}
+	if c.watch {
+		watchWG.Add(1)
+		go func() {
+			defer watchWG.Done()
+			c.watchScript(vusCtx, args[0], test, testRunState, srv)
+		}()
+	}
+
+	// Spin up the REST API server, if not disabled. It's used for changing
+	// the faults of the routes at runtime and for submitting test runs,
+	// besides getting the metrics.
+	outputHandlers := getOutputHTTPHandlers(outputs)
+	if c.gs.Flags.Address == "" {
+		for pattern := range outputHandlers {
+			logger.Warnf("The %s endpoint of the outputs isn't served, since the REST API is disabled", pattern)
+		}
+	}
+	if c.gs.Flags.Address != "" {
+		apiSecurity, serr := getAPISecurity(c.gs)
+		if serr != nil {
+			return serr
+		}
+		apiWG := &sync.WaitGroup{}
+		apiWG.Add(2)
+		defer apiWG.Wait()
+
+		apiCtx, apiCancel := context.WithCancel(globalCtx)
+		defer apiCancel()
+
+		// The test runs are stopped before the REST API.
+		runs := newTestRunQueue(c.gs, c.runLimits)
+		runs.start()
+		defer runs.stop()
+
+		apiSrv := api.NewServer(c.gs.Flags.Address, c.gs.Flags.ProfilingEnabled, &v1.ControlSurface{
+			RunCtx:        globalCtx,
+			Samples:       samples,
+			MetricsEngine: metricsEngine,
+			RunState:      testRunState,
+			Faults:        srv,
+			Runs:          runs,
+			Logs:          logs,
+			Handlers:      outputHandlers,
+		}, apiSecurity)
+		go func() {
+			defer apiWG.Done()
+			logger.Debugf("Starting the REST API server on %s", c.gs.Flags.Address)
+			if aerr := api.ListenAndServe(apiSrv); aerr != nil && !errors.Is(aerr, http.ErrServerClosed) {
+				if cmd.Flags().Lookup("address").Changed {
+					logger.WithError(aerr).Error("Error from API server")
+					c.gs.OSExit(int(exitcodes.CannotStartRESTAPI))
+				} else {
+					logger.WithError(aerr).Warn("Error from API server")
+				}
+			}
+		}()
+		go func() {
+			defer apiWG.Done()
+			<-apiCtx.Done()
+			shutdCtx, shutdCancel := context.WithTimeout(globalCtx, 1*time.Second)
+			defer shutdCancel()
+			if aerr := apiSrv.Shutdown(shutdCtx); aerr != nil {
+				logger.WithError(aerr).Debug("REST API server did not shut down correctly")
+			}
+		}()
+	}
+
+	httpSrv := &http.Server{Addr: c.listen, Handler: srv, ReadHeaderTimeout: 10 * time.Second}
+	httpSrv.RegisterOnShutdown(srv.CloseStreams)
+	shutdownWG.Add(1)
+	go func() {
+		defer shutdownWG.Done()
+		<-stopReceived
+		shutdCtx, shutdCancel := context.WithTimeout(globalCtx, 5*time.Second)
+		defer shutdCancel()
+		if serr := httpSrv.Shutdown(shutdCtx); serr != nil {
+			logger.WithError(serr).Debug("The server did not shut down correctly")
+		}
+	}()
+	stopSignalHandling := handleTestAbortSignals(c.gs, func(sig os.Signal) {
+		logger.WithField("sig", sig).Debug("Stopping the server in response to signal...")
+		stopServer(nil)
+	}, func(sig os.Signal) {
+		logger.WithField("sig", sig).Error("Aborting k6 in response to signal")
+		globalCancel()
+	})
+	defer stopSignalHandling()
+
+	// The gRPC server is started only if the script registered gRPC methods.
+	hasGRPC := srv.HasGRPCMethods()
+	if hasGRPC {
+		grpcListener, lerr := net.Listen("tcp", c.grpcListen)
+		if lerr != nil {
+			return fmt.Errorf("couldn't listen for gRPC on %s: %w", c.grpcListen, lerr)
+		}
+		grpcSrv := srv.NewGRPCServer()
+		go func() {
+			if serr := grpcSrv.Serve(grpcListener); serr != nil {
+				logger.WithError(serr).Error("Error from the gRPC server")
+				stopServer(serr)
+			}
+		}()
+		shutdownWG.Add(1)
+		go func() {
+			defer shutdownWG.Done()
+			<-stopReceived
+			stopped := make(chan struct{})
+			go func() {
+				grpcSrv.GracefulStop()
+				close(stopped)
+			}()
+			select {
+			case <-stopped:
+			case <-time.After(5 * time.Second):
+				logger.Debug("The gRPC server did not shut down gracefully")
+				grpcSrv.Stop()
+			}
+		}()
+	}
+
+	c.printDescription(args[0], outputs, hasGRPC)
+
+	if err = httpSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		stopServer(nil)
+		return err
+	}
+	<-stopReceived
+	logger.Debug("The server was stopped")
+	return stopErr
+}
+
+// watchScript reloads the server whenever the script or one of the local
+// modules it imports is changed, until ctx is done. If the new version can't
+// be loaded, the previous one keeps being served.
+func (c *cmdServer) watchScript(
+	ctx context.Context, scriptPath string, test *loadedAndConfiguredTest,
+	testRunState *lib.TestRunState, srv *server.Server,
+) {
+	logger := c.gs.Logger
+	watcher := server.NewFileWatcher(c.gs.FS)
+	watcher.SetFiles(server.LocalFiles(test.moduleResolver.Imported()))
+
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+		changed := watcher.Changed()
+		if len(changed) == 0 {
+			continue
+		}
+
+		logger.WithField("files", changed).Info("The script was changed, reloading it...")
+		runner, err := c.reloadScript(scriptPath, test, testRunState, srv)
+		if err != nil {
+			logger.WithError(err).Error("Couldn't reload the script, still serving the previous version")
+			continue
+		}
+		watcher.SetFiles(server.LocalFiles(runner.Bundle.ModuleResolver.Imported()))
+		logger.Info("The script was reloaded")
+	}
+}
+
+// reloadScript compiles the current version of the script with the already
+// consolidated options, and replaces the one served by srv with it. The
+// options exported by the new version aren't applied.
+func (c *cmdServer) reloadScript(
+	scriptPath string, test *loadedAndConfiguredTest, testRunState *lib.TestRunState, srv *server.Server,
+) (*js.Runner, error) {
+	src, fileSystems, _, err := readSource(c.gs, scriptPath)
+	if err != nil {
+		return nil, err
+	}
+	runner, err := js.New(test.preInitState, src, fileSystems)
+	if err != nil {
+		return nil, err
+	}
+	if err = runner.SetOptions(test.derivedConfig.Options); err != nil {
+		return nil, err
+	}
+
+	newState := *testRunState
+	newState.Runner = runner
+	if err = srv.Reload(&newState); err != nil {
+		return nil, err
+	}
+	return runner, nil
+}
+
+// printDescription prints the settings of the server and the outputs which
+// receive its metrics.
+func (c *cmdServer) printDescription(filename string, outputs []output.Output, hasGRPC bool) {
+	gs := c.gs
+	valueColor := getColor(gs.Flags.NoColor || !gs.Stdout.IsTTY, color.FgCyan)
+
+	var outputDescriptions []string
+	for _, out := range outputs {
+		if desc := out.Description(); desc != engine.IngesterDescription {
+			outputDescriptions = append(outputDescriptions, desc)
+		}
+	}
+	if len(outputDescriptions) == 0 {
+		outputDescriptions = append(outputDescriptions, "-")
+	}
 
-	if !fileExists {
-		return fmt.Errorf("%s not exists", target)
+	buf := &strings.Builder{}
+	fmt.Fprintf(buf, "     execution: %s\n", valueColor.Sprint("server"))
+	fmt.Fprintf(buf, "        script: %s\n", valueColor.Sprint(filename))
+	fmt.Fprintf(buf, "        output: %s\n", valueColor.Sprint(strings.Join(outputDescriptions, ", ")))
+	fmt.Fprintf(buf, "        listen: %s\n", valueColor.Sprint(c.listen))
+	if hasGRPC {
+		fmt.Fprintf(buf, "   grpc listen: %s\n", valueColor.Sprint(c.grpcListen))
+	}
+	if gs.Flags.Address != "" {
+		fmt.Fprintf(buf, "      rest api: %s\n", valueColor.Sprintf("http://%s/v1/", gs.Flags.Address))
+	}
+	fmt.Fprintf(buf, "           vus: %s\n\n", valueColor.Sprintf("%d-%d", c.pool.MinVUs, c.pool.MaxVUs))
+	if c.watch {
+		buf.WriteString("Serving the routes and reloading the script on changes until k6 is stopped with Ctrl+C.\n")
+	} else {
+		buf.WriteString("Serving the routes until k6 is stopped with Ctrl+C.\n")
 	}
 
-	valueColor := getColor(c.gs.Flags.NoColor || !c.gs.Stdout.IsTTY, color.Bold)
-	printToStdout(c.gs, fmt.Sprintf(
-		"Initialized a new k6 test script in %s. You can now execute it by running `%s run %s`.\n",
-		valueColor.Sprint(target),
-		c.gs.BinaryName,
-		target,
-	))
+	printToStdout(gs, buf.String())
+}
 
-	return nil
+func (c *cmdServer) flagSet() *pflag.FlagSet {
+	flags := pflag.NewFlagSet("", pflag.ContinueOnError)
+	flags.SortFlags = false
+	flags.StringVar(&c.listen, "listen", defaultServerListenAddress, "`address` the script routes are served on")
+	flags.StringVar(&c.grpcListen, "grpc-listen", defaultServerGRPCListenAddress,
+		"`address` the gRPC methods of the script are served on, if it registers any")
+	flags.Int64Var(&c.pool.MinVUs, "min-vus", c.pool.MinVUs, "number of VUs kept initialized, even when idle")
+	flags.Int64Var(&c.pool.MaxVUs, "max-vus", c.pool.MaxVUs, "maximum number of VUs, i.e. of concurrently served requests")
+	flags.DurationVar(&c.pool.IdleTimeout, "vu-idle-timeout", c.pool.IdleTimeout,
+		"time after which idle VUs above --min-vus are removed, 0 to keep them")
+	flags.DurationVar(&c.pool.WaitTimeout, "vu-wait-timeout", c.pool.WaitTimeout,
+		"time a request waits for a free VU before it's rejected with 503, 0 to wait indefinitely")
+	flags.BoolVar(&c.watch, "watch", false, "reload the script when it or one of the local modules it imports changes")
+	flags.IntVar(&c.runLimits.maxQueued, "max-queued-runs", defaultMaxQueuedRuns,
+		"maximum number of test runs submitted through the REST API waiting for the current one")
+	flags.IntVar(&c.runLimits.maxEnded, "max-ended-runs", defaultMaxEndedRuns,
+		"maximum number of ended test runs whose results are kept, 0 for no limit")
+	flags.DurationVar(&c.runLimits.endedRunsRetention, "ended-runs-retention", defaultEndedRunRetention,
+		"time the results of the ended test runs are kept, 0 to keep them until --max-ended-runs is reached")
+	flags.AddFlagSet(optionFlagSet())
+	flags.AddFlagSet(runtimeOptionFlagSet(true))
+	flags.AddFlagSet(configFlagSet())
+	return flags
 }
 
-func getCmdServer(_ *state.GlobalState) *cobra.Command {
-	// versionCmd represents the version command.
-	return &cobra.Command{
+func getCmdServer(gs *state.GlobalState) *cobra.Command {
+	c := &cmdServer{gs: gs, pool: server.DefaultPoolConfig()}
+
+	exampleText := getExampleText(gs, `
+  # Serve the routes registered by script.js on the default address.
+  {{.}} server script.js
+
+  # Serve them on another address, with an environment variable for the script.
+  {{.}} server --listen localhost:3000 -e BACKEND=staging script.js
+
+  # Serve up to 50 requests concurrently, keeping 5 VUs always initialized.
+  {{.}} server --min-vus 5 --max-vus 50 script.js
+
+  # Reload the script on every change while developing it.
+  {{.}} server --watch script.js
+
+  # Run an archive on the server and get its summary once it's done.
+  {{.}} archive -O test.tar test.js
+  curl --data-binary @test.tar localhost:6565/v1/runs
+  curl localhost:6565/v1/runs/<id>/summary
+
+  # Send the metrics of the served requests to an influxdb server.
+  {{.}} server -o influxdb=http://1.2.3.4:8086/k6 script.js`[1:])
+
+	serverCmd := &cobra.Command{
 		Use:   "server",
-		Short: "Show application version",
-		Long:  `Show the application version and exit.`,
-		Run: func(cmd *cobra.Command, _ []string) {
-			root := cmd.Root()
-			root.SetArgs([]string{"--server"})
-			_ = root.Execute()
-		},
+		Short: "Start a scripted HTTP server",
+		Long: `Start a scripted HTTP server.
+
+The script is loaded just like with the run command, and the routes it registers
+with the k6/server module in the init context are served until k6 is stopped.
+Every request is handled by a VU from a pool, which grows on demand up to
+--max-vus and shrinks back to --min-vus when the VUs stay idle.
+
+The server_* metrics of the served requests, like server_reqs and
+server_req_duration, are tagged with their route, method and status. They are
+sent to the outputs and checked against the thresholds just like the metrics of
+a test run, and the end-of-test summary is shown when the server is stopped.
+
+With --watch, the script is reloaded whenever it or one of the local modules it
+imports is changed. The requests that are already being served are finished by
+the previous version, and if the new one doesn't compile, the previous version
+keeps being served. The options of the script are only read on start.
+
+Faults, like added latency, error responses or connection resets, can be
+injected into the responses of the routes with the faults() function of the
+k6/server module, or with the serverFaults option. They can be changed while the
+server is running through the /v1/faults endpoints of the REST API.
+
+The ws() and sse() functions of the k6/server module register WebSocket and
+Server-Sent Events routes, whose handlers are called on the event loop of a VU
+for the connection's lifetime. Every open connection holds a VU, so --max-vus
+also limits the number of them. Their server_ws_* and server_sse_* metrics count
+the connections and the messages sent and received.
+
+The proto files loaded with the loadProto() and loadProtoset() functions of the
+k6/server module, which parse them just like the k6/net/grpc client, describe
+the gRPC methods that the grpc() function registers handlers for. They are
+served on --grpc-listen, along with the gRPC reflection service, and their
+server_grpc_* metrics are tagged with the method and the status code.
+
+Test runs can be submitted to the server by POSTing the archives of the archive
+command to the /v1/runs endpoint of the REST API. They are queued and run one at
+a time with the same pipeline as the run command, in the order they were
+submitted. The /v1/runs/{id} endpoints return the state of a run, and its
+status, metrics and end-of-test summary, and POSTing to /v1/runs/{id}/cancel
+stops it like Ctrl+C does.`,
+		Example: exampleText,
+		Args:    exactArgsWithMsg(1, "arg should either be \"-\", if reading script from stdin, or a path to a script file"),
+		RunE:    c.run,
 	}
+
+	serverCmd.Flags().SortFlags = false
+	serverCmd.Flags().AddFlagSet(c.flagSet())
+
+	return serverCmd
 }
@@ -0,0 +1,469 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	uuid "github.com/nu7hatch/gouuid"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"gopkg.in/guregu/null.v3"
+
+	v1 "github.com/liuxd6825/k6server/api/v1"
+	"github.com/liuxd6825/k6server/cmd/state"
+	"github.com/liuxd6825/k6server/errext"
+	"github.com/liuxd6825/k6server/event"
+	"github.com/liuxd6825/k6server/lib"
+	"github.com/liuxd6825/k6server/lib/fsext"
+	"github.com/liuxd6825/k6server/ui/console"
+)
+
+const (
+	defaultMaxQueuedRuns     = 10
+	defaultMaxEndedRuns      = 100
+	defaultEndedRunRetention = 24 * time.Hour
+
+	// runSummaryExport is where the summary of a submitted test run is
+	// exported to, on the in-memory filesystem of the run.
+	runSummaryExport = "/summary.json"
+)
+
+// testRunQueue runs the archives submitted through the REST API of the server
+// mode with the same pipeline as `k6 run`. They are run one at a time, in the
+// order they were submitted, so that they don't compete for the resources of
+// the machine.
+type testRunQueue struct {
+	gs     *state.GlobalState
+	limits testRunLimits
+
+	mu      sync.Mutex
+	runs    []*queuedRun // all of them, in the order they were submitted
+	pending []*queuedRun
+	closed  bool
+
+	wake chan struct{}
+	done chan struct{}
+}
+
+var _ v1.TestRuns = &testRunQueue{}
+
+// testRunLimits are the limits of the runs of a testRunQueue. The ended runs
+// over them are forgotten, the ones that ended first before the others.
+type testRunLimits struct {
+	maxQueued          int           // the runs waiting for the current one
+	maxEnded           int           // the ended runs that are kept, 0 for no limit
+	endedRunsRetention time.Duration // how long the ended runs are kept, 0 for no limit
+}
+
+// queuedRun is a submitted test run, whose fields are guarded by the mutex of
+// its queue, except for output, which is only read after it has ended.
+type queuedRun struct {
+	v1.Run
+	archive []byte
+
+	cs        *v1.ControlSurface // set while it's running, once it's initialized
+	snapshot  *v1.RunSnapshot    // set once it has ended, if it was initialized
+	sigC      chan<- os.Signal   // set while its signals are trapped
+	cancelled bool
+	output    bytes.Buffer
+	summary   v1.RunSummary
+}
+
+func newTestRunQueue(gs *state.GlobalState, limits testRunLimits) *testRunQueue {
+	return &testRunQueue{
+		gs:     gs,
+		limits: limits,
+		wake:   make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+}
+
+// start starts running the submitted archives, until stop is called.
+func (q *testRunQueue) start() {
+	go func() {
+		defer close(q.done)
+		for run := q.next(); run != nil; run = q.next() {
+			q.execute(run)
+		}
+	}()
+}
+
+// stop cancels the queued runs and the running one, which is stopped
+// gracefully, and waits for it to end.
+func (q *testRunQueue) stop() {
+	q.mu.Lock()
+	q.closed = true
+	now := null.TimeFrom(time.Now())
+	for _, run := range q.pending {
+		run.Status, run.Ended, run.archive = v1.RunCancelled, now, nil
+	}
+	q.pending = nil
+	for _, run := range q.runs {
+		if run.Status == v1.RunRunning {
+			q.interrupt(run)
+		}
+	}
+	q.mu.Unlock()
+
+	q.notify()
+	<-q.done
+}
+
+func (q *testRunQueue) notify() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// next waits for the next queued run and marks it as running. It returns nil
+// once the queue is stopped.
+func (q *testRunQueue) next() *queuedRun {
+	for {
+		q.mu.Lock()
+		if q.closed {
+			q.mu.Unlock()
+			return nil
+		}
+		if len(q.pending) > 0 {
+			run := q.pending[0]
+			q.pending = q.pending[1:]
+			run.Status, run.Started = v1.RunRunning, null.TimeFrom(time.Now())
+			q.mu.Unlock()
+			return run
+		}
+		q.mu.Unlock()
+		<-q.wake
+	}
+}
+
+// interrupt stops a running test run like Ctrl+C does, so the first time it's
+// stopped gracefully, with the end-of-test summary, and the second time it's
+// aborted right away.
+func (q *testRunQueue) interrupt(run *queuedRun) {
+	run.cancelled = true
+	if run.sigC == nil {
+		return // the signal is sent once they are trapped
+	}
+	select {
+	case run.sigC <- os.Interrupt:
+	default:
+	}
+}
+
+func (q *testRunQueue) execute(run *queuedRun) {
+	logger := q.gs.Logger.WithField("run", run.ID)
+	logger.Infof("Starting the test run of %s", run.Script)
+
+	ctx, abort := context.WithCancel(q.gs.Ctx)
+	defer abort()
+	gs := q.newRunState(ctx, abort, run)
+
+	c := newCmdRun(gs)
+	c.testRunInitialized = func(cs *v1.ControlSurface) {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		run.cs = cs
+	}
+	cmd := &cobra.Command{Use: "run"}
+	cmd.Flags().AddFlagSet(c.flagSet())
+	err := cmd.Flags().Set("summary-export", runSummaryExport)
+	if err == nil {
+		err = c.run(cmd, []string{"-"})
+	}
+
+	summary := v1.RunSummary{Text: run.output.String()}
+	if data, rerr := fsext.ReadFile(gs.FS, runSummaryExport); rerr == nil {
+		summary.Data = data
+	}
+
+	// Only the snapshot of the status and the metrics of the run is kept, so
+	// its VUs and the rest of its test run state can be freed.
+	q.mu.Lock()
+	cs := run.cs
+	q.mu.Unlock()
+	var snapshot *v1.RunSnapshot
+	if cs != nil {
+		s := v1.NewRunSnapshot(cs)
+		snapshot = &s
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	defer q.prune(time.Now())
+	run.Ended, run.summary, run.archive, run.sigC = null.TimeFrom(time.Now()), summary, nil, nil
+	run.cs, run.snapshot = nil, snapshot
+	if err == nil {
+		run.Status, run.ExitCode = v1.RunPassed, null.IntFrom(0)
+		logger.Info("The test run passed")
+		return
+	}
+
+	exitCode := -1
+	var ecerr errext.HasExitCode
+	if errors.As(err, &ecerr) {
+		exitCode = int(ecerr.ExitCode())
+	}
+	run.ExitCode = null.IntFrom(int64(exitCode))
+	run.Error, _ = errext.Format(err)
+	if run.cancelled {
+		run.Status = v1.RunCancelled
+		logger.Info("The test run was cancelled")
+	} else {
+		run.Status = v1.RunFailed
+		logger.WithError(err).Info("The test run failed")
+	}
+}
+
+// newRunState returns the global state of a test run, which reads the archive
+// from stdin, captures what's written to stdout and stderr, and keeps the
+// files it writes, like the summary, in memory. Its signals are the
+// cancellations of the run, and exiting aborts it.
+func (q *testRunQueue) newRunState(ctx context.Context, abort func(), run *queuedRun) *state.GlobalState {
+	gs := q.gs
+
+	flags := gs.Flags
+	flags.Address = ""                                    // its status is served by the REST API of the server
+	flags.Quiet = true                                    // no banner and progress bars, only the summary
+	flags.ConfigFilePath = gs.DefaultFlags.ConfigFilePath // the options are the ones of the archive
+
+	// The logs of the run go where the ones of the server do, tagged with it.
+	logger := &logrus.Logger{
+		Out:       gs.Logger.Out,
+		Formatter: gs.Logger.Formatter,
+		Hooks:     make(logrus.LevelHooks),
+		Level:     gs.Logger.Level,
+	}
+	logger.AddHook(runLogHook(run.ID))
+	for level, hooks := range gs.Logger.Hooks {
+		logger.Hooks[level] = append(logger.Hooks[level], hooks...)
+	}
+
+	outMutex := &sync.Mutex{}
+	output := &console.Writer{Mutex: outMutex, Writer: &run.output}
+	return &state.GlobalState{
+		Ctx:          ctx,
+		FS:           fsext.NewMemMapFs(),
+		Getwd:        func() (string, error) { return "/", nil },
+		BinaryName:   gs.BinaryName,
+		CmdArgs:      gs.CmdArgs,
+		Env:          gs.Env,
+		Events:       event.NewEventSystem(100, logger),
+		DefaultFlags: gs.DefaultFlags,
+		Flags:        flags,
+		OutMutex:     outMutex,
+		Stdout:       output,
+		Stderr:       output,
+		Stdin:        bytes.NewReader(run.archive),
+		OSExit:       func(int) { abort() },
+		SignalNotify: func(c chan<- os.Signal, _ ...os.Signal) {
+			q.mu.Lock()
+			defer q.mu.Unlock()
+			run.sigC = c
+			if run.cancelled {
+				q.interrupt(run)
+			}
+		},
+		SignalStop: func(chan<- os.Signal) {
+			q.mu.Lock()
+			defer q.mu.Unlock()
+			run.sigC = nil
+		},
+		Logger:         logger,
+		FallbackLogger: gs.FallbackLogger,
+	}
+}
+
+// runLogHook adds the ID of a test run to its log entries.
+type runLogHook string
+
+func (h runLogHook) Levels() []logrus.Level { return logrus.AllLevels }
+
+func (h runLogHook) Fire(entry *logrus.Entry) error {
+	entry.Data["run"] = string(h)
+	return nil
+}
+
+// SubmitRun queues the archive, after checking that it's a valid one.
+func (q *testRunQueue) SubmitRun(archive io.Reader) (v1.Run, error) {
+	data, err := io.ReadAll(archive)
+	if err != nil {
+		return v1.Run{}, fmt.Errorf("%w: %w", v1.ErrInvalidRun, err)
+	}
+	arc, err := lib.ReadArchive(bytes.NewReader(data))
+	if err != nil {
+		return v1.Run{}, fmt.Errorf("%w: %w", v1.ErrInvalidRun, err)
+	}
+	if arc.Type != testTypeJS {
+		return v1.Run{}, fmt.Errorf("%w: unsupported test type '%s'", v1.ErrInvalidRun, arc.Type)
+	}
+	id, err := uuid.NewV4()
+	if err != nil {
+		return v1.Run{}, err
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return v1.Run{}, fmt.Errorf("%w, the server is stopping", v1.ErrRunQueueFull)
+	}
+	if len(q.pending) >= q.limits.maxQueued {
+		return v1.Run{}, fmt.Errorf("%w, at most %d can be", v1.ErrRunQueueFull, q.limits.maxQueued)
+	}
+	run := &queuedRun{
+		Run: v1.Run{
+			ID:        id.String(),
+			Status:    v1.RunQueued,
+			Script:    arc.Filename,
+			Submitted: null.TimeFrom(time.Now()),
+		},
+		archive: data,
+	}
+	q.runs = append(q.runs, run)
+	q.pending = append(q.pending, run)
+	q.notify()
+	return run.Run, nil
+}
+
+// prune forgets the ended runs over the limits of the queue.
+func (q *testRunQueue) prune(now time.Time) {
+	var ended []*queuedRun
+	for _, run := range q.runs {
+		if run.Status.Ended() {
+			ended = append(ended, run)
+		}
+	}
+	sort.SliceStable(ended, func(a, b int) bool { return ended[a].Ended.Time.Before(ended[b].Ended.Time) })
+
+	forgotten := make(map[*queuedRun]bool)
+	for i, run := range ended {
+		tooMany := q.limits.maxEnded > 0 && len(ended)-i > q.limits.maxEnded
+		tooOld := q.limits.endedRunsRetention > 0 && now.Sub(run.Ended.Time) > q.limits.endedRunsRetention
+		if tooMany || tooOld {
+			forgotten[run] = true
+		}
+	}
+	if len(forgotten) == 0 {
+		return
+	}
+	runs := q.runs[:0]
+	for _, run := range q.runs {
+		if !forgotten[run] {
+			runs = append(runs, run)
+		}
+	}
+	clear(q.runs[len(runs):])
+	q.runs = runs
+}
+
+// Runs returns all of the submitted runs that are kept, in the order they
+// were submitted.
+func (q *testRunQueue) Runs() []v1.Run {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.prune(time.Now())
+	runs := make([]v1.Run, 0, len(q.runs))
+	for _, run := range q.runs {
+		runs = append(runs, run.Run)
+	}
+	return runs
+}
+
+func (q *testRunQueue) find(id string) (*queuedRun, error) {
+	q.prune(time.Now())
+	for _, run := range q.runs {
+		if run.ID == id {
+			return run, nil
+		}
+	}
+	return nil, v1.ErrRunNotFound
+}
+
+func (q *testRunQueue) GetRun(id string) (v1.Run, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	run, err := q.find(id)
+	if err != nil {
+		return v1.Run{}, err
+	}
+	return run.Run, nil
+}
+
+// CancelRun removes a queued run from the queue, or stops a running one. The
+// running ones are stopped gracefully, like with Ctrl+C, and are aborted if
+// they are cancelled again.
+func (q *testRunQueue) CancelRun(id string) (v1.Run, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	run, err := q.find(id)
+	if err != nil {
+		return v1.Run{}, err
+	}
+	switch run.Status {
+	case v1.RunQueued:
+		for i, pending := range q.pending {
+			if pending == run {
+				q.pending = append(q.pending[:i], q.pending[i+1:]...)
+				break
+			}
+		}
+		run.Status, run.Ended, run.archive = v1.RunCancelled, null.TimeFrom(time.Now()), nil
+	case v1.RunRunning:
+		q.interrupt(run)
+	default:
+		return run.Run, v1.ErrRunEnded
+	}
+	return run.Run, nil
+}
+
+func (q *testRunQueue) RunControlSurface(id string) (*v1.ControlSurface, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	run, err := q.find(id)
+	if err != nil {
+		return nil, err
+	}
+	if run.Status.Ended() {
+		return nil, v1.ErrRunEnded
+	}
+	if run.cs == nil {
+		return nil, v1.ErrRunNotStarted
+	}
+	return run.cs, nil
+}
+
+func (q *testRunQueue) RunSnapshot(id string) (v1.RunSnapshot, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	run, err := q.find(id)
+	if err != nil {
+		return v1.RunSnapshot{}, err
+	}
+	if !run.Status.Ended() {
+		return v1.RunSnapshot{}, v1.ErrRunNotEnded
+	}
+	if run.snapshot == nil {
+		return v1.RunSnapshot{}, v1.ErrRunNotStarted
+	}
+	return *run.snapshot, nil
+}
+
+func (q *testRunQueue) RunSummary(id string) (v1.RunSummary, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	run, err := q.find(id)
+	if err != nil {
+		return v1.RunSummary{}, err
+	}
+	if !run.Status.Ended() {
+		return v1.RunSummary{}, v1.ErrRunNotEnded
+	}
+	return run.summary, nil
+}
@@ -0,0 +1,197 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v3"
+
+	v1 "github.com/liuxd6825/k6server/api/v1"
+	"github.com/liuxd6825/k6server/cmd/tests"
+	"github.com/liuxd6825/k6server/errext/exitcodes"
+	"github.com/liuxd6825/k6server/lib"
+	"github.com/liuxd6825/k6server/lib/consts"
+	"github.com/liuxd6825/k6server/lib/fsext"
+)
+
+// getTestArchive returns the archive of the script, with the given options,
+// like the ones `k6 archive` makes.
+func getTestArchive(t *testing.T, script, options string) []byte {
+	t.Helper()
+
+	var opts lib.Options
+	require.NoError(t, json.Unmarshal([]byte(options), &opts))
+	fs := fsext.NewMemMapFs()
+	require.NoError(t, fsext.WriteFile(fs, "/test/script.js", []byte(script), 0o644))
+	arc := &lib.Archive{
+		Type:        testTypeJS,
+		Options:     opts,
+		FilenameURL: &url.URL{Scheme: "file", Path: "/test/script.js"},
+		Data:        []byte(script),
+		PwdURL:      &url.URL{Scheme: "file", Path: "/test/"},
+		Filesystems: map[string]fsext.Fs{"file": fs},
+		K6Version:   consts.Version,
+	}
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, arc.Write(buf))
+	return buf.Bytes()
+}
+
+func waitForRun(t *testing.T, q *testRunQueue, id string, done func(v1.Run) bool) v1.Run {
+	t.Helper()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		run, err := q.GetRun(id)
+		require.NoError(t, err)
+		if done(run) {
+			return run
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("the run %s is still %s", id, run.Status)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func runEnded(run v1.Run) bool { return run.Status.Ended() }
+
+func TestTestRunQueue(t *testing.T) {
+	t.Parallel()
+
+	passing := getTestArchive(t, `export default function () {}`,
+		`{"iterations": 2, "thresholds": {"iterations": ["count == 2"]}}`)
+	failing := getTestArchive(t, `export default function () {}`,
+		`{"iterations": 1, "thresholds": {"iterations": ["count > 1"]}}`)
+
+	ts := tests.NewGlobalTestState(t)
+	q := newTestRunQueue(ts.GlobalState, testRunLimits{maxQueued: 10})
+	q.start()
+	defer q.stop()
+
+	first, err := q.SubmitRun(bytes.NewReader(passing))
+	require.NoError(t, err)
+	assert.Equal(t, v1.RunQueued, first.Status)
+	assert.Equal(t, "file:///test/script.js", first.Script)
+	second, err := q.SubmitRun(bytes.NewReader(failing))
+	require.NoError(t, err)
+
+	run := waitForRun(t, q, first.ID, runEnded)
+	assert.Equal(t, v1.RunPassed, run.Status)
+	assert.Equal(t, int64(0), run.ExitCode.Int64)
+	assert.True(t, run.Started.Valid)
+	assert.True(t, run.Ended.Valid)
+
+	summary, err := q.RunSummary(first.ID)
+	require.NoError(t, err)
+	assert.Contains(t, summary.Text, "iterations")
+	var data struct {
+		Metrics map[string]map[string]interface{} `json:"metrics"`
+	}
+	require.NoError(t, json.Unmarshal(summary.Data, &data))
+	assert.Equal(t, 2.0, data.Metrics["iterations"]["count"])
+
+	// the control surface of the ended run isn't kept, only its snapshot
+	_, err = q.RunControlSurface(first.ID)
+	assert.ErrorIs(t, err, v1.ErrRunEnded)
+	snapshot, err := q.RunSnapshot(first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, lib.ExecutionStatusEnded, snapshot.Status.Status().Status)
+	assert.NotEmpty(t, snapshot.Metrics.Data)
+
+	run = waitForRun(t, q, second.ID, runEnded)
+	assert.Equal(t, v1.RunFailed, run.Status)
+	assert.Equal(t, int64(exitcodes.ThresholdsHaveFailed), run.ExitCode.Int64)
+	assert.Contains(t, run.Error, "thresholds on metrics 'iterations' have been crossed")
+
+	runs := q.Runs()
+	require.Len(t, runs, 2)
+	assert.Equal(t, first.ID, runs[0].ID)
+	assert.Equal(t, second.ID, runs[1].ID)
+
+	_, err = q.CancelRun(first.ID)
+	assert.ErrorIs(t, err, v1.ErrRunEnded)
+	_, err = q.GetRun("nope")
+	assert.ErrorIs(t, err, v1.ErrRunNotFound)
+	_, err = q.SubmitRun(bytes.NewReader([]byte("export default function() {}")))
+	assert.ErrorIs(t, err, v1.ErrInvalidRun)
+}
+
+func TestTestRunQueueCancel(t *testing.T) {
+	t.Parallel()
+
+	long := getTestArchive(t, `
+		import { sleep } from "k6";
+		export default function () { sleep(0.05); }
+	`, `{"vus": 1, "duration": "1m"}`)
+
+	ts := tests.NewGlobalTestState(t)
+	q := newTestRunQueue(ts.GlobalState, testRunLimits{maxQueued: 1})
+	q.start()
+
+	running, err := q.SubmitRun(bytes.NewReader(long))
+	require.NoError(t, err)
+	waitForRun(t, q, running.ID, func(run v1.Run) bool {
+		cs, _ := q.RunControlSurface(run.ID)
+		return cs != nil && cs.Scheduler.GetState().GetFullIterationCount() > 0
+	})
+
+	queued, err := q.SubmitRun(bytes.NewReader(long))
+	require.NoError(t, err)
+	_, err = q.SubmitRun(bytes.NewReader(long))
+	assert.ErrorIs(t, err, v1.ErrRunQueueFull)
+
+	run, err := q.CancelRun(queued.ID)
+	require.NoError(t, err)
+	assert.Equal(t, v1.RunCancelled, run.Status)
+
+	_, err = q.CancelRun(running.ID)
+	require.NoError(t, err)
+	run = waitForRun(t, q, running.ID, runEnded)
+	assert.Equal(t, v1.RunCancelled, run.Status)
+	assert.Equal(t, int64(exitcodes.ExternalAbort), run.ExitCode.Int64)
+	summary, err := q.RunSummary(running.ID)
+	require.NoError(t, err)
+	assert.Contains(t, summary.Text, "iterations")
+
+	// the queued runs are cancelled when the queue is stopped
+	pending, err := q.SubmitRun(bytes.NewReader(long))
+	require.NoError(t, err)
+	q.stop()
+	run, err = q.GetRun(pending.ID)
+	require.NoError(t, err)
+	assert.Equal(t, v1.RunCancelled, run.Status)
+}
+
+func TestTestRunQueuePrune(t *testing.T) {
+	t.Parallel()
+
+	ts := tests.NewGlobalTestState(t)
+	q := newTestRunQueue(ts.GlobalState, testRunLimits{maxQueued: 10, maxEnded: 2, endedRunsRetention: time.Hour})
+
+	now := time.Now()
+	for i, ended := range []time.Duration{3 * time.Hour, 30 * time.Minute, 20 * time.Minute, 10 * time.Minute, 0} {
+		run := &queuedRun{Run: v1.Run{ID: strconv.Itoa(i), Status: v1.RunPassed, Ended: null.TimeFrom(now.Add(-ended))}}
+		if ended == 0 {
+			run.Status, run.Ended = v1.RunRunning, null.Time{}
+		}
+		q.runs = append(q.runs, run)
+	}
+
+	// the runs that ended too long ago and the oldest ones over the limit
+	// are forgotten, but not the ones that haven't ended
+	var ids []string
+	for _, run := range q.Runs() {
+		ids = append(ids, run.ID)
+	}
+	assert.Equal(t, []string{"2", "3", "4"}, ids)
+	_, err := q.GetRun("0")
+	assert.ErrorIs(t, err, v1.ErrRunNotFound)
+}
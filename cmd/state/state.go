@@ -17,7 +17,10 @@ import (
 	"github.com/liuxd6825/k6server/ui/console"
 )
 
-const defaultConfigFileName = "config.json"
+const (
+	defaultConfigFileName = "config.json"
+	defaultHistoryDirName = "history"
+)
 
 // GlobalState contains the GlobalFlags and accessors for most of the global
 // process-external state like CLI arguments, env vars, standard input, output
@@ -135,10 +138,17 @@ func NewGlobalState(ctx context.Context) *GlobalState {
 // GlobalFlags contains global config values that apply for all k6 sub-commands.
 type GlobalFlags struct {
 	ConfigFilePath   string
+	HistoryDir       string
 	Quiet            bool
 	NoColor          bool
 	Address          string
 	ProfilingEnabled bool
+	APIToken         string
+	APITLSCert       string
+	APITLSKey        string
+	APITLSClientCA   string
+	APIReadOnly      bool
+	APILogBuffer     int
 	LogOutput        string
 	LogFormat        string
 	Verbose          bool
@@ -150,7 +160,9 @@ func GetDefaultFlags(homeDir string) GlobalFlags {
 		Address:          "localhost:6565",
 		ProfilingEnabled: false,
 		ConfigFilePath:   filepath.Join(homeDir, "loadimpact", "k6", defaultConfigFileName),
+		HistoryDir:       filepath.Join(homeDir, "loadimpact", "k6", defaultHistoryDirName),
 		LogOutput:        "stderr",
+		APILogBuffer:     1000,
 	}
 }
 
@@ -163,6 +175,9 @@ func getFlags(defaultFlags GlobalFlags, env map[string]string) GlobalFlags {
 	if val, ok := env["K6_CONFIG"]; ok {
 		result.ConfigFilePath = val
 	}
+	if val, ok := env["K6_HISTORY_DIR"]; ok {
+		result.HistoryDir = val
+	}
 	if val, ok := env["K6_LOG_OUTPUT"]; ok {
 		result.LogOutput = val
 	}
@@ -180,5 +195,10 @@ func getFlags(defaultFlags GlobalFlags, env map[string]string) GlobalFlags {
 	if _, ok := env["K6_PROFILING_ENABLED"]; ok {
 		result.ProfilingEnabled = true
 	}
+	// The token is better kept out of the command line, where the other users
+	// of the host can see it.
+	if val, ok := env["K6_API_TOKEN"]; ok {
+		result.APIToken = val
+	}
 	return result
 }
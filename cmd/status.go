@@ -3,20 +3,22 @@ package cmd
 import (
 	"github.com/spf13/cobra"
 
-	"github.com/liuxd6825/k6server/api/v1/client"
 	"github.com/liuxd6825/k6server/cmd/state"
 )
 
 func getCmdStatus(gs *state.GlobalState) *cobra.Command {
+	var clientFlags apiClientFlags
+
 	// statusCmd represents the status command
 	statusCmd := &cobra.Command{
 		Use:   "status",
 		Short: "Show test status",
 		Long: `Show test status.
 
-  Use the global --address flag to specify the URL to the API server.`,
+  Use the global --address flag to specify the URL to the API server, and the
+  global --api-token flag for the servers which require a bearer token.`,
 		RunE: func(_ *cobra.Command, _ []string) error {
-			c, err := client.New(gs.Flags.Address)
+			c, err := newAPIClient(gs, &clientFlags)
 			if err != nil {
 				return err
 			}
@@ -28,5 +30,7 @@ func getCmdStatus(gs *state.GlobalState) *cobra.Command {
 			return yamlPrint(gs.Stdout, status)
 		},
 	}
+	statusCmd.Flags().AddFlagSet(clientFlags.flagSet())
+
 	return statusCmd
 }
@@ -203,6 +203,11 @@ func (lt *loadedTest) consolidateDeriveAndValidateConfig(
 		return nil, err
 	}
 
+	consolidatedConfig.Scenarios, err = lt.loadScenarioFiles(consolidatedConfig.Scenarios)
+	if err != nil {
+		return nil, errext.WithExitCodeIfNone(err, exitcodes.InvalidConfig)
+	}
+
 	gs.Logger.Debug("Parsing thresholds and validating config...")
 	// Parse the thresholds, only if the --no-threshold flag is not set.
 	// If parsing the threshold expressions failed, consider it as an
@@ -233,6 +238,42 @@ func (lt *loadedTest) consolidateDeriveAndValidateConfig(
 	}, nil
 }
 
+// loadScenarioFiles returns the scenarios with the options loaded from their
+// files, e.g. the traces of the trace-replay ones. The relative paths are
+// resolved from the working directory.
+func (lt *loadedTest) loadScenarioFiles(scenarios lib.ScenarioConfigs) (lib.ScenarioConfigs, error) {
+	readFile := func(filename string) ([]byte, error) {
+		if !filepath.IsAbs(filename) {
+			filename = filepath.Join(lt.pwd, filename)
+		}
+		return fsext.ReadFile(lt.fs, filename)
+	}
+
+	var result lib.ScenarioConfigs
+	for name, conf := range scenarios {
+		withFiles, ok := conf.(lib.ExecutorConfigWithFiles)
+		if !ok {
+			continue
+		}
+		loaded, err := withFiles.LoadFiles(readFile)
+		if err != nil {
+			return nil, fmt.Errorf("scenario %s: %w", name, err)
+		}
+		if result == nil {
+			// the scenarios may be shared with the options of the runner
+			result = make(lib.ScenarioConfigs, len(scenarios))
+			for k, v := range scenarios {
+				result[k] = v
+			}
+		}
+		result[name] = loaded
+	}
+	if result == nil {
+		return scenarios, nil
+	}
+	return result, nil
+}
+
 // loadedAndConfiguredTest contains the whole loadedTest, as well as the
 // consolidated test config and the full test run state.
 type loadedAndConfiguredTest struct {
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	v1 "github.com/liuxd6825/k6server/api/v1"
+	"github.com/liuxd6825/k6server/cmd/state"
+)
+
+func getCmdVUs(gs *state.GlobalState) *cobra.Command {
+	var clientFlags apiClientFlags
+
+	// vusCmd represents the vus command
+	vusCmd := &cobra.Command{
+		Use:   "vus",
+		Short: "Show the state of the VUs",
+		Long: `Show the state of the VUs.
+
+  Every initialized VU is listed with the scenario and the iteration it's
+  running, the time spent in the iteration, its tags and metadata, and whether
+  it's waiting on the event loop for asynchronous work, e.g. promises or timers,
+  which is useful for debugging the tests with stuck VUs.
+
+  Use the global --address flag to specify the URL to the API server, and the
+  global --api-token flag for the servers which require a bearer token.`,
+		Args: cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			c, err := newAPIClient(gs, &clientFlags)
+			if err != nil {
+				return err
+			}
+			vus, err := c.VUs(gs.Ctx)
+			if err != nil {
+				return err
+			}
+
+			w := tabwriter.NewWriter(gs.Stdout, 0, 0, 2, ' ', 0)
+			_, _ = fmt.Fprintln(w, "VU\tGLOBAL\tSTATE\tSCENARIO\tITERATION\tTIME\tTAGS\tMETADATA")
+			for _, vu := range vus {
+				scenario, iteration, duration := "-", "-", "-"
+				if vu.Scenario != "" {
+					scenario = vu.Scenario
+					iteration = fmt.Sprintf("%d (%d)", vu.ScenarioIteration, vu.Iteration)
+				}
+				if vu.IterationDuration.Valid {
+					duration = vu.IterationDuration.TimeDuration().Round(time.Millisecond).String()
+				}
+				_, _ = fmt.Fprintf(w, "%d\t%d\t%s\t%s\t%s\t%s\t%s\t%s\n", vu.ID, vu.IDGlobal, vuStateText(vu),
+					scenario, iteration, duration, formatKeyValues(vu.Tags), formatKeyValues(vu.Metadata))
+			}
+			return w.Flush()
+		},
+	}
+	vusCmd.Flags().AddFlagSet(clientFlags.flagSet())
+
+	return vusCmd
+}
+
+func vuStateText(vu v1.VU) string {
+	switch {
+	case !vu.Active:
+		return "idle"
+	case vu.InEventLoop:
+		return "event loop"
+	case vu.IterationDuration.Valid:
+		return "running"
+	default:
+		return "active"
+	}
+}
+
+// formatKeyValues formats the map as a list of key=value pairs, sorted by the
+// keys.
+func formatKeyValues(m map[string]string) string {
+	if len(m) == 0 {
+		return "-"
+	}
+	pairs := make([]string, 0, len(m))
+	for k, v := range m {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
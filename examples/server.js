@@ -0,0 +1,57 @@
+import { get, post, use, faults, ws, sse } from "k6/server";
+
+// Run with `k6 server examples/server.js` and try `curl localhost:8080/users/42`.
+
+let users = {};
+
+use("/users", function(req, res) {
+    res.header("X-Mock", "k6");
+});
+
+get("/users/:id", function(req, res) {
+    let user = users[req.params.id];
+    if (!user) {
+        res.status(404).json({ error: "no such user" });
+        return;
+    }
+    res.json(user);
+});
+
+post("/users/:id", function(req, res) {
+    users[req.params.id] = req.json();
+    res.status(201).json(users[req.params.id]);
+});
+
+// Every tenth read of a user fails and all of them are a bit slow. The faults can
+// be changed at runtime with the /v1/faults endpoints of the REST API.
+faults("GET", "/users/:id", {
+    latency: { distribution: "normal", mean: "50ms", stdDev: "20ms" },
+    error: { rate: 0.1, status: 503 },
+});
+
+// Echoes the messages of the WebSocket clients, e.g. of the k6/ws module.
+ws("/echo", {
+    open(socket, req) {
+        socket.send("hello " + req.remoteAddr);
+    },
+    message(socket, data) {
+        socket.send(data);
+    },
+});
+
+// Sends the number of users every second, try `curl -N localhost:8080/users-count`.
+// Every connection has a VU of its own, so it's the only one using the timer.
+let timer;
+sse("/users-count", {
+    open(stream) {
+        timer = setInterval(function() {
+            stream.send({ event: "count", data: { count: Object.keys(users).length } });
+        }, 1000);
+    },
+    close(stream) {
+        // the timers have to be cleared, or they are stopped with a warning
+        clearInterval(timer);
+    },
+});
+
+export default function() {}
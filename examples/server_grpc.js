@@ -0,0 +1,62 @@
+import { loadProto, grpc } from "k6/server";
+import { StatusNotFound } from "k6/net/grpc";
+
+// Run with `k6 server examples/server_grpc.js` and then, e.g.,
+// `GRPC_ADDR=127.0.0.1:9090 k6 run examples/grpc_invoke.js`. The server has the
+// reflection service enabled, so `grpcurl -plaintext localhost:9090 list` works too.
+
+loadProto([], "../lib/testutils/grpcservice/route_guide.proto");
+
+let features = {};
+
+grpc("main.FeatureExplorer/GetFeature", function(call, point) {
+    let feature = features[point.latitude + ":" + point.longitude];
+    if (!feature) {
+        // an unknown location has a feature with no name, like in the real server
+        call.send({ name: "", location: point });
+        return;
+    }
+    call.send(feature);
+});
+
+// Streams the known features within the rectangle, one every 100ms.
+grpc("main.FeatureExplorer/ListFeatures", function(call, rect) {
+    let found = Object.values(features).filter(function(f) {
+        return f.location.latitude >= rect.lo.latitude && f.location.latitude <= rect.hi.latitude &&
+            f.location.longitude >= rect.lo.longitude && f.location.longitude <= rect.hi.longitude;
+    });
+    let timer = setInterval(function() {
+        if (found.length === 0) {
+            clearInterval(timer);
+            call.end();
+            return;
+        }
+        call.send(found.shift());
+    }, 100);
+});
+
+// Records the points sent by the client as features, and responds once it's done.
+let count;
+grpc("main.RouteGuide/RecordRoute", {
+    open(call) {
+        count = 0;
+    },
+    message(call, point) {
+        count++;
+        features[point.latitude + ":" + point.longitude] = { name: "point " + count, location: point };
+    },
+    end(call) {
+        if (count === 0) {
+            call.error(StatusNotFound, "no points were recorded");
+            return;
+        }
+        call.send({ pointCount: count });
+    },
+});
+
+// Echoes the notes of the client.
+grpc("main.RouteGuide/RouteChat", function(call, note) {
+    call.send(note);
+});
+
+export default function() {}
@@ -0,0 +1,288 @@
+package distributed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/liuxd6825/k6server/errext"
+	"github.com/liuxd6825/k6server/errext/exitcodes"
+	"github.com/liuxd6825/k6server/execution"
+)
+
+// AgentController implements the execution.Controller of an instance of a
+// distributed test run, by synchronizing it with the other instances through
+// the coordinator of the test run.
+type AgentController struct {
+	instanceID uint32
+	stream     DistributedTest_CommandAndControlClient
+	logger     logrus.FieldLogger
+
+	sendMu sync.Mutex
+
+	mu     sync.Mutex
+	events map[string]*agentEvent
+	data   map[string]*agentData
+	err    error // set once the stream is closed
+
+	abortOnce sync.Once
+	aborted   chan struct{}
+	abortErr  error
+
+	done chan struct{}
+}
+
+var _ execution.Controller = &AgentController{}
+
+type agentEvent struct {
+	done chan struct{}
+	err  error
+}
+
+type agentData struct {
+	callback func() ([]byte, error)
+	done     chan struct{}
+	data     []byte
+	err      error
+}
+
+// NewAgentController opens the command and control stream of the instance
+// with the given ID, which should have been registered with the coordinator.
+func NewAgentController(
+	ctx context.Context, instanceID uint32, client DistributedTestClient, logger logrus.FieldLogger,
+) (*AgentController, error) {
+	stream, err := client.CommandAndControl(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = stream.Send(&AgentMessage{Message: &AgentMessage_InitInstanceID{InitInstanceID: instanceID}})
+	if err != nil {
+		return nil, err
+	}
+
+	c := &AgentController{
+		instanceID: instanceID,
+		stream:     stream,
+		logger:     logger.WithField("component", "agent-controller"),
+		events:     make(map[string]*agentEvent),
+		data:       make(map[string]*agentData),
+		aborted:    make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	go c.receive()
+	return c, nil
+}
+
+// Close closes the stream and waits for the coordinator to close it as well.
+func (c *AgentController) Close() error {
+	c.sendMu.Lock()
+	err := c.stream.CloseSend()
+	c.sendMu.Unlock()
+	<-c.done
+	return err
+}
+
+// Aborted is closed once the coordinator has aborted the test run, whose
+// error is then returned by AbortError.
+func (c *AgentController) Aborted() <-chan struct{} {
+	return c.aborted
+}
+
+// AbortError returns the error the coordinator has aborted the test run with.
+func (c *AgentController) AbortError() error {
+	select {
+	case <-c.aborted:
+		return c.abortErr
+	default:
+		return nil
+	}
+}
+
+func (c *AgentController) send(msg *AgentMessage) error {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+	return c.stream.Send(msg)
+}
+
+func (c *AgentController) receive() {
+	defer close(c.done)
+	for {
+		msg, err := c.stream.Recv()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				err = errors.New("the coordinator closed the connection")
+			}
+			c.fail(err)
+			return
+		}
+		switch m := msg.Message.(type) {
+		case *ControllerMessage_EventDone:
+			c.eventDone(m.EventDone)
+		case *ControllerMessage_DataWithID:
+			c.dataDone(m.DataWithID.Id, m.DataWithID.Data, packetError(m.DataWithID.Error))
+		case *ControllerMessage_CreateDataWithID:
+			go c.createData(m.CreateDataWithID)
+		case *ControllerMessage_Abort:
+			c.abortOnce.Do(func() {
+				c.abortErr = errors.New(m.Abort.Error)
+				if m.Abort.ExitCode != 0 {
+					c.abortErr = errext.WithExitCodeIfNone(c.abortErr, exitcodes.ExitCode(m.Abort.ExitCode))
+				}
+				close(c.aborted)
+			})
+		default:
+			c.logger.Warnf("Received an unexpected message %T", msg.Message)
+		}
+	}
+}
+
+func packetError(msg string) error {
+	if msg == "" {
+		return nil
+	}
+	return errors.New(msg)
+}
+
+// fail makes all of the events and data that are being waited for fail.
+func (c *AgentController) fail(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.err = fmt.Errorf("lost the connection to the coordinator: %w", err)
+	for _, ev := range c.events {
+		select {
+		case <-ev.done:
+		default:
+			ev.err = c.err
+			close(ev.done)
+		}
+	}
+	for _, d := range c.data {
+		select {
+		case <-d.done:
+		default:
+			d.err = c.err
+			close(d.done)
+		}
+	}
+}
+
+func (c *AgentController) event(id string) *agentEvent {
+	ev, ok := c.events[id]
+	if !ok {
+		ev = &agentEvent{done: make(chan struct{})}
+		c.events[id] = ev
+	}
+	return ev
+}
+
+func (c *AgentController) eventDone(signal *Signal) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ev := c.event(signal.EventID)
+	select {
+	case <-ev.done:
+	default:
+		ev.err = packetError(signal.Error)
+		close(ev.done)
+	}
+}
+
+// Subscribe returns a function that waits until all of the instances have
+// reached the event, or until one of them has signalled an error for it.
+func (c *AgentController) Subscribe(eventID string) func() error {
+	c.mu.Lock()
+	ev := c.event(eventID)
+	if c.err != nil {
+		select {
+		case <-ev.done:
+		default:
+			ev.err = c.err
+			close(ev.done)
+		}
+	}
+	c.mu.Unlock()
+
+	return func() error {
+		<-ev.done
+		return ev.err
+	}
+}
+
+// Signal notifies the coordinator that this instance has reached the event, or
+// that it has had an error.
+func (c *AgentController) Signal(eventID string, err error) error {
+	signal := &Signal{EventID: eventID}
+	if err != nil {
+		signal.Error = err.Error()
+	}
+	if sendErr := c.send(&AgentMessage{Message: &AgentMessage_Signal{Signal: signal}}); sendErr != nil {
+		return fmt.Errorf("couldn't signal the event '%s' to the coordinator: %w", eventID, sendErr)
+	}
+	return nil
+}
+
+// GetOrCreateData returns the data with the given ID. The callback is called
+// only by the first of the instances to request it, and its result is then
+// sent to all of the others.
+func (c *AgentController) GetOrCreateData(id string, callback func() ([]byte, error)) ([]byte, error) {
+	c.mu.Lock()
+	d, ok := c.data[id]
+	if !ok {
+		d = &agentData{callback: callback, done: make(chan struct{})}
+		c.data[id] = d
+	}
+	err := c.err
+	c.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		msg := &AgentMessage{Message: &AgentMessage_GetOrCreateDataWithID{GetOrCreateDataWithID: id}}
+		if err := c.send(msg); err != nil {
+			c.dataDone(id, nil, fmt.Errorf("couldn't request the data '%s' from the coordinator: %w", id, err))
+		}
+	}
+	<-d.done
+	return d.data, d.err
+}
+
+func (c *AgentController) createData(id string) {
+	c.mu.Lock()
+	d, ok := c.data[id]
+	c.mu.Unlock()
+	if !ok {
+		c.logger.Warnf("The coordinator requested the creation of the unknown data '%s'", id)
+		return
+	}
+
+	c.logger.Debugf("Creating the data '%s'", id)
+	data, err := d.callback()
+	packet := &DataPacket{Id: id, Data: data}
+	if err != nil {
+		packet.Error = err.Error()
+	}
+	if sendErr := c.send(&AgentMessage{Message: &AgentMessage_CreatedData{CreatedData: packet}}); sendErr != nil {
+		c.logger.WithError(sendErr).Errorf("Couldn't send the data '%s' to the coordinator", id)
+	}
+	c.dataDone(id, data, err)
+}
+
+func (c *AgentController) dataDone(id string, data []byte, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	d, ok := c.data[id]
+	if !ok {
+		return
+	}
+	select {
+	case <-d.done:
+	default:
+		d.data, d.err = data, err
+		close(d.done)
+	}
+}
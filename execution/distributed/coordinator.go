@@ -0,0 +1,460 @@
+package distributed
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"gopkg.in/guregu/null.v3"
+
+	"github.com/liuxd6825/k6server/errext"
+	"github.com/liuxd6825/k6server/lib"
+)
+
+// Coordinator is the gRPC server of a distributed test run. It hands out the
+// execution segments of the test to the agents that register with it, and it
+// synchronizes them: the events they signal are barriers, which are done once
+// all of the instances have reached them or when one of them fails, and the
+// data they get or create is created only once, by the first one to request
+// it, and then sent to all of them.
+type Coordinator struct {
+	UnimplementedDistributedTestServer
+
+	logger        logrus.FieldLogger
+	instanceCount int
+	archives      [][]byte // with the execution segment of each instance
+
+	mu         sync.Mutex
+	registered int
+	agents     map[uint32]*agentStream
+	finished   map[uint32]struct{} // the instances that closed their stream
+	gone       map[uint32]error    // why the disconnected instances can't reach the events
+	events     map[string]*coordinatorEvent
+	data       map[string]*coordinatorData
+	err        error // the first error signalled by the instances
+	abort      *Abort
+
+	aggregator *MetricsAggregator
+	done       chan struct{}
+}
+
+var _ DistributedTestServer = &Coordinator{}
+
+type coordinatorEvent struct {
+	signalled map[uint32]struct{}
+	done      *Signal // set once it's done
+}
+
+type coordinatorData struct {
+	creator uint32
+	packet  *DataPacket // set once it's created
+	waiting []uint32
+}
+
+// agentStream serializes the messages sent on the stream of an agent.
+type agentStream struct {
+	mu     sync.Mutex
+	stream DistributedTest_CommandAndControlServer
+}
+
+func (a *agentStream) send(msg *ControllerMessage) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.stream.Send(msg)
+}
+
+// delivery is a message to be sent to an agent, once the lock of the
+// coordinator is released.
+type delivery struct {
+	agent *agentStream
+	msg   *ControllerMessage
+}
+
+// NewCoordinator returns the coordinator of the test in the archive, which is
+// split into instanceCount execution segments. They are the ones of the
+// execution segment sequence of the test, if it has one, or equal ones.
+func NewCoordinator(arc *lib.Archive, instanceCount int, logger logrus.FieldLogger) (*Coordinator, error) {
+	if instanceCount < 1 {
+		return nil, fmt.Errorf("the instance count should be at least 1, not %d", instanceCount)
+	}
+	if arc.Options.ExecutionSegment != nil {
+		return nil, errors.New("the execution segments of a distributed test run are set by its coordinator")
+	}
+
+	var sequence lib.ExecutionSegmentSequence
+	if arc.Options.ExecutionSegmentSequence != nil {
+		sequence = *arc.Options.ExecutionSegmentSequence
+		if len(sequence) != instanceCount {
+			return nil, fmt.Errorf(
+				"the execution segment sequence '%s' has %d segments, but the test run has %d instances",
+				sequence, len(sequence), instanceCount,
+			)
+		}
+	} else {
+		segments, err := (*lib.ExecutionSegment)(nil).Split(int64(instanceCount))
+		if err != nil {
+			return nil, err
+		}
+		if sequence, err = lib.NewExecutionSegmentSequence(segments...); err != nil {
+			return nil, err
+		}
+	}
+
+	options := arc.Options
+	if !options.RandomSeed.Valid {
+		// the instances split the random choices of the executors, e.g. the
+		// stochastic arrivals, so they need the same seed
+		options.RandomSeed = null.IntFrom(rand.Int63()) //nolint:gosec
+	}
+
+	archives := make([][]byte, instanceCount)
+	for i, segment := range sequence {
+		instanceArc := *arc
+		instanceArc.Options = options
+		instanceArc.Options.ExecutionSegment = segment
+		instanceArc.Options.ExecutionSegmentSequence = &sequence
+		buf := &bytes.Buffer{}
+		if err := instanceArc.Write(buf); err != nil {
+			return nil, err
+		}
+		archives[i] = buf.Bytes()
+	}
+
+	return &Coordinator{
+		logger:        logger.WithField("component", "coordinator"),
+		instanceCount: instanceCount,
+		archives:      archives,
+		agents:        make(map[uint32]*agentStream),
+		finished:      make(map[uint32]struct{}),
+		gone:          make(map[uint32]error),
+		events:        make(map[string]*coordinatorEvent),
+		data:          make(map[string]*coordinatorData),
+		done:          make(chan struct{}),
+	}, nil
+}
+
+// SetMetricsAggregator sets the aggregator of the metrics the instances send.
+// It should be called before the coordinator is served.
+func (c *Coordinator) SetMetricsAggregator(aggregator *MetricsAggregator) {
+	c.aggregator = aggregator
+}
+
+// Abort makes all of the instances abort the test run with the error, e.g.
+// because a threshold with abortOnFail has failed. Only the first error is
+// sent to them.
+func (c *Coordinator) Abort(err error) {
+	c.mu.Lock()
+	if c.abort != nil {
+		c.mu.Unlock()
+		return
+	}
+	c.abort = &Abort{Error: err.Error()}
+	var ecerr errext.HasExitCode
+	if errors.As(err, &ecerr) {
+		c.abort.ExitCode = uint32(ecerr.ExitCode())
+	}
+	c.logger.WithError(err).Debug("Aborting the test run of all of the instances")
+
+	deliveries := make([]delivery, 0, len(c.agents))
+	for _, agent := range c.agents {
+		deliveries = append(deliveries, delivery{agent, &ControllerMessage{
+			Message: &ControllerMessage_Abort{Abort: c.abort},
+		}})
+	}
+	c.mu.Unlock()
+	c.send(deliveries)
+}
+
+// Done is closed once all of the instances have registered and finished, i.e.
+// closed their stream. The ones that lose the connection can connect again,
+// so they aren't finished, and they can reach the events that are signalled
+// afterwards.
+func (c *Coordinator) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err returns the first error of the instances, if any of them failed.
+func (c *Coordinator) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// Register assigns the next instance ID to the agent, until all of the
+// instances have registered.
+func (c *Coordinator) Register(ctx context.Context, _ *RegisterRequest) (*RegisterResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.registered >= c.instanceCount {
+		return nil, status.Errorf(codes.ResourceExhausted,
+			"all of the %d instances of the test run have already registered", c.instanceCount)
+	}
+	c.registered++
+	id := uint32(c.registered)
+
+	logger := c.logger.WithField("instance", id)
+	if p, ok := peer.FromContext(ctx); ok {
+		logger = logger.WithField("address", p.Addr.String())
+	}
+	logger.Infof("Instance %d of %d registered", id, c.instanceCount)
+
+	return &RegisterResponse{
+		InstanceID:    id,
+		InstanceCount: uint32(c.instanceCount),
+		Archive:       c.archives[id-1],
+	}, nil
+}
+
+// CommandAndControl handles the messages of an agent, whose first one is its
+// instance ID.
+func (c *Coordinator) CommandAndControl(stream DistributedTest_CommandAndControlServer) (err error) {
+	msg, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	id, ok := msg.Message.(*AgentMessage_InitInstanceID)
+	if !ok {
+		return status.Error(codes.InvalidArgument, "the first message should be the instance ID")
+	}
+	agent, deliveries, err := c.connect(id.InitInstanceID, stream)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		c.send(c.disconnect(id.InitInstanceID, err))
+	}()
+	c.send(deliveries)
+
+	for {
+		msg, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch m := msg.Message.(type) {
+		case *AgentMessage_Signal:
+			c.send(c.signal(id.InitInstanceID, m.Signal))
+		case *AgentMessage_GetOrCreateDataWithID:
+			c.send(c.getOrCreateData(id.InitInstanceID, agent, m.GetOrCreateDataWithID))
+		case *AgentMessage_CreatedData:
+			c.send(c.createdData(m.CreatedData))
+		case *AgentMessage_Metrics:
+			if c.aggregator != nil {
+				c.aggregator.add(id.InitInstanceID, m.Metrics)
+			}
+		default:
+			return status.Errorf(codes.InvalidArgument, "unexpected message %T", msg.Message)
+		}
+	}
+}
+
+func (c *Coordinator) send(deliveries []delivery) {
+	for _, d := range deliveries {
+		if err := d.agent.send(d.msg); err != nil {
+			c.logger.WithError(err).Debug("Couldn't send a message to an agent")
+		}
+	}
+}
+
+// connect adds the stream of the agent, to which the events that are already
+// done are sent, as well as the abort of the test run.
+func (c *Coordinator) connect(id uint32, stream DistributedTest_CommandAndControlServer) (
+	*agentStream, []delivery, error,
+) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if id == 0 || int(id) > c.registered {
+		return nil, nil, status.Errorf(codes.InvalidArgument, "the instance %d isn't registered", id)
+	}
+	if _, ok := c.agents[id]; ok {
+		return nil, nil, status.Errorf(codes.AlreadyExists, "the instance %d is already connected", id)
+	}
+	if _, ok := c.finished[id]; ok {
+		return nil, nil, status.Errorf(codes.FailedPrecondition, "the instance %d has already finished", id)
+	}
+	agent := &agentStream{stream: stream}
+	c.agents[id] = agent
+	delete(c.gone, id)
+
+	var deliveries []delivery
+	for _, ev := range c.events {
+		if ev.done != nil {
+			deliveries = append(deliveries, delivery{agent, &ControllerMessage{
+				Message: &ControllerMessage_EventDone{EventDone: ev.done},
+			}})
+		}
+	}
+	if c.abort != nil {
+		deliveries = append(deliveries, delivery{agent, &ControllerMessage{
+			Message: &ControllerMessage_Abort{Abort: c.abort},
+		}})
+	}
+	return agent, deliveries, nil
+}
+
+// disconnect removes the stream of the agent, which has finished if it closed
+// it without an error. Since it can't reach the events any more, the ones that
+// aren't done and that it hasn't reached fail, as well as the data it was
+// creating.
+func (c *Coordinator) disconnect(id uint32, err error) []delivery {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.agents, id)
+	if err == nil {
+		c.finished[id] = struct{}{}
+		if len(c.finished) == c.instanceCount {
+			if c.aggregator != nil {
+				c.aggregator.stop()
+			}
+			defer close(c.done)
+		}
+	}
+
+	if err == nil {
+		err = errors.New("disconnected")
+	}
+	err = fmt.Errorf("instance %d: %w", id, err)
+	c.gone[id] = err
+
+	var deliveries []delivery
+	for eventID, ev := range c.events {
+		if _, ok := ev.signalled[id]; !ok && ev.done == nil {
+			deliveries = append(deliveries, c.finishEvent(eventID, ev, err)...)
+		}
+	}
+	for dataID, d := range c.data {
+		if d.packet == nil && d.creator == id {
+			deliveries = append(deliveries, c.finishData(&DataPacket{Id: dataID, Error: err.Error()})...)
+		}
+	}
+	return deliveries
+}
+
+// goneError returns the error of the first of the disconnected instances
+// which hasn't reached the event, if any, since it won't reach it.
+func (c *Coordinator) goneError(ev *coordinatorEvent) error {
+	for id := uint32(1); int(id) <= c.registered; id++ {
+		err, ok := c.gone[id]
+		if _, signalled := ev.signalled[id]; ok && !signalled {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Coordinator) event(id string) *coordinatorEvent {
+	ev, ok := c.events[id]
+	if !ok {
+		ev = &coordinatorEvent{signalled: make(map[uint32]struct{})}
+		c.events[id] = ev
+	}
+	return ev
+}
+
+// signal marks the event as reached by the instance, or fails it.
+func (c *Coordinator) signal(id uint32, signal *Signal) []delivery {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ev := c.event(signal.EventID)
+	switch {
+	case ev.done != nil:
+		return nil
+	case signal.Error != "":
+		return c.finishEvent(signal.EventID, ev, fmt.Errorf("instance %d: %s", id, signal.Error))
+	}
+
+	ev.signalled[id] = struct{}{}
+	c.logger.WithField("instance", id).Debugf("Instance reached the event '%s'", signal.EventID)
+	if err := c.goneError(ev); err != nil {
+		return c.finishEvent(signal.EventID, ev, err)
+	}
+	if len(ev.signalled) < c.instanceCount {
+		return nil
+	}
+	return c.finishEvent(signal.EventID, ev, nil)
+}
+
+func (c *Coordinator) finishEvent(eventID string, ev *coordinatorEvent, err error) []delivery {
+	ev.done = &Signal{EventID: eventID}
+	if err != nil {
+		ev.done.Error = err.Error()
+		if c.err == nil {
+			c.err = err
+		}
+		c.logger.WithError(err).Debugf("The event '%s' failed", eventID)
+	} else {
+		c.logger.Debugf("All of the instances reached the event '%s'", eventID)
+		if eventID == runStartEventID && c.aggregator != nil {
+			c.aggregator.start()
+		}
+	}
+
+	deliveries := make([]delivery, 0, len(c.agents))
+	for _, agent := range c.agents {
+		deliveries = append(deliveries, delivery{agent, &ControllerMessage{
+			Message: &ControllerMessage_EventDone{EventDone: ev.done},
+		}})
+	}
+	return deliveries
+}
+
+// getOrCreateData sends the data to the agent if it has been created, or asks
+// it to create it, if it's the first one to request it.
+func (c *Coordinator) getOrCreateData(id uint32, agent *agentStream, dataID string) []delivery {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	d, ok := c.data[dataID]
+	switch {
+	case !ok:
+		c.data[dataID] = &coordinatorData{creator: id}
+		c.logger.WithField("instance", id).Debugf("Instance is creating the data '%s'", dataID)
+		return []delivery{{agent, &ControllerMessage{
+			Message: &ControllerMessage_CreateDataWithID{CreateDataWithID: dataID},
+		}}}
+	case d.packet == nil:
+		d.waiting = append(d.waiting, id)
+		return nil
+	default:
+		return []delivery{{agent, &ControllerMessage{
+			Message: &ControllerMessage_DataWithID{DataWithID: d.packet},
+		}}}
+	}
+}
+
+func (c *Coordinator) createdData(packet *DataPacket) []delivery {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if d, ok := c.data[packet.Id]; !ok || d.packet != nil {
+		return nil
+	}
+	return c.finishData(packet)
+}
+
+func (c *Coordinator) finishData(packet *DataPacket) []delivery {
+	d := c.data[packet.Id]
+	d.packet = packet
+	if packet.Error != "" && c.err == nil {
+		c.err = errors.New(packet.Error)
+	}
+
+	deliveries := make([]delivery, 0, len(d.waiting))
+	for _, id := range d.waiting {
+		if agent, ok := c.agents[id]; ok {
+			deliveries = append(deliveries, delivery{agent, &ControllerMessage{
+				Message: &ControllerMessage_DataWithID{DataWithID: packet},
+			}})
+		}
+	}
+	d.waiting = nil
+	return deliveries
+}
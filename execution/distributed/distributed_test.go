@@ -0,0 +1,465 @@
+package distributed
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"gopkg.in/guregu/null.v3"
+
+	"github.com/liuxd6825/k6server/errext"
+	"github.com/liuxd6825/k6server/errext/exitcodes"
+	"github.com/liuxd6825/k6server/execution"
+	"github.com/liuxd6825/k6server/lib"
+	"github.com/liuxd6825/k6server/lib/consts"
+	"github.com/liuxd6825/k6server/lib/fsext"
+	"github.com/liuxd6825/k6server/lib/testutils"
+	"github.com/liuxd6825/k6server/metrics"
+	"github.com/liuxd6825/k6server/metrics/engine"
+)
+
+func getTestArchive(t *testing.T, opts lib.Options) *lib.Archive {
+	t.Helper()
+
+	script := []byte("export default function() {}")
+	fs := fsext.NewMemMapFs()
+	require.NoError(t, fsext.WriteFile(fs, "/test/script.js", script, 0o644))
+	return &lib.Archive{
+		Type:        "js",
+		Options:     opts,
+		FilenameURL: &url.URL{Scheme: "file", Path: "/test/script.js"},
+		Data:        script,
+		PwdURL:      &url.URL{Scheme: "file", Path: "/test/"},
+		Filesystems: map[string]fsext.Fs{"file": fs},
+		K6Version:   consts.Version,
+	}
+}
+
+func newTestCoordinator(t *testing.T, instanceCount int) (*Coordinator, DistributedTestClient) {
+	t.Helper()
+
+	coordinator, err := NewCoordinator(getTestArchive(t, lib.Options{}), instanceCount, testutils.NewLogger(t))
+	require.NoError(t, err)
+
+	l := bufconn.Listen(1024 * 1024)
+	t.Cleanup(func() { _ = l.Close() })
+	s := grpc.NewServer()
+	RegisterDistributedTestServer(s, coordinator)
+	go func() { _ = s.Serve(l) }()
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return l.DialContext(ctx)
+		}),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return coordinator, NewDistributedTestClient(conn)
+}
+
+func newTestAgents(t *testing.T, client DistributedTestClient, count int) []*AgentController {
+	t.Helper()
+
+	agents := make([]*AgentController, count)
+	for i := range agents {
+		resp, err := client.Register(context.Background(), &RegisterRequest{})
+		require.NoError(t, err)
+		agents[i], err = NewAgentController(context.Background(), resp.InstanceID, client, testutils.NewLogger(t))
+		require.NoError(t, err)
+	}
+	return agents
+}
+
+func closeAgents(t *testing.T, agents []*AgentController) {
+	t.Helper()
+
+	for _, a := range agents {
+		_ = a.Close()
+	}
+}
+
+func waitFor(t *testing.T, ch <-chan error) error {
+	t.Helper()
+
+	select {
+	case err := <-ch:
+		return err
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out")
+		return nil
+	}
+}
+
+func TestNewCoordinatorErrors(t *testing.T) {
+	t.Parallel()
+
+	logger := testutils.NewLogger(t)
+	_, err := NewCoordinator(getTestArchive(t, lib.Options{}), 0, logger)
+	assert.ErrorContains(t, err, "the instance count should be at least 1")
+
+	segment, err := lib.NewExecutionSegmentFromString("0:1/2")
+	require.NoError(t, err)
+	_, err = NewCoordinator(getTestArchive(t, lib.Options{ExecutionSegment: segment}), 2, logger)
+	assert.ErrorContains(t, err, "set by its coordinator")
+
+	sequence, err := lib.NewExecutionSegmentSequenceFromString("0,1/4,1")
+	require.NoError(t, err)
+	_, err = NewCoordinator(getTestArchive(t, lib.Options{ExecutionSegmentSequence: &sequence}), 3, logger)
+	assert.ErrorContains(t, err, "has 2 segments, but the test run has 3 instances")
+}
+
+func TestCoordinatorRegister(t *testing.T) {
+	t.Parallel()
+
+	sequence, err := lib.NewExecutionSegmentSequenceFromString("0,1/4,1/2,1")
+	require.NoError(t, err)
+	coordinator, err := NewCoordinator(
+		getTestArchive(t, lib.Options{ExecutionSegmentSequence: &sequence}), 3, testutils.NewLogger(t))
+	require.NoError(t, err)
+
+	var seed null.Int
+	for i, segment := range []string{"0:1/4", "1/4:1/2", "1/2:1"} {
+		resp, err := coordinator.Register(context.Background(), &RegisterRequest{})
+		require.NoError(t, err)
+		assert.Equal(t, uint32(i+1), resp.InstanceID)
+		assert.Equal(t, uint32(3), resp.InstanceCount)
+
+		arc, err := lib.ReadArchive(bytes.NewReader(resp.Archive))
+		require.NoError(t, err)
+		assert.Equal(t, segment, arc.Options.ExecutionSegment.String())
+		assert.Equal(t, "0,1/4,1/2,1", arc.Options.ExecutionSegmentSequence.String())
+		assert.True(t, arc.Options.RandomSeed.Valid)
+		if i > 0 {
+			assert.Equal(t, seed, arc.Options.RandomSeed, "the instances have the same seed")
+		}
+		seed = arc.Options.RandomSeed
+	}
+
+	_, err = coordinator.Register(context.Background(), &RegisterRequest{})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
+
+func TestSignalAndWait(t *testing.T) {
+	t.Parallel()
+
+	coordinator, client := newTestCoordinator(t, 3)
+	agents := newTestAgents(t, client, 3)
+
+	results := make(chan error, 3)
+	for _, a := range agents[:2] {
+		go func(a *AgentController) {
+			results <- execution.SignalAndWait(a, "test-event")
+		}(a)
+	}
+	select {
+	case <-results:
+		t.Fatal("the event is done before all of the instances have reached it")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	go func() {
+		results <- execution.SignalAndWait(agents[2], "test-event")
+	}()
+	for range agents {
+		assert.NoError(t, waitFor(t, results))
+	}
+
+	// the instances that subscribe to the event afterwards don't wait
+	assert.NoError(t, agents[0].Subscribe("test-event")())
+
+	closeAgents(t, agents)
+	<-coordinator.Done()
+	assert.NoError(t, coordinator.Err())
+}
+
+func TestSignalError(t *testing.T) {
+	t.Parallel()
+
+	coordinator, client := newTestCoordinator(t, 3)
+	agents := newTestAgents(t, client, 3)
+
+	results := make(chan error, 2)
+	for _, a := range agents[1:] {
+		go func(a *AgentController) {
+			results <- execution.SignalAndWait(a, "test-event")
+		}(a)
+	}
+	wait := agents[0].Subscribe("test-event")
+	err := execution.SignalErrorOrWait(agents[0], "test-event", errors.New("something went wrong"))
+	assert.EqualError(t, err, "something went wrong")
+
+	assert.EqualError(t, wait(), "instance 1: something went wrong")
+	for range agents[1:] {
+		assert.EqualError(t, waitFor(t, results), "instance 1: something went wrong")
+	}
+
+	closeAgents(t, agents)
+	<-coordinator.Done()
+	assert.EqualError(t, coordinator.Err(), "instance 1: something went wrong")
+}
+
+func TestGetOrCreateData(t *testing.T) {
+	t.Parallel()
+
+	coordinator, client := newTestCoordinator(t, 3)
+	agents := newTestAgents(t, client, 3)
+
+	var calls int64
+	var wg sync.WaitGroup
+	for _, a := range agents {
+		wg.Add(1)
+		go func(a *AgentController) {
+			defer wg.Done()
+			data, err := a.GetOrCreateData("setup", func() ([]byte, error) {
+				atomic.AddInt64(&calls, 1)
+				time.Sleep(50 * time.Millisecond)
+				return []byte(`{"token":"abc"}`), nil
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, `{"token":"abc"}`, string(data))
+		}(a)
+	}
+	wg.Wait()
+	assert.Equal(t, int64(1), atomic.LoadInt64(&calls))
+
+	// the errors of the callback are sent as well
+	_, err := agents[0].GetOrCreateData("teardown", func() ([]byte, error) {
+		return nil, errors.New("teardown failed")
+	})
+	assert.EqualError(t, err, "teardown failed")
+	_, err = agents[1].GetOrCreateData("teardown", func() ([]byte, error) {
+		t.Error("the data was created twice")
+		return nil, nil
+	})
+	assert.EqualError(t, err, "teardown failed")
+
+	closeAgents(t, agents)
+	<-coordinator.Done()
+}
+
+func TestAgentDisconnect(t *testing.T) {
+	t.Parallel()
+
+	coordinator, client := newTestCoordinator(t, 2)
+	agents := newTestAgents(t, client, 2)
+
+	results := make(chan error, 1)
+	go func() {
+		results <- execution.SignalAndWait(agents[0], "test-event")
+	}()
+	require.NoError(t, agents[1].Close())
+	assert.EqualError(t, waitFor(t, results), "instance 2: disconnected")
+
+	// the events that are signalled afterwards fail as well
+	assert.EqualError(t, execution.SignalAndWait(agents[0], "other-event"), "instance 2: disconnected")
+
+	require.NoError(t, agents[0].Close())
+	<-coordinator.Done()
+	assert.Error(t, coordinator.Err())
+
+	// and the controller can't be used after its stream is closed
+	_, err := agents[0].GetOrCreateData("setup", func() ([]byte, error) { return nil, nil })
+	assert.ErrorContains(t, err, "lost the connection to the coordinator")
+}
+
+func TestAgentFinishedEarly(t *testing.T) {
+	t.Parallel()
+
+	coordinator, client := newTestCoordinator(t, 2)
+	agents := newTestAgents(t, client, 2)
+
+	// the first instance reaches the last event before the other one and
+	// finishes, which doesn't fail the event for the other one
+	require.NoError(t, agents[0].Signal("test-end", nil))
+	require.NoError(t, agents[0].Close())
+	require.NoError(t, execution.SignalAndWait(agents[1], "test-end"))
+	assert.NoError(t, coordinator.Err())
+
+	// but the events it hasn't reached do fail
+	assert.EqualError(t, execution.SignalAndWait(agents[1], "other-event"), "instance 1: disconnected")
+
+	require.NoError(t, agents[1].Close())
+	<-coordinator.Done()
+	assert.EqualError(t, coordinator.Err(), "instance 1: disconnected")
+}
+
+func TestAgentReconnect(t *testing.T) {
+	t.Parallel()
+
+	coordinator, client := newTestCoordinator(t, 2)
+	agents := newTestAgents(t, client, 1)
+
+	resp, err := client.Register(context.Background(), &RegisterRequest{})
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	lost, err := NewAgentController(ctx, resp.InstanceID, client, testutils.NewLogger(t))
+	require.NoError(t, err)
+
+	connected := func() bool {
+		coordinator.mu.Lock()
+		defer coordinator.mu.Unlock()
+		_, ok := coordinator.agents[resp.InstanceID]
+		return ok
+	}
+	require.Eventually(t, connected, 5*time.Second, 10*time.Millisecond)
+
+	// the instance that loses the connection hasn't finished, so it can
+	// connect again
+	cancel()
+	<-lost.done
+	require.Eventually(t, func() bool { return !connected() }, 5*time.Second, 10*time.Millisecond)
+	reconnected, err := NewAgentController(context.Background(), resp.InstanceID, client, testutils.NewLogger(t))
+	require.NoError(t, err)
+	require.Eventually(t, connected, 5*time.Second, 10*time.Millisecond)
+
+	// and it reaches the events that are signalled afterwards
+	results := make(chan error, 1)
+	go func() {
+		results <- execution.SignalAndWait(agents[0], "test-event")
+	}()
+	require.NoError(t, execution.SignalAndWait(reconnected, "test-event"))
+	require.NoError(t, waitFor(t, results))
+
+	require.NoError(t, agents[0].Close())
+	select {
+	case <-coordinator.Done():
+		t.Fatal("the coordinator is done before all of the instances have finished")
+	case <-time.After(100 * time.Millisecond):
+	}
+	require.NoError(t, reconnected.Close())
+	<-coordinator.Done()
+	assert.NoError(t, coordinator.Err())
+
+	// but the instances that have finished can't
+	stream, err := client.CommandAndControl(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&AgentMessage{Message: &AgentMessage_InitInstanceID{InitInstanceID: 1}}))
+	_, err = stream.Recv()
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+}
+
+func TestCoordinatorAbort(t *testing.T) {
+	t.Parallel()
+
+	coordinator, client := newTestCoordinator(t, 2)
+	agents := newTestAgents(t, client, 1)
+
+	coordinator.Abort(errext.WithExitCodeIfNone(errors.New("threshold failed"), exitcodes.ThresholdsHaveFailed))
+	coordinator.Abort(errors.New("ignored"))
+
+	// the agents that connect afterwards are aborted as well
+	agents = append(agents, newTestAgents(t, client, 1)...)
+	for _, a := range agents {
+		select {
+		case <-a.Aborted():
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out")
+		}
+		err := a.AbortError()
+		assert.EqualError(t, err, "threshold failed")
+		var ecerr errext.HasExitCode
+		require.ErrorAs(t, err, &ecerr)
+		assert.Equal(t, exitcodes.ThresholdsHaveFailed, ecerr.ExitCode())
+	}
+
+	closeAgents(t, agents)
+	<-coordinator.Done()
+}
+
+func TestMetricsAggregator(t *testing.T) {
+	t.Parallel()
+
+	coordinator, client := newTestCoordinator(t, 2)
+	registry := metrics.NewRegistry()
+	metricsEngine, err := engine.NewMetricsEngine(registry, testutils.NewLogger(t))
+	require.NoError(t, err)
+	aggregator := NewMetricsAggregator(registry, metricsEngine, testutils.NewLogger(t))
+	coordinator.SetMetricsAggregator(aggregator)
+	agents := newTestAgents(t, client, 2)
+
+	now := time.Now()
+	for i, a := range agents {
+		// each agent has its own registry, like the instances do
+		agentRegistry := metrics.NewRegistry()
+		builtin := metrics.RegisterBuiltinMetrics(agentRegistry)
+		gauge := agentRegistry.MustNewMetric("my_gauge", metrics.Gauge)
+		pusher := NewMetricsPusher(a, agentRegistry, map[string]metrics.Thresholds{
+			"http_req_duration{status:200}": {},
+		})
+		tags := agentRegistry.RootTagSet().With("status", "200")
+		var samples metrics.Samples
+		for j := 1; j <= 50; j++ {
+			samples = append(samples,
+				metrics.Sample{
+					TimeSeries: metrics.TimeSeries{Metric: builtin.HTTPReqDuration, Tags: tags},
+					Value:      float64(i*50 + j),
+				},
+				metrics.Sample{
+					TimeSeries: metrics.TimeSeries{Metric: builtin.HTTPReqs, Tags: tags},
+					Value:      1,
+				},
+			)
+		}
+		// the gauge of the first agent is the latest one, and the VUs are
+		// the ones of all of them
+		samples = append(samples,
+			metrics.Sample{
+				TimeSeries: metrics.TimeSeries{Metric: builtin.VUs, Tags: agentRegistry.RootTagSet()},
+				Time:       now,
+				Value:      float64(10 * (i + 1)),
+			},
+			metrics.Sample{
+				TimeSeries: metrics.TimeSeries{Metric: gauge, Tags: agentRegistry.RootTagSet()},
+				Time:       now.Add(-time.Duration(i) * time.Second),
+				Value:      float64(5 * (i + 1)),
+			},
+		)
+		pusher.AddMetricSamples([]metrics.SampleContainer{samples})
+		pusher.flush()
+	}
+
+	assert.Eventually(t, func() bool {
+		metricsEngine.MetricsLock.Lock()
+		defer metricsEngine.MetricsLock.Unlock()
+		m, ok := metricsEngine.ObservedMetrics[metrics.HTTPReqsName]
+		return ok && m.Sink.(*metrics.CounterSink).Value == 100
+	}, 5*time.Second, 10*time.Millisecond)
+
+	metricsEngine.MetricsLock.Lock()
+	defer metricsEngine.MetricsLock.Unlock()
+	sm := metricsEngine.ObservedMetrics["http_req_duration{status:200}"]
+	require.NotNil(t, sm)
+	trend := sm.Sink.(*metrics.TrendSink)
+	assert.Equal(t, uint64(100), trend.Count())
+	assert.Equal(t, 1.0, trend.Min())
+	assert.Equal(t, 100.0, trend.Max())
+	assert.InEpsilon(t, 95.0, trend.P(0.95), metrics.TrendSketchAccuracy)
+	assert.Contains(t, metricsEngine.ObservedMetrics, metrics.HTTPReqDurationName)
+
+	vus := metricsEngine.ObservedMetrics[metrics.VUsName].Sink.(*metrics.GaugeSink)
+	assert.Equal(t, 30.0, vus.Value)
+	gauge := metricsEngine.ObservedMetrics["my_gauge"].Sink.(*metrics.GaugeSink)
+	assert.Equal(t, 5.0, gauge.Value)
+	assert.Equal(t, 5.0, gauge.Min)
+	assert.Equal(t, 10.0, gauge.Max)
+	assert.Equal(t, now.UnixNano(), gauge.Last.UnixNano())
+
+	closeAgents(t, agents)
+	<-coordinator.Done()
+}
@@ -0,0 +1,6 @@
+// Package distributed implements the execution.Controller of the test runs
+// that span several instances, whose agents synchronize with each other over
+// gRPC, through the coordinator of the test run.
+package distributed
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative ./distributed.proto
@@ -0,0 +1,394 @@
+package distributed
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/liuxd6825/k6server/lib"
+	"github.com/liuxd6825/k6server/metrics"
+	"github.com/liuxd6825/k6server/metrics/engine"
+	"github.com/liuxd6825/k6server/output"
+)
+
+const metricsPushInterval = time.Second
+
+// runStartEventID is the event the execution.Scheduler signals once the VUs
+// of an instance are initialized, right before the test run starts.
+const runStartEventID = "scheduler-run-start"
+
+var _ output.Output = &MetricsPusher{}
+
+// MetricsPusher is an output that aggregates the metric samples of an agent,
+// and periodically sends the sinks of its metrics to the coordinator, which
+// aggregates the ones of all of the instances. The trends are sent as
+// sketches, which are merged by the coordinator.
+type MetricsPusher struct {
+	output.SampleBuffer
+
+	controller      *AgentController
+	logger          logrus.FieldLogger
+	periodicFlusher *output.PeriodicFlusher
+
+	sinks  map[*metrics.Metric]metrics.Sink
+	trends map[*metrics.Metric]*metrics.TrendSketch
+	order  []*metrics.Metric // the sinks and trends, in the order they were observed
+
+	checks      map[checkKey]*CheckCount
+	checksOrder []*CheckCount
+}
+
+type checkKey struct {
+	groupPath, name string
+}
+
+// NewMetricsPusher returns the output that sends the metrics of the agent to
+// the coordinator. The submetrics of the thresholds are added to the registry,
+// since the coordinator evaluates them.
+func NewMetricsPusher(
+	controller *AgentController, registry *metrics.Registry, thresholds map[string]metrics.Thresholds,
+) *MetricsPusher {
+	for name := range thresholds {
+		parent, submetric, ok := strings.Cut(name, "{")
+		if !ok || !strings.HasSuffix(submetric, "}") {
+			continue
+		}
+		// The invalid ones are reported by the coordinator.
+		if m := registry.Get(parent); m != nil {
+			_, _ = m.AddSubmetric(strings.TrimSuffix(submetric, "}"))
+		}
+	}
+
+	return &MetricsPusher{
+		controller: controller,
+		logger:     controller.logger.WithField("component", "metrics-pusher"),
+		sinks:      make(map[*metrics.Metric]metrics.Sink),
+		trends:     make(map[*metrics.Metric]*metrics.TrendSketch),
+		checks:     make(map[checkKey]*CheckCount),
+	}
+}
+
+// Description returns a human-readable description of the output.
+func (p *MetricsPusher) Description() string {
+	return fmt.Sprintf("coordinator (instance %d)", p.controller.instanceID)
+}
+
+// Start starts sending the metrics periodically.
+func (p *MetricsPusher) Start() error {
+	pf, err := output.NewPeriodicFlusher(metricsPushInterval, p.flush)
+	if err != nil {
+		return err
+	}
+	p.periodicFlusher = pf
+	return nil
+}
+
+// Stop sends the remaining metrics and stops sending them.
+func (p *MetricsPusher) Stop() error {
+	p.periodicFlusher.Stop()
+	return nil
+}
+
+func (p *MetricsPusher) flush() {
+	sampleContainers := p.GetBufferedSamples()
+	if len(sampleContainers) == 0 {
+		return
+	}
+	for _, sc := range sampleContainers {
+		for _, sample := range sc.GetSamples() {
+			p.add(sample.Metric, sample)
+			for _, sm := range sample.Metric.Submetrics {
+				if sample.Tags.Contains(sm.Tags) {
+					p.add(sm.Metric, sample)
+				}
+			}
+			if sample.Metric.Name == metrics.ChecksName {
+				p.addCheck(sample)
+			}
+		}
+	}
+
+	snapshot, err := p.snapshot()
+	if err == nil {
+		err = p.controller.send(&AgentMessage{Message: &AgentMessage_Metrics{Metrics: snapshot}})
+	}
+	if err != nil {
+		p.logger.WithError(err).Warn("Couldn't send the metrics to the coordinator")
+	}
+}
+
+func (p *MetricsPusher) add(m *metrics.Metric, sample metrics.Sample) {
+	if m.Type == metrics.Trend {
+		trend, ok := p.trends[m]
+		if !ok {
+			trend = metrics.NewTrendSketch()
+			p.trends[m] = trend
+			p.order = append(p.order, m)
+		}
+		trend.Add(sample.Value)
+		return
+	}
+
+	sink, ok := p.sinks[m]
+	if !ok {
+		sink = metrics.NewSink(m.Type)
+		p.sinks[m] = sink
+		p.order = append(p.order, m)
+	}
+	sink.Add(sample)
+}
+
+func (p *MetricsPusher) addCheck(sample metrics.Sample) {
+	key := checkKey{}
+	key.groupPath, _ = sample.Tags.Get("group")
+	key.name, _ = sample.Tags.Get("check")
+	check, ok := p.checks[key]
+	if !ok {
+		check = &CheckCount{GroupPath: key.groupPath, Name: key.name}
+		p.checks[key] = check
+		p.checksOrder = append(p.checksOrder, check)
+	}
+	if sample.Value != 0 {
+		check.Passes++
+	} else {
+		check.Fails++
+	}
+}
+
+func (p *MetricsPusher) snapshot() (*MetricsSnapshot, error) {
+	snapshot := &MetricsSnapshot{Metrics: make([]*MetricSink, 0, len(p.order))}
+	for _, m := range p.order {
+		ms := &MetricSink{Name: m.Name, Type: m.Type.String(), Contains: m.Contains.String()}
+		if trend, ok := p.trends[m]; ok {
+			data, err := trend.MarshalBinary()
+			if err != nil {
+				return nil, err
+			}
+			ms.Sink = &MetricSink_TrendSketch{TrendSketch: data}
+		} else {
+			switch sink := p.sinks[m].(type) {
+			case *metrics.CounterSink:
+				ms.Sink = &MetricSink_Counter{Counter: &CounterSink{Value: sink.Value, First: sink.First.UnixNano()}}
+			case *metrics.GaugeSink:
+				gauge := &GaugeSink{Value: sink.Value, Min: sink.Min, Max: sink.Max}
+				if !sink.Last.IsZero() {
+					gauge.Time = sink.Last.UnixNano()
+				}
+				ms.Sink = &MetricSink_Gauge{Gauge: gauge}
+			case *metrics.RateSink:
+				ms.Sink = &MetricSink_Rate{Rate: &RateSink{Trues: sink.Trues, Total: sink.Total}}
+			}
+		}
+		snapshot.Metrics = append(snapshot.Metrics, ms)
+	}
+	for _, check := range p.checksOrder {
+		snapshot.Checks = append(snapshot.Checks, &CheckCount{
+			GroupPath: check.GroupPath, Name: check.Name, Passes: check.Passes, Fails: check.Fails,
+		})
+	}
+	return snapshot, nil
+}
+
+// MetricsAggregator merges the sinks of the metrics sent by the instances of
+// a distributed test run, and sets them on the metrics engine of the
+// coordinator, which evaluates the thresholds of the test on them.
+type MetricsAggregator struct {
+	registry *metrics.Registry
+	engine   *engine.MetricsEngine
+	logger   logrus.FieldLogger
+
+	mu        sync.Mutex
+	snapshots map[uint32]*MetricsSnapshot
+
+	// The thresholds get the duration while the engine is locked, which is
+	// done by add while mu is.
+	timesMu        sync.Mutex
+	started, ended time.Time
+}
+
+// NewMetricsAggregator returns an aggregator of the metrics of the registry,
+// whose sinks are set on the engine.
+func NewMetricsAggregator(
+	registry *metrics.Registry, engine *engine.MetricsEngine, logger logrus.FieldLogger,
+) *MetricsAggregator {
+	return &MetricsAggregator{
+		registry:  registry,
+		engine:    engine,
+		logger:    logger.WithField("component", "metrics-aggregator"),
+		snapshots: make(map[uint32]*MetricsSnapshot),
+	}
+}
+
+// TestRunDuration returns the duration of the test run so far, since all of
+// the instances have started it.
+func (a *MetricsAggregator) TestRunDuration() time.Duration {
+	a.timesMu.Lock()
+	defer a.timesMu.Unlock()
+	switch {
+	case a.started.IsZero():
+		return 0
+	case a.ended.IsZero():
+		return time.Since(a.started)
+	default:
+		return a.ended.Sub(a.started)
+	}
+}
+
+func (a *MetricsAggregator) start() {
+	a.timesMu.Lock()
+	defer a.timesMu.Unlock()
+	a.started = time.Now()
+}
+
+func (a *MetricsAggregator) stop() {
+	a.timesMu.Lock()
+	defer a.timesMu.Unlock()
+	if !a.started.IsZero() {
+		a.ended = time.Now()
+	}
+}
+
+// add replaces the snapshot of the instance, and sets the sinks aggregated
+// from the ones of all of the instances on the engine.
+func (a *MetricsAggregator) add(instanceID uint32, snapshot *MetricsSnapshot) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.snapshots[instanceID] = snapshot
+
+	sinks := make(map[*metrics.Metric]metrics.Sink)
+	for _, id := range a.instanceIDs() {
+		for _, ms := range a.snapshots[id].Metrics {
+			m, err := a.metric(ms)
+			if err != nil {
+				a.logger.WithError(err).Warnf("Couldn't aggregate the metric '%s'", ms.Name)
+				continue
+			}
+			sink, ok := sinks[m]
+			if !ok {
+				sink = metrics.NewSink(m.Type)
+				sinks[m] = sink
+			}
+			name, _, _ := strings.Cut(ms.Name, "{") // the submetrics are additive like their metrics
+			if err = mergeSink(sink, ms, additiveGauges[name]); err != nil {
+				a.logger.WithError(err).Warnf("Couldn't aggregate the metric '%s'", ms.Name)
+			}
+		}
+	}
+	a.engine.SetSinks(sinks)
+}
+
+func (a *MetricsAggregator) instanceIDs() []uint32 {
+	ids := make([]uint32, 0, len(a.snapshots))
+	for id := range a.snapshots {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// metric returns the metric or submetric of the sink, which is registered if
+// it doesn't exist, e.g. if the agents created it after the init context.
+func (a *MetricsAggregator) metric(ms *MetricSink) (*metrics.Metric, error) {
+	var (
+		typ      metrics.MetricType
+		contains metrics.ValueType
+	)
+	if err := typ.UnmarshalText([]byte(ms.Type)); err != nil {
+		return nil, err
+	}
+	if err := contains.UnmarshalText([]byte(ms.Contains)); err != nil {
+		return nil, err
+	}
+
+	name, submetric, isSubmetric := strings.Cut(ms.Name, "{")
+	m, err := a.registry.NewMetric(name, typ, contains)
+	if err != nil || !isSubmetric {
+		return m, err
+	}
+	sm, err := m.AddSubmetric(strings.TrimSuffix(submetric, "}"))
+	if err != nil {
+		return nil, err
+	}
+	return sm.Metric, nil
+}
+
+// additiveGauges are the gauges whose values on the instances are parts of
+// the same total, so they are summed instead of having the latest one.
+var additiveGauges = map[string]bool{ //nolint:gochecknoglobals
+	metrics.VUsName:    true,
+	metrics.VUsMaxName: true,
+}
+
+// mergeSink merges the sink of an instance into the aggregated one. The
+// gauges have the latest value of the instances, unless they are additive.
+func mergeSink(sink metrics.Sink, ms *MetricSink, additive bool) error {
+	switch sink := sink.(type) {
+	case *metrics.CounterSink:
+		if c := ms.GetCounter(); c != nil {
+			sink.Merge(&metrics.CounterSink{Value: c.Value, First: time.Unix(0, c.First)})
+			return nil
+		}
+	case *metrics.GaugeSink:
+		if g := ms.GetGauge(); g != nil {
+			// its extremes are added, since whether it's empty isn't exported
+			other := &metrics.GaugeSink{}
+			other.Add(metrics.Sample{Value: g.Min})
+			other.Add(metrics.Sample{Value: g.Max})
+			other.Value, other.Last = g.Value, time.Unix(0, g.Time)
+			if additive {
+				sink.Sum(other)
+			} else {
+				sink.Merge(other)
+			}
+			return nil
+		}
+	case *metrics.RateSink:
+		if r := ms.GetRate(); r != nil {
+			sink.Merge(&metrics.RateSink{Trues: r.Trues, Total: r.Total})
+			return nil
+		}
+	case *metrics.TrendSink:
+		if data := ms.GetTrendSketch(); data != nil {
+			sketch := metrics.NewTrendSketch()
+			if err := sketch.UnmarshalBinary(data); err != nil {
+				return err
+			}
+			sink.MergeSketch(sketch)
+			return nil
+		}
+	}
+	return fmt.Errorf("the sink of the %s metric is a %T", ms.Type, ms.Sink)
+}
+
+// RootGroup returns the root group of the test run, with the checks of all of
+// the instances.
+func (a *MetricsAggregator) RootGroup() (*lib.Group, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	root, err := lib.NewGroup("", nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range a.instanceIDs() {
+		for _, c := range a.snapshots[id].Checks {
+			group := root
+			for _, name := range strings.Split(c.GroupPath, lib.GroupSeparator)[1:] {
+				if group, err = group.Group(name); err != nil {
+					return nil, err
+				}
+			}
+			check, err := group.Check(c.Name)
+			if err != nil {
+				return nil, err
+			}
+			check.Passes += c.Passes
+			check.Fails += c.Fails
+		}
+	}
+	return root, nil
+}
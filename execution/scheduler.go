@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"runtime"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -324,22 +325,88 @@ func (e *Scheduler) initVUsAndExecutors(ctx context.Context, samplesOut chan<- m
 	return nil
 }
 
+// waitForScenarios waits for all of the given scenarios to finish, or for any
+// of them, and returns false if the context is done before that. The scenarios
+// without a channel in finished are considered finished.
+func waitForScenarios(ctx context.Context, finished map[string]chan struct{}, names []string, anyOf bool) bool {
+	first := make(chan struct{}, len(names))
+	for _, name := range names {
+		done, ok := finished[name]
+		if !ok {
+			done = make(chan struct{})
+			close(done)
+		}
+		if anyOf {
+			go func() {
+				select {
+				case <-done:
+					first <- struct{}{}
+				case <-ctx.Done():
+				}
+			}()
+			continue
+		}
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return false
+		}
+	}
+	if !anyOf {
+		return true
+	}
+
+	select {
+	case <-first:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
 // runExecutor gets called by the public Run() method once per configured
-// executor, each time in a new goroutine. It is responsible for waiting out the
-// configured startTime for the specific executor and then running its Run()
-// method.
+// executor, each time in a new goroutine. It is responsible for waiting for the
+// scenarios it starts after to finish, then waiting out the configured
+// startTime for the specific executor and then running its Run() method. It
+// closes the channel of the executor in finished when it's done.
+//
+//nolint:funlen
 func (e *Scheduler) runExecutor(
 	runCtx context.Context, runResults chan<- error, engineOut chan<- metrics.SampleContainer, executor lib.Executor,
+	finished map[string]chan struct{},
 ) {
 	executorConfig := executor.GetConfig()
+	defer close(finished[executorConfig.GetName()])
+
 	executorStartTime := executorConfig.GetStartTime()
+	executorStartAfter := executorConfig.GetStartAfter()
 	executorLogger := e.state.Test.Logger.WithFields(logrus.Fields{
-		"executor":  executorConfig.GetName(),
-		"type":      executorConfig.GetType(),
-		"startTime": executorStartTime,
+		"executor":   executorConfig.GetName(),
+		"type":       executorConfig.GetType(),
+		"startTime":  executorStartTime,
+		"startAfter": executorStartAfter,
 	})
 	executorProgress := executor.GetProgress()
 
+	// Check if we have to wait for other scenarios to finish first
+	if len(executorStartAfter) > 0 {
+		separator := " and "
+		anyOf := executorConfig.GetStartWhen() == lib.StartWhenAny
+		if anyOf {
+			separator = " or "
+		}
+		executorProgress.Modify(
+			pb.WithStatus(pb.Waiting),
+			pb.WithConstProgress(0, "waiting for "+strings.Join(executorStartAfter, separator)),
+		)
+
+		executorLogger.Debugf("Waiting for the scenarios the executor starts after...")
+		if !waitForScenarios(runCtx, finished, executorStartAfter, anyOf) {
+			runResults <- nil // no error since executor hasn't started yet
+			return
+		}
+	}
+
 	// Check if we have to wait before starting the actual executor execution
 	if executorStartTime > 0 {
 		startTime := time.Now()
@@ -494,10 +561,18 @@ func (e *Scheduler) Run(globalCtx, runCtx context.Context, samplesOut chan<- met
 	logger.Debug("Start all executors...")
 	e.state.SetExecutionStatus(lib.ExecutionStatusRunning)
 
+	// The channels of the executors are closed when they finish, so the ones
+	// starting after them can start. The scenarios without work don't have
+	// any, so they're considered finished.
+	finished := make(map[string]chan struct{}, len(e.executors))
+	for _, exec := range e.executors {
+		finished[exec.GetConfig().GetName()] = make(chan struct{})
+	}
+
 	executorsRunCtx, executorsRunCancel := context.WithCancel(withExecStateCtx)
 	defer executorsRunCancel()
 	for _, exec := range e.executors {
-		go e.runExecutor(executorsRunCtx, runResults, samplesOut, exec)
+		go e.runExecutor(executorsRunCtx, runResults, samplesOut, exec, finished)
 	}
 
 	// Wait for all executors to finish
@@ -942,6 +942,114 @@ func TestSchedulerEndErrors(t *testing.T) {
 	assert.Empty(t, hook.Entries)
 }
 
+func TestSchedulerStartAfter(t *testing.T) {
+	t.Parallel()
+
+	first := executor.NewSharedIterationsConfig("first")
+	first.VUs = null.IntFrom(2)
+	first.Iterations = null.IntFrom(6)
+	first.MaxDuration = types.NullDurationFrom(10 * time.Second)
+	first.GracefulStop = types.NullDurationFrom(0)
+
+	second := executor.NewPerVUIterationsConfig("second")
+	second.VUs = null.IntFrom(1)
+	second.Iterations = null.IntFrom(1)
+	second.StartAfter = []string{"first"}
+	second.StartTime = types.NullDurationFrom(100 * time.Millisecond)
+
+	var firstEnd, secondStart int64
+	runner := &minirunner.MiniRunner{
+		Fn: func(ctx context.Context, _ *lib.State, _ chan<- metrics.SampleContainer) error {
+			if lib.GetScenarioState(ctx).Name == "second" {
+				atomic.StoreInt64(&secondStart, time.Now().UnixNano())
+				return nil
+			}
+			time.Sleep(50 * time.Millisecond)
+			atomic.StoreInt64(&firstEnd, time.Now().UnixNano())
+			return nil
+		},
+		Options: lib.Options{
+			Scenarios: lib.ScenarioConfigs{first.GetName(): first, second.GetName(): second},
+		},
+	}
+	ctx, cancel, execScheduler, samples := newTestScheduler(t, runner, nil, lib.Options{})
+	defer cancel()
+
+	// the plan counts the whole maxDuration of the first scenario...
+	endTime, isFinal := lib.GetEndOffset(execScheduler.GetExecutionPlan())
+	assert.Equal(t, 10*time.Second+100*time.Millisecond+10*time.Minute+30*time.Second, endTime)
+	assert.True(t, isFinal)
+
+	// ... but the second one starts after the first one actually finishes
+	startTime := time.Now()
+	require.NoError(t, execScheduler.Run(ctx, ctx, samples))
+	assert.Less(t, time.Since(startTime), 2*time.Second)
+	require.NotZero(t, atomic.LoadInt64(&secondStart))
+	assert.GreaterOrEqual(t,
+		time.Duration(atomic.LoadInt64(&secondStart)-atomic.LoadInt64(&firstEnd)), 100*time.Millisecond)
+}
+
+func TestSchedulerStartAfterEarlyEnd(t *testing.T) {
+	t.Parallel()
+
+	first := executor.NewSharedIterationsConfig("first")
+	first.VUs = null.IntFrom(1)
+	first.Iterations = null.IntFrom(1)
+	first.MaxDuration = types.NullDurationFrom(2 * time.Second)
+	first.GracefulStop = types.NullDurationFrom(0)
+
+	// it's planned to be done before the second one starts, but they
+	// overlap when the first one is done early
+	concurrent := executor.NewPerVUIterationsConfig("concurrent")
+	concurrent.VUs = null.IntFrom(3)
+	concurrent.Iterations = null.IntFrom(1)
+	concurrent.MaxDuration = types.NullDurationFrom(time.Second)
+	concurrent.GracefulStop = types.NullDurationFrom(0)
+
+	second := executor.NewPerVUIterationsConfig("second")
+	second.VUs = null.IntFrom(2)
+	second.Iterations = null.IntFrom(1)
+	second.StartAfter = []string{"first"}
+
+	var running, maxRunning, secondIters int64
+	runner := &minirunner.MiniRunner{
+		Fn: func(ctx context.Context, _ *lib.State, _ chan<- metrics.SampleContainer) error {
+			name := lib.GetScenarioState(ctx).Name
+			if name == "first" {
+				return nil
+			}
+			if name == "second" {
+				atomic.AddInt64(&secondIters, 1)
+			}
+			current := atomic.AddInt64(&running, 1)
+			for {
+				previous := atomic.LoadInt64(&maxRunning)
+				if current <= previous || atomic.CompareAndSwapInt64(&maxRunning, previous, current) {
+					break
+				}
+			}
+			time.Sleep(500 * time.Millisecond)
+			atomic.AddInt64(&running, -1)
+			return nil
+		},
+		Options: lib.Options{
+			Scenarios: lib.ScenarioConfigs{
+				first.GetName(): first, concurrent.GetName(): concurrent, second.GetName(): second,
+			},
+		},
+	}
+	ctx, cancel, execScheduler, samples := newTestScheduler(t, runner, nil, lib.Options{})
+	defer cancel()
+
+	// the VUs of the second scenario are planned from its earliest start...
+	assert.Equal(t, uint64(6), lib.GetMaxPlannedVUs(execScheduler.GetExecutionPlan()))
+
+	// ... so it doesn't have to wait for the VUs of the concurrent one
+	require.NoError(t, execScheduler.Run(ctx, ctx, samples))
+	assert.Equal(t, int64(2), atomic.LoadInt64(&secondIters))
+	assert.Equal(t, int64(5), atomic.LoadInt64(&maxRunning))
+}
+
 func TestSchedulerEndIterations(t *testing.T) {
 	t.Parallel()
 	registry := metrics.NewRegistry()
@@ -0,0 +1,235 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// DefaultAlpha is the significance level of the regressions.
+const DefaultAlpha = 0.05
+
+// Change is the difference of a metric value between two test runs. Only the
+// changes of the trend and rate metrics are tested for significance, the
+// P-value of the others is NaN.
+type Change struct {
+	Metric string
+	Stat   string
+	Base   float64
+	New    float64
+	PValue float64
+
+	// Regression and Improvement are set when the change is significant,
+	// depending on whether it's for the worse or for the better.
+	Regression  bool
+	Improvement bool
+}
+
+// Relative returns the relative change of the value, e.g. 0.1 for +10%.
+func (c Change) Relative() float64 {
+	if c.Base == 0 {
+		if c.New == 0 {
+			return 0
+		}
+		return math.Inf(1)
+	}
+	return (c.New - c.Base) / math.Abs(c.Base)
+}
+
+type summaryMetric struct {
+	Type   string             `json:"type"`
+	Values map[string]float64 `json:"values"`
+}
+
+func (r *Record) summaryMetrics() (map[string]summaryMetric, error) {
+	var summary struct {
+		Metrics map[string]summaryMetric `json:"metrics"`
+	}
+	if err := json.Unmarshal(r.Summary, &summary); err != nil {
+		return nil, fmt.Errorf("couldn't parse the summary of the test run %s: %w", r.ID, err)
+	}
+	return summary.Metrics, nil
+}
+
+// intervalValues returns the values of a metric stat in the intervals where
+// the metric had samples.
+func (r *Record) intervalValues(metric, stat string) []float64 {
+	values := make([]float64, 0, len(r.Intervals))
+	for _, interval := range r.Intervals {
+		if v, ok := interval.Metrics[metric][stat]; ok {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// Diff compares the metrics that both runs have, sorted by name. The changes
+// of the trends are tested with Welch's t-test over the values of their
+// intervals, and the ones of the rates with a two-proportion z-test, at the
+// alpha significance level.
+//
+// The trends, e.g. durations, regress when they increase. The rates regress
+// when they increase too, e.g. http_req_failed, except for checks, which
+// regresses when fewer of them pass.
+func Diff(base, next *Record, alpha float64) ([]Change, error) {
+	baseMetrics, err := base.summaryMetrics()
+	if err != nil {
+		return nil, err
+	}
+	nextMetrics, err := next.summaryMetrics()
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(baseMetrics))
+	for name, m := range baseMetrics {
+		if n, ok := nextMetrics[name]; ok && n.Type == m.Type {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var changes []Change
+	for _, name := range names {
+		b, n := baseMetrics[name], nextMetrics[name]
+		stats := make([]string, 0, len(b.Values))
+		for stat := range b.Values {
+			if _, ok := n.Values[stat]; ok {
+				stats = append(stats, stat)
+			}
+		}
+		sort.Strings(stats)
+
+		for _, stat := range stats {
+			c := Change{Metric: name, Stat: stat, Base: b.Values[stat], New: n.Values[stat], PValue: math.NaN()}
+			higherIsWorse := true
+			switch {
+			case b.Type == "trend" && stat != "count":
+				c.PValue = welchTTest(base.intervalValues(name, stat), next.intervalValues(name, stat))
+			case b.Type == "rate" && stat == "rate":
+				c.PValue = proportionZTest(
+					b.Values["passes"], b.Values["passes"]+b.Values["fails"],
+					n.Values["passes"], n.Values["passes"]+n.Values["fails"],
+				)
+				higherIsWorse = name != "checks"
+			}
+			if c.PValue < alpha && c.New != c.Base {
+				c.Regression = (c.New > c.Base) == higherIsWorse
+				c.Improvement = !c.Regression
+			}
+			changes = append(changes, c)
+		}
+	}
+	return changes, nil
+}
+
+// welchTTest returns the two-sided P-value of Welch's t-test, or NaN if either
+// of the samples has fewer than two values.
+func welchTTest(a, b []float64) float64 {
+	if len(a) < 2 || len(b) < 2 {
+		return math.NaN()
+	}
+	meanA, varA := meanVariance(a)
+	meanB, varB := meanVariance(b)
+	na, nb := float64(len(a)), float64(len(b))
+
+	se2 := varA/na + varB/nb
+	if se2 == 0 {
+		if meanA == meanB {
+			return 1
+		}
+		return 0
+	}
+	t := (meanA - meanB) / math.Sqrt(se2)
+	df := se2 * se2 / ((varA*varA)/(na*na*(na-1)) + (varB*varB)/(nb*nb*(nb-1)))
+
+	// The two-sided tail of the Student's t-distribution.
+	return regularizedIncompleteBeta(df/2, 0.5, df/(df+t*t))
+}
+
+func meanVariance(values []float64) (mean, variance float64) {
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+	return mean, variance / float64(len(values)-1)
+}
+
+// proportionZTest returns the two-sided P-value of the two-proportion z-test of
+// x1 successes out of n1 and x2 out of n2, or NaN if either of them is empty.
+func proportionZTest(x1, n1, x2, n2 float64) float64 {
+	if n1 == 0 || n2 == 0 {
+		return math.NaN()
+	}
+	p1, p2 := x1/n1, x2/n2
+	pooled := (x1 + x2) / (n1 + n2)
+	se := math.Sqrt(pooled * (1 - pooled) * (1/n1 + 1/n2))
+	if se == 0 {
+		if p1 == p2 {
+			return 1
+		}
+		return 0
+	}
+	z := (p1 - p2) / se
+	return math.Erfc(math.Abs(z) / math.Sqrt2)
+}
+
+// regularizedIncompleteBeta returns I_x(a, b), evaluated with its continued
+// fraction, as in Numerical Recipes.
+func regularizedIncompleteBeta(a, b, x float64) float64 {
+	switch {
+	case x <= 0:
+		return 0
+	case x >= 1:
+		return 1
+	}
+	lga, _ := math.Lgamma(a)
+	lgb, _ := math.Lgamma(b)
+	lgab, _ := math.Lgamma(a + b)
+	front := math.Exp(lgab - lga - lgb + a*math.Log(x) + b*math.Log(1-x))
+
+	// The continued fraction converges quickly only on this side.
+	if x > (a+1)/(a+b+2) {
+		return 1 - front*betaContinuedFraction(b, a, 1-x)/b
+	}
+	return front * betaContinuedFraction(a, b, x) / a
+}
+
+func betaContinuedFraction(a, b, x float64) float64 {
+	const (
+		maxIterations = 300
+		epsilon       = 1e-14
+		tiny          = 1e-300
+	)
+	clamp := func(v float64) float64 {
+		if math.Abs(v) < tiny {
+			return tiny
+		}
+		return v
+	}
+
+	c, d := 1.0, 1/clamp(1-(a+b)*x/(a+1))
+	h := d
+	for m := 1.0; m <= maxIterations; m++ {
+		// the even step
+		num := m * (b - m) * x / ((a + 2*m - 1) * (a + 2*m))
+		d = 1 / clamp(1+num*d)
+		c = clamp(1 + num/c)
+		h *= d * c
+
+		// the odd step
+		num = -(a + m) * (a + b + m) * x / ((a + 2*m) * (a + 2*m + 1))
+		d = 1 / clamp(1+num*d)
+		c = clamp(1 + num/c)
+		delta := d * c
+		h *= delta
+		if math.Abs(delta-1) < epsilon {
+			break
+		}
+	}
+	return h
+}
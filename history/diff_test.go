@@ -0,0 +1,128 @@
+package history
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWelchTTest(t *testing.T) {
+	t.Parallel()
+
+	assert.InDelta(t, 0.3466, welchTTest([]float64{1, 2, 3, 4, 5}, []float64{2, 3, 4, 5, 6}), 1e-4)
+	assert.InDelta(t, 0.05, welchTTest([]float64{0, 0.5, 1, 1.5, 2}, []float64{1.153, 1.653, 2.153, 2.653, 3.153}), 1e-3)
+	assert.Equal(t, 1.0, welchTTest([]float64{1, 1}, []float64{1, 1}))
+	assert.Equal(t, 0.0, welchTTest([]float64{1, 1}, []float64{2, 2}))
+	assert.True(t, math.IsNaN(welchTTest([]float64{1}, []float64{1, 2})))
+}
+
+func TestProportionZTest(t *testing.T) {
+	t.Parallel()
+
+	assert.InDelta(t, 0.1552, proportionZTest(50, 100, 60, 100), 1e-4)
+	assert.Equal(t, 1.0, proportionZTest(0, 10, 0, 20))
+	assert.True(t, math.IsNaN(proportionZTest(0, 0, 1, 2)))
+}
+
+func testRecord(t *testing.T, id string, summary string, durations []float64) *Record {
+	t.Helper()
+
+	r := &Record{ID: id, Summary: json.RawMessage(summary)}
+	for _, d := range durations {
+		r.Intervals = append(r.Intervals, Interval{Metrics: map[string]map[string]float64{
+			"http_req_duration": {"p(95)": d},
+		}})
+	}
+	return r
+}
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+
+	base := testRecord(t, "1", `{"metrics": {
+		"http_req_duration": {"type": "trend", "values": {"p(95)": 100}},
+		"http_req_failed": {"type": "rate", "values": {"rate": 0.01, "passes": 10, "fails": 990}},
+		"checks": {"type": "rate", "values": {"rate": 0.99, "passes": 990, "fails": 10}},
+		"iterations": {"type": "counter", "values": {"count": 1000}},
+		"only_in_base": {"type": "counter", "values": {"count": 1}}
+	}}`, []float64{98, 101, 99, 102, 100})
+
+	t.Run("regressions", func(t *testing.T) {
+		t.Parallel()
+
+		next := testRecord(t, "2", `{"metrics": {
+			"http_req_duration": {"type": "trend", "values": {"p(95)": 150}},
+			"http_req_failed": {"type": "rate", "values": {"rate": 0.05, "passes": 50, "fails": 950}},
+			"checks": {"type": "rate", "values": {"rate": 0.95, "passes": 950, "fails": 50}},
+			"iterations": {"type": "counter", "values": {"count": 1200}}
+		}}`, []float64{148, 151, 149, 152, 150})
+
+		changes, err := Diff(base, next, DefaultAlpha)
+		require.NoError(t, err)
+		regressions := make(map[string]bool)
+		for _, c := range changes {
+			assert.False(t, c.Improvement, c.Metric)
+			regressions[c.Metric+" "+c.Stat] = c.Regression
+		}
+		assert.Equal(t, map[string]bool{
+			"checks fails":            false,
+			"checks passes":           false,
+			"checks rate":             true,
+			"http_req_duration p(95)": true,
+			"http_req_failed fails":   false,
+			"http_req_failed passes":  false,
+			"http_req_failed rate":    true,
+			"iterations count":        false,
+		}, regressions)
+
+		assert.Equal(t, "checks", changes[0].Metric)
+		assert.InDelta(t, 0.2, changes[len(changes)-1].Relative(), 1e-9)
+		assert.True(t, math.IsNaN(changes[len(changes)-1].PValue))
+	})
+
+	t.Run("no significant changes", func(t *testing.T) {
+		t.Parallel()
+
+		next := testRecord(t, "2", `{"metrics": {
+			"http_req_duration": {"type": "trend", "values": {"p(95)": 100.5}},
+			"http_req_failed": {"type": "rate", "values": {"rate": 0.011, "passes": 11, "fails": 989}}
+		}}`, []float64{102, 99, 101, 98, 100.5})
+
+		changes, err := Diff(base, next, DefaultAlpha)
+		require.NoError(t, err)
+		require.NotEmpty(t, changes)
+		for _, c := range changes {
+			assert.False(t, c.Regression, c.Metric)
+			assert.False(t, c.Improvement, c.Metric)
+		}
+	})
+
+	t.Run("improvements", func(t *testing.T) {
+		t.Parallel()
+
+		next := testRecord(t, "2", `{"metrics": {
+			"http_req_duration": {"type": "trend", "values": {"p(95)": 50}}
+		}}`, []float64{48, 51, 49, 52, 50})
+
+		changes, err := Diff(next, base, DefaultAlpha)
+		require.NoError(t, err)
+		require.Len(t, changes, 1)
+		assert.True(t, changes[0].Regression)
+
+		changes, err = Diff(base, next, DefaultAlpha)
+		require.NoError(t, err)
+		require.Len(t, changes, 1)
+		assert.True(t, changes[0].Improvement)
+		assert.InDelta(t, -0.5, changes[0].Relative(), 1e-9)
+	})
+
+	t.Run("invalid summary", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := Diff(base, &Record{ID: "2", Summary: json.RawMessage(`[]`)}, DefaultAlpha)
+		assert.ErrorContains(t, err, "couldn't parse the summary of the test run 2")
+	})
+}
@@ -0,0 +1,173 @@
+// Package history implements the local store of the results of the test runs,
+// which `k6 run --history` records them in and `k6 history` lists and compares.
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/liuxd6825/k6server/lib/fsext"
+)
+
+// ErrRunNotFound is returned when there is no recorded run with the given ID.
+var ErrRunNotFound = errors.New("no recorded test run with that ID was found")
+
+// Record is the result of a test run.
+type Record struct {
+	// ID is assigned by the store, in the order the runs were recorded.
+	ID        string    `json:"id"`
+	Script    string    `json:"script"`
+	K6Version string    `json:"k6Version"`
+	Started   time.Time `json:"started"`
+	Ended     time.Time `json:"ended"`
+
+	Options json.RawMessage   `json:"options"`
+	Tags    map[string]string `json:"tags,omitempty"`
+
+	// ExitCode is the exit code of `k6 run`, and Error its error, if it
+	// failed.
+	ExitCode   int         `json:"exitCode"`
+	Error      string      `json:"error,omitempty"`
+	Thresholds []Threshold `json:"thresholds,omitempty"`
+
+	// Summary is the data of the end-of-test summary, which is passed to
+	// handleSummary().
+	Summary   json.RawMessage `json:"summary"`
+	Intervals []Interval      `json:"intervals,omitempty"`
+}
+
+// Threshold is the outcome of a threshold at the end of the test run.
+type Threshold struct {
+	Metric string `json:"metric"`
+	Source string `json:"source"`
+	OK     bool   `json:"ok"`
+}
+
+// ThresholdsPassed returns whether none of the thresholds of the run failed.
+func (r *Record) ThresholdsPassed() bool {
+	for _, t := range r.Thresholds {
+		if !t.OK {
+			return false
+		}
+	}
+	return true
+}
+
+// Store keeps the records as JSON files in a directory, one per run, which are
+// named after the IDs of the runs.
+type Store struct {
+	fs  fsext.Fs
+	dir string
+}
+
+// NewStore returns a store of the records in the given directory, which is
+// created with the first record.
+func NewStore(fs fsext.Fs, dir string) *Store {
+	return &Store{fs: fs, dir: dir}
+}
+
+// Dir returns the directory of the store.
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+func (s *Store) path(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
+
+// ids returns the IDs of the records, in the order they were recorded.
+func (s *Store) ids() ([]int, error) {
+	entries, err := fsext.ReadDir(s.fs, s.dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int, 0, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		if id, err := strconv.Atoi(strings.TrimSuffix(name, ".json")); err == nil && id > 0 {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+	return ids, nil
+}
+
+// Save records the run with the next ID, which is set on the record.
+func (s *Store) Save(r *Record) error {
+	if err := s.fs.MkdirAll(s.dir, 0o755); err != nil {
+		return fmt.Errorf("couldn't create the history directory: %w", err)
+	}
+	ids, err := s.ids()
+	if err != nil {
+		return err
+	}
+	next := 1
+	if len(ids) > 0 {
+		next = ids[len(ids)-1] + 1
+	}
+
+	// Another run could be recorded at the same time, so the file is only
+	// created if it doesn't exist, and the ID after it is tried if it does.
+	for ; ; next++ {
+		r.ID = strconv.Itoa(next)
+		f, err := s.fs.OpenFile(s.path(r.ID), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		err = json.NewEncoder(f).Encode(r)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		return err
+	}
+}
+
+// Load returns the record of the run with the given ID.
+func (s *Store) Load(id string) (*Record, error) {
+	data, err := fsext.ReadFile(s.fs, s.path(id))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrRunNotFound, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var r Record
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("couldn't parse the record of the test run %s: %w", id, err)
+	}
+	return &r, nil
+}
+
+// List returns all of the records, in the order they were recorded.
+func (s *Store) List() ([]*Record, error) {
+	ids, err := s.ids()
+	if err != nil {
+		return nil, err
+	}
+	records := make([]*Record, 0, len(ids))
+	for _, id := range ids {
+		r, err := s.Load(strconv.Itoa(id))
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, r)
+	}
+	return records, nil
+}
@@ -0,0 +1,87 @@
+package history
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/liuxd6825/k6server/lib/fsext"
+)
+
+func TestStore(t *testing.T) {
+	t.Parallel()
+
+	fs := fsext.NewMemMapFs()
+	store := NewStore(fs, "/history")
+
+	records, err := store.List()
+	require.NoError(t, err)
+	assert.Empty(t, records)
+
+	started := time.Date(2023, time.March, 1, 12, 0, 0, 0, time.UTC)
+	first := &Record{
+		Script:     "file:///script.js",
+		Started:    started,
+		Ended:      started.Add(time.Minute),
+		Options:    json.RawMessage(`{"vus":1}`),
+		Thresholds: []Threshold{{Metric: "checks", Source: "rate>0.9", OK: true}},
+		Summary:    json.RawMessage(`{"metrics":{}}`),
+	}
+	require.NoError(t, store.Save(first))
+	assert.Equal(t, "1", first.ID)
+	second := &Record{
+		Script:     "file:///script.js",
+		ExitCode:   99,
+		Thresholds: []Threshold{{Metric: "checks", Source: "rate>0.9", OK: false}},
+	}
+	require.NoError(t, store.Save(second))
+	assert.Equal(t, "2", second.ID)
+
+	// the files that aren't records are ignored
+	require.NoError(t, fsext.WriteFile(fs, "/history/notes.txt", []byte("notes"), 0o644))
+
+	records, err = store.List()
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, first.ID, records[0].ID)
+	assert.Equal(t, started, records[0].Started.UTC())
+	assert.JSONEq(t, `{"vus":1}`, string(records[0].Options))
+	assert.True(t, records[0].ThresholdsPassed())
+	assert.Equal(t, 99, records[1].ExitCode)
+	assert.False(t, records[1].ThresholdsPassed())
+
+	_, err = store.Load("3")
+	assert.ErrorIs(t, err, ErrRunNotFound)
+}
+
+func TestStoreConcurrentSaves(t *testing.T) {
+	t.Parallel()
+
+	store := NewStore(fsext.NewMemMapFs(), "/history")
+
+	const runs = 10
+	var wg sync.WaitGroup
+	records := make([]*Record, runs)
+	for i := range records {
+		records[i] = &Record{}
+		wg.Add(1)
+		go func(r *Record) {
+			defer wg.Done()
+			assert.NoError(t, store.Save(r))
+		}(records[i])
+	}
+	wg.Wait()
+
+	ids := make(map[string]struct{})
+	for _, r := range records {
+		ids[r.ID] = struct{}{}
+	}
+	assert.Len(t, ids, runs)
+	saved, err := store.List()
+	require.NoError(t, err)
+	assert.Len(t, saved, runs)
+}
@@ -0,0 +1,120 @@
+package history
+
+import (
+	"sort"
+	"time"
+
+	"github.com/liuxd6825/k6server/metrics"
+	"github.com/liuxd6825/k6server/output"
+)
+
+const flushPeriod = 200 * time.Millisecond
+
+// DefaultInterval is the length of the intervals the metrics of a recorded run
+// are aggregated in.
+const DefaultInterval = 5 * time.Second
+
+// Interval has the aggregated values of the metrics in an interval of the test
+// run, by metric name, with the same keys as the end-of-test summary.
+type Interval struct {
+	Time    time.Time                     `json:"time"`
+	Metrics map[string]map[string]float64 `json:"metrics"`
+}
+
+// Recorder is an output that aggregates the metric samples of a test run in
+// intervals, for its record. The trend metrics are aggregated with the
+// summary trend stats, so they can be compared with the ones of the summary.
+type Recorder struct {
+	output.SampleBuffer
+
+	interval        time.Duration
+	resolvers       map[string]func(*metrics.TrendSink) float64
+	periodicFlusher *output.PeriodicFlusher
+
+	// buckets are only accessed by the flusher, and after it's stopped.
+	buckets map[time.Time]map[*metrics.Metric]metrics.Sink
+}
+
+var _ output.Output = &Recorder{}
+
+// NewRecorder returns a recorder with the given interval and trend stats.
+func NewRecorder(interval time.Duration, trendStats []string) (*Recorder, error) {
+	resolvers, err := metrics.GetResolversForTrendColumns(trendStats)
+	if err != nil {
+		return nil, err
+	}
+	return &Recorder{
+		interval:  interval,
+		resolvers: resolvers,
+		buckets:   make(map[time.Time]map[*metrics.Metric]metrics.Sink),
+	}, nil
+}
+
+// Description returns a human-readable description of the output.
+func (r *Recorder) Description() string {
+	return "history"
+}
+
+// Start starts the goroutine that aggregates the buffered samples.
+func (r *Recorder) Start() error {
+	pf, err := output.NewPeriodicFlusher(flushPeriod, r.flushMetrics)
+	if err != nil {
+		return err
+	}
+	r.periodicFlusher = pf
+	return nil
+}
+
+// Stop aggregates the remaining samples and stops the goroutine.
+func (r *Recorder) Stop() error {
+	r.periodicFlusher.Stop()
+	return nil
+}
+
+func (r *Recorder) flushMetrics() {
+	for _, sc := range r.GetBufferedSamples() {
+		for _, sample := range sc.GetSamples() {
+			at := sample.Time.Truncate(r.interval)
+			bucket, ok := r.buckets[at]
+			if !ok {
+				bucket = make(map[*metrics.Metric]metrics.Sink)
+				r.buckets[at] = bucket
+			}
+			sink, ok := bucket[sample.Metric]
+			if !ok {
+				sink = metrics.NewSink(sample.Metric.Type)
+				bucket[sample.Metric] = sink
+			}
+			sink.Add(sample)
+		}
+	}
+}
+
+// Intervals returns the aggregated intervals, in chronological order. It must
+// only be called after the output is stopped.
+func (r *Recorder) Intervals() []Interval {
+	intervals := make([]Interval, 0, len(r.buckets))
+	for at, bucket := range r.buckets {
+		values := make(map[string]map[string]float64, len(bucket))
+		for m, sink := range bucket {
+			values[m.Name] = r.format(sink)
+		}
+		intervals = append(intervals, Interval{Time: at, Metrics: values})
+	}
+	sort.Slice(intervals, func(i, j int) bool {
+		return intervals[i].Time.Before(intervals[j].Time)
+	})
+	return intervals
+}
+
+func (r *Recorder) format(sink metrics.Sink) map[string]float64 {
+	trend, ok := sink.(*metrics.TrendSink)
+	if !ok {
+		return sink.Format(r.interval)
+	}
+	values := make(map[string]float64, len(r.resolvers))
+	for stat, resolve := range r.resolvers {
+		values[stat] = resolve(trend)
+	}
+	return values
+}
@@ -0,0 +1,71 @@
+package history
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/liuxd6825/k6server/metrics"
+)
+
+func TestRecorder(t *testing.T) {
+	t.Parallel()
+
+	registry := metrics.NewRegistry()
+	duration, err := registry.NewMetric("duration", metrics.Trend, metrics.Time)
+	require.NoError(t, err)
+	failed, err := registry.NewMetric("failed", metrics.Rate)
+	require.NoError(t, err)
+	iterations, err := registry.NewMetric("iterations", metrics.Counter)
+	require.NoError(t, err)
+
+	recorder, err := NewRecorder(10*time.Second, []string{"avg", "p(90)"})
+	require.NoError(t, err)
+	require.NoError(t, recorder.Start())
+
+	start := time.Date(2023, time.March, 1, 12, 0, 0, 0, time.UTC)
+	sample := func(m *metrics.Metric, at time.Duration, value float64) metrics.SampleContainer {
+		return metrics.Sample{
+			TimeSeries: metrics.TimeSeries{Metric: m, Tags: registry.RootTagSet().With("key", "val")},
+			Time:       start.Add(at),
+			Value:      value,
+		}
+	}
+	recorder.AddMetricSamples([]metrics.SampleContainer{
+		sample(duration, time.Second, 10),
+		sample(duration, 2*time.Second, 20),
+		sample(failed, 3*time.Second, 1),
+		sample(iterations, 4*time.Second, 1),
+		sample(iterations, 5*time.Second, 1),
+	})
+	recorder.AddMetricSamples([]metrics.SampleContainer{
+		sample(duration, 11*time.Second, 100),
+		sample(failed, 12*time.Second, 0),
+	})
+	require.NoError(t, recorder.Stop())
+
+	intervals := recorder.Intervals()
+	require.Len(t, intervals, 2)
+
+	assert.Equal(t, start, intervals[0].Time)
+	assert.Equal(t, map[string]map[string]float64{
+		"duration":   {"avg": 15, "p(90)": 19},
+		"failed":     {"rate": 1},
+		"iterations": {"count": 2, "rate": 0.2},
+	}, intervals[0].Metrics)
+
+	assert.Equal(t, start.Add(10*time.Second), intervals[1].Time)
+	assert.Equal(t, map[string]map[string]float64{
+		"duration": {"avg": 100, "p(90)": 100},
+		"failed":   {"rate": 0},
+	}, intervals[1].Metrics)
+}
+
+func TestRecorderInvalidTrendStats(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewRecorder(DefaultInterval, []string{"p(101)"})
+	assert.Error(t, err)
+}
@@ -20,6 +20,7 @@ import (
 	"github.com/liuxd6825/k6server/js/modules/k6/html"
 	"github.com/liuxd6825/k6server/js/modules/k6/http"
 	"github.com/liuxd6825/k6server/js/modules/k6/metrics"
+	"github.com/liuxd6825/k6server/js/modules/k6/server"
 	"github.com/liuxd6825/k6server/js/modules/k6/timers"
 	"github.com/liuxd6825/k6server/js/modules/k6/ws"
 
@@ -52,6 +53,7 @@ func getInternalJSModules() map[string]interface{} {
 		"k6/html":                 html.New(),
 		"k6/http":                 http.New(),
 		"k6/metrics":              metrics.New(),
+		"k6/server":               server.New(),
 		"k6/ws":                   ws.New(),
 		"k6/experimental/grpc": newRemovedModule(
 			"k6/experimental/grpc has been graduated, please use k6/net/grpc instead." +
@@ -44,7 +44,33 @@ func (c *Client) Load(importPaths []string, filenames ...string) ([]MethodInfo,
 		return nil, errors.New("load must be called in the init context")
 	}
 
-	initEnv := c.vu.InitEnv()
+	fdset, err := LoadProtoFiles(c.vu.InitEnv(), importPaths, filenames...)
+	if err != nil {
+		return nil, err
+	}
+	return c.convertToMethodInfo(fdset)
+}
+
+// LoadProtoset will parse the given protoset file (serialized FileDescriptorSet) and make the file
+// descriptors available to request.
+func (c *Client) LoadProtoset(protosetPath string) ([]MethodInfo, error) {
+	if c.vu.State() != nil {
+		return nil, errors.New("load must be called in the init context")
+	}
+
+	fdset, err := LoadProtoset(c.vu.InitEnv(), protosetPath)
+	if err != nil {
+		return nil, err
+	}
+	return c.convertToMethodInfo(fdset)
+}
+
+// LoadProtoFiles parses the given proto files, relative to the import paths or
+// to the current working directory of the script, into a FileDescriptorSet
+// which also contains all of their dependencies.
+func LoadProtoFiles(
+	initEnv *common.InitEnvironment, importPaths []string, filenames ...string,
+) (*descriptorpb.FileDescriptorSet, error) {
 	if initEnv == nil {
 		return nil, errors.New("missing init environment")
 	}
@@ -74,17 +100,12 @@ func (c *Client) Load(importPaths []string, filenames ...string) ([]MethodInfo,
 	for _, fd := range fds {
 		fdset.File = append(fdset.File, walkFileDescriptors(seen, fd)...)
 	}
-	return c.convertToMethodInfo(fdset)
+	return fdset, nil
 }
 
-// LoadProtoset will parse the given protoset file (serialized FileDescriptorSet) and make the file
-// descriptors available to request.
-func (c *Client) LoadProtoset(protosetPath string) ([]MethodInfo, error) {
-	if c.vu.State() != nil {
-		return nil, errors.New("load must be called in the init context")
-	}
-
-	initEnv := c.vu.InitEnv()
+// LoadProtoset reads the given protoset file, i.e. a serialized
+// FileDescriptorSet.
+func LoadProtoset(initEnv *common.InitEnvironment, protosetPath string) (*descriptorpb.FileDescriptorSet, error) {
 	if initEnv == nil {
 		return nil, errors.New("missing init environment")
 	}
@@ -105,8 +126,7 @@ func (c *Client) LoadProtoset(protosetPath string) ([]MethodInfo, error) {
 	if err = proto.Unmarshal(fdsetBytes, fdset); err != nil {
 		return nil, fmt.Errorf("couldn't unmarshal protoset file %s: %w", protosetPath, err)
 	}
-
-	return c.convertToMethodInfo(fdset)
+	return fdset, nil
 }
 
 // Note: this function was lifted from `lib/options.go`
@@ -0,0 +1,314 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/dop251/goja"
+	"github.com/mstoykov/k6-taskqueue-lib/taskqueue"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/liuxd6825/k6server/js/common"
+)
+
+// GRPCMethod is a gRPC method whose handlers were registered by a script.
+type GRPCMethod struct {
+	// FullMethod is the full name of the method as used by gRPC, e.g.
+	// /hello.HelloService/SayHello
+	FullMethod string
+	Descriptor protoreflect.MethodDescriptor
+
+	handlers streamHandlers
+}
+
+// grpcServices are the proto files loaded by a script and the handlers of
+// their methods.
+type grpcServices struct {
+	files       *protoregistry.Files
+	descriptors map[string]protoreflect.MethodDescriptor // all of the loaded methods
+	methods     map[string]*GRPCMethod                   // the methods with handlers
+}
+
+func newGRPCServices() *grpcServices {
+	return &grpcServices{
+		files:       new(protoregistry.Files),
+		descriptors: make(map[string]protoreflect.MethodDescriptor),
+		methods:     make(map[string]*GRPCMethod),
+	}
+}
+
+// GRPCMethods returns the gRPC methods with handlers, sorted by name.
+func (r *Router) GRPCMethods() []GRPCMethod {
+	methods := make([]GRPCMethod, 0, len(r.grpc.methods))
+	for _, m := range r.grpc.methods {
+		methods = append(methods, *m)
+	}
+	sort.Slice(methods, func(i, j int) bool { return methods[i].FullMethod < methods[j].FullMethod })
+	return methods
+}
+
+// GRPCFiles returns the descriptors of the proto files loaded by the script.
+func (r *Router) GRPCFiles() *protoregistry.Files {
+	return r.grpc.files
+}
+
+// addProtoFiles makes the services of the files available for registering
+// gRPC handlers. The files which were already loaded are skipped.
+func (r *Router) addProtoFiles(fdset *descriptorpb.FileDescriptorSet) error {
+	files, err := protodesc.NewFiles(fdset)
+	if err != nil {
+		return err
+	}
+	files.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+		if _, ferr := r.grpc.files.FindFileByPath(fd.Path()); ferr == nil {
+			return true
+		}
+		if err = r.grpc.files.RegisterFile(fd); err != nil {
+			return false
+		}
+		sds := fd.Services()
+		for i := 0; i < sds.Len(); i++ {
+			mds := sds.Get(i).Methods()
+			for j := 0; j < mds.Len(); j++ {
+				md := mds.Get(j)
+				r.grpc.descriptors[fmt.Sprintf("/%s/%s", md.Parent().FullName(), md.Name())] = md
+			}
+		}
+		return true
+	})
+	return err
+}
+
+func (r *Router) addGRPCMethod(method string, handlers streamHandlers) error {
+	fullMethod := method
+	if !strings.HasPrefix(fullMethod, "/") {
+		fullMethod = "/" + fullMethod
+	}
+	md, ok := r.grpc.descriptors[fullMethod]
+	if !ok {
+		return fmt.Errorf("method %q not found in the loaded proto files", method)
+	}
+	if _, ok = r.grpc.methods[fullMethod]; ok {
+		return fmt.Errorf("the gRPC method '%s' is already registered", method)
+	}
+	r.grpc.methods[fullMethod] = &GRPCMethod{FullMethod: fullMethod, Descriptor: md, handlers: handlers}
+	return nil
+}
+
+// ServeGRPC runs the handlers of the gRPC method for stream. It has to be
+// called on the VU event loop, and the call is finished only after the event
+// loop is done, when the status of the call is returned by GRPCCall.Finish.
+// The event loop keeps running until the client stops sending messages or the
+// script ends the call, and is released when the context of the VU is done.
+func (r *Router) ServeGRPC(
+	rt *goja.Runtime, fullMethod string, stream grpc.ServerStream, observer StreamObserver,
+) (*GRPCCall, error) {
+	method, ok := r.grpc.methods[fullMethod]
+	if !ok {
+		return nil, fmt.Errorf("the script didn't register the gRPC method '%s'", fullMethod)
+	}
+
+	md, _ := metadata.FromIncomingContext(stream.Context())
+	call := &GRPCCall{
+		Metadata: md,
+		rt:       rt,
+		stream:   stream,
+		method:   method,
+		observer: observer,
+		stop:     func() bool { return true },
+	}
+	call.value = rt.ToValue(call)
+	observer.Opened()
+
+	desc := method.Descriptor
+	if desc.IsStreamingClient() {
+		call.tq = taskqueue.New(r.vu.RegisterCallback)
+		call.stop = context.AfterFunc(r.vu.Context(), call.tq.Close)
+	}
+	if err := method.handlers.call("open", call.value); err != nil || call.ended {
+		return call, err
+	}
+	if desc.IsStreamingClient() {
+		go call.readLoop()
+		return call, nil
+	}
+
+	req, err := call.recv()
+	if err != nil {
+		call.status = status.Convert(err)
+		call.End()
+		return call, nil
+	}
+	if call.ended {
+		return call, nil
+	}
+	return call, method.handlers.call("message", call.value, req)
+}
+
+// GRPCCall is the JS representation of a call of a gRPC method.
+type GRPCCall struct {
+	// Metadata is the metadata sent by the client. It isn't a metadata.MD,
+	// whose methods would hide the keys from the script.
+	Metadata map[string][]string `js:"metadata"`
+
+	rt       *goja.Runtime
+	stream   grpc.ServerStream
+	method   *GRPCMethod
+	observer StreamObserver
+	tq       *taskqueue.TaskQueue // only for the client-streaming methods
+	value    goja.Value
+	stop     func() bool
+
+	// these are used only on the event loop
+	sent   int
+	ended  bool
+	status *status.Status
+}
+
+// Send sends a response message. The methods which aren't server-streaming
+// can send only one, which also ends the call.
+func (c *GRPCCall) Send(v goja.Value) error {
+	if c.ended {
+		return errors.New("the gRPC call has already ended")
+	}
+	desc := c.method.Descriptor
+	if !desc.IsStreamingServer() && c.sent > 0 {
+		return fmt.Errorf("the method %s isn't server-streaming, it can send only one response", c.method.FullMethod)
+	}
+
+	msg := dynamicpb.NewMessage(desc.Output())
+	if !common.IsNullish(v) {
+		b, err := v.ToObject(c.rt).MarshalJSON()
+		if err != nil {
+			return fmt.Errorf("unable to serialise the response object: %w", err)
+		}
+		if err = protojson.Unmarshal(b, msg); err != nil {
+			return fmt.Errorf("unable to serialise the response object to a protocol buffer: %w", err)
+		}
+	}
+	if err := c.stream.SendMsg(msg); err != nil {
+		return fmt.Errorf("error sending the gRPC response: %w", err)
+	}
+	c.sent++
+	c.observer.MessageSent()
+	if !desc.IsStreamingServer() {
+		c.End()
+	}
+	return nil
+}
+
+// End ends the call with the OK status, or with the one set by Error. The
+// messages the client sends after it are ignored.
+func (c *GRPCCall) End() {
+	if c.ended {
+		return
+	}
+	c.ended = true
+	c.stop()
+	if c.tq != nil {
+		c.tq.Close()
+	}
+	c.observer.Closed()
+}
+
+// Error ends the call with the status code, e.g. StatusNotFound from the
+// k6/net/grpc module, and the message.
+func (c *GRPCCall) Error(code int, message string) error {
+	if c.ended {
+		return errors.New("the gRPC call has already ended")
+	}
+	c.status = status.New(codes.Code(code), message) //nolint:gosec
+	c.End()
+	return nil
+}
+
+// Finish returns the status of the call. It has to be called after the event
+// loop is done.
+func (c *GRPCCall) Finish() error {
+	c.stop()
+	if !c.ended {
+		c.ended = true
+		c.observer.Closed()
+	}
+	switch {
+	case c.status != nil:
+		return c.status.Err()
+	case !c.method.Descriptor.IsStreamingServer() && c.sent == 0:
+		return status.Errorf(codes.Internal, "the handlers of %s didn't send a response", c.method.FullMethod)
+	default:
+		return nil
+	}
+}
+
+// recv receives a message from the client and converts it to a JS object.
+func (c *GRPCCall) recv() (goja.Value, error) {
+	msg := dynamicpb.NewMessage(c.method.Descriptor.Input())
+	if err := c.stream.RecvMsg(msg); err != nil {
+		return nil, err
+	}
+	c.observer.MessageReceived()
+	return c.toValue(msg)
+}
+
+func (c *GRPCCall) toValue(msg *dynamicpb.Message) (goja.Value, error) {
+	// the zero values are included, like in the responses of the grpc client
+	raw, err := protojson.MarshalOptions{EmitUnpopulated: true}.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal the gRPC message: %w", err)
+	}
+	var v interface{}
+	if err = json.Unmarshal(raw, &v); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal the gRPC message: %w", err)
+	}
+	return c.rt.ToValue(v), nil
+}
+
+// readLoop receives the messages of a client-streaming method and runs the
+// message handler for them on the event loop, and then the end handler once
+// the client is done sending.
+func (c *GRPCCall) readLoop() {
+	for {
+		msg := dynamicpb.NewMessage(c.method.Descriptor.Input())
+		err := c.stream.RecvMsg(msg)
+		if errors.Is(err, io.EOF) {
+			c.tq.Queue(func() error {
+				defer c.tq.Close()
+				if c.ended {
+					return nil
+				}
+				return c.method.handlers.call("end", c.value)
+			})
+			return
+		}
+		if err != nil {
+			// e.g. the call was canceled
+			c.tq.Close()
+			return
+		}
+		c.observer.MessageReceived()
+		c.tq.Queue(func() error {
+			if c.ended {
+				return nil
+			}
+			v, verr := c.toValue(msg)
+			if verr != nil {
+				return verr
+			}
+			return c.method.handlers.call("message", c.value, v)
+		})
+	}
+}
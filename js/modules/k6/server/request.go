@@ -0,0 +1,155 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/dop251/goja"
+
+	"github.com/liuxd6825/k6server/js/common"
+)
+
+// Request is the JS representation of an incoming HTTP request.
+type Request struct {
+	Method     string            `js:"method"`
+	URL        string            `js:"url"`
+	Path       string            `js:"path"`
+	Host       string            `js:"host"`
+	RemoteAddr string            `js:"remoteAddr"`
+	Headers    map[string]string `js:"headers"`
+	Query      map[string]string `js:"query"`
+	Params     map[string]string `js:"params"`
+	Body       string            `js:"body"`
+
+	rt *goja.Runtime
+}
+
+func newRequest(rt *goja.Runtime, req *http.Request, params []routeParam) (*Request, error) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the request body: %w", err)
+	}
+
+	r := &Request{
+		Method:     req.Method,
+		URL:        req.URL.String(),
+		Path:       req.URL.Path,
+		Host:       req.Host,
+		RemoteAddr: req.RemoteAddr,
+		Headers:    make(map[string]string, len(req.Header)),
+		Query:      make(map[string]string),
+		Params:     make(map[string]string, len(params)),
+		Body:       string(body),
+		rt:         rt,
+	}
+	for name, values := range req.Header {
+		r.Headers[strings.ToLower(name)] = strings.Join(values, ", ")
+	}
+	for name, values := range req.URL.Query() {
+		r.Query[name] = values[0]
+	}
+	for _, p := range params {
+		r.Params[p.name] = req.PathValue(p.wildcard)
+	}
+	return r, nil
+}
+
+// JSON parses the request body as JSON.
+func (r *Request) JSON() (goja.Value, error) {
+	var v interface{}
+	if err := json.Unmarshal([]byte(r.Body), &v); err != nil {
+		return nil, fmt.Errorf("the request body isn't valid JSON: %w", err)
+	}
+	return r.rt.ToValue(v), nil
+}
+
+// Response is the response to an HTTP request built by the route handlers. It
+// is buffered and written only after all of the handlers are done.
+type Response struct {
+	status int
+	header http.Header
+	body   []byte
+	sent   bool
+}
+
+func newResponse() *Response {
+	return &Response{status: http.StatusOK, header: make(http.Header)}
+}
+
+// StatusCode returns the status code of the response.
+func (r *Response) StatusCode() int {
+	return r.status
+}
+
+// Body returns the body of the response.
+func (r *Response) Body() []byte {
+	return r.body
+}
+
+// Write writes the response to w.
+func (r *Response) Write(w http.ResponseWriter) error {
+	for name, values := range r.header {
+		w.Header()[name] = values
+	}
+	w.WriteHeader(r.status)
+	_, err := w.Write(r.body)
+	return err
+}
+
+func (r *Response) send(body []byte) error {
+	if r.sent {
+		return errors.New("the response has already been sent")
+	}
+	r.body = body
+	r.sent = true
+	return nil
+}
+
+// jsResponse is the JS representation of a Response.
+type jsResponse struct {
+	res *Response
+}
+
+// Status sets the status code of the response.
+func (r *jsResponse) Status(code int) *jsResponse {
+	r.res.status = code
+	return r
+}
+
+// Header sets a response header.
+func (r *jsResponse) Header(name, value string) *jsResponse {
+	r.res.header.Set(name, value)
+	return r
+}
+
+// JSON sends v encoded as JSON.
+func (r *jsResponse) JSON(v goja.Value) error {
+	var data interface{}
+	if v != nil {
+		data = v.Export()
+	}
+	body, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("error encoding the response as JSON: %w", err)
+	}
+	if r.res.header.Get("Content-Type") == "" {
+		r.res.header.Set("Content-Type", "application/json")
+	}
+	return r.res.send(body)
+}
+
+// Send sends body, which has to be a string or an ArrayBuffer.
+func (r *jsResponse) Send(body goja.Value) error {
+	if common.IsNullish(body) {
+		return r.res.send(nil)
+	}
+	data, err := common.ToBytes(body.Export())
+	if err != nil {
+		return err
+	}
+	return r.res.send(data)
+}
@@ -0,0 +1,225 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/dop251/goja"
+
+	"github.com/liuxd6825/k6server/js/modules"
+	"github.com/liuxd6825/k6server/lib/fault"
+)
+
+// RouteKind is the kind of the connections that a route accepts.
+type RouteKind string
+
+// The kinds of routes.
+const (
+	// HTTPRoute responds to the requests with the response built by its
+	// handler.
+	HTTPRoute RouteKind = "http"
+	// WebSocketRoute accepts WebSocket connections, see Router.ServeWebSocket.
+	WebSocketRoute RouteKind = "ws"
+	// SSERoute sends Server-Sent Events, see Router.ServeSSE.
+	SSERoute RouteKind = "sse"
+)
+
+// Route is a single HTTP route registered by a script.
+type Route struct {
+	Kind   RouteKind
+	Method string
+	// Path is the path as it was registered by the script, e.g. /users/:id
+	Path string
+	// Pattern is the net/http ServeMux pattern of the route, e.g.
+	// "GET /users/{id}". It also uniquely identifies the route.
+	Pattern string
+	// Faults are the faults declared by the script for the route, if any.
+	Faults *fault.Rule
+
+	params  []routeParam
+	handler goja.Callable
+	stream  streamHandlers
+}
+
+// routeParam maps the name of a path param in the script to its name in the
+// ServeMux pattern.
+type routeParam struct {
+	name, wildcard string
+}
+
+type middleware struct {
+	prefix  string
+	handler goja.Callable
+}
+
+// Router contains the routes and middlewares registered by a single VU.
+type Router struct {
+	vu          modules.VU
+	routes      []*Route
+	index       map[string]*Route
+	middlewares []middleware
+	grpc        *grpcServices
+}
+
+// routerKey is the symbol under which every VU keeps its Router in the global
+// object, so that it can be found by the server from the VU runtime.
+var routerKey = goja.NewSymbol("k6/server.router") //nolint:gochecknoglobals
+
+// routerFor returns the Router of the VU, creating it if needed.
+func routerFor(vu modules.VU) *Router {
+	rt := vu.Runtime()
+	if r := RouterOf(rt); r != nil {
+		return r
+	}
+	r := &Router{vu: vu, index: make(map[string]*Route), grpc: newGRPCServices()}
+	//nolint:errcheck,gosec // the global object is always extensible
+	rt.GlobalObject().SetSymbol(routerKey, rt.ToValue(r))
+	return r
+}
+
+// RouterOf returns the Router of the given VU runtime, or nil if the script
+// didn't import the k6/server module.
+func RouterOf(rt *goja.Runtime) *Router {
+	v := rt.GlobalObject().GetSymbol(routerKey)
+	if v == nil {
+		return nil
+	}
+	r, _ := v.Export().(*Router)
+	return r
+}
+
+// Routes returns the routes in the order they were registered.
+func (r *Router) Routes() []Route {
+	routes := make([]Route, len(r.routes))
+	for i, route := range r.routes {
+		routes[i] = *route
+	}
+	return routes
+}
+
+func (r *Router) addRoute(method, path string, handler goja.Callable) error {
+	return r.add(&Route{Kind: HTTPRoute, Method: method, Path: path, handler: handler})
+}
+
+// addStreamRoute registers a WebSocket or an SSE route, which are always
+// requested with GET.
+func (r *Router) addStreamRoute(kind RouteKind, path string, handlers streamHandlers) error {
+	return r.add(&Route{Kind: kind, Method: http.MethodGet, Path: path, stream: handlers})
+}
+
+func (r *Router) add(route *Route) error {
+	route.Method = strings.ToUpper(route.Method)
+	pattern, params, err := toPattern(route.Method, route.Path)
+	if err != nil {
+		return err
+	}
+	if _, ok := r.index[pattern]; ok {
+		return fmt.Errorf("the route '%s %s' is already registered", route.Method, route.Path)
+	}
+
+	route.Pattern, route.params = pattern, params
+	r.routes = append(r.routes, route)
+	r.index[pattern] = route
+	return nil
+}
+
+func (r *Router) setFaults(method, path string, rule fault.Rule) error {
+	method = strings.ToUpper(method)
+	pattern, _, err := toPattern(method, path)
+	if err != nil {
+		return err
+	}
+	route, ok := r.index[pattern]
+	if !ok {
+		return fmt.Errorf("the route '%s %s' has to be registered before its faults", method, path)
+	}
+	if err = rule.Validate(); err != nil {
+		return fmt.Errorf("invalid faults of the route '%s %s': %w", method, path, err)
+	}
+	route.Faults = &rule
+	return nil
+}
+
+func (r *Router) addMiddleware(prefix string, handler goja.Callable) {
+	r.middlewares = append(r.middlewares, middleware{prefix: prefix, handler: handler})
+}
+
+// Serve runs the middlewares and the handler of the route with the given
+// pattern for req. It has to be called on the VU event loop and the returned
+// Response is complete only after the event loop is done, since the handlers
+// may be asynchronous.
+func (r *Router) Serve(rt *goja.Runtime, pattern string, req *http.Request) (*Response, error) {
+	route, err := r.lookup(pattern, HTTPRoute)
+	if err != nil {
+		return nil, err
+	}
+
+	jsReq, err := newRequest(rt, req, route.params)
+	if err != nil {
+		return nil, err
+	}
+	res := newResponse()
+	reqValue, resValue := rt.ToValue(jsReq), rt.ToValue(&jsResponse{res: res})
+
+	for _, m := range r.middlewares {
+		if !hasPathPrefix(req.URL.Path, m.prefix) {
+			continue
+		}
+		if _, err = m.handler(goja.Undefined(), reqValue, resValue); err != nil {
+			return res, err
+		}
+		if res.sent {
+			return res, nil
+		}
+	}
+
+	_, err = route.handler(goja.Undefined(), reqValue, resValue)
+	return res, err
+}
+
+// lookup returns the route of the kind with the given pattern.
+func (r *Router) lookup(pattern string, kind RouteKind) (*Route, error) {
+	route, ok := r.index[pattern]
+	if !ok {
+		return nil, fmt.Errorf("the script didn't register the route '%s'", pattern)
+	}
+	if route.Kind != kind {
+		return nil, fmt.Errorf("the route '%s' is a %s route, not a %s one", pattern, route.Kind, kind)
+	}
+	return route, nil
+}
+
+// toPattern converts an express-like path, e.g. /users/:id/*, to a ServeMux
+// pattern, e.g. "GET /users/{id}/{wildcard...}", and returns its params.
+func toPattern(method, path string) (string, []routeParam, error) {
+	if !strings.HasPrefix(path, "/") {
+		return "", nil, fmt.Errorf("the route path '%s' must start with '/'", path)
+	}
+
+	var params []routeParam
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		switch {
+		case s == "*":
+			if i != len(segments)-1 {
+				return "", nil, fmt.Errorf("the wildcard in the route path '%s' must be the last segment", path)
+			}
+			params = append(params, routeParam{name: "*", wildcard: "wildcard"})
+			segments[i] = "{wildcard...}"
+		case strings.HasPrefix(s, ":"):
+			params = append(params, routeParam{name: s[1:], wildcard: s[1:]})
+			segments[i] = "{" + s[1:] + "}"
+		case strings.HasPrefix(s, "{") && strings.HasSuffix(s, "}"):
+			name := strings.TrimSuffix(s[1:len(s)-1], "...")
+			params = append(params, routeParam{name: name, wildcard: name})
+		}
+	}
+
+	return method + " " + strings.Join(segments, "/"), params, nil
+}
+
+func hasPathPrefix(path, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return prefix == "" || path == prefix || strings.HasPrefix(path, prefix+"/")
+}
@@ -0,0 +1,244 @@
+// Package server implements the k6/server module, which lets scripts register
+// the HTTP routes that are served by the `k6 server` command.
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/dop251/goja"
+
+	"github.com/liuxd6825/k6server/js/common"
+	"github.com/liuxd6825/k6server/js/modules"
+	"github.com/liuxd6825/k6server/js/modules/k6/grpc"
+	"github.com/liuxd6825/k6server/lib/fault"
+)
+
+type (
+	// RootModule is the global module instance that will create module
+	// instances for each VU.
+	RootModule struct{}
+
+	// ModuleInstance represents an instance of the server module for every VU.
+	ModuleInstance struct {
+		vu     modules.VU
+		router *Router
+	}
+)
+
+var (
+	_ modules.Module   = &RootModule{}
+	_ modules.Instance = &ModuleInstance{}
+)
+
+// ErrRouteOutsideInitContext is returned when a route or a middleware is
+// registered after the init context.
+var ErrRouteOutsideInitContext = common.NewInitContextError("routes can only be registered in the init context")
+
+// New returns a pointer to a new RootModule instance.
+func New() *RootModule {
+	return &RootModule{}
+}
+
+// NewModuleInstance implements the modules.Module interface to return
+// a new instance for each VU. All of the instances in the same VU share
+// a single Router.
+func (*RootModule) NewModuleInstance(vu modules.VU) modules.Instance {
+	return &ModuleInstance{vu: vu, router: routerFor(vu)}
+}
+
+// Exports returns the exports of the server module.
+func (mi *ModuleInstance) Exports() modules.Exports {
+	rt := mi.vu.Runtime()
+	return modules.Exports{
+		Named: map[string]interface{}{
+			"get":    rt.ToValue(mi.methodRoute(http.MethodGet)),
+			"post":   rt.ToValue(mi.methodRoute(http.MethodPost)),
+			"put":    rt.ToValue(mi.methodRoute(http.MethodPut)),
+			"patch":  rt.ToValue(mi.methodRoute(http.MethodPatch)),
+			"delete": rt.ToValue(mi.methodRoute(http.MethodDelete)),
+			"route":  rt.ToValue(mi.route),
+			"ws":     rt.ToValue(mi.ws),
+			"sse":    rt.ToValue(mi.sse),
+			"grpc":   rt.ToValue(mi.grpc),
+			"use":    rt.ToValue(mi.use),
+			"faults": rt.ToValue(mi.faults),
+
+			"loadProto":    rt.ToValue(mi.loadProto),
+			"loadProtoset": rt.ToValue(mi.loadProtoset),
+		},
+	}
+}
+
+func (mi *ModuleInstance) methodRoute(method string) func(string, goja.Value) error {
+	return func(path string, handler goja.Value) error {
+		return mi.route(method, path, handler)
+	}
+}
+
+// route registers handler for the given method and path.
+func (mi *ModuleInstance) route(method, path string, handler goja.Value) error {
+	if mi.vu.State() != nil {
+		return ErrRouteOutsideInitContext
+	}
+	fn, ok := goja.AssertFunction(handler)
+	if !ok {
+		return fmt.Errorf("the handler of the route '%s %s' must be a function", method, path)
+	}
+	return mi.router.addRoute(method, path, fn)
+}
+
+// ws registers a WebSocket route, e.g.
+// ws("/chat", { open(socket, req) {}, message(socket, data) {}, close(socket, code, reason) {} }).
+func (mi *ModuleInstance) ws(path string, handlers goja.Value) error {
+	if mi.vu.State() != nil {
+		return ErrRouteOutsideInitContext
+	}
+	h, err := mi.streamHandlers(fmt.Sprintf("ws route '%s'", path), handlers, "open", "message", "close")
+	if err != nil {
+		return err
+	}
+	return mi.router.addStreamRoute(WebSocketRoute, path, h)
+}
+
+// sse registers a Server-Sent Events route, e.g.
+// sse("/events", { open(stream, req) {}, close(stream) {} }).
+func (mi *ModuleInstance) sse(path string, handlers goja.Value) error {
+	if mi.vu.State() != nil {
+		return ErrRouteOutsideInitContext
+	}
+	h, err := mi.streamHandlers(fmt.Sprintf("sse route '%s'", path), handlers, "open", "close")
+	if err != nil {
+		return err
+	}
+	return mi.router.addStreamRoute(SSERoute, path, h)
+}
+
+// loadProto parses the proto files, just like the Client.load() of the
+// k6/net/grpc module, so that handlers can be registered for their methods.
+func (mi *ModuleInstance) loadProto(importPaths []string, filenames ...string) error {
+	if mi.vu.State() != nil {
+		return ErrRouteOutsideInitContext
+	}
+	fdset, err := grpc.LoadProtoFiles(mi.vu.InitEnv(), importPaths, filenames...)
+	if err != nil {
+		return err
+	}
+	return mi.router.addProtoFiles(fdset)
+}
+
+// loadProtoset loads a protoset file, just like the Client.loadProtoset() of
+// the k6/net/grpc module, so that handlers can be registered for its methods.
+func (mi *ModuleInstance) loadProtoset(protosetPath string) error {
+	if mi.vu.State() != nil {
+		return ErrRouteOutsideInitContext
+	}
+	fdset, err := grpc.LoadProtoset(mi.vu.InitEnv(), protosetPath)
+	if err != nil {
+		return err
+	}
+	return mi.router.addProtoFiles(fdset)
+}
+
+// grpc registers the handlers of a gRPC method of the loaded proto files, e.g.
+// grpc("hello.HelloService/SayHello", (call, req) => call.send({ reply: "hi" })),
+// where the function is the message handler, or
+// grpc("hello.HelloService/LotsOfGreetings", { open(call) {}, message(call, req) {}, end(call) {} }).
+func (mi *ModuleInstance) grpc(method string, handlers goja.Value) error {
+	if mi.vu.State() != nil {
+		return ErrRouteOutsideInitContext
+	}
+	var h streamHandlers
+	if fn, ok := goja.AssertFunction(handlers); ok {
+		h = streamHandlers{"message": fn}
+	} else {
+		var err error
+		if h, err = mi.streamHandlers(fmt.Sprintf("gRPC method '%s'", method), handlers, "open", "message", "end"); err != nil {
+			return err
+		}
+	}
+	return mi.router.addGRPCMethod(method, h)
+}
+
+// streamHandlers returns the handlers of a WebSocket or an SSE route, or of a
+// gRPC method, from the object with them, which can have only the handlers
+// with the given names. The name is how the errors refer to the route, e.g.
+// "ws route '/chat'".
+func (mi *ModuleInstance) streamHandlers(name string, v goja.Value, names ...string) (streamHandlers, error) {
+	if common.IsNullish(v) {
+		return nil, fmt.Errorf("the handlers of the %s must be an object", name)
+	}
+	obj := v.ToObject(mi.vu.Runtime())
+	handlers := make(streamHandlers, len(names))
+	for _, key := range obj.Keys() {
+		if !slices.Contains(names, key) {
+			return nil, fmt.Errorf("unknown handler '%s' of the %s, available are: %s",
+				key, name, strings.Join(names, ", "))
+		}
+		handler := obj.Get(key)
+		if common.IsNullish(handler) {
+			continue
+		}
+		fn, ok := goja.AssertFunction(handler)
+		if !ok {
+			return nil, fmt.Errorf("the %s handler of the %s must be a function", key, name)
+		}
+		handlers[key] = fn
+	}
+	return handlers, nil
+}
+
+// faults declares the faults which are injected into the responses of an
+// already registered route, e.g. faults("GET", "/users/:id", { error: { rate: 0.1 } }).
+func (mi *ModuleInstance) faults(method, path string, rule goja.Value) error {
+	if mi.vu.State() != nil {
+		return ErrRouteOutsideInitContext
+	}
+	if common.IsNullish(rule) {
+		return fmt.Errorf("the faults of the route '%s %s' must be an object", method, path)
+	}
+
+	// The rules are parsed just like the same rules in the options.
+	data, err := json.Marshal(rule.Export())
+	if err != nil {
+		return err
+	}
+	var r fault.Rule
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err = dec.Decode(&r); err != nil {
+		return fmt.Errorf("invalid faults of the route '%s %s': %w", method, path, err)
+	}
+	return mi.router.setFaults(method, path, r)
+}
+
+// use registers a middleware, which is called before the handlers of all
+// routes, or only of the ones under the prefix when it's given as the first
+// argument.
+func (mi *ModuleInstance) use(args ...goja.Value) error {
+	if mi.vu.State() != nil {
+		return ErrRouteOutsideInitContext
+	}
+
+	var prefix string
+	switch len(args) {
+	case 1:
+	case 2:
+		prefix = args[0].String()
+		args = args[1:]
+	default:
+		return fmt.Errorf("use() expects a middleware function and an optional path prefix, got %d arguments", len(args))
+	}
+
+	fn, ok := goja.AssertFunction(args[0])
+	if !ok {
+		return errors.New("the middleware must be a function")
+	}
+	mi.router.addMiddleware(prefix, fn)
+	return nil
+}
@@ -0,0 +1,311 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/liuxd6825/k6server/js/modulestest"
+	"github.com/liuxd6825/k6server/lib"
+	"github.com/liuxd6825/k6server/lib/fault"
+	"github.com/liuxd6825/k6server/lib/types"
+)
+
+func setupServerTest(t *testing.T) *modulestest.Runtime {
+	t.Helper()
+
+	runtime := modulestest.NewRuntime(t)
+	m, ok := New().NewModuleInstance(runtime.VU).(*ModuleInstance)
+	require.True(t, ok)
+	for name, export := range m.Exports().Named {
+		require.NoError(t, runtime.VU.Runtime().Set(name, export))
+	}
+	return runtime
+}
+
+func serve(t *testing.T, runtime *modulestest.Runtime, pattern string, req *http.Request) *httptest.ResponseRecorder {
+	t.Helper()
+
+	rt := runtime.VU.Runtime()
+	var res *Response
+	err := runtime.EventLoop.Start(func() error {
+		var err error
+		res, err = RouterOf(rt).Serve(rt, pattern, req)
+		return err
+	})
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	require.NoError(t, res.Write(rec))
+	return rec
+}
+
+func TestRoutes(t *testing.T) {
+	t.Parallel()
+
+	runtime := setupServerTest(t)
+	_, err := runtime.VU.Runtime().RunString(`
+		get("/users/:id", function(req, res) {
+			res.status(201).header("X-Id", req.params.id).json({ id: req.params.id, q: req.query.q });
+		});
+		post("/echo", function(req, res) {
+			res.send(req.body.toUpperCase());
+		});
+		route("OPTIONS", "/files/*", function(req, res) {
+			res.send(req.params["*"]);
+		});
+	`)
+	require.NoError(t, err)
+
+	routes := RouterOf(runtime.VU.Runtime()).Routes()
+	require.Len(t, routes, 3)
+	assert.Equal(t, "GET /users/{id}", routes[0].Pattern)
+	assert.Equal(t, "POST /echo", routes[1].Pattern)
+	assert.Equal(t, "OPTIONS /files/{wildcard...}", routes[2].Pattern)
+
+	runtime.MoveToVUContext(&lib.State{})
+
+	mux := http.NewServeMux()
+	for _, route := range routes {
+		route := route
+		mux.HandleFunc(route.Pattern, func(w http.ResponseWriter, r *http.Request) {
+			rec := serve(t, runtime, route.Pattern, r)
+			w.WriteHeader(rec.Code)
+			for k, v := range rec.Header() {
+				w.Header()[k] = v
+			}
+			_, _ = w.Write(rec.Body.Bytes())
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users/42?q=test", nil))
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.JSONEq(t, `{"id":"42","q":"test"}`, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader("hello")))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "HELLO", rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodOptions, "/files/a/b.txt", nil))
+	assert.Equal(t, "a/b.txt", rec.Body.String())
+}
+
+func TestMiddlewares(t *testing.T) {
+	t.Parallel()
+
+	runtime := setupServerTest(t)
+	_, err := runtime.VU.Runtime().RunString(`
+		use(function(req, res) {
+			res.header("X-Seen", "yes");
+		});
+		use("/admin", function(req, res) {
+			if (req.headers["authorization"] !== "secret") {
+				res.status(401).send("denied");
+			}
+		});
+		get("/admin/stats", function(req, res) {
+			res.json({ ok: true });
+		});
+		get("/administrator", function(req, res) {
+			res.send("not protected");
+		});
+	`)
+	require.NoError(t, err)
+	runtime.MoveToVUContext(&lib.State{})
+
+	rec := serve(t, runtime, "GET /admin/stats", httptest.NewRequest(http.MethodGet, "/admin/stats", nil))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Equal(t, "denied", rec.Body.String())
+	assert.Equal(t, "yes", rec.Header().Get("X-Seen"))
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/stats", nil)
+	req.Header.Set("Authorization", "secret")
+	rec = serve(t, runtime, "GET /admin/stats", req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	rec = serve(t, runtime, "GET /administrator", httptest.NewRequest(http.MethodGet, "/administrator", nil))
+	assert.Equal(t, "not protected", rec.Body.String())
+}
+
+func TestFaults(t *testing.T) {
+	t.Parallel()
+
+	runtime := setupServerTest(t)
+	_, err := runtime.VU.Runtime().RunString(`
+		get("/users/:id", function() {});
+		get("/users", function() {});
+		faults("get", "/users/:id", {
+			latency: { distribution: "uniform", min: "10ms", max: 100 },
+			truncate: { rate: 0.1, ratio: 0.5 },
+		});
+	`)
+	require.NoError(t, err)
+
+	routes := RouterOf(runtime.VU.Runtime()).Routes()
+	require.Len(t, routes, 2)
+	assert.Nil(t, routes[1].Faults)
+
+	rule := routes[0].Faults
+	require.NotNil(t, rule)
+	assert.Equal(t, fault.Uniform, rule.Latency.Distribution)
+	assert.Equal(t, types.Duration(10*time.Millisecond), rule.Latency.Min)
+	assert.Equal(t, types.Duration(100*time.Millisecond), rule.Latency.Max)
+	assert.Equal(t, &fault.Truncate{Rate: 0.1, Ratio: 0.5}, rule.Truncate)
+	assert.Nil(t, rule.Error)
+}
+
+func TestStreamRoutes(t *testing.T) {
+	t.Parallel()
+
+	runtime := setupServerTest(t)
+	_, err := runtime.VU.Runtime().RunString(`
+		get("/", function() {});
+		ws("/chat/:room", { message(socket, data) { socket.send(data); } });
+		sse("/events", { open(stream) { stream.send("hi"); } });
+	`)
+	require.NoError(t, err)
+
+	routes := RouterOf(runtime.VU.Runtime()).Routes()
+	require.Len(t, routes, 3)
+	assert.Equal(t, HTTPRoute, routes[0].Kind)
+	assert.Equal(t, WebSocketRoute, routes[1].Kind)
+	assert.Equal(t, "GET /chat/{room}", routes[1].Pattern)
+	assert.Equal(t, SSERoute, routes[2].Kind)
+	assert.Equal(t, "GET /events", routes[2].Pattern)
+
+	runtime.MoveToVUContext(&lib.State{})
+	rt := runtime.VU.Runtime()
+	err = runtime.EventLoop.Start(func() error {
+		_, serr := RouterOf(rt).Serve(rt, "GET /events", httptest.NewRequest(http.MethodGet, "/events", nil))
+		return serr
+	})
+	require.ErrorContains(t, err, "the route 'GET /events' is a sse route, not a http one")
+}
+
+func TestFormatEvent(t *testing.T) {
+	t.Parallel()
+
+	rt := setupServerTest(t).VU.Runtime()
+	testCases := []struct {
+		script, event string
+	}{
+		{`"hello"`, "data: hello\n\n"},
+		{`"two\nlines"`, "data: two\ndata: lines\n\n"},
+		{`[1, 2]`, "data: [1,2]\n\n"},
+		{
+			`({ id: 7, event: "tick", retry: 1000, data: { n: 1 } })`,
+			"id: 7\nevent: tick\nretry: 1000\ndata: {\"n\":1}\n\n",
+		},
+		{`({ event: "ping\nx" })`, "event: pingx\ndata: \n\n"},
+	}
+	for _, tc := range testCases {
+		v, err := rt.RunString(tc.script)
+		require.NoError(t, err)
+		event, err := formatEvent(v)
+		require.NoError(t, err)
+		assert.Equal(t, tc.event, string(event), tc.script)
+	}
+}
+
+func TestRouteErrors(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name, script, err string
+	}{
+		{
+			name:   "not a function",
+			script: `get("/a", 5)`,
+			err:    "the handler of the route 'GET /a' must be a function",
+		},
+		{
+			name:   "relative path",
+			script: `get("a", function() {})`,
+			err:    "the route path 'a' must start with '/'",
+		},
+		{
+			name:   "duplicate",
+			script: `get("/a", function() {}); get("/a", function() {})`,
+			err:    "the route 'GET /a' is already registered",
+		},
+		{
+			name:   "wildcard in the middle",
+			script: `get("/a/*/b", function() {})`,
+			err:    "the wildcard in the route path '/a/*/b' must be the last segment",
+		},
+		{
+			name:   "stream route without handlers",
+			script: `ws("/a")`,
+			err:    "the handlers of the ws route '/a' must be an object",
+		},
+		{
+			name:   "unknown stream handler",
+			script: `sse("/a", { message() {} })`,
+			err:    "unknown handler 'message' of the sse route '/a', available are: open, close",
+		},
+		{
+			name:   "stream handler not a function",
+			script: `ws("/a", { open: 5 })`,
+			err:    "the open handler of the ws route '/a' must be a function",
+		},
+		{
+			name:   "stream route conflicting with a GET one",
+			script: `get("/a", function() {}); ws("/a", {})`,
+			err:    "the route 'GET /a' is already registered",
+		},
+		{
+			name:   "faults of an unknown route",
+			script: `faults("GET", "/a", { reset: { rate: 1 } })`,
+			err:    "the route 'GET /a' has to be registered before its faults",
+		},
+		{
+			name:   "unknown fault",
+			script: `get("/a", function() {}); faults("GET", "/a", { explode: true })`,
+			err:    `unknown field "explode"`,
+		},
+		{
+			name:   "invalid fault",
+			script: `get("/a", function() {}); faults("GET", "/a", { error: { rate: 2 } })`,
+			err:    "the error rate should be between 0 and 1, got 2",
+		},
+		{
+			name:   "gRPC method without proto files",
+			script: `grpc("hello.HelloService/SayHello", function() {})`,
+			err:    `method "hello.HelloService/SayHello" not found in the loaded proto files`,
+		},
+		{
+			name:   "unknown gRPC handler",
+			script: `grpc("hello.HelloService/SayHello", { close() {} })`,
+			err:    "unknown handler 'close' of the gRPC method 'hello.HelloService/SayHello', available are: open, message, end",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			runtime := setupServerTest(t)
+			_, err := runtime.VU.Runtime().RunString(tc.script)
+			require.ErrorContains(t, err, tc.err)
+		})
+	}
+
+	t.Run("outside the init context", func(t *testing.T) {
+		t.Parallel()
+
+		runtime := setupServerTest(t)
+		runtime.MoveToVUContext(&lib.State{})
+		_, err := runtime.VU.Runtime().RunString(`get("/a", function() {})`)
+		require.ErrorContains(t, err, ErrRouteOutsideInitContext.Error())
+	})
+}
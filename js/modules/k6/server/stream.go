@@ -0,0 +1,398 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/gorilla/websocket"
+	"github.com/mstoykov/k6-taskqueue-lib/taskqueue"
+
+	"github.com/liuxd6825/k6server/js/common"
+)
+
+// streamHandlers are the handlers of a WebSocket or an SSE route by name,
+// e.g. "open". All of them are optional.
+type streamHandlers map[string]goja.Callable
+
+// call calls the handler with the given name, if the script registered it.
+func (h streamHandlers) call(name string, args ...goja.Value) error {
+	fn, ok := h[name]
+	if !ok {
+		return nil
+	}
+	_, err := fn(goja.Undefined(), args...)
+	return err
+}
+
+// StreamObserver is notified about the connections of the WebSocket and SSE
+// routes and their messages, e.g. for emitting their metrics.
+type StreamObserver interface {
+	// Opened is called when the connection is ready for messages, i.e. when
+	// the WebSocket is open or the SSE response was started.
+	Opened()
+	// MessageSent is called for every message or event sent to the client.
+	MessageSent()
+	// MessageReceived is called for every message received from the client,
+	// off the VU event loop.
+	MessageReceived()
+	// Closed is called on the VU event loop when the connection is closed,
+	// after the close handler. Only the timers which the handlers didn't clear
+	// keep the event loop running after it.
+	Closed()
+}
+
+// closeTimeout is how long a close frame may take to be written.
+const closeTimeout = time.Second
+
+// ServeWebSocket runs the handlers of the WebSocket route with the given
+// pattern for conn, which was upgraded from req. It has to be called on the VU
+// event loop, which keeps running until the connection is closed, either by
+// the client, by the script, or because the context of req or of the VU is
+// done.
+func (r *Router) ServeWebSocket(
+	rt *goja.Runtime, pattern string, req *http.Request, conn *websocket.Conn, observer StreamObserver,
+) error {
+	route, err := r.lookup(pattern, WebSocketRoute)
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+	jsReq, err := newRequest(rt, req, route.params)
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+
+	s := &socket{
+		rt:       rt,
+		conn:     conn,
+		handlers: route.stream,
+		observer: observer,
+		tq:       taskqueue.New(r.vu.RegisterCallback),
+	}
+	s.value = rt.ToValue(s)
+
+	// The client is told that the server is going away when the request is
+	// canceled, while the VU being stopped just drops the connection.
+	stopReq := context.AfterFunc(req.Context(), func() { s.closeWith(websocket.CloseGoingAway, "") })
+	stopVU := context.AfterFunc(r.vu.Context(), func() { _ = conn.Close() })
+	s.stop = func() {
+		stopReq()
+		stopVU()
+	}
+
+	observer.Opened()
+	go s.readLoop()
+	if err = s.handlers.call("open", s.value, rt.ToValue(jsReq)); err != nil {
+		s.closeWith(websocket.CloseInternalServerErr, "")
+		return err
+	}
+	return nil
+}
+
+// socket is the JS representation of a WebSocket connection.
+type socket struct {
+	rt       *goja.Runtime
+	conn     *websocket.Conn
+	handlers streamHandlers
+	observer StreamObserver
+	tq       *taskqueue.TaskQueue
+	value    goja.Value
+	stop     func()
+
+	// closeCode and closeReason are set when the server closes the connection,
+	// so they are the ones reported to the close handler.
+	mu          sync.Mutex
+	closeCode   int
+	closeReason string
+}
+
+// Send sends data to the client, as a text message if it's a string and as a
+// binary one if it's an ArrayBuffer.
+func (s *socket) Send(data goja.Value) error {
+	if s.closing() {
+		return errors.New("the WebSocket is closed")
+	}
+
+	messageType, payload := websocket.TextMessage, []byte(nil)
+	if str, ok := data.Export().(string); ok {
+		payload = []byte(str)
+	} else {
+		var err error
+		if payload, err = common.ToBytes(data.Export()); err != nil {
+			return err
+		}
+		messageType = websocket.BinaryMessage
+	}
+
+	if err := s.conn.WriteMessage(messageType, payload); err != nil {
+		return fmt.Errorf("error sending a WebSocket message: %w", err)
+	}
+	s.observer.MessageSent()
+	return nil
+}
+
+// Close closes the connection with the given close code, 1000 by default, and
+// the optional reason.
+func (s *socket) Close(code goja.Value, reason string) {
+	c := websocket.CloseNormalClosure
+	if !common.IsNullish(code) {
+		c = int(code.ToInteger())
+	}
+	s.closeWith(c, reason)
+}
+
+func (s *socket) closing() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closeCode != 0
+}
+
+// closeWith sends a close frame and closes the connection. It's safe to call
+// it from any goroutine.
+func (s *socket) closeWith(code int, reason string) {
+	s.mu.Lock()
+	if s.closeCode != 0 {
+		s.mu.Unlock()
+		return
+	}
+	s.closeCode, s.closeReason = code, reason
+	s.mu.Unlock()
+
+	msg := websocket.FormatCloseMessage(code, reason)
+	_ = s.conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(closeTimeout))
+	_ = s.conn.Close()
+}
+
+// readLoop reads the messages from the client and runs the message handler for
+// them on the event loop, until the connection is closed.
+func (s *socket) readLoop() {
+	for {
+		messageType, data, err := s.conn.ReadMessage()
+		if err != nil {
+			s.closed(err)
+			return
+		}
+		s.observer.MessageReceived()
+		s.tq.Queue(func() error {
+			var msg goja.Value
+			if messageType == websocket.BinaryMessage {
+				msg = s.rt.ToValue(s.rt.NewArrayBuffer(data))
+			} else {
+				msg = s.rt.ToValue(string(data))
+			}
+			if herr := s.handlers.call("message", s.value, msg); herr != nil {
+				s.closeWith(websocket.CloseInternalServerErr, "")
+				return herr
+			}
+			return nil
+		})
+	}
+}
+
+// closed runs the close handler on the event loop, with the close code and
+// reason of the side that closed the connection, and releases the event loop.
+func (s *socket) closed(readErr error) {
+	s.mu.Lock()
+	code, reason := s.closeCode, s.closeReason
+	s.mu.Unlock()
+	if code == 0 {
+		code = websocket.CloseAbnormalClosure
+		var closeErr *websocket.CloseError
+		if errors.As(readErr, &closeErr) {
+			code, reason = closeErr.Code, closeErr.Text
+		}
+	}
+
+	s.tq.Queue(func() error {
+		defer s.tq.Close()
+		defer s.observer.Closed()
+		s.stop()
+		_ = s.conn.Close()
+		return s.handlers.call("close", s.value, s.rt.ToValue(code), s.rt.ToValue(reason))
+	})
+}
+
+// ServeSSE runs the handlers of the SSE route with the given pattern for req
+// and sends the events to w. It has to be called on the VU event loop, which
+// keeps running until the stream is closed, either by the script, or because
+// the context of req or of the VU is done, e.g. when the client disconnects.
+// The response is started after the open handler, so if it fails, the caller
+// can still respond with an error.
+func (r *Router) ServeSSE(
+	rt *goja.Runtime, pattern string, req *http.Request, w http.ResponseWriter, observer StreamObserver,
+) error {
+	route, err := r.lookup(pattern, SSERoute)
+	if err != nil {
+		return err
+	}
+	jsReq, err := newRequest(rt, req, route.params)
+	if err != nil {
+		return err
+	}
+
+	s := &eventStream{
+		rt:       rt,
+		w:        w,
+		rc:       http.NewResponseController(w),
+		handlers: route.stream,
+		observer: observer,
+		tq:       taskqueue.New(r.vu.RegisterCallback),
+	}
+	s.value = rt.ToValue(s)
+	stopReq := context.AfterFunc(req.Context(), func() { s.tq.Queue(s.finish) })
+	stopVU := context.AfterFunc(r.vu.Context(), func() { s.tq.Queue(s.finish) })
+	s.stop = func() {
+		stopReq()
+		stopVU()
+	}
+
+	if err = s.handlers.call("open", s.value, rt.ToValue(jsReq)); err != nil {
+		s.closed = true
+		s.stop()
+		s.tq.Close()
+		return err
+	}
+	return s.start()
+}
+
+// eventStream is the JS representation of the stream of an SSE route. All of
+// its fields are used only on the VU event loop.
+type eventStream struct {
+	rt       *goja.Runtime
+	w        http.ResponseWriter
+	rc       *http.ResponseController
+	handlers streamHandlers
+	observer StreamObserver
+	tq       *taskqueue.TaskQueue
+	value    goja.Value
+	stop     func()
+
+	started, closed, finished bool
+}
+
+// start sends the headers of the response, if they weren't already sent.
+func (s *eventStream) start() error {
+	if s.started || s.closed {
+		return nil
+	}
+	s.started = true
+	h := s.w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Connection", "keep-alive")
+	s.w.WriteHeader(http.StatusOK)
+	s.observer.Opened()
+	return s.rc.Flush()
+}
+
+// Send sends an event to the client. It's either the data of the event, which
+// is encoded as JSON if it isn't a string, or an object with the data and the
+// optional event, id and retry fields.
+func (s *eventStream) Send(v goja.Value) error {
+	if s.closed {
+		return errors.New("the event stream is closed")
+	}
+	event, err := formatEvent(v)
+	if err != nil {
+		return err
+	}
+	if err = s.start(); err != nil {
+		return err
+	}
+	if _, err = s.w.Write(event); err != nil {
+		return fmt.Errorf("error sending an event: %w", err)
+	}
+	if err = s.rc.Flush(); err != nil {
+		return fmt.Errorf("error sending an event: %w", err)
+	}
+	s.observer.MessageSent()
+	return nil
+}
+
+// Close ends the stream, after which the close handler is called.
+func (s *eventStream) Close() {
+	if s.closed {
+		return
+	}
+	s.closed = true
+	s.tq.Queue(s.finish)
+}
+
+// finish runs the close handler, if the stream was started, and releases the
+// event loop. It's called once, on the event loop.
+func (s *eventStream) finish() error {
+	if s.finished {
+		return nil
+	}
+	s.finished, s.closed = true, true
+	s.stop()
+	defer s.tq.Close()
+	defer s.observer.Closed()
+	if !s.started {
+		return nil
+	}
+	return s.handlers.call("close", s.value)
+}
+
+// formatEvent encodes an event in the text/event-stream format.
+func formatEvent(v goja.Value) ([]byte, error) {
+	var b strings.Builder
+	data := v
+	if obj, ok := v.(*goja.Object); ok && !isArrayLike(v) {
+		for _, field := range []string{"id", "event"} {
+			if f := obj.Get(field); !common.IsNullish(f) {
+				b.WriteString(field + ": " + singleLine(f.String()) + "\n")
+			}
+		}
+		if retry := obj.Get("retry"); !common.IsNullish(retry) {
+			b.WriteString("retry: " + strconv.FormatInt(retry.ToInteger(), 10) + "\n")
+		}
+		data = obj.Get("data")
+	}
+
+	var payload string
+	switch {
+	case common.IsNullish(data):
+	case isString(data):
+		payload = data.String()
+	default:
+		encoded, err := json.Marshal(data.Export())
+		if err != nil {
+			return nil, fmt.Errorf("error encoding the event data as JSON: %w", err)
+		}
+		payload = string(encoded)
+	}
+	for _, line := range strings.Split(payload, "\n") {
+		b.WriteString("data: " + line + "\n")
+	}
+	b.WriteString("\n")
+	return []byte(b.String()), nil
+}
+
+func isString(v goja.Value) bool {
+	_, ok := v.Export().(string)
+	return ok
+}
+
+func isArrayLike(v goja.Value) bool {
+	switch v.Export().(type) {
+	case []interface{}, goja.ArrayBuffer:
+		return true
+	default:
+		return false
+	}
+}
+
+// singleLine removes the line breaks, which would end a field of an event.
+func singleLine(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
@@ -77,7 +77,7 @@ func (r *LegacyRequireImpl) Require(specifier string) (*goja.Object, error) {
 //	@param specifier
 //	@return bool
 func (r *LegacyRequireImpl) systemPackage(key string, specifier string) bool {
-	return specifier != key && !strings.HasPrefix(specifier, key+"/") && strings.Contains(specifier, "/"+key+"/")
+	return specifier == key || strings.HasPrefix(specifier, key+"/") || strings.Contains(specifier, "/"+key+"/")
 }
 
 // CurrentlyRequiredModule returns the module that is currently being required.
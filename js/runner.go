@@ -641,12 +641,19 @@ type VU struct {
 	state *lib.State
 	// count of iterations executed by this VU in each scenario
 	scenarioIter map[string]uint64
+
+	// info is the part of the state of the VU which is inspected from other
+	// goroutines, see GetVUInfo()
+	info   lib.VUInfo
+	infoMx sync.Mutex
 }
 
 // Verify that interfaces are implemented
 var (
-	_ lib.ActiveVU      = &ActiveVU{}
-	_ lib.InitializedVU = &VU{}
+	_ lib.ActiveVU                     = &ActiveVU{}
+	_ lib.ActiveVUWithIterationOptions = &ActiveVU{}
+	_ lib.InitializedVU                = &VU{}
+	_ lib.InspectableVU                = &VU{}
 )
 
 // ActiveVU holds a VU and its activation parameters
@@ -658,8 +665,15 @@ type ActiveVU struct {
 	scenarioName              string
 	getNextIterationCounters  func() (uint64, uint64)
 	scIterLocal, scIterGlobal uint64
+
+	// execTagged is whether the last iteration was tagged with its function.
+	execTagged bool
 }
 
+// execTagName is the tag of the metrics of the iterations running another
+// function than the one of their scenario, e.g. picked by its execMix.
+const execTagName = "exec"
+
 // GetID returns the unique VU ID.
 func (u *VU) GetID() uint64 {
 	return u.ID
@@ -669,6 +683,25 @@ func (u *VU) GetRuntime() *goja.Runtime {
 	return u.Runtime
 }
 
+// GetVUInfo returns a snapshot of the current state of the VU.
+func (u *VU) GetVUInfo() lib.VUInfo {
+	u.infoMx.Lock()
+	info := u.info
+	u.infoMx.Unlock()
+
+	info.IDLocal, info.IDGlobal = u.ID, u.IDGlobal
+	tagsAndMeta := u.state.Tags.GetCurrentValues()
+	info.Tags = tagsAndMeta.Tags.Map()
+	info.Metadata = tagsAndMeta.Metadata
+	return info
+}
+
+func (u *VU) updateInfo(fn func(info *lib.VUInfo)) {
+	u.infoMx.Lock()
+	defer u.infoMx.Unlock()
+	fn(&u.info)
+}
+
 // Activate the VU so it will be able to run code.
 func (u *VU) Activate(params *lib.VUActivationParams) lib.ActiveVU {
 	u.Runtime.ClearInterrupt()
@@ -709,6 +742,10 @@ func (u *VU) Activate(params *lib.VUActivationParams) lib.ActiveVU {
 
 	ctx := params.RunContext
 	u.moduleVUImpl.ctx = ctx
+	u.updateInfo(func(info *lib.VUInfo) {
+		info.Active = true
+		info.Scenario = params.Scenario
+	})
 
 	u.state.GetScenarioVUIter = func() uint64 {
 		return u.scenarioIter[params.Scenario]
@@ -739,6 +776,9 @@ func (u *VU) Activate(params *lib.VUActivationParams) lib.ActiveVU {
 		// Wait for the VU to stop running, if it was, and prevent it from
 		// running again for this activation
 		avu.busy <- struct{}{}
+		u.updateInfo(func(info *lib.VUInfo) {
+			info.Active = false
+		})
 
 		if params.DeactivateCallback != nil {
 			params.DeactivateCallback(u)
@@ -750,6 +790,12 @@ func (u *VU) Activate(params *lib.VUActivationParams) lib.ActiveVU {
 
 // RunOnce runs the configured Exec function once.
 func (u *ActiveVU) RunOnce() error {
+	return u.RunOnceWith(lib.IterationOptions{})
+}
+
+// RunOnceWith runs the exported function of the options, or the configured
+// one, once, with their payload as its second argument.
+func (u *ActiveVU) RunOnceWith(opts lib.IterationOptions) error {
 	select {
 	case <-u.RunContext.Done():
 		return u.RunContext.Err() // we are done, return
@@ -774,13 +820,45 @@ func (u *ActiveVU) RunOnce() error {
 		}
 	}
 
-	fn := u.getCallableExport(u.Exec)
+	exec := u.Exec
+	if opts.Exec != "" {
+		exec = opts.Exec
+	}
+	fn := u.getCallableExport(exec)
 	if fn == nil {
 		// Shouldn't happen; this is validated in cmd.validateScenarioConfig()
-		panic(fmt.Sprintf("function '%s' not found in exports", u.Exec))
+		panic(fmt.Sprintf("function '%s' not found in exports", exec))
+	}
+
+	if opts.Exec != "" || u.execTagged {
+		u.state.Tags.Modify(func(tagsAndMeta *metrics.TagsAndMeta) {
+			if opts.Exec != "" {
+				tagsAndMeta.SetTag(execTagName, opts.Exec)
+			} else {
+				tagsAndMeta.DeleteTag(execTagName)
+			}
+		})
+		u.execTagged = opts.Exec != ""
+	}
+
+	args := []goja.Value{u.setupData}
+	if len(opts.Payload) != 0 {
+		var payload interface{}
+		if err := json.Unmarshal(opts.Payload, &payload); err != nil {
+			return fmt.Errorf("error unmarshaling the payload of the iteration from JSON: %w", err)
+		}
+		args = append(args, u.Runtime.ToValue(payload))
 	}
 
 	u.incrIteration()
+	u.updateInfo(func(info *lib.VUInfo) {
+		info.Iteration = u.iteration
+		info.ScenarioIteration = int64(u.scenarioIter[u.scenarioName])
+		info.IterationStart = time.Now()
+	})
+	defer u.updateInfo(func(info *lib.VUInfo) {
+		info.IterationStart = time.Time{}
+	})
 	if err := u.Runtime.Set("__ITER", u.iteration); err != nil {
 		panic(fmt.Errorf("error setting __ITER in goja runtime: %w", err))
 	}
@@ -798,7 +876,7 @@ func (u *ActiveVU) RunOnce() error {
 	u.emitAndWaitEvent(&event.Event{Type: event.IterStart, Data: eventIterData})
 
 	// Call the exported function.
-	_, isFullIteration, totalTime, err := u.runFn(ctx, true, fn, cancel, u.setupData)
+	_, isFullIteration, totalTime, err := u.runFn(ctx, true, fn, cancel, args...)
 	if err != nil {
 		var x *goja.InterruptedError
 		if errors.As(err, &x) {
@@ -827,6 +905,46 @@ func (u *ActiveVU) RunOnce() error {
 	return err
 }
 
+// RunFunc runs fn on the VU event loop and waits for all of the asynchronous
+// work started by it to finish. Unlike RunOnce(), it doesn't start a new
+// iteration and doesn't emit any iteration metrics; it's used by the server
+// mode to run the route handlers registered by the script. The asynchronous
+// work, e.g. the timers, is also stopped when ctx is done.
+func (u *ActiveVU) RunFunc(ctx context.Context, fn func(rt *goja.Runtime) error) error {
+	select {
+	case <-u.RunContext.Done():
+		return u.RunContext.Err() // we are done, return
+	case u.busy <- struct{}{}:
+		// nothing else can run now, and the VU cannot be deactivated
+	}
+	defer func() {
+		<-u.busy // unlock deactivation again
+	}()
+
+	runCtx, cancel := context.WithCancel(u.RunContext)
+	defer cancel()
+	stop := context.AfterFunc(ctx, cancel)
+	defer stop()
+	u.moduleVUImpl.ctx = runCtx
+
+	if u.moduleVUImpl.eventLoop == nil {
+		u.moduleVUImpl.eventLoop = eventloop.New(u.moduleVUImpl)
+	}
+	err := common.RunWithPanicCatching(u.state.Logger, u.Runtime, func() error {
+		return u.moduleVUImpl.eventLoop.Start(func() error {
+			return fn(u.Runtime)
+		})
+	})
+	cancel()
+	u.moduleVUImpl.eventLoop.WaitOnRegistered()
+
+	var exception *goja.Exception
+	if errors.As(err, &exception) {
+		err = &ScriptExceptionError{inner: exception}
+	}
+	return err
+}
+
 func (u *ActiveVU) emitAndWaitEvent(evt *event.Event) {
 	waitDone := u.moduleVUImpl.events.local.Emit(evt)
 	waitCtx, waitCancel := context.WithTimeout(u.RunContext, 30*time.Minute)
@@ -868,6 +986,11 @@ func (u *VU) runFn(
 	err = common.RunWithPanicCatching(u.state.Logger, u.Runtime, func() error {
 		return u.moduleVUImpl.eventLoop.Start(func() (err error) {
 			v, err = fn(goja.Undefined(), args...) // Actually run the JS script
+			if isDefault {
+				u.updateInfo(func(info *lib.VUInfo) {
+					info.InEventLoop = true
+				})
+			}
 			return err
 		})
 	})
@@ -883,6 +1006,11 @@ func (u *VU) runFn(
 		cancel()
 		u.moduleVUImpl.eventLoop.WaitOnRegistered()
 	}
+	if isDefault {
+		u.updateInfo(func(info *lib.VUInfo) {
+			info.InEventLoop = false
+		})
+	}
 	endTime := time.Now()
 	var exception *goja.Exception
 	if errors.As(err, &exception) {
@@ -8,6 +8,7 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
+	"encoding/json"
 	"encoding/pem"
 	"fmt"
 	"go/build"
@@ -2887,3 +2888,111 @@ func TestArchivingAnArchiveWorks(t *testing.T) {
 	require.NoError(t, err)
 	require.NotNil(t, r3)
 }
+
+func TestVUInfo(t *testing.T) {
+	t.Parallel()
+	r, err := getSimpleRunner(t, "/script.js", `
+		var exec = require("k6/execution");
+		exports.default = function() {
+			exec.vu.tags.mytag = "value";
+			exec.vu.metrics.metadata.trace_id = "abc";
+			setTimeout(function() {}, 500);
+		}
+	`)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	initVU, err := r.NewVU(ctx, 3, 13, make(chan metrics.SampleContainer, 100))
+	require.NoError(t, err)
+	vu, ok := initVU.(lib.InspectableVU)
+	require.True(t, ok)
+
+	info := vu.GetVUInfo()
+	assert.Equal(t, uint64(3), info.IDLocal)
+	assert.Equal(t, uint64(13), info.IDGlobal)
+	assert.False(t, info.Active)
+	assert.True(t, info.IterationStart.IsZero())
+
+	activeVU := vu.Activate(&lib.VUActivationParams{RunContext: ctx, Scenario: "sc"})
+	errCh := make(chan error)
+	go func() { errCh <- activeVU.RunOnce() }()
+
+	require.Eventually(t, func() bool { return vu.GetVUInfo().InEventLoop }, 2*time.Second, 10*time.Millisecond)
+	info = vu.GetVUInfo()
+	assert.True(t, info.Active)
+	assert.Equal(t, "sc", info.Scenario)
+	assert.Equal(t, int64(0), info.Iteration)
+	assert.False(t, info.IterationStart.IsZero())
+	assert.Equal(t, "value", info.Tags["mytag"])
+	assert.Equal(t, "abc", info.Metadata["trace_id"])
+
+	require.NoError(t, <-errCh)
+	info = vu.GetVUInfo()
+	assert.False(t, info.InEventLoop)
+	assert.True(t, info.IterationStart.IsZero())
+
+	cancel()
+	require.Eventually(t, func() bool { return !vu.GetVUInfo().Active }, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestVURunOnceWith(t *testing.T) {
+	t.Parallel()
+	r, err := getSimpleRunner(t, "/script.js", `
+		exports.default = function(data, payload) {
+			if (payload !== undefined) { throw new Error("unexpected payload " + payload); }
+		}
+		exports.login = function(data, payload) {
+			if (data.host !== "test.k6.io") { throw new Error("wrong setup data " + JSON.stringify(data)); }
+			if (payload.user !== "alice") { throw new Error("wrong payload " + JSON.stringify(payload)); }
+		}
+	`)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r.SetSetupData([]byte(`{"host": "test.k6.io"}`))
+	initVU, err := r.NewVU(ctx, 1, 1, make(chan metrics.SampleContainer, 100))
+	require.NoError(t, err)
+	activeVU, ok := initVU.Activate(&lib.VUActivationParams{RunContext: ctx}).(lib.ActiveVUWithIterationOptions)
+	require.True(t, ok)
+
+	require.NoError(t, activeVU.RunOnce())
+	require.NoError(t, activeVU.RunOnceWith(lib.IterationOptions{
+		Exec: "login", Payload: json.RawMessage(`{"user": "alice"}`),
+	}))
+	assert.Error(t, activeVU.RunOnceWith(lib.IterationOptions{Payload: json.RawMessage(`"unexpected"`)}))
+	assert.Error(t, activeVU.RunOnceWith(lib.IterationOptions{Exec: "login", Payload: json.RawMessage(`{`)}))
+}
+
+func TestVURunOnceWithExecTag(t *testing.T) {
+	t.Parallel()
+	r, err := getSimpleRunner(t, "/script.js", `
+		exports.default = function() {}
+		exports.browse = function() {}
+	`)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	samples := make(chan metrics.SampleContainer, 100)
+	initVU, err := r.NewVU(ctx, 1, 1, samples)
+	require.NoError(t, err)
+	activeVU, ok := initVU.Activate(&lib.VUActivationParams{RunContext: ctx}).(lib.ActiveVUWithIterationOptions)
+	require.True(t, ok)
+
+	require.NoError(t, activeVU.RunOnce())
+	require.NoError(t, activeVU.RunOnceWith(lib.IterationOptions{Exec: "browse"}))
+	require.NoError(t, activeVU.RunOnce())
+
+	var execs []string
+	for _, sc := range metrics.GetBufferedSamples(samples) {
+		for _, s := range sc.GetSamples() {
+			if s.Metric.Name == metrics.IterationsName {
+				exec, _ := s.Tags.Get("exec")
+				execs = append(execs, exec)
+			}
+		}
+	}
+	assert.Equal(t, []string{"", "browse", ""}, execs)
+}
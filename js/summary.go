@@ -60,6 +60,26 @@ func metricValueGetter(summaryTrendStats []string) func(metrics.Sink, time.Durat
 // summarizeMetricsToObject transforms the summary objects in a way that's
 // suitable to pass to the JS runtime or export to JSON.
 func summarizeMetricsToObject(data *lib.Summary, options lib.Options, setupData []byte) map[string]interface{} {
+	m := SummarizeMetrics(data, options)
+
+	var setupDataI interface{}
+	if setupData != nil {
+		if err := json.Unmarshal(setupData, &setupDataI); err != nil {
+			// TODO: log the error
+			return m
+		}
+	} else {
+		setupDataI = goja.Undefined()
+	}
+
+	m["setup_data"] = setupDataI
+
+	return m
+}
+
+// SummarizeMetrics returns the data of the end-of-test summary that's passed to
+// handleSummary(), without the setup data, so it can be exported to JSON.
+func SummarizeMetrics(data *lib.Summary, options lib.Options) map[string]interface{} {
 	m := make(map[string]interface{})
 	m["root_group"] = exportGroup(data.RootGroup)
 	m["options"] = map[string]interface{}{
@@ -87,9 +107,13 @@ func summarizeMetricsToObject(data *lib.Summary, options lib.Options, setupData
 		if len(m.Thresholds.Thresholds) > 0 {
 			thresholds := make(map[string]interface{})
 			for _, threshold := range m.Thresholds.Thresholds {
-				thresholds[threshold.Source] = map[string]interface{}{
+				thresholdData := map[string]interface{}{
 					"ok": !threshold.LastFailed,
 				}
+				if threshold.Muted {
+					thresholdData["muted"] = true
+				}
+				thresholds[threshold.Source] = thresholdData
 			}
 			metricData["thresholds"] = thresholds
 		}
@@ -97,17 +121,31 @@ func summarizeMetricsToObject(data *lib.Summary, options lib.Options, setupData
 	}
 	m["metrics"] = metricsData
 
-	var setupDataI interface{}
-	if setupData != nil {
-		if err := json.Unmarshal(setupData, &setupDataI); err != nil {
-			// TODO: log the error
-			return m
+	if len(data.ThresholdChanges) > 0 {
+		changes := make([]map[string]interface{}, len(data.ThresholdChanges))
+		for i, change := range data.ThresholdChanges {
+			changes[i] = map[string]interface{}{
+				"time":      change.Time.Format(time.RFC3339),
+				"action":    change.Action,
+				"metric":    change.Metric,
+				"threshold": change.Threshold,
+			}
 		}
-	} else {
-		setupDataI = goja.Undefined()
+		m["threshold_changes"] = changes
 	}
 
-	m["setup_data"] = setupDataI
+	if len(data.Capacities) > 0 {
+		capacities := make([]map[string]interface{}, len(data.Capacities))
+		for i, capacity := range data.Capacities {
+			capacities[i] = map[string]interface{}{
+				"scenario": capacity.Scenario,
+				"value":    capacity.Value,
+				"unit":     capacity.Unit,
+				"at_least": capacity.AtLeast,
+			}
+		}
+		m["capacities"] = capacities
+	}
 
 	return m
 }
@@ -358,7 +358,7 @@ function summarizeMetrics(options, data, decorate) {
         return decorate(text, palette.green)
       }
       forEach(metric.thresholds, function (name, threshold) {
-        if (!threshold.ok) {
+        if (!threshold.ok && !threshold.muted) {
           mark = failMark
           markColor = function (text) {
             return decorate(text, palette.red)
@@ -382,6 +382,52 @@ function summarizeMetrics(options, data, decorate) {
   return result
 }
 
+function summarizeThresholdChanges(indent, data, decorate) {
+  var result = []
+  if (!data.threshold_changes || data.threshold_changes.length == 0) {
+    return result
+  }
+
+  result.push('')
+  result.push(indent + 'threshold changes:')
+  for (var change of data.threshold_changes) {
+    result.push(
+      indent +
+        '  ' +
+        decorate(change.time, palette.faint) +
+        ' ' +
+        change.action +
+        ' ' +
+        decorate(change.threshold, palette.cyan) +
+        ' on ' +
+        change.metric
+    )
+  }
+  return result
+}
+
+function summarizeCapacities(indent, data, decorate) {
+  var result = []
+  if (!data.capacities || data.capacities.length == 0) {
+    return result
+  }
+
+  result.push('')
+  result.push(indent + 'capacity:')
+  for (var capacity of data.capacities) {
+    result.push(
+      indent +
+        '  ' +
+        capacity.scenario +
+        ': ' +
+        decorate((capacity.at_least ? '≥ ' : '') + capacity.value, palette.cyan) +
+        ' ' +
+        capacity.unit
+    )
+  }
+  return result
+}
+
 function generateTextSummary(data, options) {
   var mergedOpts = Object.assign({}, defaultOptions, data.options, options)
   var lines = []
@@ -407,6 +453,16 @@ function generateTextSummary(data, options) {
 
   Array.prototype.push.apply(lines, summarizeMetrics(mergedOpts, data, decorate))
 
+  Array.prototype.push.apply(
+    lines,
+    summarizeThresholdChanges(mergedOpts.indent + '  ', data, decorate)
+  )
+
+  Array.prototype.push.apply(
+    lines,
+    summarizeCapacities(mergedOpts.indent + '  ', data, decorate)
+  )
+
   return lines.join('\n')
 }
 
@@ -133,6 +133,93 @@ func TestTextSummaryWithSubMetrics(t *testing.T) {
 	assert.Equal(t, "\n"+expected+"\n", string(summaryOut))
 }
 
+func TestTextSummaryWithThresholdChanges(t *testing.T) {
+	t.Parallel()
+
+	registry := metrics.NewRegistry()
+	counter, err := registry.NewMetric("my_counter", metrics.Counter)
+	require.NoError(t, err)
+	counter.Sink.Add(metrics.Sample{Value: 11})
+	counter.Thresholds = metrics.NewThresholds([]string{"count<10"})
+	counter.Thresholds.Thresholds[0].LastFailed = true
+	counter.Thresholds.Thresholds[0].Muted = true
+
+	changedAt := time.Date(2022, time.March, 1, 12, 30, 0, 0, time.UTC)
+	summary := &lib.Summary{
+		Metrics:         map[string]*metrics.Metric{counter.Name: counter},
+		RootGroup:       &lib.Group{},
+		TestRunDuration: time.Second,
+		ThresholdChanges: []metrics.ThresholdChange{
+			{Time: changedAt, Action: "add", Metric: "my_counter", Threshold: "count<10"},
+			{Time: changedAt, Action: "mute", Metric: "my_counter", Threshold: "count<10"},
+		},
+	}
+
+	runner, err := getSimpleRunner(
+		t,
+		"/script.js",
+		"exports.default = function() {/* we don't run this, metrics are mocked */};",
+		lib.RuntimeOptions{CompatibilityMode: null.NewString("base", true)},
+	)
+	require.NoError(t, err)
+
+	result, err := runner.HandleSummary(context.Background(), summary)
+	require.NoError(t, err)
+
+	require.Len(t, result, 1)
+	stdout := result["stdout"]
+	require.NotNil(t, stdout)
+
+	summaryOut, err := io.ReadAll(stdout)
+	require.NoError(t, err)
+
+	// the muted thresholds don't fail their metrics
+	expected := "   ✓ my_counter...: 11 11/s\n" +
+		"\n" +
+		"   threshold changes:\n" +
+		"     2022-03-01T12:30:00Z add count<10 on my_counter\n" +
+		"     2022-03-01T12:30:00Z mute count<10 on my_counter\n"
+	assert.Equal(t, "\n"+expected+"\n", string(summaryOut))
+}
+
+func TestTextSummaryWithCapacities(t *testing.T) {
+	t.Parallel()
+
+	summary := &lib.Summary{
+		Metrics:         map[string]*metrics.Metric{},
+		RootGroup:       &lib.Group{},
+		TestRunDuration: time.Second,
+		Capacities: []lib.Capacity{
+			{Scenario: "search", Value: 120, Unit: "iterations/s"},
+			{Scenario: "browse", Value: 50, Unit: "VUs", AtLeast: true},
+		},
+	}
+
+	runner, err := getSimpleRunner(
+		t,
+		"/script.js",
+		"exports.default = function() {/* we don't run this, metrics are mocked */};",
+		lib.RuntimeOptions{CompatibilityMode: null.NewString("base", true)},
+	)
+	require.NoError(t, err)
+
+	result, err := runner.HandleSummary(context.Background(), summary)
+	require.NoError(t, err)
+
+	require.Len(t, result, 1)
+	stdout := result["stdout"]
+	require.NotNil(t, stdout)
+
+	summaryOut, err := io.ReadAll(stdout)
+	require.NoError(t, err)
+
+	expected := "\n" +
+		"   capacity:\n" +
+		"     search: 120 iterations/s\n" +
+		"     browse: ≥ 50 VUs\n"
+	assert.Equal(t, "\n"+expected+"\n", string(summaryOut))
+}
+
 func createTestMetrics(t *testing.T) (map[string]*metrics.Metric, *lib.Group) {
 	registry := metrics.NewRegistry()
 	testMetrics := make(map[string]*metrics.Metric)
@@ -176,6 +176,9 @@ func ReadArchive(in io.Reader) (*Archive, error) {
 			return nil, fmt.Errorf("unknown file prefix `%s` for file `%s`", pfx, normPath)
 		}
 	}
+	if arc.FilenameURL == nil {
+		return nil, errors.New("the archive doesn't have a metadata.json file")
+	}
 	scheme, pathOnFs := getURLPathOnFs(arc.FilenameURL)
 	var err error
 	pathOnFs, err = url.PathUnescape(pathOnFs)
@@ -311,6 +311,19 @@ func TestMalformedMetadata(t *testing.T) {
 	require.Equal(t, err.Error(), `invalid character ',' looking for beginning of object key string`)
 }
 
+func TestMissingMetadata(t *testing.T) {
+	t.Parallel()
+	fs := fsext.NewMemMapFs()
+	require.NoError(t, fsext.WriteFile(fs, "/data", []byte("export default function() {}"), 0o644))
+	b, err := dumpMemMapFsToBuf(fs)
+	require.NoError(t, err)
+	_, err = ReadArchive(b)
+	require.EqualError(t, err, "the archive doesn't have a metadata.json file")
+
+	_, err = ReadArchive(bytes.NewReader(nil))
+	require.EqualError(t, err, "the archive doesn't have a metadata.json file")
+}
+
 func TestStrangePaths(t *testing.T) {
 	t.Parallel()
 	pathsToChange := []string{
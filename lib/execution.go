@@ -105,6 +105,12 @@ type ExecutionState struct {
 	// MaxTimeToWaitForPlannedVU.
 	vus chan InitializedVU
 
+	// allVUs are all the VUs which were initialized, in the order of their
+	// initialization, both the ones in the vus buffer and the ones which are
+	// currently borrowed by the executors, so they can be inspected.
+	allVUs   []InitializedVU
+	allVUsMx sync.RWMutex
+
 	// The segmented index used to generate unique local (current k6 instance)
 	// and global (across k6 instances) VU IDs, starting from 1
 	// (for backwards compatibility...).
@@ -529,6 +535,7 @@ func (es *ExecutionState) InitializeNewVU(ctx context.Context, logger *logrus.En
 		return nil, err
 	}
 	es.ModInitializedVUsCount(+1)
+	es.addToAllVUs(newVU)
 	return newVU, err
 }
 
@@ -537,6 +544,21 @@ func (es *ExecutionState) InitializeNewVU(ctx context.Context, logger *logrus.En
 func (es *ExecutionState) AddInitializedVU(vu InitializedVU) {
 	es.vus <- vu
 	es.ModInitializedVUsCount(+1)
+	es.addToAllVUs(vu)
+}
+
+func (es *ExecutionState) addToAllVUs(vu InitializedVU) {
+	es.allVUsMx.Lock()
+	defer es.allVUsMx.Unlock()
+	es.allVUs = append(es.allVUs, vu)
+}
+
+// GetInitializedVUs returns all the VUs which were initialized, regardless of
+// whether they are currently active or not.
+func (es *ExecutionState) GetInitializedVUs() []InitializedVU {
+	es.allVUsMx.RLock()
+	defer es.allVUsMx.RUnlock()
+	return append([]InitializedVU(nil), es.allVUs...)
 }
 
 // ReturnVU is a helper function that puts VUs back into the buffer and
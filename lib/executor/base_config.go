@@ -27,12 +27,20 @@ type BaseConfig struct {
 	Name         string               `json:"-"` // set via the JS object key
 	Type         string               `json:"executor"`
 	StartTime    types.NullDuration   `json:"startTime"`
+	StartAfter   []string             `json:"startAfter"` // scenario names, externally validated
+	StartWhen    null.String          `json:"startWhen"`
 	GracefulStop types.NullDuration   `json:"gracefulStop"`
 	Env          map[string]string    `json:"env"`
 	Exec         null.String          `json:"exec"` // function name, externally validated
 	Tags         map[string]string    `json:"tags"`
 	Options      *lib.ScenarioOptions `json:"options,omitempty"`
 
+	// ExecMix replaces Exec with the weights of exported functions, one of
+	// which is picked for each iteration, e.g. {"browse": 70, "search": 30}.
+	// The picks are seeded from the randomSeed option, so they're the same in
+	// each run with the same seed.
+	ExecMix map[string]float64 `json:"execMix"`
+
 	// TODO: future extensions like distribution, others?
 }
 
@@ -58,6 +66,7 @@ func (bc BaseConfig) Validate() (errors []error) {
 	if bc.Exec.Valid && bc.Exec.String == "" {
 		errors = append(errors, fmt.Errorf("exec value cannot be empty"))
 	}
+	errors = append(errors, bc.validateExecMix()...)
 	if bc.Type == "" {
 		errors = append(errors, fmt.Errorf("missing or empty type field"))
 	}
@@ -65,6 +74,14 @@ func (bc BaseConfig) Validate() (errors []error) {
 	if bc.StartTime.Duration < 0 {
 		errors = append(errors, fmt.Errorf("the startTime can't be negative"))
 	}
+	if bc.StartWhen.Valid {
+		switch {
+		case len(bc.StartAfter) == 0:
+			errors = append(errors, fmt.Errorf("the startWhen option requires startAfter"))
+		case bc.StartWhen.String != lib.StartWhenAll && bc.StartWhen.String != lib.StartWhenAny:
+			errors = append(errors, fmt.Errorf("the startWhen should be '%s' or '%s'", lib.StartWhenAll, lib.StartWhenAny))
+		}
+	}
 	if bc.GracefulStop.Duration < 0 {
 		errors = append(errors, fmt.Errorf("the gracefulStop timeout can't be negative"))
 	}
@@ -87,6 +104,21 @@ func (bc BaseConfig) GetStartTime() time.Duration {
 	return bc.StartTime.TimeDuration()
 }
 
+// GetStartAfter returns the names of the scenarios which have to finish before
+// the start time of this executor is counted.
+func (bc BaseConfig) GetStartAfter() []string {
+	return bc.StartAfter
+}
+
+// GetStartWhen returns whether all of the scenarios of GetStartAfter() have to
+// finish before the start time of this executor is counted, or any of them.
+func (bc BaseConfig) GetStartWhen() string {
+	if bc.StartWhen.Valid {
+		return bc.StartWhen.String
+	}
+	return lib.StartWhenAll
+}
+
 // GetGracefulStop returns how long k6 is supposed to wait for any still
 // running iterations to finish executing at the end of the normal executor
 // duration, before it actually kills them.
@@ -103,8 +135,12 @@ func (bc BaseConfig) GetEnv() map[string]string {
 	return bc.Env
 }
 
-// GetExec returns the configured custom exec value, if any.
+// GetExec returns the configured custom exec value, if any. With an execMix,
+// it's the first one of its functions, the others are returned by GetExecs().
 func (bc BaseConfig) GetExec() string {
+	if execs := bc.getExecMixNames(); len(execs) > 0 {
+		return execs[0]
+	}
 	exec := bc.Exec.ValueOrZero()
 	if exec == "" {
 		exec = consts.DefaultFn
@@ -112,6 +148,14 @@ func (bc BaseConfig) GetExec() string {
 	return exec
 }
 
+// GetExecs returns the other functions of the execMix, if any.
+func (bc BaseConfig) GetExecs() []string {
+	if execs := bc.getExecMixNames(); len(execs) > 1 {
+		return execs[1:]
+	}
+	return nil
+}
+
 // GetScenarioOptions returns the options specific to a scenario.
 func (bc BaseConfig) GetScenarioOptions() *lib.ScenarioOptions {
 	return bc.Options
@@ -128,11 +172,30 @@ func (bc BaseConfig) IsDistributable() bool {
 	return true
 }
 
+// getBaseConfig returns the base config of the executor configs embedding it.
+func (bc BaseConfig) getBaseConfig() BaseConfig {
+	return bc
+}
+
 // getBaseInfo is a helper method for the "parent" String methods.
 func (bc BaseConfig) getBaseInfo(facts ...string) string {
 	if bc.Exec.Valid {
 		facts = append(facts, fmt.Sprintf("exec: %s", bc.Exec.String))
 	}
+	if execs := bc.getExecMixNames(); len(execs) > 0 {
+		mix := make([]string, len(execs))
+		for i, exec := range execs {
+			mix[i] = fmt.Sprintf("%s %g", exec, bc.ExecMix[exec])
+		}
+		facts = append(facts, fmt.Sprintf("execMix: %s", strings.Join(mix, " / ")))
+	}
+	if len(bc.StartAfter) > 0 {
+		separator := " and "
+		if bc.GetStartWhen() == lib.StartWhenAny {
+			separator = " or "
+		}
+		facts = append(facts, fmt.Sprintf("startAfter: %s", strings.Join(bc.StartAfter, separator)))
+	}
 	if bc.StartTime.Duration > 0 {
 		facts = append(facts, fmt.Sprintf("startTime: %s", bc.StartTime.Duration))
 	}
@@ -24,6 +24,8 @@ type BaseExecutor struct {
 	iterSegIndex   *lib.SegmentedIndex
 	logger         *logrus.Entry
 	progress       *pb.ProgressBar
+	control        *scenarioControl
+	execMix        *execMix
 }
 
 // NewBaseExecutor returns an initialized BaseExecutor
@@ -35,6 +37,8 @@ func NewBaseExecutor(config lib.ExecutorConfig, es *lib.ExecutionState, logger *
 		logger:         logger,
 		iterSegIndexMx: new(sync.Mutex),
 		iterSegIndex:   segIdx,
+		control:        newScenarioControl(),
+		execMix:        newExecMix(config, es.Test.Options),
 		progress: pb.New(
 			pb.WithLeft(config.GetName),
 			pb.WithLogger(logger),
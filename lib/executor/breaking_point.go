@@ -0,0 +1,550 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/guregu/null.v3"
+
+	"github.com/liuxd6825/k6server/lib"
+	"github.com/liuxd6825/k6server/lib/types"
+	"github.com/liuxd6825/k6server/metrics"
+	"github.com/liuxd6825/k6server/ui/pb"
+)
+
+const breakingPointType = "breaking-point"
+
+// The modes of the breaking-point executor, i.e. what its levels of load are.
+const (
+	// BreakingPointModeArrivalRate makes the levels rates of iterations.
+	BreakingPointModeArrivalRate = "arrival-rate"
+	// BreakingPointModeVUs makes the levels numbers of looping VUs.
+	BreakingPointModeVUs = "vus"
+)
+
+// BreakingPointCapacityMetricName is the name of the gauge of the capacities
+// found by the breaking-point executors.
+const BreakingPointCapacityMetricName = "breaking_point_capacity"
+
+func init() {
+	lib.RegisterExecutorConfigType(
+		breakingPointType,
+		func(name string, rawJSON []byte) (lib.ExecutorConfig, error) {
+			config := NewBreakingPointConfig(name)
+			err := lib.StrictJSONUnmarshal(rawJSON, &config)
+			return config, err
+		},
+	)
+}
+
+// BreakingPointConfig stores config for the breaking-point executor, which
+// searches for the highest level of load passing the thresholds.
+type BreakingPointConfig struct {
+	BaseConfig
+	Mode     null.String        `json:"mode"`
+	TimeUnit types.NullDuration `json:"timeUnit"`
+
+	// The search starts at the Start level, which defaults to Step, and steps
+	// it up by Step until the thresholds fail or it reaches Max. Then it
+	// binary-searches the highest level passing them, until it's at most
+	// Precision below the lowest level failing them.
+	Start     null.Int `json:"start"`
+	Step      null.Int `json:"step"`
+	Max       null.Int `json:"max"`
+	Precision null.Int `json:"precision"`
+
+	// Each level is evaluated for StepDuration, and each step of the binary
+	// search is preceded by a Cooldown at the highest level which passed.
+	StepDuration types.NullDuration `json:"stepDuration"`
+	Cooldown     types.NullDuration `json:"cooldown"`
+
+	// Initialize `PreAllocatedVUs` number of VUs, and if more than that are needed,
+	// they will be dynamically allocated, until `MaxVUs` is reached, which is an
+	// absolutely hard limit on the number of VUs the executor will use. They're
+	// only used in the arrival-rate mode.
+	PreAllocatedVUs null.Int `json:"preAllocatedVUs"`
+	MaxVUs          null.Int `json:"maxVUs"`
+}
+
+// NewBreakingPointConfig returns a BreakingPointConfig with default values
+func NewBreakingPointConfig(name string) *BreakingPointConfig {
+	return &BreakingPointConfig{
+		BaseConfig: NewBaseConfig(name, breakingPointType),
+		Mode:       null.NewString(BreakingPointModeArrivalRate, false),
+		TimeUnit:   types.NewNullDuration(1*time.Second, false),
+		Precision:  null.NewInt(1, false),
+		Cooldown:   types.NewNullDuration(0, false),
+	}
+}
+
+// Make sure we implement the lib.ExecutorConfig interface
+var _ lib.ExecutorConfig = &BreakingPointConfig{}
+
+func (bpc BreakingPointConfig) isArrivalRate() bool {
+	return bpc.Mode.String == BreakingPointModeArrivalRate
+}
+
+// GetStart returns the first level of the search.
+func (bpc BreakingPointConfig) GetStart() int64 {
+	if bpc.Start.Valid {
+		return bpc.Start.Int64
+	}
+	return bpc.Step.Int64
+}
+
+// GetUnit returns the unit of the levels, e.g. VUs or iterations/s.
+func (bpc BreakingPointConfig) GetUnit() string {
+	if !bpc.isArrivalRate() {
+		return "VUs"
+	}
+	if timeUnit := bpc.TimeUnit.TimeDuration(); timeUnit != time.Second {
+		return "iterations/" + timeUnit.String()
+	}
+	return "iterations/s"
+}
+
+// GetPreAllocatedVUs is just a helper method that returns the scaled pre-allocated VUs.
+func (bpc BreakingPointConfig) GetPreAllocatedVUs(et *lib.ExecutionTuple) int64 {
+	return et.ScaleInt64(bpc.PreAllocatedVUs.Int64)
+}
+
+// GetMaxVUs is just a helper method that returns the scaled max VUs, which are
+// the maximum level in the VUs mode.
+func (bpc BreakingPointConfig) GetMaxVUs(et *lib.ExecutionTuple) int64 {
+	if !bpc.isArrivalRate() {
+		return et.ScaleInt64(bpc.Max.Int64)
+	}
+	return et.ScaleInt64(bpc.MaxVUs.Int64)
+}
+
+// getMaxSteps returns the maximum numbers of steps of the ramp up and of the
+// binary search.
+func (bpc BreakingPointConfig) getMaxSteps() (ramp, binary int64) {
+	start, step := bpc.GetStart(), bpc.Step.Int64
+	ramp = 1 + (bpc.Max.Int64-start+step-1)/step
+
+	// the binary search starts between the first failing level and the one
+	// before it, and halves the range at each of its steps
+	width := step
+	if start > step {
+		width = start
+	}
+	for ; width > bpc.Precision.Int64; width -= width / 2 {
+		binary++
+	}
+	return ramp, binary
+}
+
+// getMaxDuration returns the duration of the longest possible search.
+func (bpc BreakingPointConfig) getMaxDuration() time.Duration {
+	ramp, binary := bpc.getMaxSteps()
+	return time.Duration(ramp+binary)*bpc.StepDuration.TimeDuration() +
+		time.Duration(binary)*bpc.Cooldown.TimeDuration()
+}
+
+// GetDescription returns a human-readable description of the executor options
+func (bpc BreakingPointConfig) GetDescription(et *lib.ExecutionTuple) string {
+	var facts []string
+	if bpc.isArrivalRate() {
+		preAllocatedVUs, maxVUs := bpc.GetPreAllocatedVUs(et), bpc.GetMaxVUs(et)
+		maxVUsRange := fmt.Sprintf("maxVUs: %d", preAllocatedVUs)
+		if maxVUs > preAllocatedVUs {
+			maxVUsRange += fmt.Sprintf("-%d", maxVUs)
+		}
+		facts = append(facts, maxVUsRange)
+	}
+
+	return fmt.Sprintf("Breaking point between %d and %d %s, in steps of %d for %s, up to %s%s",
+		bpc.GetStart(), bpc.Max.Int64, bpc.GetUnit(), bpc.Step.Int64, bpc.StepDuration.Duration,
+		types.Duration(bpc.getMaxDuration()), bpc.getBaseInfo(facts...))
+}
+
+// Validate makes sure all options are configured and valid
+//
+//nolint:funlen,cyclop
+func (bpc *BreakingPointConfig) Validate() []error {
+	errors := bpc.BaseConfig.Validate()
+
+	switch bpc.Mode.String {
+	case BreakingPointModeArrivalRate:
+		if bpc.TimeUnit.TimeDuration() <= 0 {
+			errors = append(errors, fmt.Errorf("the timeUnit must be more than 0"))
+		}
+	case BreakingPointModeVUs:
+		if bpc.PreAllocatedVUs.Valid || bpc.MaxVUs.Valid {
+			errors = append(errors, fmt.Errorf("preAllocatedVUs and maxVUs can only be used in the %s mode",
+				BreakingPointModeArrivalRate))
+		}
+	default:
+		errors = append(errors, fmt.Errorf("the mode must be either %s or %s, but is '%s'",
+			BreakingPointModeArrivalRate, BreakingPointModeVUs, bpc.Mode.String))
+	}
+
+	if !bpc.Step.Valid {
+		errors = append(errors, fmt.Errorf("the step isn't specified"))
+	} else if bpc.Step.Int64 <= 0 {
+		errors = append(errors, fmt.Errorf("the step must be more than 0"))
+	}
+
+	if bpc.Start.Valid && bpc.Start.Int64 <= 0 {
+		errors = append(errors, fmt.Errorf("the start must be more than 0"))
+	}
+
+	if !bpc.Max.Valid {
+		errors = append(errors, fmt.Errorf("the max isn't specified"))
+	} else if bpc.Max.Int64 < bpc.GetStart() {
+		errors = append(errors, fmt.Errorf("the max can't be less than the start"))
+	}
+
+	if bpc.Precision.Int64 <= 0 {
+		errors = append(errors, fmt.Errorf("the precision must be more than 0"))
+	}
+
+	if !bpc.StepDuration.Valid {
+		errors = append(errors, fmt.Errorf("the stepDuration is unspecified"))
+	} else if bpc.StepDuration.TimeDuration() < minDuration {
+		errors = append(errors, fmt.Errorf(
+			"the stepDuration must be at least %s, but is %s", minDuration, bpc.StepDuration,
+		))
+	}
+
+	if bpc.Cooldown.TimeDuration() < 0 {
+		errors = append(errors, fmt.Errorf("the cooldown can't be negative"))
+	}
+
+	if !bpc.isArrivalRate() {
+		return errors
+	}
+
+	if !bpc.PreAllocatedVUs.Valid {
+		errors = append(errors, fmt.Errorf("the number of preAllocatedVUs isn't specified"))
+	} else if bpc.PreAllocatedVUs.Int64 < 0 {
+		errors = append(errors, fmt.Errorf("the number of preAllocatedVUs can't be negative"))
+	}
+
+	if !bpc.MaxVUs.Valid {
+		// TODO: don't change the config while validating
+		bpc.MaxVUs.Int64 = bpc.PreAllocatedVUs.Int64
+	} else if bpc.MaxVUs.Int64 < bpc.PreAllocatedVUs.Int64 {
+		errors = append(errors, fmt.Errorf("maxVUs can't be less than preAllocatedVUs"))
+	}
+
+	return errors
+}
+
+// GetExecutionRequirements returns the number of required VUs to run the
+// executor for its whole duration (disregarding any startTime), including the
+// maximum waiting time for any iterations to gracefully stop. This is used by
+// the execution scheduler in its VU reservation calculations, so it knows how
+// many VUs to pre-initialize.
+func (bpc BreakingPointConfig) GetExecutionRequirements(et *lib.ExecutionTuple) []lib.ExecutionStep {
+	first := lib.ExecutionStep{TimeOffset: 0, PlannedVUs: uint64(bpc.GetMaxVUs(et))}
+	if bpc.isArrivalRate() {
+		first.PlannedVUs = uint64(bpc.GetPreAllocatedVUs(et))
+		first.MaxUnplannedVUs = uint64(bpc.GetMaxVUs(et) - bpc.GetPreAllocatedVUs(et))
+	}
+	return []lib.ExecutionStep{
+		first, {
+			TimeOffset:      bpc.getMaxDuration() + bpc.GracefulStop.TimeDuration(),
+			PlannedVUs:      0,
+			MaxUnplannedVUs: 0,
+		},
+	}
+}
+
+// NewExecutor creates a new BreakingPoint executor
+func (bpc BreakingPointConfig) NewExecutor(es *lib.ExecutionState, logger *logrus.Entry) (lib.Executor, error) {
+	capacityMetric, err := es.Test.Registry.NewMetric(BreakingPointCapacityMetricName, metrics.Gauge)
+	if err != nil {
+		return nil, err
+	}
+	return &BreakingPoint{
+		BaseExecutor:   NewBaseExecutor(&bpc, es, logger),
+		config:         bpc,
+		capacityMetric: capacityMetric,
+	}, nil
+}
+
+// HasWork reports whether there is any work to be done for the given execution segment.
+func (bpc BreakingPointConfig) HasWork(et *lib.ExecutionTuple) bool {
+	return bpc.GetMaxVUs(et) > 0
+}
+
+// IsDistributable returns false, since the thresholds evaluated at each step
+// of the search are only the ones of the local instance.
+func (BreakingPointConfig) IsDistributable() bool {
+	return false
+}
+
+// breakingPointSearch steps up the level of load from start by step, until
+// the thresholds fail or it reaches max. Then it binary-searches the highest
+// level passing them, until it's at most precision below the lowest level
+// failing them.
+type breakingPointSearch struct {
+	step, max, precision int64
+
+	level  int64 // the level being evaluated
+	passed int64 // the highest level which passed, 0 if none did
+	failed int64 // the lowest level which failed, 0 if none did
+	done   bool
+}
+
+func newBreakingPointSearch(config BreakingPointConfig) *breakingPointSearch {
+	return &breakingPointSearch{
+		step:      config.Step.Int64,
+		max:       config.Max.Int64,
+		precision: config.Precision.Int64,
+		level:     config.GetStart(),
+	}
+}
+
+// isBinary returns whether the search is past its ramp up, i.e. a level
+// failed the thresholds.
+func (s *breakingPointSearch) isBinary() bool {
+	return s.failed != 0
+}
+
+// next records whether the level being evaluated passed the thresholds, and
+// returns the next level to evaluate, or false if the search is over.
+func (s *breakingPointSearch) next(passed bool) (int64, bool) {
+	if passed {
+		s.passed = s.level
+	} else {
+		s.failed = s.level
+	}
+
+	switch {
+	case !s.isBinary() && s.level < s.max:
+		s.level += s.step
+		if s.level > s.max {
+			s.level = s.max
+		}
+	case !s.isBinary() || s.failed-s.passed <= s.precision:
+		s.done = true
+		return 0, false
+	default:
+		s.level = (s.passed + s.failed) / 2
+	}
+	return s.level, true
+}
+
+// capacity returns the highest level which passed, and whether the capacity
+// may be higher, i.e. the search didn't finish or nothing failed up to max.
+func (s *breakingPointSearch) capacity() (int64, bool) {
+	return s.passed, !s.done || !s.isBinary()
+}
+
+// BreakingPoint searches for the highest rate of iterations, or number of
+// looping VUs, passing the thresholds of the test run.
+type BreakingPoint struct {
+	*BaseExecutor
+	config         BreakingPointConfig
+	et             *lib.ExecutionTuple
+	capacityMetric *metrics.Metric
+
+	newThresholdsWindow func() lib.ThresholdsWindow
+
+	level  int64 // the current level, accessed atomically
+	mu     sync.Mutex
+	search *breakingPointSearch
+}
+
+// Make sure we implement the lib.Executor interface.
+var (
+	_ lib.Executor                     = &BreakingPoint{}
+	_ lib.ScenarioControllableExecutor = &BreakingPoint{}
+	_ lib.ThresholdsDrivenExecutor     = &BreakingPoint{}
+	_ lib.CapacityReportingExecutor    = &BreakingPoint{}
+)
+
+// Init values needed for the execution
+func (bp *BreakingPoint) Init(_ context.Context) error {
+	if !bp.config.isArrivalRate() {
+		bp.et = bp.executionState.ExecutionTuple
+		return nil
+	}
+
+	// err should always be nil, because Init() won't be called for executors
+	// with no work, as determined by their config's HasWork() method.
+	et, err := bp.executionState.ExecutionTuple.GetNewExecutionTupleFromValue(bp.config.MaxVUs.Int64)
+	bp.et = et
+	bp.iterSegIndex = lib.NewSegmentedIndex(et)
+
+	return err
+}
+
+// SetThresholdsWindows sets the function creating the window of the
+// thresholds, which evaluates each step of the search.
+func (bp *BreakingPoint) SetThresholdsWindows(newWindow func() lib.ThresholdsWindow) {
+	bp.newThresholdsWindow = newWindow
+}
+
+// GetCapacity returns the highest level which passed the thresholds so far,
+// or false if the search didn't start.
+func (bp *BreakingPoint) GetCapacity() (lib.Capacity, bool) {
+	bp.mu.Lock()
+	defer bp.mu.Unlock()
+	if bp.search == nil {
+		return lib.Capacity{}, false
+	}
+	value, atLeast := bp.search.capacity()
+	return lib.Capacity{
+		Scenario: bp.config.Name,
+		Value:    value,
+		Unit:     bp.config.GetUnit(),
+		AtLeast:  atLeast,
+	}, true
+}
+
+// runSearch evaluates the levels of the search, until it's over or the context
+// is done. setLevel is called with each level to put the load at.
+func (bp *BreakingPoint) runSearch(ctx context.Context, window lib.ThresholdsWindow, setLevel func(int64)) {
+	wait := func(d time.Duration) bool {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	stepDuration, cooldown := bp.config.StepDuration.TimeDuration(), bp.config.Cooldown.TimeDuration()
+	search := bp.search
+	for level, ok := search.level, true; ok; {
+		setLevel(level)
+		window.Reset()
+		if !wait(stepDuration) {
+			return
+		}
+		breached := window.Breached()
+		bp.logger.WithFields(logrus.Fields{
+			"level": level, "unit": bp.config.GetUnit(), "breached": breached,
+		}).Debug("Evaluated a level of the breaking point search")
+
+		bp.mu.Lock()
+		level, ok = search.next(len(breached) == 0)
+		bp.mu.Unlock()
+
+		// the load is backed off to the highest level which passed, before
+		// each step of the binary search
+		if ok && search.isBinary() && cooldown > 0 {
+			setLevel(search.passed)
+			if !wait(cooldown) {
+				return
+			}
+		}
+	}
+}
+
+// Run searches for the highest level of load passing the thresholds, and
+// emits it as the breaking_point_capacity metric.
+//
+//nolint:funlen
+func (bp *BreakingPoint) Run(parentCtx context.Context, out chan<- metrics.SampleContainer) (err error) {
+	if bp.newThresholdsWindow == nil {
+		return errors.New("the breaking-point executor can't evaluate the thresholds, e.g. they're disabled")
+	}
+	window := bp.newThresholdsWindow()
+	defer window.Close()
+	if !window.HasThresholds() {
+		return errors.New("the breaking-point executor needs thresholds to evaluate its levels of load")
+	}
+
+	gracefulStop := bp.config.GetGracefulStop()
+	maxDuration := bp.config.getMaxDuration()
+	maxVUs := bp.config.GetMaxVUs(bp.executionState.ExecutionTuple)
+
+	bp.logger.WithFields(logrus.Fields{
+		"mode": bp.config.Mode.String, "start": bp.config.GetStart(), "step": bp.config.Step.Int64,
+		"max": bp.config.Max.Int64, "maxVUs": maxVUs, "maxDuration": maxDuration, "type": bp.config.GetType(),
+	}).Debug("Starting executor run...")
+
+	bp.mu.Lock()
+	bp.search = newBreakingPointSearch(bp.config)
+	bp.mu.Unlock()
+	defer func() {
+		if capacity, atLeast := bp.search.capacity(); parentCtx.Err() == nil {
+			bp.logger.WithFields(logrus.Fields{
+				"capacity": capacity, "unit": bp.config.GetUnit(), "atLeast": atLeast,
+			}).Debug("Finished the breaking point search")
+			metrics.PushIfNotDone(parentCtx, out, metrics.Sample{
+				TimeSeries: metrics.TimeSeries{
+					Metric: bp.capacityMetric,
+					Tags:   bp.getMetricTags(nil),
+				},
+				Time:  time.Now(),
+				Value: float64(capacity),
+			})
+		}
+	}()
+
+	waitOnProgressChannel := make(chan struct{})
+	startTime, maxDurationCtx, regDurationCtx, cancel := bp.getDurationContexts(parentCtx, maxDuration, gracefulStop)
+	defer func() {
+		cancel()
+		<-waitOnProgressChannel
+	}()
+
+	vusFmt := pb.GetFixedLengthIntFormat(maxVUs)
+	unit := bp.config.GetUnit()
+	progressFn := func() (float64, []string) {
+		regular := bp.control.getDurations().getRegularDuration()
+		spent := time.Since(startTime)
+		right := []string{
+			fmt.Sprintf(vusFmt+"/"+vusFmt+" VUs", bp.executionState.GetCurrentlyActiveVUsCount(), maxVUs),
+			regular.String(),
+			fmt.Sprintf("%d %s", atomic.LoadInt64(&bp.level), unit),
+		}
+		if spent > regular {
+			return 1, right
+		}
+		right[1] = pb.GetFixedLengthDuration(spent, regular) + "/" + regular.String()
+		return math.Min(1, float64(spent)/float64(regular)), right
+	}
+	bp.progress.Modify(pb.WithProgress(progressFn))
+	maxDurationCtx = lib.WithScenarioState(maxDurationCtx, &lib.ScenarioState{
+		Name:       bp.config.Name,
+		Executor:   bp.config.Type,
+		StartTime:  startTime,
+		ProgressFn: progressFn,
+	})
+
+	go func() {
+		trackProgress(parentCtx, maxDurationCtx, regDurationCtx, bp, progressFn)
+		close(waitOnProgressChannel)
+	}()
+
+	lr := levelsRun{
+		executor:       bp.BaseExecutor,
+		config:         &bp.config.BaseConfig,
+		level:          &bp.level,
+		parentCtx:      parentCtx,
+		maxDurationCtx: maxDurationCtx,
+		regDurationCtx: regDurationCtx,
+		cancel:         cancel,
+		out:            out,
+	}
+	search := func(setLevel func(int64)) {
+		bp.runSearch(regDurationCtx, window, setLevel)
+		bp.endSearch()
+	}
+	if bp.config.isArrivalRate() {
+		return lr.runArrivalRate(bp.et, bp.config.TimeUnit.TimeDuration(),
+			bp.config.GetPreAllocatedVUs(bp.executionState.ExecutionTuple), maxVUs, search)
+	}
+	return lr.runVUs(maxVUs, search)
+}
+
+// endSearch ends the regular duration when the search is over before it, so
+// the iterations in progress are gracefully stopped.
+func (bp *BreakingPoint) endSearch() {
+	bp.control.getDurations().stop()
+}
@@ -0,0 +1,184 @@
+package executor
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v3"
+
+	"github.com/liuxd6825/k6server/lib"
+	"github.com/liuxd6825/k6server/lib/types"
+	"github.com/liuxd6825/k6server/metrics"
+)
+
+func getTestBreakingPointConfig(mode string) *BreakingPointConfig {
+	config := NewBreakingPointConfig("breaking")
+	config.GracefulStop = types.NullDurationFrom(0)
+	config.Mode = null.StringFrom(mode)
+	config.Step = null.IntFrom(10)
+	config.Max = null.IntFrom(50)
+	config.StepDuration = types.NullDurationFrom(50 * time.Millisecond)
+	if mode == BreakingPointModeArrivalRate {
+		config.TimeUnit = types.NullDurationFrom(100 * time.Millisecond)
+		config.PreAllocatedVUs = null.IntFrom(10)
+		config.MaxVUs = null.IntFrom(10)
+	}
+	return config
+}
+
+// breakingPointTestWindow fails the thresholds above a level of the executor.
+type breakingPointTestWindow struct {
+	executor *BreakingPoint
+	capacity int64
+
+	mu     sync.Mutex
+	levels []int64
+	closed bool
+}
+
+func (w *breakingPointTestWindow) HasThresholds() bool { return true }
+
+func (w *breakingPointTestWindow) Reset() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.levels = append(w.levels, atomic.LoadInt64(&w.executor.level))
+}
+
+func (w *breakingPointTestWindow) Breached() []string {
+	if atomic.LoadInt64(&w.executor.level) > w.capacity {
+		return []string{"http_req_duration"}
+	}
+	return nil
+}
+
+func (w *breakingPointTestWindow) Close() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.closed = true
+}
+
+func TestBreakingPointConfigValidate(t *testing.T) {
+	t.Parallel()
+
+	config := getTestBreakingPointConfig(BreakingPointModeArrivalRate)
+	config.StepDuration = types.NullDurationFrom(time.Minute)
+	assert.Empty(t, config.Validate())
+	assert.Equal(t, "iterations/100ms", config.GetUnit())
+
+	config.Start = null.IntFrom(60)
+	config.Precision = null.IntFrom(0)
+	assert.Len(t, config.Validate(), 2)
+
+	config = getTestBreakingPointConfig(BreakingPointModeVUs)
+	config.StepDuration = types.NullDurationFrom(time.Minute)
+	assert.Empty(t, config.Validate())
+	assert.Equal(t, "VUs", config.GetUnit())
+	config.MaxVUs = null.IntFrom(10)
+	config.StepDuration = types.NullDurationFrom(time.Millisecond)
+	assert.Len(t, config.Validate(), 2)
+
+	config.Mode = null.StringFrom("requests")
+	config.Step = null.NewInt(0, false)
+	assert.Len(t, config.Validate(), 3)
+}
+
+func TestBreakingPointSearch(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range []struct{ start, step, max, precision int64 }{
+		{10, 10, 100, 1}, {25, 10, 100, 1}, {5, 20, 90, 4}, {1, 1, 10, 1},
+	} {
+		c := c
+		config := NewBreakingPointConfig("breaking")
+		config.Start = null.IntFrom(c.start)
+		config.Step = null.IntFrom(c.step)
+		config.Max = null.IntFrom(c.max)
+		config.Precision = null.IntFrom(c.precision)
+		maxRamp, maxBinary := config.getMaxSteps()
+
+		for capacity := int64(0); capacity <= c.max+c.step; capacity++ {
+			name := strconv.FormatInt(c.start, 10) + "/" + strconv.FormatInt(capacity, 10)
+			search := newBreakingPointSearch(*config)
+			var ramp, binary int64
+			for level, ok := search.level, true; ok; {
+				if search.isBinary() {
+					binary++
+				} else {
+					ramp++
+				}
+				require.LessOrEqual(t, level, c.max, name)
+				level, ok = search.next(level <= capacity)
+			}
+			assert.LessOrEqual(t, ramp, maxRamp, name)
+			assert.LessOrEqual(t, binary, maxBinary, name)
+
+			found, atLeast := search.capacity()
+			if capacity >= c.max {
+				assert.Equal(t, c.max, found, name)
+				assert.True(t, atLeast, name)
+				continue
+			}
+			assert.False(t, atLeast, name)
+			assert.LessOrEqual(t, found, capacity, name)
+			assert.Less(t, capacity-found, c.precision, name)
+		}
+	}
+}
+
+func TestBreakingPointRun(t *testing.T) {
+	t.Parallel()
+
+	for _, mode := range []string{BreakingPointModeArrivalRate, BreakingPointModeVUs} {
+		mode := mode
+		t.Run(mode, func(t *testing.T) {
+			t.Parallel()
+
+			var count int64
+			runner := simpleRunner(func(_ context.Context, _ *lib.State) error {
+				atomic.AddInt64(&count, 1)
+				time.Sleep(time.Millisecond)
+				return nil
+			})
+			config := getTestBreakingPointConfig(mode)
+			config.Start = null.IntFrom(5)
+			config.Cooldown = types.NullDurationFrom(20 * time.Millisecond)
+			test := setupExecutorTest(t, "", "", lib.Options{}, runner, config)
+			defer test.cancel()
+
+			executor := test.executor.(*BreakingPoint)
+			_, ok := executor.GetCapacity()
+			assert.False(t, ok)
+			window := &breakingPointTestWindow{executor: executor, capacity: 22}
+			executor.SetThresholdsWindows(func() lib.ThresholdsWindow { return window })
+
+			engineOut := make(chan metrics.SampleContainer, 1000)
+			require.NoError(t, test.executor.Run(test.ctx, engineOut))
+			assert.Equal(t, []int64{5, 15, 25, 20, 22, 23}, window.levels)
+			assert.True(t, window.closed)
+			assert.Greater(t, atomic.LoadInt64(&count), int64(0))
+
+			capacity, ok := executor.GetCapacity()
+			require.True(t, ok)
+			assert.Equal(t, lib.Capacity{Scenario: "breaking", Value: 22, Unit: config.GetUnit()}, capacity)
+			assert.Equal(t, float64(22), sumMetricValues(engineOut, BreakingPointCapacityMetricName))
+			assert.Empty(t, test.logHook.Drain())
+		})
+	}
+}
+
+func TestBreakingPointRunWithoutThresholds(t *testing.T) {
+	t.Parallel()
+
+	runner := simpleRunner(func(_ context.Context, _ *lib.State) error { return nil })
+	test := setupExecutorTest(t, "", "", lib.Options{}, runner, getTestBreakingPointConfig(BreakingPointModeVUs))
+	defer test.cancel()
+
+	engineOut := make(chan metrics.SampleContainer, 1000)
+	assert.Error(t, test.executor.Run(test.ctx, engineOut))
+}
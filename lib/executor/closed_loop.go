@@ -0,0 +1,551 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/guregu/null.v3"
+
+	"github.com/liuxd6825/k6server/lib"
+	"github.com/liuxd6825/k6server/lib/types"
+	"github.com/liuxd6825/k6server/metrics"
+	"github.com/liuxd6825/k6server/ui/pb"
+)
+
+const closedLoopType = "closed-loop"
+
+// The modes of the closed-loop executor, i.e. what its levels of load are.
+const (
+	// ClosedLoopModeArrivalRate makes the levels rates of iterations.
+	ClosedLoopModeArrivalRate = "arrival-rate"
+	// ClosedLoopModeVUs makes the levels numbers of looping VUs.
+	ClosedLoopModeVUs = "vus"
+)
+
+// The names of the gauges of the state of the controllers of the closed-loop
+// executors.
+const (
+	ClosedLoopLevelMetricName    = "closed_loop_level"
+	ClosedLoopValueMetricName    = "closed_loop_value"
+	ClosedLoopErrorMetricName    = "closed_loop_error"
+	ClosedLoopIntegralMetricName = "closed_loop_integral"
+)
+
+func init() {
+	lib.RegisterExecutorConfigType(
+		closedLoopType,
+		func(name string, rawJSON []byte) (lib.ExecutorConfig, error) {
+			config := NewClosedLoopConfig(name)
+			err := lib.StrictJSONUnmarshal(rawJSON, &config)
+			return config, err
+		},
+	)
+}
+
+// ClosedLoopConfig stores config for the closed-loop executor, which adjusts
+// its load with a feedback controller, so a metric stays near a setpoint.
+type ClosedLoopConfig struct {
+	BaseConfig
+	Mode     null.String        `json:"mode"`
+	TimeUnit types.NullDuration `json:"timeUnit"`
+	Duration types.NullDuration `json:"duration"`
+
+	// Metric is the metric, or the sub-metric, kept near the Setpoint, which
+	// is an aggregation method and its value, e.g. http_req_duration and
+	// p(95)=300ms, or http_reqs and rate=500/s. The metric should increase
+	// with the load.
+	Metric   null.String `json:"metric"`
+	Setpoint null.String `json:"setpoint"`
+
+	// The level of load starts at Start, which defaults to Min, and it's kept
+	// between Min and Max.
+	Start null.Int `json:"start"`
+	Min   null.Int `json:"min"`
+	Max   null.Int `json:"max"`
+
+	// The level is adjusted at each Interval, by a PID controller whose gains
+	// are Kp, Ki and Kd. Its error is relative to the setpoint, and its
+	// output is relative to Max, e.g. with a Kp of 0.5, the level is Max/2
+	// above Start when the metric is 0.
+	Interval types.NullDuration `json:"interval"`
+	Kp       null.Float         `json:"kp"`
+	Ki       null.Float         `json:"ki"`
+	Kd       null.Float         `json:"kd"`
+
+	// Initialize `PreAllocatedVUs` number of VUs, and if more than that are needed,
+	// they will be dynamically allocated, until `MaxVUs` is reached, which is an
+	// absolutely hard limit on the number of VUs the executor will use. They're
+	// only used in the arrival-rate mode.
+	PreAllocatedVUs null.Int `json:"preAllocatedVUs"`
+	MaxVUs          null.Int `json:"maxVUs"`
+}
+
+// NewClosedLoopConfig returns a ClosedLoopConfig with default values
+func NewClosedLoopConfig(name string) *ClosedLoopConfig {
+	return &ClosedLoopConfig{
+		BaseConfig: NewBaseConfig(name, closedLoopType),
+		Mode:       null.NewString(ClosedLoopModeArrivalRate, false),
+		TimeUnit:   types.NewNullDuration(1*time.Second, false),
+		Min:        null.NewInt(1, false),
+		Interval:   types.NewNullDuration(5*time.Second, false),
+		Kp:         null.NewFloat(0.2, false),
+		Ki:         null.NewFloat(0.1, false),
+		Kd:         null.NewFloat(0, false),
+	}
+}
+
+// Make sure we implement the lib.ExecutorConfig interface
+var _ lib.ExecutorConfig = &ClosedLoopConfig{}
+
+func (clc ClosedLoopConfig) isArrivalRate() bool {
+	return clc.Mode.String == ClosedLoopModeArrivalRate
+}
+
+// GetStart returns the first level of load.
+func (clc ClosedLoopConfig) GetStart() int64 {
+	if clc.Start.Valid {
+		return clc.Start.Int64
+	}
+	return clc.Min.Int64
+}
+
+// GetUnit returns the unit of the levels, e.g. VUs or iterations/s.
+func (clc ClosedLoopConfig) GetUnit() string {
+	if !clc.isArrivalRate() {
+		return "VUs"
+	}
+	if timeUnit := clc.TimeUnit.TimeDuration(); timeUnit != time.Second {
+		return "iterations/" + timeUnit.String()
+	}
+	return "iterations/s"
+}
+
+// parseSetpoint returns the aggregation method and the value of the setpoint.
+// The durations, e.g. 300ms, are in milliseconds like the values of the time
+// metrics, and the rates can be written per second, e.g. 500/s.
+func (clc ClosedLoopConfig) parseSetpoint() (string, float64, error) {
+	parts := strings.SplitN(clc.Setpoint.String, "=", 2)
+	if len(parts) != 2 {
+		return "", 0, fmt.Errorf("the setpoint should be an aggregation method and its value, e.g. p(95)=300ms, "+
+			"but is '%s'", clc.Setpoint.String)
+	}
+	aggregation := strings.TrimSpace(parts[0])
+	if _, err := metrics.ParseAggregation(aggregation); err != nil {
+		return "", 0, err
+	}
+
+	rawValue := strings.TrimSuffix(strings.TrimSpace(parts[1]), "/s")
+	value, err := strconv.ParseFloat(rawValue, 64)
+	if err != nil {
+		d, derr := time.ParseDuration(rawValue)
+		if derr != nil {
+			return "", 0, fmt.Errorf("invalid value of the setpoint '%s'", rawValue)
+		}
+		value = float64(d) / float64(time.Millisecond)
+	}
+	if !(value > 0) || math.IsInf(value, 0) {
+		return "", 0, fmt.Errorf("the value of the setpoint must be more than 0")
+	}
+	return aggregation, value, nil
+}
+
+// GetPreAllocatedVUs is just a helper method that returns the scaled pre-allocated VUs.
+func (clc ClosedLoopConfig) GetPreAllocatedVUs(et *lib.ExecutionTuple) int64 {
+	return et.ScaleInt64(clc.PreAllocatedVUs.Int64)
+}
+
+// GetMaxVUs is just a helper method that returns the scaled max VUs, which are
+// the maximum level in the VUs mode.
+func (clc ClosedLoopConfig) GetMaxVUs(et *lib.ExecutionTuple) int64 {
+	if !clc.isArrivalRate() {
+		return et.ScaleInt64(clc.Max.Int64)
+	}
+	return et.ScaleInt64(clc.MaxVUs.Int64)
+}
+
+// GetDescription returns a human-readable description of the executor options
+func (clc ClosedLoopConfig) GetDescription(et *lib.ExecutionTuple) string {
+	var facts []string
+	if clc.isArrivalRate() {
+		preAllocatedVUs, maxVUs := clc.GetPreAllocatedVUs(et), clc.GetMaxVUs(et)
+		maxVUsRange := fmt.Sprintf("maxVUs: %d", preAllocatedVUs)
+		if maxVUs > preAllocatedVUs {
+			maxVUsRange += fmt.Sprintf("-%d", maxVUs)
+		}
+		facts = append(facts, maxVUsRange)
+	}
+
+	return fmt.Sprintf("Closed loop holding %s %s with %d-%d %s, for %s%s",
+		clc.Metric.String, clc.Setpoint.String, clc.Min.Int64, clc.Max.Int64, clc.GetUnit(),
+		clc.Duration.Duration, clc.getBaseInfo(facts...))
+}
+
+// Validate makes sure all options are configured and valid
+//
+//nolint:funlen,cyclop
+func (clc *ClosedLoopConfig) Validate() []error {
+	errors := clc.BaseConfig.Validate()
+
+	switch clc.Mode.String {
+	case ClosedLoopModeArrivalRate:
+		if clc.TimeUnit.TimeDuration() <= 0 {
+			errors = append(errors, fmt.Errorf("the timeUnit must be more than 0"))
+		}
+	case ClosedLoopModeVUs:
+		if clc.PreAllocatedVUs.Valid || clc.MaxVUs.Valid {
+			errors = append(errors, fmt.Errorf("preAllocatedVUs and maxVUs can only be used in the %s mode",
+				ClosedLoopModeArrivalRate))
+		}
+	default:
+		errors = append(errors, fmt.Errorf("the mode must be either %s or %s, but is '%s'",
+			ClosedLoopModeArrivalRate, ClosedLoopModeVUs, clc.Mode.String))
+	}
+
+	if !clc.Duration.Valid {
+		errors = append(errors, fmt.Errorf("the duration is unspecified"))
+	} else if clc.Duration.TimeDuration() < minDuration {
+		errors = append(errors, fmt.Errorf(
+			"the duration must be at least %s, but is %s", minDuration, clc.Duration,
+		))
+	}
+
+	if !clc.Metric.Valid || clc.Metric.String == "" {
+		errors = append(errors, fmt.Errorf("the metric isn't specified"))
+	}
+	if !clc.Setpoint.Valid {
+		errors = append(errors, fmt.Errorf("the setpoint isn't specified"))
+	} else if _, _, err := clc.parseSetpoint(); err != nil {
+		errors = append(errors, err)
+	}
+
+	if clc.Min.Int64 <= 0 {
+		errors = append(errors, fmt.Errorf("the min must be more than 0"))
+	}
+	if clc.GetStart() < clc.Min.Int64 {
+		errors = append(errors, fmt.Errorf("the start can't be less than the min"))
+	}
+	if !clc.Max.Valid {
+		errors = append(errors, fmt.Errorf("the max isn't specified"))
+	} else if clc.Max.Int64 < clc.GetStart() {
+		errors = append(errors, fmt.Errorf("the max can't be less than the start"))
+	}
+
+	if clc.Interval.TimeDuration() <= 0 {
+		errors = append(errors, fmt.Errorf("the interval must be more than 0"))
+	}
+	for _, gain := range []null.Float{clc.Kp, clc.Ki, clc.Kd} {
+		if gain.Float64 < 0 || math.IsInf(gain.Float64, 0) || math.IsNaN(gain.Float64) {
+			errors = append(errors, fmt.Errorf("the gains of the controller can't be negative, and they must be finite numbers"))
+			break
+		}
+	}
+
+	if !clc.isArrivalRate() {
+		return errors
+	}
+
+	if !clc.PreAllocatedVUs.Valid {
+		errors = append(errors, fmt.Errorf("the number of preAllocatedVUs isn't specified"))
+	} else if clc.PreAllocatedVUs.Int64 < 0 {
+		errors = append(errors, fmt.Errorf("the number of preAllocatedVUs can't be negative"))
+	}
+
+	if !clc.MaxVUs.Valid {
+		// TODO: don't change the config while validating
+		clc.MaxVUs.Int64 = clc.PreAllocatedVUs.Int64
+	} else if clc.MaxVUs.Int64 < clc.PreAllocatedVUs.Int64 {
+		errors = append(errors, fmt.Errorf("maxVUs can't be less than preAllocatedVUs"))
+	}
+
+	return errors
+}
+
+// GetExecutionRequirements returns the number of required VUs to run the
+// executor for its whole duration (disregarding any startTime), including the
+// maximum waiting time for any iterations to gracefully stop. This is used by
+// the execution scheduler in its VU reservation calculations, so it knows how
+// many VUs to pre-initialize.
+func (clc ClosedLoopConfig) GetExecutionRequirements(et *lib.ExecutionTuple) []lib.ExecutionStep {
+	first := lib.ExecutionStep{TimeOffset: 0, PlannedVUs: uint64(clc.GetMaxVUs(et))}
+	if clc.isArrivalRate() {
+		first.PlannedVUs = uint64(clc.GetPreAllocatedVUs(et))
+		first.MaxUnplannedVUs = uint64(clc.GetMaxVUs(et) - clc.GetPreAllocatedVUs(et))
+	}
+	return []lib.ExecutionStep{
+		first, {
+			TimeOffset:      clc.Duration.TimeDuration() + clc.GracefulStop.TimeDuration(),
+			PlannedVUs:      0,
+			MaxUnplannedVUs: 0,
+		},
+	}
+}
+
+// NewExecutor creates a new ClosedLoop executor
+func (clc ClosedLoopConfig) NewExecutor(es *lib.ExecutionState, logger *logrus.Entry) (lib.Executor, error) {
+	cl := &ClosedLoop{
+		BaseExecutor: NewBaseExecutor(&clc, es, logger),
+		config:       clc,
+	}
+	for name, metric := range map[string]**metrics.Metric{
+		ClosedLoopLevelMetricName:    &cl.levelMetric,
+		ClosedLoopValueMetricName:    &cl.valueMetric,
+		ClosedLoopErrorMetricName:    &cl.errorMetric,
+		ClosedLoopIntegralMetricName: &cl.integralMetric,
+	} {
+		m, err := es.Test.Registry.NewMetric(name, metrics.Gauge)
+		if err != nil {
+			return nil, err
+		}
+		*metric = m
+	}
+	return cl, nil
+}
+
+// HasWork reports whether there is any work to be done for the given execution segment.
+func (clc ClosedLoopConfig) HasWork(et *lib.ExecutionTuple) bool {
+	return clc.GetMaxVUs(et) > 0
+}
+
+// IsDistributable returns false, since the metric kept near the setpoint is
+// only the one of the local instance.
+func (ClosedLoopConfig) IsDistributable() bool {
+	return false
+}
+
+// closedLoopController is the PID controller of the closed-loop executor. Its
+// error is relative to the setpoint, so its gains don't depend on the unit of
+// the metric, and its output is the level of load.
+type closedLoopController struct {
+	kp, ki, kd     float64
+	setpoint       float64
+	start, minimum float64
+	maximum        float64
+
+	integral  float64
+	lastError float64
+	updated   bool
+}
+
+func newClosedLoopController(config ClosedLoopConfig, setpoint float64) *closedLoopController {
+	return &closedLoopController{
+		kp:       config.Kp.Float64,
+		ki:       config.Ki.Float64,
+		kd:       config.Kd.Float64,
+		setpoint: setpoint,
+		start:    float64(config.GetStart()),
+		minimum:  float64(config.Min.Int64),
+		maximum:  float64(config.Max.Int64),
+	}
+}
+
+// update returns the level of load for the value of the metric, measured over
+// the interval since the last update, and the relative error of the value.
+func (c *closedLoopController) update(value float64, interval time.Duration) (level, relError float64) {
+	relError = (c.setpoint - value) / c.setpoint
+	seconds := interval.Seconds()
+	var derivative float64
+	if c.updated && seconds > 0 {
+		derivative = (relError - c.lastError) / seconds
+	}
+	c.lastError, c.updated = relError, true
+
+	// the integral is limited to the values keeping the level within its
+	// limits, so it doesn't wind up while the level is at one of them
+	c.integral += relError * seconds
+	if c.ki > 0 {
+		rest := c.kp*relError + c.kd*derivative
+		low := ((c.minimum-c.start)/c.maximum - rest) / c.ki
+		high := ((c.maximum-c.start)/c.maximum - rest) / c.ki
+		c.integral = math.Max(low, math.Min(high, c.integral))
+	}
+
+	level = c.start + c.maximum*(c.kp*relError+c.ki*c.integral+c.kd*derivative)
+	level = math.Max(c.minimum, math.Min(c.maximum, level))
+	return level, relError
+}
+
+// ClosedLoop adjusts the rate of iterations, or the number of looping VUs,
+// with a feedback controller, so a metric stays near a setpoint.
+type ClosedLoop struct {
+	*BaseExecutor
+	config ClosedLoopConfig
+	et     *lib.ExecutionTuple
+
+	levelMetric, valueMetric, errorMetric, integralMetric *metrics.Metric
+
+	newMetricWindow func(metricName, aggregation string) (lib.MetricWindow, error)
+	level           int64 // the current level, accessed atomically
+}
+
+// Make sure we implement the lib.Executor interface.
+var (
+	_ lib.Executor                     = &ClosedLoop{}
+	_ lib.ScenarioControllableExecutor = &ClosedLoop{}
+	_ lib.MetricsDrivenExecutor        = &ClosedLoop{}
+)
+
+// Init values needed for the execution
+func (cl *ClosedLoop) Init(_ context.Context) error {
+	if !cl.config.isArrivalRate() {
+		cl.et = cl.executionState.ExecutionTuple
+		return nil
+	}
+
+	// err should always be nil, because Init() won't be called for executors
+	// with no work, as determined by their config's HasWork() method.
+	et, err := cl.executionState.ExecutionTuple.GetNewExecutionTupleFromValue(cl.config.MaxVUs.Int64)
+	cl.et = et
+	cl.iterSegIndex = lib.NewSegmentedIndex(et)
+
+	return err
+}
+
+// SetMetricWindows sets the function creating the window of the metric kept
+// near the setpoint.
+func (cl *ClosedLoop) SetMetricWindows(newWindow func(metricName, aggregation string) (lib.MetricWindow, error)) {
+	cl.newMetricWindow = newWindow
+}
+
+// runController adjusts the level at each interval, until the context is done.
+func (cl *ClosedLoop) runController(
+	parentCtx, ctx context.Context, out chan<- metrics.SampleContainer,
+	window lib.MetricWindow, setpoint float64, setLevel func(int64),
+) {
+	controller := newClosedLoopController(cl.config, setpoint)
+	metricTags := cl.getMetricTags(nil)
+	setLevel(cl.config.GetStart())
+	window.Reset()
+
+	ticker := time.NewTicker(cl.config.Interval.TimeDuration())
+	defer ticker.Stop()
+	last := time.Now()
+	for {
+		select {
+		case now := <-ticker.C:
+			value, ok := window.Value()
+			window.Reset()
+			if !ok {
+				// there's nothing to adjust the level to, e.g. no requests
+				// were made during the interval
+				cl.logger.Debug("The metric of the closed loop doesn't have any values during the interval")
+				continue
+			}
+			level, relError := controller.update(value, now.Sub(last))
+			last = now
+			setLevel(int64(math.Round(level)))
+
+			metrics.PushIfNotDone(parentCtx, out, metrics.ConnectedSamples{
+				Samples: []metrics.Sample{
+					cl.newGauge(cl.levelMetric, metricTags, now, float64(atomic.LoadInt64(&cl.level))),
+					cl.newGauge(cl.valueMetric, metricTags, now, value),
+					cl.newGauge(cl.errorMetric, metricTags, now, relError),
+					cl.newGauge(cl.integralMetric, metricTags, now, controller.integral),
+				},
+				Tags: metricTags,
+				Time: now,
+			})
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (cl *ClosedLoop) newGauge(m *metrics.Metric, tags *metrics.TagSet, t time.Time, value float64) metrics.Sample {
+	return metrics.Sample{
+		TimeSeries: metrics.TimeSeries{Metric: m, Tags: tags},
+		Time:       t,
+		Value:      value,
+	}
+}
+
+// Run adjusts the load with a feedback controller, so the metric stays near
+// the setpoint, for the duration of the scenario.
+//
+//nolint:funlen
+func (cl *ClosedLoop) Run(parentCtx context.Context, out chan<- metrics.SampleContainer) (err error) {
+	if cl.newMetricWindow == nil {
+		return errors.New("the closed-loop executor can't observe the metrics, " +
+			"e.g. the end-of-test summary and the thresholds are disabled")
+	}
+	aggregation, setpoint, err := cl.config.parseSetpoint()
+	if err != nil {
+		return err
+	}
+	window, err := cl.newMetricWindow(cl.config.Metric.String, aggregation)
+	if err != nil {
+		return fmt.Errorf("the closed-loop executor can't observe the metric %s: %w", cl.config.Metric.String, err)
+	}
+	defer window.Close()
+
+	gracefulStop := cl.config.GetGracefulStop()
+	duration := cl.config.Duration.TimeDuration()
+	maxVUs := cl.config.GetMaxVUs(cl.executionState.ExecutionTuple)
+
+	cl.logger.WithFields(logrus.Fields{
+		"mode": cl.config.Mode.String, "metric": cl.config.Metric.String, "setpoint": cl.config.Setpoint.String,
+		"min": cl.config.Min.Int64, "max": cl.config.Max.Int64, "maxVUs": maxVUs, "duration": duration,
+		"type": cl.config.GetType(),
+	}).Debug("Starting executor run...")
+
+	waitOnProgressChannel := make(chan struct{})
+	startTime, maxDurationCtx, regDurationCtx, cancel := cl.getDurationContexts(parentCtx, duration, gracefulStop)
+	defer func() {
+		cancel()
+		<-waitOnProgressChannel
+	}()
+
+	vusFmt := pb.GetFixedLengthIntFormat(maxVUs)
+	unit := cl.config.GetUnit()
+	progressFn := func() (float64, []string) {
+		regular := cl.control.getDurations().getRegularDuration()
+		spent := time.Since(startTime)
+		right := []string{
+			fmt.Sprintf(vusFmt+"/"+vusFmt+" VUs", cl.executionState.GetCurrentlyActiveVUsCount(), maxVUs),
+			regular.String(),
+			fmt.Sprintf("%d %s", atomic.LoadInt64(&cl.level), unit),
+		}
+		if spent > regular {
+			return 1, right
+		}
+		right[1] = pb.GetFixedLengthDuration(spent, regular) + "/" + regular.String()
+		return math.Min(1, float64(spent)/float64(regular)), right
+	}
+	cl.progress.Modify(pb.WithProgress(progressFn))
+	maxDurationCtx = lib.WithScenarioState(maxDurationCtx, &lib.ScenarioState{
+		Name:       cl.config.Name,
+		Executor:   cl.config.Type,
+		StartTime:  startTime,
+		ProgressFn: progressFn,
+	})
+
+	go func() {
+		trackProgress(parentCtx, maxDurationCtx, regDurationCtx, cl, progressFn)
+		close(waitOnProgressChannel)
+	}()
+
+	lr := levelsRun{
+		executor:       cl.BaseExecutor,
+		config:         &cl.config.BaseConfig,
+		level:          &cl.level,
+		parentCtx:      parentCtx,
+		maxDurationCtx: maxDurationCtx,
+		regDurationCtx: regDurationCtx,
+		cancel:         cancel,
+		out:            out,
+	}
+	control := func(setLevel func(int64)) {
+		cl.runController(parentCtx, regDurationCtx, out, window, setpoint, setLevel)
+	}
+	if cl.config.isArrivalRate() {
+		return lr.runArrivalRate(cl.et, cl.config.TimeUnit.TimeDuration(),
+			cl.config.GetPreAllocatedVUs(cl.executionState.ExecutionTuple), maxVUs, control)
+	}
+	return lr.runVUs(maxVUs, control)
+}
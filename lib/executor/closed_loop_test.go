@@ -0,0 +1,181 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v3"
+
+	"github.com/liuxd6825/k6server/lib"
+	"github.com/liuxd6825/k6server/lib/types"
+	"github.com/liuxd6825/k6server/metrics"
+)
+
+func getTestClosedLoopConfig(mode string) *ClosedLoopConfig {
+	config := NewClosedLoopConfig("closed")
+	config.GracefulStop = types.NullDurationFrom(0)
+	config.Mode = null.StringFrom(mode)
+	config.Duration = types.NullDurationFrom(time.Second)
+	config.Metric = null.StringFrom("http_req_duration")
+	config.Setpoint = null.StringFrom("p(95)=300ms")
+	config.Max = null.IntFrom(50)
+	config.Interval = types.NullDurationFrom(10 * time.Millisecond)
+	config.Kp = null.FloatFrom(0.1)
+	config.Ki = null.FloatFrom(5)
+	if mode == ClosedLoopModeArrivalRate {
+		config.TimeUnit = types.NullDurationFrom(100 * time.Millisecond)
+		config.PreAllocatedVUs = null.IntFrom(10)
+		config.MaxVUs = null.IntFrom(10)
+	}
+	return config
+}
+
+// closedLoopTestWindow makes the metric proportional to the level of the
+// executor, e.g. 10ms per VU.
+type closedLoopTestWindow struct {
+	executor *ClosedLoop
+	perLevel float64
+	closed   int32
+}
+
+func (w *closedLoopTestWindow) Reset() {}
+
+func (w *closedLoopTestWindow) Value() (float64, bool) {
+	return w.perLevel * float64(atomic.LoadInt64(&w.executor.level)), true
+}
+
+func (w *closedLoopTestWindow) Close() { atomic.StoreInt32(&w.closed, 1) }
+
+func TestClosedLoopConfigValidate(t *testing.T) {
+	t.Parallel()
+
+	config := getTestClosedLoopConfig(ClosedLoopModeVUs)
+	assert.Empty(t, config.Validate())
+	aggregation, value, err := config.parseSetpoint()
+	require.NoError(t, err)
+	assert.Equal(t, "p(95)", aggregation)
+	assert.Equal(t, 300.0, value)
+
+	config.Setpoint = null.StringFrom("rate = 500/s")
+	aggregation, value, err = config.parseSetpoint()
+	require.NoError(t, err)
+	assert.Equal(t, "rate", aggregation)
+	assert.Equal(t, 500.0, value)
+
+	for _, setpoint := range []string{"p(95)<300", "p95=300", "avg=fast", "rate=0"} {
+		config.Setpoint = null.StringFrom(setpoint)
+		assert.Len(t, config.Validate(), 1, setpoint)
+	}
+
+	config = getTestClosedLoopConfig(ClosedLoopModeArrivalRate)
+	assert.Empty(t, config.Validate())
+	config.Start = null.IntFrom(60)
+	config.Kd = null.FloatFrom(-1)
+	assert.Len(t, config.Validate(), 2)
+
+	config.Mode = null.StringFrom("rps")
+	config.Min = null.IntFrom(0)
+	config.Metric = null.NewString("", false)
+	assert.Len(t, config.Validate(), 5)
+}
+
+func TestClosedLoopController(t *testing.T) {
+	t.Parallel()
+
+	config := getTestClosedLoopConfig(ClosedLoopModeVUs)
+	controller := newClosedLoopController(*config, 300)
+
+	// the metric increases by 10 for each VU, so the setpoint is at 30 VUs
+	level := float64(config.GetStart())
+	for i := 0; i < 500; i++ {
+		level, _ = controller.update(10*math.Round(level), 10*time.Millisecond)
+		require.GreaterOrEqual(t, level, 1.0)
+		require.LessOrEqual(t, level, 50.0)
+	}
+	assert.InDelta(t, 30, level, 0.5)
+
+	// the integral doesn't wind up while the level is at the max
+	controller = newClosedLoopController(*config, 300)
+	for i := 0; i < 500; i++ {
+		level, _ = controller.update(100, 10*time.Millisecond)
+	}
+	assert.Equal(t, 50.0, level)
+	assert.Less(t, controller.integral, 1.0)
+	level, relError := controller.update(600, 10*time.Millisecond)
+	assert.Equal(t, -1.0, relError)
+	assert.Less(t, level, 50.0)
+}
+
+func TestClosedLoopRun(t *testing.T) {
+	t.Parallel()
+
+	for _, mode := range []string{ClosedLoopModeArrivalRate, ClosedLoopModeVUs} {
+		mode := mode
+		t.Run(mode, func(t *testing.T) {
+			t.Parallel()
+
+			var count int64
+			runner := simpleRunner(func(_ context.Context, _ *lib.State) error {
+				atomic.AddInt64(&count, 1)
+				time.Sleep(time.Millisecond)
+				return nil
+			})
+			config := getTestClosedLoopConfig(mode)
+			test := setupExecutorTest(t, "", "", lib.Options{}, runner, config)
+			defer test.cancel()
+
+			executor := test.executor.(*ClosedLoop)
+			window := &closedLoopTestWindow{executor: executor, perLevel: 10}
+			executor.SetMetricWindows(func(metricName, aggregation string) (lib.MetricWindow, error) {
+				assert.Equal(t, "http_req_duration", metricName)
+				assert.Equal(t, "p(95)", aggregation)
+				return window, nil
+			})
+
+			engineOut := make(chan metrics.SampleContainer, 10000)
+			start := time.Now()
+			require.NoError(t, test.executor.Run(test.ctx, engineOut))
+			assert.InDelta(t, time.Second, time.Since(start), float64(100*time.Millisecond))
+			assert.Equal(t, int32(1), atomic.LoadInt32(&window.closed))
+			assert.Greater(t, atomic.LoadInt64(&count), int64(0))
+
+			var levels, values []float64
+			for _, sc := range metrics.GetBufferedSamples(engineOut) {
+				for _, s := range sc.GetSamples() {
+					switch s.Metric.Name {
+					case ClosedLoopLevelMetricName:
+						levels = append(levels, s.Value)
+					case ClosedLoopValueMetricName:
+						values = append(values, s.Value)
+					}
+				}
+			}
+			require.NotEmpty(t, levels)
+			require.Len(t, values, len(levels))
+			assert.InDelta(t, 30, levels[len(levels)-1], 2)
+			assert.Empty(t, test.logHook.Drain())
+		})
+	}
+}
+
+func TestClosedLoopRunWithoutMetric(t *testing.T) {
+	t.Parallel()
+
+	runner := simpleRunner(func(_ context.Context, _ *lib.State) error { return nil })
+	test := setupExecutorTest(t, "", "", lib.Options{}, runner, getTestClosedLoopConfig(ClosedLoopModeVUs))
+	defer test.cancel()
+
+	engineOut := make(chan metrics.SampleContainer, 1000)
+	assert.Error(t, test.executor.Run(test.ctx, engineOut), "the metrics aren't observed")
+
+	test.executor.(*ClosedLoop).SetMetricWindows(func(string, string) (lib.MetricWindow, error) {
+		return nil, errors.New("metric 'http_req_duration' does not exist in the script")
+	})
+	assert.Error(t, test.executor.Run(test.ctx, engineOut))
+}
@@ -167,7 +167,11 @@ type ConstantArrivalRate struct {
 }
 
 // Make sure we implement the lib.Executor interface.
-var _ lib.Executor = &ConstantArrivalRate{}
+var (
+	_ lib.Executor                     = &ConstantArrivalRate{}
+	_ lib.ScenarioControllableExecutor = &ConstantArrivalRate{}
+	_ lib.RateMultipliableExecutor     = &ConstantArrivalRate{}
+)
 
 // Init values needed for the execution
 func (car *ConstantArrivalRate) Init(_ context.Context) error {
@@ -180,6 +184,17 @@ func (car *ConstantArrivalRate) Init(_ context.Context) error {
 	return err
 }
 
+// GetRateMultiplier returns the multiplier of the configured rate.
+func (car ConstantArrivalRate) GetRateMultiplier() float64 {
+	return car.control.getRateMultiplier()
+}
+
+// SetRateMultiplier multiplies the configured rate while the scenario is
+// running, e.g. 2 doubles it and 0.5 halves it.
+func (car ConstantArrivalRate) SetRateMultiplier(multiplier float64) error {
+	return car.control.setRateMultiplier(multiplier)
+}
+
 // Run executes a constant number of iterations per second.
 //
 // TODO: Split this up and make an independent component that can be reused
@@ -212,7 +227,7 @@ func (car ConstantArrivalRate) Run(parentCtx context.Context, out chan<- metrics
 
 	returnedVUs := make(chan struct{})
 	waitOnProgressChannel := make(chan struct{})
-	startTime, maxDurationCtx, regDurationCtx, cancel := getDurationContexts(parentCtx, duration, gracefulStop)
+	startTime, maxDurationCtx, regDurationCtx, cancel := car.getDurationContexts(parentCtx, duration, gracefulStop)
 	defer func() {
 		cancel()
 		<-waitOnProgressChannel
@@ -273,7 +288,7 @@ func (car ConstantArrivalRate) Run(parentCtx context.Context, out chan<- metrics
 		activeVUsWg.Done()
 	}
 
-	runIterationBasic := getIterationRunner(car.executionState, car.logger)
+	runIterationBasic := getIterationRunner(car.executionState, car.logger, car.execMix)
 	activateVU := func(initVU lib.InitializedVU) lib.ActiveVU {
 		activeVUsWg.Add(1)
 		activeVU := initVU.Activate(getVUActivationParams(
@@ -329,36 +344,40 @@ func (car ConstantArrivalRate) Run(parentCtx context.Context, out chan<- metrics
 		timer.Reset(t)
 		select {
 		case <-timer.C:
-			if vusPool.TryRunIteration() {
-				continue
-			}
+			// the scheduled iteration is skipped while the scenario is paused,
+			// and it's multiplied by the rate multiplier
+			for n := car.control.iterationsToStart(); n > 0; n-- {
+				if vusPool.TryRunIteration() {
+					continue
+				}
 
-			// Since there aren't any free VUs available, consider this iteration
-			// dropped - we aren't going to try to recover it, but
-
-			metrics.PushIfNotDone(parentCtx, out, metrics.Sample{
-				TimeSeries: metrics.TimeSeries{
-					Metric: droppedIterationMetric,
-					Tags:   metricTags,
-				},
-				Time:  time.Now(),
-				Value: 1,
-			})
-
-			// We'll try to start allocating another VU in the background,
-			// non-blockingly, if we have remainingUnplannedVUs...
-			if remainingUnplannedVUs == 0 {
-				if !shownWarning {
-					car.logger.Warningf("Insufficient VUs, reached %d active VUs and cannot initialize more", maxVUs)
-					shownWarning = true
+				// Since there aren't any free VUs available, consider this iteration
+				// dropped - we aren't going to try to recover it, but
+
+				metrics.PushIfNotDone(parentCtx, out, metrics.Sample{
+					TimeSeries: metrics.TimeSeries{
+						Metric: droppedIterationMetric,
+						Tags:   metricTags,
+					},
+					Time:  time.Now(),
+					Value: 1,
+				})
+
+				// We'll try to start allocating another VU in the background,
+				// non-blockingly, if we have remainingUnplannedVUs...
+				if remainingUnplannedVUs == 0 {
+					if !shownWarning {
+						car.logger.Warningf("Insufficient VUs, reached %d active VUs and cannot initialize more", maxVUs)
+						shownWarning = true
+					}
+					continue
 				}
-				continue
-			}
 
-			select {
-			case makeUnplannedVUCh <- struct{}{}: // great!
-				remainingUnplannedVUs--
-			default: // we're already allocating a new VU
+				select {
+				case makeUnplannedVUCh <- struct{}{}: // great!
+					remainingUnplannedVUs--
+				default: // we're already allocating a new VU
+				}
 			}
 
 		case <-regDurationCtx.Done():
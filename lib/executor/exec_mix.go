@@ -0,0 +1,111 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+
+	"github.com/liuxd6825/k6server/lib"
+)
+
+// getExecMixNames returns the functions of the execMix, sorted by their names,
+// so the picks don't depend on the order of the map.
+func (bc BaseConfig) getExecMixNames() []string {
+	if len(bc.ExecMix) == 0 {
+		return nil
+	}
+	execs := make([]string, 0, len(bc.ExecMix))
+	for exec := range bc.ExecMix {
+		execs = append(execs, exec)
+	}
+	sort.Strings(execs)
+	return execs
+}
+
+func (bc BaseConfig) validateExecMix() (errors []error) {
+	if len(bc.ExecMix) == 0 {
+		return nil
+	}
+	if bc.Exec.Valid {
+		errors = append(errors, fmt.Errorf("the exec and execMix options can't be used together"))
+	}
+	for _, exec := range bc.getExecMixNames() {
+		if exec == "" {
+			errors = append(errors, fmt.Errorf("the execMix function names can't be empty"))
+		} else if bc.ExecMix[exec] <= 0 {
+			errors = append(errors, fmt.Errorf("the execMix weight of %s should be more than 0", exec))
+		}
+	}
+	return errors
+}
+
+// execMix picks the exported function of each iteration of a scenario, by the
+// weights of its execMix.
+type execMix struct {
+	execs      []string
+	cumulative []float64 // the cumulative weights of execs
+
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+// newExecMix returns the execMix of the config, or nil if it has none.
+func newExecMix(config lib.ExecutorConfig, options lib.Options) *execMix {
+	bc, ok := config.(interface{ getBaseConfig() BaseConfig })
+	if !ok {
+		return nil
+	}
+	base := bc.getBaseConfig()
+	execs := base.getExecMixNames()
+	if len(execs) == 0 {
+		return nil
+	}
+
+	mix := &execMix{
+		execs:      execs,
+		cumulative: make([]float64, len(execs)),
+		rng:        rand.New(rand.NewSource(getRandomSeed(options, base.Name, "execMix"))), //nolint:gosec
+	}
+	var total float64
+	for i, exec := range execs {
+		total += base.ExecMix[exec]
+		mix.cumulative[i] = total
+	}
+	return mix
+}
+
+// next returns the function of the next iteration. The sequence of the picks
+// is the same for a given seed, though the VUs running them may differ.
+func (m *execMix) next() string {
+	m.mu.Lock()
+	r := m.rng.Float64() * m.cumulative[len(m.cumulative)-1]
+	m.mu.Unlock()
+
+	i := sort.Search(len(m.cumulative), func(i int) bool { return r < m.cumulative[i] })
+	if i == len(m.execs) {
+		i-- // just in case of rounding errors
+	}
+	return m.execs[i]
+}
+
+// withExecMix returns the runIteration function running the iterations with
+// the functions picked by the mix, unless they already have a function, e.g.
+// from a trace. It returns runIteration as it is when the mix is nil.
+func withExecMix(
+	runIteration func(context.Context, lib.ActiveVU) bool, mix *execMix,
+) func(context.Context, lib.ActiveVU) bool {
+	if mix == nil {
+		return runIteration
+	}
+	return func(ctx context.Context, avu lib.ActiveVU) bool {
+		if u, ok := avu.(activeVUWithOptions); ok {
+			if u.opts.Exec == "" {
+				u.opts.Exec = mix.next()
+			}
+			return runIteration(ctx, u)
+		}
+		return runIteration(ctx, withIterationOptions(avu, lib.IterationOptions{Exec: mix.next()}))
+	}
+}
@@ -0,0 +1,133 @@
+package executor
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v3"
+
+	"github.com/liuxd6825/k6server/lib"
+	"github.com/liuxd6825/k6server/lib/testutils/minirunner"
+	"github.com/liuxd6825/k6server/lib/types"
+	"github.com/liuxd6825/k6server/metrics"
+)
+
+func TestExecMixValidate(t *testing.T) {
+	t.Parallel()
+
+	config := NewBaseConfig("mixed", "constant-vus")
+	config.ExecMix = map[string]float64{"search": 20, "browse": 70, "checkout": 10}
+	assert.Empty(t, config.Validate())
+	assert.Equal(t, "browse", config.GetExec())
+	assert.Equal(t, []string{"checkout", "search"}, config.GetExecs())
+	assert.Equal(t, " (execMix: browse 70 / checkout 10 / search 20, gracefulStop: 30s)", config.getBaseInfo())
+
+	config.Exec = null.StringFrom("browse")
+	config.ExecMix["search"] = 0
+	config.ExecMix[""] = 1
+	assert.Len(t, config.Validate(), 3)
+
+	config = NewBaseConfig("mixed", "constant-vus")
+	assert.Empty(t, config.Validate())
+	assert.Equal(t, "default", config.GetExec())
+	assert.Empty(t, config.GetExecs())
+	assert.Nil(t, newExecMix(ConstantVUsConfig{BaseConfig: config}, lib.Options{}))
+}
+
+func TestExecMixNext(t *testing.T) {
+	t.Parallel()
+
+	config := NewBaseConfig("mixed", "constant-vus")
+	config.ExecMix = map[string]float64{"browse": 70, "search": 20, "checkout": 10}
+	picks := func(config BaseConfig, randomSeed null.Int, n int) []string {
+		mix := newExecMix(ConstantVUsConfig{BaseConfig: config}, lib.Options{RandomSeed: randomSeed})
+		require.NotNil(t, mix)
+		result := make([]string, n)
+		for i := range result {
+			result[i] = mix.next()
+		}
+		return result
+	}
+
+	sequence := picks(config, null.IntFrom(1), 10000)
+	counts := make(map[string]int)
+	for _, exec := range sequence {
+		counts[exec]++
+	}
+	assert.InDelta(t, 7000, counts["browse"], 300)
+	assert.InDelta(t, 2000, counts["search"], 300)
+	assert.InDelta(t, 1000, counts["checkout"], 300)
+
+	// the picks are the same for the same randomSeed, and differ for other
+	// seeds, other scenarios or without a seed
+	assert.Equal(t, sequence, picks(config, null.IntFrom(1), 10000))
+	assert.NotEqual(t, sequence, picks(config, null.IntFrom(2), 10000))
+	assert.NotEqual(t, sequence, picks(config, null.Int{}, 10000))
+	config.Name = "other"
+	assert.NotEqual(t, sequence, picks(config, null.IntFrom(1), 10000))
+}
+
+func TestExecMixRun(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu    sync.Mutex
+		execs []string
+	)
+	runner := simpleRunner(func(ctx context.Context, _ *lib.State) error {
+		opts, _ := minirunner.IterationOptionsFromContext(ctx)
+		mu.Lock()
+		execs = append(execs, opts.Exec)
+		mu.Unlock()
+		return nil
+	})
+	getCounts := func() map[string]int {
+		mu.Lock()
+		defer mu.Unlock()
+		counts := make(map[string]int)
+		for _, exec := range execs {
+			counts[exec]++
+		}
+		execs = nil
+		return counts
+	}
+	mix := map[string]float64{"browse": 3, "search": 1}
+
+	t.Run("shared-iterations", func(t *testing.T) {
+		config := getTestSharedIterationsConfig()
+		config.ExecMix = mix
+		test := setupExecutorTest(t, "", "", lib.Options{RandomSeed: null.IntFrom(1)}, runner, config)
+		defer test.cancel()
+
+		engineOut := make(chan metrics.SampleContainer, 1000)
+		require.NoError(t, test.executor.Run(test.ctx, engineOut))
+
+		// the VUs running the iterations differ, but not the picks
+		expected := make(map[string]int)
+		picks := newExecMix(config, test.options)
+		for i := int64(0); i < config.Iterations.Int64; i++ {
+			expected[picks.next()]++
+		}
+		assert.Equal(t, expected, getCounts())
+	})
+
+	t.Run("constant-arrival-rate", func(t *testing.T) {
+		config := getTestConstantArrivalRateConfig()
+		config.ExecMix = mix
+		config.Rate = null.IntFrom(200)
+		config.Duration = types.NullDurationFrom(500 * time.Millisecond)
+		test := setupExecutorTest(t, "", "", lib.Options{}, runner, config)
+		defer test.cancel()
+
+		engineOut := make(chan metrics.SampleContainer, 1000)
+		require.NoError(t, test.executor.Run(test.ctx, engineOut))
+
+		counts := getCounts()
+		require.Len(t, counts, 2)
+		assert.Greater(t, counts["browse"], counts["search"])
+	})
+}
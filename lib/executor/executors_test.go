@@ -406,8 +406,183 @@ var configMapTestCases = []configMapTestCase{
 	{`{"varrival": {"executor": "ramping-arrival-rate", "preAllocatedVUs": 20, "maxVUs": 50, "stages": [{"duration": "5m", "target": 10}], "timeUnit": "-1s"}}`, exp{validationError: true}},
 	{`{"varrival": {"executor": "ramping-arrival-rate", "preAllocatedVUs": 20, "maxVUs": 50, "stages": [{"duration": "5m", "target": 10}], "timeUnit": "0s"}}`, exp{validationError: true}},
 	{`{"varrival": {"executor": "ramping-arrival-rate", "preAllocatedVUs": 30, "maxVUs": 20, "stages": [{"duration": "5m", "target": 10}]}}`, exp{validationError: true}},
+	// stochastic-arrival-rate
+	{
+		`{"sarrival": {"executor": "stochastic-arrival-rate", "rate": 30, "timeUnit": "1m", "duration": "10m", "preAllocatedVUs": 20, "maxVUs": 30, "distribution": "pareto", "randomSeed": 7}}`,
+		exp{custom: func(t *testing.T, cm lib.ScenarioConfigs) {
+			sched := NewStochasticArrivalRateConfig("sarrival")
+			sched.Rate = null.IntFrom(30)
+			sched.Duration = types.NullDurationFrom(10 * time.Minute)
+			sched.TimeUnit = types.NullDurationFrom(1 * time.Minute)
+			sched.Distribution = null.StringFrom(DistributionPareto)
+			sched.RandomSeed = null.IntFrom(7)
+			sched.PreAllocatedVUs = null.IntFrom(20)
+			sched.MaxVUs = null.IntFrom(30)
+			require.Equal(t, cm, lib.ScenarioConfigs{"sarrival": sched})
+
+			assert.Empty(t, cm["sarrival"].Validate())
+			et, err := lib.NewExecutionTuple(nil, nil)
+			require.NoError(t, err)
+			assert.Equal(t, "0.50 iterations/s on average, pareto arrivals, for 10m0s (maxVUs: 20-30, gracefulStop: 30s)",
+				cm["sarrival"].GetDescription(et))
+		}},
+	},
+	{`{"sarrival": {"executor": "stochastic-arrival-rate", "rate": 10, "duration": "10m", "preAllocatedVUs": 20}}`, exp{}},
+	{`{"sarrival": {"executor": "stochastic-arrival-rate", "rate": 10, "duration": "10m", "preAllocatedVUs": 20, "distribution": "poisson"}}`, exp{validationError: true}},
+	{`{"sarrival": {"executor": "stochastic-arrival-rate", "rate": 10, "duration": "10m", "preAllocatedVUs": 20, "distribution": "pareto", "paretoShape": 0.5}}`, exp{validationError: true}},
+	{`{"sarrival": {"executor": "stochastic-arrival-rate", "rate": 0, "duration": "10m", "preAllocatedVUs": 20}}`, exp{validationError: true}},
+	{`{"sarrival": {"executor": "stochastic-arrival-rate", "rate": 10, "preAllocatedVUs": 20}}`, exp{validationError: true}},
+	// breaking-point
+	{
+		`{"capacity": {"executor": "breaking-point", "step": 10, "max": 100, "stepDuration": "1m", "cooldown": "30s", "preAllocatedVUs": 20, "maxVUs": 50}}`,
+		exp{custom: func(t *testing.T, cm lib.ScenarioConfigs) {
+			sched := NewBreakingPointConfig("capacity")
+			sched.Step = null.IntFrom(10)
+			sched.Max = null.IntFrom(100)
+			sched.StepDuration = types.NullDurationFrom(1 * time.Minute)
+			sched.Cooldown = types.NullDurationFrom(30 * time.Second)
+			sched.PreAllocatedVUs = null.IntFrom(20)
+			sched.MaxVUs = null.IntFrom(50)
+			require.Equal(t, cm, lib.ScenarioConfigs{"capacity": sched})
+
+			assert.Empty(t, cm["capacity"].Validate())
+			assert.False(t, cm["capacity"].IsDistributable())
+			et, err := lib.NewExecutionTuple(nil, nil)
+			require.NoError(t, err)
+			assert.Equal(t, "Breaking point between 10 and 100 iterations/s, in steps of 10 for 1m0s, up to 16m0s "+
+				"(maxVUs: 20-50, gracefulStop: 30s)", cm["capacity"].GetDescription(et))
+			assert.Equal(t, []lib.ExecutionStep{
+				{TimeOffset: 0, PlannedVUs: 20, MaxUnplannedVUs: 30},
+				{TimeOffset: 16*time.Minute + 30*time.Second},
+			}, cm["capacity"].GetExecutionRequirements(et))
+		}},
+	},
+	{`{"capacity": {"executor": "breaking-point", "mode": "vus", "start": 5, "step": 5, "max": 50, "stepDuration": "1m"}}`, exp{}},
+	{`{"capacity": {"executor": "breaking-point", "mode": "vus", "step": 5, "max": 50, "stepDuration": "1m", "maxVUs": 50}}`, exp{validationError: true}},
+	{`{"capacity": {"executor": "breaking-point", "mode": "rps", "step": 5, "max": 50, "stepDuration": "1m"}}`, exp{validationError: true}},
+	{`{"capacity": {"executor": "breaking-point", "step": 5, "max": 50, "preAllocatedVUs": 20}}`, exp{validationError: true}},
+	{`{"capacity": {"executor": "breaking-point", "step": 50, "max": 10, "stepDuration": "1m", "preAllocatedVUs": 20}}`, exp{validationError: true}},
+	// closed-loop
+	{
+		`{"steady": {"executor": "closed-loop", "duration": "10m", "metric": "http_req_duration", "setpoint": "p(95)=300ms", "max": 100, "preAllocatedVUs": 20, "maxVUs": 50}}`,
+		exp{custom: func(t *testing.T, cm lib.ScenarioConfigs) {
+			sched := NewClosedLoopConfig("steady")
+			sched.Duration = types.NullDurationFrom(10 * time.Minute)
+			sched.Metric = null.StringFrom("http_req_duration")
+			sched.Setpoint = null.StringFrom("p(95)=300ms")
+			sched.Max = null.IntFrom(100)
+			sched.PreAllocatedVUs = null.IntFrom(20)
+			sched.MaxVUs = null.IntFrom(50)
+			require.Equal(t, cm, lib.ScenarioConfigs{"steady": sched})
+
+			assert.Empty(t, cm["steady"].Validate())
+			assert.False(t, cm["steady"].IsDistributable())
+			et, err := lib.NewExecutionTuple(nil, nil)
+			require.NoError(t, err)
+			assert.Equal(t, "Closed loop holding http_req_duration p(95)=300ms with 1-100 iterations/s, for 10m0s "+
+				"(maxVUs: 20-50, gracefulStop: 30s)", cm["steady"].GetDescription(et))
+			assert.Equal(t, []lib.ExecutionStep{
+				{TimeOffset: 0, PlannedVUs: 20, MaxUnplannedVUs: 30},
+				{TimeOffset: 10*time.Minute + 30*time.Second},
+			}, cm["steady"].GetExecutionRequirements(et))
+		}},
+	},
+	{`{"steady": {"executor": "closed-loop", "mode": "vus", "duration": "1m", "metric": "http_reqs", "setpoint": "rate=500/s", "min": 5, "max": 50}}`, exp{}},
+	{`{"steady": {"executor": "closed-loop", "mode": "vus", "duration": "1m", "metric": "http_reqs", "setpoint": "rate>500", "max": 50}}`, exp{validationError: true}},
+	{`{"steady": {"executor": "closed-loop", "mode": "vus", "duration": "1m", "setpoint": "rate=500/s", "max": 50}}`, exp{validationError: true}},
+	{`{"steady": {"executor": "closed-loop", "mode": "vus", "duration": "1m", "metric": "http_reqs", "setpoint": "rate=500/s", "min": 60, "max": 50}}`, exp{validationError: true}},
+	{`{"steady": {"executor": "closed-loop", "mode": "vus", "duration": "1m", "metric": "http_reqs", "setpoint": "rate=500/s", "max": 50, "gain": 1}}`, exp{parseError: true}},
 	// TODO: more tests of mixed executors and execution plans
 
+	// scenario dependencies
+	{
+		`{"warmup": {"executor": "constant-vus", "vus": 5, "duration": "1m", "gracefulStop": "10s"},
+		"smoke": {"executor": "per-vu-iterations", "vus": 2, "iterations": 5, "maxDuration": "30s", "gracefulStop": "0s"},
+		"load": {"executor": "constant-vus", "vus": 20, "duration": "5m", "startAfter": ["warmup", "smoke"], "startTime": "10s"},
+		"spike": {"executor": "constant-vus", "vus": 50, "duration": "1m", "startAfter": ["warmup", "smoke"], "startWhen": "any"},
+		"cooldown": {"executor": "constant-vus", "vus": 5, "duration": "1m", "startAfter": ["load"], "gracefulStop": "0s"}}`,
+		exp{custom: func(t *testing.T, cm lib.ScenarioConfigs) {
+			assert.Equal(t, []string{"warmup", "smoke"}, cm["load"].GetStartAfter())
+			assert.Equal(t, lib.StartWhenAll, cm["load"].GetStartWhen())
+			assert.Equal(t, lib.StartWhenAny, cm["spike"].GetStartWhen())
+
+			et, err := lib.NewExecutionTuple(nil, nil)
+			require.NoError(t, err)
+			assert.Equal(t, "20 looping VUs for 5m0s (startAfter: warmup and smoke, startTime: 10s, gracefulStop: 30s)",
+				cm["load"].GetDescription(et))
+			assert.Equal(t, "50 looping VUs for 1m0s (startAfter: warmup or smoke, gracefulStop: 30s)",
+				cm["spike"].GetDescription(et))
+
+			assert.Equal(t, map[string]time.Duration{
+				"warmup":   0,
+				"smoke":    0,
+				"load":     80 * time.Second,
+				"spike":    30 * time.Second,
+				"cooldown": 80*time.Second + 5*time.Minute + 30*time.Second,
+			}, cm.GetStartOffsets(et))
+
+			var names []string
+			for _, config := range cm.GetSortedConfigs() {
+				names = append(names, config.GetName())
+			}
+			assert.Equal(t, []string{"smoke", "warmup", "spike", "load", "cooldown"}, names)
+
+			// the scenarios starting after other ones can start as soon as 10s
+			// in, if the ones they start after are done early, so their VUs are
+			// planned from their earliest start until their planned end
+			assert.Equal(t, []lib.ExecutionStep{
+				{TimeOffset: 0, PlannedVUs: 2},
+				{TimeOffset: 0, PlannedVUs: 7},
+				{TimeOffset: 0, PlannedVUs: 57},
+				{TimeOffset: 10 * time.Second, PlannedVUs: 77},
+				{TimeOffset: 10 * time.Second, PlannedVUs: 82},
+				{TimeOffset: 30 * time.Second, PlannedVUs: 80},
+				{TimeOffset: 70 * time.Second, PlannedVUs: 75},
+				{TimeOffset: 120 * time.Second, PlannedVUs: 25},
+				{TimeOffset: 410 * time.Second, PlannedVUs: 5},
+				{TimeOffset: 470 * time.Second, PlannedVUs: 0},
+			}, cm.GetFullExecutionRequirements(et))
+		}},
+	},
+	{`{"load": {"executor": "constant-vus", "vus": 20, "duration": "5m", "startAfter": ["warmup"]}}`, exp{validationError: true}},
+	{`{"load": {"executor": "constant-vus", "vus": 20, "duration": "5m", "startAfter": ["load"]}}`, exp{validationError: true}},
+	{`{"load": {"executor": "constant-vus", "vus": 20, "duration": "5m", "startWhen": "any"}}`, exp{validationError: true}},
+	{`{"load": {"executor": "constant-vus", "vus": 20, "duration": "5m", "startAfter": "warmup"}}`, exp{parseError: true}},
+	{
+		`{"warmup": {"executor": "constant-vus", "vus": 5, "duration": "1m"},
+		"load": {"executor": "constant-vus", "vus": 20, "duration": "5m", "startAfter": ["warmup"], "startWhen": "first"}}`,
+		exp{validationError: true},
+	},
+	{
+		`{"a": {"executor": "constant-vus", "vus": 5, "duration": "1m", "startAfter": ["c"]},
+		"b": {"executor": "constant-vus", "vus": 5, "duration": "1m", "startAfter": ["a"]},
+		"c": {"executor": "constant-vus", "vus": 5, "duration": "1m", "startAfter": ["b"]}}`,
+		exp{validationError: true, custom: func(t *testing.T, cm lib.ScenarioConfigs) {
+			errs := cm.Validate()
+			require.Len(t, errs, 1)
+			assert.Equal(t, "scenarios can't start after each other: a -> c -> b -> a", errs[0].Error())
+		}},
+	},
+
+	// weighted exec mix
+	{
+		`{"mixed": {"executor": "constant-arrival-rate", "rate": 10, "duration": "1m", "preAllocatedVUs": 5,
+		"execMix": {"browse": 70, "search": 20, "checkout": 10}}}`,
+		exp{custom: func(t *testing.T, cm lib.ScenarioConfigs) {
+			assert.Equal(t, "browse", cm["mixed"].GetExec())
+			assert.Equal(t, []string{"checkout", "search"}, cm["mixed"].(lib.MultiExecExecutorConfig).GetExecs())
+
+			et, err := lib.NewExecutionTuple(nil, nil)
+			require.NoError(t, err)
+			assert.Equal(t, "10.00 iterations/s for 1m0s (maxVUs: 5, execMix: browse 70 / checkout 10 / search 20, "+
+				"gracefulStop: 30s)", cm["mixed"].GetDescription(et))
+		}},
+	},
+	{`{"mixed": {"executor": "constant-vus", "duration": "1m", "execMix": {"browse": 70, "search": -1}}}`, exp{validationError: true}},
+	{`{"mixed": {"executor": "constant-vus", "duration": "1m", "exec": "browse", "execMix": {"browse": 1}}}`, exp{validationError: true}},
+	{`{"mixed": {"executor": "constant-vus", "duration": "1m", "execMixSeed": 42}}`, exp{parseError: true}},
+	{`{"mixed": {"executor": "constant-vus", "duration": "1m", "execMix": ["browse", "search"]}}`, exp{parseError: true}},
+
 	// scenario options
 	{
 		`{"ui": {"executor": "shared-iterations", "iterations": 22, "vus": 12, "maxDuration": "100s", "options": {"browser": {"someBrowserOption": true}}}}`,
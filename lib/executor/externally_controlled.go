@@ -155,6 +155,7 @@ func (mec ExternallyControlledConfig) NewExecutor(es *lib.ExecutionState, logger
 		newControlConfigs:    make(chan updateConfigEvent),
 		pauseEvents:          make(chan pauseEvent),
 		hasStarted:           make(chan struct{}),
+		hasFinished:          make(chan struct{}),
 	}, nil
 }
 
@@ -185,6 +186,7 @@ type ExternallyControlled struct {
 	newControlConfigs    chan updateConfigEvent
 	pauseEvents          chan pauseEvent
 	hasStarted           chan struct{}
+	hasFinished          chan struct{} // closed when Run returns, so nothing receives the events anymore
 }
 
 // Make sure we implement all the interfaces
@@ -192,6 +194,8 @@ var (
 	_ lib.Executor              = &ExternallyControlled{}
 	_ lib.PausableExecutor      = &ExternallyControlled{}
 	_ lib.LiveUpdatableExecutor = &ExternallyControlled{}
+
+	_ lib.ScenarioControllableExecutor = &ExternallyControlled{}
 )
 
 // GetCurrentConfig just returns the executor's current configuration.
@@ -231,21 +235,45 @@ func (mex ExternallyControlled) Init(_ context.Context) error {
 
 // SetPaused pauses or resumes the executor.
 func (mex *ExternallyControlled) SetPaused(paused bool) error {
+	if mex.IsScenarioStopped() {
+		return errScenarioStopped
+	}
 	select {
 	case <-mex.hasStarted:
 		event := pauseEvent{isPaused: paused, err: make(chan error)}
-		mex.pauseEvents <- event
-		return <-event.err
+		select {
+		case mex.pauseEvents <- event:
+			return <-event.err
+		case <-mex.control.stopped:
+			return errScenarioStopped
+		case <-mex.hasFinished:
+			return fmt.Errorf("the externally controlled executor has already finished")
+		}
 	default:
 		return fmt.Errorf("cannot pause the externally controlled executor before it has started")
 	}
 }
 
+// SetScenarioPaused pauses or resumes the scenario of the executor, like
+// SetPaused does.
+func (mex *ExternallyControlled) SetScenarioPaused(paused bool) error {
+	if mex.IsScenarioStopped() {
+		return errScenarioStopped
+	}
+	if err := mex.SetPaused(paused); err != nil {
+		return err
+	}
+	return mex.BaseExecutor.SetScenarioPaused(paused)
+}
+
 // UpdateConfig validates the supplied config and updates it in real time. It is
 // possible to update the configuration even when k6 is paused, either in the
 // beginning (i.e. when running k6 with --paused) or in the middle of the script
 // execution.
 func (mex *ExternallyControlled) UpdateConfig(ctx context.Context, newConf interface{}) error {
+	if mex.IsScenarioStopped() {
+		return errScenarioStopped
+	}
 	newConfigParams, ok := newConf.(ExternallyControlledConfigParams)
 	if !ok {
 		return errors.New("invalid config type")
@@ -273,8 +301,16 @@ func (mex *ExternallyControlled) UpdateConfig(ctx context.Context, newConf inter
 	case <-mex.hasStarted:
 		mex.configLock.Unlock()
 		event := updateConfigEvent{newConfig: newConfigParams, err: make(chan error)}
-		mex.newControlConfigs <- event
-		return <-event.err
+		select {
+		case mex.newControlConfigs <- event:
+			return <-event.err
+		case <-mex.control.stopped:
+			return errScenarioStopped
+		case <-mex.hasFinished:
+			return fmt.Errorf("the externally controlled executor has already finished")
+		case <-ctx.Done():
+			return ctx.Err()
+		}
 	case <-ctx.Done():
 		mex.configLock.Unlock()
 		return ctx.Err()
@@ -488,6 +524,7 @@ func (mex *ExternallyControlled) Run(parentCtx context.Context, _ chan<- metrics
 	currentControlConfig := mex.currentControlConfig
 	close(mex.hasStarted)
 	mex.configLock.RUnlock()
+	defer close(mex.hasFinished)
 
 	ctx, cancel := context.WithCancel(parentCtx)
 	waitOnProgressChannel := make(chan struct{})
@@ -523,7 +560,7 @@ func (mex *ExternallyControlled) Run(parentCtx context.Context, _ chan<- metrics
 		currentlyPaused: false,
 		activeVUsCount:  new(int64),
 		maxVUs:          new(int64),
-		runIteration:    getIterationRunner(mex.executionState, mex.logger),
+		runIteration:    getIterationRunner(mex.executionState, mex.logger, mex.execMix),
 	}
 	ss.ProgressFn = runState.progressFn
 
@@ -553,6 +590,17 @@ func (mex *ExternallyControlled) Run(parentCtx context.Context, _ chan<- metrics
 		select {
 		case <-ctx.Done():
 			return nil
+		case <-mex.control.stopped:
+			// the iterations in progress are finished, like when pausing,
+			// before the VUs are released
+			activeVUs := currentControlConfig.VUs.Int64
+			for i := int64(0); i < activeVUs; i++ {
+				runState.vuHandles[i].gracefulStop()
+			}
+			for i := int64(0); i < activeVUs; i++ {
+				runState.vuHandles[i].wg.Wait()
+			}
+			return nil
 		case updateConfigEvent := <-mex.newControlConfigs:
 			err := runState.handleConfigChange(currentControlConfig, updateConfigEvent.newConfig) //nolint:contextcheck
 			if err != nil {
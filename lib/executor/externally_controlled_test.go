@@ -107,3 +107,38 @@ func TestExternallyControlledRun(t *testing.T) {
 	assert.InDelta(t, 48, int(atomic.LoadUint64(doneIters)), 2)
 	assert.Equal(t, [][]int64{{2, 10}, {4, 10}, {8, 20}, {4, 10}, {0, 10}}, resultVUCount)
 }
+
+func TestExternallyControlledStopScenario(t *testing.T) {
+	t.Parallel()
+
+	startedIters, doneIters := new(uint64), new(uint64)
+	runner := simpleRunner(func(ctx context.Context, _ *lib.State) error {
+		atomic.AddUint64(startedIters, 1)
+		select {
+		case <-time.After(300 * time.Millisecond):
+			atomic.AddUint64(doneIters, 1)
+		case <-ctx.Done():
+		}
+		return nil
+	})
+
+	test := setupExecutorTest(t, "", "", lib.Options{}, runner, getTestExternallyControlledConfig())
+	defer test.cancel()
+	mex := test.executor.(*ExternallyControlled) //nolint:forcetypeassert
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- mex.Run(test.ctx, nil) }()
+	time.Sleep(100 * time.Millisecond)
+
+	mex.StopScenario()
+	require.NoError(t, <-errCh)
+	// the iterations in progress weren't interrupted
+	assert.Equal(t, uint64(2), atomic.LoadUint64(startedIters))
+	assert.Equal(t, uint64(2), atomic.LoadUint64(doneIters))
+
+	// nothing receives the events anymore, so these don't block
+	assert.Error(t, mex.SetPaused(true))
+	assert.Error(t, mex.SetScenarioPaused(false))
+	assert.Error(t, mex.UpdateConfig(test.ctx, getTestExternallyControlledConfig().ExternallyControlledConfigParams))
+	assert.False(t, mex.IsScenarioPaused())
+}
@@ -2,9 +2,12 @@ package executor
 
 import (
 	"context"
+	"encoding/binary"
 	"errors"
 	"fmt"
+	"hash/fnv"
 	"math/big"
+	"math/rand"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -98,13 +101,14 @@ func handleInterrupt(ctx context.Context, err error) bool {
 
 // getIterationRunner is a helper function that returns an iteration executor
 // closure. It takes care of updating the execution state statistics and
-// warning messages. And returns whether a full iteration was finished or not
+// warning messages. And returns whether a full iteration was finished or not.
+// The iterations run the functions picked by the mix, if it isn't nil.
 //
 // TODO: emit the end-of-test iteration metrics here (https://github.com/k6io/k6/issues/1250)
 func getIterationRunner(
-	executionState *lib.ExecutionState, logger *logrus.Entry,
+	executionState *lib.ExecutionState, logger *logrus.Entry, mix *execMix,
 ) func(context.Context, lib.ActiveVU) bool {
-	return func(ctx context.Context, vu lib.ActiveVU) bool {
+	return withExecMix(func(ctx context.Context, vu lib.ActiveVU) bool {
 		err := vu.RunOnce()
 
 		// TODO: track (non-ramp-down) errors from script iterations as a metric,
@@ -137,46 +141,7 @@ func getIterationRunner(
 			executionState.AddFullIterations(1)
 			return true
 		}
-	}
-}
-
-// getDurationContexts is used to create sub-contexts that can restrict an
-// executor to only run for its allotted time.
-//
-// If the executor doesn't have a graceful stop period for iterations, then
-// both returned sub-contexts will be the same one, with a timeout equal to
-// the supplied regular executor duration.
-//
-// But if a graceful stop is enabled, then the first returned context (and the
-// cancel func) will be for the "outer" sub-context. Its timeout will include
-// both the regular duration and the specified graceful stop period. The second
-// context will be a sub-context of the first one and its timeout will include
-// only the regular duration.
-//
-// In either case, the usage of these contexts should be like this:
-//   - As long as the regDurationCtx isn't done, new iterations can be started.
-//   - After regDurationCtx is done, no new iterations should be started; every
-//     VU that finishes an iteration from now on can be returned to the buffer
-//     pool in the ExecutionState struct.
-//   - After maxDurationCtx is done, any VUs with iterations will be
-//     interrupted by the context's closing and will be returned to the buffer.
-//   - If you want to interrupt the execution of all VUs prematurely (e.g. there
-//     was an error or something like that), trigger maxDurationCancel().
-//   - If the whole test is aborted, the parent context will be cancelled, so
-//     that will also cancel these contexts, thus the "general abort" case is
-//     handled transparently.
-func getDurationContexts(parentCtx context.Context, regularDuration, gracefulStop time.Duration) (
-	startTime time.Time, maxDurationCtx, regDurationCtx context.Context, maxDurationCancel func(),
-) {
-	startTime = time.Now()
-	maxEndTime := startTime.Add(regularDuration + gracefulStop)
-
-	maxDurationCtx, maxDurationCancel = context.WithDeadline(parentCtx, maxEndTime)
-	if gracefulStop == 0 {
-		return startTime, maxDurationCtx, maxDurationCtx, maxDurationCancel
-	}
-	regDurationCtx, _ = context.WithDeadline(maxDurationCtx, startTime.Add(regularDuration)) //nolint:govet
-	return startTime, maxDurationCtx, regDurationCtx, maxDurationCancel
+	}, mix)
 }
 
 // trackProgress is a helper function that monitors certain end-events in an
@@ -262,3 +227,20 @@ func getVUActivationParams(
 		GetNextIterationCounters: nextIterationCounters,
 	}
 }
+
+// getRandomSeed returns the seed of the random choices of a scenario, e.g. its
+// arrivals, derived from the randomSeed option, the name of the scenario and
+// the purpose of the choices, so all the instances of a test make the same
+// choices while the scenarios and their purposes make different ones. It's
+// random when randomSeed isn't set.
+func getRandomSeed(options lib.Options, scenario, purpose string) int64 {
+	if !options.RandomSeed.Valid {
+		return rand.Int63() //nolint:gosec
+	}
+	h := fnv.New64a()
+	_ = binary.Write(h, binary.LittleEndian, options.RandomSeed.Int64)
+	_, _ = h.Write([]byte(scenario))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(purpose))
+	return int64(h.Sum64())
+}
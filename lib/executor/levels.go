@@ -0,0 +1,229 @@
+package executor
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/liuxd6825/k6server/lib"
+	"github.com/liuxd6825/k6server/metrics"
+)
+
+// levelsRun runs the iterations of the executors whose load is a level which
+// changes while they run, e.g. the breaking-point one, either as a rate of
+// iterations or as a number of looping VUs.
+type levelsRun struct {
+	executor *BaseExecutor
+	config   *BaseConfig
+	level    *int64 // the current level, accessed atomically
+
+	parentCtx, maxDurationCtx, regDurationCtx context.Context
+	cancel                                    func()
+	out                                       chan<- metrics.SampleContainer
+}
+
+// runVUs runs the iterations on as many looping VUs as the level, scaled to
+// the execution segment, up to maxVUs. The levels are set by control, and the
+// VUs are gracefully stopped when it returns.
+func (lr levelsRun) runVUs(maxVUs int64, control func(setLevel func(int64))) error {
+	bs := lr.executor
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	getVU := func() (lib.InitializedVU, error) {
+		pvu, err := bs.executionState.GetPlannedVU(bs.logger, false)
+		if err != nil {
+			bs.logger.WithError(err).Error("Cannot get a VU from the buffer")
+			lr.cancel()
+			return pvu, err
+		}
+		wg.Add(1)
+		bs.executionState.ModCurrentlyActiveVUsCount(+1)
+		return pvu, err
+	}
+	returnVU := func(initVU lib.InitializedVU) {
+		bs.executionState.ReturnVU(initVU, false)
+		wg.Done()
+		bs.executionState.ModCurrentlyActiveVUsCount(-1)
+	}
+	runIteration := bs.control.pausable(
+		getIterationRunner(bs.executionState, bs.logger, bs.execMix), lr.regDurationCtx.Done())
+
+	vuHandles := make([]*vuHandle, maxVUs)
+	for i := range vuHandles {
+		vuHandles[i] = newStoppedVUHandle(
+			lr.maxDurationCtx, getVU, returnVU, bs.nextIterationCounters,
+			lr.config, bs.logger.WithField("vuNum", i))
+		go vuHandles[i].runLoopsIfPossible(runIteration) //nolint:contextcheck
+	}
+
+	var cur int64 // the current number of looping VUs
+	setLevel := func(l int64) {
+		atomic.StoreInt64(lr.level, l)
+		vus := bs.executionState.ExecutionTuple.ScaleInt64(l)
+		if vus > maxVUs {
+			vus = maxVUs
+		}
+		for ; cur < vus; cur++ {
+			_ = vuHandles[cur].start() // TODO: handle the error
+		}
+		for ; vus < cur; cur-- {
+			vuHandles[cur-1].gracefulStop()
+		}
+	}
+
+	control(setLevel)
+	setLevel(0)
+	return nil
+}
+
+// runArrivalRate starts the iterations at the rate of the level per timeUnit,
+// scaled to the execution segment of et, on preAllocatedVUs and up to maxVUs.
+// The levels are set by control, which is run in its own goroutine, and the
+// iterations are started until the end of the regular duration.
+//
+//nolint:funlen
+func (lr levelsRun) runArrivalRate(
+	et *lib.ExecutionTuple, timeUnit time.Duration, preAllocatedVUs, maxVUs int64,
+	control func(setLevel func(int64)),
+) error {
+	bs := lr.executor
+	activeVUsWg := &sync.WaitGroup{}
+
+	returnedVUs := make(chan struct{})
+	vusPool := newActiveVUPool(bs.executionState)
+	defer func() {
+		// Make sure all VUs aren't executing iterations anymore, for the lr.cancel()
+		// below to deactivate them.
+		<-returnedVUs
+		// first close the vusPool so we wait for the gracefulShutdown
+		vusPool.Close()
+		lr.cancel()
+		activeVUsWg.Wait()
+	}()
+
+	returnVU := func(u lib.InitializedVU) {
+		// Return the VU without decreasing the global active VU counter, which
+		// is done in the goroutine started by activeVUPool.AddVU, whenever the
+		// VU finishes running an iteration. This results in a more accurate
+		// report of VUs that are _actually_ active.
+		bs.executionState.ReturnVU(u, false)
+		activeVUsWg.Done()
+	}
+
+	runIterationBasic := getIterationRunner(bs.executionState, bs.logger, bs.execMix)
+	activateVU := func(initVU lib.InitializedVU) lib.ActiveVU {
+		activeVUsWg.Add(1)
+		activeVU := initVU.Activate(getVUActivationParams(
+			lr.maxDurationCtx, *lr.config, returnVU,
+			bs.nextIterationCounters,
+		))
+		vusPool.AddVU(lr.maxDurationCtx, activeVU, runIterationBasic)
+		return activeVU
+	}
+
+	remainingUnplannedVUs := maxVUs - preAllocatedVUs
+	makeUnplannedVUCh := make(chan struct{})
+	defer close(makeUnplannedVUCh)
+	go func() {
+		defer close(returnedVUs)
+		for range makeUnplannedVUCh {
+			bs.logger.Debug("Starting initialization of an unplanned VU...")
+			initVU, err := bs.executionState.GetUnplannedVU(lr.maxDurationCtx, bs.logger)
+			if err != nil {
+				// TODO figure out how to return it to the Run goroutine
+				bs.logger.WithError(err).Error("Error while allocating unplanned VU")
+			} else {
+				bs.logger.Debug("The unplanned VU finished initializing successfully!")
+				activateVU(initVU)
+			}
+		}
+	}()
+
+	// Get the pre-allocated VUs in the local buffer
+	for i := int64(0); i < preAllocatedVUs; i++ {
+		initVU, err := bs.executionState.GetPlannedVU(bs.logger, false)
+		if err != nil {
+			return err
+		}
+		activateVU(initVU)
+	}
+
+	droppedIterationMetric := bs.executionState.Test.BuiltinMetrics.DroppedIterations
+	shownWarning := false
+	metricTags := bs.getMetricTags(nil)
+	startIterations := func() {
+		// the iterations are skipped while the scenario is paused, and they're
+		// multiplied by the rate multiplier
+		for n := bs.control.iterationsToStart(); n > 0; n-- {
+			if vusPool.TryRunIteration() {
+				continue
+			}
+
+			// Since there aren't any free VUs available, consider this iteration
+			// dropped - we aren't going to try to recover it, but
+
+			metrics.PushIfNotDone(lr.parentCtx, lr.out, metrics.Sample{
+				TimeSeries: metrics.TimeSeries{
+					Metric: droppedIterationMetric,
+					Tags:   metricTags,
+				},
+				Time:  time.Now(),
+				Value: 1,
+			})
+
+			// We'll try to start allocating another VU in the background,
+			// non-blockingly, if we have remainingUnplannedVUs...
+			if remainingUnplannedVUs == 0 {
+				if !shownWarning {
+					bs.logger.Warningf("Insufficient VUs, reached %d active VUs and cannot initialize more", maxVUs)
+					shownWarning = true
+				}
+				continue
+			}
+
+			select {
+			case makeUnplannedVUCh <- struct{}{}: // great!
+				remainingUnplannedVUs--
+			default: // we're already allocating a new VU
+			}
+		}
+	}
+
+	// the levels are sent by control to the loop starting the iterations
+	levels := make(chan int64)
+	controlDone := make(chan struct{})
+	go func() {
+		defer close(controlDone)
+		control(func(l int64) {
+			atomic.StoreInt64(lr.level, l)
+			select {
+			case levels <- l:
+			case <-lr.regDurationCtx.Done():
+			}
+		})
+	}()
+	defer func() { <-controlDone }()
+
+	ticker := time.NewTicker(time.Hour)
+	ticker.Stop()
+	defer ticker.Stop()
+	for {
+		select {
+		case l := <-levels:
+			rate := getScaledArrivalRate(et.Segment, l, timeUnit)
+			period := getTickerPeriod(rate)
+			if !period.Valid {
+				ticker.Stop()
+				continue
+			}
+			ticker.Reset(period.TimeDuration())
+			startIterations()
+		case <-ticker.C:
+			startIterations()
+		case <-lr.regDurationCtx.Done():
+			return nil
+		}
+	}
+}
@@ -2,6 +2,7 @@ package executor
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math"
 	"sync"
@@ -158,7 +159,12 @@ type RampingArrivalRate struct {
 }
 
 // Make sure we implement the lib.Executor interface.
-var _ lib.Executor = &RampingArrivalRate{}
+var (
+	_ lib.Executor                     = &RampingArrivalRate{}
+	_ lib.ScenarioControllableExecutor = &RampingArrivalRate{}
+	_ lib.RateMultipliableExecutor     = &RampingArrivalRate{}
+	_ StagesUpdatableExecutor          = &RampingArrivalRate{}
+)
 
 // Init values needed for the execution
 func (varr *RampingArrivalRate) Init(_ context.Context) error {
@@ -171,6 +177,29 @@ func (varr *RampingArrivalRate) Init(_ context.Context) error {
 	return err //nolint:wrapcheck
 }
 
+// GetRateMultiplier returns the multiplier of the rate of the stages.
+func (varr RampingArrivalRate) GetRateMultiplier() float64 {
+	return varr.control.getRateMultiplier()
+}
+
+// SetRateMultiplier multiplies the rate of the stages while the scenario is
+// running, e.g. 2 doubles it and 0.5 halves it.
+func (varr RampingArrivalRate) SetRateMultiplier(multiplier float64) error {
+	return varr.control.setRateMultiplier(multiplier)
+}
+
+// GetStages returns all the stages of the scenario, including the ones added
+// while it's running.
+func (varr RampingArrivalRate) GetStages() []Stage {
+	return varr.control.currentStages(varr.config.Stages)
+}
+
+// UpdateStages appends the stages to the ones of the running scenario, or
+// replaces the remaining ones with them, starting from the current rate.
+func (varr RampingArrivalRate) UpdateStages(stages []Stage, replace bool) error {
+	return varr.control.updateStages(stages, replace)
+}
+
 // cal calculates the  transtitions between stages and gives the next full value produced by the
 // stages. In this explanation we are talking about events and in practice those events are starting
 // of an iteration, but could really be anything that needs to occur at a constant or linear rate.
@@ -232,6 +261,12 @@ func (varr *RampingArrivalRate) Init(_ context.Context) error {
 // the striping algorithm from the lib.ExecutionTuple for additional speed up but this could
 // possibly be refactored if need for this arises.
 func (varc RampingArrivalRateConfig) cal(et *lib.ExecutionTuple, ch chan<- time.Duration) {
+	varc.calUntil(et, ch, nil)
+}
+
+// calUntil is like cal, but it stops calculating the times when done is closed,
+// e.g. because the stages were updated and the times should be recalculated.
+func (varc RampingArrivalRateConfig) calUntil(et *lib.ExecutionTuple, ch chan<- time.Duration, done <-chan struct{}) {
 	start, offsets, _ := et.GetStripedOffsets()
 	li := -1
 	// TODO: move this to a utility function, or directly what GetStripedOffsets uses once we see everywhere we will use it
@@ -259,12 +294,20 @@ func (varc RampingArrivalRateConfig) cal(et *lib.ExecutionTuple, ch chan<- time.
 				// somewhere where it is less in the middle of the equation
 				x := (from*dur - noNegativeSqrt(dur*(from*from*dur+2*(i-doneSoFar)*(to-from)))) / (from - to)
 
-				ch <- time.Duration(x) + stageStart
+				select {
+				case ch <- time.Duration(x) + stageStart:
+				case <-done:
+					return
+				}
 			}
 		} else {
 			endCount += dur * to
 			for ; i <= endCount; i += float64(next()) {
-				ch <- time.Duration((i-doneSoFar)/to) + stageStart
+				select {
+				case ch <- time.Duration((i-doneSoFar)/to) + stageStart:
+				case <-done:
+					return
+				}
 			}
 		}
 		doneSoFar = endCount
@@ -326,7 +369,8 @@ func (varr RampingArrivalRate) Run(parentCtx context.Context, out chan<- metrics
 
 	returnedVUs := make(chan struct{})
 	waitOnProgressChannel := make(chan struct{})
-	startTime, maxDurationCtx, regDurationCtx, cancel := getDurationContexts(parentCtx, duration, gracefulStop)
+	startTime, maxDurationCtx, regDurationCtx, cancel := varr.getDurationContexts(parentCtx, duration, gracefulStop)
+	durations := varr.control.getDurations()
 
 	vusPool := newActiveVUPool(varr.executionState)
 
@@ -354,10 +398,11 @@ func (varr RampingArrivalRate) Run(parentCtx context.Context, out chan<- metrics
 
 		itersPerSec := 0.0
 		if currentTickerPeriod > 0 {
-			itersPerSec = float64(time.Second) / float64(currentTickerPeriod)
+			itersPerSec = float64(time.Second) / float64(currentTickerPeriod) * varr.control.getRateMultiplier()
 		}
 		progIters := fmt.Sprintf(itersFmt, itersPerSec)
 
+		duration := durations.getRegularDuration()
 		right := []string{progVUs, duration.String(), progIters}
 
 		spent := time.Since(startTime)
@@ -393,7 +438,7 @@ func (varr RampingArrivalRate) Run(parentCtx context.Context, out chan<- metrics
 		activeVUsWg.Done()
 	}
 
-	runIterationBasic := getIterationRunner(varr.executionState, varr.logger)
+	runIterationBasic := getIterationRunner(varr.executionState, varr.logger, varr.execMix)
 
 	activateVU := func(initVU lib.InitializedVU) lib.ActiveVU {
 		activeVUsWg.Add(1)
@@ -438,17 +483,58 @@ func (varr RampingArrivalRate) Run(parentCtx context.Context, out chan<- metrics
 	regDurationDone := regDurationCtx.Done()
 	timer := time.NewTimer(time.Hour)
 	start := time.Now()
-	ch := make(chan time.Duration, 10) // buffer 10 iteration times ahead
-	var prevTime time.Duration
+	var prevTime, scheduleOffset time.Duration
 	shownWarning := false
 	metricTags := varr.getMetricTags(nil)
-	go varr.config.cal(varr.et, ch)
-	for nextTime := range ch {
+
+	stages := varr.config.Stages
+	varr.control.setStages(stages)
+	ch := make(chan time.Duration, 10) // buffer 10 iteration times ahead
+	stopCal := make(chan struct{})
+	defer func() { close(stopCal) }()
+	go varr.config.calUntil(varr.et, ch, stopCal)
+
+	// updateStages recalculates the times of the iterations for the remaining
+	// stages, starting from the rate reached so far
+	updateStages := func(update stagesUpdate) error {
+		elapsed := time.Since(startTime)
+		all := updatedStages(varr.config.StartRate.Int64, stages, update, elapsed)
+		_, remaining := splitStages(varr.config.StartRate.Int64, all, elapsed)
+		if !durations.setRegularDuration(elapsed + sumStagesDuration(remaining)) {
+			return errors.New("the regular duration of the scenario is over")
+		}
+
+		stages = all
+		varr.control.setStages(all)
+		close(stopCal)
+		stopCal = make(chan struct{})
+		ch = make(chan time.Duration, 10)
+		config := varr.config
+		config.StartRate = null.IntFrom(stagesValueAt(varr.config.StartRate.Int64, all, elapsed))
+		config.Stages = remaining
+		scheduleOffset, prevTime = time.Since(start), time.Since(start)
+		go config.calUntil(varr.et, ch, stopCal)
+
+		varr.logger.WithField("stages", len(all)).Debug("Updated the stages of the scenario")
+		return nil
+	}
+
+	for {
+		var nextTime time.Duration
 		select {
+		case t, ok := <-ch:
+			if !ok {
+				ch = nil // wait for the end of the regular duration or for more stages
+				continue
+			}
+			nextTime = t + scheduleOffset
+		case update := <-varr.control.stagesUpdates:
+			update.err <- updateStages(update)
+			continue
 		case <-regDurationDone:
 			return nil
-		default:
 		}
+
 		atomic.StoreInt64(&tickerPeriod, int64(nextTime-prevTime))
 		prevTime = nextTime
 		b := time.Until(start.Add(nextTime))
@@ -456,49 +542,58 @@ func (varr RampingArrivalRate) Run(parentCtx context.Context, out chan<- metrics
 			timer.Reset(b)
 			select {
 			case <-timer.C:
+			case update := <-varr.control.stagesUpdates:
+				if !timer.Stop() {
+					<-timer.C
+				}
+				update.err <- updateStages(update)
+				continue // the times of the iterations were recalculated
 			case <-regDurationDone:
 				return nil
 			}
 		}
 
-		if vusPool.TryRunIteration() {
-			continue
-		}
+		// the scheduled iteration is skipped while the scenario is paused, and
+		// it's multiplied by the rate multiplier
+		for n := varr.control.iterationsToStart(); n > 0; n-- {
+			if vusPool.TryRunIteration() {
+				continue
+			}
 
-		// Since there aren't any free VUs available, consider this iteration
-		// dropped - we aren't going to try to recover it, but
-		metrics.PushIfNotDone(parentCtx, out, metrics.Sample{
-			TimeSeries: metrics.TimeSeries{
-				Metric: varr.executionState.Test.BuiltinMetrics.DroppedIterations,
-				Tags:   metricTags,
-			},
-			Time:  time.Now(),
-			Value: 1,
-		})
-
-		// We'll try to start allocating another VU in the background,
-		// non-blockingly, if we have remainingUnplannedVUs...
-		if remainingUnplannedVUs == 0 {
-			if !shownWarning {
-				varr.logger.Warningf("Insufficient VUs, reached %d active VUs and cannot initialize more", maxVUs)
-				shownWarning = true
+			// Since there aren't any free VUs available, consider this iteration
+			// dropped - we aren't going to try to recover it, but
+			metrics.PushIfNotDone(parentCtx, out, metrics.Sample{
+				TimeSeries: metrics.TimeSeries{
+					Metric: varr.executionState.Test.BuiltinMetrics.DroppedIterations,
+					Tags:   metricTags,
+				},
+				Time:  time.Now(),
+				Value: 1,
+			})
+
+			// We'll try to start allocating another VU in the background,
+			// non-blockingly, if we have remainingUnplannedVUs...
+			if remainingUnplannedVUs == 0 {
+				if !shownWarning {
+					varr.logger.Warningf("Insufficient VUs, reached %d active VUs and cannot initialize more", maxVUs)
+					shownWarning = true
+				}
+				continue
 			}
-			continue
-		}
 
-		select {
-		case makeUnplannedVUCh <- struct{}{}: // great!
-			remainingUnplannedVUs--
-		default: // we're already allocating a new VU
+			select {
+			case makeUnplannedVUCh <- struct{}{}: // great!
+				remainingUnplannedVUs--
+			default: // we're already allocating a new VU
+			}
 		}
 	}
-	return nil
 }
 
 // activeVUPool controls the activeVUs
 // executing the received requests for iterations.
 type activeVUPool struct {
-	iterations chan struct{}
+	iterations chan lib.IterationOptions
 	running    uint64
 	execState  *lib.ExecutionState
 	wg         sync.WaitGroup
@@ -507,7 +602,7 @@ type activeVUPool struct {
 // newActiveVUPool returns an activeVUPool.
 func newActiveVUPool(es *lib.ExecutionState) *activeVUPool {
 	return &activeVUPool{
-		iterations: make(chan struct{}),
+		iterations: make(chan lib.IterationOptions),
 		execState:  es,
 	}
 }
@@ -516,8 +611,14 @@ func newActiveVUPool(es *lib.ExecutionState) *activeVUPool {
 // When there are no available VUs to process the request
 // then false is returned.
 func (p *activeVUPool) TryRunIteration() bool {
+	return p.TryRunIterationWith(lib.IterationOptions{})
+}
+
+// TryRunIterationWith is like TryRunIteration, but the iteration is run with
+// the given options, by the VUs which support them.
+func (p *activeVUPool) TryRunIterationWith(opts lib.IterationOptions) bool {
 	select {
-	case p.iterations <- struct{}{}:
+	case p.iterations <- opts:
 		return true
 	default:
 		return false
@@ -540,10 +641,10 @@ func (p *activeVUPool) AddVU(ctx context.Context, avu lib.ActiveVU, runfn func(c
 		defer p.wg.Done()
 
 		close(ch)
-		for range p.iterations {
+		for opts := range p.iterations {
 			atomic.AddUint64(&p.running, uint64(1))
 			p.execState.ModCurrentlyActiveVUsCount(+1)
-			runfn(ctx, avu)
+			runfn(ctx, withIterationOptions(avu, opts))
 			p.execState.ModCurrentlyActiveVUsCount(-1)
 			atomic.AddUint64(&p.running, ^uint64(0))
 		}
@@ -557,3 +658,25 @@ func (p *activeVUPool) Close() {
 	close(p.iterations)
 	p.wg.Wait()
 }
+
+// activeVUWithOptions runs the iterations of an active VU with specific options.
+type activeVUWithOptions struct {
+	lib.ActiveVUWithIterationOptions
+	opts lib.IterationOptions
+}
+
+func (u activeVUWithOptions) RunOnce() error {
+	return u.RunOnceWith(u.opts)
+}
+
+// withIterationOptions returns the VU running its next iteration with the
+// options, if they aren't empty and the VU supports them.
+func withIterationOptions(avu lib.ActiveVU, opts lib.IterationOptions) lib.ActiveVU {
+	if opts.Exec == "" && len(opts.Payload) == 0 {
+		return avu
+	}
+	if u, ok := avu.(lib.ActiveVUWithIterationOptions); ok {
+		return activeVUWithOptions{ActiveVUWithIterationOptions: u, opts: opts}
+	}
+	return avu
+}
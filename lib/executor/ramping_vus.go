@@ -2,6 +2,7 @@ package executor
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"sync/atomic"
@@ -472,7 +473,11 @@ type RampingVUs struct {
 }
 
 // Make sure we implement the lib.Executor interface.
-var _ lib.Executor = &RampingVUs{}
+var (
+	_ lib.Executor                     = &RampingVUs{}
+	_ lib.ScenarioControllableExecutor = &RampingVUs{}
+	_ StagesUpdatableExecutor          = &RampingVUs{}
+)
 
 // Init initializes the rampingVUs executor by precalculating the raw
 // and graceful steps.
@@ -486,6 +491,20 @@ func (vlv *RampingVUs) Init(_ context.Context) error {
 	return nil
 }
 
+// GetStages returns all the stages of the scenario, including the ones added
+// while it's running.
+func (vlv *RampingVUs) GetStages() []Stage {
+	return vlv.control.currentStages(vlv.config.Stages)
+}
+
+// UpdateStages appends the stages to the ones of the running scenario, or
+// replaces the remaining ones with them, starting from the current number of
+// VUs. Since the VUs are initialized before the test run, the updated stages
+// can't need more VUs than the original ones.
+func (vlv *RampingVUs) UpdateStages(stages []Stage, replace bool) error {
+	return vlv.control.updateStages(stages, replace)
+}
+
 // Run constantly loops through as many iterations as possible on a variable
 // number of VUs for the specified stages.
 func (vlv *RampingVUs) Run(ctx context.Context, _ chan<- metrics.SampleContainer) error {
@@ -498,7 +517,7 @@ func (vlv *RampingVUs) Run(ctx context.Context, _ chan<- metrics.SampleContainer
 		return fmt.Errorf("%s expected graceful end offset at %s to be final", vlv.config.GetName(), maxDuration)
 	}
 	waitOnProgressChannel := make(chan struct{})
-	startTime, maxDurationCtx, regularDurationCtx, cancel := getDurationContexts(
+	startTime, maxDurationCtx, regularDurationCtx, cancel := vlv.getDurationContexts(
 		ctx, regularDuration, maxDuration-regularDuration,
 	)
 	defer func() {
@@ -516,16 +535,20 @@ func (vlv *RampingVUs) Run(ctx context.Context, _ chan<- metrics.SampleContainer
 		"numStages": len(vlv.config.Stages),
 	}).Debug("Starting executor run...")
 
+	vlv.control.setStages(vlv.config.Stages)
 	runState := &rampingVUsRunState{
 		executor:       vlv,
 		vuHandles:      make([]*vuHandle, maxVUs),
 		maxVUs:         maxVUs,
 		activeVUsCount: new(int64),
 		started:        startTime,
-		runIteration:   getIterationRunner(vlv.executionState, vlv.logger),
+		rawSteps:       vlv.rawSteps,
+		gracefulSteps:  vlv.gracefulSteps,
+		runIteration: vlv.control.pausable(
+			getIterationRunner(vlv.executionState, vlv.logger, vlv.execMix), regularDurationCtx.Done()),
 	}
 
-	progressFn := runState.makeProgressFn(regularDuration)
+	progressFn := runState.makeProgressFn(vlv.control.getDurations().getRegularDuration)
 	maxDurationCtx = lib.WithScenarioState(maxDurationCtx, &lib.ScenarioState{
 		Name:       vlv.config.Name,
 		Executor:   vlv.config.Type,
@@ -547,12 +570,17 @@ func (vlv *RampingVUs) Run(ctx context.Context, _ chan<- metrics.SampleContainer
 		handleNewScheduledVUs  = runState.scheduledVUsHandlerStrategy()
 	)
 	handledGracefulSteps := runState.iterateSteps(
-		ctx,
+		regularDurationCtx,
 		handleNewMaxAllowedVUs,
 		handleNewScheduledVUs,
 	)
+	if regularDurationCtx.Err() != nil {
+		// the regular duration was ended before the last step, e.g. because
+		// the scenario was stopped, so all VUs are gracefully stopped
+		handleNewScheduledVUs(lib.ExecutionStep{PlannedVUs: 0})
+	}
 	go runState.runRemainingGracefulSteps(
-		ctx,
+		maxDurationCtx,
 		handleNewMaxAllowedVUs,
 		handledGracefulSteps,
 	)
@@ -570,14 +598,21 @@ type rampingVUsRunState struct {
 	started        time.Time
 	wg             sync.WaitGroup
 
+	// the steps of the scenario, which are recalculated when its stages are
+	// updated while it's running
+	rawSteps, gracefulSteps []lib.ExecutionStep
+
 	runIteration func(context.Context, lib.ActiveVU) bool // a helper closure function that runs a single iteration
 }
 
-func (rs *rampingVUsRunState) makeProgressFn(regular time.Duration) (progressFn func() (float64, []string)) {
+func (rs *rampingVUsRunState) makeProgressFn(
+	getRegularDuration func() time.Duration,
+) (progressFn func() (float64, []string)) {
 	vusFmt := pb.GetFixedLengthIntFormat(int64(rs.maxVUs))
-	regularDuration := pb.GetFixedLengthDuration(regular, regular)
 
 	return func() (float64, []string) {
+		regular := getRegularDuration()
+		regularDuration := pb.GetFixedLengthDuration(regular, regular)
 		spent := time.Since(rs.started)
 		cur := atomic.LoadInt64(rs.activeVUsCount)
 		progVUs := fmt.Sprintf(vusFmt+"/"+vusFmt+" VUs", cur, rs.maxVUs)
@@ -623,20 +658,26 @@ func (rs *rampingVUsRunState) iterateSteps(
 	ctx context.Context,
 	handleNewMaxAllowedVUs, handleNewScheduledVUs func(lib.ExecutionStep),
 ) (handledGracefulSteps int) {
-	wait := waiter(ctx, rs.started)
+	wait := rs.stepsWaiter(ctx)
 	i, j := 0, 0
-	for i != len(rs.executor.rawSteps) {
-		r, g := rs.executor.rawSteps[i], rs.executor.gracefulSteps[j]
+	for i != len(rs.rawSteps) {
+		r, g := rs.rawSteps[i], rs.gracefulSteps[j]
+		offset := r.TimeOffset
+		if g.TimeOffset < r.TimeOffset {
+			offset = g.TimeOffset
+		}
+		done, updated := wait(offset)
+		if done {
+			break
+		}
+		if updated {
+			i, j = 0, 0 // the steps were recalculated from now on
+			continue
+		}
 		if g.TimeOffset < r.TimeOffset {
-			if wait(g.TimeOffset) {
-				break
-			}
 			handleNewMaxAllowedVUs(g)
 			j++
 		} else {
-			if wait(r.TimeOffset) {
-				break
-			}
 			handleNewScheduledVUs(r)
 			i++
 		}
@@ -644,6 +685,69 @@ func (rs *rampingVUsRunState) iterateSteps(
 	return j
 }
 
+// stepsWaiter is like waiter, but it also stops waiting when the stages of
+// the scenario are updated, after their steps are recalculated.
+func (rs *rampingVUsRunState) stepsWaiter(ctx context.Context) func(offset time.Duration) (done, updated bool) {
+	timer := time.NewTimer(time.Hour * 24)
+	return func(offset time.Duration) (bool, bool) {
+		diff := offset - time.Since(rs.started)
+		if diff <= 0 {
+			return false, false
+		}
+		timer.Reset(diff)
+		select {
+		case <-ctx.Done():
+			return true, false
+		case <-timer.C:
+			return false, false
+		case update := <-rs.executor.control.stagesUpdates:
+			if !timer.Stop() {
+				<-timer.C
+			}
+			update.err <- rs.updateStages(update)
+			return false, true
+		}
+	}
+}
+
+// updateStages recalculates the steps of the scenario after its stages are
+// updated, and keeps only the ones from now on.
+func (rs *rampingVUsRunState) updateStages(update stagesUpdate) error {
+	vlv := rs.executor
+	elapsed := time.Since(rs.started)
+	startVUs := vlv.config.StartVUs.Int64
+	all := updatedStages(startVUs, vlv.control.currentStages(vlv.config.Stages), update, elapsed)
+
+	config := vlv.config
+	config.Stages = all
+	et := vlv.executionState.ExecutionTuple
+	rawSteps := config.getRawExecutionSteps(et, true)
+	gracefulSteps := config.GetExecutionRequirements(et)
+	if maxVUs := lib.GetMaxPlannedVUs(gracefulSteps); maxVUs > rs.maxVUs {
+		return fmt.Errorf("the updated stages need %d VUs, more than the %d initialized ones", maxVUs, rs.maxVUs)
+	}
+	regularDuration, _ := lib.GetEndOffset(rawSteps)
+	if !vlv.control.getDurations().setRegularDuration(regularDuration) {
+		return errors.New("the regular duration of the scenario is over")
+	}
+
+	vlv.control.setStages(all)
+	rs.rawSteps = stepsFrom(rawSteps, elapsed)
+	rs.gracefulSteps = stepsFrom(gracefulSteps, elapsed)
+	vlv.logger.WithField("stages", len(all)).Debug("Updated the stages of the scenario")
+	return nil
+}
+
+// stepsFrom returns the steps from the offset on.
+func stepsFrom(steps []lib.ExecutionStep, offset time.Duration) []lib.ExecutionStep {
+	for i, step := range steps {
+		if step.TimeOffset >= offset {
+			return steps[i:]
+		}
+	}
+	return nil
+}
+
 // runRemainingGracefulSteps runs the remaining gracefulSteps concurrently
 // before the gracefulStop timeout period stops VUs.
 //
@@ -657,7 +761,7 @@ func (rs *rampingVUsRunState) runRemainingGracefulSteps(
 	handledGracefulSteps int,
 ) {
 	wait := waiter(ctx, rs.started)
-	for _, s := range rs.executor.gracefulSteps[handledGracefulSteps:] {
+	for _, s := range rs.gracefulSteps[handledGracefulSteps:] {
 		if wait(s.TimeOffset) {
 			return
 		}
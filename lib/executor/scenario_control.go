@@ -0,0 +1,462 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/liuxd6825/k6server/lib"
+	"github.com/liuxd6825/k6server/lib/types"
+	"gopkg.in/guregu/null.v3"
+)
+
+// StagesUpdatableExecutor should be implemented by the executors whose stages
+// can be appended to or replaced in the middle of the test execution.
+type StagesUpdatableExecutor interface {
+	// GetStages returns all the stages of the scenario, since its start.
+	GetStages() []Stage
+	// UpdateStages appends the stages to the ones of the scenario, or replaces
+	// its stages from now on with them.
+	UpdateStages(stages []Stage, replace bool) error
+}
+
+type stagesUpdate struct {
+	stages  []Stage
+	replace bool
+	err     chan error
+}
+
+// scenarioControl keeps what can be changed in a scenario while it's running,
+// independently of the other scenarios. The executors take it into account in
+// their Run() methods.
+type scenarioControl struct {
+	mu sync.Mutex
+	// resumed is nil while the scenario isn't paused, and it's closed when the
+	// scenario is resumed
+	resumed        chan struct{}
+	stopped        chan struct{}
+	stopOnce       sync.Once
+	rateMultiplier float64
+	rateCredit     float64
+	durations      *scenarioDurations
+	stages         []Stage
+
+	stagesUpdates chan stagesUpdate
+}
+
+func newScenarioControl() *scenarioControl {
+	return &scenarioControl{
+		stopped:        make(chan struct{}),
+		rateMultiplier: 1,
+		stagesUpdates:  make(chan stagesUpdate),
+	}
+}
+
+var errScenarioStopped = errors.New("the scenario is stopped")
+
+// IsScenarioPaused returns whether the scenario of the executor is paused.
+func (bs *BaseExecutor) IsScenarioPaused() bool {
+	bs.control.mu.Lock()
+	defer bs.control.mu.Unlock()
+	return bs.control.resumed != nil
+}
+
+// SetScenarioPaused pauses or resumes the scenario of the executor. While it's
+// paused, the iterations in progress finish, but no new ones are started.
+func (bs *BaseExecutor) SetScenarioPaused(paused bool) error {
+	c := bs.control
+	if paused && bs.IsScenarioStopped() {
+		return errScenarioStopped
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch {
+	case paused && c.resumed == nil:
+		c.resumed = make(chan struct{})
+	case !paused && c.resumed != nil:
+		close(c.resumed)
+		c.resumed = nil
+	}
+	return nil
+}
+
+// IsScenarioStopped returns whether the scenario of the executor was stopped
+// with StopScenario.
+func (bs *BaseExecutor) IsScenarioStopped() bool {
+	select {
+	case <-bs.control.stopped:
+		return true
+	default:
+		return false
+	}
+}
+
+// StopScenario ends the regular duration of the scenario of the executor now,
+// as if it was reached, so the iterations in progress are interrupted after
+// its graceful stop period. The scenario doesn't start at all if it's stopped
+// before it.
+func (bs *BaseExecutor) StopScenario() {
+	c := bs.control
+	c.stopOnce.Do(func() {
+		close(c.stopped)
+		c.mu.Lock()
+		durations := c.durations
+		c.mu.Unlock()
+		if durations != nil {
+			durations.stop()
+		}
+	})
+}
+
+// waitWhilePaused blocks while the scenario is paused and returns true when
+// it's resumed, or false if done is closed before that.
+func (c *scenarioControl) waitWhilePaused(done <-chan struct{}) bool {
+	c.mu.Lock()
+	resumed := c.resumed
+	c.mu.Unlock()
+	if resumed == nil {
+		return true
+	}
+	select {
+	case <-resumed:
+		return true
+	case <-done:
+		return false
+	}
+}
+
+// pausable wraps the iteration runner so no iterations are started while the
+// scenario is paused, for the executors which don't loop the iterations
+// themselves.
+func (c *scenarioControl) pausable(
+	runIteration func(context.Context, lib.ActiveVU) bool, regDurationDone <-chan struct{},
+) func(context.Context, lib.ActiveVU) bool {
+	return func(ctx context.Context, vu lib.ActiveVU) bool {
+		c.mu.Lock()
+		resumed := c.resumed
+		c.mu.Unlock()
+		if resumed != nil {
+			select {
+			case <-resumed:
+			case <-regDurationDone:
+				return false
+			case <-ctx.Done():
+				return false
+			}
+		}
+		return runIteration(ctx, vu)
+	}
+}
+
+func (c *scenarioControl) getRateMultiplier() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.rateMultiplier
+}
+
+func (c *scenarioControl) setRateMultiplier(multiplier float64) error {
+	if !(multiplier > 0) || math.IsInf(multiplier, 0) {
+		return fmt.Errorf("the rate multiplier should be a positive number, not %g", multiplier)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rateMultiplier = multiplier
+	return nil
+}
+
+// iterationsToStart returns how many iterations the arrival-rate executors
+// should start instead of each of their scheduled ones. It's 0 while the
+// scenario is paused, and it's otherwise the rate multiplier on average, so
+// the fractions of the multiplied iterations are carried over.
+func (c *scenarioControl) iterationsToStart() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.resumed != nil {
+		return 0
+	}
+	c.rateCredit += c.rateMultiplier
+	n := math.Floor(c.rateCredit)
+	c.rateCredit -= n
+	return int(n)
+}
+
+func (c *scenarioControl) getDurations() *scenarioDurations {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.durations
+}
+
+// currentStages returns all the stages of the scenario since its start, or
+// the configured ones if it hasn't started yet.
+func (c *scenarioControl) currentStages(configured []Stage) []Stage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.stages == nil {
+		return append([]Stage(nil), configured...)
+	}
+	return append([]Stage(nil), c.stages...)
+}
+
+func (c *scenarioControl) setStages(stages []Stage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stages = stages
+}
+
+// updateStages sends the update of the stages to the Run() method of the
+// executor, and returns the error it replies with.
+func (c *scenarioControl) updateStages(stages []Stage, replace bool) error {
+	if errs := validateStages(stages); len(errs) > 0 {
+		return fmt.Errorf("invalid stages: %w", errors.Join(errs...))
+	}
+
+	durations := c.getDurations()
+	if durations == nil {
+		return errors.New("the stages can only be updated while the scenario is running")
+	}
+
+	update := stagesUpdate{stages: stages, replace: replace, err: make(chan error)}
+	select {
+	case c.stagesUpdates <- update:
+		return <-update.err
+	case <-durations.regDurationCtx.Done():
+		return errors.New("the regular duration of the scenario is over")
+	}
+}
+
+// updatedStages returns all the stages of the scenario, since its start, after
+// the update at the offset from its start.
+func updatedStages(startValue int64, current []Stage, update stagesUpdate, offset time.Duration) []Stage {
+	if !update.replace {
+		return append(append([]Stage(nil), current...), update.stages...)
+	}
+	before, _ := splitStages(startValue, current, offset)
+	return append(before, update.stages...)
+}
+
+// splitStages splits the stages at the offset from their start. The stage in
+// progress at the offset is split in two, the first one ending with the value
+// it has reached at the offset.
+func splitStages(startValue int64, stages []Stage, offset time.Duration) (before, after []Stage) {
+	var stageStart time.Duration
+	from := startValue
+	for i, stage := range stages {
+		stageDuration := stage.Duration.TimeDuration()
+		if stageStart+stageDuration <= offset {
+			before = append(before, stage)
+			stageStart += stageDuration
+			from = stage.Target.Int64
+			continue
+		}
+
+		elapsed := offset - stageStart
+		reached := from + int64(math.Round(
+			float64(stage.Target.Int64-from)*float64(elapsed)/float64(stageDuration),
+		))
+		if elapsed > 0 {
+			before = append(before, Stage{Duration: types.NullDurationFrom(elapsed), Target: null.IntFrom(reached)})
+		}
+		after = append(after, Stage{Duration: types.NullDurationFrom(stageDuration - elapsed), Target: stage.Target})
+		return before, append(after, stages[i+1:]...)
+	}
+	return before, nil
+}
+
+// stagesValueAt returns the value the stages have reached at the offset from
+// their start, e.g. the number of VUs or the rate of iterations.
+func stagesValueAt(startValue int64, stages []Stage, offset time.Duration) int64 {
+	before, _ := splitStages(startValue, stages, offset)
+	if len(before) == 0 {
+		return startValue
+	}
+	return before[len(before)-1].Target.Int64
+}
+
+// scenarioDurations keeps the duration contexts of a running scenario. They
+// end at their deadlines like the contexts of context.WithDeadline, but the
+// regular duration, and so the deadlines, can be changed while the scenario
+// is running, and they're cancelled on top of that when it's stopped.
+type scenarioDurations struct {
+	mu                sync.Mutex
+	parentCtx         context.Context
+	startTime         time.Time
+	regularDuration   time.Duration
+	gracefulStop      time.Duration
+	generation        int
+	stopped           bool
+	regExpired        bool
+	maxExpired        bool
+	regTimer          *time.Timer
+	regCtx            context.Context // the cancel context under regDurationCtx
+	regDurationCtx    context.Context
+	regDurationCancel func()
+	maxDurationCancel func()
+}
+
+// durationContext is one of the duration contexts of a running scenario. It's
+// done when its deadline is reached, with a context.DeadlineExceeded error, or
+// when it's cancelled, e.g. by StopScenario, with a context.Canceled one.
+type durationContext struct {
+	context.Context
+	durations *scenarioDurations
+	graceful  bool // if it's the context of the regular duration and the graceful stop
+}
+
+// Deadline returns the current deadline of the context, which may be moved
+// when the stages of the scenario are updated.
+func (c *durationContext) Deadline() (time.Time, bool) {
+	d := c.durations
+	d.mu.Lock()
+	deadline := d.startTime.Add(d.regularDuration)
+	if c.graceful {
+		deadline = deadline.Add(d.gracefulStop)
+	}
+	d.mu.Unlock()
+
+	if parentDeadline, ok := d.parentCtx.Deadline(); ok && parentDeadline.Before(deadline) {
+		return parentDeadline, true
+	}
+	return deadline, true
+}
+
+func (c *durationContext) Err() error {
+	err := c.Context.Err()
+	if !errors.Is(err, context.Canceled) || c.durations.parentCtx.Err() != nil {
+		return err
+	}
+	d := c.durations
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if (c.graceful && d.maxExpired) || (!c.graceful && d.regExpired) {
+		return context.DeadlineExceeded
+	}
+	return err
+}
+
+// getDurationContexts is used to create sub-contexts that can restrict an
+// executor to only run for its allotted time.
+//
+// If the executor doesn't have a graceful stop period for iterations, then
+// both returned sub-contexts will end after the supplied regular executor
+// duration.
+//
+// But if a graceful stop is enabled, then the first returned context (and the
+// cancel func) will be for the "outer" sub-context. It will end after both
+// the regular duration and the specified graceful stop period. The second
+// context will be a sub-context of the first one and it will end after only
+// the regular duration.
+//
+// In either case, the usage of these contexts should be like this:
+//   - As long as the regDurationCtx isn't done, new iterations can be started.
+//   - After regDurationCtx is done, no new iterations should be started; every
+//     VU that finishes an iteration from now on can be returned to the buffer
+//     pool in the ExecutionState struct.
+//   - After maxDurationCtx is done, any VUs with iterations will be
+//     interrupted by the context's closing and will be returned to the buffer.
+//   - If you want to interrupt the execution of all VUs prematurely (e.g. there
+//     was an error or something like that), trigger maxDurationCancel().
+//   - If the whole test is aborted, the parent context will be cancelled, so
+//     that will also cancel these contexts, thus the "general abort" case is
+//     handled transparently.
+//
+// The regular duration can be changed while the executor is running, e.g. when
+// its stages are updated, which moves the deadlines of both contexts. When the
+// scenario is stopped with StopScenario, the regular duration ends right away
+// and the contexts are cancelled instead of reaching their deadlines.
+func (bs *BaseExecutor) getDurationContexts(parentCtx context.Context, regularDuration, gracefulStop time.Duration) (
+	startTime time.Time, maxDurationCtx, regDurationCtx context.Context, maxDurationCancel func(),
+) {
+	d := &scenarioDurations{
+		parentCtx:       parentCtx,
+		startTime:       time.Now(),
+		regularDuration: regularDuration,
+		gracefulStop:    gracefulStop,
+	}
+	maxCtx, maxCancel := context.WithCancel(parentCtx)
+	regCtx, regCancel := context.WithCancel(maxCtx)
+	maxDurationCtx = &durationContext{Context: maxCtx, durations: d, graceful: true}
+	d.regCtx = regCtx
+	d.regDurationCtx = &durationContext{Context: regCtx, durations: d}
+	d.maxDurationCancel, d.regDurationCancel = maxCancel, regCancel
+	d.regTimer = time.AfterFunc(regularDuration, d.endRegularDuration(0))
+
+	bs.control.mu.Lock()
+	bs.control.durations = d
+	bs.control.mu.Unlock()
+	if bs.IsScenarioStopped() {
+		d.stop()
+	}
+
+	maxDurationCancel = func() {
+		d.mu.Lock()
+		d.regTimer.Stop()
+		d.mu.Unlock()
+		d.maxDurationCancel()
+	}
+	return d.startTime, maxDurationCtx, d.regDurationCtx, maxDurationCancel
+}
+
+func (d *scenarioDurations) endRegularDuration(generation int) func() {
+	return func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		if generation != d.generation {
+			return // the regular duration was changed meanwhile
+		}
+		d.generation++ // it can't be changed anymore
+		d.regExpired = !d.stopped
+		d.regDurationCancel()
+		if d.gracefulStop > 0 {
+			time.AfterFunc(d.gracefulStop, d.endGracefulStop)
+		} else {
+			d.maxExpired = d.regExpired
+			d.maxDurationCancel()
+		}
+	}
+}
+
+func (d *scenarioDurations) endGracefulStop() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.maxExpired = !d.stopped
+	d.maxDurationCancel()
+}
+
+// getRegularDuration returns the current regular duration of the scenario.
+func (d *scenarioDurations) getRegularDuration() time.Duration {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.regularDuration
+}
+
+// setRegularDuration changes the regular duration of the scenario, and
+// returns false if it's already over.
+func (d *scenarioDurations) setRegularDuration(regularDuration time.Duration) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.setRegularDurationLocked(regularDuration)
+}
+
+func (d *scenarioDurations) setRegularDurationLocked(regularDuration time.Duration) bool {
+	if d.regCtx.Err() != nil {
+		return false
+	}
+	d.regTimer.Stop()
+	d.generation++
+	d.regularDuration = regularDuration
+	d.regTimer = time.AfterFunc(time.Until(d.startTime.Add(regularDuration)), d.endRegularDuration(d.generation))
+	return true
+}
+
+// stop ends the regular duration of the scenario now, and its contexts are
+// cancelled instead of reaching their deadlines.
+func (d *scenarioDurations) stop() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.stopped = true
+	d.setRegularDurationLocked(time.Since(d.startTime))
+}
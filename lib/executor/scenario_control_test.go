@@ -0,0 +1,267 @@
+package executor
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v3"
+
+	"github.com/liuxd6825/k6server/lib"
+	"github.com/liuxd6825/k6server/lib/types"
+	"github.com/liuxd6825/k6server/metrics"
+)
+
+func newStage(duration time.Duration, target int64) Stage {
+	return Stage{Duration: types.NullDurationFrom(duration), Target: null.IntFrom(target)}
+}
+
+func TestSplitStages(t *testing.T) {
+	t.Parallel()
+
+	stages := []Stage{newStage(2*time.Second, 10), newStage(time.Second, 10), newStage(4*time.Second, 0)}
+
+	before, after := splitStages(0, stages, time.Second)
+	assert.Equal(t, []Stage{newStage(time.Second, 5)}, before)
+	assert.Equal(t, []Stage{newStage(time.Second, 10), stages[1], stages[2]}, after)
+
+	before, after = splitStages(0, stages, 3*time.Second)
+	assert.Equal(t, stages[:2], before)
+	assert.Equal(t, stages[2:], after)
+
+	before, after = splitStages(0, stages, 10*time.Second)
+	assert.Equal(t, stages, before)
+	assert.Empty(t, after)
+
+	assert.Equal(t, int64(4), stagesValueAt(4, stages, 0))
+	assert.Equal(t, int64(5), stagesValueAt(0, stages, 5*time.Second))
+
+	update := stagesUpdate{stages: []Stage{newStage(time.Second, 20)}}
+	assert.Equal(t, append(stages[:3:3], update.stages...), updatedStages(0, stages, update, time.Second))
+	update.replace = true
+	assert.Equal(t, []Stage{newStage(time.Second, 5), newStage(time.Second, 20)},
+		updatedStages(0, stages, update, time.Second))
+}
+
+func TestScenarioControlIterationsToStart(t *testing.T) {
+	t.Parallel()
+
+	c := newScenarioControl()
+	assert.Equal(t, 1, c.iterationsToStart())
+
+	require.NoError(t, c.setRateMultiplier(1.5))
+	assert.Equal(t, 1.5, c.getRateMultiplier())
+	var started []int
+	for i := 0; i < 4; i++ {
+		started = append(started, c.iterationsToStart())
+	}
+	assert.Equal(t, []int{1, 2, 1, 2}, started)
+
+	assert.Error(t, c.setRateMultiplier(0))
+	assert.Error(t, c.setRateMultiplier(-1))
+
+	bs := &BaseExecutor{control: c}
+	require.NoError(t, bs.SetScenarioPaused(true))
+	assert.True(t, bs.IsScenarioPaused())
+	assert.Equal(t, 0, c.iterationsToStart())
+	require.NoError(t, bs.SetScenarioPaused(false))
+	assert.False(t, bs.IsScenarioPaused())
+
+	bs.StopScenario()
+	bs.StopScenario()
+	assert.True(t, bs.IsScenarioStopped())
+	assert.Error(t, bs.SetScenarioPaused(true))
+}
+
+func TestScenarioDurationContexts(t *testing.T) {
+	t.Parallel()
+
+	t.Run("deadlines", func(t *testing.T) {
+		t.Parallel()
+		bs := &BaseExecutor{control: newScenarioControl()}
+		startTime, maxDurationCtx, regDurationCtx, maxDurationCancel := bs.getDurationContexts(
+			context.Background(), 50*time.Millisecond, 50*time.Millisecond)
+		defer maxDurationCancel()
+
+		deadline, ok := regDurationCtx.Deadline()
+		assert.True(t, ok)
+		assert.Equal(t, startTime.Add(50*time.Millisecond), deadline)
+		deadline, ok = maxDurationCtx.Deadline()
+		assert.True(t, ok)
+		assert.Equal(t, startTime.Add(100*time.Millisecond), deadline)
+
+		// the regular duration is extended, like when stages are appended
+		require.True(t, bs.control.getDurations().setRegularDuration(100*time.Millisecond))
+		deadline, _ = maxDurationCtx.Deadline()
+		assert.Equal(t, startTime.Add(150*time.Millisecond), deadline)
+
+		<-regDurationCtx.Done()
+		assert.GreaterOrEqual(t, time.Since(startTime), 100*time.Millisecond)
+		assert.Equal(t, context.DeadlineExceeded, regDurationCtx.Err())
+		assert.NoError(t, maxDurationCtx.Err())
+		<-maxDurationCtx.Done()
+		assert.Equal(t, context.DeadlineExceeded, maxDurationCtx.Err())
+		assert.False(t, bs.control.getDurations().setRegularDuration(time.Second))
+	})
+
+	t.Run("stopped", func(t *testing.T) {
+		t.Parallel()
+		bs := &BaseExecutor{control: newScenarioControl()}
+		startTime, maxDurationCtx, regDurationCtx, maxDurationCancel := bs.getDurationContexts(
+			context.Background(), time.Minute, 50*time.Millisecond)
+		defer maxDurationCancel()
+
+		bs.StopScenario()
+		<-regDurationCtx.Done()
+		assert.Equal(t, context.Canceled, regDurationCtx.Err())
+		<-maxDurationCtx.Done()
+		assert.Equal(t, context.Canceled, maxDurationCtx.Err())
+		assert.Less(t, time.Since(startTime), time.Minute)
+	})
+
+	t.Run("parent deadline", func(t *testing.T) {
+		t.Parallel()
+		parentCtx, parentCancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer parentCancel()
+		bs := &BaseExecutor{control: newScenarioControl()}
+		_, maxDurationCtx, regDurationCtx, maxDurationCancel := bs.getDurationContexts(parentCtx, time.Minute, 0)
+		defer maxDurationCancel()
+
+		parentDeadline, _ := parentCtx.Deadline()
+		deadline, _ := regDurationCtx.Deadline()
+		assert.Equal(t, parentDeadline, deadline)
+		<-maxDurationCtx.Done()
+		assert.Equal(t, context.DeadlineExceeded, maxDurationCtx.Err())
+	})
+}
+
+func TestConstantVUsScenarioPausedAndStopped(t *testing.T) {
+	t.Parallel()
+
+	var iterCount int64
+	runner := simpleRunner(func(_ context.Context, _ *lib.State) error {
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt64(&iterCount, 1)
+		return nil
+	})
+	test := setupExecutorTest(t, "", "", lib.Options{}, runner, getTestConstantVUsConfig())
+	defer test.cancel()
+
+	executor, ok := test.executor.(lib.ScenarioControllableExecutor)
+	require.True(t, ok)
+	require.NoError(t, executor.SetScenarioPaused(true))
+
+	start := time.Now()
+	errCh := make(chan error)
+	go func() { errCh <- test.executor.Run(test.ctx, nil) }()
+
+	time.Sleep(200 * time.Millisecond)
+	assert.Equal(t, int64(0), atomic.LoadInt64(&iterCount))
+
+	require.NoError(t, executor.SetScenarioPaused(false))
+	time.Sleep(200 * time.Millisecond)
+	assert.NotZero(t, atomic.LoadInt64(&iterCount))
+
+	executor.StopScenario()
+	require.NoError(t, <-errCh)
+	assert.Less(t, time.Since(start), getTestConstantVUsConfig().Duration.TimeDuration())
+}
+
+func TestConstantArrivalRateRateMultiplier(t *testing.T) {
+	t.Parallel()
+
+	var count int64
+	runner := simpleRunner(func(_ context.Context, _ *lib.State) error {
+		atomic.AddInt64(&count, 1)
+		return nil
+	})
+	config := getTestConstantArrivalRateConfig()
+	config.Duration = types.NullDurationFrom(time.Second)
+	test := setupExecutorTest(t, "", "", lib.Options{}, runner, config)
+	defer test.cancel()
+
+	executor, ok := test.executor.(lib.RateMultipliableExecutor)
+	require.True(t, ok)
+	require.NoError(t, executor.SetRateMultiplier(2))
+
+	engineOut := make(chan metrics.SampleContainer, 1000)
+	require.NoError(t, test.executor.Run(test.ctx, engineOut))
+	// 50 iterations per second, doubled
+	assert.InDelta(t, 100, atomic.LoadInt64(&count), 10)
+}
+
+func TestRampingArrivalRateUpdateStages(t *testing.T) {
+	t.Parallel()
+
+	var count int64
+	runner := simpleRunner(func(_ context.Context, _ *lib.State) error {
+		atomic.AddInt64(&count, 1)
+		return nil
+	})
+	config := &RampingArrivalRateConfig{
+		TimeUnit:        types.NullDurationFrom(time.Second),
+		StartRate:       null.IntFrom(20),
+		Stages:          []Stage{newStage(time.Second, 20)},
+		PreAllocatedVUs: null.IntFrom(5),
+		MaxVUs:          null.IntFrom(5),
+	}
+	test := setupExecutorTest(t, "", "", lib.Options{}, runner, config)
+	defer test.cancel()
+
+	executor, ok := test.executor.(StagesUpdatableExecutor)
+	require.True(t, ok)
+	assert.Error(t, executor.UpdateStages([]Stage{newStage(time.Second, 20)}, false), "not started")
+
+	start := time.Now()
+	errCh := make(chan error)
+	go func() { errCh <- test.executor.Run(test.ctx, nil) }()
+
+	time.Sleep(500 * time.Millisecond)
+	assert.Error(t, executor.UpdateStages([]Stage{{Duration: types.NullDurationFrom(time.Second)}}, false))
+	require.NoError(t, executor.UpdateStages([]Stage{newStage(time.Second, 40)}, true))
+	assert.Len(t, executor.GetStages(), 2)
+
+	require.NoError(t, <-errCh)
+	assert.InDelta(t, 1500*time.Millisecond, time.Since(start), float64(300*time.Millisecond))
+	// 10 iterations in the first half second, and 30 in the next second
+	assert.InDelta(t, 40, atomic.LoadInt64(&count), 5)
+}
+
+func TestRampingVUsUpdateStages(t *testing.T) {
+	t.Parallel()
+
+	config := RampingVUsConfig{
+		BaseConfig:       BaseConfig{GracefulStop: types.NullDurationFrom(0)},
+		GracefulRampDown: types.NullDurationFrom(0),
+		StartVUs:         null.IntFrom(5),
+		Stages:           []Stage{newStage(time.Second, 5)},
+	}
+	runner := simpleRunner(func(_ context.Context, _ *lib.State) error {
+		time.Sleep(50 * time.Millisecond)
+		return nil
+	})
+	test := setupExecutorTest(t, "", "", lib.Options{}, runner, config)
+	defer test.cancel()
+	require.NoError(t, test.executor.Init(test.ctx))
+
+	executor, ok := test.executor.(StagesUpdatableExecutor)
+	require.True(t, ok)
+
+	start := time.Now()
+	errCh := make(chan error)
+	go func() { errCh <- test.executor.Run(test.ctx, nil) }()
+
+	time.Sleep(200 * time.Millisecond)
+	assert.Error(t, executor.UpdateStages([]Stage{newStage(time.Second, 10)}, false), "more VUs than initialized")
+	require.NoError(t, executor.UpdateStages([]Stage{newStage(0, 2), newStage(500*time.Millisecond, 2)}, false))
+	assert.Len(t, executor.GetStages(), 3)
+
+	time.Sleep(1050 * time.Millisecond)
+	assert.Equal(t, int64(2), test.state.GetCurrentlyActiveVUsCount())
+
+	require.NoError(t, <-errCh)
+	assert.InDelta(t, 1500*time.Millisecond, time.Since(start), float64(300*time.Millisecond))
+}
@@ -171,7 +171,7 @@ func (si SharedIterations) Run(parentCtx context.Context, out chan<- metrics.Sam
 	gracefulStop := si.config.GetGracefulStop()
 
 	waitOnProgressChannel := make(chan struct{})
-	startTime, maxDurationCtx, regDurationCtx, cancel := getDurationContexts(parentCtx, duration, gracefulStop)
+	startTime, maxDurationCtx, regDurationCtx, cancel := si.getDurationContexts(parentCtx, duration, gracefulStop)
 	defer func() {
 		cancel()
 		<-waitOnProgressChannel
@@ -229,7 +229,7 @@ func (si SharedIterations) Run(parentCtx context.Context, out chan<- metrics.Sam
 	}()
 
 	regDurationDone := regDurationCtx.Done()
-	runIteration := getIterationRunner(si.executionState, si.logger)
+	runIteration := getIterationRunner(si.executionState, si.logger, si.execMix)
 
 	returnVU := func(u lib.InitializedVU) {
 		si.executionState.ReturnVU(u, true)
@@ -250,6 +250,9 @@ func (si SharedIterations) Run(parentCtx context.Context, out chan<- metrics.Sam
 			default:
 				// continue looping
 			}
+			if !si.control.waitWhilePaused(regDurationDone) {
+				continue
+			}
 
 			attemptedIterNumber := atomic.AddUint64(&attemptedIters, 1)
 			if attemptedIterNumber > totalIters {
@@ -0,0 +1,464 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/big"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/guregu/null.v3"
+
+	"github.com/liuxd6825/k6server/lib"
+	"github.com/liuxd6825/k6server/lib/types"
+	"github.com/liuxd6825/k6server/metrics"
+	"github.com/liuxd6825/k6server/ui/pb"
+)
+
+const stochasticArrivalRateType = "stochastic-arrival-rate"
+
+// The distributions of the times between the arrivals of the
+// stochastic-arrival-rate executor.
+const (
+	// DistributionExponential makes the arrivals a Poisson process.
+	DistributionExponential = "exponential"
+	// DistributionPareto has a heavy tail, i.e. bursts of arrivals separated
+	// by long pauses.
+	DistributionPareto = "pareto"
+)
+
+func init() {
+	lib.RegisterExecutorConfigType(
+		stochasticArrivalRateType,
+		func(name string, rawJSON []byte) (lib.ExecutorConfig, error) {
+			config := NewStochasticArrivalRateConfig(name)
+			err := lib.StrictJSONUnmarshal(rawJSON, &config)
+			return config, err
+		},
+	)
+}
+
+// StochasticArrivalRateConfig stores config for the stochastic arrival-rate
+// executor, whose iterations arrive at a mean rate, with random times between
+// them.
+type StochasticArrivalRateConfig struct {
+	BaseConfig
+	Rate     null.Int           `json:"rate"`
+	TimeUnit types.NullDuration `json:"timeUnit"`
+	Duration types.NullDuration `json:"duration"`
+
+	Distribution null.String `json:"distribution"`
+	// ParetoShape is the shape of the Pareto distribution, the lower it is,
+	// the heavier its tail. It must be more than 1, for the mean rate to be
+	// the configured one.
+	ParetoShape null.Float `json:"paretoShape"`
+	// RandomSeed overrides the seed of the random times between the arrivals,
+	// which is otherwise derived from the randomSeed option and the name of
+	// the scenario.
+	RandomSeed null.Int `json:"randomSeed"`
+
+	// Initialize `PreAllocatedVUs` number of VUs, and if more than that are needed,
+	// they will be dynamically allocated, until `MaxVUs` is reached, which is an
+	// absolutely hard limit on the number of VUs the executor will use
+	PreAllocatedVUs null.Int `json:"preAllocatedVUs"`
+	MaxVUs          null.Int `json:"maxVUs"`
+}
+
+// NewStochasticArrivalRateConfig returns a StochasticArrivalRateConfig with default values
+func NewStochasticArrivalRateConfig(name string) *StochasticArrivalRateConfig {
+	return &StochasticArrivalRateConfig{
+		BaseConfig:   NewBaseConfig(name, stochasticArrivalRateType),
+		TimeUnit:     types.NewNullDuration(1*time.Second, false),
+		Distribution: null.NewString(DistributionExponential, false),
+		ParetoShape:  null.NewFloat(1.5, false),
+	}
+}
+
+// Make sure we implement the lib.ExecutorConfig interface
+var _ lib.ExecutorConfig = &StochasticArrivalRateConfig{}
+
+// GetPreAllocatedVUs is just a helper method that returns the scaled pre-allocated VUs.
+func (sarc StochasticArrivalRateConfig) GetPreAllocatedVUs(et *lib.ExecutionTuple) int64 {
+	return et.ScaleInt64(sarc.PreAllocatedVUs.Int64)
+}
+
+// GetMaxVUs is just a helper method that returns the scaled max VUs.
+func (sarc StochasticArrivalRateConfig) GetMaxVUs(et *lib.ExecutionTuple) int64 {
+	return et.ScaleInt64(sarc.MaxVUs.Int64)
+}
+
+// GetDescription returns a human-readable description of the executor options
+func (sarc StochasticArrivalRateConfig) GetDescription(et *lib.ExecutionTuple) string {
+	preAllocatedVUs, maxVUs := sarc.GetPreAllocatedVUs(et), sarc.GetMaxVUs(et)
+	maxVUsRange := fmt.Sprintf("maxVUs: %d", preAllocatedVUs)
+	if maxVUs > preAllocatedVUs {
+		maxVUsRange += fmt.Sprintf("-%d", maxVUs)
+	}
+
+	timeUnit := sarc.TimeUnit.TimeDuration()
+	var arrRatePerSec float64
+	if maxVUs != 0 {
+		ratio := big.NewRat(maxVUs, sarc.MaxVUs.Int64)
+		arrRate := big.NewRat(sarc.Rate.Int64, int64(timeUnit))
+		arrRate.Mul(arrRate, ratio)
+		arrRatePerSec, _ = getArrivalRatePerSec(arrRate).Float64()
+	}
+
+	return fmt.Sprintf("%.2f iterations/s on average, %s arrivals, for %s%s", arrRatePerSec,
+		sarc.Distribution.String, sarc.Duration.Duration, sarc.getBaseInfo(maxVUsRange))
+}
+
+// Validate makes sure all options are configured and valid
+func (sarc *StochasticArrivalRateConfig) Validate() []error {
+	errors := sarc.BaseConfig.Validate()
+	if !sarc.Rate.Valid {
+		errors = append(errors, fmt.Errorf("the iteration rate isn't specified"))
+	} else if sarc.Rate.Int64 <= 0 {
+		errors = append(errors, fmt.Errorf("the iteration rate must be more than 0"))
+	}
+
+	if sarc.TimeUnit.TimeDuration() <= 0 {
+		errors = append(errors, fmt.Errorf("the timeUnit must be more than 0"))
+	}
+
+	if !sarc.Duration.Valid {
+		errors = append(errors, fmt.Errorf("the duration is unspecified"))
+	} else if sarc.Duration.TimeDuration() < minDuration {
+		errors = append(errors, fmt.Errorf(
+			"the duration must be at least %s, but is %s", minDuration, sarc.Duration,
+		))
+	}
+
+	switch sarc.Distribution.String {
+	case DistributionExponential:
+	case DistributionPareto:
+		if !(sarc.ParetoShape.Float64 > 1) || math.IsInf(sarc.ParetoShape.Float64, 0) {
+			errors = append(errors, fmt.Errorf("the paretoShape must be more than 1"))
+		}
+	default:
+		errors = append(errors, fmt.Errorf("the distribution must be either %s or %s, but is '%s'",
+			DistributionExponential, DistributionPareto, sarc.Distribution.String))
+	}
+
+	if !sarc.PreAllocatedVUs.Valid {
+		errors = append(errors, fmt.Errorf("the number of preAllocatedVUs isn't specified"))
+	} else if sarc.PreAllocatedVUs.Int64 < 0 {
+		errors = append(errors, fmt.Errorf("the number of preAllocatedVUs can't be negative"))
+	}
+
+	if !sarc.MaxVUs.Valid {
+		// TODO: don't change the config while validating
+		sarc.MaxVUs.Int64 = sarc.PreAllocatedVUs.Int64
+	} else if sarc.MaxVUs.Int64 < sarc.PreAllocatedVUs.Int64 {
+		errors = append(errors, fmt.Errorf("maxVUs can't be less than preAllocatedVUs"))
+	}
+
+	return errors
+}
+
+// GetExecutionRequirements returns the number of required VUs to run the
+// executor for its whole duration (disregarding any startTime), including the
+// maximum waiting time for any iterations to gracefully stop. This is used by
+// the execution scheduler in its VU reservation calculations, so it knows how
+// many VUs to pre-initialize.
+func (sarc StochasticArrivalRateConfig) GetExecutionRequirements(et *lib.ExecutionTuple) []lib.ExecutionStep {
+	return []lib.ExecutionStep{
+		{
+			TimeOffset:      0,
+			PlannedVUs:      uint64(et.ScaleInt64(sarc.PreAllocatedVUs.Int64)),
+			MaxUnplannedVUs: uint64(et.ScaleInt64(sarc.MaxVUs.Int64) - et.ScaleInt64(sarc.PreAllocatedVUs.Int64)),
+		}, {
+			TimeOffset:      sarc.Duration.TimeDuration() + sarc.GracefulStop.TimeDuration(),
+			PlannedVUs:      0,
+			MaxUnplannedVUs: 0,
+		},
+	}
+}
+
+// NewExecutor creates a new StochasticArrivalRate executor
+func (sarc StochasticArrivalRateConfig) NewExecutor(
+	es *lib.ExecutionState, logger *logrus.Entry,
+) (lib.Executor, error) {
+	return &StochasticArrivalRate{
+		BaseExecutor: NewBaseExecutor(&sarc, es, logger),
+		config:       sarc,
+		seed:         sarc.getSeed(es.Test.Options),
+	}, nil
+}
+
+// HasWork reports whether there is any work to be done for the given execution segment.
+func (sarc StochasticArrivalRateConfig) HasWork(et *lib.ExecutionTuple) bool {
+	return sarc.GetMaxVUs(et) > 0
+}
+
+// getSeed returns the seed of the random times between the arrivals.
+func (sarc StochasticArrivalRateConfig) getSeed(options lib.Options) int64 {
+	if sarc.RandomSeed.Valid {
+		return sarc.RandomSeed.Int64
+	}
+	return getRandomSeed(options, sarc.Name, "arrivals")
+}
+
+// newArrivalTimes returns a function returning the offsets of the consecutive
+// arrivals of the whole test, i.e. of all its execution segments, which are
+// the same for a given seed.
+func (sarc StochasticArrivalRateConfig) newArrivalTimes(seed int64) func() time.Duration {
+	rng := rand.New(rand.NewSource(seed)) //nolint:gosec
+	mean := float64(sarc.TimeUnit.TimeDuration()) / float64(sarc.Rate.Int64)
+
+	interval := func() float64 { return rng.ExpFloat64() * mean }
+	if sarc.Distribution.String == DistributionPareto {
+		// the scale of the distribution is its minimum, and its mean is
+		// shape*scale/(shape-1)
+		shape := sarc.ParetoShape.Float64
+		scale := mean * (shape - 1) / shape
+		interval = func() float64 { return scale / math.Pow(1-rng.Float64(), 1/shape) }
+	}
+
+	var offset float64
+	return func() time.Duration {
+		offset += interval()
+		return time.Duration(offset)
+	}
+}
+
+// StochasticArrivalRate starts the iterations at a mean rate, with random times
+// between them.
+type StochasticArrivalRate struct {
+	*BaseExecutor
+	config StochasticArrivalRateConfig
+	seed   int64
+	et     *lib.ExecutionTuple
+}
+
+// Make sure we implement the lib.Executor interface.
+var (
+	_ lib.Executor                     = &StochasticArrivalRate{}
+	_ lib.ScenarioControllableExecutor = &StochasticArrivalRate{}
+	_ lib.RateMultipliableExecutor     = &StochasticArrivalRate{}
+)
+
+// Init values needed for the execution
+func (sar *StochasticArrivalRate) Init(_ context.Context) error {
+	// err should always be nil, because Init() won't be called for executors
+	// with no work, as determined by their config's HasWork() method.
+	et, err := sar.BaseExecutor.executionState.ExecutionTuple.GetNewExecutionTupleFromValue(sar.config.MaxVUs.Int64)
+	sar.et = et
+	sar.iterSegIndex = lib.NewSegmentedIndex(et)
+
+	return err
+}
+
+// GetRateMultiplier returns the multiplier of the configured rate.
+func (sar StochasticArrivalRate) GetRateMultiplier() float64 {
+	return sar.control.getRateMultiplier()
+}
+
+// SetRateMultiplier multiplies the configured rate while the scenario is
+// running, e.g. 2 doubles it and 0.5 halves it.
+func (sar StochasticArrivalRate) SetRateMultiplier(multiplier float64) error {
+	return sar.control.setRateMultiplier(multiplier)
+}
+
+// newLocalArrivalTimes returns a function returning the offsets of the
+// consecutive arrivals of the execution segment. All the instances of a test
+// generate the same arrivals, which are striped between them like the
+// iterations of the constant-arrival-rate executor.
+func (sar StochasticArrivalRate) newLocalArrivalTimes() func() time.Duration {
+	arrivalTimes := sar.config.newArrivalTimes(sar.seed)
+	start, offsets, _ := sar.et.GetStripedOffsets()
+	li, gi, next := 0, int64(-1), start
+	return func() time.Duration {
+		var offset time.Duration
+		for ; gi < next; gi++ {
+			offset = arrivalTimes()
+		}
+		next += offsets[li%len(offsets)]
+		li++
+		return offset
+	}
+}
+
+// Run starts the iterations at a mean rate, with random times between them.
+//
+//nolint:funlen
+func (sar StochasticArrivalRate) Run(parentCtx context.Context, out chan<- metrics.SampleContainer) (err error) {
+	gracefulStop := sar.config.GetGracefulStop()
+	duration := sar.config.Duration.TimeDuration()
+	preAllocatedVUs := sar.config.GetPreAllocatedVUs(sar.executionState.ExecutionTuple)
+	maxVUs := sar.config.GetMaxVUs(sar.executionState.ExecutionTuple)
+	arrivalRate := getScaledArrivalRate(sar.et.Segment, sar.config.Rate.Int64, sar.config.TimeUnit.TimeDuration())
+	arrivalRatePerSec, _ := getArrivalRatePerSec(arrivalRate).Float64()
+
+	// Make sure the log and the progress bar have accurate information
+	sar.logger.WithFields(logrus.Fields{
+		"maxVUs": maxVUs, "preAllocatedVUs": preAllocatedVUs, "duration": duration,
+		"distribution": sar.config.Distribution.String, "seed": sar.seed,
+		"type": sar.config.GetType(),
+	}).Debug("Starting executor run...")
+
+	activeVUsWg := &sync.WaitGroup{}
+
+	returnedVUs := make(chan struct{})
+	waitOnProgressChannel := make(chan struct{})
+	startTime, maxDurationCtx, regDurationCtx, cancel := sar.getDurationContexts(parentCtx, duration, gracefulStop)
+	defer func() {
+		cancel()
+		<-waitOnProgressChannel
+	}()
+
+	vusPool := newActiveVUPool(sar.executionState)
+	defer func() {
+		// Make sure all VUs aren't executing iterations anymore, for the cancel()
+		// below to deactivate them.
+		<-returnedVUs
+		// first close the vusPool so we wait for the gracefulShutdown
+		vusPool.Close()
+		cancel()
+		activeVUsWg.Wait()
+	}()
+	activeVUsCount := uint64(0)
+
+	vusFmt := pb.GetFixedLengthIntFormat(maxVUs)
+	progIters := fmt.Sprintf(
+		"~"+pb.GetFixedLengthFloatFormat(arrivalRatePerSec, 2)+" iters/s", arrivalRatePerSec)
+	progressFn := func() (float64, []string) {
+		spent := time.Since(startTime)
+		currActiveVUs := atomic.LoadUint64(&activeVUsCount)
+		progVUs := fmt.Sprintf(vusFmt+"/"+vusFmt+" VUs",
+			vusPool.Running(), currActiveVUs)
+
+		right := []string{progVUs, duration.String(), progIters}
+
+		if spent > duration {
+			return 1, right
+		}
+
+		spentDuration := pb.GetFixedLengthDuration(spent, duration)
+		progDur := fmt.Sprintf("%s/%s", spentDuration, duration)
+		right[1] = progDur
+
+		return math.Min(1, float64(spent)/float64(duration)), right
+	}
+	sar.progress.Modify(pb.WithProgress(progressFn))
+	maxDurationCtx = lib.WithScenarioState(maxDurationCtx, &lib.ScenarioState{
+		Name:       sar.config.Name,
+		Executor:   sar.config.Type,
+		StartTime:  startTime,
+		ProgressFn: progressFn,
+	})
+
+	go func() {
+		trackProgress(parentCtx, maxDurationCtx, regDurationCtx, &sar, progressFn)
+		close(waitOnProgressChannel)
+	}()
+
+	returnVU := func(u lib.InitializedVU) {
+		// Return the VU without decreasing the global active VU counter, which
+		// is done in the goroutine started by activeVUPool.AddVU, whenever the
+		// VU finishes running an iteration. This results in a more accurate
+		// report of VUs that are _actually_ active.
+		sar.executionState.ReturnVU(u, false)
+		activeVUsWg.Done()
+	}
+
+	runIterationBasic := getIterationRunner(sar.executionState, sar.logger, sar.execMix)
+	activateVU := func(initVU lib.InitializedVU) lib.ActiveVU {
+		activeVUsWg.Add(1)
+		activeVU := initVU.Activate(getVUActivationParams(
+			maxDurationCtx, sar.config.BaseConfig, returnVU,
+			sar.nextIterationCounters,
+		))
+		atomic.AddUint64(&activeVUsCount, 1)
+		vusPool.AddVU(maxDurationCtx, activeVU, runIterationBasic)
+		return activeVU
+	}
+
+	remainingUnplannedVUs := maxVUs - preAllocatedVUs
+	makeUnplannedVUCh := make(chan struct{})
+	defer close(makeUnplannedVUCh)
+	go func() {
+		defer close(returnedVUs)
+		for range makeUnplannedVUCh {
+			sar.logger.Debug("Starting initialization of an unplanned VU...")
+			initVU, err := sar.executionState.GetUnplannedVU(maxDurationCtx, sar.logger)
+			if err != nil {
+				// TODO figure out how to return it to the Run goroutine
+				sar.logger.WithError(err).Error("Error while allocating unplanned VU")
+			} else {
+				sar.logger.Debug("The unplanned VU finished initializing successfully!")
+				activateVU(initVU)
+			}
+		}
+	}()
+
+	// Get the pre-allocated VUs in the local buffer
+	for i := int64(0); i < preAllocatedVUs; i++ {
+		initVU, err := sar.executionState.GetPlannedVU(sar.logger, false)
+		if err != nil {
+			return err
+		}
+		activateVU(initVU)
+	}
+
+	timer := time.NewTimer(time.Hour * 24)
+	defer timer.Stop()
+
+	droppedIterationMetric := sar.executionState.Test.BuiltinMetrics.DroppedIterations
+	shownWarning := false
+	metricTags := sar.getMetricTags(nil)
+	nextArrival := sar.newLocalArrivalTimes()
+	for {
+		offset := nextArrival()
+		if offset >= duration {
+			<-regDurationCtx.Done()
+			return nil
+		}
+		timer.Reset(offset - time.Since(startTime))
+		select {
+		case <-timer.C:
+			// the arrival is skipped while the scenario is paused, and it's
+			// multiplied by the rate multiplier
+			for n := sar.control.iterationsToStart(); n > 0; n-- {
+				if vusPool.TryRunIteration() {
+					continue
+				}
+
+				// Since there aren't any free VUs available, consider this iteration
+				// dropped - we aren't going to try to recover it, but
+
+				metrics.PushIfNotDone(parentCtx, out, metrics.Sample{
+					TimeSeries: metrics.TimeSeries{
+						Metric: droppedIterationMetric,
+						Tags:   metricTags,
+					},
+					Time:  time.Now(),
+					Value: 1,
+				})
+
+				// We'll try to start allocating another VU in the background,
+				// non-blockingly, if we have remainingUnplannedVUs...
+				if remainingUnplannedVUs == 0 {
+					if !shownWarning {
+						sar.logger.Warningf("Insufficient VUs, reached %d active VUs and cannot initialize more", maxVUs)
+						shownWarning = true
+					}
+					continue
+				}
+
+				select {
+				case makeUnplannedVUCh <- struct{}{}: // great!
+					remainingUnplannedVUs--
+				default: // we're already allocating a new VU
+				}
+			}
+
+		case <-regDurationCtx.Done():
+			return nil
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package executor
+
+import (
+	"context"
+	"sort"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v3"
+
+	"github.com/liuxd6825/k6server/lib"
+	"github.com/liuxd6825/k6server/lib/types"
+	"github.com/liuxd6825/k6server/metrics"
+)
+
+func getTestStochasticArrivalRateConfig() *StochasticArrivalRateConfig {
+	config := NewStochasticArrivalRateConfig("stochastic")
+	config.GracefulStop = types.NullDurationFrom(0)
+	config.Rate = null.IntFrom(50)
+	config.Duration = types.NullDurationFrom(time.Second)
+	config.RandomSeed = null.IntFrom(42)
+	config.PreAllocatedVUs = null.IntFrom(10)
+	config.MaxVUs = null.IntFrom(20)
+	return config
+}
+
+func TestStochasticArrivalRateConfigValidate(t *testing.T) {
+	t.Parallel()
+
+	config := getTestStochasticArrivalRateConfig()
+	assert.Empty(t, config.Validate())
+
+	config.Distribution = null.StringFrom(DistributionPareto)
+	assert.Empty(t, config.Validate())
+	config.ParetoShape = null.FloatFrom(1)
+	assert.Len(t, config.Validate(), 1)
+
+	config.Distribution = null.StringFrom("uniform")
+	assert.Len(t, config.Validate(), 1)
+}
+
+func TestStochasticArrivalRateArrivalTimes(t *testing.T) {
+	t.Parallel()
+
+	for _, distribution := range []string{DistributionExponential, DistributionPareto} {
+		distribution := distribution
+		t.Run(distribution, func(t *testing.T) {
+			t.Parallel()
+
+			config := getTestStochasticArrivalRateConfig()
+			config.Distribution = null.StringFrom(distribution)
+			config.ParetoShape = null.FloatFrom(3)
+
+			const count = 20000
+			next, again := config.newArrivalTimes(config.RandomSeed.Int64), config.newArrivalTimes(config.RandomSeed.Int64)
+			var last time.Duration
+			for i := 0; i < count; i++ {
+				offset := next()
+				require.Equal(t, offset, again(), "the arrivals are reproducible")
+				require.GreaterOrEqual(t, offset, last)
+				if distribution == DistributionPareto {
+					// the minimum time between the arrivals is the scale
+					require.GreaterOrEqual(t, offset-last, 13*time.Millisecond)
+				}
+				last = offset
+			}
+			// 50 iterations per second on average
+			assert.InEpsilon(t, count*20*time.Millisecond, last, 0.05)
+
+			assert.NotEqual(t, config.newArrivalTimes(42)(), config.newArrivalTimes(43)())
+		})
+	}
+}
+
+func TestStochasticArrivalRateSeed(t *testing.T) {
+	t.Parallel()
+
+	getArrivals := func(name string, override, randomSeed null.Int) []time.Duration {
+		runner := simpleRunner(func(_ context.Context, _ *lib.State) error { return nil })
+		config := getTestStochasticArrivalRateConfig()
+		config.Name = name
+		config.RandomSeed = override
+		test := setupExecutorTest(t, "", "", lib.Options{RandomSeed: randomSeed}, runner, config)
+		defer test.cancel()
+
+		next := test.executor.(*StochasticArrivalRate).newLocalArrivalTimes()
+		arrivals := make([]time.Duration, 10)
+		for i := range arrivals {
+			arrivals[i] = next()
+		}
+		return arrivals
+	}
+
+	arrivals := getArrivals("stochastic", null.Int{}, null.IntFrom(1))
+	assert.Equal(t, arrivals, getArrivals("stochastic", null.Int{}, null.IntFrom(1)))
+	assert.NotEqual(t, arrivals, getArrivals("stochastic", null.Int{}, null.IntFrom(2)))
+	assert.NotEqual(t, arrivals, getArrivals("other", null.Int{}, null.IntFrom(1)))
+	assert.NotEqual(t, arrivals, getArrivals("stochastic", null.Int{}, null.Int{}))
+
+	overridden := getArrivals("stochastic", null.IntFrom(42), null.IntFrom(1))
+	assert.Equal(t, overridden, getArrivals("stochastic", null.IntFrom(42), null.IntFrom(2)))
+	assert.NotEqual(t, arrivals, overridden)
+}
+
+func TestStochasticArrivalRateExecutionSegments(t *testing.T) {
+	t.Parallel()
+
+	config := getTestStochasticArrivalRateConfig()
+	runner := simpleRunner(func(_ context.Context, _ *lib.State) error { return nil })
+	const count = 100
+
+	var expected []time.Duration
+	next := config.newArrivalTimes(config.RandomSeed.Int64)
+	for i := 0; i < count; i++ {
+		expected = append(expected, next())
+	}
+
+	var all []time.Duration
+	for _, segment := range []string{"0:1/3", "1/3:2/3", "2/3:1"} {
+		test := setupExecutorTest(t, segment, "0,1/3,2/3,1", lib.Options{}, runner, config)
+		nextLocal := test.executor.(*StochasticArrivalRate).newLocalArrivalTimes()
+		for offset := nextLocal(); offset <= expected[count-1]; offset = nextLocal() {
+			all = append(all, offset)
+		}
+		test.cancel()
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i] < all[j] })
+	assert.Equal(t, expected, all)
+}
+
+func TestStochasticArrivalRateRun(t *testing.T) {
+	t.Parallel()
+
+	config := getTestStochasticArrivalRateConfig()
+	var expected int64
+	next := config.newArrivalTimes(config.RandomSeed.Int64)
+	for offset := next(); offset < config.Duration.TimeDuration(); offset = next() {
+		expected++
+	}
+
+	var count int64
+	runner := simpleRunner(func(_ context.Context, _ *lib.State) error {
+		atomic.AddInt64(&count, 1)
+		return nil
+	})
+	test := setupExecutorTest(t, "", "", lib.Options{}, runner, config)
+	defer test.cancel()
+
+	start := time.Now()
+	engineOut := make(chan metrics.SampleContainer, 1000)
+	require.NoError(t, test.executor.Run(test.ctx, engineOut))
+	assert.InDelta(t, time.Second, time.Since(start), float64(100*time.Millisecond))
+	assert.Equal(t, expected, atomic.LoadInt64(&count))
+	assert.Empty(t, test.logHook.Drain())
+}
+
+func TestStochasticArrivalRateDroppedIterations(t *testing.T) {
+	t.Parallel()
+
+	config := getTestStochasticArrivalRateConfig()
+	config.PreAllocatedVUs = null.IntFrom(5)
+	config.MaxVUs = null.IntFrom(5)
+	var expected int64
+	next := config.newArrivalTimes(config.RandomSeed.Int64)
+	for offset := next(); offset < config.Duration.TimeDuration(); offset = next() {
+		expected++
+	}
+
+	var count int64
+	runner := simpleRunner(func(ctx context.Context, _ *lib.State) error {
+		atomic.AddInt64(&count, 1)
+		<-ctx.Done()
+		return nil
+	})
+	test := setupExecutorTest(t, "", "", lib.Options{}, runner, config)
+	defer test.cancel()
+
+	engineOut := make(chan metrics.SampleContainer, 1000)
+	require.NoError(t, test.executor.Run(test.ctx, engineOut))
+	logs := test.logHook.Drain()
+	require.Len(t, logs, 1)
+	assert.Contains(t, logs[0].Message, "cannot initialize more")
+	assert.Equal(t, int64(5), count)
+	assert.Equal(t, float64(expected-5), sumMetricValues(engineOut, metrics.DroppedIterationsName))
+}
@@ -0,0 +1,597 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/guregu/null.v3"
+
+	"github.com/liuxd6825/k6server/lib"
+	"github.com/liuxd6825/k6server/lib/types"
+	"github.com/liuxd6825/k6server/metrics"
+	"github.com/liuxd6825/k6server/ui/pb"
+)
+
+const traceReplayType = "trace-replay"
+
+func init() {
+	lib.RegisterExecutorConfigType(
+		traceReplayType,
+		func(name string, rawJSON []byte) (lib.ExecutorConfig, error) {
+			config := NewTraceReplayConfig(name)
+			err := lib.StrictJSONUnmarshal(rawJSON, &config)
+			return config, err
+		},
+	)
+}
+
+// TraceArrival is an arrival of a trace, i.e. an iteration to be started at a
+// specific offset from the start of the scenario.
+type TraceArrival struct {
+	Offset types.Duration `json:"offset"`
+	// Exec is the exported function of the iteration, the one of the scenario
+	// is run when it's empty.
+	Exec string `json:"exec,omitempty"`
+	// Payload is passed to the exported function, after the setup data.
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// TraceReplayConfig stores the config for the trace-replay executor, which
+// starts the iterations at the offsets of the arrivals of a recorded trace.
+type TraceReplayConfig struct {
+	BaseConfig
+	// File is the trace, which is loaded in Arrivals when the test is loaded,
+	// unless they are already specified. Each line of it is either a CSV
+	// record `time[,exec[,payload]]`, where the payload is the rest of the
+	// line, or a JSON object with the time, exec and payload keys. The time
+	// is either a number of TimeUnits or a RFC3339 timestamp, and the offsets
+	// of the arrivals are relative to the earliest one.
+	File null.String `json:"file"`
+	// TimeUnit is the unit of the numeric times of the trace, and the time the
+	// iteration of the last arrival has before the regular duration ends.
+	TimeUnit types.NullDuration `json:"timeUnit"`
+	// Speed speeds up the replay of the trace when it's more than 1, and
+	// slows it down when it's less than 1.
+	Speed null.Float `json:"speed"`
+
+	// Initialize `PreAllocatedVUs` number of VUs, and if more than that are needed,
+	// they will be dynamically allocated, until `MaxVUs` is reached, which is an
+	// absolutely hard limit on the number of VUs the executor will use
+	PreAllocatedVUs null.Int `json:"preAllocatedVUs"`
+	MaxVUs          null.Int `json:"maxVUs"`
+
+	Arrivals []TraceArrival `json:"arrivals"`
+}
+
+// NewTraceReplayConfig returns a TraceReplayConfig with default values
+func NewTraceReplayConfig(name string) *TraceReplayConfig {
+	return &TraceReplayConfig{
+		BaseConfig: NewBaseConfig(name, traceReplayType),
+		TimeUnit:   types.NewNullDuration(1*time.Second, false),
+		Speed:      null.NewFloat(1, false),
+	}
+}
+
+// Make sure we implement the lib.ExecutorConfig interface
+var (
+	_ lib.ExecutorConfig          = &TraceReplayConfig{}
+	_ lib.ExecutorConfigWithFiles = &TraceReplayConfig{}
+	_ lib.MultiExecExecutorConfig = &TraceReplayConfig{}
+)
+
+// GetPreAllocatedVUs is just a helper method that returns the scaled pre-allocated VUs.
+func (trc TraceReplayConfig) GetPreAllocatedVUs(et *lib.ExecutionTuple) int64 {
+	return et.ScaleInt64(trc.PreAllocatedVUs.Int64)
+}
+
+// GetMaxVUs is just a helper method that returns the scaled max VUs.
+func (trc TraceReplayConfig) GetMaxVUs(et *lib.ExecutionTuple) int64 {
+	return et.ScaleInt64(trc.MaxVUs.Int64)
+}
+
+// getOffset returns the offset of the arrival, scaled by the speed.
+func (trc TraceReplayConfig) getOffset(arrival TraceArrival) time.Duration {
+	return time.Duration(float64(arrival.Offset) / trc.Speed.Float64)
+}
+
+// getDuration returns the regular duration of the scenario, scaled by the
+// speed. Like the iterations of the arrival-rate executors, which are started
+// at least one interval before its end, the iteration of the last arrival is
+// started a time unit before it, so it isn't interrupted as soon as it starts.
+func (trc TraceReplayConfig) getDuration() time.Duration {
+	var last types.Duration
+	if len(trc.Arrivals) != 0 {
+		last = trc.Arrivals[len(trc.Arrivals)-1].Offset
+	}
+	return trc.getOffset(TraceArrival{Offset: last + types.Duration(trc.TimeUnit.TimeDuration())})
+}
+
+// GetDescription returns a human-readable description of the executor options
+func (trc TraceReplayConfig) GetDescription(et *lib.ExecutionTuple) string {
+	preAllocatedVUs, maxVUs := trc.GetPreAllocatedVUs(et), trc.GetMaxVUs(et)
+	maxVUsRange := fmt.Sprintf("maxVUs: %d", preAllocatedVUs)
+	if maxVUs > preAllocatedVUs {
+		maxVUsRange += fmt.Sprintf("-%d", maxVUs)
+	}
+
+	return fmt.Sprintf("%d trace arrivals over %s at %gx speed%s", len(trc.Arrivals),
+		trc.getDuration(), trc.Speed.Float64, trc.getBaseInfo(maxVUsRange))
+}
+
+// Validate makes sure all options are configured and valid
+func (trc *TraceReplayConfig) Validate() []error {
+	errors := trc.BaseConfig.Validate()
+	if len(trc.Arrivals) == 0 {
+		errors = append(errors, fmt.Errorf("the trace doesn't have any arrivals, either the file "+
+			"or the arrivals must be specified"))
+	}
+	for i, arrival := range trc.Arrivals {
+		if arrival.Offset < 0 || (i > 0 && arrival.Offset < trc.Arrivals[i-1].Offset) {
+			errors = append(errors, fmt.Errorf("the offsets of the arrivals must be positive and sorted"))
+			break
+		}
+	}
+
+	if trc.TimeUnit.TimeDuration() <= 0 {
+		errors = append(errors, fmt.Errorf("the timeUnit must be more than 0"))
+	}
+
+	if trc.Speed.Float64 <= 0 || math.IsInf(trc.Speed.Float64, 0) || math.IsNaN(trc.Speed.Float64) {
+		errors = append(errors, fmt.Errorf("the speed must be more than 0"))
+	}
+
+	if !trc.PreAllocatedVUs.Valid {
+		errors = append(errors, fmt.Errorf("the number of preAllocatedVUs isn't specified"))
+	} else if trc.PreAllocatedVUs.Int64 < 0 {
+		errors = append(errors, fmt.Errorf("the number of preAllocatedVUs can't be negative"))
+	}
+
+	if !trc.MaxVUs.Valid {
+		// TODO: don't change the config while validating
+		trc.MaxVUs.Int64 = trc.PreAllocatedVUs.Int64
+	} else if trc.MaxVUs.Int64 < trc.PreAllocatedVUs.Int64 {
+		errors = append(errors, fmt.Errorf("maxVUs can't be less than preAllocatedVUs"))
+	}
+
+	return errors
+}
+
+// LoadFiles returns the config with the arrivals of its trace file, if it has
+// one and they aren't already loaded, e.g. when the test is run from an
+// archive.
+func (trc TraceReplayConfig) LoadFiles(readFile func(filename string) ([]byte, error)) (lib.ExecutorConfig, error) {
+	if !trc.File.Valid || len(trc.Arrivals) != 0 {
+		return &trc, nil
+	}
+	data, err := readFile(trc.File.String)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read the trace file: %w", err)
+	}
+	trc.Arrivals, err = parseTrace(data, trc.TimeUnit.TimeDuration())
+	if err != nil {
+		return nil, fmt.Errorf("couldn't parse the trace file %s: %w", trc.File.String, err)
+	}
+	return &trc, nil
+}
+
+// GetExecs returns the exported functions of the arrivals, and the ones of the
+// execMix, if any.
+func (trc TraceReplayConfig) GetExecs() []string {
+	execs := trc.BaseConfig.GetExecs()
+	seen := map[string]bool{trc.GetExec(): true}
+	for _, exec := range execs {
+		seen[exec] = true
+	}
+	for _, arrival := range trc.Arrivals {
+		if arrival.Exec != "" && !seen[arrival.Exec] {
+			seen[arrival.Exec] = true
+			execs = append(execs, arrival.Exec)
+		}
+	}
+	return execs
+}
+
+// GetExecutionRequirements returns the number of required VUs to run the
+// executor for its whole duration (disregarding any startTime), including the
+// maximum waiting time for any iterations to gracefully stop. This is used by
+// the execution scheduler in its VU reservation calculations, so it knows how
+// many VUs to pre-initialize.
+func (trc TraceReplayConfig) GetExecutionRequirements(et *lib.ExecutionTuple) []lib.ExecutionStep {
+	return []lib.ExecutionStep{
+		{
+			TimeOffset:      0,
+			PlannedVUs:      uint64(trc.GetPreAllocatedVUs(et)),
+			MaxUnplannedVUs: uint64(trc.GetMaxVUs(et) - trc.GetPreAllocatedVUs(et)),
+		}, {
+			TimeOffset:      trc.getDuration() + trc.GracefulStop.TimeDuration(),
+			PlannedVUs:      0,
+			MaxUnplannedVUs: 0,
+		},
+	}
+}
+
+// NewExecutor creates a new TraceReplay executor
+func (trc TraceReplayConfig) NewExecutor(
+	es *lib.ExecutionState, logger *logrus.Entry,
+) (lib.Executor, error) {
+	return &TraceReplay{
+		BaseExecutor: NewBaseExecutor(&trc, es, logger),
+		config:       trc,
+	}, nil
+}
+
+// HasWork reports whether there is any work to be done for the given execution segment.
+func (trc TraceReplayConfig) HasWork(et *lib.ExecutionTuple) bool {
+	return trc.GetMaxVUs(et) > 0
+}
+
+// parseTrace returns the arrivals of a trace file, sorted by their offsets.
+func parseTrace(data []byte, timeUnit time.Duration) ([]TraceArrival, error) {
+	var (
+		arrivals            []TraceArrival
+		times               []time.Duration
+		absolute, jsonLines bool
+	)
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if len(arrivals) == 0 {
+			jsonLines = strings.HasPrefix(line, "{")
+		}
+
+		var (
+			rawTime string
+			arrival TraceArrival
+			err     error
+		)
+		if jsonLines {
+			rawTime, arrival, err = parseTraceJSONLine(line)
+		} else {
+			rawTime, arrival, err = parseTraceCSVLine(line)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+
+		t, isAbsolute, err := parseTraceTime(rawTime, timeUnit)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		if len(arrivals) == 0 {
+			absolute = isAbsolute
+		} else if isAbsolute != absolute {
+			return nil, fmt.Errorf("line %d: the times can't be both numbers and timestamps", i+1)
+		}
+		arrivals = append(arrivals, arrival)
+		times = append(times, t)
+	}
+	if len(arrivals) == 0 {
+		return nil, errors.New("the trace doesn't have any arrivals")
+	}
+
+	start := times[0]
+	for _, t := range times {
+		if t < start {
+			start = t
+		}
+	}
+	for i := range arrivals {
+		arrivals[i].Offset = types.Duration(times[i] - start)
+	}
+	sort.SliceStable(arrivals, func(i, j int) bool { return arrivals[i].Offset < arrivals[j].Offset })
+	return arrivals, nil
+}
+
+func parseTraceCSVLine(line string) (string, TraceArrival, error) {
+	var arrival TraceArrival
+	fields := strings.SplitN(line, ",", 3)
+	if len(fields) > 1 {
+		arrival.Exec = strings.TrimSpace(fields[1])
+	}
+	if len(fields) > 2 {
+		payload, err := json.Marshal(fields[2])
+		if err != nil {
+			return "", arrival, err
+		}
+		arrival.Payload = payload
+	}
+	return strings.TrimSpace(fields[0]), arrival, nil
+}
+
+func parseTraceJSONLine(line string) (string, TraceArrival, error) {
+	var record struct {
+		Time    json.RawMessage `json:"time"`
+		Exec    string          `json:"exec"`
+		Payload json.RawMessage `json:"payload"`
+	}
+	if err := json.Unmarshal([]byte(line), &record); err != nil {
+		return "", TraceArrival{}, err
+	}
+	arrival := TraceArrival{Exec: record.Exec}
+	if len(record.Payload) != 0 && !bytes.Equal(record.Payload, []byte("null")) {
+		arrival.Payload = record.Payload
+	}
+
+	rawTime := string(record.Time)
+	if strings.HasPrefix(rawTime, `"`) {
+		if err := json.Unmarshal(record.Time, &rawTime); err != nil {
+			return "", arrival, err
+		}
+	}
+	return rawTime, arrival, nil
+}
+
+// parseTraceTime parses the time of an arrival, which is either a number of
+// time units or a RFC3339 timestamp.
+func parseTraceTime(s string, timeUnit time.Duration) (t time.Duration, absolute bool, err error) {
+	if s == "" {
+		return 0, false, errors.New("the time of the arrival is missing")
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		if math.IsInf(f, 0) || math.IsNaN(f) {
+			return 0, false, fmt.Errorf("invalid time '%s'", s)
+		}
+		return time.Duration(f * float64(timeUnit)), false, nil
+	}
+	ts, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return 0, false, fmt.Errorf("the time '%s' is neither a number nor a RFC3339 timestamp", s)
+	}
+	return time.Duration(ts.UnixNano()), true, nil
+}
+
+// TraceReplay starts the iterations at the offsets of the arrivals of a trace.
+type TraceReplay struct {
+	*BaseExecutor
+	config TraceReplayConfig
+	et     *lib.ExecutionTuple
+}
+
+// Make sure we implement the lib.Executor interface.
+var (
+	_ lib.Executor                     = &TraceReplay{}
+	_ lib.ScenarioControllableExecutor = &TraceReplay{}
+	_ lib.RateMultipliableExecutor     = &TraceReplay{}
+)
+
+// Init values needed for the execution
+func (tr *TraceReplay) Init(_ context.Context) error {
+	// err should always be nil, because Init() won't be called for executors
+	// with no work, as determined by their config's HasWork() method.
+	et, err := tr.BaseExecutor.executionState.ExecutionTuple.GetNewExecutionTupleFromValue(tr.config.MaxVUs.Int64)
+	tr.et = et
+	tr.iterSegIndex = lib.NewSegmentedIndex(et)
+
+	return err
+}
+
+// GetRateMultiplier returns the multiplier of the arrivals.
+func (tr TraceReplay) GetRateMultiplier() float64 {
+	return tr.control.getRateMultiplier()
+}
+
+// SetRateMultiplier multiplies the arrivals while the scenario is running,
+// e.g. with 2 two iterations are started for each of them.
+func (tr TraceReplay) SetRateMultiplier(multiplier float64) error {
+	return tr.control.setRateMultiplier(multiplier)
+}
+
+// getLocalArrivals returns the indexes of the arrivals of the execution
+// segment, which are striped like the iterations of the arrival-rate
+// executors, so the instances of a distributed test split the trace.
+func (tr TraceReplay) getLocalArrivals() []int64 {
+	var local []int64
+	start, offsets, _ := tr.et.GetStripedOffsets()
+	for li, gi := 0, start; gi < int64(len(tr.config.Arrivals)); li, gi = li+1, gi+offsets[li%len(offsets)] {
+		local = append(local, gi)
+	}
+	return local
+}
+
+// Run starts the iterations of the arrivals of the trace, at their offsets.
+//
+//nolint:funlen,cyclop
+func (tr TraceReplay) Run(parentCtx context.Context, out chan<- metrics.SampleContainer) (err error) {
+	gracefulStop := tr.config.GetGracefulStop()
+	duration := tr.config.getDuration()
+	preAllocatedVUs := tr.config.GetPreAllocatedVUs(tr.executionState.ExecutionTuple)
+	maxVUs := tr.config.GetMaxVUs(tr.executionState.ExecutionTuple)
+	localArrivals := tr.getLocalArrivals()
+
+	// Make sure the log and the progress bar have accurate information
+	tr.logger.WithFields(logrus.Fields{
+		"maxVUs": maxVUs, "preAllocatedVUs": preAllocatedVUs, "duration": duration,
+		"arrivals": len(localArrivals), "type": tr.config.GetType(),
+	}).Debug("Starting executor run...")
+
+	activeVUsWg := &sync.WaitGroup{}
+
+	returnedVUs := make(chan struct{})
+	waitOnProgressChannel := make(chan struct{})
+	startTime, maxDurationCtx, regDurationCtx, cancel := tr.getDurationContexts(parentCtx, duration, gracefulStop)
+	defer func() {
+		cancel()
+		<-waitOnProgressChannel
+	}()
+
+	vusPool := newActiveVUPool(tr.executionState)
+	defer func() {
+		// Make sure all VUs aren't executing iterations anymore, for the cancel()
+		// below to deactivate them.
+		<-returnedVUs
+		// first close the vusPool so we wait for the gracefulShutdown
+		vusPool.Close()
+		cancel()
+		activeVUsWg.Wait()
+	}()
+	activeVUsCount := uint64(0)
+	startedArrivals := uint64(0)
+
+	vusFmt := pb.GetFixedLengthIntFormat(maxVUs)
+	arrivalsFmt := pb.GetFixedLengthIntFormat(int64(len(localArrivals)))
+	progressFn := func() (float64, []string) {
+		spent := time.Since(startTime)
+		currActiveVUs := atomic.LoadUint64(&activeVUsCount)
+		progVUs := fmt.Sprintf(vusFmt+"/"+vusFmt+" VUs",
+			vusPool.Running(), currActiveVUs)
+		progArrivals := fmt.Sprintf(arrivalsFmt+"/"+arrivalsFmt+" arrivals",
+			atomic.LoadUint64(&startedArrivals), len(localArrivals))
+
+		right := []string{progVUs, duration.String(), progArrivals}
+
+		if spent > duration {
+			return 1, right
+		}
+
+		spentDuration := pb.GetFixedLengthDuration(spent, duration)
+		progDur := fmt.Sprintf("%s/%s", spentDuration, duration)
+		right[1] = progDur
+
+		return math.Min(1, float64(spent)/float64(duration)), right
+	}
+	tr.progress.Modify(pb.WithProgress(progressFn))
+	maxDurationCtx = lib.WithScenarioState(maxDurationCtx, &lib.ScenarioState{
+		Name:       tr.config.Name,
+		Executor:   tr.config.Type,
+		StartTime:  startTime,
+		ProgressFn: progressFn,
+	})
+
+	go func() {
+		trackProgress(parentCtx, maxDurationCtx, regDurationCtx, &tr, progressFn)
+		close(waitOnProgressChannel)
+	}()
+
+	returnVU := func(u lib.InitializedVU) {
+		// Return the VU without decreasing the global active VU counter, which
+		// is done in the goroutine started by activeVUPool.AddVU, whenever the
+		// VU finishes running an iteration. This results in a more accurate
+		// report of VUs that are _actually_ active.
+		tr.executionState.ReturnVU(u, false)
+		activeVUsWg.Done()
+	}
+
+	var unsupportedOptionsWarning sync.Once
+	runIterationBasic := getIterationRunner(tr.executionState, tr.logger, tr.execMix)
+	activateVU := func(initVU lib.InitializedVU) lib.ActiveVU {
+		activeVUsWg.Add(1)
+		activeVU := initVU.Activate(getVUActivationParams(
+			maxDurationCtx, tr.config.BaseConfig, returnVU,
+			tr.nextIterationCounters,
+		))
+		if _, ok := activeVU.(lib.ActiveVUWithIterationOptions); !ok {
+			unsupportedOptionsWarning.Do(func() {
+				tr.logger.Warn("The VUs don't support the exec and payload of the arrivals, they are ignored")
+			})
+		}
+		atomic.AddUint64(&activeVUsCount, 1)
+		vusPool.AddVU(maxDurationCtx, activeVU, runIterationBasic)
+		return activeVU
+	}
+
+	remainingUnplannedVUs := maxVUs - preAllocatedVUs
+	makeUnplannedVUCh := make(chan struct{})
+	defer close(makeUnplannedVUCh)
+	go func() {
+		defer close(returnedVUs)
+		for range makeUnplannedVUCh {
+			tr.logger.Debug("Starting initialization of an unplanned VU...")
+			initVU, err := tr.executionState.GetUnplannedVU(maxDurationCtx, tr.logger)
+			if err != nil {
+				// TODO figure out how to return it to the Run goroutine
+				tr.logger.WithError(err).Error("Error while allocating unplanned VU")
+			} else {
+				tr.logger.Debug("The unplanned VU finished initializing successfully!")
+				activateVU(initVU)
+			}
+		}
+	}()
+
+	// Get the pre-allocated VUs in the local buffer
+	for i := int64(0); i < preAllocatedVUs; i++ {
+		initVU, err := tr.executionState.GetPlannedVU(tr.logger, false)
+		if err != nil {
+			return err
+		}
+		activateVU(initVU)
+	}
+
+	timer := time.NewTimer(time.Hour * 24)
+	defer timer.Stop()
+
+	droppedIterationMetric := tr.executionState.Test.BuiltinMetrics.DroppedIterations
+	shownWarning := false
+	metricTags := tr.getMetricTags(nil)
+	for _, gi := range localArrivals {
+		arrival := tr.config.Arrivals[gi]
+		if wait := tr.config.getOffset(arrival) - time.Since(startTime); wait > 0 {
+			timer.Reset(wait)
+			select {
+			case <-timer.C:
+			case <-regDurationCtx.Done():
+				// The regular duration ends after the offset of the last
+				// arrival, so the remaining arrivals are overdue and still
+				// started, unless the scenario was stopped or the test run
+				// was aborted.
+				if parentCtx.Err() != nil || tr.IsScenarioStopped() {
+					return nil
+				}
+			}
+		}
+
+		atomic.AddUint64(&startedArrivals, 1)
+		opts := lib.IterationOptions{Exec: arrival.Exec, Payload: arrival.Payload}
+		// the arrival is skipped while the scenario is paused, and it's
+		// multiplied by the rate multiplier
+		for n := tr.control.iterationsToStart(); n > 0; n-- {
+			if vusPool.TryRunIterationWith(opts) {
+				continue
+			}
+
+			// Since there aren't any free VUs available, consider this iteration
+			// dropped - we aren't going to try to recover it, but
+			metrics.PushIfNotDone(parentCtx, out, metrics.Sample{
+				TimeSeries: metrics.TimeSeries{
+					Metric: droppedIterationMetric,
+					Tags:   metricTags,
+				},
+				Time:  time.Now(),
+				Value: 1,
+			})
+
+			// We'll try to start allocating another VU in the background,
+			// non-blockingly, if we have remainingUnplannedVUs...
+			if remainingUnplannedVUs == 0 {
+				if !shownWarning {
+					tr.logger.Warningf("Insufficient VUs, reached %d active VUs and cannot initialize more", maxVUs)
+					shownWarning = true
+				}
+				continue
+			}
+
+			select {
+			case makeUnplannedVUCh <- struct{}{}: // great!
+				remainingUnplannedVUs--
+			default: // we're already allocating a new VU
+			}
+		}
+	}
+
+	// The graceful stop of the iterations in progress starts at the end of the
+	// regular duration, a time unit after the last arrival
+	<-regDurationCtx.Done()
+	return nil
+}
@@ -0,0 +1,222 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v3"
+
+	"github.com/liuxd6825/k6server/lib"
+	"github.com/liuxd6825/k6server/lib/testutils/minirunner"
+	"github.com/liuxd6825/k6server/lib/types"
+	"github.com/liuxd6825/k6server/metrics"
+)
+
+func TestParseTrace(t *testing.T) {
+	t.Parallel()
+
+	t.Run("csv", func(t *testing.T) {
+		t.Parallel()
+		arrivals, err := parseTrace([]byte(
+			"# time,exec,payload\n12.5,login,{\"user\": \"a, b\"}\n10\n\n11,,/checkout\n",
+		), time.Second)
+		require.NoError(t, err)
+		assert.Equal(t, []TraceArrival{
+			{Offset: 0},
+			{Offset: types.Duration(time.Second), Payload: json.RawMessage(`"/checkout"`)},
+			{Offset: types.Duration(2500 * time.Millisecond), Exec: "login", Payload: json.RawMessage(`"{\"user\": \"a, b\"}"`)},
+		}, arrivals)
+	})
+
+	t.Run("json lines", func(t *testing.T) {
+		t.Parallel()
+		arrivals, err := parseTrace([]byte(
+			`{"time": "2024-05-01T10:00:01.5Z", "exec": "search", "payload": {"q": "k6"}}`+"\n"+
+				`{"time": "2024-05-01T10:00:00Z"}`+"\n",
+		), time.Second)
+		require.NoError(t, err)
+		assert.Equal(t, []TraceArrival{
+			{Offset: 0},
+			{Offset: types.Duration(1500 * time.Millisecond), Exec: "search", Payload: json.RawMessage(`{"q": "k6"}`)},
+		}, arrivals)
+	})
+
+	t.Run("time unit", func(t *testing.T) {
+		t.Parallel()
+		arrivals, err := parseTrace([]byte("1000\n1250\n"), time.Millisecond)
+		require.NoError(t, err)
+		assert.Equal(t, []TraceArrival{{Offset: 0}, {Offset: types.Duration(250 * time.Millisecond)}}, arrivals)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		t.Parallel()
+		for _, data := range []string{
+			"",
+			"# only a comment\n",
+			"1\nsoon\n",
+			"1\n2024-05-01T10:00:00Z\n",
+			`{"time": 1` + "\n",
+			`{"exec": "login"}` + "\n",
+		} {
+			_, err := parseTrace([]byte(data), time.Second)
+			assert.Error(t, err, data)
+		}
+	})
+}
+
+func TestTraceReplayConfigLoadFiles(t *testing.T) {
+	t.Parallel()
+
+	config := NewTraceReplayConfig("replay")
+	config.File = null.StringFrom("trace.csv")
+	config.PreAllocatedVUs = null.IntFrom(1)
+	assert.NotEmpty(t, config.Validate())
+
+	loaded, err := config.LoadFiles(func(filename string) ([]byte, error) {
+		assert.Equal(t, "trace.csv", filename)
+		return []byte("0,login\n2,browse\n4,login\n"), nil
+	})
+	require.NoError(t, err)
+	assert.Empty(t, loaded.Validate())
+	assert.Equal(t, []string{"login", "browse"}, loaded.(lib.MultiExecExecutorConfig).GetExecs())
+	assert.Empty(t, config.Arrivals, "the original config isn't modified")
+
+	// the arrivals which are already loaded, e.g. from an archive, are kept
+	reloaded, err := loaded.(lib.ExecutorConfigWithFiles).LoadFiles(func(string) ([]byte, error) {
+		return nil, errors.New("the file shouldn't be read")
+	})
+	require.NoError(t, err)
+	assert.Equal(t, loaded, reloaded)
+
+	_, err = config.LoadFiles(func(string) ([]byte, error) { return []byte("# empty\n"), nil })
+	assert.Error(t, err)
+
+	config.Speed = null.FloatFrom(0)
+	config.Arrivals = []TraceArrival{{Offset: types.Duration(time.Second)}, {Offset: 0}}
+	assert.Len(t, config.Validate(), 2)
+}
+
+func TestTraceReplayRun(t *testing.T) {
+	t.Parallel()
+
+	type iteration struct {
+		offset time.Duration
+		opts   lib.IterationOptions
+	}
+	var (
+		mu         sync.Mutex
+		iterations []iteration
+	)
+	start := time.Now()
+	runner := simpleRunner(func(ctx context.Context, _ *lib.State) error {
+		opts, _ := minirunner.IterationOptionsFromContext(ctx)
+		mu.Lock()
+		iterations = append(iterations, iteration{offset: time.Since(start), opts: opts})
+		mu.Unlock()
+		return nil
+	})
+	config := &TraceReplayConfig{
+		// the regular duration ends a time unit after the last iteration
+		BaseConfig: BaseConfig{GracefulStop: types.NullDurationFrom(time.Second)},
+		TimeUnit:   types.NullDurationFrom(200 * time.Millisecond),
+		Speed:      null.FloatFrom(2),
+		Arrivals: []TraceArrival{
+			{Offset: 0},
+			{Offset: types.Duration(200 * time.Millisecond), Exec: "login"},
+			{Offset: types.Duration(200 * time.Millisecond), Payload: json.RawMessage(`{"id":1}`)},
+			{Offset: types.Duration(time.Second)},
+		},
+		PreAllocatedVUs: null.IntFrom(2),
+		MaxVUs:          null.IntFrom(2),
+	}
+	test := setupExecutorTest(t, "", "", lib.Options{}, runner, config)
+	defer test.cancel()
+
+	start = time.Now()
+	engineOut := make(chan metrics.SampleContainer, 1000)
+	require.NoError(t, test.executor.Run(test.ctx, engineOut))
+	assert.InDelta(t, 600*time.Millisecond, time.Since(start), float64(100*time.Millisecond))
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, iterations, 4)
+	for i, offset := range []time.Duration{0, 100 * time.Millisecond, 100 * time.Millisecond, 500 * time.Millisecond} {
+		assert.InDelta(t, offset, iterations[i].offset, float64(50*time.Millisecond), i)
+	}
+	assert.ElementsMatch(t, []lib.IterationOptions{
+		{}, {Exec: "login"}, {Payload: json.RawMessage(`{"id":1}`)}, {},
+	}, []lib.IterationOptions{iterations[0].opts, iterations[1].opts, iterations[2].opts, iterations[3].opts})
+}
+
+func TestTraceReplayLastArrival(t *testing.T) {
+	t.Parallel()
+
+	for name, arrivals := range map[string][]TraceArrival{
+		"single arrival": {{Offset: 0}},
+		"last arrival":   {{Offset: 0}, {Offset: types.Duration(100 * time.Millisecond)}},
+	} {
+		arrivals := arrivals
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			runner := simpleRunner(func(_ context.Context, _ *lib.State) error {
+				time.Sleep(50 * time.Millisecond)
+				return nil
+			})
+			// without a graceful stop, the iteration of the last arrival is
+			// still finished before the end of the regular duration
+			config := &TraceReplayConfig{
+				BaseConfig:      BaseConfig{GracefulStop: types.NullDurationFrom(0)},
+				TimeUnit:        types.NullDurationFrom(200 * time.Millisecond),
+				Speed:           null.FloatFrom(1),
+				Arrivals:        arrivals,
+				PreAllocatedVUs: null.IntFrom(1),
+				MaxVUs:          null.IntFrom(1),
+			}
+			test := setupExecutorTest(t, "", "", lib.Options{}, runner, config)
+			defer test.cancel()
+
+			engineOut := make(chan metrics.SampleContainer, 1000)
+			require.NoError(t, test.executor.Run(test.ctx, engineOut))
+			assert.Equal(t, uint64(len(arrivals)), test.state.GetFullIterationCount())
+			assert.Equal(t, uint64(0), test.state.GetPartialIterationCount())
+		})
+	}
+}
+
+func TestTraceReplayExecutionSegments(t *testing.T) {
+	t.Parallel()
+
+	arrivals := make([]TraceArrival, 10)
+	for i := range arrivals {
+		arrivals[i].Offset = types.Duration(time.Duration(i) * time.Second)
+	}
+	config := &TraceReplayConfig{
+		Speed:           null.FloatFrom(1),
+		Arrivals:        arrivals,
+		PreAllocatedVUs: null.IntFrom(3),
+		MaxVUs:          null.IntFrom(3),
+	}
+	runner := simpleRunner(func(_ context.Context, _ *lib.State) error { return nil })
+
+	seen := make(map[int64]bool)
+	total := 0
+	for _, segment := range []string{"0:1/3", "1/3:2/3", "2/3:1"} {
+		test := setupExecutorTest(t, segment, "0,1/3,2/3,1", lib.Options{}, runner, config)
+		local := test.executor.(*TraceReplay).getLocalArrivals()
+		assert.NotEmpty(t, local, segment)
+		for _, gi := range local {
+			assert.False(t, seen[gi], "arrival %d is replayed by several segments", gi)
+			seen[gi] = true
+		}
+		total += len(local)
+		test.cancel()
+	}
+	assert.Equal(t, len(arrivals), total)
+}
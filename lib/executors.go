@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math"
 	"sort"
 	"strings"
 	"sync"
@@ -65,6 +66,13 @@ type ExecutorConfig interface {
 	GetStartTime() time.Duration
 	GetGracefulStop() time.Duration
 
+	// GetStartAfter returns the names of the scenarios which have to finish,
+	// including their graceful stop, before the start time of this one is
+	// counted. GetStartWhen returns whether all of them, or any of them, have
+	// to finish.
+	GetStartAfter() []string
+	GetStartWhen() string
+
 	// This is used to validate whether a particular script can run in the cloud
 	// or, in the future, in the native k6 distributed execution. Currently only
 	// the externally-controlled executor should return false.
@@ -93,6 +101,33 @@ type ExecutorConfig interface {
 	HasWork(*ExecutionTuple) bool
 }
 
+// ExecutorConfigWithFiles should be implemented by the executor configs which
+// load some of their options from files, e.g. the trace of the trace-replay
+// executor. LoadFiles is called when the test is loaded, before the config is
+// validated, and returns the config with the loaded options, which is the one
+// archived with the test, so it shouldn't need the files anymore.
+type ExecutorConfigWithFiles interface {
+	ExecutorConfig
+	LoadFiles(readFile func(filename string) ([]byte, error)) (ExecutorConfig, error)
+}
+
+// MultiExecExecutorConfig should be implemented by the executor configs whose
+// iterations can run other exported functions than the one of GetExec(), so
+// they can be validated as well.
+type MultiExecExecutorConfig interface {
+	ExecutorConfig
+	// GetExecs returns the exported functions, besides the one of GetExec().
+	GetExecs() []string
+}
+
+// The values of the startWhen option of the scenarios, i.e. whether a scenario
+// starts after all of the scenarios of its startAfter have finished, or after
+// the first one of them has.
+const (
+	StartWhenAll = "all"
+	StartWhenAny = "any"
+)
+
 // ScenarioOptions are options specific to a scenario. These include k6 browser
 // options, which are validated by the browser module, and not by k6 core.
 type ScenarioOptions struct {
@@ -135,6 +170,88 @@ type LiveUpdatableExecutor interface {
 	UpdateConfig(ctx context.Context, newConfig interface{}) error
 }
 
+// ScenarioControllableExecutor should be implemented by the executors whose
+// scenario can be paused, resumed and gracefully stopped on its own, in the
+// middle of the test execution, without affecting the other scenarios.
+type ScenarioControllableExecutor interface {
+	IsScenarioPaused() bool
+	SetScenarioPaused(bool) error
+	IsScenarioStopped() bool
+	// StopScenario stops starting new iterations, and interrupts the ones in
+	// progress after the graceful stop period of the scenario.
+	StopScenario()
+}
+
+// RateMultipliableExecutor should be implemented by the executors whose
+// target rate of iterations can be multiplied in the middle of the test
+// execution, i.e. the arrival-rate ones.
+type RateMultipliableExecutor interface {
+	GetRateMultiplier() float64
+	SetRateMultiplier(float64) error
+}
+
+// ThresholdsWindow evaluates the thresholds of the test run against only the
+// metric samples since it was last reset, e.g. of a step of an executor.
+type ThresholdsWindow interface {
+	// HasThresholds returns whether the test run has any thresholds to
+	// evaluate, i.e. they're defined and not disabled.
+	HasThresholds() bool
+	// Reset drops the samples of the window and starts it again.
+	Reset()
+	// Breached returns the metrics whose thresholds fail in the window.
+	Breached() []string
+	// Close stops collecting the samples of the window.
+	Close()
+}
+
+// ThresholdsDrivenExecutor should be implemented by the executors which adapt
+// their load to the outcome of the thresholds, e.g. the breaking-point one.
+// They are given a function to create the windows of the thresholds before
+// the test run starts.
+type ThresholdsDrivenExecutor interface {
+	SetThresholdsWindows(newWindow func() ThresholdsWindow)
+}
+
+// Capacity is the highest level of load which passed the thresholds, found by
+// an executor searching for it, e.g. the breaking-point one.
+type Capacity struct {
+	Scenario string
+	Value    int64
+	// Unit is the unit of the value, e.g. VUs or iterations/s.
+	Unit string
+	// AtLeast is true when the capacity may be higher than the value, i.e.
+	// when the search didn't finish or the thresholds passed up to the
+	// maximum level.
+	AtLeast bool
+}
+
+// CapacityReportingExecutor should be implemented by the executors which find
+// a capacity, for the end-of-test summary. It returns false if the executor
+// didn't start searching for it.
+type CapacityReportingExecutor interface {
+	GetCapacity() (Capacity, bool)
+}
+
+// MetricWindow aggregates the values of a metric since it was last reset,
+// e.g. its p(95) during the last interval of an executor.
+type MetricWindow interface {
+	// Reset drops the samples of the window and starts it again.
+	Reset()
+	// Value returns the aggregated value of the samples of the window, or
+	// false if it doesn't have any.
+	Value() (float64, bool)
+	// Close stops collecting the samples of the window.
+	Close()
+}
+
+// MetricsDrivenExecutor should be implemented by the executors which adapt
+// their load to the values of a metric, e.g. the closed-loop one. They are
+// given a function to create the windows of the metrics before the test run
+// starts.
+type MetricsDrivenExecutor interface {
+	SetMetricWindows(newWindow func(metricName, aggregation string) (MetricWindow, error))
+}
+
 // ExecutorConfigConstructor is a simple function that returns a concrete
 // Config instance with the specified name and all default values correctly
 // initialized
@@ -203,7 +320,123 @@ func (scs ScenarioConfigs) Validate() (errors []error) {
 				fmt.Errorf("scenario %s has configuration errors: %s", name, ConcatErrors(execErr, ", ")))
 		}
 	}
-	return errors
+	return append(errors, scs.validateStartAfter()...)
+}
+
+// validateStartAfter checks that the scenarios start after other existing
+// scenarios, and that they don't wait for each other.
+func (scs ScenarioConfigs) validateStartAfter() (errors []error) {
+	names := make([]string, 0, len(scs))
+	for name := range scs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		for _, dependency := range scs[name].GetStartAfter() {
+			switch _, exists := scs[dependency]; {
+			case dependency == name:
+				errors = append(errors, fmt.Errorf("scenario %s can't start after itself", name))
+			case !exists:
+				errors = append(errors, fmt.Errorf("scenario %s starts after the unknown scenario '%s'", name, dependency))
+			}
+		}
+	}
+	if len(errors) > 0 {
+		return errors
+	}
+
+	// a depth-first search, with the scenarios of the current path in the
+	// path slice, finds the first cycle of the dependencies
+	visited := make(map[string]bool, len(scs))
+	var path []string
+	var findCycle func(name string) []string
+	findCycle = func(name string) []string {
+		for i, previous := range path {
+			if previous == name {
+				return append(path[i:len(path):len(path)], name)
+			}
+		}
+		if visited[name] {
+			return nil
+		}
+		visited[name] = true
+		path = append(path, name)
+		for _, dependency := range scs[name].GetStartAfter() {
+			if cycle := findCycle(dependency); cycle != nil {
+				return cycle
+			}
+		}
+		path = path[:len(path)-1]
+		return nil
+	}
+	for _, name := range names {
+		if cycle := findCycle(name); cycle != nil {
+			return []error{fmt.Errorf("scenarios can't start after each other: %s", strings.Join(cycle, " -> "))}
+		}
+	}
+	return nil
+}
+
+// GetStartOffsets returns the planned start time of each scenario, relative to
+// the beginning of the test. That's its startTime, counted from the planned
+// end of the scenarios it starts after, including their graceful stop, e.g. a
+// scenario with a startTime of 10s, which starts after one planned to run for
+// 1m, starts at 1m10s. The scenarios with no work for the execution segment
+// end when they start.
+func (scs ScenarioConfigs) GetStartOffsets(et *ExecutionTuple) map[string]time.Duration {
+	return scs.getStartOffsets(et, false)
+}
+
+// getEarliestStartOffsets returns the earliest time each scenario can start,
+// relative to the beginning of the test. The scenarios start after the ones
+// they depend on actually finish, which can be well before their planned end,
+// e.g. when their iterations are done or when they're stopped, so that's like
+// their dependencies had no work at all.
+func (scs ScenarioConfigs) getEarliestStartOffsets(et *ExecutionTuple) map[string]time.Duration {
+	return scs.getStartOffsets(et, true)
+}
+
+func (scs ScenarioConfigs) getStartOffsets(et *ExecutionTuple, earliest bool) map[string]time.Duration {
+	offsets := make(map[string]time.Duration, len(scs))
+	resolving := make(map[string]bool, len(scs))
+
+	var resolve func(name string) time.Duration
+	resolve = func(name string) time.Duration {
+		if offset, ok := offsets[name]; ok {
+			return offset
+		}
+		config, exists := scs[name]
+		if !exists || resolving[name] {
+			return 0 // an invalid dependency, which Validate() reports
+		}
+		resolving[name] = true
+
+		var after time.Duration
+		for i, dependency := range config.GetStartAfter() {
+			end := resolve(dependency)
+			if dependencyConfig, exists := scs[dependency]; exists && !earliest && dependencyConfig.HasWork(et) {
+				duration, _ := GetEndOffset(dependencyConfig.GetExecutionRequirements(et))
+				end += duration
+			}
+			switch {
+			case i == 0:
+				after = end
+			case config.GetStartWhen() == StartWhenAny && end < after:
+				after = end
+			case config.GetStartWhen() != StartWhenAny && end > after:
+				after = end
+			}
+		}
+
+		offsets[name] = after + config.GetStartTime()
+		return offsets[name]
+	}
+
+	for name := range scs {
+		resolve(name)
+	}
+	return offsets
 }
 
 // GetSortedConfigs returns a slice with the executor configurations,
@@ -212,12 +445,20 @@ func (scs ScenarioConfigs) Validate() (errors []error) {
 // them) and avoid the unpredictable iterations over Go maps. Slices allow us
 // constant-time lookups and ordered iterations.
 //
-// The configs in the returned slice will be sorted by their start times in an
-// ascending order, and alphabetically by their names (which are unique) if
-// there are ties.
+// The configs in the returned slice will be sorted by their planned start
+// times, from GetStartOffsets(), in an ascending order, and alphabetically by
+// their names (which are unique) if there are ties.
 func (scs ScenarioConfigs) GetSortedConfigs() []ExecutorConfig {
 	configs := make([]ExecutorConfig, len(scs))
 
+	// The start offsets only depend on the execution segment when some of the
+	// scenarios have no work for it, so the ones of the whole test are used,
+	// and the order is the same for all the instances of a distributed test.
+	startOffsets := make(map[string]time.Duration, len(scs))
+	if et, err := NewExecutionTuple(nil, nil); err == nil {
+		startOffsets = scs.GetStartOffsets(et)
+	}
+
 	// Populate the configs slice with sorted executor configs
 	i := 0
 	for _, config := range scs {
@@ -226,9 +467,9 @@ func (scs ScenarioConfigs) GetSortedConfigs() []ExecutorConfig {
 	}
 	sort.Slice(configs, func(a, b int) bool { // sort by (start time, name)
 		switch {
-		case configs[a].GetStartTime() < configs[b].GetStartTime():
+		case startOffsets[configs[a].GetName()] < startOffsets[configs[b].GetName()]:
 			return true
-		case configs[a].GetStartTime() == configs[b].GetStartTime():
+		case startOffsets[configs[a].GetName()] == startOffsets[configs[b].GetName()]:
 			return strings.Compare(configs[a].GetName(), configs[b].GetName()) < 0
 		default:
 			return false
@@ -238,12 +479,65 @@ func (scs ScenarioConfigs) GetSortedConfigs() []ExecutorConfig {
 	return configs
 }
 
+// widenExecutionSteps returns the execution requirements of a scenario that
+// can start at any moment in a window after its earliest start. They're the
+// most VUs the scenario can need at each moment, relative to its earliest
+// start, and they end when the scenario ends if it starts at the end of the
+// window.
+func widenExecutionSteps(steps []ExecutionStep, window time.Duration) []ExecutionStep {
+	if window <= 0 || len(steps) == 0 {
+		return steps
+	}
+
+	// Every step is needed from its offset, if the scenario starts as early as
+	// possible, until the offset of the next one, if it starts as late as
+	// possible. The last step is needed until the end of the test.
+	ends := make([]time.Duration, len(steps))
+	offsets := make([]time.Duration, 0, 2*len(steps))
+	for i, step := range steps {
+		ends[i] = time.Duration(math.MaxInt64)
+		if i+1 < len(steps) {
+			ends[i] = steps[i+1].TimeOffset + window
+			offsets = append(offsets, ends[i])
+		}
+		offsets = append(offsets, step.TimeOffset)
+	}
+	sort.Slice(offsets, func(a, b int) bool { return offsets[a] < offsets[b] })
+
+	widened := make([]ExecutionStep, 0, len(offsets))
+	for i, offset := range offsets {
+		if i > 0 && offset == offsets[i-1] {
+			continue
+		}
+		current := ExecutionStep{TimeOffset: offset}
+		for j, step := range steps {
+			if step.TimeOffset <= offset && offset < ends[j] {
+				if step.PlannedVUs > current.PlannedVUs {
+					current.PlannedVUs = step.PlannedVUs
+				}
+				if step.MaxUnplannedVUs > current.MaxUnplannedVUs {
+					current.MaxUnplannedVUs = step.MaxUnplannedVUs
+				}
+			}
+		}
+		widened = append(widened, current)
+	}
+	return widened
+}
+
 // GetFullExecutionRequirements combines the execution requirements from all of
-// the configured executors. It takes into account their start times and their
-// individual VU requirements and calculates the total VU requirements for each
-// moment in the test execution.
+// the configured executors. It takes into account their planned start times,
+// from GetStartOffsets(), and their individual VU requirements and calculates
+// the total VU requirements for each moment in the test execution.
+//
+// The scenarios that start after other ones can start earlier than planned,
+// when the ones they start after finish early, so their requirements are the
+// most VUs they can need at each moment between their earliest and planned
+// starts, and the VUs are there whenever they start.
 func (scs ScenarioConfigs) GetFullExecutionRequirements(et *ExecutionTuple) []ExecutionStep {
 	sortedConfigs := scs.GetSortedConfigs()
+	startOffsets := scs.GetStartOffsets(et)
+	earliestStartOffsets := scs.getEarliestStartOffsets(et)
 
 	// Combine the steps and requirements from all different executors, and
 	// sort them by their time offset, counting the executors' startTimes as
@@ -254,8 +548,12 @@ func (scs ScenarioConfigs) GetFullExecutionRequirements(et *ExecutionTuple) []Ex
 	}
 	trackedSteps := []trackedStep{}
 	for configID, config := range sortedConfigs { // orderly iteration over a slice
-		configStartTime := config.GetStartTime()
+		configStartTime := startOffsets[config.GetName()]
 		configSteps := config.GetExecutionRequirements(et)
+		if earliest := earliestStartOffsets[config.GetName()]; earliest < configStartTime {
+			configSteps = widenExecutionSteps(configSteps, configStartTime-earliest)
+			configStartTime = earliest
+		}
 		for _, cs := range configSteps {
 			cs.TimeOffset += configStartTime // add the executor start time to the step time offset
 			trackedSteps = append(trackedSteps, trackedStep{cs, configID})
@@ -0,0 +1,199 @@
+// Package fault contains the rules of the faults that the server mode injects
+// into the responses of the routes, which can be declared by the scripts, in
+// the options, and changed at runtime through the REST API.
+package fault
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/liuxd6825/k6server/lib/types"
+)
+
+// Distribution is the probability distribution of the added latency.
+type Distribution string
+
+// The supported latency distributions.
+const (
+	// Fixed always adds the Mean latency.
+	Fixed Distribution = "fixed"
+	// Uniform adds a latency between Min and Max.
+	Uniform Distribution = "uniform"
+	// Normal adds a latency around Mean, with a standard deviation of StdDev.
+	Normal Distribution = "normal"
+	// Exponential adds a latency with a mean of Mean, like the delays between
+	// the events of a Poisson process.
+	Exponential Distribution = "exponential"
+)
+
+// Rule is the set of faults which are injected into the responses of a route.
+// Each of the faults is optional, and the ones with a rate are only injected
+// into that fraction of the responses.
+type Rule struct {
+	Latency  *Latency  `json:"latency,omitempty"`
+	Error    *Error    `json:"error,omitempty"`
+	Reset    *Reset    `json:"reset,omitempty"`
+	SlowDrip *SlowDrip `json:"slowDrip,omitempty"`
+	Truncate *Truncate `json:"truncate,omitempty"`
+}
+
+// Latency delays the handling of the requests.
+type Latency struct {
+	Distribution Distribution   `json:"distribution,omitempty"`
+	Mean         types.Duration `json:"mean,omitempty"`
+	StdDev       types.Duration `json:"stdDev,omitempty"`
+	Min          types.Duration `json:"min,omitempty"`
+	// Max is the upper bound of the uniform distribution, and a cap for the
+	// other ones, if it's set.
+	Max types.Duration `json:"max,omitempty"`
+}
+
+// Error responds with an error status code instead of handling the requests.
+type Error struct {
+	Rate   float64 `json:"rate"`
+	Status int     `json:"status,omitempty"`
+	Body   string  `json:"body,omitempty"`
+}
+
+// Reset closes the connection abruptly instead of responding.
+type Reset struct {
+	Rate float64 `json:"rate"`
+}
+
+// SlowDrip sends the body of the responses in small chunks, waiting between
+// them.
+type SlowDrip struct {
+	Rate      float64        `json:"rate"`
+	ChunkSize int            `json:"chunkSize,omitempty"`
+	Interval  types.Duration `json:"interval"`
+}
+
+// Truncate sends only a part of the body of the responses, while their
+// Content-Length header is still the one of the whole body, and then closes
+// the connection.
+type Truncate struct {
+	Rate float64 `json:"rate"`
+	// Ratio is the fraction of the body which is sent.
+	Ratio float64 `json:"ratio"`
+}
+
+// Validate checks if the rule is valid, and sets the defaults of its unset
+// fields.
+func (r *Rule) Validate() error {
+	var errs []error
+	if r.Latency != nil {
+		errs = append(errs, r.Latency.validate())
+	}
+	if r.Error != nil {
+		errs = append(errs, validateRate("error", r.Error.Rate))
+		if r.Error.Status == 0 {
+			r.Error.Status = http.StatusInternalServerError
+		}
+		if r.Error.Status < 100 || r.Error.Status > 599 {
+			errs = append(errs, fmt.Errorf("the error status should be between 100 and 599, got %d", r.Error.Status))
+		}
+	}
+	if r.Reset != nil {
+		errs = append(errs, validateRate("reset", r.Reset.Rate))
+	}
+	if r.SlowDrip != nil {
+		errs = append(errs, validateRate("slowDrip", r.SlowDrip.Rate))
+		if r.SlowDrip.ChunkSize == 0 {
+			r.SlowDrip.ChunkSize = 1
+		}
+		if r.SlowDrip.ChunkSize < 0 {
+			errs = append(errs, fmt.Errorf("the slowDrip chunk size can't be negative, got %d", r.SlowDrip.ChunkSize))
+		}
+		if r.SlowDrip.Interval <= 0 {
+			errs = append(errs, fmt.Errorf("the slowDrip interval should be more than 0, got %s", r.SlowDrip.Interval))
+		}
+	}
+	if r.Truncate != nil {
+		errs = append(errs, validateRate("truncate", r.Truncate.Rate))
+		if r.Truncate.Ratio < 0 || r.Truncate.Ratio >= 1 {
+			errs = append(errs, fmt.Errorf("the truncate ratio should be in [0, 1), got %v", r.Truncate.Ratio))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (l *Latency) validate() error {
+	if l.Distribution == "" {
+		l.Distribution = Fixed
+	}
+	switch {
+	case l.Mean < 0, l.StdDev < 0, l.Min < 0, l.Max < 0:
+		return errors.New("the latency durations can't be negative")
+	case l.Max > 0 && l.Min > l.Max:
+		return fmt.Errorf("the minimum latency (%s) can't be more than the maximum (%s)", l.Min, l.Max)
+	}
+	switch l.Distribution {
+	case Fixed, Normal, Exponential:
+		return nil
+	case Uniform:
+		if l.Max == 0 {
+			return errors.New("the maximum latency is required for the uniform distribution")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown latency distribution '%s', available are: %s, %s, %s, %s",
+			l.Distribution, Fixed, Uniform, Normal, Exponential)
+	}
+}
+
+func validateRate(name string, rate float64) error {
+	if rate < 0 || rate > 1 || math.IsNaN(rate) {
+		return fmt.Errorf("the %s rate should be between 0 and 1, got %v", name, rate)
+	}
+	return nil
+}
+
+// Sample returns a random latency from the distribution.
+func (l *Latency) Sample(rnd *rand.Rand) time.Duration {
+	var d float64
+	switch l.Distribution {
+	case Uniform:
+		d = float64(l.Min) + rnd.Float64()*float64(l.Max-l.Min)
+	case Normal:
+		d = float64(l.Mean) + rnd.NormFloat64()*float64(l.StdDev)
+	case Exponential:
+		d = rnd.ExpFloat64() * float64(l.Mean)
+	default:
+		d = float64(l.Mean)
+	}
+	if d < float64(l.Min) {
+		d = float64(l.Min)
+	}
+	if l.Max > 0 && d > float64(l.Max) {
+		d = float64(l.Max)
+	}
+	return time.Duration(d)
+}
+
+// Hit returns whether a fault with the rate should be injected, i.e. true with
+// the probability of rate.
+func Hit(rnd *rand.Rand, rate float64) bool {
+	return rate > 0 && rnd.Float64() < rate
+}
+
+// ErrUnknownRoute is returned when the faults of a route which isn't served
+// are changed.
+var ErrUnknownRoute = errors.New("unknown route")
+
+// RouteKey returns the key of a route in the rules by route, which is its
+// method and the path it was registered with, e.g. "GET /users/:id".
+func RouteKey(method, path string) string {
+	return strings.ToUpper(method) + " " + path
+}
+
+// NormalizeRouteKey returns the key in the same format as RouteKey, so that
+// the keys in the options and the REST API don't have to be exact.
+func NormalizeRouteKey(key string) string {
+	method, path, _ := strings.Cut(strings.TrimSpace(key), " ")
+	return RouteKey(method, strings.TrimSpace(path))
+}
@@ -0,0 +1,77 @@
+package fault
+
+import (
+	"encoding/json"
+	"math/rand"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/liuxd6825/k6server/lib/types"
+)
+
+func TestRuleValidate(t *testing.T) {
+	t.Parallel()
+
+	var rule Rule
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"latency": { "mean": "100ms" },
+		"error": { "rate": 0.5 },
+		"slowDrip": { "rate": 1, "interval": 10 }
+	}`), &rule))
+	require.NoError(t, rule.Validate())
+	assert.Equal(t, Fixed, rule.Latency.Distribution)
+	assert.Equal(t, types.Duration(100*time.Millisecond), rule.Latency.Mean)
+	assert.Equal(t, http.StatusInternalServerError, rule.Error.Status)
+	assert.Equal(t, 1, rule.SlowDrip.ChunkSize)
+	assert.Equal(t, types.Duration(10*time.Millisecond), rule.SlowDrip.Interval)
+
+	invalid := map[string]Rule{
+		"distribution": {Latency: &Latency{Distribution: "pareto"}},
+		"uniform":      {Latency: &Latency{Distribution: Uniform}},
+		"min max":      {Latency: &Latency{Min: 2, Max: 1}},
+		"error rate":   {Error: &Error{Rate: 1.5}},
+		"error status": {Error: &Error{Rate: 1, Status: 1000}},
+		"reset rate":   {Reset: &Reset{Rate: -1}},
+		"interval":     {SlowDrip: &SlowDrip{Rate: 1}},
+		"ratio":        {Truncate: &Truncate{Rate: 1, Ratio: 1}},
+	}
+	for name, rule := range invalid {
+		assert.Error(t, rule.Validate(), name)
+	}
+}
+
+func TestLatencySample(t *testing.T) {
+	t.Parallel()
+
+	rnd := rand.New(rand.NewSource(1)) //nolint:gosec
+	ms := types.Duration(time.Millisecond)
+
+	fixed := &Latency{Distribution: Fixed, Mean: 5 * ms}
+	assert.Equal(t, 5*time.Millisecond, fixed.Sample(rnd))
+
+	uniform := &Latency{Distribution: Uniform, Min: 10 * ms, Max: 20 * ms}
+	normal := &Latency{Distribution: Normal, Mean: 10 * ms, StdDev: 50 * ms, Max: 30 * ms}
+	exponential := &Latency{Distribution: Exponential, Mean: 10 * ms}
+	var sum time.Duration
+	for i := 0; i < 1000; i++ {
+		d := uniform.Sample(rnd)
+		assert.True(t, d >= 10*time.Millisecond && d <= 20*time.Millisecond, d)
+
+		d = normal.Sample(rnd)
+		assert.True(t, d >= 0 && d <= 30*time.Millisecond, d)
+
+		sum += exponential.Sample(rnd)
+	}
+	assert.InDelta(t, 10*time.Millisecond, sum/1000, float64(2*time.Millisecond))
+}
+
+func TestRouteKey(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "GET /users/:id", RouteKey("get", "/users/:id"))
+	assert.Equal(t, "POST /users", NormalizeRouteKey(" post   /users "))
+}
@@ -9,6 +9,7 @@ import (
 	"net"
 	"reflect"
 
+	"github.com/liuxd6825/k6server/lib/fault"
 	"github.com/liuxd6825/k6server/lib/types"
 	"github.com/liuxd6825/k6server/metrics"
 	"gopkg.in/guregu/null.v3"
@@ -247,6 +248,11 @@ type Options struct {
 	ExecutionSegment         *ExecutionSegment         `json:"executionSegment" ignored:"true"`
 	ExecutionSegmentSequence *ExecutionSegmentSequence `json:"executionSegmentSequence" ignored:"true"`
 
+	// RandomSeed seeds the random choices of the executors, e.g. the arrivals
+	// of the stochastic-arrival-rate scenarios, so the runs are reproducible.
+	// All the instances of a test need the same seed to split these choices.
+	RandomSeed null.Int `json:"randomSeed" envconfig:"K6_RANDOM_SEED"`
+
 	// Timeouts for the setup() and teardown() functions
 	NoSetup         null.Bool          `json:"noSetup" envconfig:"K6_NO_SETUP"`
 	SetupTimeout    types.NullDuration `json:"setupTimeout" envconfig:"K6_SETUP_TIMEOUT"`
@@ -344,6 +350,10 @@ type Options struct {
 
 	// Specify client IP ranges and/or CIDR from which VUs will make requests
 	LocalIPs types.NullIPPool `json:"-" envconfig:"K6_LOCAL_IPS"`
+
+	// Faults injected into the responses of the server mode routes, by route,
+	// e.g. "GET /users/:id"
+	ServerFaults map[string]fault.Rule `json:"serverFaults"`
 }
 
 // Apply returns the result of overwriting any fields with any that are set on the argument.
@@ -405,6 +415,9 @@ func (o Options) Apply(opts Options) Options {
 	if opts.ExecutionSegmentSequence != nil {
 		o.ExecutionSegmentSequence = opts.ExecutionSegmentSequence
 	}
+	if opts.RandomSeed.Valid {
+		o.RandomSeed = opts.RandomSeed
+	}
 	if opts.NoSetup.Valid {
 		o.NoSetup = opts.NoSetup
 	}
@@ -513,6 +526,9 @@ func (o Options) Apply(opts Options) Options {
 	if opts.DNS.Policy.Valid {
 		o.DNS.Policy = opts.DNS.Policy
 	}
+	if opts.ServerFaults != nil {
+		o.ServerFaults = opts.ServerFaults
+	}
 
 	return o
 }
@@ -536,6 +552,11 @@ func (o Options) Validate() []error {
 					o.ExecutionSegment, o.ExecutionSegmentSequence))
 		}
 	}
+	for route, rule := range o.ServerFaults {
+		if err := rule.Validate(); err != nil {
+			errors = append(errors, fmt.Errorf("invalid server faults of the route '%s': %w", route, err))
+		}
+	}
 	return append(errors, o.Scenarios.Validate()...)
 }
 
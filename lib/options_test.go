@@ -611,6 +611,10 @@ func TestOptionsEnv(t *testing.T) {
 			"":    null.Int{},
 			"123": null.IntFrom(123),
 		},
+		{"RandomSeed", "K6_RANDOM_SEED"}: {
+			"":   null.Int{},
+			"42": null.IntFrom(42),
+		},
 		{"NoSetup", "K6_NO_SETUP"}: {
 			"":      null.Bool{},
 			"true":  null.BoolFrom(true),
@@ -2,6 +2,7 @@ package lib
 
 import (
 	"context"
+	"encoding/json"
 	"github.com/dop251/goja"
 	"io"
 	"time"
@@ -17,6 +18,26 @@ type ActiveVU interface {
 	RunOnce() error
 }
 
+// IterationOptions are the options of a single iteration, which override the
+// ones of the VU activation, e.g. for replaying the arrivals of a trace.
+type IterationOptions struct {
+	// Exec is the exported function of the iteration, the one of the
+	// activation is run when it's empty.
+	Exec string
+	// Payload is passed as JSON to the exported function, after the data
+	// returned by setup().
+	Payload json.RawMessage
+}
+
+// ActiveVUWithIterationOptions is implemented by the active VUs which can run
+// an iteration with specific options.
+type ActiveVUWithIterationOptions interface {
+	ActiveVU
+
+	// RunOnceWith is like RunOnce, but with the options of the iteration.
+	RunOnceWith(IterationOptions) error
+}
+
 // InitializedVU represents a virtual user ready for work. It needs to be
 // activated (i.e. given a context) before it can actually be used. Activation
 // also requires a callback function, which will be called when the supplied
@@ -29,6 +50,38 @@ type InitializedVU interface {
 	GetID() uint64
 }
 
+// InspectableVU is implemented by the VUs which can report their live state,
+// e.g. for debugging the tests with stuck VUs.
+type InspectableVU interface {
+	InitializedVU
+
+	// GetVUInfo returns a snapshot of the current state of the VU, it's safe
+	// to call it concurrently with the VU running.
+	GetVUInfo() VUInfo
+}
+
+// VUInfo is a snapshot of the state of a VU.
+type VUInfo struct {
+	IDLocal, IDGlobal uint64
+	// Active is true while the VU is activated by an executor.
+	Active bool
+	// Scenario is the scenario of the current activation of the VU.
+	Scenario string
+	// Iteration is the number of the current or last iteration of the VU,
+	// i.e. __ITER, and ScenarioIteration is the one in the scenario.
+	Iteration, ScenarioIteration int64
+	// IterationStart is when the current iteration started, it's zero when the
+	// VU isn't running an iteration.
+	IterationStart time.Time
+	// InEventLoop is true when the exported function of the current iteration
+	// has returned, but the VU is still waiting on the event loop for the
+	// asynchronous work, e.g. promises or timers, to finish.
+	InEventLoop bool
+	// Tags and Metadata are the ones of k6/execution's vu.tags and
+	// vu.metrics.metadata.
+	Tags, Metadata map[string]string
+}
+
 // VUActivationParams are supplied by each executor when it retrieves a VU from
 // the buffer pool and activates it for use.
 type VUActivationParams struct {
@@ -108,4 +161,9 @@ type Summary struct {
 	TestRunDuration time.Duration // TODO: use lib.ExecutionState-based interface instead?
 	NoColor         bool          // TODO: drop this when noColor is part of the (runtime) options
 	UIState         UIState
+
+	// ThresholdChanges are the changes of the thresholds during the test run
+	ThresholdChanges []metrics.ThresholdChange
+	// Capacities are the capacities found by the scenarios searching for them
+	Capacities []Capacity
 }
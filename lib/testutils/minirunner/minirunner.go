@@ -12,9 +12,10 @@ import (
 
 // Ensure mock implementations conform to the interfaces.
 var (
-	_ lib.Runner        = &MiniRunner{}
-	_ lib.InitializedVU = &VU{}
-	_ lib.ActiveVU      = &ActiveVU{}
+	_ lib.Runner                       = &MiniRunner{}
+	_ lib.InitializedVU                = &VU{}
+	_ lib.ActiveVU                     = &ActiveVU{}
+	_ lib.ActiveVUWithIterationOptions = &ActiveVU{}
 )
 
 // MiniRunner partially implements the lib.Runner interface, but instead of
@@ -210,6 +211,25 @@ func (vu *ActiveVU) incrIteration() {
 
 // RunOnce runs the mock default function once, incrementing its iteration.
 func (vu *ActiveVU) RunOnce() error {
+	return vu.runOnce(vu.RunContext)
+}
+
+type iterationOptionsKey struct{}
+
+// RunOnceWith runs the mock default function once, like RunOnce, with the
+// options of the iteration in its context, see IterationOptionsFromContext.
+func (vu *ActiveVU) RunOnceWith(opts lib.IterationOptions) error {
+	return vu.runOnce(context.WithValue(vu.RunContext, iterationOptionsKey{}, opts))
+}
+
+// IterationOptionsFromContext returns the options of an iteration run with
+// RunOnceWith.
+func IterationOptionsFromContext(ctx context.Context) (lib.IterationOptions, bool) {
+	opts, ok := ctx.Value(iterationOptionsKey{}).(lib.IterationOptions)
+	return opts, ok
+}
+
+func (vu *ActiveVU) runOnce(ctx context.Context) error {
 	if vu.R.Fn == nil {
 		return nil
 	}
@@ -225,5 +245,5 @@ func (vu *ActiveVU) RunOnce() error {
 	}()
 
 	vu.incrIteration()
-	return vu.R.Fn(vu.RunContext, vu.State(), vu.Out)
+	return vu.R.Fn(ctx, vu.State(), vu.Out)
 }
@@ -0,0 +1,135 @@
+package log
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Entry is a log entry kept by a Buffer.
+type Entry struct {
+	// ID is the sequence number of the entry, starting from 1.
+	ID      uint64
+	Time    time.Time
+	Level   logrus.Level
+	Message string
+	// Fields are the structured fields of the entry, the values which can't be
+	// encoded as JSON are formatted as strings, e.g. the errors.
+	Fields map[string]interface{}
+}
+
+// Buffer is a hook which keeps the most recent log entries, up to its size,
+// e.g. for retrieving them through the REST API. The new entries are also sent
+// to its subscribers, which don't block the logger when they are slow: the
+// entries which don't fit in their buffers are dropped.
+type Buffer struct {
+	mu          sync.Mutex
+	entries     []Entry
+	head        int // the index of the oldest entry, when the buffer is full
+	lastID      uint64
+	subscribers map[chan Entry]struct{}
+}
+
+var _ logrus.Hook = &Buffer{}
+
+// NewBuffer returns a new Buffer which keeps up to size log entries.
+func NewBuffer(size int) *Buffer {
+	return &Buffer{
+		entries:     make([]Entry, 0, size),
+		subscribers: make(map[chan Entry]struct{}),
+	}
+}
+
+// Levels returns all the levels, the entries are filtered when retrieved.
+func (b *Buffer) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire adds the entry to the buffer, overwriting the oldest one when it's full,
+// and sends it to the subscribers.
+func (b *Buffer) Fire(e *logrus.Entry) error {
+	fields := make(map[string]interface{}, len(e.Data))
+	for k, v := range e.Data {
+		fields[k] = fieldValue(v)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastID++
+	entry := Entry{ID: b.lastID, Time: e.Time, Level: e.Level, Message: e.Message, Fields: fields}
+	switch {
+	case cap(b.entries) == 0:
+	case len(b.entries) < cap(b.entries):
+		b.entries = append(b.entries, entry)
+	default:
+		b.entries[b.head] = entry
+		b.head = (b.head + 1) % len(b.entries)
+	}
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- entry:
+		default:
+		}
+	}
+	return nil
+}
+
+// Entries returns the entries in the buffer, from the oldest to the newest.
+func (b *Buffer) Entries() []Entry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.entriesLocked()
+}
+
+func (b *Buffer) entriesLocked() []Entry {
+	entries := make([]Entry, 0, len(b.entries))
+	entries = append(entries, b.entries[b.head:]...)
+	return append(entries, b.entries[:b.head]...)
+}
+
+// Subscribe returns the entries in the buffer and a channel that receives the
+// new ones, until unsubscribe is called. The entries which don't fit in the
+// buffer of the channel are dropped.
+func (b *Buffer) Subscribe(bufferSize int) (entries []Entry, newEntries <-chan Entry, unsubscribe func()) {
+	ch := make(chan Entry, bufferSize)
+	b.mu.Lock()
+	b.subscribers[ch] = struct{}{}
+	entries = b.entriesLocked()
+	b.mu.Unlock()
+
+	var once sync.Once
+	return entries, ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subscribers, ch)
+			b.mu.Unlock()
+		})
+	}
+}
+
+// fieldValue returns the value of a field of an entry, as a value which can be
+// encoded as JSON.
+func fieldValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case nil, string, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return v
+	case float32:
+		return fieldValue(float64(v))
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Sprint(v)
+		}
+		return v
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return fmt.Sprintf("%+v", v)
+	}
+}
@@ -0,0 +1,50 @@
+package log
+
+import (
+	"errors"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuffer(t *testing.T) {
+	t.Parallel()
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	buffer := NewBuffer(3)
+	logger.AddHook(buffer)
+
+	logger.Info("first")
+	_, newEntries, unsubscribe := buffer.Subscribe(1)
+	logger.WithError(errors.New("oops")).WithField("value", math.NaN()).Warn("second")
+	logger.WithField("source", "console").Info("third")
+	logger.Error("fourth")
+
+	entries := buffer.Entries()
+	require.Len(t, entries, 3, "the oldest entry is overwritten")
+	for i, msg := range []string{"second", "third", "fourth"} {
+		assert.Equal(t, uint64(i+2), entries[i].ID)
+		assert.Equal(t, msg, entries[i].Message)
+	}
+	assert.Equal(t, logrus.WarnLevel, entries[0].Level)
+	assert.Equal(t, map[string]interface{}{"error": "oops", "value": "NaN"}, entries[0].Fields)
+	assert.Equal(t, map[string]interface{}{"source": "console"}, entries[1].Fields)
+
+	entry := <-newEntries
+	assert.Equal(t, "second", entry.Message)
+	assert.Empty(t, newEntries, "the entries which don't fit in the channel are dropped")
+
+	unsubscribe()
+	unsubscribe()
+	logger.Info("fifth")
+	assert.Empty(t, newEntries)
+
+	recent, _, unsubscribe := buffer.Subscribe(1)
+	defer unsubscribe()
+	assert.Equal(t, buffer.Entries(), recent)
+}
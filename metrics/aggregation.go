@@ -0,0 +1,85 @@
+package metrics
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gopkg.in/guregu/null.v3"
+)
+
+// Aggregation is a method aggregating the values of a metric, like the ones
+// of the thresholds, e.g. p(95) or rate.
+type Aggregation struct {
+	Method string
+	// Percentile is the percentile of the p(N) method.
+	Percentile null.Float
+}
+
+// ParseAggregation parses an aggregation method, e.g. p(95) or rate.
+func ParseAggregation(input string) (Aggregation, error) {
+	method, percentile, err := parseThresholdAggregationMethod(strings.TrimSpace(input))
+	if err != nil {
+		return Aggregation{}, fmt.Errorf("invalid aggregation method %q: %w", input, err)
+	}
+	return Aggregation{Method: method, Percentile: percentile}, nil
+}
+
+// String returns the aggregation method as it's written, e.g. p(95).
+func (a Aggregation) String() string {
+	return (&thresholdExpression{AggregationMethod: a.Method, AggregationValue: a.Percentile}).SinkKey()
+}
+
+// Validate returns an error if the metrics of the type can't be aggregated
+// with the method.
+func (a Aggregation) Validate(mt MetricType) error {
+	if !mt.supportsAggregationMethod(a.Method) {
+		return fmt.Errorf("unsupported aggregation method %s on metric of type %s, the supported ones are: %s",
+			a, mt, strings.Join(mt.supportedAggregationMethods(), ", "))
+	}
+	return nil
+}
+
+// Aggregate returns the aggregated value of the sink, whose samples were
+// collected over the duration, or false if it doesn't have one, e.g. it's
+// empty.
+func (a Aggregation) Aggregate(sink Sink, duration time.Duration) (float64, bool) {
+	if sink.IsEmpty() {
+		return 0, false
+	}
+
+	switch sinkImpl := sink.(type) {
+	case *CounterSink:
+		switch a.Method {
+		case tokenCount:
+			return sinkImpl.Value, true
+		case tokenRate:
+			if duration <= 0 {
+				return 0, false
+			}
+			return sinkImpl.Value / duration.Seconds(), true
+		}
+	case *GaugeSink:
+		if a.Method == tokenValue {
+			return sinkImpl.Value, true
+		}
+	case *TrendSink:
+		switch a.Method {
+		case tokenMin:
+			return sinkImpl.Min(), true
+		case tokenMax:
+			return sinkImpl.Max(), true
+		case tokenAvg:
+			return sinkImpl.Avg(), true
+		case tokenMed:
+			return sinkImpl.P(0.5), true
+		case tokenPercentile:
+			return sinkImpl.P(a.Percentile.Float64 / 100), true
+		}
+	case *RateSink:
+		if a.Method == tokenRate {
+			return float64(sinkImpl.Trues) / float64(sinkImpl.Total), true
+		}
+	}
+	return 0, false
+}
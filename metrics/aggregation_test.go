@@ -0,0 +1,68 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAggregation(t *testing.T) {
+	t.Parallel()
+
+	aggregation, err := ParseAggregation(" p(99.9) ")
+	require.NoError(t, err)
+	assert.Equal(t, "p(99.9)", aggregation.String())
+	assert.NoError(t, aggregation.Validate(Trend))
+	assert.Error(t, aggregation.Validate(Counter))
+
+	aggregation, err = ParseAggregation("rate")
+	require.NoError(t, err)
+	assert.Equal(t, "rate", aggregation.String())
+	assert.NoError(t, aggregation.Validate(Counter))
+	assert.Error(t, aggregation.Validate(Gauge))
+
+	_, err = ParseAggregation("p95")
+	assert.Error(t, err)
+}
+
+func TestAggregationAggregate(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	counter := &CounterSink{}
+	counter.Add(Sample{Time: now, Value: 10})
+	rate := &RateSink{}
+	rate.Add(Sample{Value: 1})
+	rate.Add(Sample{Value: 0})
+	gauge := &GaugeSink{}
+	gauge.Add(Sample{Value: 7})
+
+	for _, tc := range []struct {
+		method   string
+		sink     Sink
+		expected float64
+	}{
+		{"count", counter, 10},
+		{"rate", counter, 5},
+		{"rate", rate, 0.5},
+		{"value", gauge, 7},
+		{"med", getTrendSink(1, 2, 3), 2},
+		{"max", getTrendSink(1, 2, 3), 3},
+		{"p(100)", getTrendSink(1, 2, 3), 3},
+	} {
+		aggregation, err := ParseAggregation(tc.method)
+		require.NoError(t, err)
+		value, ok := aggregation.Aggregate(tc.sink, 2*time.Second)
+		require.True(t, ok, tc.method)
+		assert.Equal(t, tc.expected, value, tc.method)
+	}
+
+	aggregation, err := ParseAggregation("avg")
+	require.NoError(t, err)
+	_, ok := aggregation.Aggregate(getTrendSink(), time.Second)
+	assert.False(t, ok, "empty sink")
+	_, ok = aggregation.Aggregate(counter, time.Second)
+	assert.False(t, ok, "unsupported method")
+}
@@ -31,6 +31,24 @@ const (
 
 	GRPCReqDurationName = "grpc_req_duration"
 
+	ServerVUsName                = "server_vus"
+	ServerPoolWaitsName          = "server_pool_waits"
+	ServerPoolWaitDurationName   = "server_pool_wait_duration"
+	ServerPoolRejectedName       = "server_pool_rejected"
+	ServerReqsName               = "server_reqs"
+	ServerReqDurationName        = "server_req_duration"
+	ServerReqFailedName          = "server_req_failed"
+	ServerActiveRequestsName     = "server_active_requests"
+	ServerWSSessionsName         = "server_ws_sessions"
+	ServerWSSessionDurationName  = "server_ws_session_duration"
+	ServerWSMessagesSentName     = "server_ws_msgs_sent"
+	ServerWSMessagesReceivedName = "server_ws_msgs_received"
+	ServerSSEStreamsName         = "server_sse_streams"
+	ServerSSEStreamDurationName  = "server_sse_stream_duration"
+	ServerSSEEventsSentName      = "server_sse_events_sent"
+	ServerGRPCReqsName           = "server_grpc_reqs"
+	ServerGRPCReqDurationName    = "server_grpc_req_duration"
+
 	DataSentName     = "data_sent"
 	DataReceivedName = "data_received"
 )
@@ -69,6 +87,25 @@ type BuiltinMetrics struct {
 	// gRPC-related
 	GRPCReqDuration *Metric
 
+	// Server-mode related
+	ServerVUs                *Metric
+	ServerPoolWaits          *Metric
+	ServerPoolWaitDuration   *Metric
+	ServerPoolRejected       *Metric
+	ServerReqs               *Metric
+	ServerReqDuration        *Metric
+	ServerReqFailed          *Metric
+	ServerActiveRequests     *Metric
+	ServerWSSessions         *Metric
+	ServerWSSessionDuration  *Metric
+	ServerWSMessagesSent     *Metric
+	ServerWSMessagesReceived *Metric
+	ServerSSEStreams         *Metric
+	ServerSSEStreamDuration  *Metric
+	ServerSSEEventsSent      *Metric
+	ServerGRPCReqs           *Metric
+	ServerGRPCReqDuration    *Metric
+
 	// Network-related; used for future protocols as well.
 	DataSent     *Metric
 	DataReceived *Metric
@@ -105,6 +142,24 @@ func RegisterBuiltinMetrics(registry *Registry) *BuiltinMetrics {
 
 		GRPCReqDuration: registry.MustNewMetric(GRPCReqDurationName, Trend, Time),
 
+		ServerVUs:                registry.MustNewMetric(ServerVUsName, Gauge),
+		ServerPoolWaits:          registry.MustNewMetric(ServerPoolWaitsName, Counter),
+		ServerPoolWaitDuration:   registry.MustNewMetric(ServerPoolWaitDurationName, Trend, Time),
+		ServerPoolRejected:       registry.MustNewMetric(ServerPoolRejectedName, Counter),
+		ServerReqs:               registry.MustNewMetric(ServerReqsName, Counter),
+		ServerReqDuration:        registry.MustNewMetric(ServerReqDurationName, Trend, Time),
+		ServerReqFailed:          registry.MustNewMetric(ServerReqFailedName, Rate),
+		ServerActiveRequests:     registry.MustNewMetric(ServerActiveRequestsName, Gauge),
+		ServerWSSessions:         registry.MustNewMetric(ServerWSSessionsName, Counter),
+		ServerWSSessionDuration:  registry.MustNewMetric(ServerWSSessionDurationName, Trend, Time),
+		ServerWSMessagesSent:     registry.MustNewMetric(ServerWSMessagesSentName, Counter),
+		ServerWSMessagesReceived: registry.MustNewMetric(ServerWSMessagesReceivedName, Counter),
+		ServerSSEStreams:         registry.MustNewMetric(ServerSSEStreamsName, Counter),
+		ServerSSEStreamDuration:  registry.MustNewMetric(ServerSSEStreamDurationName, Trend, Time),
+		ServerSSEEventsSent:      registry.MustNewMetric(ServerSSEEventsSentName, Counter),
+		ServerGRPCReqs:           registry.MustNewMetric(ServerGRPCReqsName, Counter),
+		ServerGRPCReqDuration:    registry.MustNewMetric(ServerGRPCReqDurationName, Trend, Time),
+
 		DataSent:     registry.MustNewMetric(DataSentName, Counter, Data),
 		DataReceived: registry.MustNewMetric(DataReceivedName, Counter, Data),
 	}
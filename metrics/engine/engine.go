@@ -30,6 +30,10 @@ type MetricsEngine struct {
 	// These can be both top-level metrics or sub-metrics
 	metricsWithThresholds   []*metrics.Metric
 	breachedThresholdsCount uint32
+	thresholdsEnabled       bool
+	thresholdChanges        []metrics.ThresholdChange
+	thresholdsWindows       map[*ThresholdsWindow]struct{}
+	metricWindows           map[*metrics.Metric]map[*MetricWindow]struct{}
 
 	// TODO: completely refactor:
 	//   - make these private, add a method to export the raw data
@@ -38,6 +42,11 @@ type MetricsEngine struct {
 	//     the metrics are decoupled from their types
 	MetricsLock     sync.Mutex
 	ObservedMetrics map[string]*metrics.Metric
+
+	subscribersLock sync.Mutex
+	subscribers     map[chan []metrics.Sample]struct{}
+
+	series *seriesStore
 }
 
 // NewMetricsEngine creates a new metrics Engine with the given parameters.
@@ -46,11 +55,56 @@ func NewMetricsEngine(registry *metrics.Registry, logger logrus.FieldLogger) (*M
 		registry:        registry,
 		logger:          logger.WithField("component", "metrics-engine"),
 		ObservedMetrics: make(map[string]*metrics.Metric),
+		subscribers:     make(map[chan []metrics.Sample]struct{}),
 	}
+	me.series = newSeriesStore(me.logger)
 
 	return me, nil
 }
 
+// SubscribeSamples returns a channel that receives the batches of the metric
+// samples ingested by the engine, e.g. for streaming them through the REST
+// API, until unsubscribe is called. The ingester doesn't wait for slow
+// subscribers, the batches which don't fit in the buffer are dropped.
+func (me *MetricsEngine) SubscribeSamples(bufferSize int) (samples <-chan []metrics.Sample, unsubscribe func()) {
+	ch := make(chan []metrics.Sample, bufferSize)
+	me.subscribersLock.Lock()
+	me.subscribers[ch] = struct{}{}
+	me.subscribersLock.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			me.subscribersLock.Lock()
+			delete(me.subscribers, ch)
+			me.subscribersLock.Unlock()
+		})
+	}
+}
+
+// publishSamples sends the samples to the subscribers, if there are any.
+func (me *MetricsEngine) publishSamples(sampleContainers []metrics.SampleContainer) {
+	me.subscribersLock.Lock()
+	defer me.subscribersLock.Unlock()
+	if len(me.subscribers) == 0 {
+		return
+	}
+
+	var batch []metrics.Sample
+	for _, sc := range sampleContainers {
+		batch = append(batch, sc.GetSamples()...)
+	}
+	if len(batch) == 0 {
+		return
+	}
+	for ch := range me.subscribers {
+		select {
+		case ch <- batch:
+		default:
+		}
+	}
+}
+
 // CreateIngester returns a pseudo-Output that uses the given metric samples to
 // update the engine's inner state.
 func (me *MetricsEngine) CreateIngester() *OutputIngester {
@@ -114,10 +168,30 @@ func (me *MetricsEngine) markObserved(metric *metrics.Metric) {
 	}
 }
 
+// SetSinks replaces the sinks of the metrics and marks them as observed. It's
+// used instead of an ingester when the samples are aggregated elsewhere, e.g.
+// by the instances of a distributed test run, whose sinks are merged.
+func (me *MetricsEngine) SetSinks(sinks map[*metrics.Metric]metrics.Sink) {
+	me.MetricsLock.Lock()
+	defer me.MetricsLock.Unlock()
+
+	for m, sink := range sinks {
+		m.Sink = sink
+		me.markObserved(m)
+		if m.Sub != nil {
+			me.markObserved(m.Sub.Parent)
+		}
+	}
+}
+
 // InitSubMetricsAndThresholds parses the thresholds from the test Options and
 // initializes both the thresholds themselves, as well as any submetrics that
 // were referenced in them.
 func (me *MetricsEngine) InitSubMetricsAndThresholds(options lib.Options, onlyLogErrors bool) error {
+	me.MetricsLock.Lock()
+	defer me.MetricsLock.Unlock()
+
+	me.thresholdsEnabled = !onlyLogErrors
 	for metricName, thresholds := range options.Thresholds {
 		metric, err := me.getThresholdMetricOrSubmetric(metricName)
 
@@ -158,15 +232,13 @@ func (me *MetricsEngine) InitSubMetricsAndThresholds(options lib.Options, onlyLo
 
 // StartThresholdCalculations spins up a new goroutine to crunch thresholds and
 // returns a callback that will stop the goroutine and finalizes calculations.
+// The goroutine is started even if no thresholds were defined, since they can
+// be added during the test run with AddThreshold.
 func (me *MetricsEngine) StartThresholdCalculations(
 	ingester *OutputIngester,
 	abortRun func(error),
 	getCurrentTestRunDuration func() time.Duration,
 ) (finalize func() (breached []string)) {
-	if len(me.metricsWithThresholds) == 0 {
-		return nil // no thresholds were defined
-	}
-
 	stop := make(chan struct{})
 	done := make(chan struct{})
 
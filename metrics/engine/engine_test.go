@@ -153,6 +153,27 @@ func TestMetricsEngineEvaluateIgnoreEmptySink(t *testing.T) {
 	assert.Empty(t, breached)
 }
 
+func TestMetricsEngineSetSinks(t *testing.T) {
+	t.Parallel()
+
+	me := newTestMetricsEngine(t)
+	m1, err := me.registry.NewMetric("m1", metrics.Counter)
+	require.NoError(t, err)
+	sm, err := m1.AddSubmetric("tag:value")
+	require.NoError(t, err)
+
+	ths := metrics.NewThresholds([]string{"count<5"})
+	require.NoError(t, ths.Parse())
+	sm.Metric.Thresholds = ths
+	me.metricsWithThresholds = []*metrics.Metric{sm.Metric}
+
+	me.SetSinks(map[*metrics.Metric]metrics.Sink{sm.Metric: &metrics.CounterSink{Value: 10, First: time.Now()}})
+	assert.Equal(t, map[string]*metrics.Metric{"m1": m1, sm.Metric.Name: sm.Metric}, me.ObservedMetrics)
+
+	breached, _ := me.evaluateThresholds(true, zeroTestRunDuration)
+	assert.Equal(t, []string{"m1{tag:value}"}, breached)
+}
+
 func newTestMetricsEngine(t *testing.T) *MetricsEngine {
 	m, err := NewMetricsEngine(metrics.NewRegistry(), testutils.NewLogger(t))
 	require.NoError(t, err)
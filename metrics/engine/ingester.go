@@ -64,6 +64,8 @@ func (oi *OutputIngester) flushMetrics() {
 	if len(sampleContainers) == 0 {
 		return
 	}
+	oi.metricsEngine.publishSamples(sampleContainers)
+	oi.metricsEngine.series.addSamples(sampleContainers)
 
 	oi.metricsEngine.MetricsLock.Lock()
 	defer oi.metricsEngine.MetricsLock.Unlock()
@@ -88,6 +90,7 @@ func (oi *OutputIngester) flushMetrics() {
 			m := sample.Metric               // this should have come from the Registry, no need to look it up
 			oi.metricsEngine.markObserved(m) // mark it as observed so it shows in the end-of-test summary
 			m.Sink.Add(sample)               // finally, add its value to its own sink
+			oi.metricsEngine.addToWindows(m, sample)
 
 			// and also to the same for any submetrics that match the metric sample
 			for _, sm := range m.Submetrics {
@@ -96,6 +99,7 @@ func (oi *OutputIngester) flushMetrics() {
 				}
 				oi.metricsEngine.markObserved(sm.Metric)
 				sm.Metric.Sink.Add(sample)
+				oi.metricsEngine.addToWindows(sm.Metric, sample)
 			}
 
 			oi.cardinality.Add(sample.TimeSeries)
@@ -205,3 +205,29 @@ func newTestPreInitState(tb testing.TB) *lib.TestPreInitState {
 		BuiltinMetrics: metrics.RegisterBuiltinMetrics(reg),
 	}
 }
+
+func TestIngesterOutputPublishSamples(t *testing.T) {
+	t.Parallel()
+
+	piState := newTestPreInitState(t)
+	testMetric, err := piState.Registry.NewMetric("test_metric", metrics.Counter)
+	require.NoError(t, err)
+
+	me, err := NewMetricsEngine(piState.Registry, piState.Logger)
+	require.NoError(t, err)
+	samples, unsubscribe := me.SubscribeSamples(1)
+
+	ingester := me.CreateIngester()
+	require.NoError(t, ingester.Start())
+	sample := metrics.Sample{TimeSeries: metrics.TimeSeries{Metric: testMetric}, Value: 1}
+	ingester.AddMetricSamples([]metrics.SampleContainer{sample})
+	require.NoError(t, ingester.Stop())
+
+	assert.Equal(t, []metrics.Sample{sample}, <-samples)
+
+	// the samples aren't sent after unsubscribing
+	unsubscribe()
+	unsubscribe()
+	me.publishSamples([]metrics.SampleContainer{sample})
+	assert.Empty(t, samples)
+}
@@ -0,0 +1,97 @@
+package engine
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/liuxd6825/k6server/lib"
+	"github.com/liuxd6825/k6server/metrics"
+)
+
+// MetricWindow aggregates the samples of a metric, or of a sub-metric,
+// ingested since it was last reset, e.g. for the closed-loop executor to
+// adjust its load to the recent values of the metric.
+type MetricWindow struct {
+	me          *MetricsEngine
+	metric      *metrics.Metric
+	aggregation metrics.Aggregation
+	start       time.Time
+	sink        metrics.Sink
+}
+
+var _ lib.MetricWindow = &MetricWindow{}
+
+// NewMetricWindow returns a new window of the metric, e.g. http_req_duration
+// or http_req_duration{status:200}, aggregated with the method, e.g. p(95),
+// and started now. It should be closed when it isn't used anymore.
+func (me *MetricsEngine) NewMetricWindow(metricName, aggregation string) (*MetricWindow, error) {
+	agg, err := metrics.ParseAggregation(aggregation)
+	if err != nil {
+		return nil, err
+	}
+
+	me.MetricsLock.Lock()
+	defer me.MetricsLock.Unlock()
+
+	metric, err := me.getThresholdMetricOrSubmetric(metricName)
+	if err != nil {
+		return nil, err
+	}
+	if err = agg.Validate(metric.Type); err != nil {
+		return nil, fmt.Errorf("invalid window of the metric %s: %w", metricName, err)
+	}
+
+	w := &MetricWindow{
+		me:          me,
+		metric:      metric,
+		aggregation: agg,
+		start:       time.Now(),
+		sink:        metrics.NewSink(metric.Type),
+	}
+	if me.metricWindows == nil {
+		me.metricWindows = make(map[*metrics.Metric]map[*MetricWindow]struct{})
+	}
+	if me.metricWindows[metric] == nil {
+		me.metricWindows[metric] = make(map[*MetricWindow]struct{})
+	}
+	me.metricWindows[metric][w] = struct{}{}
+	return w, nil
+}
+
+// addToWindows adds the sample of the metric to the windows of the thresholds
+// and of the metrics. It should be called with the MetricsLock held.
+func (me *MetricsEngine) addToWindows(m *metrics.Metric, s metrics.Sample) {
+	me.addToThresholdsWindows(m, s)
+	for w := range me.metricWindows[m] {
+		w.sink.Add(s)
+	}
+}
+
+// Reset drops the samples of the window and starts it again.
+func (w *MetricWindow) Reset() {
+	w.me.MetricsLock.Lock()
+	defer w.me.MetricsLock.Unlock()
+
+	w.start = time.Now()
+	w.sink = metrics.NewSink(w.metric.Type)
+}
+
+// Value returns the aggregated value of the samples of the window, or false if
+// it doesn't have any.
+func (w *MetricWindow) Value() (float64, bool) {
+	w.me.MetricsLock.Lock()
+	defer w.me.MetricsLock.Unlock()
+
+	return w.aggregation.Aggregate(w.sink, time.Since(w.start))
+}
+
+// Close stops adding the samples to the window.
+func (w *MetricWindow) Close() {
+	w.me.MetricsLock.Lock()
+	defer w.me.MetricsLock.Unlock()
+
+	delete(w.me.metricWindows[w.metric], w)
+	if len(w.me.metricWindows[w.metric]) == 0 {
+		delete(w.me.metricWindows, w.metric)
+	}
+}
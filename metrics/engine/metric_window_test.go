@@ -0,0 +1,68 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/liuxd6825/k6server/lib"
+	"github.com/liuxd6825/k6server/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricWindow(t *testing.T) {
+	t.Parallel()
+
+	me := newTestMetricsEngine(t)
+	trend, err := me.registry.NewMetric("my_trend", metrics.Trend)
+	require.NoError(t, err)
+	require.NoError(t, me.InitSubMetricsAndThresholds(lib.Options{}, false))
+
+	_, err = me.NewMetricWindow("my_trend", "rate")
+	assert.Error(t, err, "unsupported aggregation")
+	_, err = me.NewMetricWindow("unknown", "max")
+	assert.Error(t, err)
+
+	window, err := me.NewMetricWindow("my_trend", "max")
+	require.NoError(t, err)
+	defer window.Close()
+	subWindow, err := me.NewMetricWindow("my_trend{status:500}", "max")
+	require.NoError(t, err)
+	defer subWindow.Close()
+
+	ingester := me.CreateIngester()
+	require.NoError(t, ingester.Start())
+	add := func(value float64, status string) {
+		ingester.AddMetricSamples([]metrics.SampleContainer{metrics.Sample{
+			TimeSeries: metrics.TimeSeries{Metric: trend, Tags: me.registry.RootTagSet().With("status", status)},
+			Time:       time.Now(),
+			Value:      value,
+		}})
+		ingester.flushMetrics()
+	}
+
+	_, ok := window.Value()
+	assert.False(t, ok)
+	add(200, "500")
+	add(300, "200")
+	value, ok := window.Value()
+	require.True(t, ok)
+	assert.Equal(t, 300.0, value)
+	value, ok = subWindow.Value()
+	require.True(t, ok)
+	assert.Equal(t, 200.0, value)
+
+	// only the samples since the reset are aggregated
+	window.Reset()
+	add(100, "200")
+	value, ok = window.Value()
+	require.True(t, ok)
+	assert.Equal(t, 100.0, value)
+
+	window.Close()
+	window.Reset()
+	add(400, "200")
+	_, ok = window.Value()
+	assert.False(t, ok)
+	require.NoError(t, ingester.Stop())
+}
@@ -0,0 +1,298 @@
+package engine
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/liuxd6825/k6server/metrics"
+)
+
+const (
+	// seriesResolution is the duration of the buckets the samples of the time
+	// series are aggregated in, the intervals of the queries are multiples of it.
+	seriesResolution = time.Second
+	// seriesRetention is how long the buckets of the time series are kept.
+	seriesRetention = 10 * time.Minute
+	// seriesLimit is the maximum number of the time series which are kept, the
+	// samples of the ones which are seen afterwards are only in the sinks.
+	seriesLimit = 10_000
+)
+
+// SeriesQuery is a query of the recent values of the time series of a metric.
+type SeriesQuery struct {
+	Metric *metrics.Metric
+	// Tags are the tags the time series should have.
+	Tags map[string]string
+	// GroupBy are the tags whose values the time series are grouped by, all
+	// of them are aggregated together if it's empty.
+	GroupBy []string
+	// Interval is the duration of the buckets of the values, which is rounded
+	// up to a multiple of a second.
+	Interval time.Duration
+	// Since is the time of the oldest values, which are otherwise the ones of
+	// the last ten minutes.
+	Since time.Time
+}
+
+// SeriesGroup are the values of the time series with the same values of the
+// grouped tags.
+type SeriesGroup struct {
+	Tags    map[string]string
+	Buckets []SeriesBucket
+}
+
+// SeriesBucket is the sink of the values of a group of time series in an
+// interval, which starts at Time.
+type SeriesBucket struct {
+	Time time.Time
+	Sink metrics.Sink
+}
+
+// seriesStore keeps the aggregates of the samples of every time series in
+// ring buffers of buckets, so the recent values of a metric can be queried
+// by tags and over time, instead of only the ones of its whole sink.
+type seriesStore struct {
+	logger logrus.FieldLogger
+
+	mu          sync.RWMutex
+	series      map[metrics.TimeSeries]*seriesRing
+	byMetric    map[*metrics.Metric][]*seriesRing
+	limitWarned bool
+}
+
+func newSeriesStore(logger logrus.FieldLogger) *seriesStore {
+	return &seriesStore{
+		logger:   logger,
+		series:   make(map[metrics.TimeSeries]*seriesRing),
+		byMetric: make(map[*metrics.Metric][]*seriesRing),
+	}
+}
+
+// seriesRing is the ring buffer of the buckets of a time series, the slot of
+// a bucket is its index modulo the number of the slots.
+type seriesRing struct {
+	timeSeries metrics.TimeSeries
+	buckets    []*seriesBucket
+}
+
+type seriesBucket struct {
+	index int64 // the start of the bucket, in seriesResolution units
+
+	count    uint64
+	sum      float64
+	min, max float64
+	last     float64
+	lastTime time.Time
+	trues    uint64
+	sketch   *metrics.TrendSketch
+}
+
+// addSamples adds the samples to the buckets of their time series.
+func (s *seriesStore) addSamples(sampleContainers []metrics.SampleContainer) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, sc := range sampleContainers {
+		for _, sample := range sc.GetSamples() {
+			s.add(sample)
+		}
+	}
+}
+
+func (s *seriesStore) add(sample metrics.Sample) {
+	ring, ok := s.series[sample.TimeSeries]
+	if !ok {
+		if len(s.series) >= seriesLimit {
+			if !s.limitWarned {
+				s.limitWarned = true
+				s.logger.Warnf(
+					"The test has more than %d unique time series, the values of the new ones "+
+						"can't be queried over time through the REST API", seriesLimit)
+			}
+			return
+		}
+		ring = &seriesRing{
+			timeSeries: sample.TimeSeries,
+			buckets:    make([]*seriesBucket, int(seriesRetention/seriesResolution)),
+		}
+		s.series[sample.TimeSeries] = ring
+		s.byMetric[sample.Metric] = append(s.byMetric[sample.Metric], ring)
+	}
+	ring.add(sample)
+}
+
+func (r *seriesRing) add(sample metrics.Sample) {
+	index := sample.Time.UnixNano() / int64(seriesResolution)
+	slot := index % int64(len(r.buckets))
+	if slot < 0 {
+		slot += int64(len(r.buckets))
+	}
+
+	b := r.buckets[slot]
+	switch {
+	case b == nil || b.index < index:
+		b = &seriesBucket{index: index, min: sample.Value, max: sample.Value}
+		if sample.Metric.Type == metrics.Trend {
+			b.sketch = metrics.NewTrendSketch()
+		}
+		r.buckets[slot] = b
+	case b.index > index:
+		return // the sample is older than the retention
+	}
+
+	b.count++
+	b.sum += sample.Value
+	if sample.Value < b.min {
+		b.min = sample.Value
+	}
+	if sample.Value > b.max {
+		b.max = sample.Value
+	}
+	if !sample.Time.Before(b.lastTime) {
+		b.last, b.lastTime = sample.Value, sample.Time
+	}
+	if sample.Value != 0 {
+		b.trues++
+	}
+	if b.sketch != nil {
+		b.sketch.Add(sample.Value)
+	}
+}
+
+// QuerySeries returns the recent values of the time series of the metric,
+// which are kept for the last ten minutes, grouped by the values of the tags
+// and aggregated in buckets of the interval.
+func (me *MetricsEngine) QuerySeries(q SeriesQuery) []SeriesGroup {
+	interval := (q.Interval + seriesResolution - 1) / seriesResolution * seriesResolution
+	if interval < seriesResolution {
+		interval = seriesResolution
+	}
+	now := time.Now().UnixNano() / int64(seriesResolution)
+	oldest := now - int64(seriesRetention/seriesResolution) + 1
+	if since := q.Since.UnixNano() / int64(seriesResolution); !q.Since.IsZero() && since > oldest {
+		oldest = since
+	}
+
+	s := me.series
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	type group struct {
+		tags  map[string]string
+		sinks map[int64]metrics.Sink
+	}
+	groups := make(map[string]*group)
+	for _, ring := range s.byMetric[q.Metric] {
+		if !hasTags(ring.timeSeries.Tags, q.Tags) {
+			continue
+		}
+		tags := make(map[string]string, len(q.GroupBy))
+		keys := make([]string, 0, len(q.GroupBy))
+		for _, key := range q.GroupBy {
+			value, _ := ring.timeSeries.Tags.Get(key)
+			tags[key] = value
+			keys = append(keys, key+":"+value)
+		}
+		key := strings.Join(keys, ",")
+		g, ok := groups[key]
+		if !ok {
+			g = &group{tags: tags, sinks: make(map[int64]metrics.Sink)}
+			groups[key] = g
+		}
+		ring.mergeInto(g.sinks, oldest, int64(interval/seriesResolution))
+	}
+
+	keys := make([]string, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	result := make([]SeriesGroup, 0, len(groups))
+	for _, key := range keys {
+		g := groups[key]
+		indexes := make([]int64, 0, len(g.sinks))
+		for i := range g.sinks {
+			indexes = append(indexes, i)
+		}
+		sort.Slice(indexes, func(a, b int) bool { return indexes[a] < indexes[b] })
+
+		sg := SeriesGroup{Tags: g.tags, Buckets: make([]SeriesBucket, 0, len(indexes))}
+		for _, i := range indexes {
+			sg.Buckets = append(sg.Buckets, SeriesBucket{
+				Time: time.Unix(0, i*int64(interval)),
+				Sink: g.sinks[i],
+			})
+		}
+		result = append(result, sg)
+	}
+	return result
+}
+
+func hasTags(tags *metrics.TagSet, selectors map[string]string) bool {
+	for key, value := range selectors {
+		if v, ok := tags.Get(key); !ok || v != value {
+			return false
+		}
+	}
+	return true
+}
+
+// mergeInto merges the buckets of the ring since the oldest one into the
+// sinks of the intervals of the given number of buckets. The gauges of every
+// time series have their last values in the intervals, and the ones of the
+// time series of a group are summed, like their minimums and maximums.
+func (r *seriesRing) mergeInto(sinks map[int64]metrics.Sink, oldest, bucketsPerInterval int64) {
+	gauges := make(map[int64]*seriesBucket)
+	for _, b := range r.buckets {
+		if b == nil || b.index < oldest {
+			continue
+		}
+		i := b.index / bucketsPerInterval
+		sink, ok := sinks[i]
+		if !ok {
+			sink = metrics.NewSink(r.timeSeries.Metric.Type)
+			sinks[i] = sink
+		}
+
+		switch sink := sink.(type) {
+		case *metrics.CounterSink:
+			sink.Merge(&metrics.CounterSink{Value: b.sum, First: time.Unix(0, b.index*int64(seriesResolution))})
+		case *metrics.RateSink:
+			sink.Merge(&metrics.RateSink{Trues: int64(b.trues), Total: int64(b.count)})
+		case *metrics.TrendSink:
+			sink.MergeSketch(b.sketch)
+		case *metrics.GaugeSink:
+			g, ok := gauges[i]
+			if !ok {
+				g = &seriesBucket{index: b.index, min: b.min, max: b.max, last: b.last}
+				gauges[i] = g
+				continue
+			}
+			if b.index > g.index {
+				g.index, g.last = b.index, b.last
+			}
+			if b.min < g.min {
+				g.min = b.min
+			}
+			if b.max > g.max {
+				g.max = b.max
+			}
+		}
+	}
+
+	for i, g := range gauges {
+		// the extremes are added, since whether a gauge is empty isn't exported
+		other := &metrics.GaugeSink{}
+		other.Add(metrics.Sample{Value: g.min})
+		other.Add(metrics.Sample{Value: g.max})
+		other.Value, other.Last = g.last, time.Unix(0, g.index*int64(seriesResolution))
+		sinks[i].(*metrics.GaugeSink).Sum(other) //nolint:forcetypeassert
+	}
+}
@@ -0,0 +1,118 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/liuxd6825/k6server/lib/testutils"
+	"github.com/liuxd6825/k6server/metrics"
+)
+
+func TestQuerySeries(t *testing.T) {
+	t.Parallel()
+
+	registry := metrics.NewRegistry()
+	me, err := NewMetricsEngine(registry, testutils.NewLogger(t))
+	require.NoError(t, err)
+	trend, err := registry.NewMetric("trend", metrics.Trend)
+	require.NoError(t, err)
+	counter, err := registry.NewMetric("counter", metrics.Counter)
+	require.NoError(t, err)
+	gauge, err := registry.NewMetric("gauge", metrics.Gauge)
+	require.NoError(t, err)
+
+	// the samples are in the two ten seconds long intervals before the current one
+	start := time.Now().Truncate(10 * time.Second).Add(-20 * time.Second)
+	tags := registry.RootTagSet()
+	var samples metrics.Samples
+	for i := 0; i < 20; i++ {
+		status := "200"
+		if i%4 == 0 {
+			status = "500"
+		}
+		sampleTags := tags.With("status", status).With("name", "test")
+		sampleTime := start.Add(time.Duration(i) * time.Second)
+		samples = append(samples,
+			metrics.Sample{
+				TimeSeries: metrics.TimeSeries{Metric: trend, Tags: sampleTags},
+				Time:       sampleTime,
+				Value:      float64(i),
+			},
+			metrics.Sample{
+				TimeSeries: metrics.TimeSeries{Metric: counter, Tags: sampleTags},
+				Time:       sampleTime,
+				Value:      1,
+			},
+			metrics.Sample{
+				TimeSeries: metrics.TimeSeries{Metric: gauge, Tags: sampleTags},
+				Time:       sampleTime,
+				Value:      float64(i),
+			},
+		)
+	}
+	// the samples which are older than the retention are ignored
+	samples = append(samples, metrics.Sample{
+		TimeSeries: metrics.TimeSeries{Metric: counter, Tags: tags.With("status", "200").With("name", "test")},
+		Time:       start.Add(-seriesRetention),
+		Value:      100,
+	})
+	me.series.addSamples([]metrics.SampleContainer{samples})
+
+	t.Run("grouped", func(t *testing.T) {
+		t.Parallel()
+
+		groups := me.QuerySeries(SeriesQuery{Metric: counter, GroupBy: []string{"status"}, Interval: 10 * time.Second})
+		require.Len(t, groups, 2)
+		assert.Equal(t, map[string]string{"status": "200"}, groups[0].Tags)
+		assert.Equal(t, map[string]string{"status": "500"}, groups[1].Tags)
+
+		require.Len(t, groups[0].Buckets, 2)
+		assert.Equal(t, start, groups[0].Buckets[0].Time)
+		assert.Equal(t, start.Add(10*time.Second), groups[0].Buckets[1].Time)
+		assert.Equal(t, 7.0, groups[0].Buckets[0].Sink.(*metrics.CounterSink).Value)
+		assert.Equal(t, 8.0, groups[0].Buckets[1].Sink.(*metrics.CounterSink).Value)
+		assert.Equal(t, 3.0, groups[1].Buckets[0].Sink.(*metrics.CounterSink).Value)
+	})
+
+	t.Run("filtered", func(t *testing.T) {
+		t.Parallel()
+
+		groups := me.QuerySeries(SeriesQuery{
+			Metric:   trend,
+			Tags:     map[string]string{"status": "500"},
+			Interval: 10 * time.Second,
+		})
+		require.Len(t, groups, 1)
+		assert.Empty(t, groups[0].Tags)
+		require.Len(t, groups[0].Buckets, 2)
+		sink := groups[0].Buckets[0].Sink.(*metrics.TrendSink)
+		assert.Equal(t, uint64(3), sink.Count())
+		assert.Equal(t, 0.0, sink.Min())
+		assert.Equal(t, 8.0, sink.Max())
+		assert.Equal(t, uint64(2), groups[0].Buckets[1].Sink.(*metrics.TrendSink).Count())
+	})
+
+	t.Run("gauges", func(t *testing.T) {
+		t.Parallel()
+
+		groups := me.QuerySeries(SeriesQuery{Metric: gauge, Interval: 10 * time.Second})
+		require.Len(t, groups, 1)
+		require.Len(t, groups[0].Buckets, 2)
+		// the last values of the two time series in the interval are summed
+		sink := groups[0].Buckets[0].Sink.(*metrics.GaugeSink)
+		assert.Equal(t, 9.0+8.0, sink.Value)
+		assert.Equal(t, 0.0+1.0, sink.Min)
+		assert.Equal(t, 8.0+9.0, sink.Max)
+	})
+
+	t.Run("since", func(t *testing.T) {
+		t.Parallel()
+
+		groups := me.QuerySeries(SeriesQuery{Metric: counter, Since: start.Add(15 * time.Second), Interval: time.Second})
+		require.Len(t, groups, 1)
+		assert.Len(t, groups[0].Buckets, 5)
+	})
+}
@@ -0,0 +1,151 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/liuxd6825/k6server/metrics"
+)
+
+var (
+	// ErrThresholdsDisabled is returned when the thresholds are changed in a
+	// test run which doesn't evaluate them, e.g. with --no-thresholds.
+	ErrThresholdsDisabled = errors.New("the thresholds are disabled")
+	// ErrUnknownThreshold is returned when a threshold that doesn't exist is
+	// muted or unmuted.
+	ErrUnknownThreshold = errors.New("unknown threshold")
+)
+
+// ThresholdState is the state of a threshold of a metric, since it was last
+// evaluated.
+type ThresholdState struct {
+	Metric      string
+	Source      string
+	AbortOnFail bool
+	Failed      bool
+	Muted       bool
+}
+
+// Thresholds returns the state of all thresholds, sorted by their metrics.
+func (me *MetricsEngine) Thresholds() []ThresholdState {
+	me.MetricsLock.Lock()
+	defer me.MetricsLock.Unlock()
+
+	var states []ThresholdState
+	for _, m := range me.metricsWithThresholds {
+		for _, t := range m.Thresholds.Thresholds {
+			states = append(states, ThresholdState{
+				Metric:      m.Name,
+				Source:      t.Source,
+				AbortOnFail: t.AbortOnFail,
+				Failed:      t.LastFailed,
+				Muted:       t.Muted,
+			})
+		}
+	}
+	sort.SliceStable(states, func(i, j int) bool { return states[i].Metric < states[j].Metric })
+	return states
+}
+
+// AddThreshold adds the threshold expression to the metric during the test
+// run, like if it was defined in the options. The metric can be a submetric,
+// e.g. http_req_duration{status:500}, which is created if it doesn't exist.
+func (me *MetricsEngine) AddThreshold(metricName, source string, abortOnFail bool) error {
+	me.MetricsLock.Lock()
+	defer me.MetricsLock.Unlock()
+
+	if !me.thresholdsEnabled {
+		return ErrThresholdsDisabled
+	}
+
+	thresholds := metrics.NewThresholds([]string{source})
+	if err := thresholds.Validate(metricName, me.registry); err != nil {
+		return err
+	}
+	metric, err := me.getThresholdMetricOrSubmetric(metricName)
+	if err != nil {
+		return fmt.Errorf("invalid metric '%s': %w", metricName, err)
+	}
+	for _, t := range metric.Thresholds.Thresholds {
+		if t.Source == source {
+			return fmt.Errorf("the threshold '%s' is already defined on the metric '%s'", source, metric.Name)
+		}
+	}
+
+	threshold := thresholds.Thresholds[0]
+	threshold.AbortOnFail = abortOnFail
+	if !me.hasThresholds(metric) {
+		me.metricsWithThresholds = append(me.metricsWithThresholds, metric)
+	}
+	metric.Thresholds.Thresholds = append(metric.Thresholds.Thresholds, threshold)
+
+	me.markObserved(metric)
+	if metric.Sub != nil {
+		me.markObserved(metric.Sub.Parent)
+	}
+	me.recordThresholdChange("add", metric.Name, source)
+	return nil
+}
+
+func (me *MetricsEngine) hasThresholds(metric *metrics.Metric) bool {
+	for _, m := range me.metricsWithThresholds {
+		if m == metric {
+			return true
+		}
+	}
+	return false
+}
+
+// SetThresholdMuted mutes or unmutes the threshold of the metric. The muted
+// thresholds are still evaluated, but they don't fail or abort the test run.
+func (me *MetricsEngine) SetThresholdMuted(metricName, source string, muted bool) error {
+	me.MetricsLock.Lock()
+	defer me.MetricsLock.Unlock()
+
+	if !me.thresholdsEnabled {
+		return ErrThresholdsDisabled
+	}
+
+	for _, m := range me.metricsWithThresholds {
+		if m.Name != metricName {
+			continue
+		}
+		for _, t := range m.Thresholds.Thresholds {
+			if t.Source != source {
+				continue
+			}
+			if t.Muted == muted {
+				return nil
+			}
+			t.Muted = muted
+			action := "unmute"
+			if muted {
+				action = "mute"
+			}
+			me.recordThresholdChange(action, metricName, source)
+			return nil
+		}
+	}
+	return fmt.Errorf("%w '%s' on the metric '%s'", ErrUnknownThreshold, source, metricName)
+}
+
+// ThresholdChanges returns the changes of the thresholds during the test run,
+// in the order they were made.
+func (me *MetricsEngine) ThresholdChanges() []metrics.ThresholdChange {
+	me.MetricsLock.Lock()
+	defer me.MetricsLock.Unlock()
+
+	return append([]metrics.ThresholdChange(nil), me.thresholdChanges...)
+}
+
+func (me *MetricsEngine) recordThresholdChange(action, metricName, source string) {
+	me.logger.WithField("metric_name", metricName).Infof("Threshold '%s' changed: %s", source, action)
+	me.thresholdChanges = append(me.thresholdChanges, metrics.ThresholdChange{
+		Time:      time.Now(),
+		Action:    action,
+		Metric:    metricName,
+		Threshold: source,
+	})
+}
@@ -0,0 +1,67 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/liuxd6825/k6server/lib"
+	"github.com/liuxd6825/k6server/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsEngineChangeThresholds(t *testing.T) {
+	t.Parallel()
+
+	me := newTestMetricsEngine(t)
+	m, err := me.registry.NewMetric("my_trend", metrics.Trend)
+	require.NoError(t, err)
+	require.NoError(t, me.InitSubMetricsAndThresholds(lib.Options{}, false))
+
+	require.NoError(t, me.AddThreshold("my_trend", "p(95)<100", false))
+	require.NoError(t, me.AddThreshold("my_trend{status:500}", "max<10", true))
+	assert.Error(t, me.AddThreshold("my_trend", "p(95)<100", false), "duplicated")
+	assert.Error(t, me.AddThreshold("my_trend", "rate<100", false), "unsupported aggregation")
+	assert.Error(t, me.AddThreshold("unknown", "p(95)<100", false))
+
+	sub := me.ObservedMetrics["my_trend{status:500}"]
+	require.NotNil(t, sub)
+	assert.Contains(t, me.ObservedMetrics, "my_trend")
+
+	m.Sink.Add(metrics.Sample{Value: 200})
+	sub.Sink.Add(metrics.Sample{Value: 200})
+	breached, abort := me.evaluateThresholds(false, zeroTestRunDuration)
+	assert.Equal(t, []string{"my_trend", "my_trend{status:500}"}, breached)
+	assert.True(t, abort)
+
+	require.NoError(t, me.SetThresholdMuted("my_trend{status:500}", "max<10", true))
+	require.NoError(t, me.SetThresholdMuted("my_trend{status:500}", "max<10", true), "already muted")
+	assert.ErrorIs(t, me.SetThresholdMuted("my_trend", "max<10", true), ErrUnknownThreshold)
+	breached, abort = me.evaluateThresholds(false, zeroTestRunDuration)
+	assert.Equal(t, []string{"my_trend"}, breached)
+	assert.False(t, abort)
+
+	assert.Equal(t, []ThresholdState{
+		{Metric: "my_trend", Source: "p(95)<100", Failed: true},
+		{Metric: "my_trend{status:500}", Source: "max<10", AbortOnFail: true, Failed: true, Muted: true},
+	}, me.Thresholds())
+
+	changes := me.ThresholdChanges()
+	require.Len(t, changes, 3)
+	for i, action := range []string{"add", "add", "mute"} {
+		assert.Equal(t, action, changes[i].Action)
+	}
+	assert.Equal(t, "my_trend{status:500}", changes[2].Metric)
+	assert.Equal(t, "max<10", changes[2].Threshold)
+}
+
+func TestMetricsEngineChangeThresholdsDisabled(t *testing.T) {
+	t.Parallel()
+
+	me := newTestMetricsEngine(t)
+	_, err := me.registry.NewMetric("my_trend", metrics.Trend)
+	require.NoError(t, err)
+	require.NoError(t, me.InitSubMetricsAndThresholds(lib.Options{}, true))
+
+	assert.ErrorIs(t, me.AddThreshold("my_trend", "p(95)<100", false), ErrThresholdsDisabled)
+	assert.Empty(t, me.Thresholds())
+}
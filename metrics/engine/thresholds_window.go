@@ -0,0 +1,110 @@
+package engine
+
+import (
+	"sort"
+	"time"
+
+	"github.com/liuxd6825/k6server/lib"
+	"github.com/liuxd6825/k6server/metrics"
+)
+
+// ThresholdsWindow evaluates the thresholds of the test run against only the
+// samples ingested since it was last reset, rather than since the start of
+// the test run, e.g. for the breaking-point executor to evaluate each of its
+// steps on its own.
+type ThresholdsWindow struct {
+	me    *MetricsEngine
+	start time.Time
+	sinks map[*metrics.Metric]metrics.Sink
+}
+
+var _ lib.ThresholdsWindow = &ThresholdsWindow{}
+
+// NewThresholdsWindow returns a new window, started now. It should be closed
+// when it isn't used anymore.
+func (me *MetricsEngine) NewThresholdsWindow() *ThresholdsWindow {
+	w := &ThresholdsWindow{me: me, start: time.Now(), sinks: make(map[*metrics.Metric]metrics.Sink)}
+
+	me.MetricsLock.Lock()
+	defer me.MetricsLock.Unlock()
+	if me.thresholdsWindows == nil {
+		me.thresholdsWindows = make(map[*ThresholdsWindow]struct{})
+	}
+	me.thresholdsWindows[w] = struct{}{}
+	return w
+}
+
+// addToThresholdsWindows adds the sample of the metric to the windows, if the
+// metric has thresholds. It should be called with the MetricsLock held.
+func (me *MetricsEngine) addToThresholdsWindows(m *metrics.Metric, s metrics.Sample) {
+	if len(me.thresholdsWindows) == 0 || len(m.Thresholds.Thresholds) == 0 {
+		return
+	}
+	for w := range me.thresholdsWindows {
+		sink, ok := w.sinks[m]
+		if !ok {
+			sink = metrics.NewSink(m.Type)
+			w.sinks[m] = sink
+		}
+		sink.Add(s)
+	}
+}
+
+// HasThresholds returns whether the test run has any thresholds to evaluate.
+func (w *ThresholdsWindow) HasThresholds() bool {
+	w.me.MetricsLock.Lock()
+	defer w.me.MetricsLock.Unlock()
+
+	if !w.me.thresholdsEnabled {
+		return false
+	}
+	for _, m := range w.me.metricsWithThresholds {
+		if len(m.Thresholds.Thresholds) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Reset drops the samples of the window and starts it again.
+func (w *ThresholdsWindow) Reset() {
+	w.me.MetricsLock.Lock()
+	defer w.me.MetricsLock.Unlock()
+
+	w.start = time.Now()
+	w.sinks = make(map[*metrics.Metric]metrics.Sink)
+}
+
+// Breached returns the metrics whose thresholds fail with the samples of the
+// window, sorted by their names. The metrics without samples are ignored.
+func (w *ThresholdsWindow) Breached() []string {
+	w.me.MetricsLock.Lock()
+	defer w.me.MetricsLock.Unlock()
+
+	duration := time.Since(w.start)
+	var breached []string
+	for _, m := range w.me.metricsWithThresholds {
+		sink, ok := w.sinks[m]
+		if !ok || sink.IsEmpty() {
+			continue
+		}
+		succ, err := m.Thresholds.RunWindow(sink, duration)
+		if err != nil {
+			w.me.logger.WithField("metric_name", m.Name).WithError(err).Error("Threshold error")
+			continue
+		}
+		if !succ {
+			breached = append(breached, m.Name)
+		}
+	}
+	sort.Strings(breached)
+	return breached
+}
+
+// Close stops adding the samples to the window.
+func (w *ThresholdsWindow) Close() {
+	w.me.MetricsLock.Lock()
+	defer w.me.MetricsLock.Unlock()
+
+	delete(w.me.thresholdsWindows, w)
+}
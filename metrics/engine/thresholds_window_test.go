@@ -0,0 +1,64 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/liuxd6825/k6server/lib"
+	"github.com/liuxd6825/k6server/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestThresholdsWindow(t *testing.T) {
+	t.Parallel()
+
+	me := newTestMetricsEngine(t)
+	trend, err := me.registry.NewMetric("my_trend", metrics.Trend)
+	require.NoError(t, err)
+	counter, err := me.registry.NewMetric("my_counter", metrics.Counter)
+	require.NoError(t, err)
+	require.NoError(t, me.InitSubMetricsAndThresholds(lib.Options{}, false))
+
+	window := me.NewThresholdsWindow()
+	defer window.Close()
+	assert.False(t, window.HasThresholds())
+	require.NoError(t, me.AddThreshold("my_trend", "max<100", false))
+	require.NoError(t, me.AddThreshold("my_counter", "count<10", false))
+	assert.True(t, window.HasThresholds())
+
+	ingester := me.CreateIngester()
+	require.NoError(t, ingester.Start())
+	add := func(m *metrics.Metric, value float64) {
+		ingester.AddMetricSamples([]metrics.SampleContainer{metrics.Sample{
+			TimeSeries: metrics.TimeSeries{Metric: m, Tags: me.registry.RootTagSet()},
+			Time:       time.Now(),
+			Value:      value,
+		}})
+		ingester.flushMetrics()
+	}
+
+	add(trend, 200)
+	add(counter, 20)
+	assert.Equal(t, []string{"my_counter", "my_trend"}, window.Breached())
+
+	// only the samples since the reset are evaluated, unlike the thresholds
+	// of the whole test run
+	window.Reset()
+	assert.Empty(t, window.Breached())
+	add(trend, 50)
+	assert.Empty(t, window.Breached())
+	breached, _ := me.evaluateThresholds(false, zeroTestRunDuration)
+	assert.Equal(t, []string{"my_counter", "my_trend"}, breached)
+
+	// the muted thresholds don't fail the window
+	add(counter, 20)
+	require.NoError(t, me.SetThresholdMuted("my_counter", "count<10", true))
+	assert.Empty(t, window.Breached())
+
+	window.Close()
+	window.Reset()
+	add(trend, 200)
+	assert.Empty(t, window.Breached())
+	require.NoError(t, ingester.Stop())
+}
@@ -60,6 +60,17 @@ func (c *CounterSink) Add(s Sample) {
 // IsEmpty indicates whether the CounterSink is empty.
 func (c *CounterSink) IsEmpty() bool { return c.First.IsZero() }
 
+// Merge adds the count of the other sink to this one.
+func (c *CounterSink) Merge(other *CounterSink) {
+	if other.IsEmpty() {
+		return
+	}
+	c.Value += other.Value
+	if c.First.IsZero() || other.First.Before(c.First) {
+		c.First = other.First
+	}
+}
+
 // Format counter and return a map
 func (c *CounterSink) Format(t time.Duration) map[string]float64 {
 	return map[string]float64{
@@ -72,6 +83,7 @@ func (c *CounterSink) Format(t time.Duration) map[string]float64 {
 type GaugeSink struct {
 	Value    float64
 	Max, Min float64
+	Last     time.Time // the time of the sample of the value
 	minSet   bool
 }
 
@@ -80,7 +92,7 @@ func (g *GaugeSink) IsEmpty() bool { return !g.minSet }
 
 // Add a single sample to the sink
 func (g *GaugeSink) Add(s Sample) {
-	g.Value = s.Value
+	g.Value, g.Last = s.Value, s.Time
 	if s.Value > g.Max {
 		g.Max = s.Value
 	}
@@ -90,6 +102,45 @@ func (g *GaugeSink) Add(s Sample) {
 	}
 }
 
+// Merge adds the samples of the other sink to this one, as if they had been
+// added to it, so its value is the latest one of both sinks, by the time of
+// their samples, and its extremes are the ones of both.
+func (g *GaugeSink) Merge(other *GaugeSink) {
+	if other.IsEmpty() {
+		return
+	}
+	if g.IsEmpty() {
+		*g = *other
+		return
+	}
+	if !other.Last.Before(g.Last) {
+		g.Value, g.Last = other.Value, other.Last
+	}
+	g.Min = math.Min(g.Min, other.Min)
+	g.Max = math.Max(g.Max, other.Max)
+}
+
+// Sum adds the value of the other sink to this one, for the gauges that are
+// parts of the same total, like the VUs of the instances of a distributed
+// test run. Their extremes are summed too, though the ones of the parts may
+// not have been reached at the same time, so they're the bounds of the ones
+// of the total.
+func (g *GaugeSink) Sum(other *GaugeSink) {
+	if other.IsEmpty() {
+		return
+	}
+	if g.IsEmpty() {
+		*g = *other
+		return
+	}
+	g.Value += other.Value
+	g.Min += other.Min
+	g.Max += other.Max
+	if other.Last.After(g.Last) {
+		g.Last = other.Last
+	}
+}
+
 // Format gauge and return a map
 func (g *GaugeSink) Format(_ time.Duration) map[string]float64 {
 	return map[string]float64{"value": g.Value}
@@ -105,6 +156,10 @@ type TrendSink struct {
 	values []float64
 	sorted bool
 
+	// sketch is set once a sketch is merged into the sink, and then it has
+	// all of the values instead of the values slice.
+	sketch *TrendSketch
+
 	count    uint64
 	min, max float64
 	sum      float64
@@ -126,14 +181,47 @@ func (t *TrendSink) Add(s Sample) {
 		}
 	}
 
-	t.values = append(t.values, s.Value)
-	t.sorted = false
 	t.count++
 	t.sum += s.Value
+	if t.sketch != nil {
+		t.sketch.Add(s.Value)
+		return
+	}
+	t.values = append(t.values, s.Value)
+	t.sorted = false
+}
+
+// MergeSketch adds the values of the sketch to the sink, e.g. when the trends
+// of the instances of a distributed test run are aggregated. Its percentiles
+// are estimated from a sketch of all of its values afterwards.
+func (t *TrendSink) MergeSketch(sketch *TrendSketch) {
+	if sketch.Count() == 0 {
+		return
+	}
+	if t.sketch == nil {
+		t.sketch = NewTrendSketch()
+		for _, v := range t.values {
+			t.sketch.Add(v)
+		}
+		t.values, t.sorted = nil, false
+	}
+	t.sketch.Merge(sketch)
+
+	if t.count == 0 || sketch.Min() < t.min {
+		t.min = sketch.Min()
+	}
+	if t.count == 0 || sketch.Max() > t.max {
+		t.max = sketch.Max()
+	}
+	t.count += sketch.Count()
+	t.sum += sketch.Total()
 }
 
 // P calculates the given percentile from sink values.
 func (t *TrendSink) P(pct float64) float64 {
+	if t.sketch != nil {
+		return t.sketch.P(pct)
+	}
 	switch t.count {
 	case 0:
 		return 0
@@ -214,6 +302,12 @@ func (r *RateSink) Add(s Sample) {
 	}
 }
 
+// Merge adds the samples of the other sink to this one.
+func (r *RateSink) Merge(other *RateSink) {
+	r.Trues += other.Trues
+	r.Total += other.Total
+}
+
 // Format rate and return a map
 func (r RateSink) Format(_ time.Duration) map[string]float64 {
 	var rate float64
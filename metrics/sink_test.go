@@ -63,6 +63,15 @@ func TestCounterSink(t *testing.T) {
 		}
 		assert.Equal(t, map[string]float64{"count": 145, "rate": 145.0}, sink.Format(1*time.Second))
 	})
+	t.Run("merge", func(t *testing.T) {
+		t.Parallel()
+		sink := CounterSink{}
+		sink.Merge(&CounterSink{Value: 10, First: now.Add(time.Second)})
+		sink.Merge(&CounterSink{Value: 5, First: now})
+		sink.Merge(&CounterSink{})
+		assert.Equal(t, 15.0, sink.Value)
+		assert.Equal(t, now, sink.First)
+	})
 }
 
 func TestGaugeSink(t *testing.T) {
@@ -100,6 +109,47 @@ func TestGaugeSink(t *testing.T) {
 		}
 		assert.Equal(t, map[string]float64{"value": 5.0}, sink.Format(0))
 	})
+	t.Run("merge", func(t *testing.T) {
+		t.Parallel()
+		sink := GaugeSink{}
+		other := GaugeSink{}
+		sink.Merge(&other)
+		assert.True(t, sink.IsEmpty())
+		now := time.Now()
+		for i, s := range samples6 {
+			other.Add(Sample{TimeSeries: TimeSeries{Metric: &Metric{}}, Time: now.Add(time.Duration(i)), Value: s})
+		}
+		sink.Merge(&other)
+
+		// the value is the latest one
+		earlier := GaugeSink{}
+		earlier.Add(Sample{TimeSeries: TimeSeries{Metric: &Metric{}}, Time: now, Value: 20.0})
+		sink.Merge(&earlier)
+		assert.Equal(t, 5.0, sink.Value)
+		assert.Equal(t, 1.0, sink.Min)
+		assert.Equal(t, 20.0, sink.Max)
+		later := GaugeSink{}
+		later.Add(Sample{TimeSeries: TimeSeries{Metric: &Metric{}}, Time: now.Add(time.Hour), Value: 0.5})
+		sink.Merge(&later)
+		assert.Equal(t, 0.5, sink.Value)
+		assert.Equal(t, 0.5, sink.Min)
+		assert.Equal(t, now.Add(time.Hour), sink.Last)
+	})
+	t.Run("sum", func(t *testing.T) {
+		t.Parallel()
+		sink := GaugeSink{}
+		other := GaugeSink{}
+		sink.Sum(&other)
+		assert.True(t, sink.IsEmpty())
+		for _, s := range samples6 {
+			other.Add(Sample{TimeSeries: TimeSeries{Metric: &Metric{}}, Value: s})
+		}
+		sink.Sum(&other)
+		sink.Sum(&other)
+		assert.Equal(t, 10.0, sink.Value)
+		assert.Equal(t, 2.0, sink.Min)
+		assert.Equal(t, 20.0, sink.Max)
+	})
 }
 
 func TestTrendSink(t *testing.T) {
@@ -274,4 +324,10 @@ func TestRateSink(t *testing.T) {
 		}
 		assert.Equal(t, map[string]float64{"rate": 0.5}, sink.Format(0))
 	})
+	t.Run("merge", func(t *testing.T) {
+		t.Parallel()
+		sink := RateSink{Trues: 1, Total: 2}
+		sink.Merge(&RateSink{Trues: 3, Total: 6})
+		assert.Equal(t, RateSink{Trues: 4, Total: 8}, sink)
+	})
 }
@@ -0,0 +1,237 @@
+package metrics
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"sort"
+)
+
+// TrendSketchAccuracy is the maximum relative error of the percentiles that
+// are estimated from a TrendSketch.
+const TrendSketchAccuracy = 0.01
+
+// minSketchedValue is the smallest absolute value that has its own bucket in a
+// TrendSketch, the ones closer to zero are counted as zeros.
+const minSketchedValue = 1e-9
+
+var (
+	sketchGamma    = (1 + TrendSketchAccuracy) / (1 - TrendSketchAccuracy)
+	sketchLogGamma = math.Log(sketchGamma)
+)
+
+// TrendSketch is a mergeable summary of the values of a trend, from which its
+// percentiles can be estimated with a relative error of at most
+// TrendSketchAccuracy, without keeping all of the values. The buckets of the
+// values are logarithmic, like the ones of DDSketch, so their number only
+// grows with the range of the values.
+//
+// Sketches are used to aggregate the trends of the instances of a distributed
+// test run, since merging two of them gives the sketch of all of their values.
+type TrendSketch struct {
+	positive map[int32]uint64
+	negative map[int32]uint64
+	zeros    uint64
+
+	count    uint64
+	min, max float64
+	sum      float64
+}
+
+// NewTrendSketch returns an empty sketch.
+func NewTrendSketch() *TrendSketch {
+	return &TrendSketch{
+		positive: make(map[int32]uint64),
+		negative: make(map[int32]uint64),
+	}
+}
+
+func sketchIndex(v float64) int32 {
+	return int32(math.Ceil(math.Log(v) / sketchLogGamma))
+}
+
+// sketchValue returns the value of a bucket, whose relative error is the
+// lowest for all of the values in it.
+func sketchValue(i int32) float64 {
+	return 2 * math.Pow(sketchGamma, float64(i)) / (sketchGamma + 1)
+}
+
+// Add adds the value to the sketch.
+func (s *TrendSketch) Add(v float64) {
+	if s.count == 0 || v < s.min {
+		s.min = v
+	}
+	if s.count == 0 || v > s.max {
+		s.max = v
+	}
+	s.count++
+	s.sum += v
+
+	switch {
+	case v > minSketchedValue:
+		s.positive[sketchIndex(v)]++
+	case v < -minSketchedValue:
+		s.negative[sketchIndex(-v)]++
+	default:
+		s.zeros++
+	}
+}
+
+// Merge adds all of the values of the other sketch to this one.
+func (s *TrendSketch) Merge(other *TrendSketch) {
+	if other.count == 0 {
+		return
+	}
+	if s.count == 0 || other.min < s.min {
+		s.min = other.min
+	}
+	if s.count == 0 || other.max > s.max {
+		s.max = other.max
+	}
+	s.count += other.count
+	s.sum += other.sum
+	s.zeros += other.zeros
+	for i, c := range other.positive {
+		s.positive[i] += c
+	}
+	for i, c := range other.negative {
+		s.negative[i] += c
+	}
+}
+
+// P estimates the given percentile of the values, with the same rank as the
+// ones of TrendSink.
+func (s *TrendSketch) P(pct float64) float64 {
+	switch {
+	case s.count == 0:
+		return 0
+	case pct <= 0:
+		return s.min
+	case pct >= 1:
+		return s.max
+	}
+
+	rank := pct * float64(s.count-1)
+	var seen uint64
+
+	// The buckets are visited from the lowest values to the highest ones.
+	negative := sortedIndexes(s.negative)
+	for j := len(negative) - 1; j >= 0; j-- {
+		if seen += s.negative[negative[j]]; float64(seen) > rank {
+			return s.clamp(-sketchValue(negative[j]))
+		}
+	}
+	if seen += s.zeros; float64(seen) > rank {
+		return s.clamp(0)
+	}
+	for _, i := range sortedIndexes(s.positive) {
+		if seen += s.positive[i]; float64(seen) > rank {
+			return s.clamp(sketchValue(i))
+		}
+	}
+	return s.max
+}
+
+func (s *TrendSketch) clamp(v float64) float64 {
+	return math.Max(s.min, math.Min(s.max, v))
+}
+
+func sortedIndexes(buckets map[int32]uint64) []int32 {
+	indexes := make([]int32, 0, len(buckets))
+	for i := range buckets {
+		indexes = append(indexes, i)
+	}
+	sort.Slice(indexes, func(a, b int) bool { return indexes[a] < indexes[b] })
+	return indexes
+}
+
+// Count returns the number of values.
+func (s *TrendSketch) Count() uint64 { return s.count }
+
+// Min returns the minimum value.
+func (s *TrendSketch) Min() float64 { return s.min }
+
+// Max returns the maximum value.
+func (s *TrendSketch) Max() float64 { return s.max }
+
+// Total returns the sum of the values.
+func (s *TrendSketch) Total() float64 { return s.sum }
+
+// MarshalBinary encodes the sketch, its buckets being sorted so equal
+// sketches have the same encoding.
+func (s *TrendSketch) MarshalBinary() ([]byte, error) {
+	buf := make([]byte, 0, 5*binary.MaxVarintLen64+binary.MaxVarintLen64*(len(s.positive)+len(s.negative)))
+	buf = binary.AppendUvarint(buf, s.count)
+	buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(s.min))
+	buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(s.max))
+	buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(s.sum))
+	buf = binary.AppendUvarint(buf, s.zeros)
+	for _, buckets := range []map[int32]uint64{s.positive, s.negative} {
+		buf = binary.AppendUvarint(buf, uint64(len(buckets)))
+		for _, i := range sortedIndexes(buckets) {
+			buf = binary.AppendVarint(buf, int64(i))
+			buf = binary.AppendUvarint(buf, buckets[i])
+		}
+	}
+	return buf, nil
+}
+
+var errInvalidTrendSketch = errors.New("invalid trend sketch encoding")
+
+// UnmarshalBinary decodes a sketch encoded by MarshalBinary.
+func (s *TrendSketch) UnmarshalBinary(data []byte) error {
+	r := &sketchReader{data: data}
+	sketch := NewTrendSketch()
+	sketch.count = r.uvarint()
+	sketch.min = r.float64()
+	sketch.max = r.float64()
+	sketch.sum = r.float64()
+	sketch.zeros = r.uvarint()
+	for _, buckets := range []map[int32]uint64{sketch.positive, sketch.negative} {
+		n := r.uvarint()
+		for j := uint64(0); j < n && r.err == nil; j++ {
+			i := r.varint()
+			buckets[int32(i)] = r.uvarint()
+		}
+	}
+	if r.err != nil || len(r.data) != 0 {
+		return errInvalidTrendSketch
+	}
+	*s = *sketch
+	return nil
+}
+
+type sketchReader struct {
+	data []byte
+	err  error
+}
+
+func (r *sketchReader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.data)
+	if n <= 0 {
+		r.err, r.data = errInvalidTrendSketch, nil
+		return 0
+	}
+	r.data = r.data[n:]
+	return v
+}
+
+func (r *sketchReader) varint() int64 {
+	v, n := binary.Varint(r.data)
+	if n <= 0 {
+		r.err, r.data = errInvalidTrendSketch, nil
+		return 0
+	}
+	r.data = r.data[n:]
+	return v
+}
+
+func (r *sketchReader) float64() float64 {
+	if len(r.data) < 8 {
+		r.err, r.data = errInvalidTrendSketch, nil
+		return 0
+	}
+	v := math.Float64frombits(binary.LittleEndian.Uint64(r.data))
+	r.data = r.data[8:]
+	return v
+}
@@ -0,0 +1,112 @@
+package metrics
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrendSketch(t *testing.T) {
+	t.Parallel()
+
+	t.Run("empty", func(t *testing.T) {
+		t.Parallel()
+
+		s := NewTrendSketch()
+		assert.Equal(t, uint64(0), s.Count())
+		assert.Equal(t, 0.0, s.P(0.95))
+	})
+
+	t.Run("percentiles", func(t *testing.T) {
+		t.Parallel()
+
+		r := rand.New(rand.NewSource(1)) //nolint:gosec
+		sketch, sink := NewTrendSketch(), NewTrendSink()
+		for i := 0; i < 10000; i++ {
+			v := r.ExpFloat64() * 100
+			sketch.Add(v)
+			sink.Add(Sample{Value: v})
+		}
+
+		assert.Equal(t, sink.Count(), sketch.Count())
+		assert.Equal(t, sink.Min(), sketch.Min())
+		assert.Equal(t, sink.Max(), sketch.Max())
+		assert.InDelta(t, sink.Total(), sketch.Total(), 1e-6)
+		for _, pct := range []float64{0, 0.01, 0.5, 0.9, 0.95, 0.99, 1} {
+			assert.InEpsilon(t, sink.P(pct), sketch.P(pct), TrendSketchAccuracy, "p(%g)", pct*100)
+		}
+	})
+
+	t.Run("negative values and zeros", func(t *testing.T) {
+		t.Parallel()
+
+		s := NewTrendSketch()
+		for _, v := range []float64{-100, -10, 0, 0, 10, 100} {
+			s.Add(v)
+		}
+		assert.Equal(t, -100.0, s.P(0))
+		assert.InEpsilon(t, -10.0, s.P(0.2), TrendSketchAccuracy)
+		assert.Equal(t, 0.0, s.P(0.5))
+		assert.InEpsilon(t, 10.0, s.P(0.8), TrendSketchAccuracy)
+		assert.Equal(t, 100.0, s.P(1))
+	})
+
+	t.Run("merge", func(t *testing.T) {
+		t.Parallel()
+
+		a, b, all := NewTrendSketch(), NewTrendSketch(), NewTrendSketch()
+		for i := 1; i <= 100; i++ {
+			all.Add(float64(i))
+			if i%3 == 0 {
+				a.Add(float64(i))
+			} else {
+				b.Add(float64(i))
+			}
+		}
+		a.Merge(b)
+		a.Merge(NewTrendSketch())
+		assert.Equal(t, all, a)
+	})
+
+	t.Run("encoding", func(t *testing.T) {
+		t.Parallel()
+
+		s := NewTrendSketch()
+		for _, v := range []float64{-3.5, 0, 1, 2.5, 1000} {
+			s.Add(v)
+		}
+		data, err := s.MarshalBinary()
+		require.NoError(t, err)
+
+		decoded := NewTrendSketch()
+		require.NoError(t, decoded.UnmarshalBinary(data))
+		assert.Equal(t, s, decoded)
+
+		assert.Error(t, decoded.UnmarshalBinary(data[:len(data)-1]))
+		assert.Error(t, decoded.UnmarshalBinary(append(data, 0)))
+	})
+}
+
+func TestTrendSinkMergeSketch(t *testing.T) {
+	t.Parallel()
+
+	sink := NewTrendSink()
+	for _, v := range []float64{1, 2, 3} {
+		sink.Add(Sample{Value: v})
+	}
+	sketch := NewTrendSketch()
+	for _, v := range []float64{4, 5, 6, 7} {
+		sketch.Add(v)
+	}
+	sink.MergeSketch(sketch)
+	sink.Add(Sample{Value: 8})
+
+	assert.Equal(t, uint64(8), sink.Count())
+	assert.Equal(t, 1.0, sink.Min())
+	assert.Equal(t, 8.0, sink.Max())
+	assert.Equal(t, 4.5, sink.Avg())
+	assert.InEpsilon(t, 4.0, sink.P(0.5), TrendSketchAccuracy)
+	assert.Equal(t, 8.0, sink.P(1))
+}
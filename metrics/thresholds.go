@@ -24,6 +24,9 @@ type Threshold struct {
 	// AbortGracePeriod is a the minimum amount of time a test should be running before a failing
 	// this threshold will abort the test
 	AbortGracePeriod types.NullDuration
+	// Muted marks a threshold whose failures are ignored, i.e. they neither
+	// fail the test nor abort it, but LastFailed is still updated
+	Muted bool
 	// parsed is the threshold expression parsed from the Source
 	parsed *thresholdExpression
 }
@@ -151,6 +154,9 @@ func (ts *Thresholds) runAll(timeSpentInTest time.Duration) (bool, error) {
 		}
 
 		if !b {
+			if threshold.Muted {
+				continue
+			}
 			succeeded = false
 
 			if ts.Abort || !threshold.AbortOnFail {
@@ -168,8 +174,39 @@ func (ts *Thresholds) runAll(timeSpentInTest time.Duration) (bool, error) {
 // Run processes all the thresholds with the provided Sink at the provided time and returns if any
 // of them fails
 func (ts *Thresholds) Run(sink Sink, duration time.Duration) (bool, error) {
-	// Initialize the sinks store
-	ts.sinked = make(map[string]float64)
+	sinked, err := ts.sinkValues(sink, duration)
+	if err != nil {
+		return false, err
+	}
+	ts.sinked = sinked
+
+	return ts.runAll(duration)
+}
+
+// RunWindow evaluates the thresholds with the provided Sink of a window of the
+// test run, lasting the provided duration, and returns if any of the ones
+// which aren't muted fails. Unlike Run, it doesn't change their state.
+func (ts *Thresholds) RunWindow(sink Sink, duration time.Duration) (bool, error) {
+	sinked, err := ts.sinkValues(sink, duration)
+	if err != nil {
+		return false, err
+	}
+	for i, threshold := range ts.Thresholds {
+		passes, err := threshold.runNoTaint(sinked)
+		if err != nil {
+			return false, fmt.Errorf("threshold %d run error: %w", i, err)
+		}
+		if !passes && !threshold.Muted {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// sinkValues returns the values of the sink for the aggregation methods of the
+// thresholds.
+func (ts *Thresholds) sinkValues(sink Sink, duration time.Duration) (map[string]float64, error) {
+	sinked := make(map[string]float64)
 
 	// FIXME: Remove this comment as soon as the metrics.Sink does not expose Format anymore.
 	//
@@ -180,15 +217,15 @@ func (ts *Thresholds) Run(sink Sink, duration time.Duration) (bool, error) {
 	// For more details, see https://github.com/grafana/k6/issues/2320
 	switch sinkImpl := sink.(type) {
 	case *CounterSink:
-		ts.sinked["count"] = sinkImpl.Value
-		ts.sinked["rate"] = sinkImpl.Value / (float64(duration) / float64(time.Second))
+		sinked["count"] = sinkImpl.Value
+		sinked["rate"] = sinkImpl.Value / (float64(duration) / float64(time.Second))
 	case *GaugeSink:
-		ts.sinked["value"] = sinkImpl.Value
+		sinked["value"] = sinkImpl.Value
 	case *TrendSink:
-		ts.sinked["min"] = sinkImpl.Min()
-		ts.sinked["max"] = sinkImpl.Max()
-		ts.sinked["avg"] = sinkImpl.Avg()
-		ts.sinked["med"] = sinkImpl.P(0.5)
+		sinked["min"] = sinkImpl.Min()
+		sinked["max"] = sinkImpl.Max()
+		sinked["avg"] = sinkImpl.Avg()
+		sinked["med"] = sinkImpl.P(0.5)
 
 		// Parse the percentile thresholds and insert them in
 		// the sinks mapping.
@@ -198,19 +235,19 @@ func (ts *Thresholds) Run(sink Sink, duration time.Duration) (bool, error) {
 			}
 
 			key := fmt.Sprintf("p(%g)", threshold.parsed.AggregationValue.Float64)
-			ts.sinked[key] = sinkImpl.P(threshold.parsed.AggregationValue.Float64 / 100)
+			sinked[key] = sinkImpl.P(threshold.parsed.AggregationValue.Float64 / 100)
 		}
 	case *RateSink:
 		// We want to avoid division by zero, which
 		// would lead to [#2520](https://github.com/grafana/k6/issues/2520)
 		if sinkImpl.Total > 0 {
-			ts.sinked["rate"] = float64(sinkImpl.Trues) / float64(sinkImpl.Total)
+			sinked["rate"] = float64(sinkImpl.Trues) / float64(sinkImpl.Total)
 		}
 	default:
-		return false, fmt.Errorf("unable to run Thresholds; reason: unknown sink type")
+		return nil, fmt.Errorf("unable to run Thresholds; reason: unknown sink type")
 	}
 
-	return ts.runAll(duration)
+	return sinked, nil
 }
 
 // Parse parses the Thresholds and fills each Threshold.parsed field with the result.
@@ -325,6 +362,16 @@ func MarshalJSONWithoutHTMLEscape(t interface{}) ([]byte, error) {
 	return bytes, err
 }
 
+// ThresholdChange is a change of the thresholds during the test run, e.g.
+// through the REST API, which is kept for the end-of-test summary.
+type ThresholdChange struct {
+	Time time.Time `json:"time"`
+	// Action is one of "add", "mute" and "unmute"
+	Action    string `json:"action"`
+	Metric    string `json:"metric"`
+	Threshold string `json:"threshold"`
+}
+
 var (
 	_ json.Unmarshaler = &Thresholds{}
 	_ json.Marshaler   = &Thresholds{}
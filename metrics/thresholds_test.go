@@ -639,6 +639,47 @@ func TestThresholdsRunAll(t *testing.T) {
 	}
 }
 
+func TestThresholdsRunAllMuted(t *testing.T) {
+	t.Parallel()
+
+	thresholds := NewThresholds([]string{`rate<0.01`, `p(95)<200`})
+	require.NoError(t, thresholds.Parse())
+	thresholds.sinked = map[string]float64{"rate": 0.0001, "p(95)": 500}
+	thresholds.Thresholds[1].AbortOnFail = true
+	thresholds.Thresholds[1].Muted = true
+
+	succeeded, err := thresholds.runAll(time.Second)
+	require.NoError(t, err)
+	assert.True(t, succeeded)
+	assert.False(t, thresholds.Abort)
+	assert.True(t, thresholds.Thresholds[1].LastFailed)
+}
+
+func TestThresholdsRunWindow(t *testing.T) {
+	t.Parallel()
+
+	thresholds := NewThresholds([]string{`p(95)<200`, `max<300`})
+	require.NoError(t, thresholds.Parse())
+	thresholds.Thresholds[1].Muted = true
+
+	succeeded, err := thresholds.RunWindow(getTrendSink(100, 150), time.Second)
+	require.NoError(t, err)
+	assert.True(t, succeeded)
+
+	// the muted thresholds don't fail the window
+	succeeded, err = thresholds.RunWindow(getTrendSink(100, 150, 400), time.Second)
+	require.NoError(t, err)
+	assert.False(t, succeeded)
+	succeeded, err = thresholds.RunWindow(getTrendSink(100, 150, 190, 190, 190, 190, 190, 190, 190, 190, 190,
+		190, 190, 190, 190, 190, 190, 190, 190, 190, 400), time.Second)
+	require.NoError(t, err)
+	assert.True(t, succeeded)
+
+	// the state of the thresholds isn't changed
+	assert.False(t, thresholds.Thresholds[0].LastFailed)
+	assert.Empty(t, thresholds.sinked)
+}
+
 func getTrendSink(values ...float64) *TrendSink {
 	sink := NewTrendSink()
 	for _, v := range values {
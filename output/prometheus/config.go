@@ -0,0 +1,160 @@
+package prometheus
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/guregu/null.v3"
+
+	"github.com/mstoykov/envconfig"
+)
+
+// The ways the trend metrics can be exposed.
+const (
+	TrendAsSummary   = "summary"
+	TrendAsHistogram = "histogram"
+)
+
+// Config is the config for the prometheus output
+type Config struct {
+	// Addr is the address of the /metrics endpoint. If it's empty, the
+	// endpoint is served by the REST API server of k6 instead.
+	Addr      null.String `json:"addr" envconfig:"K6_PROMETHEUS_ADDR"`
+	Namespace null.String `json:"namespace" envconfig:"K6_PROMETHEUS_NAMESPACE"`
+	// TrendAs is either summary, with quantiles, or histogram, with buckets.
+	TrendAs null.String `json:"trendAs" envconfig:"K6_PROMETHEUS_TREND_AS"`
+	// Buckets are the upper bounds of the buckets of the trend histograms,
+	// separated by commas.
+	Buckets null.String `json:"buckets" envconfig:"K6_PROMETHEUS_BUCKETS"`
+	// MaxSeries is the maximum number of series, i.e. of the unique
+	// combinations of the metrics and their tags. The samples of any new
+	// series after that are dropped.
+	MaxSeries null.Int `json:"maxSeries" envconfig:"K6_PROMETHEUS_MAX_SERIES"`
+}
+
+// NewConfig creates a new Config instance with default values for some fields.
+func NewConfig() Config {
+	return Config{
+		Addr:      null.NewString("", false),
+		Namespace: null.NewString("k6", false),
+		TrendAs:   null.NewString(TrendAsSummary, false),
+		Buckets:   null.NewString("5,10,25,50,100,250,500,1000,2500,5000,10000", false),
+		MaxSeries: null.NewInt(10000, false),
+	}
+}
+
+// Apply merges two configs by overwriting properties in the old config
+func (c Config) Apply(cfg Config) Config {
+	if cfg.Addr.Valid {
+		c.Addr = cfg.Addr
+	}
+	if cfg.Namespace.Valid {
+		c.Namespace = cfg.Namespace
+	}
+	if cfg.TrendAs.Valid {
+		c.TrendAs = cfg.TrendAs
+	}
+	if cfg.Buckets.Valid {
+		c.Buckets = cfg.Buckets
+	}
+	if cfg.MaxSeries.Valid {
+		c.MaxSeries = cfg.MaxSeries
+	}
+	return c
+}
+
+// Validate returns an error if the config is invalid.
+func (c Config) Validate() error {
+	if c.TrendAs.String != TrendAsSummary && c.TrendAs.String != TrendAsHistogram {
+		return fmt.Errorf("the trendAs option should be %s or %s, not %q", TrendAsSummary, TrendAsHistogram, c.TrendAs.String)
+	}
+	if c.MaxSeries.Int64 <= 0 {
+		return fmt.Errorf("the maxSeries option should be positive, not %d", c.MaxSeries.Int64)
+	}
+	if _, err := c.parseBuckets(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// parseBuckets returns the sorted upper bounds of the buckets.
+func (c Config) parseBuckets() ([]float64, error) {
+	var buckets []float64
+	for _, s := range strings.Split(c.Buckets.String, ",") {
+		b, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bucket %q: %w", s, err)
+		}
+		buckets = append(buckets, b)
+	}
+	sort.Float64s(buckets)
+	return buckets, nil
+}
+
+// ParseArg takes an arg string and converts it to a config
+func ParseArg(arg string) (Config, error) {
+	c := Config{}
+
+	pairs := strings.Split(arg, ",")
+	for _, pair := range pairs {
+		r := strings.SplitN(pair, "=", 2)
+		if len(r) != 2 {
+			return c, fmt.Errorf("couldn't parse %q as argument for prometheus output", arg)
+		}
+		switch r[0] {
+		case "addr":
+			c.Addr = null.StringFrom(r[1])
+		case "namespace":
+			c.Namespace = null.StringFrom(r[1])
+		case "trendAs":
+			c.TrendAs = null.StringFrom(r[1])
+		case "maxSeries":
+			maxSeries, err := strconv.ParseInt(r[1], 10, 64)
+			if err != nil {
+				return c, fmt.Errorf("invalid maxSeries %q: %w", r[1], err)
+			}
+			c.MaxSeries = null.IntFrom(maxSeries)
+		default:
+			return c, fmt.Errorf("unknown key %q as argument for prometheus output", r[0])
+		}
+	}
+
+	return c, nil
+}
+
+// GetConsolidatedConfig combines {default config values + JSON config +
+// environment vars + arg config values}, and returns the final result.
+func GetConsolidatedConfig(
+	jsonRawConf json.RawMessage, env map[string]string, arg string,
+) (Config, error) {
+	result := NewConfig()
+	if jsonRawConf != nil {
+		jsonConf := Config{}
+		if err := json.Unmarshal(jsonRawConf, &jsonConf); err != nil {
+			return result, err
+		}
+		result = result.Apply(jsonConf)
+	}
+
+	envConfig := Config{}
+	if err := envconfig.Process("", &envConfig, func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	}); err != nil {
+		return result, err
+	}
+	result = result.Apply(envConfig)
+
+	if arg != "" {
+		argConf, err := ParseArg(arg)
+		if err != nil {
+			return result, err
+		}
+		result = result.Apply(argConf)
+	}
+
+	return result, result.Validate()
+}
@@ -0,0 +1,43 @@
+package prometheus
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetConsolidatedConfig(t *testing.T) {
+	t.Parallel()
+
+	config, err := GetConsolidatedConfig(nil, nil, "")
+	require.NoError(t, err)
+	assert.Equal(t, "", config.Addr.String)
+	assert.Equal(t, "k6", config.Namespace.String)
+	assert.Equal(t, TrendAsSummary, config.TrendAs.String)
+	assert.Equal(t, int64(10000), config.MaxSeries.Int64)
+
+	config, err = GetConsolidatedConfig(
+		json.RawMessage(`{"namespace":"load","buckets":"100, 10"}`),
+		map[string]string{"K6_PROMETHEUS_TREND_AS": "histogram", "K6_PROMETHEUS_MAX_SERIES": "5"},
+		"addr=localhost:9464,maxSeries=10",
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "localhost:9464", config.Addr.String)
+	assert.Equal(t, "load", config.Namespace.String)
+	assert.Equal(t, TrendAsHistogram, config.TrendAs.String)
+	assert.Equal(t, int64(10), config.MaxSeries.Int64)
+	buckets, err := config.parseBuckets()
+	require.NoError(t, err)
+	assert.Equal(t, []float64{10, 100}, buckets)
+
+	_, err = GetConsolidatedConfig(nil, nil, "trendAs=native")
+	assert.Error(t, err)
+	_, err = GetConsolidatedConfig(nil, nil, "maxSeries=0")
+	assert.Error(t, err)
+	_, err = GetConsolidatedConfig(nil, nil, "unknown=1")
+	assert.Error(t, err)
+	_, err = GetConsolidatedConfig(json.RawMessage(`{"buckets":"1,a"}`), nil, "")
+	assert.Error(t, err)
+}
@@ -0,0 +1,6 @@
+/*
+Package prometheus implements an output exposing the metrics of the test run
+on a /metrics endpoint, in the Prometheus text exposition format, so they can
+be scraped by Prometheus like the metrics of any other service.
+*/
+package prometheus
@@ -0,0 +1,336 @@
+package prometheus
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/liuxd6825/k6server/metrics"
+	"github.com/liuxd6825/k6server/output"
+)
+
+const (
+	flushPeriod = time.Second
+	metricsPath = "/metrics"
+)
+
+var summaryQuantiles = []float64{0.5, 0.9, 0.95, 0.99} //nolint:gochecknoglobals
+
+// Output implements the output.Output interface, exposing the metrics of the
+// test run on a /metrics endpoint to be scraped.
+type Output struct {
+	output.SampleBuffer
+
+	config          Config
+	buckets         []float64
+	logger          logrus.FieldLogger
+	periodicFlusher *output.PeriodicFlusher
+	server          *http.Server
+	listener        net.Listener
+
+	mu             sync.Mutex
+	series         map[metrics.TimeSeries]*series
+	droppedSamples uint64
+}
+
+// series is the aggregated value of a metric with a specific set of tags.
+type series struct {
+	labels string
+	sink   metrics.Sink
+}
+
+// histogramSink counts the values of a trend in buckets, so they don't need
+// to be kept, unlike with the trend sinks.
+type histogramSink struct {
+	buckets []float64
+	counts  []uint64
+	count   uint64
+	sum     float64
+}
+
+func (h *histogramSink) Add(s metrics.Sample) {
+	for i, b := range h.buckets {
+		if s.Value <= b {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += s.Value
+}
+
+func (h *histogramSink) Format(_ time.Duration) map[string]float64 {
+	return map[string]float64{"count": float64(h.count), "sum": h.sum}
+}
+
+func (h *histogramSink) IsEmpty() bool { return h.count == 0 }
+
+var _ output.WithHTTPHandler = &Output{}
+
+// New creates an instance of the prometheus output.
+func New(params output.Params) (output.Output, error) {
+	config, err := GetConsolidatedConfig(params.JSONConfig, params.Environment, params.ConfigArgument)
+	if err != nil {
+		return nil, err
+	}
+	buckets, err := config.parseBuckets()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Output{
+		config:  config,
+		buckets: buckets,
+		logger:  params.Logger.WithField("output", "prometheus"),
+		series:  make(map[metrics.TimeSeries]*series),
+	}, nil
+}
+
+// Description returns a human-readable description of the output.
+func (o *Output) Description() string {
+	if o.config.Addr.String == "" {
+		return "prometheus (" + metricsPath + " on the REST API server)"
+	}
+	return fmt.Sprintf("prometheus (http://%s%s)", o.config.Addr.String, metricsPath)
+}
+
+// HTTPHandler returns the handler of the /metrics endpoint, if it should be
+// served by the REST API server.
+func (o *Output) HTTPHandler() (string, http.Handler) {
+	if o.config.Addr.String != "" {
+		return "", nil
+	}
+	return metricsPath, o
+}
+
+// Start starts the /metrics endpoint on its own address, if it has one, and
+// the goroutine aggregating the samples.
+func (o *Output) Start() error {
+	if addr := o.config.Addr.String; addr != "" {
+		listener, err := net.Listen("tcp", addr)
+		if err != nil {
+			return fmt.Errorf("couldn't listen on %s: %w", addr, err)
+		}
+		o.listener = listener
+		mux := http.NewServeMux()
+		mux.Handle(metricsPath, o)
+		o.server = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+		go func() {
+			if err := o.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				o.logger.WithError(err).Error("The /metrics endpoint stopped")
+			}
+		}()
+	}
+
+	pf, err := output.NewPeriodicFlusher(flushPeriod, o.flushMetrics)
+	if err != nil {
+		return err
+	}
+	o.periodicFlusher = pf
+	return nil
+}
+
+// Stop flushes the buffered samples and stops the /metrics endpoint, if it's
+// served on its own address.
+func (o *Output) Stop() error {
+	o.periodicFlusher.Stop()
+	if o.server == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return o.server.Shutdown(ctx)
+}
+
+// addr returns the address the /metrics endpoint is listening on.
+func (o *Output) addr() net.Addr {
+	return o.listener.Addr()
+}
+
+func (o *Output) flushMetrics() {
+	samples := o.GetBufferedSamples()
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for _, sc := range samples {
+		for _, s := range sc.GetSamples() {
+			o.addSample(s)
+		}
+	}
+}
+
+func (o *Output) addSample(s metrics.Sample) {
+	ser, ok := o.series[s.TimeSeries]
+	if !ok {
+		if int64(len(o.series)) >= o.config.MaxSeries.Int64 {
+			if o.droppedSamples == 0 {
+				o.logger.Warnf("Reached the maximum of %d series, the samples of new ones are dropped; "+
+					"consider removing high-cardinality tags or raising maxSeries", o.config.MaxSeries.Int64)
+			}
+			o.droppedSamples++
+			return
+		}
+		ser = &series{labels: formatLabels(s.Tags.Map()), sink: o.newSink(s.Metric.Type)}
+		o.series[s.TimeSeries] = ser
+	}
+	ser.sink.Add(s)
+}
+
+func (o *Output) newSink(mt metrics.MetricType) metrics.Sink {
+	if mt == metrics.Trend && o.config.TrendAs.String == TrendAsHistogram {
+		return &histogramSink{buckets: o.buckets, counts: make([]uint64, len(o.buckets))}
+	}
+	return metrics.NewSink(mt)
+}
+
+// ServeHTTP writes all series in the Prometheus text exposition format.
+func (o *Output) ServeHTTP(rw http.ResponseWriter, _ *http.Request) {
+	o.flushMetrics()
+
+	rw.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = rw.Write(o.exposition())
+}
+
+func (o *Output) exposition() []byte {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	byMetric := make(map[*metrics.Metric][]*series)
+	for ts, ser := range o.series {
+		byMetric[ts.Metric] = append(byMetric[ts.Metric], ser)
+	}
+	sorted := make([]*metrics.Metric, 0, len(byMetric))
+	for m := range byMetric {
+		sorted = append(sorted, m)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	var buf bytes.Buffer
+	for _, m := range sorted {
+		list := byMetric[m]
+		sort.Slice(list, func(i, j int) bool { return list[i].labels < list[j].labels })
+		o.writeMetric(&buf, m, list)
+	}
+
+	name := o.metricName("prometheus_dropped_samples")
+	fmt.Fprintf(&buf, "# HELP %s_total Samples dropped because of the maximum number of series.\n", name)
+	fmt.Fprintf(&buf, "# TYPE %s_total counter\n", name)
+	fmt.Fprintf(&buf, "%s_total %d\n", name, o.droppedSamples)
+	return buf.Bytes()
+}
+
+func (o *Output) writeMetric(buf *bytes.Buffer, m *metrics.Metric, list []*series) {
+	name := o.metricName(m.Name)
+	switch m.Type {
+	case metrics.Counter:
+		fmt.Fprintf(buf, "# TYPE %s_total counter\n", name)
+		for _, ser := range list {
+			writeSample(buf, name+"_total", ser.labels, "", ser.sink.(*metrics.CounterSink).Value)
+		}
+	case metrics.Gauge:
+		fmt.Fprintf(buf, "# TYPE %s gauge\n", name)
+		for _, ser := range list {
+			writeSample(buf, name, ser.labels, "", ser.sink.(*metrics.GaugeSink).Value)
+		}
+	case metrics.Rate:
+		// the rates are exposed as the ratio of the non-zero values, besides
+		// their counts, so they can also be aggregated over the series
+		fmt.Fprintf(buf, "# TYPE %s_rate gauge\n", name)
+		for _, ser := range list {
+			writeSample(buf, name+"_rate", ser.labels, "", ser.sink.Format(0)["rate"])
+		}
+		fmt.Fprintf(buf, "# TYPE %s_total counter\n", name)
+		for _, ser := range list {
+			rate := ser.sink.(*metrics.RateSink)
+			writeSample(buf, name+"_total", ser.labels, `result="true"`, float64(rate.Trues))
+			writeSample(buf, name+"_total", ser.labels, `result="false"`, float64(rate.Total-rate.Trues))
+		}
+	case metrics.Trend:
+		o.writeTrend(buf, name, list)
+	}
+}
+
+func (o *Output) writeTrend(buf *bytes.Buffer, name string, list []*series) {
+	if o.config.TrendAs.String == TrendAsHistogram {
+		fmt.Fprintf(buf, "# TYPE %s histogram\n", name)
+		for _, ser := range list {
+			h := ser.sink.(*histogramSink)
+			for i, b := range h.buckets {
+				writeSample(buf, name+"_bucket", ser.labels, `le="`+formatFloat(b)+`"`, float64(h.counts[i]))
+			}
+			writeSample(buf, name+"_bucket", ser.labels, `le="+Inf"`, float64(h.count))
+			writeSample(buf, name+"_sum", ser.labels, "", h.sum)
+			writeSample(buf, name+"_count", ser.labels, "", float64(h.count))
+		}
+		return
+	}
+
+	fmt.Fprintf(buf, "# TYPE %s summary\n", name)
+	for _, ser := range list {
+		t := ser.sink.(*metrics.TrendSink)
+		for _, q := range summaryQuantiles {
+			writeSample(buf, name, ser.labels, `quantile="`+formatFloat(q)+`"`, t.P(q))
+		}
+		writeSample(buf, name+"_sum", ser.labels, "", t.Total())
+		writeSample(buf, name+"_count", ser.labels, "", float64(t.Count()))
+	}
+}
+
+func (o *Output) metricName(name string) string {
+	if ns := o.config.Namespace.String; ns != "" {
+		name = ns + "_" + name
+	}
+	return sanitizeName(name)
+}
+
+func writeSample(buf *bytes.Buffer, name, labels, extraLabel string, value float64) {
+	buf.WriteString(name)
+	switch {
+	case labels != "" && extraLabel != "":
+		buf.WriteString("{" + labels + "," + extraLabel + "}")
+	case labels != "":
+		buf.WriteString("{" + labels + "}")
+	case extraLabel != "":
+		buf.WriteString("{" + extraLabel + "}")
+	}
+	buf.WriteString(" " + formatFloat(value) + "\n")
+}
+
+// formatLabels formats the tags as the labels of a series, sorted by their
+// names.
+func formatLabels(tags map[string]string) string {
+	labels := make([]string, 0, len(tags))
+	for k, v := range tags {
+		labels = append(labels, sanitizeName(k)+`="`+labelValueReplacer.Replace(v)+`"`)
+	}
+	sort.Strings(labels)
+	return strings.Join(labels, ",")
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`) //nolint:gochecknoglobals
+
+// sanitizeName replaces the characters which aren't valid in the names of the
+// metrics and of the labels with underscores.
+func sanitizeName(name string) string {
+	b := []byte(name)
+	for i, c := range b {
+		valid := c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 0 && c >= '0' && c <= '9'
+		if !valid {
+			b[i] = '_'
+		}
+	}
+	return string(b)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
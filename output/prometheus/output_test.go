@@ -0,0 +1,140 @@
+package prometheus
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/liuxd6825/k6server/lib/testutils"
+	"github.com/liuxd6825/k6server/metrics"
+	"github.com/liuxd6825/k6server/output"
+)
+
+func newTestOutput(t *testing.T, jsonConfig string) *Output {
+	t.Helper()
+
+	params := output.Params{Logger: testutils.NewLogger(t)}
+	if jsonConfig != "" {
+		params.JSONConfig = json.RawMessage(jsonConfig)
+	}
+	out, err := New(params)
+	require.NoError(t, err)
+	o, ok := out.(*Output)
+	require.True(t, ok)
+	return o
+}
+
+func scrape(t *testing.T, handler http.Handler) string {
+	t.Helper()
+
+	rw := httptest.NewRecorder()
+	handler.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rw.Code)
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", rw.Header().Get("Content-Type"))
+	return rw.Body.String()
+}
+
+func TestOutputExposition(t *testing.T) {
+	t.Parallel()
+
+	registry := metrics.NewRegistry()
+	tags := registry.RootTagSet().WithTagsFromMap(map[string]string{"status": "200", "url": `http://k6.io/"a"`})
+	sample := func(m *metrics.Metric, value float64) metrics.Sample {
+		return metrics.Sample{TimeSeries: metrics.TimeSeries{Metric: m, Tags: tags}, Time: time.Now(), Value: value}
+	}
+	reqs := registry.MustNewMetric("http_reqs", metrics.Counter)
+	vus := registry.MustNewMetric("vus", metrics.Gauge)
+	checks := registry.MustNewMetric("checks", metrics.Rate)
+	duration := registry.MustNewMetric("http_req_duration", metrics.Trend)
+
+	o := newTestOutput(t, "")
+	pattern, handler := o.HTTPHandler()
+	require.Equal(t, "/metrics", pattern)
+	o.AddMetricSamples([]metrics.SampleContainer{metrics.Samples{
+		sample(reqs, 1), sample(reqs, 1), sample(vus, 3), sample(vus, 5),
+		sample(checks, 1), sample(checks, 0), sample(checks, 1), sample(duration, 100),
+	}})
+
+	labels := `status="200",url="http://k6.io/\"a\""`
+	assert.Equal(t, `# TYPE k6_checks_rate gauge
+k6_checks_rate{`+labels+`} 0.6666666666666666
+# TYPE k6_checks_total counter
+k6_checks_total{`+labels+`,result="true"} 2
+k6_checks_total{`+labels+`,result="false"} 1
+# TYPE k6_http_req_duration summary
+k6_http_req_duration{`+labels+`,quantile="0.5"} 100
+k6_http_req_duration{`+labels+`,quantile="0.9"} 100
+k6_http_req_duration{`+labels+`,quantile="0.95"} 100
+k6_http_req_duration{`+labels+`,quantile="0.99"} 100
+k6_http_req_duration_sum{`+labels+`} 100
+k6_http_req_duration_count{`+labels+`} 1
+# TYPE k6_http_reqs_total counter
+k6_http_reqs_total{`+labels+`} 2
+# TYPE k6_vus gauge
+k6_vus{`+labels+`} 5
+# HELP k6_prometheus_dropped_samples_total Samples dropped because of the maximum number of series.
+# TYPE k6_prometheus_dropped_samples_total counter
+k6_prometheus_dropped_samples_total 0
+`, scrape(t, handler))
+}
+
+func TestOutputHistogramAndMaxSeries(t *testing.T) {
+	t.Parallel()
+
+	registry := metrics.NewRegistry()
+	duration := registry.MustNewMetric("http_req_duration", metrics.Trend)
+	sample := func(url string, value float64) metrics.Sample {
+		return metrics.Sample{
+			TimeSeries: metrics.TimeSeries{
+				Metric: duration,
+				Tags:   registry.RootTagSet().With("url", url),
+			},
+			Time:  time.Now(),
+			Value: value,
+		}
+	}
+
+	o := newTestOutput(t, `{"namespace":"","trendAs":"histogram","buckets":"10,100","maxSeries":1}`)
+	o.AddMetricSamples([]metrics.SampleContainer{metrics.Samples{
+		sample("/a", 5), sample("/a", 50), sample("/a", 500), sample("/b", 1), sample("/c", 1),
+	}})
+
+	assert.Equal(t, `# TYPE http_req_duration histogram
+http_req_duration_bucket{url="/a",le="10"} 1
+http_req_duration_bucket{url="/a",le="100"} 2
+http_req_duration_bucket{url="/a",le="+Inf"} 3
+http_req_duration_sum{url="/a"} 555
+http_req_duration_count{url="/a"} 3
+# HELP prometheus_dropped_samples_total Samples dropped because of the maximum number of series.
+# TYPE prometheus_dropped_samples_total counter
+prometheus_dropped_samples_total 2
+`, scrape(t, o))
+}
+
+func TestOutputOwnAddress(t *testing.T) {
+	t.Parallel()
+
+	o := newTestOutput(t, `{"addr":"127.0.0.1:0"}`)
+	pattern, handler := o.HTTPHandler()
+	assert.Empty(t, pattern)
+	assert.Nil(t, handler)
+
+	require.NoError(t, o.Start())
+	addr := o.addr().String()
+	res, err := http.Get("http://" + addr + "/metrics") //nolint:noctx
+	require.NoError(t, err)
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+	assert.Contains(t, string(body), "k6_prometheus_dropped_samples_total 0")
+
+	require.NoError(t, o.Stop())
+	_, err = http.Get("http://" + addr + "/metrics") //nolint:noctx,bodyclose
+	assert.Error(t, err)
+}
@@ -6,6 +6,7 @@ package output
 import (
 	"encoding/json"
 	"io"
+	"net/http"
 	"net/url"
 
 	"github.com/sirupsen/logrus"
@@ -93,3 +94,11 @@ type WithBuiltinMetrics interface {
 	Output
 	SetBuiltinMetrics(builtinMetrics *metrics.BuiltinMetrics)
 }
+
+// WithHTTPHandler is an output that serves HTTP requests, e.g. to be scraped,
+// which can be served by the REST API server of k6. The handler is nil if the
+// output doesn't need it, e.g. because it serves them on its own address.
+type WithHTTPHandler interface {
+	Output
+	HTTPHandler() (pattern string, handler http.Handler)
+}
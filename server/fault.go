@@ -0,0 +1,276 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	jsserver "github.com/liuxd6825/k6server/js/modules/k6/server"
+	"github.com/liuxd6825/k6server/lib/fault"
+)
+
+// faultRules keeps the faults of the routes which were changed at runtime.
+// They take precedence over the ones declared by the script and the options,
+// which are kept in the route table, so they also survive reloads.
+type faultRules struct {
+	mu        sync.Mutex
+	overrides map[string]fault.Rule
+	rnd       *rand.Rand
+}
+
+func newFaultRules() *faultRules {
+	return &faultRules{
+		overrides: make(map[string]fault.Rule),
+		rnd:       rand.New(rand.NewSource(time.Now().UnixNano())), //nolint:gosec
+	}
+}
+
+// faultPlan are the faults that were picked for a single request.
+type faultPlan struct {
+	latency  time.Duration
+	reset    bool
+	err      *fault.Error
+	slowDrip *fault.SlowDrip
+	truncate *fault.Truncate
+}
+
+// declaredFaults returns the faults of the routes declared by the script and
+// the options, with an entry for every route, even the ones without faults.
+func (s *Server) declaredFaults(
+	routes []jsserver.Route, options map[string]fault.Rule,
+) map[string]fault.Rule {
+	declared := make(map[string]fault.Rule, len(routes))
+	for _, route := range routes {
+		var rule fault.Rule
+		if route.Faults != nil {
+			rule = *route.Faults
+		}
+		declared[fault.RouteKey(route.Method, route.Path)] = rule
+	}
+	for key, rule := range options {
+		key = fault.NormalizeRouteKey(key)
+		if _, ok := declared[key]; !ok {
+			s.logger.Warnf("There are faults in the options for the route '%s', which the script didn't register", key)
+			continue
+		}
+		if err := rule.Validate(); err != nil {
+			s.logger.WithError(err).Warnf("Invalid faults in the options for the route '%s'", key)
+			continue
+		}
+		declared[key] = rule
+	}
+	return declared
+}
+
+// Faults returns the current faults of all of the routes by route, e.g.
+// "GET /users/:id".
+func (s *Server) Faults() map[string]fault.Rule {
+	table := s.table.Load()
+	s.faults.mu.Lock()
+	defer s.faults.mu.Unlock()
+
+	rules := make(map[string]fault.Rule, len(table.faults))
+	for key, rule := range table.faults {
+		if override, ok := s.faults.overrides[key]; ok {
+			rule = override
+		}
+		rules[key] = rule
+	}
+	return rules
+}
+
+// SetFaults replaces the faults of a route, until they are reset.
+func (s *Server) SetFaults(route string, rule fault.Rule) error {
+	route = fault.NormalizeRouteKey(route)
+	if _, ok := s.table.Load().faults[route]; !ok {
+		return fmt.Errorf("%w '%s'", fault.ErrUnknownRoute, route)
+	}
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+
+	s.faults.mu.Lock()
+	s.faults.overrides[route] = rule
+	s.faults.mu.Unlock()
+	return nil
+}
+
+// ResetFaults restores the faults of a route to the ones declared by the
+// script and the options.
+func (s *Server) ResetFaults(route string) error {
+	route = fault.NormalizeRouteKey(route)
+	if _, ok := s.table.Load().faults[route]; !ok {
+		return fmt.Errorf("%w '%s'", fault.ErrUnknownRoute, route)
+	}
+
+	s.faults.mu.Lock()
+	delete(s.faults.overrides, route)
+	s.faults.mu.Unlock()
+	return nil
+}
+
+// planFaults picks the faults of the route which are injected into the
+// response of a single request.
+func (s *Server) planFaults(table *routeTable, key string) faultPlan {
+	s.faults.mu.Lock()
+	defer s.faults.mu.Unlock()
+
+	rule, ok := s.faults.overrides[key]
+	if !ok {
+		rule = table.faults[key]
+	}
+
+	rnd := s.faults.rnd
+	var plan faultPlan
+	if rule.Latency != nil {
+		plan.latency = rule.Latency.Sample(rnd)
+	}
+	if rule.Reset != nil && fault.Hit(rnd, rule.Reset.Rate) {
+		plan.reset = true
+	}
+	if rule.Error != nil && fault.Hit(rnd, rule.Error.Rate) {
+		plan.err = rule.Error
+	}
+	if rule.SlowDrip != nil && fault.Hit(rnd, rule.SlowDrip.Rate) {
+		plan.slowDrip = rule.SlowDrip
+	}
+	if rule.Truncate != nil && fault.Hit(rnd, rule.Truncate.Rate) {
+		plan.truncate = rule.Truncate
+	}
+	return plan
+}
+
+// injectBefore injects the faults which replace the handling of the request.
+// It returns true if the request was handled by them.
+func (p faultPlan) injectBefore(w http.ResponseWriter, r *http.Request) bool {
+	if p.latency > 0 {
+		timer := time.NewTimer(p.latency)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-r.Context().Done():
+			return true
+		}
+	}
+
+	switch {
+	case p.reset:
+		resetConnection(w)
+		return true
+	case p.err != nil:
+		body := p.err.Body
+		if body == "" {
+			body = http.StatusText(p.err.Status)
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(p.err.Status)
+		_, _ = w.Write([]byte(body))
+		return true
+	}
+	return false
+}
+
+// writer returns the writer for the body of the response, which injects the
+// faults that change how the body is sent.
+func (p faultPlan) writer(ctx context.Context, w http.ResponseWriter, body []byte) http.ResponseWriter {
+	if p.slowDrip != nil {
+		w = &drippingWriter{
+			ResponseWriter: w,
+			ctx:            ctx,
+			chunkSize:      p.slowDrip.ChunkSize,
+			interval:       time.Duration(p.slowDrip.Interval),
+		}
+	}
+	if p.truncate != nil {
+		// The client still expects the whole body, so net/http closes the
+		// connection after the truncated one is sent.
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w = &truncatingWriter{ResponseWriter: w, remaining: int(float64(len(body)) * p.truncate.Ratio)}
+	}
+	return w
+}
+
+// resetConnection closes the connection of the request without a response,
+// which the client sees as a connection reset. It always aborts the handler,
+// so the request is recorded as one without a response.
+func resetConnection(w http.ResponseWriter) {
+	// with HTTP/2, just aborting the handler resets the stream instead
+	if conn, _, err := http.NewResponseController(w).Hijack(); err == nil {
+		if tcpConn, ok := conn.(*net.TCPConn); ok {
+			// makes Close() send a RST instead of a FIN
+			_ = tcpConn.SetLinger(0)
+		}
+		_ = conn.Close()
+	}
+	panic(http.ErrAbortHandler)
+}
+
+// truncatingWriter discards everything written after the first remaining
+// bytes of the body.
+type truncatingWriter struct {
+	http.ResponseWriter
+	remaining int
+}
+
+func (tw *truncatingWriter) Write(b []byte) (int, error) {
+	n := len(b)
+	if n > tw.remaining {
+		b = b[:tw.remaining]
+	}
+	tw.remaining -= len(b)
+	if _, err := tw.ResponseWriter.Write(b); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+func (tw *truncatingWriter) Unwrap() http.ResponseWriter {
+	return tw.ResponseWriter
+}
+
+// drippingWriter sends the body in chunks of chunkSize bytes, flushing and
+// waiting for interval after each of them.
+type drippingWriter struct {
+	http.ResponseWriter
+	ctx       context.Context
+	chunkSize int
+	interval  time.Duration
+}
+
+func (dw *drippingWriter) Write(b []byte) (int, error) {
+	rc := http.NewResponseController(dw.ResponseWriter)
+	written := 0
+	for written < len(b) {
+		end := written + dw.chunkSize
+		if end > len(b) {
+			end = len(b)
+		}
+		n, err := dw.ResponseWriter.Write(b[written:end])
+		written += n
+		if err != nil {
+			return written, err
+		}
+		_ = rc.Flush()
+		if written == len(b) {
+			break
+		}
+
+		timer := time.NewTimer(dw.interval)
+		select {
+		case <-timer.C:
+		case <-dw.ctx.Done():
+			timer.Stop()
+			return written, dw.ctx.Err()
+		}
+	}
+	return written, nil
+}
+
+func (dw *drippingWriter) Unwrap() http.ResponseWriter {
+	return dw.ResponseWriter
+}
@@ -0,0 +1,167 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/liuxd6825/k6server/lib/fault"
+	"github.com/liuxd6825/k6server/lib/types"
+	"github.com/liuxd6825/k6server/metrics"
+)
+
+const faultTestScript = `
+	import { get, faults } from "k6/server";
+
+	get("/body", (req, res) => res.send("0123456789"));
+	get("/declared", (req, res) => res.send("ok"));
+
+	faults("GET", "/declared", { error: { rate: 1, status: 418, body: "teapot" } });
+
+	export default function () {}
+`
+
+func newFaultTestServer(t *testing.T, options map[string]fault.Rule) (*Server, *httptest.Server) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	samples := make(chan metrics.SampleContainer, 100)
+	go func() {
+		for range samples { //nolint:revive
+		}
+	}()
+
+	state := getTestRunState(t, faultTestScript)
+	state.Options.ServerFaults = options
+	srv, err := New(ctx, state, samples, DefaultPoolConfig())
+	require.NoError(t, err)
+
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+	return srv, ts
+}
+
+func get(t *testing.T, url string) (*http.Response, string, error) {
+	t.Helper()
+
+	res, err := http.Get(url) //nolint:gosec,noctx
+	if err != nil {
+		return nil, "", err
+	}
+	defer func() { _ = res.Body.Close() }()
+	body, err := io.ReadAll(res.Body)
+	return res, string(body), err
+}
+
+func TestServerDeclaredFaults(t *testing.T) {
+	t.Parallel()
+
+	srv, ts := newFaultTestServer(t, map[string]fault.Rule{
+		"get /body": {Latency: &fault.Latency{Mean: types.Duration(100 * time.Millisecond)}},
+	})
+
+	res, body, err := get(t, ts.URL+"/declared")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusTeapot, res.StatusCode)
+	assert.Equal(t, "teapot", body)
+
+	start := time.Now()
+	_, body, err = get(t, ts.URL+"/body")
+	require.NoError(t, err)
+	assert.Equal(t, "0123456789", body)
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+
+	rules := srv.Faults()
+	assert.Len(t, rules, 2)
+	assert.Equal(t, 418, rules["GET /declared"].Error.Status)
+	assert.Equal(t, fault.Fixed, rules["GET /body"].Latency.Distribution)
+}
+
+func TestServerRuntimeFaults(t *testing.T) {
+	t.Parallel()
+
+	srv, ts := newFaultTestServer(t, nil)
+
+	require.ErrorIs(t, srv.SetFaults("GET /missing", fault.Rule{}), fault.ErrUnknownRoute)
+	require.Error(t, srv.SetFaults("GET /body", fault.Rule{Error: &fault.Error{Rate: 2}}))
+
+	t.Run("reset", func(t *testing.T) {
+		require.NoError(t, srv.SetFaults("GET /body", fault.Rule{Reset: &fault.Reset{Rate: 1}}))
+		_, _, err := get(t, ts.URL+"/body")
+		require.Error(t, err)
+	})
+
+	t.Run("truncate", func(t *testing.T) {
+		require.NoError(t, srv.SetFaults("GET /body", fault.Rule{Truncate: &fault.Truncate{Rate: 1, Ratio: 0.5}}))
+		res, body, err := get(t, ts.URL+"/body")
+		require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+		assert.EqualValues(t, 10, res.ContentLength)
+		assert.Equal(t, "01234", body)
+	})
+
+	t.Run("slow drip", func(t *testing.T) {
+		require.NoError(t, srv.SetFaults("GET /body", fault.Rule{
+			SlowDrip: &fault.SlowDrip{Rate: 1, ChunkSize: 2, Interval: types.Duration(20 * time.Millisecond)},
+		}))
+		start := time.Now()
+		_, body, err := get(t, ts.URL+"/body")
+		require.NoError(t, err)
+		assert.Equal(t, "0123456789", body)
+		assert.GreaterOrEqual(t, time.Since(start), 80*time.Millisecond)
+	})
+
+	t.Run("override declared", func(t *testing.T) {
+		require.NoError(t, srv.SetFaults("get /declared", fault.Rule{}))
+		_, body, err := get(t, ts.URL+"/declared")
+		require.NoError(t, err)
+		assert.Equal(t, "ok", body)
+
+		require.NoError(t, srv.ResetFaults("GET /declared"))
+		_, body, err = get(t, ts.URL+"/declared")
+		require.NoError(t, err)
+		assert.Equal(t, "teapot", body)
+	})
+}
+
+func TestServerResetFaultMetrics(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	samples := make(chan metrics.SampleContainer, 100)
+	state := getTestRunState(t, faultTestScript)
+	srv, err := New(ctx, state, samples, DefaultPoolConfig())
+	require.NoError(t, err)
+	require.NoError(t, srv.SetFaults("GET /body", fault.Rule{Reset: &fault.Reset{Rate: 1}}))
+
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+	_, _, err = get(t, ts.URL+"/body")
+	require.Error(t, err)
+
+	// the handler of the hijacked connection can still be running
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case sc := <-samples:
+			for _, s := range sc.GetSamples() {
+				if s.Metric == state.BuiltinMetrics.ServerReqFailed {
+					assert.Equal(t, 1.0, s.Value)
+					assert.Equal(t, "0", s.Tags.Map()["status"])
+					return
+				}
+			}
+		case <-timeout:
+			t.Fatal("the metrics of the reset request weren't emitted")
+		}
+	}
+}
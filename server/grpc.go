@@ -0,0 +1,175 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/dop251/goja"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/reflection"
+	reflectionv1 "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	reflectionv1alpha "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+
+	jsserver "github.com/liuxd6825/k6server/js/modules/k6/server"
+	"github.com/liuxd6825/k6server/metrics"
+)
+
+// HasGRPCMethods returns whether the script registered any gRPC methods.
+func (s *Server) HasGRPCMethods() bool {
+	return len(s.table.Load().grpcMethods) > 0
+}
+
+// NewGRPCServer returns a gRPC server for the methods registered by the
+// script, along with the reflection service for the loaded proto files. Like
+// the routes, the calls are handled by the VUs of the pool, and the methods
+// are replaced when the script is reloaded.
+func (s *Server) NewGRPCServer(opts ...grpc.ServerOption) *grpc.Server {
+	gs := grpc.NewServer(append(opts, grpc.UnknownServiceHandler(s.handleGRPC))...)
+
+	services := grpcServices{s}
+	reflectionOpts := reflection.ServerOptions{Services: services, DescriptorResolver: services}
+	reflectionv1.RegisterServerReflectionServer(gs, reflection.NewServerV1(reflectionOpts))
+	reflectionv1alpha.RegisterServerReflectionServer(gs, reflection.NewServer(reflectionOpts))
+	return gs
+}
+
+// handleGRPC handles the calls of all of the methods, since they can change
+// when the script is reloaded, while the services of a grpc.Server can't.
+func (s *Server) handleGRPC(_ interface{}, stream grpc.ServerStream) (err error) {
+	fullMethod, _ := grpc.MethodFromServerStream(stream)
+	table := s.currentTable()
+	defer s.releaseTable(table)
+
+	start := s.metrics.started()
+	defer func() {
+		s.metrics.finishedGRPC(start, fullMethod, status.Code(err))
+	}()
+	if _, ok := table.grpcMethods[fullMethod]; !ok {
+		return status.Errorf(codes.Unimplemented, "unknown method %s", fullMethod)
+	}
+
+	ctx := stream.Context()
+	vu, err := table.pool.acquire(ctx)
+	switch {
+	case errors.Is(err, errPoolExhausted), errors.Is(err, context.Canceled):
+		s.logger.WithError(err).Debugf("Couldn't get a VU for %s", fullMethod)
+		return status.Error(codes.Unavailable, err.Error())
+	case err != nil:
+		s.logger.WithError(err).Errorf("Couldn't initialize a VU for %s", fullMethod)
+		return status.Error(codes.Internal, "couldn't initialize a VU")
+	}
+	defer table.pool.release(vu)
+
+	// The timers which the script didn't clear are stopped when the call is
+	// ended or canceled.
+	runCtx, runCancel := context.WithCancel(context.Background())
+	defer runCancel()
+	defer context.AfterFunc(ctx, runCancel)()
+
+	var call *jsserver.GRPCCall
+	err = vu.RunFunc(runCtx, func(rt *goja.Runtime) error {
+		var serr error
+		call, serr = jsserver.RouterOf(rt).ServeGRPC(rt, fullMethod, stream, grpcObserver{runCancel})
+		return serr
+	})
+	if err != nil {
+		s.logger.WithError(err).Errorf("Error while handling %s", fullMethod)
+		return status.Error(codes.Internal, "the handler failed")
+	}
+	return call.Finish()
+}
+
+// grpcObserver stops the event loop of a call once it's ended.
+type grpcObserver struct {
+	stopRun context.CancelFunc
+}
+
+var _ jsserver.StreamObserver = grpcObserver{}
+
+func (grpcObserver) Opened()          {}
+func (grpcObserver) MessageSent()     {}
+func (grpcObserver) MessageReceived() {}
+func (o grpcObserver) Closed()        { o.stopRun() }
+
+// finishedGRPC emits the metrics of a served gRPC call, tagged with its method
+// and status code, like the ones of the k6/net/grpc client.
+func (rm *requestMetrics) finishedGRPC(start time.Time, fullMethod string, code codes.Code) {
+	end := time.Now()
+	tags := rm.runTags.
+		With(metrics.TagMethod.String(), fullMethod).
+		With(metrics.TagStatus.String(), strconv.Itoa(int(code)))
+
+	metrics.PushIfNotDone(rm.ctx, rm.samples, metrics.ConnectedSamples{
+		Samples: []metrics.Sample{
+			{
+				TimeSeries: metrics.TimeSeries{Metric: rm.builtin.ServerGRPCReqs, Tags: tags},
+				Time:       end,
+				Value:      1,
+			},
+			{
+				TimeSeries: metrics.TimeSeries{Metric: rm.builtin.ServerGRPCReqDuration, Tags: tags},
+				Time:       end,
+				Value:      metrics.D(end.Sub(start)),
+			},
+		},
+		Tags: tags,
+		Time: end,
+	})
+	rm.pushActive(end, rm.active.Add(-1))
+}
+
+// grpcServices provides the services and the proto files of the current
+// version of the script to the reflection service.
+type grpcServices struct {
+	s *Server
+}
+
+var (
+	_ reflection.ServiceInfoProvider = grpcServices{}
+	_ protodesc.Resolver             = grpcServices{}
+)
+
+func (gs grpcServices) GetServiceInfo() map[string]grpc.ServiceInfo {
+	info := map[string]grpc.ServiceInfo{
+		reflectionv1.ServerReflection_ServiceDesc.ServiceName:      {},
+		reflectionv1alpha.ServerReflection_ServiceDesc.ServiceName: {},
+	}
+	for _, m := range gs.s.table.Load().grpcMethods {
+		service := string(m.Descriptor.Parent().FullName())
+		si := info[service]
+		si.Methods = append(si.Methods, grpc.MethodInfo{
+			Name:           string(m.Descriptor.Name()),
+			IsClientStream: m.Descriptor.IsStreamingClient(),
+			IsServerStream: m.Descriptor.IsStreamingServer(),
+		})
+		info[service] = si
+	}
+	return info
+}
+
+// FindFileByPath falls back to the global registry, which has the descriptors
+// of the reflection service itself, as does FindDescriptorByName.
+func (gs grpcServices) FindFileByPath(path string) (protoreflect.FileDescriptor, error) {
+	if files := gs.s.table.Load().grpcFiles; files != nil {
+		if fd, err := files.FindFileByPath(path); err == nil {
+			return fd, nil
+		}
+	}
+	return protoregistry.GlobalFiles.FindFileByPath(path)
+}
+
+func (gs grpcServices) FindDescriptorByName(name protoreflect.FullName) (protoreflect.Descriptor, error) {
+	if files := gs.s.table.Load().grpcFiles; files != nil {
+		if d, err := files.FindDescriptorByName(name); err == nil {
+			return d, nil
+		}
+	}
+	return protoregistry.GlobalFiles.FindDescriptorByName(name)
+}
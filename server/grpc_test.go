@@ -0,0 +1,232 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	reflectionv1 "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+
+	"github.com/liuxd6825/k6server/lib/fsext"
+	"github.com/liuxd6825/k6server/lib/testutils/grpcservice"
+	"github.com/liuxd6825/k6server/metrics"
+)
+
+const grpcTestScript = `
+	import { loadProto, grpc } from "k6/server";
+	import { StatusNotFound } from "k6/net/grpc";
+
+	loadProto([%q], "route_guide.proto");
+
+	grpc("main.FeatureExplorer/GetFeature", (call, point) => {
+		if (point.latitude === 0) {
+			call.error(StatusNotFound, "no feature");
+			return;
+		}
+		call.send({ name: call.metadata["x-name"][0], location: point });
+	});
+
+	grpc("/main.FeatureExplorer/ListFeatures", (call, rect) => {
+		let n = rect.lo.latitude;
+		const timer = setInterval(() => {
+			call.send({ name: "feature " + n });
+			if (++n === rect.hi.latitude) {
+				clearInterval(timer);
+				call.end();
+			}
+		}, 5);
+	});
+
+	let count;
+	grpc("main.RouteGuide/RecordRoute", {
+		open() {
+			count = 0;
+		},
+		message(call, point) {
+			count++;
+		},
+		end(call) {
+			call.send({ pointCount: count });
+		},
+	});
+
+	grpc("main.RouteGuide/RouteChat", {
+		message(call, note) {
+			call.send({ message: note.message.toUpperCase() });
+		},
+	});
+
+	export default function () {}
+`
+
+func newGRPCTestServer(t *testing.T, samples chan metrics.SampleContainer) (*Server, *grpc.ClientConn) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	cwd, err := os.Getwd() //nolint:forbidigo
+	require.NoError(t, err)
+	protoDir := filepath.Join(cwd, "..", "lib", "testutils", "grpcservice")
+	script := fmt.Sprintf(grpcTestScript, protoDir)
+	state := getTestRunStateWithFS(t, script,
+		&url.URL{Scheme: "file", Path: filepath.Join(cwd, "script.js")},
+		map[string]fsext.Fs{"file": fsext.NewOsFs()})
+
+	srv, err := New(ctx, state, samples, DefaultPoolConfig())
+	require.NoError(t, err)
+	require.True(t, srv.HasGRPCMethods())
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	gs := srv.NewGRPCServer()
+	go func() { _ = gs.Serve(lis) }()
+	t.Cleanup(gs.Stop)
+
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return srv, conn
+}
+
+func TestServerGRPC(t *testing.T) {
+	t.Parallel()
+
+	samples := make(chan metrics.SampleContainer, 1000)
+	_, conn := newGRPCTestServer(t, samples)
+	ctx := context.Background()
+	features := grpcservice.NewFeatureExplorerClient(conn)
+	routes := grpcservice.NewRouteGuideClient(conn)
+
+	t.Run("unary", func(t *testing.T) {
+		ctx := metadata.AppendToOutgoingContext(ctx, "x-name", "summit")
+		feature, err := features.GetFeature(ctx, &grpcservice.Point{Latitude: 1, Longitude: 2})
+		require.NoError(t, err)
+		assert.Equal(t, "summit", feature.Name)
+		assert.Equal(t, int32(2), feature.Location.Longitude)
+
+		_, err = features.GetFeature(ctx, &grpcservice.Point{})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+		assert.Equal(t, "no feature", status.Convert(err).Message())
+	})
+
+	t.Run("server streaming", func(t *testing.T) {
+		stream, err := features.ListFeatures(ctx, &grpcservice.Rectangle{
+			Lo: &grpcservice.Point{Latitude: 1},
+			Hi: &grpcservice.Point{Latitude: 4},
+		})
+		require.NoError(t, err)
+		var names []string
+		for {
+			feature, rerr := stream.Recv()
+			if rerr == io.EOF {
+				break
+			}
+			require.NoError(t, rerr)
+			names = append(names, feature.Name)
+		}
+		assert.Equal(t, []string{"feature 1", "feature 2", "feature 3"}, names)
+	})
+
+	t.Run("client streaming", func(t *testing.T) {
+		stream, err := routes.RecordRoute(ctx)
+		require.NoError(t, err)
+		for i := 0; i < 5; i++ {
+			require.NoError(t, stream.Send(&grpcservice.Point{Latitude: int32(i)}))
+		}
+		summary, err := stream.CloseAndRecv()
+		require.NoError(t, err)
+		assert.Equal(t, int32(5), summary.PointCount)
+	})
+
+	t.Run("bidi streaming", func(t *testing.T) {
+		stream, err := routes.RouteChat(ctx)
+		require.NoError(t, err)
+		for _, msg := range []string{"hello", "world"} {
+			require.NoError(t, stream.Send(&grpcservice.RouteNote{Message: msg}))
+			note, rerr := stream.Recv()
+			require.NoError(t, rerr)
+			assert.Equal(t, strings.ToUpper(msg), note.Message)
+		}
+		require.NoError(t, stream.CloseSend())
+		_, err = stream.Recv()
+		assert.Equal(t, io.EOF, err)
+	})
+
+	t.Run("unknown method", func(t *testing.T) {
+		err := conn.Invoke(ctx, "/main.Nope/Nope", &grpcservice.Point{}, &grpcservice.Point{})
+		assert.Equal(t, codes.Unimplemented, status.Code(err))
+	})
+}
+
+func TestServerGRPCReflection(t *testing.T) {
+	t.Parallel()
+
+	_, conn := newGRPCTestServer(t, make(chan metrics.SampleContainer, 1000))
+	stream, err := reflectionv1.NewServerReflectionClient(conn).ServerReflectionInfo(context.Background())
+	require.NoError(t, err)
+
+	require.NoError(t, stream.Send(&reflectionv1.ServerReflectionRequest{
+		MessageRequest: &reflectionv1.ServerReflectionRequest_ListServices{},
+	}))
+	res, err := stream.Recv()
+	require.NoError(t, err)
+	var services []string
+	for _, s := range res.GetListServicesResponse().GetService() {
+		services = append(services, s.GetName())
+	}
+	sort.Strings(services)
+	assert.Equal(t, []string{
+		"grpc.reflection.v1.ServerReflection",
+		"grpc.reflection.v1alpha.ServerReflection",
+		"main.FeatureExplorer",
+		"main.RouteGuide",
+	}, services)
+
+	require.NoError(t, stream.Send(&reflectionv1.ServerReflectionRequest{
+		MessageRequest: &reflectionv1.ServerReflectionRequest_FileContainingSymbol{
+			FileContainingSymbol: "main.RouteGuide",
+		},
+	}))
+	res, err = stream.Recv()
+	require.NoError(t, err)
+	assert.NotEmpty(t, res.GetFileDescriptorResponse().GetFileDescriptorProto())
+}
+
+func TestServerGRPCMetrics(t *testing.T) {
+	t.Parallel()
+
+	samples := make(chan metrics.SampleContainer, 1000)
+	srv, conn := newGRPCTestServer(t, samples)
+	features := grpcservice.NewFeatureExplorerClient(conn)
+	_, err := features.GetFeature(context.Background(), &grpcservice.Point{})
+	require.Error(t, err)
+
+	builtin := srv.table.Load().pool.state.BuiltinMetrics
+	for {
+		sc := <-samples
+		for _, s := range sc.GetSamples() {
+			if s.Metric != builtin.ServerGRPCReqs {
+				continue
+			}
+			tags := s.Tags.Map()
+			assert.Equal(t, "/main.FeatureExplorer/GetFeature", tags["method"])
+			assert.Equal(t, "5", tags["status"])
+			return
+		}
+	}
+}
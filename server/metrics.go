@@ -0,0 +1,134 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/liuxd6825/k6server/metrics"
+)
+
+// statusRecorder remembers the status code of the response written through it.
+type statusRecorder struct {
+	http.ResponseWriter
+	status  int
+	aborted bool // the connection was closed without a response
+}
+
+// responseStatus returns the status code of the response, which is 0 when
+// there wasn't one, just like for the failed requests in the http module.
+func (sr *statusRecorder) responseStatus() int {
+	switch {
+	case sr.aborted:
+		return 0
+	case sr.status == 0:
+		return http.StatusOK // the default of net/http
+	default:
+		return sr.status
+	}
+}
+
+// Hijack implements http.Hijacker, so the connections can be hijacked through
+// http.ResponseController. Other than the reset faults, which abort the
+// handler, they are only hijacked by the WebSocket upgrades, which respond
+// with 101 Switching Protocols.
+func (sr *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(sr.ResponseWriter).Hijack()
+	if err == nil && sr.status == 0 {
+		sr.status = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+func (sr *statusRecorder) WriteHeader(code int) {
+	if sr.status == 0 {
+		sr.status = code
+	}
+	sr.ResponseWriter.WriteHeader(code)
+}
+
+func (sr *statusRecorder) Write(b []byte) (int, error) {
+	if sr.status == 0 {
+		sr.status = http.StatusOK
+	}
+	return sr.ResponseWriter.Write(b)
+}
+
+// Unwrap allows http.ResponseController to reach the original writer.
+func (sr *statusRecorder) Unwrap() http.ResponseWriter {
+	return sr.ResponseWriter
+}
+
+// requestMetrics emits the server_* metrics of the served requests.
+type requestMetrics struct {
+	ctx     context.Context
+	samples chan<- metrics.SampleContainer
+	builtin *metrics.BuiltinMetrics
+	runTags *metrics.TagSet
+
+	active atomic.Int64
+}
+
+// started records that a request is being served and returns its start time.
+func (rm *requestMetrics) started() time.Time {
+	now := time.Now()
+	rm.pushActive(now, rm.active.Add(1))
+	return now
+}
+
+// finished emits the metrics of a served request. The route is the path it was
+// registered with, so its tag stays the same for all the paths it matches, and
+// it's empty for the requests that didn't match any route.
+func (rm *requestMetrics) finished(start time.Time, r *http.Request, route string, status int) {
+	end := time.Now()
+	builtin := rm.builtin
+
+	tags := rm.runTags.
+		With(metrics.TagMethod.String(), r.Method).
+		With(metrics.TagStatus.String(), strconv.Itoa(status))
+	if route != "" {
+		tags = tags.With("route", route)
+	}
+
+	// Only the server errors and the aborted connections are failures, the
+	// client errors are likely expected responses of a mock.
+	failed := 0.0
+	if status == 0 || status >= http.StatusInternalServerError {
+		failed = 1
+	}
+
+	metrics.PushIfNotDone(rm.ctx, rm.samples, metrics.ConnectedSamples{
+		Samples: []metrics.Sample{
+			{
+				TimeSeries: metrics.TimeSeries{Metric: builtin.ServerReqs, Tags: tags},
+				Time:       end,
+				Value:      1,
+			},
+			{
+				TimeSeries: metrics.TimeSeries{Metric: builtin.ServerReqDuration, Tags: tags},
+				Time:       end,
+				Value:      metrics.D(end.Sub(start)),
+			},
+			{
+				TimeSeries: metrics.TimeSeries{Metric: builtin.ServerReqFailed, Tags: tags},
+				Time:       end,
+				Value:      failed,
+			},
+		},
+		Tags: tags,
+		Time: end,
+	})
+	rm.pushActive(end, rm.active.Add(-1))
+}
+
+func (rm *requestMetrics) pushActive(t time.Time, active int64) {
+	metrics.PushIfNotDone(rm.ctx, rm.samples, metrics.Sample{
+		TimeSeries: metrics.TimeSeries{Metric: rm.builtin.ServerActiveRequests, Tags: rm.runTags},
+		Time:       t,
+		Value:      float64(active),
+	})
+}
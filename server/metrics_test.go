@@ -0,0 +1,71 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/liuxd6825/k6server/metrics"
+)
+
+func TestServerRequestMetrics(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	samples := make(chan metrics.SampleContainer, 100)
+	state := getTestRunState(t, `
+		import { get } from "k6/server";
+
+		get("/users/:id", (req, res) => {
+			res.json({ id: req.params.id });
+		});
+		get("/fail", () => {
+			throw new Error("oops");
+		});
+
+		export default function () {}
+	`)
+	srv, err := New(ctx, state, samples, DefaultPoolConfig())
+	require.NoError(t, err)
+
+	for _, path := range []string{"/users/1", "/users/2", "/fail", "/missing"} {
+		srv.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	close(samples)
+
+	type key struct{ metric, route, status string }
+	values := make(map[key]float64)
+	var maxActive float64
+	for sc := range samples {
+		for _, s := range sc.GetSamples() {
+			switch s.Metric {
+			case state.BuiltinMetrics.ServerReqs, state.BuiltinMetrics.ServerReqFailed:
+				tags := s.Tags.Map()
+				assert.Equal(t, http.MethodGet, tags["method"])
+				values[key{s.Metric.Name, tags["route"], tags["status"]}] += s.Value
+			case state.BuiltinMetrics.ServerReqDuration:
+				assert.Greater(t, s.Value, 0.0)
+			case state.BuiltinMetrics.ServerActiveRequests:
+				if s.Value > maxActive {
+					maxActive = s.Value
+				}
+			}
+		}
+	}
+
+	assert.Equal(t, map[key]float64{
+		{metrics.ServerReqsName, "/users/:id", "200"}:      2,
+		{metrics.ServerReqFailedName, "/users/:id", "200"}: 0,
+		{metrics.ServerReqsName, "/fail", "500"}:           1,
+		{metrics.ServerReqFailedName, "/fail", "500"}:      1,
+		{metrics.ServerReqsName, "", "404"}:                1,
+		{metrics.ServerReqFailedName, "", "404"}:           0,
+	}, values)
+	assert.Equal(t, 1.0, maxActive)
+}
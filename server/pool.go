@@ -0,0 +1,268 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/liuxd6825/k6server/lib"
+	"github.com/liuxd6825/k6server/metrics"
+)
+
+// errPoolExhausted is returned when no VU became free for a request in time.
+var errPoolExhausted = errors.New("all of the VUs are busy")
+
+// PoolConfig configures the pool of VUs that serve the requests.
+type PoolConfig struct {
+	// MinVUs is the number of VUs that are kept initialized, even when idle.
+	MinVUs int64
+	// MaxVUs is the maximum number of VUs, i.e. of concurrently served requests.
+	MaxVUs int64
+	// IdleTimeout is how long a VU above MinVUs can stay idle before it's
+	// removed from the pool. Zero means that VUs are never removed.
+	IdleTimeout time.Duration
+	// WaitTimeout is how long a request can wait for a free VU when all of
+	// them are busy before it's rejected. Zero means that it can wait as long
+	// as the client does.
+	WaitTimeout time.Duration
+}
+
+// DefaultPoolConfig returns the default pool settings.
+func DefaultPoolConfig() PoolConfig {
+	return PoolConfig{
+		MinVUs:      1,
+		MaxVUs:      10,
+		IdleTimeout: time.Minute,
+		WaitTimeout: 30 * time.Second,
+	}
+}
+
+// Validate checks if the pool settings make sense.
+func (c PoolConfig) Validate() error {
+	switch {
+	case c.MaxVUs < 1:
+		return fmt.Errorf("the maximum number of VUs should be more than 0, got %d", c.MaxVUs)
+	case c.MinVUs < 0:
+		return fmt.Errorf("the minimum number of VUs can't be negative, got %d", c.MinVUs)
+	case c.MinVUs > c.MaxVUs:
+		return fmt.Errorf("the minimum number of VUs (%d) can't be more than the maximum (%d)", c.MinVUs, c.MaxVUs)
+	case c.IdleTimeout < 0:
+		return fmt.Errorf("the VU idle timeout can't be negative, got %s", c.IdleTimeout)
+	case c.WaitTimeout < 0:
+		return fmt.Errorf("the VU wait timeout can't be negative, got %s", c.WaitTimeout)
+	}
+	return nil
+}
+
+// pooledVU is an initialized and activated VU owned by a vuPool.
+type pooledVU struct {
+	funcRunner
+	cancel   context.CancelFunc
+	lastUsed time.Time
+}
+
+// vuPool lends VUs to the requests, initializing new ones on demand, up to
+// the configured maximum, and removing the ones that stay idle for too long.
+type vuPool struct {
+	ctx     context.Context
+	config  PoolConfig
+	state   *lib.TestRunState
+	samples chan<- metrics.SampleContainer
+
+	// slots has a buffer of MaxVUs and a request has to put a value in it
+	// before it can borrow a VU, so it's full when all VUs are busy.
+	slots chan struct{}
+
+	mu     sync.Mutex
+	idle   []*pooledVU // a stack, so the least recently used VU is first
+	count  int64       // initialized VUs, or ones being initialized
+	lastID uint64
+
+	wg sync.WaitGroup // the removal of the idle VUs
+}
+
+func newVUPool(
+	ctx context.Context, state *lib.TestRunState, samples chan<- metrics.SampleContainer, config PoolConfig,
+) (*vuPool, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	p := &vuPool{
+		ctx:     ctx,
+		config:  config,
+		state:   state,
+		samples: samples,
+		slots:   make(chan struct{}, config.MaxVUs),
+	}
+
+	// At least one VU is needed for reading the routes of the script.
+	initVUs := config.MinVUs
+	if initVUs < 1 {
+		initVUs = 1
+	}
+	for i := int64(0); i < initVUs; i++ {
+		p.slots <- struct{}{}
+		p.mu.Lock()
+		p.count++
+		p.mu.Unlock()
+		vu, err := p.initVU()
+		if err != nil {
+			return nil, err
+		}
+		p.release(vu)
+	}
+
+	if config.IdleTimeout > 0 {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			p.removeIdleVUs()
+		}()
+	}
+	return p, nil
+}
+
+// acquire returns an idle VU or initializes a new one, waiting for one of the
+// busy VUs to be released if there are already MaxVUs of them.
+func (p *vuPool) acquire(ctx context.Context) (*pooledVU, error) {
+	select {
+	case p.slots <- struct{}{}:
+	default:
+		if err := p.waitForSlot(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	p.mu.Lock()
+	if n := len(p.idle); n > 0 {
+		vu := p.idle[n-1]
+		p.idle = p.idle[:n-1]
+		p.mu.Unlock()
+		return vu, nil
+	}
+	p.count++
+	p.mu.Unlock()
+
+	vu, err := p.initVU()
+	if err != nil {
+		p.mu.Lock()
+		p.count--
+		p.mu.Unlock()
+		<-p.slots
+		return nil, err
+	}
+	return vu, nil
+}
+
+func (p *vuPool) waitForSlot(ctx context.Context) error {
+	start := time.Now()
+	p.pushSample(p.state.BuiltinMetrics.ServerPoolWaits, start, 1)
+
+	var timeout <-chan time.Time
+	if p.config.WaitTimeout > 0 {
+		timer := time.NewTimer(p.config.WaitTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case p.slots <- struct{}{}:
+		now := time.Now()
+		p.pushSample(p.state.BuiltinMetrics.ServerPoolWaitDuration, now, metrics.D(now.Sub(start)))
+		return nil
+	case <-timeout:
+		p.pushSample(p.state.BuiltinMetrics.ServerPoolRejected, time.Now(), 1)
+		return errPoolExhausted
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// release returns a VU, which was acquired before, to the pool.
+func (p *vuPool) release(vu *pooledVU) {
+	vu.lastUsed = time.Now()
+	p.mu.Lock()
+	p.idle = append(p.idle, vu)
+	p.mu.Unlock()
+	<-p.slots
+}
+
+// initVU initializes and activates a new VU. The caller has to increment
+// count before calling it.
+func (p *vuPool) initVU() (*pooledVU, error) {
+	p.mu.Lock()
+	p.lastID++
+	id := p.lastID
+	count := p.count
+	p.mu.Unlock()
+
+	ctx, cancel := context.WithCancel(p.ctx)
+	initVU, err := p.state.Runner.NewVU(ctx, id, id, p.samples)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	vu, ok := initVU.Activate(&lib.VUActivationParams{RunContext: ctx}).(funcRunner)
+	if !ok {
+		cancel()
+		return nil, fmt.Errorf("the runner of type %T doesn't support the server mode", p.state.Runner)
+	}
+
+	p.state.Logger.Debugf("Initialized VU #%d for the server", id)
+	p.pushSample(p.state.BuiltinMetrics.ServerVUs, time.Now(), float64(count))
+	return &pooledVU{funcRunner: vu, cancel: cancel}, nil
+}
+
+// removeIdleVUs periodically removes the VUs above MinVUs, which have been
+// idle for longer than IdleTimeout, until the pool context is done.
+func (p *vuPool) removeIdleVUs() {
+	ticker := time.NewTicker(p.config.IdleTimeout / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			p.removeIdleSince(now.Add(-p.config.IdleTimeout))
+		case <-p.ctx.Done():
+			return
+		}
+	}
+}
+
+// wait waits for the removal of the idle VUs to stop, once the pool context is
+// done.
+func (p *vuPool) wait() {
+	p.wg.Wait()
+}
+
+func (p *vuPool) removeIdleSince(t time.Time) {
+	var removed []*pooledVU
+	p.mu.Lock()
+	for len(p.idle) > 0 && p.count > p.config.MinVUs && !p.idle[0].lastUsed.After(t) {
+		removed = append(removed, p.idle[0])
+		p.idle = p.idle[1:]
+		p.count--
+	}
+	count := p.count
+	p.mu.Unlock()
+
+	if len(removed) == 0 {
+		return
+	}
+	for _, vu := range removed {
+		vu.cancel()
+	}
+	p.state.Logger.Debugf("Removed %d idle VUs from the server pool", len(removed))
+	p.pushSample(p.state.BuiltinMetrics.ServerVUs, time.Now(), float64(count))
+}
+
+func (p *vuPool) pushSample(m *metrics.Metric, t time.Time, value float64) {
+	metrics.PushIfNotDone(p.ctx, p.samples, metrics.Sample{
+		TimeSeries: metrics.TimeSeries{Metric: m, Tags: p.state.RunTags},
+		Time:       t,
+		Value:      value,
+	})
+}
@@ -0,0 +1,135 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/liuxd6825/k6server/metrics"
+)
+
+const poolTestScript = `
+	import { get } from "k6/server";
+
+	get("/vu", async (req, res) => {
+		await new Promise((resolve) => setTimeout(resolve, 200));
+		res.send(String(__VU));
+	});
+
+	export default function () {}
+`
+
+func TestPoolConfigValidate(t *testing.T) {
+	t.Parallel()
+
+	require.NoError(t, DefaultPoolConfig().Validate())
+	require.NoError(t, PoolConfig{MinVUs: 0, MaxVUs: 1}.Validate())
+	require.Error(t, PoolConfig{MinVUs: 0, MaxVUs: 0}.Validate())
+	require.Error(t, PoolConfig{MinVUs: -1, MaxVUs: 1}.Validate())
+	require.Error(t, PoolConfig{MinVUs: 3, MaxVUs: 2}.Validate())
+	require.Error(t, PoolConfig{MinVUs: 1, MaxVUs: 2, IdleTimeout: -time.Second}.Validate())
+	require.Error(t, PoolConfig{MinVUs: 1, MaxVUs: 2, WaitTimeout: -time.Second}.Validate())
+}
+
+func TestPoolGrowsAndShrinks(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	samples := make(chan metrics.SampleContainer, 100)
+	state := getTestRunState(t, poolTestScript)
+	pool, err := newVUPool(ctx, state, samples, PoolConfig{MinVUs: 1, MaxVUs: 3, WaitTimeout: 50 * time.Millisecond})
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, pool.count)
+
+	vus := make([]*pooledVU, 0, 3)
+	for i := 0; i < 3; i++ {
+		vu, aerr := pool.acquire(ctx)
+		require.NoError(t, aerr)
+		vus = append(vus, vu)
+	}
+	assert.EqualValues(t, 3, pool.count)
+
+	_, err = pool.acquire(ctx)
+	require.ErrorIs(t, err, errPoolExhausted)
+
+	for _, vu := range vus {
+		pool.release(vu)
+	}
+	pool.removeIdleSince(time.Now())
+	assert.EqualValues(t, 1, pool.count)
+	assert.Len(t, pool.idle, 1)
+
+	close(samples)
+	counts := make(map[string]float64)
+	for sc := range samples {
+		for _, s := range sc.GetSamples() {
+			switch s.Metric.Name {
+			case metrics.ServerVUsName:
+				counts[s.Metric.Name] = s.Value // the last value
+			default:
+				counts[s.Metric.Name] += s.Value
+			}
+		}
+	}
+	assert.Equal(t, map[string]float64{
+		metrics.ServerVUsName:          1,
+		metrics.ServerPoolWaitsName:    1,
+		metrics.ServerPoolRejectedName: 1,
+	}, counts)
+}
+
+func TestServerConcurrentRequests(t *testing.T) {
+	t.Parallel()
+
+	srv := newTestServer(t, poolTestScript, PoolConfig{MinVUs: 1, MaxVUs: 3})
+
+	var wg sync.WaitGroup
+	bodies := make([]string, 3)
+	start := time.Now()
+	for i := range bodies {
+		i := i
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rec := httptest.NewRecorder()
+			srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/vu", nil))
+			assert.Equal(t, http.StatusOK, rec.Code)
+			bodies[i] = rec.Body.String()
+		}()
+	}
+	wg.Wait()
+
+	// All of the requests were served at the same time by different VUs.
+	assert.Less(t, time.Since(start), 600*time.Millisecond)
+	assert.ElementsMatch(t, []string{"1", "2", "3"}, bodies)
+}
+
+func TestServerPoolExhausted(t *testing.T) {
+	t.Parallel()
+
+	srv := newTestServer(t, poolTestScript, PoolConfig{MinVUs: 1, MaxVUs: 1, WaitTimeout: 10 * time.Millisecond})
+
+	var wg sync.WaitGroup
+	codes := make([]int, 2)
+	for i := range codes {
+		i := i
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rec := httptest.NewRecorder()
+			srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/vu", nil))
+			codes[i] = rec.Code
+		}()
+	}
+	wg.Wait()
+
+	assert.ElementsMatch(t, []int{http.StatusOK, http.StatusServiceUnavailable}, codes)
+}
@@ -0,0 +1,333 @@
+// Package server implements the HTTP server behind the `k6 server` command,
+// which serves the routes that a script registers with the k6/server module.
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/reflect/protoregistry"
+
+	jsserver "github.com/liuxd6825/k6server/js/modules/k6/server"
+	"github.com/liuxd6825/k6server/lib"
+	"github.com/liuxd6825/k6server/lib/fault"
+	"github.com/liuxd6825/k6server/metrics"
+)
+
+// reloadDrainTimeout is how long the requests that are being served by the
+// VUs of the previous version of a reloaded script can take before the VUs are
+// stopped anyway.
+const reloadDrainTimeout = 30 * time.Second
+
+// funcRunner is implemented by the active VUs of the JS runner. It allows
+// running code other than the exported functions on the VU event loop.
+type funcRunner interface {
+	RunFunc(ctx context.Context, fn func(rt *goja.Runtime) error) error
+}
+
+// Server is an http.Handler that serves the routes registered by a script.
+// Since the goja runtimes aren't goroutine-safe, every request borrows a VU
+// from a pool and runs the handler on it.
+type Server struct {
+	logger  logrus.FieldLogger
+	ctx     context.Context
+	samples chan<- metrics.SampleContainer
+	config  PoolConfig
+	metrics *requestMetrics
+	faults  *faultRules
+
+	// streams is done when the connections of the WebSocket and SSE routes
+	// have to be closed.
+	streams      context.Context
+	closeStreams context.CancelFunc
+
+	table atomic.Pointer[routeTable]
+
+	// wg tracks the requests being served and the pools of VUs, which all
+	// emit samples, see Wait.
+	wg sync.WaitGroup
+}
+
+var _ http.Handler = &Server{}
+
+// routeTable is the pool of VUs of a version of the script, along with the
+// routes it registered. It's replaced as a whole when the script is reloaded.
+type routeTable struct {
+	pool   *vuPool
+	cancel context.CancelFunc
+	mux    *http.ServeMux
+	routes map[string]string     // the registered paths by their mux patterns
+	faults map[string]fault.Rule // the declared faults by route, see fault.RouteKey
+
+	grpcMethods map[string]jsserver.GRPCMethod // the gRPC methods by their full names
+	grpcFiles   *protoregistry.Files
+
+	// streams is done when the connections of its WebSocket and SSE routes
+	// have to be closed, i.e. when the server is stopped or the table is
+	// replaced.
+	streams      context.Context
+	closeStreams context.CancelFunc
+
+	// inflight tracks the requests being served, so the table can be closed
+	// only after they are finished. mu guards adding to it once it's closed.
+	mu       sync.Mutex
+	closed   bool
+	inflight sync.WaitGroup
+}
+
+// New initializes the pool of VUs from the test runner and builds the route
+// table from the routes the script registered. The VUs are active until ctx
+// is done.
+func New(
+	ctx context.Context, state *lib.TestRunState, samples chan<- metrics.SampleContainer, config PoolConfig,
+) (*Server, error) {
+	s := &Server{
+		logger:  state.Logger,
+		ctx:     ctx,
+		samples: samples,
+		config:  config,
+		metrics: &requestMetrics{
+			ctx:     ctx,
+			samples: samples,
+			builtin: state.BuiltinMetrics,
+			runTags: state.RunTags,
+		},
+		faults: newFaultRules(),
+	}
+	s.streams, s.closeStreams = context.WithCancel(ctx)
+	table, err := s.newRouteTable(state)
+	if err != nil {
+		s.closeStreams()
+		return nil, err
+	}
+	s.table.Store(table)
+	return s, nil
+}
+
+// Reload replaces the routes and the pool of VUs with the ones of another
+// version of the script. The connections of the WebSocket and SSE routes of
+// the old version are closed, while the other requests that are already being
+// served by the old VUs are finished, for up to reloadDrainTimeout, before the
+// VUs are stopped. If the new version fails to initialize, the old one keeps
+// being served and an error is returned.
+func (s *Server) Reload(state *lib.TestRunState) error {
+	table, err := s.newRouteTable(state)
+	if err != nil {
+		return err
+	}
+	go s.closeRouteTable(s.table.Swap(table))
+	return nil
+}
+
+func (s *Server) newRouteTable(state *lib.TestRunState) (*routeTable, error) {
+	ctx, cancel := context.WithCancel(s.ctx)
+	table, err := s.buildRouteTable(ctx, state)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	table.cancel = cancel
+	table.streams, table.closeStreams = context.WithCancel(s.streams)
+	return table, nil
+}
+
+// closeRouteTable stops the VUs of a table that was replaced, once the
+// requests they are serving are finished or reloadDrainTimeout has passed.
+func (s *Server) closeRouteTable(table *routeTable) {
+	table.mu.Lock()
+	table.closed = true
+	table.mu.Unlock()
+	table.closeStreams()
+
+	drained := make(chan struct{})
+	go func() {
+		table.inflight.Wait()
+		close(drained)
+	}()
+	timer := time.NewTimer(reloadDrainTimeout)
+	defer timer.Stop()
+	select {
+	case <-drained:
+		s.logger.Debug("The VUs of the previous version of the script were stopped")
+	case <-timer.C:
+		s.logger.Warnf("Stopping the VUs of the previous version of the script, "+
+			"while they are still serving requests after %s", reloadDrainTimeout)
+	case <-s.ctx.Done():
+	}
+	table.cancel()
+}
+
+func (s *Server) buildRouteTable(ctx context.Context, state *lib.TestRunState) (*routeTable, error) {
+	pool, err := newVUPool(ctx, state, s.samples, s.config)
+	if err != nil {
+		return nil, err
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		<-ctx.Done()
+		pool.wait()
+	}()
+
+	vu, err := pool.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		routes      []jsserver.Route
+		grpcMethods []jsserver.GRPCMethod
+		grpcFiles   *protoregistry.Files
+	)
+	err = vu.RunFunc(ctx, func(rt *goja.Runtime) error {
+		if router := jsserver.RouterOf(rt); router != nil {
+			routes, grpcMethods, grpcFiles = router.Routes(), router.GRPCMethods(), router.GRPCFiles()
+		}
+		return nil
+	})
+	pool.release(vu)
+	if err != nil {
+		return nil, err
+	}
+	if len(routes) == 0 && len(grpcMethods) == 0 {
+		return nil, errors.New("the script didn't register any routes, use the k6/server module to register them")
+	}
+
+	table := &routeTable{
+		pool:        pool,
+		routes:      make(map[string]string, len(routes)),
+		faults:      s.declaredFaults(routes, state.Options.ServerFaults),
+		grpcMethods: make(map[string]jsserver.GRPCMethod, len(grpcMethods)),
+		grpcFiles:   grpcFiles,
+	}
+	for _, m := range grpcMethods {
+		table.grpcMethods[m.FullMethod] = m
+	}
+	if table.mux, err = s.newMux(table, routes); err != nil {
+		return nil, err
+	}
+	return table, nil
+}
+
+// newMux returns a ServeMux with a handler for each of the routes.
+func (s *Server) newMux(table *routeTable, routes []jsserver.Route) (mux *http.ServeMux, err error) {
+	mux = http.NewServeMux()
+	for _, route := range routes {
+		func() {
+			// ServeMux panics on invalid or conflicting patterns
+			defer func() {
+				if r := recover(); r != nil {
+					err = fmt.Errorf("invalid route '%s %s': %v", route.Method, route.Path, r)
+				}
+			}()
+			if route.Kind == jsserver.HTTPRoute {
+				mux.Handle(route.Pattern, s.routeHandler(table, route))
+			} else {
+				mux.Handle(route.Pattern, s.streamHandler(table, route))
+			}
+			table.routes[route.Pattern] = route.Path
+		}()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return mux, nil
+}
+
+func (s *Server) routeHandler(table *routeTable, route jsserver.Route) http.Handler {
+	pool, key := table.pool, fault.RouteKey(route.Method, route.Path)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		faults := s.planFaults(table, key)
+		if faults.injectBefore(w, r) {
+			return
+		}
+
+		vu, err := pool.acquire(r.Context())
+		switch {
+		case errors.Is(err, errPoolExhausted), errors.Is(err, context.Canceled):
+			s.logger.WithError(err).Debugf("Couldn't get a VU for %s %s", r.Method, r.URL.Path)
+			http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+			return
+		case err != nil:
+			s.logger.WithError(err).Errorf("Couldn't initialize a VU for %s %s", r.Method, r.URL.Path)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		defer pool.release(vu)
+
+		var res *jsserver.Response
+		err = vu.RunFunc(r.Context(), func(rt *goja.Runtime) error {
+			var serr error
+			res, serr = jsserver.RouterOf(rt).Serve(rt, route.Pattern, r)
+			return serr
+		})
+		if err != nil {
+			s.logger.WithError(err).Errorf("Error while handling %s %s", r.Method, r.URL.Path)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		if err = res.Write(faults.writer(r.Context(), w, res.Body())); err != nil {
+			s.logger.WithError(err).Debugf("Error while writing the response to %s %s", r.Method, r.URL.Path)
+		}
+	})
+}
+
+// Wait waits for the requests that are being served, including the
+// connections of the WebSocket and SSE routes, and for the pools of VUs to
+// stop, so nothing sends samples any more. It should be called once the
+// servers which use it are shut down and the context of the VUs is done.
+func (s *Server) Wait() {
+	s.wg.Wait()
+}
+
+// currentTable returns the route table of the latest version of the script,
+// with a request added to the ones it's serving, which has to be marked as
+// done with releaseTable.
+func (s *Server) currentTable() *routeTable {
+	s.wg.Add(1)
+	for {
+		table := s.table.Load()
+		table.mu.Lock()
+		if !table.closed {
+			table.inflight.Add(1)
+			table.mu.Unlock()
+			return table
+		}
+		// it was replaced and closed in the meantime
+		table.mu.Unlock()
+	}
+}
+
+func (s *Server) releaseTable(table *routeTable) {
+	table.inflight.Done()
+	s.wg.Done()
+}
+
+// ServeHTTP implements http.Handler and emits the metrics of every request,
+// including the ones that didn't match any of the routes.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	table := s.currentTable()
+	defer s.releaseTable(table)
+	start := s.metrics.started()
+
+	_, pattern := table.mux.Handler(r)
+	rec := &statusRecorder{ResponseWriter: w}
+	defer func() {
+		// e.g. the connection was aborted by a reset fault
+		p := recover()
+		if p != nil {
+			rec.aborted = true
+		}
+		s.metrics.finished(start, r, table.routes[pattern], rec.responseStatus())
+		if p != nil {
+			panic(p)
+		}
+	}()
+	table.mux.ServeHTTP(rec, r)
+}
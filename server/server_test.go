@@ -0,0 +1,203 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/liuxd6825/k6server/js"
+	"github.com/liuxd6825/k6server/lib"
+	"github.com/liuxd6825/k6server/lib/fsext"
+	"github.com/liuxd6825/k6server/lib/testutils"
+	"github.com/liuxd6825/k6server/loader"
+	"github.com/liuxd6825/k6server/metrics"
+)
+
+func getTestRunState(tb testing.TB, script string) *lib.TestRunState {
+	tb.Helper()
+	return getTestRunStateWithFS(tb, script, &url.URL{Path: "/script.js"}, nil)
+}
+
+// getTestRunStateWithFS is like getTestRunState, for scripts which read files.
+func getTestRunStateWithFS(
+	tb testing.TB, script string, scriptURL *url.URL, filesystems map[string]fsext.Fs,
+) *lib.TestRunState {
+	tb.Helper()
+
+	registry := metrics.NewRegistry()
+	piState := &lib.TestPreInitState{
+		Logger:         testutils.NewLogger(tb),
+		RuntimeOptions: lib.RuntimeOptions{},
+		Registry:       registry,
+		BuiltinMetrics: metrics.RegisterBuiltinMetrics(registry),
+	}
+	runner, err := js.New(piState, &loader.SourceData{
+		URL:  scriptURL,
+		Data: []byte(script),
+	}, filesystems)
+	require.NoError(tb, err)
+
+	return &lib.TestRunState{
+		TestPreInitState: piState,
+		Runner:           runner,
+		RunTags:          registry.RootTagSet(),
+	}
+}
+
+func newTestServer(tb testing.TB, script string, config PoolConfig) *Server {
+	tb.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	tb.Cleanup(cancel)
+
+	samples := make(chan metrics.SampleContainer, 100)
+	go func() {
+		for range samples { //nolint:revive
+		}
+	}()
+
+	srv, err := New(ctx, getTestRunState(tb, script), samples, config)
+	require.NoError(tb, err)
+	return srv
+}
+
+func TestServer(t *testing.T) {
+	t.Parallel()
+
+	srv := newTestServer(t, `
+		import { get, post } from "k6/server";
+
+		let counter = 0;
+
+		get("/counter", (req, res) => {
+			counter++;
+			res.json({ counter: counter, env: __ENV.NAME || "none" });
+		});
+		post("/fail", () => {
+			throw new Error("oops");
+		});
+		get("/async", async (req, res) => {
+			await Promise.resolve();
+			res.status(202).send("later");
+		});
+
+		export default function () {}
+	`, DefaultPoolConfig())
+
+	for i := 1; i <= 3; i++ {
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/counter", nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"counter":`+string(rune('0'+i))+`,"env":"none"}`, rec.Body.String())
+	}
+
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/fail", nil))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/async", nil))
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	assert.Equal(t, "later", rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/missing", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/counter", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestServerWithoutRoutes(t *testing.T) {
+	t.Parallel()
+
+	samples := make(chan metrics.SampleContainer, 100)
+	_, err := New(context.Background(), getTestRunState(t, `export default function () {}`), samples, DefaultPoolConfig())
+	require.ErrorContains(t, err, "the script didn't register any routes")
+}
+
+func TestServerReload(t *testing.T) {
+	t.Parallel()
+
+	srv := newTestServer(t, `
+		import { get } from "k6/server";
+
+		get("/version", async (req, res) => {
+			await new Promise((resolve) => setTimeout(resolve, Number(req.query.delay || 0)));
+			res.send("v1");
+		});
+
+		export default function () {}
+	`, DefaultPoolConfig())
+
+	// a request to the old version that is still being served during the reload
+	slow := httptest.NewRecorder()
+	slowDone := make(chan struct{})
+	go func() {
+		defer close(slowDone)
+		srv.ServeHTTP(slow, httptest.NewRequest(http.MethodGet, "/version?delay=300", nil))
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	require.NoError(t, srv.Reload(getTestRunState(t, `
+		import { get } from "k6/server";
+
+		get("/version", (req, res) => res.send("v2"));
+		get("/new", (req, res) => res.send("new"));
+
+		export default function () {}
+	`)))
+
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/new", nil))
+	assert.Equal(t, "new", rec.Body.String())
+
+	<-slowDone
+	assert.Equal(t, http.StatusOK, slow.Code)
+	assert.Equal(t, "v1", slow.Body.String())
+
+	// a version without routes isn't swapped in
+	err := srv.Reload(getTestRunState(t, `export default function () {}`))
+	require.ErrorContains(t, err, "the script didn't register any routes")
+
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/version", nil))
+	assert.Equal(t, "v2", rec.Body.String())
+}
+
+func TestServerWait(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	samples := make(chan metrics.SampleContainer, 1000)
+	srv, err := New(ctx, getTestRunState(t, `
+		import { get } from "k6/server";
+
+		get("/slow", (req, res) => {
+			const end = Date.now() + 200;
+			while (Date.now() < end) {}
+			res.send("done");
+		});
+
+		export default function () {}
+	`), samples, DefaultPoolConfig())
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	go srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	time.Sleep(50 * time.Millisecond)
+
+	// the request that is being served is interrupted and finished before
+	// Wait returns, so the samples channel can be closed afterwards
+	cancel()
+	srv.Wait()
+	close(samples)
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+}
@@ -0,0 +1,157 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/gorilla/websocket"
+
+	jsserver "github.com/liuxd6825/k6server/js/modules/k6/server"
+	"github.com/liuxd6825/k6server/lib/fault"
+	"github.com/liuxd6825/k6server/metrics"
+)
+
+//nolint:gochecknoglobals
+var upgrader = websocket.Upgrader{
+	// it's a mock for any client, including the browsers on other origins
+	CheckOrigin: func(*http.Request) bool { return true },
+}
+
+// CloseStreams closes the connections of the WebSocket and SSE routes, which
+// http.Server.Shutdown doesn't wait for. The WebSocket clients are told that
+// the server is going away, and the close handlers of the script are run.
+func (s *Server) CloseStreams() {
+	s.closeStreams()
+}
+
+// streamHandler returns the handler of a WebSocket or an SSE route. Every
+// connection holds a VU until it's closed, so the maximum number of VUs is
+// also the maximum number of the open connections.
+func (s *Server) streamHandler(table *routeTable, route jsserver.Route) http.Handler {
+	pool, key := table.pool, fault.RouteKey(route.Method, route.Path)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if route.Kind == jsserver.WebSocketRoute && !websocket.IsWebSocketUpgrade(r) {
+			w.Header().Set("Upgrade", "websocket")
+			http.Error(w, http.StatusText(http.StatusUpgradeRequired), http.StatusUpgradeRequired)
+			return
+		}
+		// Only the faults which replace the handling of the request apply to
+		// the connections, i.e. to the handshake of the WebSockets.
+		if s.planFaults(table, key).injectBefore(w, r) {
+			return
+		}
+
+		vu, err := pool.acquire(r.Context())
+		switch {
+		case errors.Is(err, errPoolExhausted), errors.Is(err, context.Canceled):
+			s.logger.WithError(err).Debugf("Couldn't get a VU for %s %s", r.Method, r.URL.Path)
+			http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+			return
+		case err != nil:
+			s.logger.WithError(err).Errorf("Couldn't initialize a VU for %s %s", r.Method, r.URL.Path)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		defer pool.release(vu)
+
+		// The connection is closed gracefully when the client disconnects, the
+		// server is stopped or the script is reloaded, while runCtx stops the
+		// timers which the script didn't clear after it was closed.
+		reqCtx, reqCancel := context.WithCancel(r.Context())
+		defer reqCancel()
+		defer context.AfterFunc(table.streams, reqCancel)()
+		r = r.WithContext(reqCtx)
+		runCtx, runCancel := context.WithCancel(context.Background())
+		defer runCancel()
+		observer := s.metrics.newStreamObserver(route, runCancel)
+
+		var conn *websocket.Conn
+		if route.Kind == jsserver.WebSocketRoute {
+			// on failure, the upgrader responds with the error
+			if conn, err = upgrader.Upgrade(w, r, nil); err != nil {
+				s.logger.WithError(err).Debugf("Couldn't upgrade %s %s to a WebSocket", r.Method, r.URL.Path)
+				return
+			}
+		}
+
+		err = vu.RunFunc(runCtx, func(rt *goja.Runtime) error {
+			router := jsserver.RouterOf(rt)
+			if conn != nil {
+				return router.ServeWebSocket(rt, route.Pattern, r, conn, observer)
+			}
+			return router.ServeSSE(rt, route.Pattern, r, w, observer)
+		})
+		if err != nil {
+			s.logger.WithError(err).Errorf("Error while handling %s %s", r.Method, r.URL.Path)
+			if conn == nil && !observer.opened {
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			}
+		}
+	})
+}
+
+// streamObserver emits the metrics of a connection of a WebSocket or an SSE
+// route, and stops its event loop once it's closed.
+type streamObserver struct {
+	rm      *requestMetrics
+	tags    *metrics.TagSet
+	opened  bool
+	start   time.Time
+	stopRun context.CancelFunc
+
+	connections, duration, sent, received *metrics.Metric
+}
+
+var _ jsserver.StreamObserver = &streamObserver{}
+
+func (rm *requestMetrics) newStreamObserver(route jsserver.Route, stopRun context.CancelFunc) *streamObserver {
+	o := &streamObserver{
+		rm:      rm,
+		tags:    rm.runTags.With("route", route.Path),
+		stopRun: stopRun,
+	}
+	if route.Kind == jsserver.WebSocketRoute {
+		o.connections, o.duration = rm.builtin.ServerWSSessions, rm.builtin.ServerWSSessionDuration
+		o.sent, o.received = rm.builtin.ServerWSMessagesSent, rm.builtin.ServerWSMessagesReceived
+	} else {
+		o.connections, o.duration = rm.builtin.ServerSSEStreams, rm.builtin.ServerSSEStreamDuration
+		o.sent = rm.builtin.ServerSSEEventsSent
+	}
+	return o
+}
+
+func (o *streamObserver) Opened() {
+	o.opened = true
+	o.start = time.Now()
+	o.push(o.connections, o.start, 1)
+}
+
+func (o *streamObserver) MessageSent() {
+	o.push(o.sent, time.Now(), 1)
+}
+
+func (o *streamObserver) MessageReceived() {
+	o.push(o.received, time.Now(), 1)
+}
+
+func (o *streamObserver) Closed() {
+	if o.opened {
+		now := time.Now()
+		o.push(o.duration, now, metrics.D(now.Sub(o.start)))
+	}
+	o.stopRun()
+}
+
+func (o *streamObserver) push(m *metrics.Metric, t time.Time, v float64) {
+	if m == nil {
+		return
+	}
+	metrics.PushIfNotDone(o.rm.ctx, o.rm.samples, metrics.Sample{
+		TimeSeries: metrics.TimeSeries{Metric: m, Tags: o.tags},
+		Time:       t,
+		Value:      v,
+	})
+}
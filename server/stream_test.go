@@ -0,0 +1,250 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/liuxd6825/k6server/lib"
+	"github.com/liuxd6825/k6server/metrics"
+)
+
+const streamTestScript = `
+	import { ws, sse } from "k6/server";
+
+	ws("/echo/:room", {
+		open(socket, req) {
+			socket.send("joined " + req.params.room);
+		},
+		message(socket, data) {
+			if (data === "bye") {
+				socket.close(4000, "see you");
+				return;
+			}
+			socket.send(data);
+		},
+		close(socket, code, reason) {
+			console.log("closed " + code + " " + reason);
+		},
+	});
+
+	let timer;
+	sse("/ticks", {
+		open(stream) {
+			let n = 0;
+			timer = setInterval(() => {
+				n++;
+				stream.send({ event: "tick", id: n, data: { n } });
+				if (n === 3) {
+					stream.close();
+				}
+			}, 10);
+		},
+		close() {
+			clearInterval(timer);
+		},
+	});
+
+	sse("/forever", {
+		open(stream) {
+			stream.send("hello");
+			timer = setInterval(() => {}, 10);
+		},
+		close() {
+			clearInterval(timer);
+		},
+	});
+
+	export default function () {}
+`
+
+// streamSamples collects the samples of the stream metrics until count of
+// them were received.
+func streamSamples(
+	t *testing.T, samples <-chan metrics.SampleContainer, state *lib.TestRunState, count int,
+) map[string]float64 {
+	t.Helper()
+
+	streamMetrics := map[*metrics.Metric]bool{
+		state.BuiltinMetrics.ServerWSSessions:         true,
+		state.BuiltinMetrics.ServerWSMessagesSent:     true,
+		state.BuiltinMetrics.ServerWSMessagesReceived: true,
+		state.BuiltinMetrics.ServerWSSessionDuration:  true,
+		state.BuiltinMetrics.ServerSSEStreams:         true,
+		state.BuiltinMetrics.ServerSSEEventsSent:      true,
+		state.BuiltinMetrics.ServerSSEStreamDuration:  true,
+	}
+	values := make(map[string]float64)
+	timeout := time.After(5 * time.Second)
+	for received := 0; received < count; {
+		select {
+		case sc := <-samples:
+			for _, s := range sc.GetSamples() {
+				if !streamMetrics[s.Metric] {
+					continue
+				}
+				received++
+				if s.Metric.Type == metrics.Trend {
+					assert.Greater(t, s.Value, 0.0)
+					values[s.Metric.Name]++
+					continue
+				}
+				values[s.Metric.Name] += s.Value
+			}
+		case <-timeout:
+			t.Fatalf("only %d of the %d stream samples were emitted: %v", received, count, values)
+		}
+	}
+	return values
+}
+
+func TestServerWebSocket(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	samples := make(chan metrics.SampleContainer, 1000)
+	state := getTestRunState(t, streamTestScript)
+	srv, err := New(ctx, state, samples, DefaultPoolConfig())
+	require.NoError(t, err)
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(ts.URL, "http") + "/echo/lobby"
+	conn, res, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+	_ = res.Body.Close()
+
+	read := func() string {
+		_, data, rerr := conn.ReadMessage()
+		require.NoError(t, rerr)
+		return string(data)
+	}
+	assert.Equal(t, "joined lobby", read())
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte("ping")))
+	assert.Equal(t, "ping", read())
+
+	// closed by the script
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte("bye")))
+	_, _, err = conn.ReadMessage()
+	var closeErr *websocket.CloseError
+	require.ErrorAs(t, err, &closeErr)
+	assert.Equal(t, 4000, closeErr.Code)
+	assert.Equal(t, "see you", closeErr.Text)
+
+	assert.Equal(t, map[string]float64{
+		metrics.ServerWSSessionsName:         1,
+		metrics.ServerWSMessagesSentName:     2,
+		metrics.ServerWSMessagesReceivedName: 2,
+		metrics.ServerWSSessionDurationName:  1,
+	}, streamSamples(t, samples, state, 6))
+
+	t.Run("not an upgrade", func(t *testing.T) {
+		r, body, gerr := get(t, ts.URL+"/echo/lobby")
+		require.NoError(t, gerr)
+		assert.Equal(t, http.StatusUpgradeRequired, r.StatusCode)
+		assert.Equal(t, "Upgrade Required\n", body)
+	})
+}
+
+func TestServerSSE(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	samples := make(chan metrics.SampleContainer, 1000)
+	state := getTestRunState(t, streamTestScript)
+	srv, err := New(ctx, state, samples, PoolConfig{MinVUs: 1, MaxVUs: 1})
+	require.NoError(t, err)
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	res, body, err := get(t, ts.URL+"/ticks")
+	require.NoError(t, err)
+	assert.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+	assert.Equal(t,
+		"id: 1\nevent: tick\ndata: {\"n\":1}\n\n"+
+			"id: 2\nevent: tick\ndata: {\"n\":2}\n\n"+
+			"id: 3\nevent: tick\ndata: {\"n\":3}\n\n",
+		body)
+	assert.Equal(t, map[string]float64{
+		metrics.ServerSSEStreamsName:        1,
+		metrics.ServerSSEEventsSentName:     3,
+		metrics.ServerSSEStreamDurationName: 1,
+	}, streamSamples(t, samples, state, 5))
+
+	t.Run("client disconnects", func(t *testing.T) {
+		reqCtx, reqCancel := context.WithCancel(context.Background())
+		req, rerr := http.NewRequestWithContext(reqCtx, http.MethodGet, ts.URL+"/forever", nil)
+		require.NoError(t, rerr)
+		res, rerr := http.DefaultClient.Do(req) //nolint:bodyclose
+		require.NoError(t, rerr)
+		line, rerr := bufio.NewReader(res.Body).ReadString('\n')
+		require.NoError(t, rerr)
+		assert.Equal(t, "data: hello\n", line)
+		reqCancel()
+
+		// the only VU is released once the close handler clears the timer
+		res, body, rerr := get(t, ts.URL+"/ticks")
+		require.NoError(t, rerr)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Contains(t, body, "id: 3\n")
+	})
+
+	t.Run("server stops", func(t *testing.T) {
+		req, rerr := http.NewRequest(http.MethodGet, ts.URL+"/forever", nil) //nolint:noctx
+		require.NoError(t, rerr)
+		res, rerr := http.DefaultClient.Do(req)
+		require.NoError(t, rerr)
+		defer func() { _ = res.Body.Close() }()
+
+		srv.CloseStreams()
+		body, rerr := bufio.NewReader(res.Body).ReadString(0)
+		require.Error(t, rerr) // EOF
+		assert.Equal(t, "data: hello\n\n", body)
+	})
+}
+
+func TestServerStreamReload(t *testing.T) {
+	t.Parallel()
+
+	srv := newTestServer(t, streamTestScript, PoolConfig{MinVUs: 1, MaxVUs: 1})
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL+"/forever", nil) //nolint:noctx
+	require.NoError(t, err)
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer func() { _ = res.Body.Close() }()
+	reader := bufio.NewReader(res.Body)
+	line, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "data: hello\n", line)
+
+	// the connections of the old version are closed, so its VUs are stopped
+	old := srv.table.Load()
+	require.NoError(t, srv.Reload(getTestRunState(t, streamTestScript)))
+	require.Eventually(t, func() bool {
+		return old.pool.ctx.Err() != nil
+	}, 5*time.Second, 10*time.Millisecond)
+	_, err = reader.ReadString(0)
+	require.Error(t, err) // EOF
+
+	// while the new version serves the new connections
+	res, body, err := get(t, ts.URL+"/ticks")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Contains(t, body, "id: 3\n")
+}
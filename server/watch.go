@@ -0,0 +1,76 @@
+package server
+
+import (
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/liuxd6825/k6server/lib/fsext"
+)
+
+// LocalFiles returns the paths of the local files among the modules imported
+// by a script, as returned by ModuleResolver.Imported(). The built-in modules
+// and the remote ones are skipped.
+func LocalFiles(imported []string) []string {
+	files := make([]string, 0, len(imported))
+	for _, specifier := range imported {
+		u, err := url.Parse(specifier)
+		if err != nil || u.Scheme != "file" {
+			continue
+		}
+		files = append(files, u.Path)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// FileWatcher detects the modifications of a set of files by polling their
+// modification times, so it works the same on all of the filesystems.
+type FileWatcher struct {
+	fs     fsext.Fs
+	mtimes map[string]time.Time
+}
+
+// NewFileWatcher returns a FileWatcher for files on fs.
+func NewFileWatcher(fs fsext.Fs) *FileWatcher {
+	return &FileWatcher{fs: fs, mtimes: make(map[string]time.Time)}
+}
+
+// SetFiles changes the watched files. The ones that were already watched keep
+// their last seen modification time, so a change made while a new version of
+// the script was being loaded isn't missed.
+func (w *FileWatcher) SetFiles(files []string) {
+	mtimes := make(map[string]time.Time, len(files))
+	for _, file := range files {
+		if mtime, ok := w.mtimes[file]; ok {
+			mtimes[file] = mtime
+		} else {
+			mtimes[file] = w.modTime(file)
+		}
+	}
+	w.mtimes = mtimes
+}
+
+// Changed returns the watched files which were modified, created or removed
+// since the last call.
+func (w *FileWatcher) Changed() []string {
+	var changed []string
+	for file, mtime := range w.mtimes {
+		if current := w.modTime(file); !current.Equal(mtime) {
+			w.mtimes[file] = current
+			changed = append(changed, file)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// modTime returns the modification time of the file, or the zero time if it
+// doesn't exist.
+func (w *FileWatcher) modTime(file string) time.Time {
+	info, err := w.fs.Stat(file)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
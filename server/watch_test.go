@@ -0,0 +1,47 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/liuxd6825/k6server/lib/fsext"
+)
+
+func TestLocalFiles(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, []string{"/a/lib.js", "/a/script.js"}, LocalFiles([]string{
+		"file:///a/script.js",
+		"k6/server",
+		"https://example.com/lib.js",
+		"file:///a/lib.js",
+	}))
+}
+
+func TestFileWatcher(t *testing.T) {
+	t.Parallel()
+
+	fs := fsext.NewMemMapFs()
+	require.NoError(t, fsext.WriteFile(fs, "/script.js", []byte("v1"), 0o644))
+	require.NoError(t, fsext.WriteFile(fs, "/lib.js", []byte("v1"), 0o644))
+
+	w := NewFileWatcher(fs)
+	w.SetFiles([]string{"/script.js"})
+	assert.Empty(t, w.Changed())
+
+	mtime := time.Now().Add(time.Minute)
+	require.NoError(t, fs.Chtimes("/script.js", mtime, mtime))
+	assert.Equal(t, []string{"/script.js"}, w.Changed())
+	assert.Empty(t, w.Changed())
+
+	// the change of an already watched file isn't missed while the files are set
+	require.NoError(t, fs.Chtimes("/script.js", mtime.Add(time.Minute), mtime.Add(time.Minute)))
+	w.SetFiles([]string{"/script.js", "/lib.js"})
+	assert.Equal(t, []string{"/script.js"}, w.Changed())
+
+	require.NoError(t, fs.Remove("/lib.js"))
+	assert.Equal(t, []string{"/lib.js"}, w.Changed())
+}